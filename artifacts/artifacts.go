@@ -0,0 +1,372 @@
+// Package artifacts loads artifact definitions in the ForensicArtifacts YAML format
+// (https://github.com/ForensicArtifacts/artifacts) so modules can reference path globs by name
+// instead of hard-coding them
+package artifacts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+	"howett.net/plist"
+)
+
+// Definition is a single artifact definition
+// MinOSVersion and MaxOSVersion are Orion extensions to the ForensicArtifacts format, both are inclusive
+type Definition struct {
+	Name         string   `yaml:"name"`
+	Doc          string   `yaml:"doc"`
+	Sources      []Source `yaml:"sources"`
+	SupportedOS  []string `yaml:"supported_os"`
+	URLs         []string `yaml:"urls"`
+	MinOSVersion string   `yaml:"min_os_version"`
+	MaxOSVersion string   `yaml:"max_os_version"`
+}
+
+// Source is a source of an artifact definition, Orion supports FILE, DIRECTORY, PATH and ARTIFACT_GROUP sources
+type Source struct {
+	Type         string           `yaml:"type"`
+	Attributes   SourceAttributes `yaml:"attributes"`
+	SupportedOS  []string         `yaml:"supported_os"`
+	MinOSVersion string           `yaml:"min_os_version"`
+	MaxOSVersion string           `yaml:"max_os_version"`
+}
+
+// SourceAttributes holds the attributes of a source used by Orion
+type SourceAttributes struct {
+	Paths     []string `yaml:"paths"`
+	Names     []string `yaml:"names"`
+	Separator string   `yaml:"separator"`
+}
+
+// homedirs are the user home directory globs %%users.homedir%% expands to, relative to the target root
+var homedirs = map[string][]string{
+	"mac":     {"Users/*", "private/var/*"},
+	"windows": {"Users/*"},
+}
+
+// defaultRecursionDepth and maxRecursionDepth bound the directory levels a ** path component matches, ** matches up
+// to defaultRecursionDepth levels like in GRR and Plaso, **<n> up to n levels
+const (
+	defaultRecursionDepth = 3
+	maxRecursionDepth     = 10
+)
+
+// Registry holds the artifact definitions available for a single Orion mode
+type Registry struct {
+	mutex *sync.RWMutex
+	mode  string
+	defs  map[string]Definition
+}
+
+// NewRegistry returns a registry holding the built-in definitions for mode
+func NewRegistry(mode string) (*Registry, error) {
+	r := &Registry{
+		mutex: &sync.RWMutex{},
+		mode:  mode,
+		defs:  make(map[string]Definition),
+	}
+	if builtin, ok := builtinDefinitions[mode]; ok {
+		err := r.LoadYAML([]byte(builtin))
+		if err != nil {
+			return r, fmt.Errorf("failed to load built-in %s artifact definitions: %s", mode, err.Error())
+		}
+	}
+	return r, nil
+}
+
+// LoadYAML loads one or more YAML documents of artifact definitions
+// Definitions replace any existing definition with the same name
+func (r *Registry) LoadYAML(data []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var def Definition
+		err := dec.Decode(&def)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if def.Name == "" {
+			if len(def.Sources) == 0 {
+				continue // empty document
+			}
+			return errors.New("artifact definition is missing a name")
+		}
+		r.mutex.Lock()
+		r.defs[def.Name] = def
+		r.mutex.Unlock()
+	}
+	return nil
+}
+
+// LoadFile loads artifact definitions from a YAML file
+func (r *Registry) LoadFile(fp string) error {
+	data, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return err
+	}
+	err = r.LoadYAML(data)
+	if err != nil {
+		return fmt.Errorf("failed to load artifact definitions from '%s': %s", fp, err.Error())
+	}
+	return nil
+}
+
+// Override replaces the paths of the named definition, creating it if it does not exist
+func (r *Registry) Override(name string, paths []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	def, ok := r.defs[name]
+	if !ok {
+		def = Definition{Name: name, Doc: "Defined in config"}
+	}
+	def.Sources = []Source{{Type: "FILE", Attributes: SourceAttributes{Paths: paths}}}
+	r.defs[name] = def
+}
+
+// AddPaths adds paths to the named definition, creating it if it does not exist
+func (r *Registry) AddPaths(name string, paths []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	def, ok := r.defs[name]
+	if !ok {
+		def = Definition{Name: name, Doc: "Defined in config"}
+	}
+	def.Sources = append(def.Sources, Source{Type: "FILE", Attributes: SourceAttributes{Paths: paths}})
+	r.defs[name] = def
+}
+
+// Get returns the named definition
+func (r *Registry) Get(name string) (Definition, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	def, ok := r.defs[name]
+	return def, ok
+}
+
+// Names returns the sorted names of every definition in the registry
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := []string{}
+	for name := range r.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Globs returns the expanded path globs of the named definition, relative to the target root
+// Definitions and sources outside of osVersion are skipped, an empty osVersion disables version checks
+func (r *Registry) Globs(name string, osVersion string) ([]string, error) {
+	return r.globs(name, osVersion, map[string]bool{})
+}
+
+func (r *Registry) globs(name string, osVersion string, seen map[string]bool) ([]string, error) {
+	if seen[name] {
+		return []string{}, fmt.Errorf("artifact group '%s' references itself", name)
+	}
+	seen[name] = true
+
+	def, ok := r.Get(name)
+	if !ok {
+		return []string{}, fmt.Errorf("unknown artifact definition '%s'", name)
+	}
+	if !versionInRange(osVersion, def.MinOSVersion, def.MaxOSVersion) {
+		zap.L().Debug("Skipping artifact '" + name + "' for OS version " + osVersion)
+		return []string{}, nil
+	}
+
+	globs := []string{}
+	for _, source := range def.Sources {
+		if !versionInRange(osVersion, source.MinOSVersion, source.MaxOSVersion) {
+			continue
+		}
+		switch source.Type {
+		case "ARTIFACT_GROUP":
+			for _, member := range source.Attributes.Names {
+				g, err := r.globs(member, osVersion, seen)
+				if err != nil {
+					return globs, err
+				}
+				globs = append(globs, g...)
+			}
+		case "FILE", "DIRECTORY", "PATH", "":
+			for _, p := range source.Attributes.Paths {
+				globs = append(globs, r.expand(p, source.Attributes.Separator)...)
+			}
+		default:
+			zap.L().Debug("Unsupported source type '" + source.Type + "' in artifact '" + name + "'")
+		}
+	}
+	return dedupe(globs), nil
+}
+
+// expand resolves ForensicArtifacts path variables and returns globs relative to the target root
+func (r *Registry) expand(p string, separator string) []string {
+	if separator == "\\" {
+		p = strings.Replace(p, "\\", "/", -1)
+	}
+	p = strings.Replace(p, "%%users.username%%", "*", -1)
+	p = strings.Replace(p, "%%environ_systemdrive%%", "", -1)
+	p = strings.Replace(p, "%%environ_systemroot%%", "Windows", -1)
+	p = strings.Replace(p, "%%environ_windir%%", "Windows", -1)
+
+	paths := []string{p}
+	for _, variable := range []string{"%%users.homedir%%", "%%users.userprofile%%"} {
+		if !strings.Contains(p, variable) {
+			continue
+		}
+		paths = []string{}
+		for _, home := range homedirs[r.mode] {
+			paths = append(paths, strings.Replace(p, variable, home, -1))
+		}
+	}
+
+	globs := []string{}
+	for _, path := range paths {
+		path = strings.TrimLeft(filepath.ToSlash(path), "/")
+		recursive, err := expandRecursion(path)
+		if err != nil {
+			zap.L().Warn("Skipping artifact path '" + p + "': " + err.Error())
+			continue
+		}
+		globs = append(globs, recursive...)
+	}
+	return globs
+}
+
+// expandRecursion expands each ** path component into the globs matching it at every depth, filepath.Match has no
+// recursive wildcard, a ** component matches zero or more directories, or one or more at the end of the path
+func expandRecursion(path string) ([]string, error) {
+	components := strings.Split(path, "/")
+	for i, c := range components {
+		if !strings.Contains(c, "**") {
+			continue
+		}
+		if !strings.HasPrefix(c, "**") {
+			return []string{}, errors.New("** must be a whole path component")
+		}
+		depth := defaultRecursionDepth
+		if c != "**" {
+			n, err := strconv.Atoi(c[2:])
+			if err != nil || n < 1 {
+				return []string{}, errors.New("invalid recursion depth '" + c + "'")
+			}
+			if n > maxRecursionDepth {
+				return []string{}, fmt.Errorf("recursion depth %d is over the limit of %d", n, maxRecursionDepth)
+			}
+			depth = n
+		}
+		last := i == len(components)-1
+		rest, err := expandRecursion(strings.Join(components[i+1:], "/"))
+		if err != nil {
+			return []string{}, err
+		}
+		globs := []string{}
+		for d := 0; d <= depth; d++ {
+			if d == 0 && last {
+				continue
+			}
+			levels := append(append([]string{}, components[:i]...), repeat("*", d)...)
+			for _, r := range rest {
+				if r != "" {
+					globs = append(globs, strings.Join(append(levels, r), "/"))
+				} else {
+					globs = append(globs, strings.Join(levels, "/"))
+				}
+			}
+		}
+		return globs, nil
+	}
+	return []string{path}, nil
+}
+
+func repeat(s string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = s
+	}
+	return out
+}
+
+func dedupe(items []string) []string {
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// DetectOSVersion returns the OS version of the target, or an empty string if it cannot be determined
+func DetectOSVersion(targetpath string, mode string) string {
+	switch mode {
+	case "mac":
+		f, err := os.Open(filepath.Join(targetpath, "System/Library/CoreServices/SystemVersion.plist"))
+		if err != nil {
+			zap.L().Debug("Could not determine target OS version: " + err.Error())
+			return ""
+		}
+		defer f.Close()
+		var data map[string]interface{}
+		err = plist.NewDecoder(f).Decode(&data)
+		if err != nil {
+			zap.L().Debug("Could not decode SystemVersion.plist: " + err.Error())
+			return ""
+		}
+		if version, ok := data["ProductVersion"].(string); ok {
+			return version
+		}
+	}
+	return ""
+}
+
+// versionInRange returns true if version is within [min, max], empty values are unbounded
+func versionInRange(version string, min string, max string) bool {
+	if version == "" {
+		return true
+	}
+	if min != "" && CompareVersions(version, min) < 0 {
+		return false
+	}
+	if max != "" && CompareVersions(version, max) > 0 {
+		return false
+	}
+	return true
+}
+
+// CompareVersions compares dotted version strings numerically, returning -1, 0 or 1
+// Missing trailing components are treated as wildcards, so "10.15.7" is equal to "10.15"
+func CompareVersions(a string, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	n := len(as)
+	if len(bs) < n {
+		n = len(bs)
+	}
+	for i := 0; i < n; i++ {
+		ai, _ := strconv.Atoi(as[i])
+		bi, _ := strconv.Atoi(bs[i])
+		if ai < bi {
+			return -1
+		} else if ai > bi {
+			return 1
+		}
+	}
+	return 0
+}
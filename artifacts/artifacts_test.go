@@ -0,0 +1,157 @@
+package artifacts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuiltinDefinitions(t *testing.T) {
+	r, err := NewRegistry("mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range r.Names() {
+		if _, err := r.Globs(name, ""); err != nil {
+			t.Errorf("Globs(%s) returned error: %s", name, err)
+		}
+	}
+}
+
+func TestGlobs(t *testing.T) {
+	r, err := NewRegistry("mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		osVersion string
+		want      []string
+	}{
+		{"MacOSUtmpx", "", []string{"private/var/run/utmpx"}},
+		{"MacOSSSHUserFiles", "", []string{
+			"Users/*/.ssh/*",
+			"private/var/*/.ssh/*",
+		}},
+		{"MacOSLoginItems", "10.12.6", []string{
+			"Users/*/Library/Preferences/com.apple.loginitems.plist",
+			"private/var/*/Library/Preferences/com.apple.loginitems.plist",
+		}},
+		{"MacOSLoginItems", "10.15.7", []string{}},
+		{"MacOSSharedFileListsSFL2", "10.12", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+tt.osVersion, func(t *testing.T) {
+			got, err := r.Globs(tt.name, tt.osVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Globs(%s, %q) = %v, want %v", tt.name, tt.osVersion, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecursiveGlobs(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+		ok   bool
+	}{
+		{"Library/Logs", []string{"Library/Logs"}, true},
+		{"Library/**/*.plist", []string{"Library/*.plist", "Library/*/*.plist", "Library/*/*/*.plist", "Library/*/*/*/*.plist"}, true},
+		{"Library/**2", []string{"Library/*", "Library/*/*"}, true},
+		{"a/**1/b/**1", []string{"a/b/*", "a/*/b/*"}, true},
+		{"Library/Logs**", []string{}, false},
+		{"Library/**x/*.plist", []string{}, false},
+		{"Library/**0", []string{}, false},
+		{"Library/**11", []string{}, false},
+	}
+	for _, tt := range tests {
+		got, err := expandRecursion(tt.path)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandRecursion(%s) = %v, %v, want %v", tt.path, got, err, tt.want)
+		}
+	}
+
+	r, err := NewRegistry("mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.LoadYAML([]byte(`
+name: Recursive
+sources:
+- type: FILE
+  attributes:
+    paths: ['/var/log/**1/*.log', '/var/log/bad**']
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the malformed path is left out instead of collecting something else
+	got, _ := r.Globs("Recursive", "")
+	if want := []string{"var/log/*.log", "var/log/*/*.log"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Globs(Recursive) = %v, want %v", got, want)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	r, err := NewRegistry("mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.LoadYAML([]byte(`
+name: MacOSUtmpx
+sources:
+- type: FILE
+  attributes:
+    paths: ['/var/run/utmpx']
+---
+name: Group
+sources:
+- type: ARTIFACT_GROUP
+  attributes:
+    names: [MacOSUtmpx, Custom]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddPaths("Custom", []string{"/opt/*.log"})
+	got, err := r.Globs("Group", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"var/run/utmpx", "opt/*.log"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Globs(Group) = %v, want %v", got, want)
+	}
+
+	r.Override("Custom", []string{"/tmp/x"})
+	if got, _ := r.Globs("Custom", ""); !reflect.DeepEqual(got, []string{"tmp/x"}) {
+		t.Errorf("Globs(Custom) after Override = %v", got)
+	}
+
+	r.LoadYAML([]byte("name: Loop\nsources:\n- type: ARTIFACT_GROUP\n  attributes:\n    names: [Loop]\n"))
+	if _, err := r.Globs("Loop", ""); err == nil {
+		t.Error("Globs expected an error for a self referencing group")
+	}
+	if _, err := r.Globs("Missing", ""); err == nil {
+		t.Error("Globs expected an error for an unknown definition")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"10.15.7", "10.15", 0},
+		{"10.9", "10.13", -1},
+		{"11.0.1", "10.15", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,545 @@
+package artifacts
+
+// builtinDefinitions holds the artifact definitions compiled into Orion for each mode
+// Paths can be overridden or extended with the ArtifactFiles, Artifacts and ArtifactsAppend config keys
+var builtinDefinitions = map[string]string{
+	"mac":     macDefinitions,
+	"windows": windowsDefinitions,
+}
+
+const macDefinitions = `
+name: MacOSAppleSystemLogs
+doc: Apple System Log (ASL) files.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/log/asl/*.asl']
+supported_os: [Darwin]
+---
+name: MacOSAuditLogs
+doc: OpenBSM audit trail files.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/audit/*']
+supported_os: [Darwin]
+---
+name: MacOSBashHistory
+doc: Shell history files (bash, zsh, fish) and Terminal bash and zsh sessions.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '%%users.homedir%%/.*_history'
+    - '%%users.homedir%%/.bash_sessions/*'
+    - '%%users.homedir%%/.zsh_sessions/*'
+    - '%%users.homedir%%/.local/share/fish/fish_history'
+supported_os: [Darwin]
+---
+name: MacOSBluetoothPreferences
+doc: Bluetooth devices known to the system and the paired devices.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/Library/Preferences/com.apple.Bluetooth.plist']
+supported_os: [Darwin]
+---
+name: MacOSChromeDataDirectory
+doc: Google Chrome user data directory.
+sources:
+- type: DIRECTORY
+  attributes:
+    paths: ['%%users.homedir%%/Library/Application Support/Google/Chrome']
+supported_os: [Darwin]
+---
+name: MacOSCronTabs
+doc: Per user cron tables.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/at/tabs/*']
+supported_os: [Darwin]
+---
+name: MacOSDeletedUsers
+doc: Deleted user accounts.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/Library/Preferences/com.apple.preferences.accounts.plist']
+supported_os: [Darwin]
+---
+name: MacOSDHCPLeases
+doc: DHCP leases of the network interfaces, named after the interface.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/db/dhcpclient/leases/*']
+supported_os: [Darwin]
+---
+name: MacOSDisabledLaunchItems
+doc: launchd overrides for disabled and sandboxed login items.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/db/com.apple.xpc.launchd/disabled.*.plist']
+supported_os: [Darwin]
+---
+name: MacOSDock
+doc: Dock preferences, the applications and folders kept in the Dock and the recent applications.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/com.apple.dock.plist']
+supported_os: [Darwin]
+---
+name: MacOSDropboxSyncHistory
+doc: Upload, download and edit events of the Dropbox client, next to info.json with the Dropbox folder of each account.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/.dropbox/instance*/sync_history.db']
+supported_os: [Darwin]
+---
+name: MacOSDSStore
+doc: Finder .DS_Store files of the home directory, its folders three levels deep and the Trash.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '%%users.homedir%%/.DS_Store'
+    - '%%users.homedir%%/*/.DS_Store'
+    - '%%users.homedir%%/*/*/.DS_Store'
+    - '%%users.homedir%%/*/*/*/.DS_Store'
+supported_os: [Darwin]
+---
+name: MacOSFinderPreferences
+doc: Finder preferences, including the recent folders list.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/com.apple.finder.plist']
+supported_os: [Darwin]
+---
+name: MacOSFirefoxProfiles
+doc: Mozilla Firefox profile directories.
+sources:
+- type: DIRECTORY
+  attributes:
+    paths: ['%%users.homedir%%/Library/Application Support/Firefox/Profiles/*.*']
+supported_os: [Darwin]
+---
+name: MacOSGatekeeperLastReject
+doc: Last file rejected by Gatekeeper.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/db/.LastGKReject']
+supported_os: [Darwin]
+---
+name: MacOSGoogleDriveFS
+doc: Metadata database of the files of each account synced by Google Drive for desktop (DriveFS).
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Application Support/Google/DriveFS/*/metadata_sqlite_db']
+supported_os: [Darwin]
+---
+name: MacOSHostsFile
+doc: Static host name lookups.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/etc/hosts']
+supported_os: [Darwin]
+---
+name: MacOSiCloudDriveClientDB
+doc: CloudDocs database of the files synced to iCloud Drive.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Application Support/CloudDocs/session/db/client.db']
+supported_os: [Darwin]
+---
+name: MacOSiDeviceBackups
+doc: iPhone and iPad backups made by iTunes or Finder.
+sources:
+- type: DIRECTORY
+  attributes:
+    paths: ['%%users.homedir%%/Library/Application Support/MobileSync/Backup/*']
+supported_os: [Darwin]
+---
+name: MacOSInternetAccounts
+doc: Accounts added in Internet Accounts or by applications.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '%%users.homedir%%/Library/Accounts/Accounts3.sqlite'
+    - '%%users.homedir%%/Library/Accounts/Accounts4.sqlite'
+supported_os: [Darwin]
+---
+name: MacOSiPodPreferences
+doc: iPods, iPhones and iPads connected to the Mac.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/com.apple.iPod.plist']
+supported_os: [Darwin]
+---
+name: MacOSKernelExtensions
+doc: Kernel extension Info.plist files.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/System/Library/Extensions/*/Contents/Info.plist'
+    - '/Library/Extensions/*/Contents/Info.plist'
+supported_os: [Darwin]
+---
+name: MacOSLaunchAgents
+doc: launchd agent property lists.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/System/Library/LaunchAgents/*'
+    - '/Library/LaunchAgents/*'
+    - '%%users.homedir%%/Library/LaunchAgents/*'
+supported_os: [Darwin]
+urls: ['https://developer.apple.com/library/archive/documentation/MacOSX/Conceptual/BPSystemStartup/Chapters/CreatingLaunchdJobs.html']
+---
+name: MacOSLaunchDaemons
+doc: launchd daemon property lists.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/System/Library/LaunchDaemons/*'
+    - '/Library/LaunchDaemons/*'
+supported_os: [Darwin]
+urls: ['https://developer.apple.com/library/archive/documentation/MacOSX/Conceptual/BPSystemStartup/Chapters/CreatingLaunchdJobs.html']
+---
+name: MacOSLocalUsers
+doc: Local directory service user records.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/db/dslocal/nodes/Default/users/*']
+supported_os: [Darwin]
+---
+name: MacOSLockdownPairRecords
+doc: Pair records of the iOS devices that trusted the Mac, named after the UDID of the device.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/db/lockdown/*.plist']
+supported_os: [Darwin]
+---
+name: MacOSLoginItems
+doc: Per user login items, replaced by backgrounditems.btm in 10.13.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/com.apple.loginitems.plist']
+supported_os: [Darwin]
+max_os_version: '10.12'
+---
+name: MacOSBackgroundItems
+doc: Per user login items managed by the background task management agent.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Application Support/com.apple.backgroundtaskmanagementagent/backgrounditems.btm']
+supported_os: [Darwin]
+min_os_version: '10.13'
+---
+name: MacOSLoginRestartApps
+doc: Applications reopened at login.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/ByHost/com.apple.loginwindow.*.plist']
+supported_os: [Darwin]
+---
+name: MacOSMessagesChatDB
+doc: Messages database of iMessage and SMS conversations.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Messages/chat.db']
+supported_os: [Darwin]
+---
+name: MacOSMicrosoftRegistrationDB
+doc: Registry of Microsoft Office 2016 and later.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Group Containers/UBF8T346G9.Office/MicrosoftRegistrationDB*.reg']
+supported_os: [Darwin]
+---
+name: MacOSMobileBluetoothDevices
+doc: Paired Bluetooth devices of 12 and later.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/Library/Bluetooth/Library/Preferences/com.apple.MobileBluetooth.devices.plist']
+supported_os: [Darwin]
+min_os_version: '12'
+---
+name: MacOSMSOfficeSecureBookmarks
+doc: Security scoped bookmarks of the documents recently opened in Microsoft Office apps.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Containers/com.microsoft.*/Data/Library/Preferences/com.microsoft.*.securebookmarks.plist']
+supported_os: [Darwin]
+---
+name: MacOSNetUsage
+doc: Network usage of each process recorded by networkd.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/private/var/networkd/netusage.sqlite'
+    - '/private/var/networkd/db/netusage.sqlite'
+supported_os: [Darwin]
+---
+name: MacOSNotes
+doc: Notes database of notes stored on the Mac and in iCloud.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Group Containers/group.com.apple.notes/NoteStore.sqlite']
+supported_os: [Darwin]
+min_os_version: '10.11'
+---
+name: MacOSNotificationCenterDB
+doc: Notification Center database, in the per user temporary folder before 15 and in the home directory after.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/private/var/folders/*/*/0/com.apple.notificationcenter/db2/db'
+    - '%%users.homedir%%/Library/Group Containers/group.com.apple.usernoted/db2/db'
+supported_os: [Darwin]
+min_os_version: '10.13'
+---
+name: MacOSOneDriveSyncEngineDB
+doc: Sync engine database of the files of each OneDrive account (Personal, Business1, ...).
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '%%users.homedir%%/Library/Containers/com.microsoft.OneDrive-mac/Data/Library/Application Support/OneDrive/settings/*/SyncEngineDatabase.db'
+    - '%%users.homedir%%/Library/Application Support/OneDrive/settings/*/SyncEngineDatabase.db'
+supported_os: [Darwin]
+---
+name: MacOSPeriodicScripts
+doc: Scripts run by periodic.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/private/etc/periodic/daily/*'
+    - '/private/etc/periodic/weekly/*'
+    - '/private/etc/periodic/monthly/*'
+supported_os: [Darwin]
+---
+name: MacOSPrivateVarDirectories
+doc: Directories under /private/var, home directories of service accounts such as root.
+sources:
+- type: DIRECTORY
+  attributes:
+    paths: ['/private/var/*']
+supported_os: [Darwin]
+---
+name: MacOSQuarantineEvents
+doc: LaunchServices quarantine events database.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/com.apple.LaunchServices.QuarantineEventsV2']
+supported_os: [Darwin]
+---
+name: MacOSQuickLookThumbnailCache
+doc: Quick Look thumbnail cache index of the per user temporary folders.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/folders/*/*/C/com.apple.QuickLook.thumbnailcache/index.sqlite']
+supported_os: [Darwin]
+---
+name: MacOSRecentItems
+doc: Apple menu recent applications, documents, servers and hosts, replaced by shared file lists in 10.11.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/com.apple.recentitems.plist']
+supported_os: [Darwin]
+---
+name: MacOSResolverConfigs
+doc: DNS resolver configuration, resolv.conf is only present while the system runs.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/private/var/run/resolv.conf'
+    - '/private/etc/resolver/*'
+supported_os: [Darwin]
+---
+name: MacOSScriptingAdditions
+doc: AppleScript scripting additions.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/System/Library/ScriptingAdditions/*.osax'
+    - '/Library/ScriptingAdditions/*.osax'
+supported_os: [Darwin]
+---
+name: MacOSSecureBookmarks
+doc: Sandboxed application security scoped bookmarks.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Containers/*/Data/Library/Preferences/*.securebookmarks.plist']
+supported_os: [Darwin]
+---
+name: MacOSSharedFileListsSFL
+doc: Shared file lists (recent items) for 10.11 and 10.12.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '%%users.homedir%%/Library/Application Support/com.apple.sharedfilelist/*.sfl'
+    - '%%users.homedir%%/Library/Application Support/com.apple.sharedfilelist/*/*.sfl'
+supported_os: [Darwin]
+max_os_version: '10.12'
+---
+name: MacOSSharedFileListsSFL2
+doc: Shared file lists (recent items) for 10.13 and later.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '%%users.homedir%%/Library/Application Support/com.apple.sharedfilelist/*.sfl2'
+    - '%%users.homedir%%/Library/Application Support/com.apple.sharedfilelist/*/*.sfl2'
+supported_os: [Darwin]
+min_os_version: '10.13'
+---
+name: MacOSSidebarLists
+doc: Finder sidebar lists, replaced by shared file lists in 10.11.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Preferences/com.apple.sidebarlists.plist']
+supported_os: [Darwin]
+---
+name: MacOSSpotlightShortcuts
+doc: Spotlight shortcuts chosen by the user.
+sources:
+- type: FILE
+  attributes:
+    paths: ['%%users.homedir%%/Library/Application Support/com.apple.spotlight/com.apple.spotlight.Shortcuts']
+supported_os: [Darwin]
+---
+name: MacOSSSHUserFiles
+doc: SSH user files, known_hosts, authorized_keys, config and key files.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '%%users.homedir%%/.ssh/*'
+supported_os: [Darwin]
+---
+name: MacOSSSHConfigs
+doc: SSH client and server configuration.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/private/etc/ssh/ssh_config'
+    - '/private/etc/ssh/ssh_config.d/*'
+    - '/private/etc/ssh/sshd_config'
+    - '/private/etc/ssh/sshd_config.d/*'
+supported_os: [Darwin]
+---
+name: MacOSStartupItems
+doc: Legacy StartupItems.
+sources:
+- type: FILE
+  attributes:
+    paths:
+    - '/System/Library/StartupItems/*/*'
+    - '/Library/StartupItems/*/*'
+supported_os: [Darwin]
+---
+name: MacOSSystemLogs
+doc: system.log and its rotations.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/log/system.log*']
+supported_os: [Darwin]
+---
+name: MacOSSavedApplicationState
+doc: Saved application state (window titles and restoration data) of every application.
+sources:
+- type: DIRECTORY
+  attributes:
+    paths: ['%%users.homedir%%/Library/Saved Application State/*.savedState']
+supported_os: [Darwin]
+---
+name: MacOSTerminalSavedState
+doc: Terminal saved application state.
+sources:
+- type: DIRECTORY
+  attributes:
+    paths: ['%%users.homedir%%/Library/Saved Application State/com.apple.Terminal.savedState']
+supported_os: [Darwin]
+---
+name: MacOSUserDirectories
+doc: User home directories under /Users.
+sources:
+- type: DIRECTORY
+  attributes:
+    paths: ['/Users/*']
+supported_os: [Darwin]
+---
+name: MacOSUtmpx
+doc: utmpx login records.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/private/var/run/utmpx']
+supported_os: [Darwin]
+---
+name: MacOSWiFiKnownNetworks
+doc: Known Wi-Fi networks of 11 and later, replacing KnownNetworks of com.apple.airport.preferences.plist.
+sources:
+- type: FILE
+  attributes:
+    paths: ['/Library/Preferences/com.apple.wifi.known-networks.plist']
+supported_os: [Darwin]
+min_os_version: '11'
+---
+name: MacOSAutoruns
+doc: Every persistence location collected by MacAutorunsModule.
+sources:
+- type: ARTIFACT_GROUP
+  attributes:
+    names:
+    - MacOSCronTabs
+    - MacOSKernelExtensions
+    - MacOSLaunchAgents
+    - MacOSLaunchDaemons
+    - MacOSLoginItems
+    - MacOSLoginRestartApps
+    - MacOSDisabledLaunchItems
+    - MacOSScriptingAdditions
+    - MacOSStartupItems
+    - MacOSPeriodicScripts
+supported_os: [Darwin]
+`
+
+const windowsDefinitions = ``
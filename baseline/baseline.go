@@ -0,0 +1,405 @@
+// Package baseline reads the output of a previous Orion run so a new run can collect incrementally:
+// skip re-hashing unchanged files, keep only history rows newer than the previous run and report
+// what changed since
+package baseline
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthonybm/Orion/timeline"
+)
+
+// outputPattern matches module output file names, <runtime>_<output>.csv
+var outputPattern = regexp.MustCompile(`^(Orion_\d{4}-\d{2}-\d{2}T\d{2}_\d{2}_\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}_\d{2}))_(.+)\.csv$`)
+
+// ManifestSuffix ends the name of the manifest file written next to the module output, <runtime>_manifest.json
+const ManifestSuffix = "_manifest.json"
+
+// logSuffix ends the name of the log file of a run, <runtime>.json
+const logSuffix = ".json"
+
+// DiffOutput is the output listing the rows a run added and removed since its baseline
+const DiffOutput = "BaselineDiff"
+
+// Derived returns true for the files Orion writes from the module outputs of a run, the super-timeline
+// (Timeline, Timeline.l2t) and the baseline diff, they are not module outputs and are never used as a baseline
+// or compared, the HTML report is not a csv file and is never indexed
+func Derived(output string) bool {
+	return output == DiffOutput || output == timeline.Output || strings.HasPrefix(output, timeline.Output+".")
+}
+
+// Baseline is the output of a previous run, read from its output directory, its zip archive or its manifest
+type Baseline struct {
+	path     string
+	runtime  string
+	manifest *Manifest
+	files    map[string]string // output name to file path, or zip entry name
+	derived  map[string]string // derived file name to file path, or zip entry name
+	log      string            // log file path, or zip entry name
+	archive  *zip.ReadCloser
+}
+
+// Open reads the previous run at fp, an output directory, a zip archive of one or a manifest file
+// When the directory or archive holds several runs the latest is used
+func Open(fp string) (*Baseline, error) {
+	info, err := os.Stat(fp)
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{path: fp, files: make(map[string]string), derived: make(map[string]string)}
+
+	switch {
+	case info.IsDir():
+		entries, err := ioutil.ReadDir(fp)
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		b.index(names, func(name string) string { return filepath.Join(fp, name) })
+	case strings.HasSuffix(strings.ToLower(fp), ".zip"):
+		b.archive, err = zip.OpenReader(fp)
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		entries := make(map[string]string)
+		for _, f := range b.archive.File {
+			// Windows packages name their entries with backslashes
+			name := path.Base(strings.Replace(f.Name, "\\", "/", -1))
+			names = append(names, name)
+			entries[name] = f.Name
+		}
+		b.index(names, func(name string) string { return entries[name] })
+	case strings.HasSuffix(fp, ManifestSuffix):
+		entries, err := ioutil.ReadDir(filepath.Dir(fp))
+		if err != nil {
+			return nil, err
+		}
+		b.runtime = strings.TrimSuffix(filepath.Base(fp), ManifestSuffix)
+		names := []string{filepath.Base(fp)}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), b.runtime+"_") {
+				names = append(names, entry.Name())
+			}
+		}
+		b.index(names, func(name string) string { return filepath.Join(filepath.Dir(fp), name) })
+	default:
+		return nil, errors.New("baseline '" + fp + "' is not an output directory, a zip archive or a " + ManifestSuffix + " file")
+	}
+
+	if b.runtime == "" {
+		b.Close()
+		return nil, errors.New("no Orion output found in baseline '" + fp + "'")
+	}
+	if manifest, ok := b.files[ManifestSuffix]; ok {
+		r, err := b.open(manifest)
+		if err == nil {
+			m := &Manifest{}
+			err = json.NewDecoder(r).Decode(m)
+			r.Close()
+			if err == nil {
+				b.manifest = m
+			}
+		}
+		if err != nil {
+			b.Close()
+			return nil, errors.New("failed to read baseline manifest: " + err.Error())
+		}
+		delete(b.files, ManifestSuffix)
+	}
+	return b, nil
+}
+
+// index picks the latest runtime among names and records its output files apart from the derived ones, location
+// returns where a name is read from
+func (b *Baseline) index(names []string, location func(string) string) {
+	runtimes := []string{}
+	for _, name := range names {
+		if m := outputPattern.FindStringSubmatch(name); m != nil {
+			runtimes = append(runtimes, m[1])
+		} else if strings.HasSuffix(name, ManifestSuffix) {
+			runtimes = append(runtimes, strings.TrimSuffix(name, ManifestSuffix))
+		}
+	}
+	if b.runtime == "" && len(runtimes) > 0 {
+		sort.Strings(runtimes)
+		b.runtime = runtimes[len(runtimes)-1]
+	}
+	for _, name := range names {
+		if name == b.runtime+ManifestSuffix {
+			b.files[ManifestSuffix] = location(name)
+		} else if name == b.runtime+logSuffix {
+			b.log = location(name)
+		} else if m := outputPattern.FindStringSubmatch(name); m != nil && m[1] == b.runtime {
+			if Derived(m[2]) {
+				b.derived[m[2]] = location(name)
+			} else {
+				b.files[m[2]] = location(name)
+			}
+		}
+	}
+}
+
+// OutputName returns the runtime and output name of an output file name, false for other files
+func OutputName(name string) (runtime string, output string, ok bool) {
+	m := outputPattern.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// Close releases the archive of the baseline
+func (b *Baseline) Close() error {
+	if b.archive != nil {
+		return b.archive.Close()
+	}
+	return nil
+}
+
+// Path returns the location the baseline was read from
+func (b *Baseline) Path() string {
+	return b.path
+}
+
+// Runtime returns the runtime of the previous run (ex. Orion_2020-08-01T10_00_00Z)
+func (b *Baseline) Runtime() string {
+	return b.runtime
+}
+
+// Manifest returns the manifest of the previous run, nil for runs without one
+func (b *Baseline) Manifest() *Manifest {
+	return b.manifest
+}
+
+// TimestampFormat returns the format the previous run wrote timestamps in, empty when it is unknown because the
+// run has no manifest
+func (b *Baseline) TimestampFormat() string {
+	if b.manifest == nil {
+		return ""
+	}
+	return b.manifest.TimestampFormat
+}
+
+// Outputs returns the names of the output files of the previous run, sorted
+func (b *Baseline) Outputs() []string {
+	names := []string{}
+	for name := range b.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has returns true if the previous run wrote output
+func (b *Baseline) Has(output string) bool {
+	_, ok := b.files[output]
+	return ok
+}
+
+// HasDerived returns true if the previous run wrote the derived file output (ex. Timeline), see Derived
+func (b *Baseline) HasDerived(output string) bool {
+	_, ok := b.derived[output]
+	return ok
+}
+
+func (b *Baseline) open(location string) (io.ReadCloser, error) {
+	if b.archive == nil {
+		return os.Open(location)
+	}
+	for _, f := range b.archive.File {
+		if f.Name == location {
+			return f.Open()
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// Log returns the JSON log of the previous run, os.ErrNotExist when the run has none
+func (b *Baseline) Log() (io.ReadCloser, error) {
+	if b.log == "" {
+		return nil, os.ErrNotExist
+	}
+	return b.open(b.log)
+}
+
+// Each calls fn with the header and every row of output or derived file, in file order
+func (b *Baseline) Each(output string, fn func(header []string, row []string)) error {
+	location, ok := b.files[output]
+	if !ok {
+		location, ok = b.derived[output]
+	}
+	if !ok {
+		return os.ErrNotExist
+	}
+	f, err := b.open(location)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return eachRow(f, fn)
+}
+
+// eachRow calls fn with the header and every row of the CSV read from in
+func eachRow(in io.Reader, fn func(header []string, row []string)) error {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		fn(header, row)
+	}
+}
+
+// ReadFile returns the header and rows of the output file fp
+func ReadFile(fp string) ([]string, [][]string, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	var header []string
+	rows := [][]string{}
+	err = eachRow(f, func(h []string, row []string) {
+		header = h
+		rows = append(rows, row)
+	})
+	return header, rows, err
+}
+
+// Rows returns the header and rows of output
+func (b *Baseline) Rows(output string) ([]string, [][]string, error) {
+	var header []string
+	rows := [][]string{}
+	err := b.Each(output, func(h []string, row []string) {
+		header = h
+		rows = append(rows, row)
+	})
+	return header, rows, err
+}
+
+// HighWater returns the latest event time among the rows of the output described by def, false when there is none
+// The value recorded in the manifest is used when present
+func (b *Baseline) HighWater(def timeline.Definition) (time.Time, bool) {
+	if b.manifest != nil {
+		if out, ok := b.manifest.Outputs[def.Output]; ok && out.HighWater != nil {
+			return *out.HighWater, true
+		}
+	}
+	if !b.Has(def.Output) {
+		return time.Time{}, false
+	}
+	var latest time.Time
+	b.Each(def.Output, func(header []string, row []string) {
+		if t, ok := RowTime(def, header, row, b.TimestampFormat()); ok && t.After(latest) {
+			latest = t
+		}
+	})
+	return latest, !latest.IsZero()
+}
+
+// RowTime returns the latest event time of a row of the output described by def, false when no event time parses
+// format is the timestamp format of the run the row was read from
+func RowTime(def timeline.Definition, header []string, row []string, format string) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	for _, ev := range def.Events {
+		for i, name := range header {
+			if name != ev.Field || i >= len(row) || row[i] == "" {
+				continue
+			}
+			t, err := timeline.ParseTime(row[i], format)
+			if err != nil {
+				continue
+			}
+			if !found || t.After(latest) {
+				latest = t
+				found = true
+			}
+		}
+	}
+	return latest, found
+}
+
+// File is what a previous dirlist recorded about a file
+type File struct {
+	Size   string
+	Mtime  time.Time
+	Inode  string
+	SHA256 string
+	MD5    string
+}
+
+// Files returns the files listed in a dirlist output by path, inodeField names the column identifying the file
+// on disk (inode on macOS, file_id on Windows)
+func (b *Baseline) Files(output string, inodeField string) (map[string]File, error) {
+	files := make(map[string]File)
+	index := map[string]int{}
+	format := b.TimestampFormat()
+	err := b.Each(output, func(header []string, row []string) {
+		if len(index) == 0 {
+			for i, name := range header {
+				index[name] = i
+			}
+		}
+		get := func(field string) string {
+			i, ok := index[field]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return row[i]
+		}
+		mtime, err := timeline.ParseTime(get("mtime"), format)
+		if err != nil || get("path") == "" {
+			return
+		}
+		files[get("path")] = File{
+			Size:   get("size"),
+			Mtime:  mtime,
+			Inode:  get(inodeField),
+			SHA256: get("sha256"),
+			MD5:    get("md5"),
+		}
+	})
+	return files, err
+}
+
+// Unchanged returns true when the file has the size, modification time and inode recorded by the baseline
+// An unknown inode never matches
+func (f File) Unchanged(size string, mtime time.Time, inode string) bool {
+	return Hash(inode) != "" && f.Inode == inode && f.Size == size && f.Mtime.Equal(mtime)
+}
+
+// Hash returns a hash or inode recorded by the baseline, empty when it was not computed
+func Hash(value string) string {
+	switch value {
+	case "", "N/E", "ERROR", "NO VALUE":
+		return ""
+	}
+	return value
+}
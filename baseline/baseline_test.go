@@ -0,0 +1,277 @@
+package baseline
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anthonybm/Orion/timeline"
+)
+
+const (
+	oldRuntime = "Orion_2020-08-01T10_00_00Z"
+	runtime    = "Orion_2020-08-02T10_00_00Z"
+)
+
+var historyDef = timeline.Definition{
+	Output:      "MacChromeModule-history",
+	Events:      []timeline.Event{{Field: "visit_time", Type: "URL Visited"}},
+	Incremental: true,
+}
+
+// writeRun writes the output files of a run to dir
+func writeRun(t *testing.T, dir string, runtime string) {
+	files := map[string]string{
+		"MacChromeModule-history": "user,visit_time,url\n" +
+			"bob,2020-07-30T08:00:00Z,https://a.example\n" +
+			"bob,2020-07-31T21:15:00Z,https://b.example\n" +
+			"bob,,https://c.example\n",
+		"MacDirlistModule": "mode,size,inode,mtime,path,sha256,md5\n" +
+			"Regular File,10,42,2020-07-01T00:00:00Z,/etc/hosts,aaaa,bbbb\n" +
+			"Regular File,99999999,43,2020-07-01T00:00:00Z,/big,N/E,N/E\n",
+		"MacAutorunsModule": "label,program\n" +
+			"com.example.agent,/usr/local/bin/agent\n",
+	}
+	for output, content := range files {
+		fp := filepath.Join(dir, runtime+"_"+output+".csv")
+		if err := ioutil.WriteFile(fp, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "baseline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestOpenDir(t *testing.T) {
+	dir := tempDir(t)
+	writeRun(t, dir, oldRuntime)
+	writeRun(t, dir, runtime)
+	ioutil.WriteFile(filepath.Join(dir, runtime+".log"), []byte("log"), 0600)
+
+	b, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if b.Runtime() != runtime {
+		t.Errorf("Runtime() = %s, want the latest run %s", b.Runtime(), runtime)
+	}
+	want := []string{"MacAutorunsModule", "MacChromeModule-history", "MacDirlistModule"}
+	if !reflect.DeepEqual(b.Outputs(), want) {
+		t.Errorf("Outputs() = %v, want %v", b.Outputs(), want)
+	}
+	header, rows, err := b.Rows("MacAutorunsModule")
+	if err != nil || len(header) != 2 || len(rows) != 1 {
+		t.Errorf("Rows() = %v %v %v", header, rows, err)
+	}
+
+	if _, err := Open(tempDir(t)); err == nil {
+		t.Error("Open() of an empty directory expected an error")
+	}
+}
+
+func TestOpenDerived(t *testing.T) {
+	dir := tempDir(t)
+	writeRun(t, dir, runtime)
+	for output, content := range map[string]string{
+		"Timeline":     "timestamp,module,output,event_type,user,description\n2020-07-31T21:15:00Z,MacChromeModule,MacChromeModule-history,URL Visited,bob,\n",
+		"Timeline.l2t": "date,time,timezone,MACB\n07/31/2020,21:15:00,UTC,....\n",
+		DiffOutput:     "module,output,change,row\nMacAutorunsModule,MacAutorunsModule,added,label=x\n",
+	} {
+		ioutil.WriteFile(filepath.Join(dir, runtime+"_"+output+".csv"), []byte(content), 0600)
+	}
+
+	b, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	// derived files are not outputs, a run collected against this one must not use them
+	want := []string{"MacAutorunsModule", "MacChromeModule-history", "MacDirlistModule"}
+	if !reflect.DeepEqual(b.Outputs(), want) {
+		t.Errorf("Outputs() = %v, want %v", b.Outputs(), want)
+	}
+	for _, output := range []string{"Timeline", "Timeline.l2t", DiffOutput} {
+		if b.Has(output) || !b.HasDerived(output) {
+			t.Errorf("Has(%s) = %v, HasDerived(%s) = %v", output, b.Has(output), output, b.HasDerived(output))
+		}
+	}
+	if _, ok := b.HighWater(timeline.Definition{Output: "Timeline", Events: []timeline.Event{{Field: "timestamp"}}}); ok {
+		t.Error("HighWater() of the super-timeline expected none")
+	}
+	header, rows, err := b.Rows("Timeline")
+	if err != nil || len(header) != 6 || len(rows) != 1 {
+		t.Errorf("Rows(Timeline) = %v %v %v", header, rows, err)
+	}
+}
+
+func TestOpenZip(t *testing.T) {
+	dir := tempDir(t)
+	out := filepath.Join(dir, "Output")
+	os.Mkdir(out, 0700)
+	writeRun(t, out, runtime)
+
+	fp := filepath.Join(dir, runtime+".zip")
+	zf, _ := os.Create(fp)
+	zw := zip.NewWriter(zf)
+	files, _ := filepath.Glob(filepath.Join(out, "*"))
+	for _, file := range files {
+		// Windows packages name their entries with backslashes
+		w, _ := zw.Create("Output\\" + filepath.Base(file))
+		data, _ := ioutil.ReadFile(file)
+		w.Write(data)
+	}
+	zw.Close()
+	zf.Close()
+
+	b, err := Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if !b.Has("MacDirlistModule") || b.Runtime() != runtime {
+		t.Fatalf("Open(zip) found %s %v", b.Runtime(), b.Outputs())
+	}
+	files2, err := b.Files("MacDirlistModule", "inode")
+	if err != nil || len(files2) != 2 {
+		t.Fatalf("Files() = %v, %v", files2, err)
+	}
+}
+
+func TestHighWater(t *testing.T) {
+	dir := tempDir(t)
+	writeRun(t, dir, runtime)
+	b, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mark, ok := b.HighWater(historyDef)
+	want := time.Date(2020, 7, 31, 21, 15, 0, 0, time.UTC)
+	if !ok || !mark.Equal(want) {
+		t.Errorf("HighWater() = %s %v, want %s", mark, ok, want)
+	}
+	if _, ok := b.HighWater(timeline.Definition{Output: "MacUtmpxModule"}); ok {
+		t.Error("HighWater() of a missing output should be false")
+	}
+
+	// The manifest high-water mark wins over the rows
+	later := want.Add(time.Hour)
+	out, err := Describe(filepath.Join(dir, runtime+"_MacChromeModule-history.csv"), &historyDef, "rfc3339nano")
+	if err != nil || out.Rows != 3 || out.SHA256 == "" || out.HighWater == nil || !out.HighWater.Equal(want) {
+		t.Fatalf("Describe() = %+v, %v", out, err)
+	}
+	out.HighWater = &later
+	m := &Manifest{Runtime: runtime, Outputs: map[string]Output{historyDef.Output: out}}
+	manifest, err := m.Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = Open(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Manifest() == nil || !b.Has("MacAutorunsModule") {
+		t.Fatalf("Open(manifest) = %+v", b)
+	}
+	if mark, _ := b.HighWater(historyDef); !mark.Equal(later) {
+		t.Errorf("HighWater() = %s, want the manifest value %s", mark, later)
+	}
+}
+
+// The rows of a run written in epoch_ms are read in milliseconds, whatever format the reading process writes
+func TestHighWaterTimestampFormat(t *testing.T) {
+	dir := tempDir(t)
+	fp := filepath.Join(dir, runtime+"_"+historyDef.Output+".csv")
+	content := "user,visit_time,url\nbob,1596143700000,https://a.example\nbob,1596230100000,https://b.example\n"
+	if err := ioutil.WriteFile(fp, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manifest{Runtime: runtime, TimestampFormat: "epoch_ms", Outputs: map[string]Output{historyDef.Output: {File: filepath.Base(fp)}}}
+	manifest, err := m.Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.TimestampFormat() != "epoch_ms" {
+		t.Errorf("TimestampFormat() = %q, want epoch_ms", b.TimestampFormat())
+	}
+	want := time.Date(2020, 7, 31, 21, 15, 0, 0, time.UTC)
+	if mark, ok := b.HighWater(historyDef); !ok || !mark.Equal(want) {
+		t.Errorf("HighWater() = %s %v, want %s", mark, ok, want)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := tempDir(t)
+	writeRun(t, dir, runtime)
+	b, _ := Open(dir)
+	files, err := b.Files("MacDirlistModule", "inode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := files["/etc/hosts"]
+	mtime := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	if !hosts.Unchanged("10", mtime, "42") || hosts.SHA256 != "aaaa" {
+		t.Errorf("/etc/hosts = %+v should be unchanged", hosts)
+	}
+	for _, c := range []struct {
+		size  string
+		mtime time.Time
+		inode string
+	}{
+		{"11", mtime, "42"},
+		{"10", mtime.Add(time.Second), "42"},
+		{"10", mtime, "7"},
+		{"10", mtime, "NO VALUE"},
+	} {
+		if hosts.Unchanged(c.size, c.mtime, c.inode) {
+			t.Errorf("Unchanged(%s, %s, %s) = true", c.size, c.mtime, c.inode)
+		}
+	}
+	if Hash(files["/big"].SHA256) != "" {
+		t.Error("Hash(N/E) should be empty")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	previousHeader := []string{"label", "program"}
+	previous := [][]string{{"a", "/a"}, {"b", "/b"}, {"b", "/b"}}
+	// columns may move and be added between versions, only shared columns are compared
+	currentHeader := []string{"program", "label", "signed"}
+	current := [][]string{{"/b", "b", "yes"}, {"/c", "c", "no"}, {"/a", "a", "yes"}}
+
+	added, removed := Diff(previousHeader, previous, currentHeader, current)
+	if !reflect.DeepEqual(added, [][]string{{"/c", "c", "no"}}) {
+		t.Errorf("added = %v", added)
+	}
+	if !reflect.DeepEqual(removed, [][]string{{"b", "/b"}}) {
+		t.Errorf("removed = %v", removed)
+	}
+	if got := Format(currentHeader, []string{"/c", "", "no", "extra"}); got != "program: /c; signed: no; column_4: extra" {
+		t.Errorf("Format() = %s", got)
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	rt, output, ok := OutputName("/out/Orion_2020-08-02T10_00_00Z_MacChromeModule-history.csv")
+	if !ok || rt != runtime || output != "MacChromeModule-history" {
+		t.Errorf("OutputName() = %s %s %v", rt, output, ok)
+	}
+	if _, _, ok := OutputName(runtime + "_manifest.json"); ok {
+		t.Error("OutputName(manifest) should be false")
+	}
+}
@@ -0,0 +1,79 @@
+package baseline
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diff returns the rows of current missing from previous (added) and the rows of previous missing from
+// current (removed), matching whole rows by the columns both headers share
+// Duplicate rows are counted, so a row present twice before and once now is removed once
+func Diff(previousHeader []string, previous [][]string, currentHeader []string, current [][]string) (added [][]string, removed [][]string) {
+	shared := []string{}
+	inPrevious := map[string]bool{}
+	for _, name := range previousHeader {
+		inPrevious[name] = true
+	}
+	for _, name := range currentHeader {
+		if inPrevious[name] {
+			shared = append(shared, name)
+		}
+	}
+	sort.Strings(shared)
+
+	counts := map[string]int{}
+	for _, row := range previous {
+		counts[rowKey(previousHeader, row, shared)]++
+	}
+	for _, row := range current {
+		key := rowKey(currentHeader, row, shared)
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		added = append(added, row)
+	}
+	for i := len(previous) - 1; i >= 0; i-- {
+		key := rowKey(previousHeader, previous[i], shared)
+		if counts[key] > 0 {
+			counts[key]--
+			removed = append(removed, previous[i])
+		}
+	}
+	// removed was collected backwards so the last duplicates are the ones reported
+	for i, j := 0, len(removed)-1; i < j; i, j = i+1, j-1 {
+		removed[i], removed[j] = removed[j], removed[i]
+	}
+	return added, removed
+}
+
+// rowKey joins the values of the columns names of row
+func rowKey(header []string, row []string, names []string) string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		for j, h := range header {
+			if h == name && j < len(row) {
+				values[i] = row[j]
+				break
+			}
+		}
+	}
+	return strings.Join(values, "\x1f")
+}
+
+// Format renders a row as "column: value; ..." leaving out empty values
+func Format(header []string, row []string) string {
+	parts := []string{}
+	for i, value := range row {
+		if value == "" {
+			continue
+		}
+		name := "column_" + strconv.Itoa(i+1)
+		if i < len(header) {
+			name = header[i]
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,109 @@
+package baseline
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anthonybm/Orion/timeline"
+)
+
+// Manifest describes a run and its output files, later runs use it as their baseline
+// TimestampFormat is the format the run's modules wrote timestamps in
+type Manifest struct {
+	Runtime         string            `json:"runtime"`
+	Host            string            `json:"host"`
+	Mode            string            `json:"mode"`
+	Started         time.Time         `json:"started"`
+	Finished        time.Time         `json:"finished"`
+	Baseline        string            `json:"baseline,omitempty"`
+	TimestampFormat string            `json:"timestampFormat,omitempty"`
+	Modules         []string          `json:"modules"`
+	Outputs         map[string]Output `json:"outputs"`
+}
+
+// Output describes an output file of a run, Keys and Ignore are the key columns the module declares for orion diff
+// Since is set on incremental outputs collected with --baseline, rows with an event time in EventFields up to Since
+// were already in the baseline and left out
+type Output struct {
+	File        string     `json:"file"`
+	Rows        int        `json:"rows"`
+	SHA256      string     `json:"sha256"`
+	HighWater   *time.Time `json:"highWater,omitempty"`
+	Since       *time.Time `json:"since,omitempty"`
+	EventFields []string   `json:"eventFields,omitempty"`
+	Keys        []string   `json:"keys,omitempty"`
+	Ignore      []string   `json:"ignore,omitempty"`
+}
+
+// Skipped returns true when row was left out of an incremental output because the baseline already had it, rows
+// without an event time are always written; format is the timestamp format of the run the row was read from
+func (o Output) Skipped(header []string, row []string, format string) bool {
+	if o.Since == nil {
+		return false
+	}
+	def := timeline.Definition{Events: []timeline.Event{}}
+	for _, field := range o.EventFields {
+		def.Events = append(def.Events, timeline.Event{Field: field})
+	}
+	t, ok := RowTime(def, header, row, format)
+	return ok && !t.After(*o.Since)
+}
+
+// Describe reads the output file fp and returns its row count, hash and, for outputs with a timeline
+// definition, its high-water mark; def is nil for other outputs and format is the timestamp format fp was written with
+func Describe(fp string, def *timeline.Definition, format string) (Output, error) {
+	out := Output{File: filepath.Base(fp)}
+	f, err := os.Open(fp)
+	if err != nil {
+		return out, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	r := csv.NewReader(bufio.NewReader(io.TeeReader(f, h)))
+	r.FieldsPerRecord = -1
+	var header []string
+	var latest time.Time
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return out, err
+		}
+		if header == nil {
+			header = row
+			continue
+		}
+		out.Rows++
+		if def == nil {
+			continue
+		}
+		if t, ok := RowTime(*def, header, row, format); ok && t.After(latest) {
+			latest = t
+		}
+	}
+	out.SHA256 = hex.EncodeToString(h.Sum(nil))
+	if !latest.IsZero() {
+		out.HighWater = &latest
+	}
+	return out, nil
+}
+
+// Write writes the manifest to <dir>/<runtime>_manifest.json and returns the file path
+func (m *Manifest) Write(dir string) (string, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	fp := filepath.Join(dir, m.Runtime+ManifestSuffix)
+	return fp, ioutil.WriteFile(fp, data, 0644)
+}
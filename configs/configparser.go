@@ -1,11 +1,16 @@
 package configs
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"math"
+	"sort"
+	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/upload"
 	"go.uber.org/zap"
 )
 
@@ -14,37 +19,53 @@ type configInterface interface {
 	GetModules() []string
 }
 
+// Config is a parsed Orion config file
+// Settings shared by every module are top-level keys, module specific settings live in [modules.<Name>] tables
+// that are decoded into the config struct declared by the module with DecodeModuleConfig
 type Config struct {
-	configpath        string
-	macconfig         MacConfig
-	macconfigbool     bool
-	windowsconfig     WindowsConfig
-	windowsconfigbool bool
+	configpath string
+	mode       string
+	file       fileConfig
+	enabled    []string
+	sections   map[string]map[string]interface{}
 }
 
-type MacConfig struct {
-	ForensicMode              bool
-	Verbose                   bool
-	Modules                   []string // modules to execute
-	DirlistExcludedDirs       []string // Folders to exclude
-	DirlistExcludedExts       []string // Extentions to exclude
-	DirlistRootWalkDir        string
-	DirlistHashSizeLimitBytes int
-	DirlistDoHashMD5          bool
-	DirlistDoHashSHA256       bool
+// fileConfig holds the top-level keys of a config file, the comment tags document them for --print-config-schema
+type fileConfig struct {
+	ForensicMode           bool                      `toml:"forensicMode" comment:"safer artifact parsing where applicable"`
+	Verbose                bool                      `toml:"verbose" comment:"more verbose module output where supported"`
+	TimestampFormat        string                    `toml:"timestampFormat" comment:"rfc3339nano, rfc3339, epoch, epoch_ms - all timestamps are UTC"`
+	DeclarativeModuleFiles []string                  `toml:"DeclarativeModuleFiles" comment:"declarative module spec files, globs allowed"`
+	PluginDir              string                    `toml:"PluginDir" comment:"directory of external plugin executables"`
+	PluginTimeout          int                       `toml:"PluginTimeout" comment:"seconds before a plugin is killed, 0 disables the limit"`
+	ArtifactFiles          []string                  `toml:"ArtifactFiles" comment:"additional artifact definition YAML files"`
+	Artifacts              map[string][]string       `toml:"Artifacts" comment:"replace the paths of artifact definitions by name"`
+	ArtifactsAppend        map[string][]string       `toml:"ArtifactsAppend" comment:"add paths to artifact definitions by name"`
+	Profiles               map[string][]string       `toml:"profiles" comment:"named module lists run with --profile, entries are module names or tags"`
+	Upload                 []upload.Config           `toml:"upload" comment:"remote sinks the finished package is uploaded to"`
+	Stream                 []datawriter.StreamConfig `toml:"stream" comment:"log pipelines every module output row is sent to as it is written"`
+	Throttle               throttle.Settings         `toml:"throttle" comment:"resource limits for latency sensitive hosts"`
+	Modules                toml.Primitive            `toml:"modules"`
 }
 
-type WindowsConfig struct {
-	ForensicMode              bool
-	Verbose                   bool
-	Modules                   []string // modules to execute
-	DirlistExcludedDrives     []string // Windows drives to exclude
-	DirlistExcludedDirs       []string // Folders to exclude
-	DirlistExcludedExts       []string // Extentions to exclude
-	DirlistRootWalkDir        string
-	DirlistHashSizeLimitBytes int
-	DirlistDoHashMD5          bool
-	DirlistDoHashSHA256       bool
+// defaultFileConfig returns the top-level keys with their default values
+func defaultFileConfig() fileConfig {
+	return fileConfig{
+		TimestampFormat:        "rfc3339nano",
+		DeclarativeModuleFiles: []string{},
+		PluginDir:              "plugins.d",
+		PluginTimeout:          600,
+		ArtifactFiles:          []string{},
+		Artifacts:              map[string][]string{},
+		ArtifactsAppend:        map[string][]string{},
+		Profiles:               map[string][]string{},
+	}
+}
+
+// legacyDirlistModules maps a mode to the module that owns the Dirlist* keys of pre-[modules] config files
+var legacyDirlistModules = map[string]string{
+	"mac":     "MacDirlistModule",
+	"windows": "WindowsDirlistModule",
 }
 
 // configTypeError defines an error occuring with Orion not ready to parse that config type.
@@ -53,115 +74,150 @@ type configTypeError struct {
 	prob string
 }
 
+// GetModulesToExecute returns the modules listed in [modules] enabled
 func (conf Config) GetModulesToExecute() ([]string, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.Modules, nil
-	case "windows":
-		return conf.windowsconfig.Modules, nil
-	}
-	return []string{}, errors.New("cannot get modules for config of type " + conf.GetConfigType())
+	return conf.enabled, nil
 }
 
-func (conf Config) GetDirlistExcludedDrives() ([]string, error) {
-	switch conf.GetConfigType() {
-	case "windows":
-		return conf.windowsconfig.DirlistExcludedDrives, nil
+// GetModuleSections returns the names of the [modules.<Name>] tables in the config file
+func (conf Config) GetModuleSections() []string {
+	names := []string{}
+	for name := range conf.sections {
+		names = append(names, name)
 	}
-	return []string{}, errors.New("could not read dirlist excluded drives key for config of type " + conf.GetConfigType())
+	sort.Strings(names)
+	return names
 }
 
-func (conf Config) GetDirlistExcludedDirs() ([]string, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.DirlistExcludedDirs, nil
-	case "windows":
-		return conf.windowsconfig.DirlistExcludedDirs, nil
+// GetModuleSection returns the raw [modules.<name>] table, empty if it is not set
+func (conf Config) GetModuleSection(name string) map[string]interface{} {
+	if section, ok := conf.sections[name]; ok {
+		return section
 	}
-	return []string{}, errors.New("could not read dirlist excluded dirs key for config of type " + conf.GetConfigType())
+	return map[string]interface{}{}
 }
 
-func (conf Config) GetDirlistExcludedExts() ([]string, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.DirlistExcludedExts, nil
-	case "windows":
-		return conf.windowsconfig.DirlistExcludedExts, nil
+// DecodeModuleConfig decodes the [modules.<name>] table into v, a pointer to the config struct of the module
+// holding its default values. Keys of the table that v does not declare are returned as an error
+func (conf Config) DecodeModuleConfig(name string, v interface{}) error {
+	section, ok := conf.sections[name]
+	if !ok {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(section); err != nil {
+		return fmt.Errorf("[modules.%s]: %s", name, err.Error())
+	}
+	md, err := toml.Decode(buf.String(), v)
+	if err != nil {
+		return fmt.Errorf("[modules.%s]: %s", name, err.Error())
 	}
-	return []string{}, errors.New("could not read dirlist excluded exts key for config of type " + conf.GetConfigType())
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := []string{}
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return fmt.Errorf("[modules.%s]: unknown keys %s", name, strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// GetProfile returns the module names and tags of the [profiles] entry name and a bool indicating it exists
+func (conf Config) GetProfile(name string) ([]string, bool) {
+	profile, ok := conf.file.Profiles[name]
+	return profile, ok
 }
 
-func (conf Config) GetDirlistRootWalkDir() (string, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.DirlistRootWalkDir, nil
-	case "windows":
-		return conf.windowsconfig.DirlistRootWalkDir, nil
+// GetProfiles returns the names of the profiles defined in [profiles]
+func (conf Config) GetProfiles() []string {
+	names := []string{}
+	for name := range conf.file.Profiles {
+		names = append(names, name)
 	}
-	return "", errors.New("could not read dirlist root walk dir key for config of type " + conf.GetConfigType())
+	sort.Strings(names)
+	return names
+}
+
+// GetUploads returns the [[upload]] sinks the finished package is uploaded to
+func (conf Config) GetUploads() []upload.Config {
+	return conf.file.Upload
+}
+
+// GetStreams returns the [[stream]] log pipelines module output rows are sent to
+func (conf Config) GetStreams() []datawriter.StreamConfig {
+	return conf.file.Stream
+}
+
+// GetThrottle returns the resource limits for a run with profile, empty for a run without --profile
+func (conf Config) GetThrottle(profile string) throttle.Config {
+	return conf.file.Throttle.ForProfile(profile)
 }
 
-func (conf Config) GetDirlistHashSizeLimitBytes() (int, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.DirlistHashSizeLimitBytes, nil
-	case "windows":
-		return conf.windowsconfig.DirlistHashSizeLimitBytes, nil
+// GetThrottleProfiles returns the names of the [throttle.profiles.<profile>] tables, sorted
+func (conf Config) GetThrottleProfiles() []string {
+	names := []string{}
+	for name := range conf.file.Throttle.Profiles {
+		names = append(names, name)
 	}
-	return math.MaxInt64, errors.New("could not read dirlist hash size limit key for config of type " + conf.GetConfigType())
+	sort.Strings(names)
+	return names
 }
 
-func (conf Config) GetDirlistDoHashMD5() (bool, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.DirlistDoHashMD5, nil
-	case "windows":
-		return conf.windowsconfig.DirlistDoHashMD5, nil
+// GetTimestampFormat returns the output format for module timestamps, defaults to rfc3339nano when not set
+func (conf Config) GetTimestampFormat() (string, error) {
+	if conf.file.TimestampFormat == "" {
+		return "rfc3339nano", nil
 	}
-	return true, errors.New("could not read dirlist md5hash key for config of type " + conf.GetConfigType())
+	return conf.file.TimestampFormat, nil
+}
+
+// GetArtifactFiles returns the paths of additional artifact definition files to load
+func (conf Config) GetArtifactFiles() ([]string, error) {
+	return conf.file.ArtifactFiles, nil
+}
+
+// GetArtifactOverrides returns artifact definition names mapped to the paths that replace their built-in paths
+func (conf Config) GetArtifactOverrides() (map[string][]string, error) {
+	return conf.file.Artifacts, nil
 }
 
-func (conf Config) GetDirlistDohashSHA256() (bool, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.DirlistDoHashSHA256, nil
-	case "windows":
-		return conf.windowsconfig.DirlistDoHashSHA256, nil
+// GetArtifactAdditions returns artifact definition names mapped to the paths added to their built-in paths
+func (conf Config) GetArtifactAdditions() (map[string][]string, error) {
+	return conf.file.ArtifactsAppend, nil
+}
+
+// GetDeclarativeModuleFiles returns the globs of declarative module spec files to load
+func (conf Config) GetDeclarativeModuleFiles() ([]string, error) {
+	return conf.file.DeclarativeModuleFiles, nil
+}
+
+// GetPluginDir returns the directory external plugin executables are discovered in, defaults to "plugins.d"
+func (conf Config) GetPluginDir() (string, error) {
+	if conf.file.PluginDir == "" {
+		return "plugins.d", nil
 	}
-	return true, errors.New("could not read dirlist sha256hash key for config of type " + conf.GetConfigType())
+	return conf.file.PluginDir, nil
+}
+
+// GetPluginTimeout returns the number of seconds a plugin may run before it is killed, 0 disables the limit
+func (conf Config) GetPluginTimeout() (int, error) {
+	return conf.file.PluginTimeout, nil
 }
 
 func (conf Config) IsForensicMode() (bool, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.ForensicMode, nil
-	case "windows":
-		return conf.windowsconfig.ForensicMode, nil
-	}
-	return true, errors.New("could not read forensicMode key for config of type " + conf.GetConfigType())
+	return conf.file.ForensicMode, nil
 }
 
 func (conf Config) IsVerbose() (bool, error) {
-	switch conf.GetConfigType() {
-	case "mac":
-		return conf.macconfig.ForensicMode, nil
-	case "windows":
-		return conf.windowsconfig.ForensicMode, nil
-	}
-	return true, errors.New("could not read verbose key for config of type " + conf.GetConfigType())
+	return conf.file.Verbose, nil
 }
 
 func (e *configTypeError) Error() string {
-	return fmt.Sprintf("%d - %s", e.arg, e.prob)
+	return fmt.Sprintf("%s - %s", e.arg, e.prob)
 }
 
 func (conf Config) GetConfigType() string {
-	if conf.macconfigbool {
-		return "mac"
-	} else if conf.windowsconfigbool {
-		return "windows"
-	}
-	return ""
+	return conf.mode
 }
 
 // initConfig takes in the device parsing type and returns a configured Config type
@@ -169,96 +225,110 @@ func initConfig(configpath string, mode string) (Config, error) {
 	if configpath == "" {
 		return Config{}, errors.New("configparser: empty filepath for config provided")
 	}
-
-	var conf Config
 	switch mode {
-	case "mac":
-		conf.macconfigbool = true
-		conf.macconfig = MacConfig{}
-		conf.configpath = configpath
-		return conf, nil
-	case "windows":
-		conf.windowsconfigbool = true
-		conf.windowsconfig = WindowsConfig{}
-		conf.configpath = configpath
-		return conf, nil
+	case "mac", "windows":
+		return Config{
+			configpath: configpath,
+			mode:       mode,
+			file:       defaultFileConfig(),
+			enabled:    []string{},
+			sections:   make(map[string]map[string]interface{}),
+		}, nil
 	}
-	err := &configTypeError{mode, "- Orion cannot use this config type."}
+	err := &configTypeError{mode, "Orion cannot use this config type."}
 	return Config{}, err
 }
 
 // Parse takes in the relative path to a config file and the device parsing type
 func Parse(configpath string, mode string) (Config, error) {
 	conf, err := initConfig(configpath, mode)
-	if conf.configpath == "" {
-		return Config{}, errors.New("configparser: struct has empty filepath for config")
-	}
 	if err != nil {
 		zap.L().Error("configparser: Orion cannot parse this type of config: ", zap.String("error", err.Error()))
 		return conf, err
 	}
-
-	switch conf.GetConfigType() {
-	case "mac":
-		conf, err := parseMacConfig(conf)
-		if err != nil {
-			zap.L().Error("configparser: syntax error in given mac config file: ", zap.String("error", err.Error()))
-			return conf, err
-		}
-		return conf, nil
-	case "windows":
-		conf, err := parseWindowsConfig(conf)
-		if err != nil {
-			zap.L().Error("configparser: syntax error in given windows config file: ", zap.String("error", err.Error()))
-			return conf, err
-		}
-		return conf, nil
+	conf, err = parseConfig(conf)
+	if err != nil {
+		zap.L().Error("configparser: error in given "+mode+" config file: ", zap.String("error", err.Error()))
+		return conf, err
 	}
-	err = errors.New("configparser: syntax error in given config file")
-	return conf, err
+	return conf, nil
 }
 
-// parseMacConfig takes in an initialized Config type and returns it configured for mac
-func parseMacConfig(conf Config) (Config, error) {
-	var tomlConf MacConfig
-	if _, err := toml.DecodeFile(conf.configpath, &tomlConf); err != nil {
-		msg := "configparser: cannot parse mac config toml file: '" + conf.configpath + "'"
-		zap.L().Error(msg, zap.String("error", err.Error()))
+// parseConfig decodes the config file of an initialized Config
+// Config files from before [modules] tables (a modules list and Dirlist* keys) are still accepted
+func parseConfig(conf Config) (Config, error) {
+	md, err := toml.DecodeFile(conf.configpath, &conf.file)
+	if err != nil {
+		return conf, fmt.Errorf("cannot parse config toml file '%s': %s", conf.configpath, err.Error())
+	}
+	var raw map[string]interface{}
+	if _, err := toml.DecodeFile(conf.configpath, &raw); err != nil {
 		return conf, err
 	}
 
-	// parse fields from TOML file
-	conf.macconfig.ForensicMode = tomlConf.ForensicMode
-	conf.macconfig.Verbose = tomlConf.Verbose
-	conf.macconfig.Modules = tomlConf.Modules
-	conf.macconfig.DirlistExcludedDirs = tomlConf.DirlistExcludedDirs
-	conf.macconfig.DirlistExcludedExts = tomlConf.DirlistExcludedExts
-	conf.macconfig.DirlistDoHashMD5 = tomlConf.DirlistDoHashMD5
-	conf.macconfig.DirlistDoHashSHA256 = tomlConf.DirlistDoHashSHA256
-	conf.macconfig.DirlistHashSizeLimitBytes = tomlConf.DirlistHashSizeLimitBytes
+	switch modules := raw["modules"].(type) {
+	case nil:
+	case []interface{}:
+		zap.L().Warn("configparser: 'modules = [...]' is deprecated, use 'enabled = [...]' in a [modules] table")
+		enabled, err := toStrings(modules)
+		if err != nil {
+			return conf, fmt.Errorf("modules: %s", err.Error())
+		}
+		conf.enabled = enabled
+	case map[string]interface{}:
+		for name, value := range modules {
+			if name == "enabled" {
+				enabled, ok := value.([]interface{})
+				if !ok {
+					return conf, errors.New("[modules] enabled must be a list of module names")
+				}
+				if conf.enabled, err = toStrings(enabled); err != nil {
+					return conf, fmt.Errorf("[modules] enabled: %s", err.Error())
+				}
+				continue
+			}
+			section, ok := value.(map[string]interface{})
+			if !ok {
+				return conf, fmt.Errorf("[modules] %s must be a [modules.%s] table", name, name)
+			}
+			conf.sections[name] = section
+		}
+	default:
+		return conf, errors.New("modules must be a [modules] table")
+	}
 
+	unknown := []string{}
+	for _, key := range md.Undecoded() {
+		top := key[0]
+		if top == "modules" {
+			continue
+		}
+		if strings.HasPrefix(top, "Dirlist") && len(key) == 1 {
+			// Move legacy Dirlist* keys into the section of the dirlist module
+			module := legacyDirlistModules[conf.mode]
+			if conf.sections[module] == nil {
+				conf.sections[module] = make(map[string]interface{})
+			}
+			conf.sections[module][strings.TrimPrefix(top, "Dirlist")] = raw[top]
+			zap.L().Warn("configparser: '" + top + "' is deprecated, use '" + strings.TrimPrefix(top, "Dirlist") + "' in [modules." + module + "]")
+			continue
+		}
+		unknown = append(unknown, key.String())
+	}
+	if len(unknown) > 0 {
+		return conf, errors.New("unknown keys " + strings.Join(unknown, ", "))
+	}
 	return conf, nil
 }
 
-// parseWindowsConfig takes in an initialized Config type and returns it configured for Windows
-func parseWindowsConfig(conf Config) (Config, error) {
-	var tomlConf WindowsConfig
-	if _, err := toml.DecodeFile(conf.configpath, &tomlConf); err != nil {
-		msg := "configparser: cannot parse Windows config toml file: '" + conf.configpath + "'"
-		zap.L().Error(msg, zap.String("error", err.Error()))
-		return conf, err
+func toStrings(values []interface{}) ([]string, error) {
+	strs := []string{}
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return strs, fmt.Errorf("expected a string, got %v", v)
+		}
+		strs = append(strs, s)
 	}
-
-	// parse fields from TOML file
-	conf.windowsconfig.ForensicMode = tomlConf.ForensicMode
-	conf.windowsconfig.Verbose = tomlConf.Verbose
-	conf.windowsconfig.Modules = tomlConf.Modules
-	conf.windowsconfig.DirlistExcludedDirs = tomlConf.DirlistExcludedDirs
-	conf.windowsconfig.DirlistExcludedExts = tomlConf.DirlistExcludedExts
-	conf.windowsconfig.DirlistDoHashMD5 = tomlConf.DirlistDoHashMD5
-	conf.windowsconfig.DirlistDoHashSHA256 = tomlConf.DirlistDoHashSHA256
-	conf.windowsconfig.DirlistHashSizeLimitBytes = tomlConf.DirlistHashSizeLimitBytes
-	conf.windowsconfig.DirlistExcludedDrives = tomlConf.DirlistExcludedDrives
-
-	return conf, nil
+	return strs, nil
 }
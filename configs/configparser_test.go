@@ -0,0 +1,202 @@
+package configs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anthonybm/Orion/throttle"
+)
+
+type testDirlistConfig struct {
+	RootWalkDir  string
+	ExcludedDirs []string
+	DoHashMD5    bool
+}
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "configs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fp := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(fp, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+func TestParseShippedConfigs(t *testing.T) {
+	for mode, fp := range map[string]string{"mac": "mac.toml", "windows": "windows.toml"} {
+		conf, err := Parse(fp, mode)
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %s", fp, err)
+		}
+		enabled, _ := conf.GetModulesToExecute()
+		if len(enabled) == 0 {
+			t.Errorf("Parse(%s) enabled no modules", fp)
+		}
+		if len(conf.GetModuleSections()) == 0 {
+			t.Errorf("Parse(%s) has no module sections", fp)
+		}
+	}
+}
+
+func TestParseModules(t *testing.T) {
+	fp := writeConfig(t, `
+timestampFormat = "epoch"
+verbose = true
+[modules]
+enabled = ["MacDirlistModule", "MacSSHModule"]
+[modules.MacDirlistModule]
+RootWalkDir = "/Users"
+[profiles]
+browser = ["browser", "MacSSHModule"]
+`)
+	conf, err := Parse(fp, "mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := conf.GetModulesToExecute(); !reflect.DeepEqual(enabled, []string{"MacDirlistModule", "MacSSHModule"}) {
+		t.Errorf("GetModulesToExecute() = %v", enabled)
+	}
+	if format, _ := conf.GetTimestampFormat(); format != "epoch" {
+		t.Errorf("GetTimestampFormat() = %s", format)
+	}
+	if profile, ok := conf.GetProfile("browser"); !ok || !reflect.DeepEqual(profile, []string{"browser", "MacSSHModule"}) {
+		t.Errorf("GetProfile(browser) = %v, %v", profile, ok)
+	}
+	if _, ok := conf.GetProfile("quick"); ok {
+		t.Error("GetProfile(quick) found an undefined profile")
+	}
+	if verbose, _ := conf.IsVerbose(); !verbose {
+		t.Error("IsVerbose() = false")
+	}
+	if dir, _ := conf.GetPluginDir(); dir != "plugins.d" {
+		t.Errorf("GetPluginDir() = %s, want default", dir)
+	}
+
+	cfg := &testDirlistConfig{RootWalkDir: "/", ExcludedDirs: []string{".fseventsd"}, DoHashMD5: true}
+	if err := conf.DecodeModuleConfig("MacDirlistModule", cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := &testDirlistConfig{RootWalkDir: "/Users", ExcludedDirs: []string{".fseventsd"}, DoHashMD5: true}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("DecodeModuleConfig() = %+v, want %+v", cfg, want)
+	}
+	if err := conf.DecodeModuleConfig("MacSSHModule", cfg); err != nil {
+		t.Errorf("DecodeModuleConfig() without a section returned %s", err)
+	}
+}
+
+func TestParseThrottle(t *testing.T) {
+	fp := writeConfig(t, `
+[modules]
+enabled = ["MacSSHModule"]
+[profiles]
+dbhost = ["MacSSHModule"]
+[throttle]
+maxIOBytesPerSec = 20971520
+nice = 10
+[throttle.profiles.dbhost]
+maxIOBytesPerSec = 5242880
+maxProcs = 1
+ioPriority = "idle"
+[throttle.profiles.nolimit]
+maxIOBytesPerSec = 0
+`)
+	conf, err := Parse(fp, "mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := conf.GetThrottle(""), (throttle.Config{MaxIOBytesPerSec: 20971520, Nice: 10}); got != want {
+		t.Errorf("GetThrottle() = %+v, want %+v", got, want)
+	}
+	want := throttle.Config{MaxIOBytesPerSec: 5242880, MaxProcs: 1, Nice: 10, IOPriority: "idle"}
+	if got := conf.GetThrottle("dbhost"); got != want {
+		t.Errorf("GetThrottle(dbhost) = %+v, want %+v", got, want)
+	}
+	if got, want := conf.GetThrottle("nolimit"), (throttle.Config{Nice: 10}); got != want {
+		t.Errorf("GetThrottle(nolimit) = %+v, want %+v", got, want)
+	}
+	if got := conf.GetThrottleProfiles(); !reflect.DeepEqual(got, []string{"dbhost", "nolimit"}) {
+		t.Errorf("GetThrottleProfiles() = %v", got)
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	fp := writeConfig(t, `
+modules = ["MacDirlistModule"]
+DirlistRootWalkDir = "/Users"
+DirlistDoHashMD5 = false
+`)
+	conf, err := Parse(fp, "mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := conf.GetModulesToExecute(); !reflect.DeepEqual(enabled, []string{"MacDirlistModule"}) {
+		t.Errorf("GetModulesToExecute() = %v", enabled)
+	}
+	cfg := &testDirlistConfig{DoHashMD5: true}
+	if err := conf.DecodeModuleConfig("MacDirlistModule", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RootWalkDir != "/Users" || cfg.DoHashMD5 {
+		t.Errorf("legacy Dirlist keys decoded to %+v", cfg)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":     "bogus = 1\n",
+		"enabled type":    "[modules]\nenabled = \"MacSSHModule\"\n",
+		"section type":    "[modules]\nMacSSHModule = 1\n",
+		"enabled values":  "[modules]\nenabled = [1]\n",
+		"invalid toml":    "[modules\n",
+		"wrong key types": "PluginTimeout = \"long\"\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(writeConfig(t, content), "mac"); err == nil {
+				t.Error("Parse() expected an error")
+			}
+		})
+	}
+	if _, err := Parse(writeConfig(t, ""), "linux"); err == nil {
+		t.Error("Parse() expected an error for an unsupported mode")
+	}
+}
+
+func TestDecodeModuleConfigUnknownKey(t *testing.T) {
+	conf, err := Parse(writeConfig(t, "[modules.MacDirlistModule]\nRootWalkDirs = \"/\"\n"), "mac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conf.DecodeModuleConfig("MacDirlistModule", &testDirlistConfig{})
+	if err == nil || !strings.Contains(err.Error(), "RootWalkDirs") {
+		t.Errorf("DecodeModuleConfig() error = %v, want unknown key RootWalkDirs", err)
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	var buf bytes.Buffer
+	modules := map[string]interface{}{"MacDirlistModule": &testDirlistConfig{RootWalkDir: "/"}}
+	if err := WriteSchema(&buf, "mac", modules); err != nil {
+		t.Fatal(err)
+	}
+	schema := buf.String()
+	for _, want := range []string{`PluginTimeout = 600 # int`, "[modules]\nenabled = []", "[modules.MacDirlistModule]\nRootWalkDir = \"/\" # string", "[Artifacts]"} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("WriteSchema() missing %q in\n%s", want, schema)
+		}
+	}
+	// The schema is itself a valid config file
+	if _, err := Parse(writeConfig(t, schema), "mac"); err != nil {
+		t.Errorf("Parse(schema) error: %s", err)
+	}
+}
@@ -0,0 +1,124 @@
+package configs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// WriteSchema writes a commented config file holding every key with its default value
+// modules maps module names to a pointer to their config struct holding default values
+func WriteSchema(w io.Writer, mode string, modules map[string]interface{}) error {
+	fmt.Fprintf(w, "# Orion %s config schema, values are defaults\n\n", mode)
+
+	defaults := defaultFileConfig()
+	tables := []string{}
+	for _, line := range schemaLines(&defaults) {
+		if strings.HasPrefix(line, "[") {
+			tables = append(tables, line)
+			continue
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintln(w, "\n[modules]")
+	fmt.Fprintln(w, "enabled = [] # modules to run")
+	names := []string{}
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "\n[modules.%s]\n", name)
+		for _, line := range schemaLines(modules[name]) {
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	for _, table := range tables {
+		fmt.Fprintf(w, "\n%s\n", table)
+	}
+	return nil
+}
+
+// schemaLines returns a "key = default # type - comment" line for each field of the struct v points to
+// Map fields are returned as a "[key] # comment" table header, struct fields as the table header followed by its keys
+func schemaLines(v interface{}) []string {
+	lines := []string{}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return lines
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" || field.Type == reflect.TypeOf(toml.Primitive{}) {
+			continue
+		}
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("toml"), ",")[0]; tag != "" {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		comment := field.Type.String()
+		if c := field.Tag.Get("comment"); c != "" {
+			comment += " - " + c
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			lines = append(lines, schemaLines(rv.Field(i).Interface())...)
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			// Tables are returned as the header with its keys, followed by the nested tables
+			table := []string{fmt.Sprintf("[%s] # %s", name, comment)}
+			nested := []string{}
+			for _, line := range schemaLines(rv.Field(i).Interface()) {
+				if strings.HasPrefix(line, "[") {
+					nested = append(nested, "["+name+"."+strings.TrimPrefix(line, "["))
+					continue
+				}
+				table = append(table, line)
+			}
+			lines = append(lines, strings.Join(table, "\n"))
+			lines = append(lines, nested...)
+			continue
+		}
+		if field.Type.Kind() == reflect.Map {
+			lines = append(lines, fmt.Sprintf("[%s] # %s", name, comment))
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			// Arrays of tables are optional, print a commented out example with the keys of one table
+			lines = append(lines, fmt.Sprintf("# [[%s]] # %s", name, comment))
+			tables := []string{}
+			for _, line := range schemaLines(reflect.New(field.Type.Elem()).Interface()) {
+				if strings.HasPrefix(line, "[") {
+					tables = append(tables, "# ["+name+"."+strings.TrimPrefix(line, "["))
+					continue
+				}
+				lines = append(lines, "# "+line)
+			}
+			lines = append(lines, tables...)
+			continue
+		}
+		var buf bytes.Buffer
+		value := rv.Field(i).Interface()
+		if field.Type.Kind() == reflect.Slice && rv.Field(i).IsNil() {
+			value = reflect.MakeSlice(field.Type, 0, 0).Interface()
+		}
+		if err := toml.NewEncoder(&buf).Encode(map[string]interface{}{name: value}); err != nil {
+			lines = append(lines, fmt.Sprintf("# %s # %s", name, comment))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s # %s", strings.TrimSpace(buf.String()), comment))
+	}
+	return lines
+}
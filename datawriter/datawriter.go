@@ -22,7 +22,11 @@ type OrionWriter struct {
 	csvmw       *CSVOrionWriter
 	xlsx        bool
 	xlsxmw      *XLSXOrionWriter
+	memory      bool
+	memorymw    *MemoryOrionWriter
 	outfilepath string
+	streams     *streamTee
+	filter      *rowFilter
 }
 
 type CSVOrionWriter struct {
@@ -81,6 +85,21 @@ func NewOrionWriter(module string, orionRuntime string, outputtype string, fp st
 			csvmw:       &csvmw,
 			xlsx:        false,
 			outfilepath: fp,
+			streams:     newStreamTee(module, orionRuntime),
+			filter:      newRowFilter(module),
+		}, nil
+	case MemoryOutputType:
+		// nothing is written to fp, modules still derive the location of their other outputs from the path
+		if !strings.HasSuffix(fp, "/") {
+			fp = fp + "/"
+		}
+		fp, _ := filepath.Abs(fp + orionRuntime + "_" + module + ".csv")
+		return OrionWriter{
+			memory:      true,
+			memorymw:    newMemoryOrionWriter(module, orionRuntime),
+			outfilepath: fp,
+			streams:     newStreamTee(module, orionRuntime),
+			filter:      newRowFilter(module),
 		}, nil
 	}
 	return OrionWriter{}, errors.New("cannot create OrionWriter for the given output type")
@@ -88,6 +107,10 @@ func NewOrionWriter(module string, orionRuntime string, outputtype string, fp st
 
 func (mw OrionWriter) SelfDestruct() error {
 	zap.L().Debug("Removing OrionWriter: " + mw.outfilepath)
+	if mw.memory {
+		removeMemoryOutput(mw.memorymw.module, mw.memorymw.runtime)
+		return nil
+	}
 	return os.Remove(mw.outfilepath)
 }
 
@@ -100,6 +123,8 @@ func (mw OrionWriter) GetOutputType() string {
 		return "csv"
 	} else if mw.xlsx == true {
 		return "xlsx"
+	} else if mw.memory {
+		return MemoryOutputType
 	} else {
 		return "ERROR"
 	}
@@ -110,6 +135,8 @@ func (mw OrionWriter) GetOrionRuntime() string {
 		return mw.csvmw.runtime
 	} else if mw.xlsx == true {
 		return mw.xlsxmw.runtime
+	} else if mw.memory {
+		return mw.memorymw.runtime
 	} else {
 		return "ERROR"
 	}
@@ -128,11 +155,19 @@ func (mw OrionWriter) Write(entry []string) error {
 	switch outputtype {
 	case "csv":
 		// zap.L().Debug("Sending entry to CSV Writer: " + strings.Join(entry, " "))
+		if len(mw.filter.apply(entry)) == 0 {
+			return nil
+		}
 		err := mw.csvmw.Write(entry)
 		if err != nil {
 			return err
 		}
+		mw.streams.write(entry)
 		return mw.csvmw.Flush()
+	case MemoryOutputType:
+		rows := mw.filter.apply(entry)
+		mw.streams.write(rows...)
+		return mw.memorymw.WriteAll(rows)
 	}
 	return errors.New("failed to write entry")
 }
@@ -145,11 +180,17 @@ func (mw OrionWriter) WriteAll(entries [][]string) error {
 	}
 	switch outputtype {
 	case "csv":
+		entries = mw.filter.apply(entries...)
 		err := mw.csvmw.WriteAll(entries)
 		if err != nil {
 			return err
 		}
+		mw.streams.write(entries...)
 		return mw.csvmw.Flush()
+	case MemoryOutputType:
+		entries = mw.filter.apply(entries...)
+		mw.streams.write(entries...)
+		return mw.memorymw.WriteAll(entries)
 	}
 	return errors.New("failed to write entries")
 }
@@ -161,11 +202,19 @@ func (mw OrionWriter) WriteOutput(header []string, entries [][]string) error {
 	}
 	switch outputtype {
 	case "csv":
+		mw.filter.apply(header)
+		entries = mw.filter.apply(entries...)
 		err := mw.csvmw.WriteOutput(header, entries)
 		if err != nil {
 			return err
 		}
+		mw.streams.write(header)
+		mw.streams.write(entries...)
 		return mw.csvmw.Flush()
+	case MemoryOutputType:
+		rows := append(mw.filter.apply(header), mw.filter.apply(entries...)...)
+		mw.streams.write(rows...)
+		return mw.memorymw.WriteAll(rows)
 	}
 	return errors.New("failed to write header and entries to output")
 }
@@ -178,6 +227,8 @@ func (mw OrionWriter) Close() error {
 	switch outputtype {
 	case "csv":
 		return mw.csvmw.Close()
+	case MemoryOutputType:
+		return nil
 	}
 	return errors.New("failed to close file")
 }
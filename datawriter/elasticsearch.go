@@ -0,0 +1,174 @@
+package datawriter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// elasticsearchBackend indexes events with the elasticsearch _bulk API, one index per module
+type elasticsearchBackend struct {
+	cfg    StreamConfig
+	host   string
+	url    string
+	client *http.Client
+}
+
+func newElasticsearchBackend(cfg StreamConfig, host string) (backend, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("stream '%s': invalid elasticsearch url '%s'", cfg.Name, cfg.URL)
+	}
+	if cfg.Index == "" {
+		cfg.Index = defaultIndex
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("ORION_ELASTIC_API_KEY")
+	}
+	return &elasticsearchBackend{
+		cfg:    cfg,
+		host:   host,
+		url:    strings.TrimSuffix(cfg.URL, "/") + "/_bulk",
+		client: newHTTPClient(cfg),
+	}, nil
+}
+
+// newHTTPClient returns the client of the http based backends
+func newHTTPClient(cfg StreamConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second, Transport: transport}
+}
+
+// index returns the index of module, elasticsearch index names must be lowercase
+func (b *elasticsearchBackend) index(module string) string {
+	return strings.ToLower(moduleValue(b.cfg.Index, module))
+}
+
+func (b *elasticsearchBackend) send(events []event) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range events {
+		action := map[string]map[string]string{"index": {"_index": b.index(e.Module)}}
+		if err := enc.Encode(action); err != nil {
+			return permanentError{err}
+		}
+		if err := enc.Encode(e.document(b.host)); err != nil {
+			return permanentError{err}
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.url, &body)
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if b.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+b.cfg.APIKey)
+	} else if b.cfg.Username != "" {
+		req.SetBasicAuth(b.cfg.Username, b.cfg.Password)
+	}
+	for k, v := range b.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	data, err := doRequest(b.client, req)
+	if err != nil {
+		return err
+	}
+
+	// The bulk API answers 200 even when single documents fail, check every item
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("unexpected elasticsearch bulk response: %s", truncate(string(data)))
+	}
+	if !result.Errors {
+		return nil
+	}
+	retry := []event{}
+	rejected := 0
+	reason := ""
+	for i, item := range result.Items {
+		for _, r := range item {
+			if r.Status/100 == 2 || i >= len(events) {
+				continue
+			}
+			if retryableStatus(r.Status) {
+				retry = append(retry, events[i])
+				continue
+			}
+			rejected++
+			reason = r.Error.Type + ": " + r.Error.Reason
+		}
+	}
+	if len(retry) == 0 {
+		return permanentError{fmt.Errorf("elasticsearch rejected %d of %d documents, last error %s", rejected, len(events), reason)}
+	}
+	err = fmt.Errorf("elasticsearch could not index %d of %d documents right now", len(retry), len(events))
+	if rejected > 0 {
+		err = fmt.Errorf("elasticsearch could not index %d and rejected %d of %d documents, last error %s", len(retry), rejected, len(events), reason)
+	}
+	return partialError{err: err, events: retry, rejected: rejected}
+}
+
+func (b *elasticsearchBackend) close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
+
+// doRequest sends req and returns the response body
+// Requests the server rejects as malformed are permanent errors, other failures (ex. a wrong token) are
+// worth spooling to send again later
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return data, nil
+	}
+	u := *req.URL
+	u.User = nil
+	err = fmt.Errorf("%s %s returned %s: %s", req.Method, u.String(), resp.Status, truncate(string(data)))
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, permanentError{err}
+	}
+	return nil, err
+}
+
+// retryableStatus returns true for HTTP status codes worth retrying (server errors, throttling and timeouts)
+func retryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+}
+
+// truncate shortens server responses for error messages
+func truncate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 512 {
+		return s[:512] + "..."
+	}
+	return s
+}
@@ -0,0 +1,69 @@
+package datawriter
+
+import "sync"
+
+// RowFilter decides whether a row of an output is written, header is the first row the module wrote
+type RowFilter func(header []string, row []string) bool
+
+var (
+	filterMutex = &sync.Mutex{}
+	rowFilters  = make(map[string]RowFilter)
+)
+
+// SetRowFilter makes OrionWriters created afterwards for output write only the rows f keeps, nil removes the filter
+// The header is always written
+func SetRowFilter(output string, f RowFilter) {
+	filterMutex.Lock()
+	defer filterMutex.Unlock()
+	if f == nil {
+		delete(rowFilters, output)
+		return
+	}
+	rowFilters[output] = f
+}
+
+// ClearRowFilters removes every row filter
+func ClearRowFilters() {
+	filterMutex.Lock()
+	defer filterMutex.Unlock()
+	rowFilters = make(map[string]RowFilter)
+}
+
+// rowFilter applies the RowFilter of an output to the rows of one OrionWriter
+type rowFilter struct {
+	mutex  *sync.Mutex
+	header []string
+	keep   RowFilter
+}
+
+// newRowFilter returns the filter for output, nil when output has none
+func newRowFilter(output string) *rowFilter {
+	filterMutex.Lock()
+	defer filterMutex.Unlock()
+	keep, ok := rowFilters[output]
+	if !ok {
+		return nil
+	}
+	return &rowFilter{mutex: &sync.Mutex{}, keep: keep}
+}
+
+// apply returns the rows to write, the first row ever passed is the header and is kept
+func (f *rowFilter) apply(rows ...[]string) [][]string {
+	if f == nil {
+		return rows
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	kept := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		if f.header == nil {
+			f.header = append([]string{}, row...)
+			kept = append(kept, row)
+			continue
+		}
+		if f.keep(f.header, row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,41 @@
+package datawriter
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestRowFilter(t *testing.T) {
+	dir := setup(t)
+	SetRowFilter("FilterModule", func(header []string, row []string) bool {
+		return header[1] == "time" && row[1] > "2"
+	})
+	defer ClearRowFilters()
+
+	mw, err := NewOrionWriter("FilterModule", "Orion_test", "csv", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw.WriteHeader([]string{"name", "time"})
+	mw.Write([]string{"a", "1"})
+	mw.Write([]string{"b", "3"})
+	mw.WriteAll([][]string{{"c", "2"}, {"d", "4"}})
+	mw.Close()
+
+	data, err := ioutil.ReadFile(mw.GetOutfilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name,time\nb,3\nd,4\n"; string(data) != want {
+		t.Errorf("filtered output = %q, want %q", data, want)
+	}
+
+	// outputs without a filter and writers created after clearing are untouched
+	ClearRowFilters()
+	mw, _ = NewOrionWriter("FilterModule", "Orion_test2", "csv", dir)
+	mw.WriteOutput([]string{"name", "time"}, [][]string{{"a", "1"}})
+	data, _ = ioutil.ReadFile(mw.GetOutfilePath())
+	if want := "name,time\na,1\n"; string(data) != want {
+		t.Errorf("unfiltered output = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,71 @@
+package datawriter
+
+import "sync"
+
+// MemoryOutputType is the output type of OrionWriters that keep rows in memory instead of writing files, used to test
+// modules
+const MemoryOutputType = "memory"
+
+var (
+	memoryMutex   = &sync.Mutex{}
+	memoryOutputs = make(map[string]map[string]*MemoryOrionWriter) // runtime to output name to writer
+)
+
+// MemoryOrionWriter keeps the rows written to an output in memory
+type MemoryOrionWriter struct {
+	mutex   *sync.Mutex
+	rows    [][]string
+	module  string
+	runtime string
+}
+
+// newMemoryOrionWriter returns the in-memory writer of module for runtime, a module creating the same output twice
+// starts it over like a file would be
+func newMemoryOrionWriter(module string, runtime string) *MemoryOrionWriter {
+	memoryMutex.Lock()
+	defer memoryMutex.Unlock()
+	w := &MemoryOrionWriter{mutex: &sync.Mutex{}, rows: [][]string{}, module: module, runtime: runtime}
+	if memoryOutputs[runtime] == nil {
+		memoryOutputs[runtime] = make(map[string]*MemoryOrionWriter)
+	}
+	memoryOutputs[runtime][module] = w
+	return w
+}
+
+// WriteAll keeps copies of rows
+func (w *MemoryOrionWriter) WriteAll(rows [][]string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, row := range rows {
+		w.rows = append(w.rows, append([]string{}, row...))
+	}
+	return nil
+}
+
+// Rows returns the rows written so far, the header first
+func (w *MemoryOrionWriter) Rows() [][]string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([][]string{}, w.rows...)
+}
+
+// MemoryOutputs returns the rows written by the in-memory writers of runtime by output name and forgets them
+func MemoryOutputs(runtime string) map[string][][]string {
+	memoryMutex.Lock()
+	writers := memoryOutputs[runtime]
+	delete(memoryOutputs, runtime)
+	memoryMutex.Unlock()
+
+	outputs := make(map[string][][]string, len(writers))
+	for name, w := range writers {
+		outputs[name] = w.Rows()
+	}
+	return outputs
+}
+
+// removeMemoryOutput forgets the output of module for runtime
+func removeMemoryOutput(module string, runtime string) {
+	memoryMutex.Lock()
+	defer memoryMutex.Unlock()
+	delete(memoryOutputs[runtime], module)
+}
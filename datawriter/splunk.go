@@ -0,0 +1,96 @@
+package datawriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// splunkBackend sends events to the Splunk HTTP Event Collector, the sourcetype is taken from the module
+type splunkBackend struct {
+	cfg    StreamConfig
+	host   string
+	url    string
+	client *http.Client
+}
+
+// hecEvent is an event of the HEC /services/collector/event endpoint
+type hecEvent struct {
+	Time       float64           `json:"time"`
+	Host       string            `json:"host"`
+	Source     string            `json:"source"`
+	Sourcetype string            `json:"sourcetype"`
+	Index      string            `json:"index,omitempty"`
+	Event      map[string]string `json:"event"`
+}
+
+func newSplunkBackend(cfg StreamConfig, host string) (backend, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("stream '%s': invalid splunk HEC url '%s'", cfg.Name, cfg.URL)
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("ORION_SPLUNK_TOKEN")
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("stream '%s': splunk HEC token not set, use token or ORION_SPLUNK_TOKEN", cfg.Name)
+	}
+	if cfg.Sourcetype == "" {
+		cfg.Sourcetype = defaultSourcetype
+	}
+	endpoint := strings.TrimSuffix(cfg.URL, "/")
+	if !strings.Contains(u.Path, "/services/collector") {
+		endpoint += "/services/collector/event"
+	}
+	return &splunkBackend{
+		cfg:    cfg,
+		host:   host,
+		url:    endpoint,
+		client: newHTTPClient(cfg),
+	}, nil
+}
+
+func (b *splunkBackend) send(events []event) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range events {
+		fields := make(map[string]string, len(e.Fields)+2)
+		for k, v := range e.Fields {
+			fields[k] = v
+		}
+		fields["orion_module"] = e.Module
+		fields["orion_runtime"] = e.Runtime
+		err := enc.Encode(hecEvent{
+			Time:       float64(e.Time.UnixNano()) / 1e9,
+			Host:       b.host,
+			Source:     "orion:" + e.Runtime,
+			Sourcetype: moduleValue(b.cfg.Sourcetype, e.Module),
+			Index:      strings.ToLower(moduleValue(b.cfg.Index, e.Module)),
+			Event:      fields,
+		})
+		if err != nil {
+			return permanentError{err}
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.url, &body)
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+b.cfg.Token)
+	for k, v := range b.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	_, err = doRequest(b.client, req)
+	return err
+}
+
+func (b *splunkBackend) close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,519 @@
+package datawriter
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Stream types
+const (
+	StreamElasticsearch = "elasticsearch"
+	StreamSplunk        = "splunk"
+	StreamSyslog        = "syslog"
+)
+
+// Syslog message formats
+const (
+	FormatRFC5424 = "rfc5424"
+	FormatJSON    = "json"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = 5
+	defaultQueueSize     = 10000
+	defaultStreamRetries = 3
+	defaultStreamTimeout = 30
+	defaultIndex         = "orion-{module}"
+	defaultSourcetype    = "orion:{module}"
+)
+
+var (
+	// streamRetryDelay is the wait before retrying a failed batch, multiplied by the attempt number
+	streamRetryDelay = time.Second
+	// streamCooldown is how long batches go straight to the spool after a batch could not be delivered,
+	// so an unreachable pipeline does not hold up the modules with retries
+	streamCooldown = 30 * time.Second
+	// spoolDir holds rows that could not be delivered, outside of the output directory that gets archived
+	spoolDir = filepath.Join(os.TempDir(), "orion-spool")
+)
+
+// StreamConfig is a [[stream]] table of the config file
+// String values expand environment variables (ex. token = "${ORION_HEC_TOKEN}")
+type StreamConfig struct {
+	Name               string            `toml:"name" comment:"name used in logs, defaults to the type"`
+	Type               string            `toml:"type" comment:"elasticsearch, splunk or syslog"`
+	URL                string            `toml:"url" comment:"elasticsearch: cluster URL (ex. https://es:9200), splunk: HEC URL (ex. https://splunk:8088), syslog: tcp://, udp:// or tls://host:port"`
+	Index              string            `toml:"index" comment:"elasticsearch index or splunk index, {module} is replaced with the lowercase module name, elasticsearch defaults to orion-{module}"`
+	Sourcetype         string            `toml:"sourcetype" comment:"splunk sourcetype, {module} is replaced with the module name, defaults to orion:{module}"`
+	Token              string            `toml:"token" comment:"splunk HEC token, defaults to $ORION_SPLUNK_TOKEN"`
+	APIKey             string            `toml:"apiKey" comment:"elasticsearch API key (base64 id:key), defaults to $ORION_ELASTIC_API_KEY"`
+	Username           string            `toml:"username" comment:"elasticsearch basic auth user"`
+	Password           string            `toml:"password" comment:"elasticsearch basic auth password, defaults to $ORION_STREAM_PASSWORD"`
+	Headers            map[string]string `toml:"headers" comment:"extra http request headers"`
+	Format             string            `toml:"format" comment:"syslog: rfc5424 (default, JSON message) or json (one JSON document per line)"`
+	InsecureSkipVerify bool              `toml:"insecureSkipVerify" comment:"do not verify the TLS certificate of the server"`
+	BatchSize          int               `toml:"batchSize" comment:"rows per request, 0 uses 500"`
+	FlushInterval      int               `toml:"flushInterval" comment:"seconds before a partial batch is sent, 0 uses 5"`
+	QueueSize          int               `toml:"queueSize" comment:"rows buffered before modules wait for the pipeline, 0 uses 10000"`
+	Retries            int               `toml:"retries" comment:"attempts per batch before it is spooled, 0 uses 3"`
+	Timeout            int               `toml:"timeout" comment:"seconds per request, 0 uses 30"`
+}
+
+// event is a single output row of a module, spooled as a JSON line when it cannot be delivered
+type event struct {
+	Module  string            `json:"module"`
+	Runtime string            `json:"runtime"`
+	Time    time.Time         `json:"time"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// document returns the fields of e with the Orion metadata, as sent to elasticsearch and syslog
+func (e event) document(host string) map[string]string {
+	doc := make(map[string]string, len(e.Fields)+4)
+	for k, v := range e.Fields {
+		doc[k] = v
+	}
+	doc["@timestamp"] = e.Time.UTC().Format(time.RFC3339Nano)
+	doc["orion_module"] = e.Module
+	doc["orion_runtime"] = e.Runtime
+	doc["orion_host"] = host
+	return doc
+}
+
+// backend delivers batches of events to a log pipeline
+type backend interface {
+	send(events []event) error
+	close() error
+}
+
+// permanentError marks a rejected batch that retrying or spooling cannot fix (ex. bad token)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// partialError is returned when only some events of a batch were accepted, events are the ones left to retry
+// and rejected the number of events that will never be accepted
+type partialError struct {
+	err      error
+	events   []event
+	rejected int
+}
+
+func (e partialError) Error() string {
+	return e.err.Error()
+}
+
+// Stream ships module rows to a log pipeline in batches from a bounded queue
+// Writers wait when the queue is full, batches that cannot be delivered are spooled to disk and
+// sent again once the pipeline accepts data, also by later runs
+type Stream struct {
+	cfg     StreamConfig
+	backend backend
+	host    string
+	spool   string
+
+	queue      chan event
+	done       chan struct{}
+	stateMutex sync.RWMutex
+	running    bool
+	spoolMutex sync.Mutex
+
+	retryAt time.Time
+	sent    int
+	dropped int
+}
+
+// NewStream validates cfg, fills in defaults and credentials from the environment and returns the stream
+// The stream does not send anything until StartStreams
+func NewStream(cfg StreamConfig) (*Stream, error) {
+	cfg = cfg.expand()
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("stream '%s': url is required", cfg.Name)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = defaultStreamRetries
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultStreamTimeout
+	}
+	if cfg.Password == "" {
+		cfg.Password = os.Getenv("ORION_STREAM_PASSWORD")
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown-host"
+	}
+
+	var b backend
+	cfg.Type = strings.ToLower(cfg.Type)
+	switch cfg.Type {
+	case StreamElasticsearch:
+		b, err = newElasticsearchBackend(cfg, host)
+	case StreamSplunk:
+		b, err = newSplunkBackend(cfg, host)
+	case StreamSyslog:
+		b, err = newSyslogBackend(cfg, host)
+	default:
+		err = fmt.Errorf("stream '%s': unknown type '%s', expected elasticsearch, splunk or syslog", cfg.Name, cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(cfg.Type + "|" + cfg.URL + "|" + cfg.Index + "|" + cfg.Name))
+	return &Stream{
+		cfg:     cfg,
+		backend: b,
+		host:    host,
+		spool:   filepath.Join(spoolDir, hex.EncodeToString(sum[:])+".jsonl"),
+	}, nil
+}
+
+// expand returns cfg with environment variables expanded in every string value
+func (cfg StreamConfig) expand() StreamConfig {
+	for _, s := range []*string{
+		&cfg.Name, &cfg.Type, &cfg.URL, &cfg.Index, &cfg.Sourcetype,
+		&cfg.Token, &cfg.APIKey, &cfg.Username, &cfg.Password, &cfg.Format,
+	} {
+		*s = os.ExpandEnv(*s)
+	}
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	cfg.Headers = headers
+	return cfg
+}
+
+// Config returns the config the stream was created from
+func (s *Stream) Config() StreamConfig {
+	return s.cfg
+}
+
+// moduleValue returns template with {module} replaced by module
+func moduleValue(template string, module string) string {
+	return strings.Replace(template, "{module}", module, -1)
+}
+
+var (
+	streamsMutex  sync.Mutex
+	activeStreams []*Stream
+)
+
+// StartStreams sends the rows of every OrionWriter created afterwards to streams, rows spooled by
+// earlier runs are sent first
+func StartStreams(streams []*Stream) {
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+	for _, s := range streams {
+		s.start()
+		activeStreams = append(activeStreams, s)
+	}
+}
+
+// StopStreams sends the queued rows, spooling what cannot be delivered, and waits for every stream to stop
+func StopStreams() {
+	streamsMutex.Lock()
+	streams := activeStreams
+	activeStreams = nil
+	streamsMutex.Unlock()
+	for _, s := range streams {
+		s.stop()
+	}
+}
+
+// getActiveStreams returns the streams rows are currently sent to
+func getActiveStreams() []*Stream {
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+	return append([]*Stream{}, activeStreams...)
+}
+
+func (s *Stream) start() {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	if s.running {
+		return
+	}
+	s.queue = make(chan event, s.cfg.QueueSize)
+	s.done = make(chan struct{})
+	s.running = true
+	go s.run()
+}
+
+func (s *Stream) stop() {
+	s.stateMutex.Lock()
+	if !s.running {
+		s.stateMutex.Unlock()
+		return
+	}
+	s.running = false
+	close(s.queue)
+	s.stateMutex.Unlock()
+
+	<-s.done
+	if err := s.backend.close(); err != nil {
+		zap.L().Debug("stream '" + s.cfg.Name + "': " + err.Error())
+	}
+	msg := fmt.Sprintf("stream '%s': sent %d rows", s.cfg.Name, s.sent)
+	if spooled := s.spoolLength(); spooled > 0 {
+		msg += fmt.Sprintf(", spooled %d rows to %s to send on the next run", spooled, s.spool)
+	}
+	if s.dropped > 0 {
+		msg += fmt.Sprintf(", %d rows were rejected", s.dropped)
+	}
+	zap.L().Info(msg)
+}
+
+// enqueue queues e for the stream, waiting while the queue is full
+// Rows written after the stream stopped are spooled
+func (s *Stream) enqueue(e event) {
+	s.stateMutex.RLock()
+	defer s.stateMutex.RUnlock()
+	if !s.running {
+		s.spoolEvents([]event{e})
+		return
+	}
+	select {
+	case s.queue <- e:
+	default:
+		zap.L().Debug("stream '"+s.cfg.Name+"': queue is full, waiting for the pipeline", zap.String("module", e.Module))
+		s.queue <- e
+	}
+}
+
+// run batches queued events until the queue is closed
+func (s *Stream) run() {
+	defer close(s.done)
+	s.replay()
+
+	ticker := time.NewTicker(time.Duration(s.cfg.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	batch := make([]event, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case e, ok := <-s.queue:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) >= s.cfg.BatchSize {
+				s.flush(batch)
+				batch = make([]event, 0, s.cfg.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = make([]event, 0, s.cfg.BatchSize)
+			}
+		}
+	}
+}
+
+// flush delivers batch, spooling it on failure, and sends the spool once the pipeline is reachable again
+func (s *Stream) flush(batch []event) {
+	if len(batch) == 0 {
+		return
+	}
+	if time.Now().Before(s.retryAt) {
+		s.spoolEvents(batch)
+		return
+	}
+	if !s.deliver(batch) {
+		return
+	}
+	s.replay()
+}
+
+// deliver sends batch with retries and returns true when the pipeline accepted it
+func (s *Stream) deliver(batch []event) bool {
+	var err error
+	for attempt := 1; attempt <= s.cfg.Retries; attempt++ {
+		err = s.backend.send(batch)
+		if err == nil {
+			s.sent += len(batch)
+			return true
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			s.dropped += len(batch)
+			zap.L().Error(fmt.Sprintf("stream '%s': %d rows rejected: %s", s.cfg.Name, len(batch), err.Error()))
+			return true
+		}
+		var partial partialError
+		if errors.As(err, &partial) {
+			if partial.rejected > 0 {
+				zap.L().Error(fmt.Sprintf("stream '%s': %d rows rejected: %s", s.cfg.Name, partial.rejected, err.Error()))
+			}
+			s.sent += len(batch) - len(partial.events) - partial.rejected
+			s.dropped += partial.rejected
+			batch = partial.events
+		}
+		if attempt < s.cfg.Retries {
+			time.Sleep(streamRetryDelay * time.Duration(attempt))
+		}
+	}
+	zap.L().Warn(fmt.Sprintf("stream '%s': failed to send %d rows after %d attempts, spooling them: %s", s.cfg.Name, len(batch), s.cfg.Retries, err.Error()))
+	s.retryAt = time.Now().Add(streamCooldown)
+	s.spoolEvents(batch)
+	return false
+}
+
+// spoolEvents appends events to the spool file of the stream
+func (s *Stream) spoolEvents(events []event) {
+	s.spoolMutex.Lock()
+	defer s.spoolMutex.Unlock()
+	err := os.MkdirAll(filepath.Dir(s.spool), 0700)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("stream '%s': lost %d rows, failed to create spool directory: %s", s.cfg.Name, len(events), err.Error()))
+		return
+	}
+	f, err := os.OpenFile(s.spool, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("stream '%s': lost %d rows, failed to open spool: %s", s.cfg.Name, len(events), err.Error()))
+		return
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			zap.L().Error("stream '" + s.cfg.Name + "': failed to spool row: " + err.Error())
+		}
+	}
+	if err := w.Flush(); err != nil {
+		zap.L().Error("stream '" + s.cfg.Name + "': failed to write spool: " + err.Error())
+	}
+}
+
+// spoolLength returns the number of rows in the spool file of the stream
+func (s *Stream) spoolLength() int {
+	s.spoolMutex.Lock()
+	defer s.spoolMutex.Unlock()
+	data, err := ioutil.ReadFile(s.spool)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "\n")
+}
+
+// replay sends the spooled events in batches, events that still cannot be delivered are spooled again
+func (s *Stream) replay() {
+	s.spoolMutex.Lock()
+	data, err := ioutil.ReadFile(s.spool)
+	if err == nil {
+		err = os.Remove(s.spool)
+	}
+	s.spoolMutex.Unlock()
+	if err != nil {
+		return
+	}
+
+	events := []event{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var e event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			zap.L().Warn("stream '" + s.cfg.Name + "': skipping unreadable spooled row: " + err.Error())
+			continue
+		}
+		events = append(events, e)
+	}
+	if len(events) == 0 {
+		return
+	}
+	zap.L().Info("stream '" + s.cfg.Name + "': sending " + strconv.Itoa(len(events)) + " spooled rows")
+	for start := 0; start < len(events); start += s.cfg.BatchSize {
+		end := start + s.cfg.BatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+		if !s.deliver(events[start:end]) {
+			s.spoolEvents(events[end:])
+			return
+		}
+	}
+}
+
+// streamTee turns the rows written by an OrionWriter into events for the active streams
+// The first row written is the header, its column names become the field names
+type streamTee struct {
+	mutex   *sync.Mutex
+	module  string
+	runtime string
+	header  []string
+	streams []*Stream
+}
+
+// newStreamTee returns the tee for module, nil when no streams are active
+func newStreamTee(module string, runtime string) *streamTee {
+	streams := getActiveStreams()
+	if len(streams) == 0 {
+		return nil
+	}
+	return &streamTee{
+		mutex:   &sync.Mutex{},
+		module:  module,
+		runtime: runtime,
+		streams: streams,
+	}
+}
+
+// write sends rows to every stream, waiting while a stream queue is full
+func (t *streamTee) write(rows ...[]string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, row := range rows {
+		if t.header == nil {
+			t.header = append([]string{}, row...)
+			continue
+		}
+		fields := make(map[string]string, len(row))
+		for i, value := range row {
+			name := "column_" + strconv.Itoa(i+1)
+			if i < len(t.header) && t.header[i] != "" {
+				name = t.header[i]
+			}
+			fields[name] = value
+		}
+		e := event{Module: t.module, Runtime: t.runtime, Time: time.Now(), Fields: fields}
+		for _, s := range t.streams {
+			s.enqueue(e)
+		}
+	}
+}
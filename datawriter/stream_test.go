@@ -0,0 +1,328 @@
+package datawriter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func init() {
+	streamRetryDelay = time.Millisecond
+}
+
+// setup points the spool at a temporary directory and returns a directory for output files
+func setup(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "datawriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	spoolDir = filepath.Join(dir, "spool")
+	return dir
+}
+
+// writeRows writes a header and rows with a new OrionWriter while streams are active
+func writeRows(t *testing.T, dir string, module string, header []string, rows [][]string, streams ...*Stream) {
+	StartStreams(streams)
+	mw, err := NewOrionWriter(module, "Orion_test", "csv", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.WriteHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.WriteAll(rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	StopStreams()
+}
+
+func TestNewStream(t *testing.T) {
+	setup(t)
+	os.Setenv("ORION_TEST_HEC_TOKEN", "secret")
+	defer os.Unsetenv("ORION_TEST_HEC_TOKEN")
+
+	s, err := NewStream(StreamConfig{Type: "Splunk", URL: "https://splunk:8088", Token: "${ORION_TEST_HEC_TOKEN}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := s.Config()
+	if cfg.Name != "Splunk" || cfg.Token != "secret" || cfg.BatchSize != defaultBatchSize || cfg.QueueSize != defaultQueueSize {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if got := s.backend.(*splunkBackend).url; got != "https://splunk:8088/services/collector/event" {
+		t.Errorf("HEC url = %s", got)
+	}
+
+	bad := []StreamConfig{
+		{Type: "kafka", URL: "kafka://broker:9092"},
+		{Type: "elasticsearch"},
+		{Type: "elasticsearch", URL: "es:9200"},
+		{Type: "splunk", URL: "https://splunk:8088"},
+		{Type: "syslog", URL: "tcp://collector"},
+		{Type: "syslog", URL: "http://collector:514"},
+		{Type: "syslog", URL: "udp://collector:514", Format: "cef"},
+	}
+	for _, cfg := range bad {
+		if _, err := NewStream(cfg); err == nil {
+			t.Errorf("NewStream(%+v) expected an error", cfg)
+		}
+	}
+}
+
+func TestElasticsearchStream(t *testing.T) {
+	dir := setup(t)
+	var mutex sync.Mutex
+	lines := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Authorization") != "ApiKey abc" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request %s %s %v", r.Method, r.URL.Path, r.Header)
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		lines = append(lines, strings.Split(strings.TrimSpace(string(data)), "\n")...)
+		mutex.Unlock()
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	s, err := NewStream(StreamConfig{Type: StreamElasticsearch, URL: server.URL, APIKey: "abc", BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRows(t, dir, "MacBashModule", []string{"user", "cmd"}, [][]string{{"root", "ls"}, {"root", "id"}, {"admin", "whoami"}}, s)
+
+	if len(lines) != 6 {
+		t.Fatalf("expected 3 actions and 3 documents, got %d lines: %v", len(lines), lines)
+	}
+	var action map[string]map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &action); err != nil || action["index"]["_index"] != "orion-macbashmodule" {
+		t.Errorf("unexpected bulk action %s", lines[0])
+	}
+	var doc map[string]string
+	if err := json.Unmarshal([]byte(lines[5]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["user"] != "admin" || doc["cmd"] != "whoami" || doc["orion_module"] != "MacBashModule" || doc["orion_runtime"] != "Orion_test" || doc["@timestamp"] == "" {
+		t.Errorf("unexpected document %v", doc)
+	}
+	if s.sent != 3 {
+		t.Errorf("sent = %d, expected 3", s.sent)
+	}
+}
+
+func TestElasticsearchPartialFailure(t *testing.T) {
+	dir := setup(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		data, _ := ioutil.ReadAll(r.Body)
+		docs := strings.Count(string(data), "\n") / 2
+		switch {
+		case requests == 1 && docs == 3:
+			// first document indexed, second throttled, third rejected by the mapping
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}]}`))
+		case requests == 2 && docs == 1:
+			w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+		default:
+			t.Errorf("unexpected request %d with %d documents", requests, docs)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewStream(StreamConfig{Type: StreamElasticsearch, URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRows(t, dir, "Sample", []string{"a"}, [][]string{{"1"}, {"2"}, {"3"}}, s)
+	if requests != 2 || s.sent != 2 {
+		t.Errorf("requests = %d, sent = %d, expected 2 and 2", requests, s.sent)
+	}
+}
+
+func TestSplunkStream(t *testing.T) {
+	dir := setup(t)
+	events := []hecEvent{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/event" || r.Header.Get("Authorization") != "Splunk token" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var e hecEvent
+			if err := dec.Decode(&e); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, e)
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	s, err := NewStream(StreamConfig{Type: StreamSplunk, URL: server.URL, Token: "token", Index: "orion"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRows(t, dir, "MacUsersModule", []string{"name", "uid"}, [][]string{{"root", "0"}, {"admin", "501", "extra"}}, s)
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	e := events[1]
+	if e.Sourcetype != "orion:MacUsersModule" || e.Index != "orion" || e.Source != "orion:Orion_test" || e.Time == 0 || e.Host == "" {
+		t.Errorf("unexpected event metadata %+v", e)
+	}
+	if e.Event["name"] != "admin" || e.Event["uid"] != "501" || e.Event["column_3"] != "extra" || e.Event["orion_module"] != "MacUsersModule" {
+		t.Errorf("unexpected event fields %v", e.Event)
+	}
+}
+
+func TestSyslogStream(t *testing.T) {
+	dir := setup(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan []string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		lines := []string{}
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		received <- lines
+	}()
+
+	s, err := NewStream(StreamConfig{Type: StreamSyslog, URL: "tcp://" + ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRows(t, dir, "MacSSHModule", []string{"user", "key"}, [][]string{{"root", "ssh-ed25519 AAAA"}}, s)
+
+	lines := <-received
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 syslog message, got %v", lines)
+	}
+	if !strings.HasPrefix(lines[0], "<134>1 ") || !strings.Contains(lines[0], " orion - MacSSHModule - {") {
+		t.Errorf("unexpected syslog header: %s", lines[0])
+	}
+	var doc map[string]string
+	if err := json.Unmarshal([]byte(lines[0][strings.Index(lines[0], "{"):]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["key"] != "ssh-ed25519 AAAA" || doc["orion_module"] != "MacSSHModule" {
+		t.Errorf("unexpected syslog message %v", doc)
+	}
+}
+
+func TestStreamSpool(t *testing.T) {
+	dir := setup(t)
+	var mutex sync.Mutex
+	up := false
+	indexed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		indexed += strings.Count(string(data), "\n") / 2
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	cfg := StreamConfig{Type: StreamElasticsearch, URL: server.URL, BatchSize: 2, Retries: 2}
+	s, err := NewStream(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRows(t, dir, "Sample", []string{"a"}, [][]string{{"1"}, {"2"}, {"3"}}, s)
+	if n := s.spoolLength(); n != 3 {
+		t.Fatalf("expected 3 spooled rows, got %d", n)
+	}
+
+	// The next run sends the spooled rows before its own
+	mutex.Lock()
+	up = true
+	mutex.Unlock()
+	s, err = NewStream(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRows(t, dir, "Sample", []string{"a"}, [][]string{{"4"}}, s)
+	if indexed != 4 {
+		t.Errorf("indexed %d rows, expected 4", indexed)
+	}
+	if _, err := os.Stat(s.spool); !os.IsNotExist(err) {
+		t.Errorf("spool %s should be removed once sent", s.spool)
+	}
+}
+
+func TestStreamBackpressure(t *testing.T) {
+	dir := setup(t)
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	indexed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		data, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		indexed += strings.Count(string(data), "\n") / 2
+		mutex.Unlock()
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s, err := NewStream(StreamConfig{Type: StreamElasticsearch, URL: server.URL, BatchSize: 1, QueueSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	StartStreams([]*Stream{s})
+	mw, err := NewOrionWriter("Sample", "Orion_test", "csv", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw.WriteHeader([]string{"a"})
+	written := make(chan struct{})
+	go func() {
+		// one row in flight, one queued, the third waits for room in the queue
+		mw.WriteAll([][]string{{"1"}, {"2"}, {"3"}})
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("writer did not wait for the stream queue")
+	case <-time.After(100 * time.Millisecond):
+	}
+	release <- struct{}{}
+	release <- struct{}{}
+	<-written
+	release <- struct{}{}
+	mw.Close()
+	StopStreams()
+	if indexed != 3 {
+		t.Errorf("indexed %d rows, expected 3", indexed)
+	}
+}
@@ -0,0 +1,134 @@
+package datawriter
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// syslogPriority is facility local0 with severity informational
+const syslogPriority = 16*8 + 6
+
+// syslogBackend writes events as JSON over syslog (RFC 5424) or as plain JSON lines to tcp, udp or tls
+// Stream protocols frame messages with a newline, udp sends a datagram per event
+type syslogBackend struct {
+	cfg     StreamConfig
+	host    string
+	network string
+	address string
+	tls     *tls.Config
+	conn    net.Conn
+}
+
+func newSyslogBackend(cfg StreamConfig, host string) (backend, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" || u.Port() == "" {
+		return nil, fmt.Errorf("stream '%s': invalid syslog url '%s', expected tcp://, udp:// or tls://host:port", cfg.Name, cfg.URL)
+	}
+	b := &syslogBackend{cfg: cfg, host: host, address: u.Host}
+	switch strings.ToLower(u.Scheme) {
+	case "tcp", "udp":
+		b.network = strings.ToLower(u.Scheme)
+	case "tls":
+		b.network = "tcp"
+		b.tls = &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: cfg.InsecureSkipVerify}
+	default:
+		return nil, fmt.Errorf("stream '%s': unknown syslog protocol '%s', expected tcp, udp or tls", cfg.Name, u.Scheme)
+	}
+	b.cfg.Format = strings.ToLower(b.cfg.Format)
+	if b.cfg.Format == "" {
+		b.cfg.Format = FormatRFC5424
+	}
+	if b.cfg.Format != FormatRFC5424 && b.cfg.Format != FormatJSON {
+		return nil, fmt.Errorf("stream '%s': unknown syslog format '%s', expected rfc5424 or json", cfg.Name, cfg.Format)
+	}
+	return b, nil
+}
+
+func (b *syslogBackend) connect() error {
+	if b.conn != nil {
+		return nil
+	}
+	dialer := &net.Dialer{Timeout: time.Duration(b.cfg.Timeout) * time.Second}
+	var err error
+	if b.tls != nil {
+		b.conn, err = tls.DialWithDialer(dialer, b.network, b.address, b.tls)
+	} else {
+		b.conn, err = dialer.Dial(b.network, b.address)
+	}
+	return err
+}
+
+// message returns the syslog message of e, without framing
+func (b *syslogBackend) message(e event) ([]byte, error) {
+	doc, err := json.Marshal(e.document(b.host))
+	if err != nil {
+		return nil, err
+	}
+	if b.cfg.Format == FormatJSON {
+		return doc, nil
+	}
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	header := fmt.Sprintf("<%d>1 %s %s orion - %s - ", syslogPriority, e.Time.UTC().Format(time.RFC3339Nano), syslogField(b.host), syslogField(e.Module))
+	return append([]byte(header), doc...), nil
+}
+
+// syslogField replaces the characters RFC 5424 does not allow in header fields
+func syslogField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (b *syslogBackend) send(events []event) error {
+	if err := b.connect(); err != nil {
+		return err
+	}
+	if err := b.conn.SetWriteDeadline(time.Now().Add(time.Duration(b.cfg.Timeout) * time.Second)); err != nil {
+		b.close()
+		return err
+	}
+	w := bufio.NewWriter(b.conn)
+	for _, e := range events {
+		msg, err := b.message(e)
+		if err != nil {
+			return permanentError{err}
+		}
+		if b.network == "udp" {
+			_, err = b.conn.Write(msg)
+		} else {
+			_, err = w.Write(append(msg, '\n'))
+		}
+		if err != nil {
+			// The whole batch is sent again on a new connection, the server may receive some events twice
+			b.close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		b.close()
+		return err
+	}
+	return nil
+}
+
+func (b *syslogBackend) close() error {
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
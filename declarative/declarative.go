@@ -0,0 +1,194 @@
+// Package declarative runs modules described by a spec instead of Go code
+// A spec gives the path globs, the SQL query or plist key path, the output columns and their time conversions
+package declarative
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported spec types
+const (
+	TypeSQLite = "sqlite"
+	TypePlist  = "plist"
+)
+
+// Special column sources that do not come from the parsed file
+const (
+	SourceUser  = "$user"  // user name taken from the file path
+	SourcePath  = "$path"  // path of the parsed file
+	SourceKey   = "$key"   // dictionary key of the row when Each is set on a plist spec
+	SourceValue = "$value" // the row itself when the rows of a plist spec are not dictionaries
+)
+
+// Spec describes a declarative module
+// Paths use the same syntax as artifact definitions (ex. %%users.homedir%%), Artifact references an artifact definition instead
+type Spec struct {
+	Name        string   `toml:"name" yaml:"name"`
+	Description string   `toml:"description" yaml:"description"`
+	Author      string   `toml:"author" yaml:"author"`
+	Version     string   `toml:"version" yaml:"version"`
+	Mode        string   `toml:"mode" yaml:"mode"` // mac, windows or empty for both
+	Tags        []string `toml:"tags" yaml:"tags"` // module tags used for selection (ex. browser)
+	Type        string   `toml:"type" yaml:"type"` // sqlite or plist
+	Artifact    string   `toml:"artifact" yaml:"artifact"`
+	Paths       []string `toml:"paths" yaml:"paths"`
+	Query       string   `toml:"query" yaml:"query"` // sqlite only
+	Root        string   `toml:"root" yaml:"root"`   // plist only, dotted key path to the rows
+	Each        bool     `toml:"each" yaml:"each"`   // plist only, treat every value of the root dictionary as a row
+	Columns     []Column `toml:"columns" yaml:"columns"`
+	Timeline    Timeline `toml:"timeline" yaml:"timeline"`
+	DiffKeys    []string `toml:"diff_keys" yaml:"diff_keys"` // columns identifying a row for orion diff
+}
+
+// Column maps a query column or plist key path to an output column
+// Time is an optional timeconv kind (ex. cocoa, webkit, unix) used to convert the value
+type Column struct {
+	Name   string `toml:"name" yaml:"name"`
+	Source string `toml:"source" yaml:"source"`
+	Time   string `toml:"time" yaml:"time"`
+}
+
+// Timeline declares the event time columns of a declarative module for the super-timeline
+type Timeline struct {
+	Events            []timeline.Event `toml:"events" yaml:"events"`
+	UserField         string           `toml:"user_field" yaml:"user_field"`
+	DescriptionFields []string         `toml:"description_fields" yaml:"description_fields"`
+	Incremental       bool             `toml:"incremental" yaml:"incremental"`
+}
+
+// tomlFile is the layout of a TOML spec file, one [[module]] table per spec
+type tomlFile struct {
+	Module []Spec `toml:"module"`
+}
+
+// LoadFile loads the specs in a TOML ([[module]] tables) or YAML (one document per spec) file
+func LoadFile(fp string) ([]Spec, error) {
+	data, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return []Spec{}, err
+	}
+	var specs []Spec
+	switch strings.ToLower(filepath.Ext(fp)) {
+	case ".toml":
+		var f tomlFile
+		if _, err := toml.Decode(string(data), &f); err != nil {
+			return []Spec{}, fmt.Errorf("failed to parse '%s': %s", fp, err.Error())
+		}
+		specs = f.Module
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var s Spec
+			err := dec.Decode(&s)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return []Spec{}, fmt.Errorf("failed to parse '%s': %s", fp, err.Error())
+			}
+			if s.Name == "" && s.Type == "" {
+				continue // empty document
+			}
+			specs = append(specs, s)
+		}
+	default:
+		return []Spec{}, fmt.Errorf("unsupported declarative module file '%s', expected .toml, .yaml or .yml", fp)
+	}
+
+	for _, s := range specs {
+		if err := s.Validate(); err != nil {
+			return []Spec{}, fmt.Errorf("invalid declarative module in '%s': %s", fp, err.Error())
+		}
+	}
+	return specs, nil
+}
+
+// Validate returns an error if the spec cannot be run
+func (s Spec) Validate() error {
+	if s.Name == "" {
+		return errors.New("module is missing a name")
+	}
+	if s.Artifact == "" && len(s.Paths) == 0 {
+		return fmt.Errorf("%s: one of artifact or paths is required", s.Name)
+	}
+	switch s.Type {
+	case TypeSQLite:
+		if s.Query == "" {
+			return fmt.Errorf("%s: sqlite modules require a query", s.Name)
+		}
+	case TypePlist:
+	default:
+		return fmt.Errorf("%s: unsupported type '%s', expected %s or %s", s.Name, s.Type, TypeSQLite, TypePlist)
+	}
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("%s: at least one column is required", s.Name)
+	}
+	names := make(map[string]bool)
+	for _, c := range s.Columns {
+		if c.Name == "" {
+			return fmt.Errorf("%s: column is missing a name", s.Name)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("%s: duplicate column '%s'", s.Name, c.Name)
+		}
+		names[c.Name] = true
+		if c.Time != "" && !timeconv.ValidKind(c.Time) {
+			return fmt.Errorf("%s: column '%s' has unknown time kind '%s'", s.Name, c.Name, c.Time)
+		}
+	}
+	for _, e := range s.Timeline.Events {
+		if !names[e.Field] {
+			return fmt.Errorf("%s: timeline event field '%s' is not a column", s.Name, e.Field)
+		}
+	}
+	for _, k := range s.DiffKeys {
+		if !names[k] {
+			return fmt.Errorf("%s: diff key '%s' is not a column", s.Name, k)
+		}
+	}
+	return nil
+}
+
+// ArtifactName returns the artifact definition the spec collects paths from
+// Specs with inline paths are registered as an artifact definition named after the module
+func (s Spec) ArtifactName() string {
+	if s.Artifact != "" {
+		return s.Artifact
+	}
+	return s.Name
+}
+
+// Header returns the output header of the spec
+func (s Spec) Header() []string {
+	header := []string{}
+	for _, c := range s.Columns {
+		header = append(header, c.Name)
+	}
+	return header
+}
+
+// TimelineDefinitions returns the super-timeline definitions of the spec, empty if it declares no events
+func (s Spec) TimelineDefinitions() []timeline.Definition {
+	if len(s.Timeline.Events) == 0 {
+		return []timeline.Definition{}
+	}
+	return []timeline.Definition{
+		{
+			Output:            s.Name,
+			Events:            s.Timeline.Events,
+			UserField:         s.Timeline.UserField,
+			DescriptionFields: s.Timeline.DescriptionFields,
+			Incremental:       s.Timeline.Incremental,
+		},
+	}
+}
@@ -0,0 +1,210 @@
+package declarative
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/anthonybm/Orion/timeline"
+)
+
+const testPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>lastUserName</key>
+	<string>alice</string>
+	<key>persistent-apps</key>
+	<array>
+		<dict>
+			<key>tile-data</key>
+			<dict>
+				<key>file-label</key>
+				<string>Safari</string>
+				<key>added</key>
+				<real>624741732</real>
+			</dict>
+		</dict>
+		<dict>
+			<key>tile-data</key>
+			<dict>
+				<key>file-label</key>
+				<string>Mail</string>
+			</dict>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "declarative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tomlSpec := filepath.Join(dir, "spec.toml")
+	ioutil.WriteFile(tomlSpec, []byte(`
+[[module]]
+name = "TestModule"
+type = "plist"
+paths = ["/Library/Preferences/test.plist"]
+columns = [{name = "user", source = "$user"}, {name = "added", time = "cocoa"}]
+[module.timeline]
+events = [{field = "added", type = "Added"}]
+`), 0600)
+	specs, err := LoadFile(tomlSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 1 || specs[0].Name != "TestModule" || !reflect.DeepEqual(specs[0].Header(), []string{"user", "added"}) {
+		t.Errorf("LoadFile(toml) = %+v", specs)
+	}
+	if defs := specs[0].TimelineDefinitions(); len(defs) != 1 || defs[0].Events[0].Type != "Added" {
+		t.Errorf("TimelineDefinitions() = %+v", defs)
+	}
+
+	yamlSpec := filepath.Join(dir, "spec.yaml")
+	ioutil.WriteFile(yamlSpec, []byte("name: A\ntype: sqlite\nartifact: X\nquery: SELECT 1\ncolumns: [{name: a}]\n---\nname: B\ntype: plist\npaths: [x]\ncolumns: [{name: b}]\n"), 0600)
+	specs, err = LoadFile(yamlSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0].ArtifactName() != "X" || specs[1].ArtifactName() != "B" {
+		t.Errorf("LoadFile(yaml) = %+v", specs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Spec{Name: "A", Type: TypeSQLite, Paths: []string{"x"}, Query: "SELECT 1", Columns: []Column{{Name: "a"}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() returned error for a valid spec: %s", err)
+	}
+	tests := map[string]func(s *Spec){
+		"no name":        func(s *Spec) { s.Name = "" },
+		"no paths":       func(s *Spec) { s.Paths = nil },
+		"bad type":       func(s *Spec) { s.Type = "xml" },
+		"no query":       func(s *Spec) { s.Query = "" },
+		"no columns":     func(s *Spec) { s.Columns = nil },
+		"duplicate":      func(s *Spec) { s.Columns = append(s.Columns, Column{Name: "a"}) },
+		"bad time kind":  func(s *Spec) { s.Columns[0].Time = "stardate" },
+		"unknown event":  func(s *Spec) { s.Timeline.Events = append(s.Timeline.Events, timeline.Event{Field: "b"}) },
+		"unnamed column": func(s *Spec) { s.Columns = []Column{{Source: "a"}} },
+		"unknown key":    func(s *Spec) { s.DiffKeys = []string{"b"} },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := valid
+			s.Columns = append([]Column{}, valid.Columns...)
+			mutate(&s)
+			if err := s.Validate(); err == nil {
+				t.Error("Validate() expected an error")
+			}
+		})
+	}
+}
+
+func TestParsePlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "declarative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "Users", "alice", "com.apple.dock.plist")
+	os.MkdirAll(filepath.Dir(fp), 0700)
+	ioutil.WriteFile(fp, []byte(testPlist), 0600)
+
+	s := Spec{
+		Name: "Dock",
+		Type: TypePlist,
+		Root: "persistent-apps",
+		Columns: []Column{
+			{Name: "user", Source: SourceUser},
+			{Name: "label", Source: "tile-data.file-label"},
+			{Name: "added", Source: "tile-data.added", Time: "cocoa"},
+		},
+	}
+	got, err := s.Parse(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"alice", "Safari", "2020-10-18T19:22:12Z"},
+		{"alice", "Mail", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+
+	s = Spec{Name: "LoginWindow", Type: TypePlist, Columns: []Column{{Name: "lastUserName"}, {Name: "first", Source: "persistent-apps.0.tile-data.file-label"}}}
+	got, err = s.Parse(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, [][]string{{"alice", "Safari"}}) {
+		t.Errorf("Parse() = %v", got)
+	}
+}
+
+func TestParseSQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "declarative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "History")
+	db, err := sql.Open("sqlite3", fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"CREATE TABLE urls (id INTEGER, url TEXT, title TEXT, last_visit_time INTEGER)",
+		"INSERT INTO urls VALUES (1, 'https://example.com', 'Example', 13247522532000000)",
+		"INSERT INTO urls VALUES (2, 'https://example.org', NULL, 0)",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	s := Spec{
+		Name:  "History",
+		Type:  TypeSQLite,
+		Query: "SELECT id, url, title, last_visit_time AS visited FROM urls ORDER BY id",
+		Columns: []Column{
+			{Name: "id"},
+			{Name: "visited", Time: "webkit"},
+			{Name: "link", Source: "url"},
+			{Name: "title"},
+		},
+	}
+	got, err := s.Parse(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"1", "2020-10-18T19:22:12Z", "https://example.com", "Example"},
+		{"2", "", "https://example.org", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+}
+
+func TestExampleSpecs(t *testing.T) {
+	for _, fp := range []string{"../configs/declarative/mac.toml", "../configs/declarative/windows.yaml"} {
+		specs, err := LoadFile(fp)
+		if err != nil || len(specs) == 0 {
+			t.Fatalf("LoadFile(%s) = %v, %v", fp, specs, err)
+		}
+		for _, s := range specs {
+			if err := s.Validate(); err != nil {
+				t.Errorf("%s: %s", s.Name, err)
+			}
+		}
+	}
+}
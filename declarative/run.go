@@ -0,0 +1,213 @@
+package declarative
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+	"howett.net/plist"
+)
+
+// Run parses every file with the spec and writes the rows with mw, the writer is closed when done
+func Run(s Spec, files []string, mw datawriter.OrionWriter) error {
+	values := [][]string{}
+	count := 0
+	for _, file := range files {
+		rows, err := s.Parse(file)
+		if err != nil {
+			zap.L().Error("failed to parse '"+file+"': "+err.Error(), zap.String("module", s.Name))
+			continue
+		}
+		count++
+		values = append(values, rows...)
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] rows from [%d/%d] files", len(values), count, len(files)), zap.String("module", s.Name))
+
+	err := mw.WriteHeader(s.Header())
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// Parse returns the output rows of a single file
+func (s Spec) Parse(fp string) ([][]string, error) {
+	var records []map[string]interface{}
+	var err error
+	switch s.Type {
+	case TypeSQLite:
+		records, err = s.sqliteRecords(fp)
+	case TypePlist:
+		records, err = s.plistRecords(fp)
+	default:
+		err = fmt.Errorf("unsupported type '%s'", s.Type)
+	}
+	if err != nil {
+		return [][]string{}, err
+	}
+
+	rows := [][]string{}
+	for _, record := range records {
+		valmap := make(map[string]string)
+		valmap = util.InitializeMapToEmptyString(valmap, s.Header())
+		for _, c := range s.Columns {
+			var raw interface{}
+			switch c.Source {
+			case SourceUser:
+				raw = util.GetUsernameFromPath(fp)
+			case SourcePath:
+				raw = fp
+			default:
+				source := c.Source
+				if source == "" {
+					source = c.Name
+				}
+				raw = lookup(record, source)
+			}
+			valmap[c.Name] = s.value(c, raw)
+		}
+		entry, err := util.EntryFromMap(valmap, s.Header())
+		if err != nil {
+			zap.L().Error("failed to create entry from map: "+err.Error(), zap.String("module", s.Name))
+			continue
+		}
+		rows = append(rows, entry)
+	}
+	return rows, nil
+}
+
+// value converts a raw value of column c to its output string
+func (s Spec) value(c Column, raw interface{}) string {
+	if raw == nil {
+		return ""
+	}
+	if c.Time != "" {
+		t, err := timeconv.ConvertToString(timeconv.Kind(c.Time), raw)
+		if err != nil {
+			zap.L().Debug("failed to convert '"+c.Name+"': "+err.Error(), zap.String("module", s.Name))
+			str, _ := util.InterfaceToString(raw)
+			return str + "<FAILED TO CONVERT>"
+		}
+		return t
+	}
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return util.MapToJSONString(v)
+	case uint64:
+		return fmt.Sprintf("%d", v)
+	case int64:
+		return fmt.Sprintf("%d", v)
+	case float64:
+		return fmt.Sprintf("%v", v)
+	case []byte:
+		return fmt.Sprintf("b64:%s", base64.StdEncoding.EncodeToString(v))
+	}
+	str, err := util.InterfaceToString(raw)
+	if err != nil {
+		return fmt.Sprintf("%v", raw)
+	}
+	return str
+}
+
+func (s Spec) sqliteRecords(fp string) ([]map[string]interface{}, error) {
+	return util.UnsafeQueryDBToMap(fp, s.Query)
+}
+
+func (s Spec) plistRecords(fp string) ([]map[string]interface{}, error) {
+	f, err := throttle.Open(fp)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	defer f.Close()
+
+	var data interface{}
+	err = plist.NewDecoder(f).Decode(&data)
+	if err != nil {
+		return []map[string]interface{}{}, fmt.Errorf("could not decode plist: %s", err.Error())
+	}
+	if s.Root != "" {
+		data = lookup(data, s.Root)
+		if data == nil {
+			zap.L().Debug("Root '"+s.Root+"' not found in '"+fp+"'", zap.String("module", s.Name))
+			return []map[string]interface{}{}, nil
+		}
+	}
+
+	records := []map[string]interface{}{}
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			records = append(records, record(item))
+		}
+	case map[string]interface{}:
+		if !s.Each {
+			return []map[string]interface{}{v}, nil
+		}
+		keys := []string{}
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			r := record(v[k])
+			r[SourceKey] = k
+			records = append(records, r)
+		}
+	default:
+		records = append(records, record(v))
+	}
+	return records, nil
+}
+
+// record returns item as a record, non dictionary items are stored under the SourceValue key
+func record(item interface{}) map[string]interface{} {
+	if m, ok := item.(map[string]interface{}); ok {
+		r := make(map[string]interface{}, len(m)+1)
+		for k, v := range m {
+			r[k] = v
+		}
+		return r
+	}
+	return map[string]interface{}{SourceValue: item}
+}
+
+// lookup returns the value at a dotted key path, numeric components index arrays
+// A key that itself contains a dot is matched before the path is split
+func lookup(data interface{}, path string) interface{} {
+	if path == "" {
+		return data
+	}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if val, ok := v[path]; ok {
+			return val
+		}
+		parts := strings.SplitN(path, ".", 2)
+		val, ok := v[parts[0]]
+		if !ok || len(parts) == 1 {
+			return val
+		}
+		return lookup(val, parts[1])
+	case []interface{}:
+		parts := strings.SplitN(path, ".", 2)
+		var index int
+		if _, err := fmt.Sscanf(parts[0], "%d", &index); err != nil || index < 0 || index >= len(v) {
+			return nil
+		}
+		if len(parts) == 1 {
+			return v[index]
+		}
+		return lookup(v[index], parts[1])
+	}
+	return nil
+}
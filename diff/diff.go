@@ -0,0 +1,355 @@
+// Package diff compares the output of two Orion runs module by module, matching rows on the key columns
+// each module declares so items are reported as added, removed or changed
+package diff
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anthonybm/Orion/baseline"
+)
+
+// Change types
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Output statuses
+const (
+	StatusCompared = "compared"
+	StatusOnlyA    = "only in run A"
+	StatusOnlyB    = "only in run B"
+)
+
+// Keys names the columns identifying a row of a module output, rows with the same key are the same item in both runs
+// Ignore lists columns expected to differ between runs (ex. atime) that do not make a row changed
+type Keys struct {
+	Output  string
+	Columns []string
+	Ignore  []string
+}
+
+// Field is a column whose value changed
+type Field struct {
+	Column string `json:"column"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Change is a row added, removed or changed between run A and run B
+// Row is the added row, the removed row or the changed row as found in run B
+type Change struct {
+	Type   string            `json:"type"`
+	Key    map[string]string `json:"key,omitempty"`
+	Row    map[string]string `json:"row"`
+	Fields []Field           `json:"fields,omitempty"`
+}
+
+// Output is the comparison of one output file
+// Skipped counts the rows of run A left out of run B because run B was collected incrementally with --baseline
+type Output struct {
+	Module  string   `json:"module"`
+	Output  string   `json:"output"`
+	Keys    []string `json:"keys,omitempty"`
+	Columns []string `json:"columns"`
+	Status  string   `json:"status"`
+	RowsA   int      `json:"rowsA"`
+	RowsB   int      `json:"rowsB"`
+	Added   int      `json:"added"`
+	Removed int      `json:"removed"`
+	Changed int      `json:"changed"`
+	Skipped int      `json:"skipped,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+// Run describes a compared run
+type Run struct {
+	Path    string `json:"path"`
+	Runtime string `json:"runtime"`
+	Host    string `json:"host,omitempty"`
+}
+
+// Report is the comparison of two runs
+type Report struct {
+	RunA    Run      `json:"runA"`
+	RunB    Run      `json:"runB"`
+	Outputs []Output `json:"outputs"`
+}
+
+// Compare compares every output of runs a and b, keys gives the key columns of outputs by output name and is
+// overridden by the keys recorded in the run manifests
+// only limits the comparison to the given modules or outputs, empty compares everything
+func Compare(a *baseline.Baseline, b *baseline.Baseline, keys map[string]Keys, only []string) (Report, error) {
+	report := Report{RunA: describe(a), RunB: describe(b), Outputs: []Output{}}
+
+	outputsA, outputsB := outputs(a), outputs(b)
+	selectedOutputs := map[string]bool{}
+	for name := range outputsA {
+		if selected(name, only) {
+			selectedOutputs[name] = true
+		}
+	}
+	for name := range outputsB {
+		if selected(name, only) {
+			selectedOutputs[name] = true
+		}
+	}
+	names := []string{}
+	for name := range selectedOutputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		k := outputKeys(name, a, b, keys)
+		var out Output
+		switch {
+		case !outputsA[name]:
+			header, rows, err := b.Rows(name)
+			if err != nil {
+				return report, err
+			}
+			out = Output{Status: StatusOnlyB, Columns: header, RowsB: len(rows), Changes: []Change{}}
+		case !outputsB[name]:
+			header, rows, err := a.Rows(name)
+			if err != nil {
+				return report, err
+			}
+			out = Output{Status: StatusOnlyA, Columns: header, RowsA: len(rows), Changes: []Change{}}
+		default:
+			headerA, rowsA, err := a.Rows(name)
+			if err != nil {
+				return report, err
+			}
+			headerB, rowsB, err := b.Rows(name)
+			if err != nil {
+				return report, err
+			}
+			out = Rows(headerA, rowsA, headerB, rowsB, k)
+			dropSkipped(&out, headerA, name, a, b)
+		}
+		out.Module = ModuleName(name)
+		out.Output = name
+		if k != nil {
+			out.Keys = k.Columns
+		}
+		report.Outputs = append(report.Outputs, out)
+	}
+	return report, nil
+}
+
+// outputs returns the module outputs of a run to compare, those listed by its manifest when it has one
+func outputs(run *baseline.Baseline) map[string]bool {
+	names := map[string]bool{}
+	m := run.Manifest()
+	for _, name := range run.Outputs() {
+		if m != nil {
+			if _, ok := m.Outputs[name]; !ok {
+				continue
+			}
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// dropSkipped removes the rows reported as removed that run b left out of an incremental output because its
+// baseline already had them, the removed rows are read from run a in its timestamp format
+func dropSkipped(out *Output, headerA []string, output string, a *baseline.Baseline, b *baseline.Baseline) {
+	m := b.Manifest()
+	if m == nil {
+		return
+	}
+	described, ok := m.Outputs[output]
+	if !ok || described.Since == nil {
+		return
+	}
+	changes := []Change{}
+	for _, c := range out.Changes {
+		if c.Type == Removed && described.Skipped(headerA, rowValues(headerA, c.Row), a.TimestampFormat()) {
+			out.Removed--
+			out.Skipped++
+			continue
+		}
+		changes = append(changes, c)
+	}
+	out.Changes = changes
+}
+
+// describe returns the description of a run for the report
+func describe(b *baseline.Baseline) Run {
+	run := Run{Path: b.Path(), Runtime: b.Runtime()}
+	if m := b.Manifest(); m != nil {
+		run.Host = m.Host
+	}
+	return run
+}
+
+// selected returns true when output, or the module writing it, is in only
+func selected(output string, only []string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, name := range only {
+		if strings.EqualFold(name, output) || strings.EqualFold(name, ModuleName(output)) {
+			return true
+		}
+	}
+	return false
+}
+
+// outputKeys returns the keys of output recorded in the manifest of b or a, then those in keys, nil when the
+// output has none
+func outputKeys(output string, a *baseline.Baseline, b *baseline.Baseline, keys map[string]Keys) *Keys {
+	for _, run := range []*baseline.Baseline{b, a} {
+		if m := run.Manifest(); m != nil {
+			if out, ok := m.Outputs[output]; ok && len(out.Keys) > 0 {
+				return &Keys{Output: output, Columns: out.Keys, Ignore: out.Ignore}
+			}
+		}
+	}
+	if k, ok := keys[output]; ok && len(k.Columns) > 0 {
+		return &k
+	}
+	return nil
+}
+
+// ModuleName returns the module writing output, outputs of modules with several are named <module>-<output>
+func ModuleName(output string) string {
+	return strings.SplitN(output, "-", 2)[0]
+}
+
+// Rows compares the rows of an output in run A and run B
+// Rows are matched on the key columns when keys is set and every key column is in both headers, otherwise
+// whole rows are matched and only reported as added or removed
+func Rows(headerA []string, rowsA [][]string, headerB []string, rowsB [][]string, keys *Keys) Output {
+	out := Output{Status: StatusCompared, Columns: headerB, RowsA: len(rowsA), RowsB: len(rowsB), Changes: []Change{}}
+	if keys == nil || len(keys.Columns) == 0 || !hasColumns(headerA, keys.Columns) || !hasColumns(headerB, keys.Columns) {
+		added, removed := baseline.Diff(headerA, rowsA, headerB, rowsB)
+		for _, row := range added {
+			out.Changes = append(out.Changes, Change{Type: Added, Row: rowMap(headerB, row)})
+		}
+		for _, row := range removed {
+			out.Changes = append(out.Changes, Change{Type: Removed, Row: rowMap(headerA, row)})
+		}
+		out.Added, out.Removed = len(added), len(removed)
+		return out
+	}
+
+	// columns compared for changes, in run B order
+	skip := map[string]bool{}
+	for _, c := range append(append([]string{}, keys.Columns...), keys.Ignore...) {
+		skip[c] = true
+	}
+	compared := []string{}
+	for _, c := range headerB {
+		if !skip[c] && indexOf(headerA, c) >= 0 {
+			compared = append(compared, c)
+		}
+	}
+
+	// rows of run A by key, rows sharing a key are matched in order
+	pending := map[string][]int{}
+	for i, row := range rowsA {
+		k := keyOf(headerA, row, keys.Columns)
+		pending[k] = append(pending[k], i)
+	}
+	matched := make([]bool, len(rowsA))
+	for _, row := range rowsB {
+		k := keyOf(headerB, row, keys.Columns)
+		if len(pending[k]) == 0 {
+			out.Changes = append(out.Changes, Change{Type: Added, Key: keyMap(headerB, row, keys.Columns), Row: rowMap(headerB, row)})
+			out.Added++
+			continue
+		}
+		i := pending[k][0]
+		pending[k] = pending[k][1:]
+		matched[i] = true
+		fields := []Field{}
+		for _, c := range compared {
+			before, after := value(headerA, rowsA[i], c), value(headerB, row, c)
+			if before != after {
+				fields = append(fields, Field{Column: c, Before: before, After: after})
+			}
+		}
+		if len(fields) > 0 {
+			out.Changes = append(out.Changes, Change{Type: Changed, Key: keyMap(headerB, row, keys.Columns), Row: rowMap(headerB, row), Fields: fields})
+			out.Changed++
+		}
+	}
+	for i, row := range rowsA {
+		if !matched[i] {
+			out.Changes = append(out.Changes, Change{Type: Removed, Key: keyMap(headerA, row, keys.Columns), Row: rowMap(headerA, row)})
+			out.Removed++
+		}
+	}
+	return out
+}
+
+func hasColumns(header []string, columns []string) bool {
+	for _, c := range columns {
+		if indexOf(header, c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(header []string, column string) int {
+	for i, h := range header {
+		if h == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// value returns the value of column in row, empty when the row is short
+func value(header []string, row []string, column string) string {
+	i := indexOf(header, column)
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+func keyOf(header []string, row []string, columns []string) string {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = value(header, row, c)
+	}
+	return strings.Join(values, "\x1f")
+}
+
+func keyMap(header []string, row []string, columns []string) map[string]string {
+	m := make(map[string]string, len(columns))
+	for _, c := range columns {
+		m[c] = value(header, row, c)
+	}
+	return m
+}
+
+// rowValues returns the values of a row returned by rowMap in the order of header
+func rowValues(header []string, row map[string]string) []string {
+	values := make([]string, len(header))
+	for i, c := range header {
+		values[i] = row[c]
+	}
+	return values
+}
+
+// rowMap returns row by column name, extra values are named column_<n>
+func rowMap(header []string, row []string) map[string]string {
+	m := make(map[string]string, len(row))
+	for i, v := range row {
+		name := "column_" + strconv.Itoa(i+1)
+		if i < len(header) && header[i] != "" {
+			name = header[i]
+		}
+		m[name] = v
+	}
+	return m
+}
@@ -0,0 +1,264 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthonybm/Orion/baseline"
+)
+
+var autorunKeys = &Keys{Output: "MacAutorunsModule", Columns: []string{"source_file", "program"}, Ignore: []string{"atime"}}
+
+func TestRowsKeyed(t *testing.T) {
+	headerA := []string{"source_file", "program", "atime", "sha256"}
+	rowsA := [][]string{
+		{"/Library/LaunchAgents/a.plist", "/bin/a", "1", "aaaa"},
+		{"/Library/LaunchAgents/b.plist", "/bin/b", "1", "bbbb"},
+		{"/Library/LaunchAgents/c.plist", "/bin/c", "1", "cccc"},
+	}
+	// columns may be reordered and added between versions
+	headerB := []string{"program", "source_file", "sha256", "atime", "signed"}
+	rowsB := [][]string{
+		{"/bin/a", "/Library/LaunchAgents/a.plist", "aaaa", "2", "yes"},
+		{"/bin/b", "/Library/LaunchAgents/b.plist", "ffff", "1", "no"},
+		{"/bin/evil", "/Library/LaunchAgents/evil.plist", "eeee", "1", "no"},
+	}
+
+	out := Rows(headerA, rowsA, headerB, rowsB, autorunKeys)
+	if out.Added != 1 || out.Removed != 1 || out.Changed != 1 || len(out.Changes) != 3 {
+		t.Fatalf("Rows() = %+v", out)
+	}
+	changed := out.Changes[0]
+	if changed.Type != Changed || changed.Key["program"] != "/bin/b" {
+		t.Errorf("changes[0] = %+v", changed)
+	}
+	// atime is ignored and signed is only in run B, so only sha256 changed
+	if want := []Field{{Column: "sha256", Before: "bbbb", After: "ffff"}}; !reflect.DeepEqual(changed.Fields, want) {
+		t.Errorf("changed fields = %+v, want %+v", changed.Fields, want)
+	}
+	if out.Changes[1].Type != Added || out.Changes[1].Row["program"] != "/bin/evil" {
+		t.Errorf("changes[1] = %+v", out.Changes[1])
+	}
+	if out.Changes[2].Type != Removed || out.Changes[2].Key["source_file"] != "/Library/LaunchAgents/c.plist" {
+		t.Errorf("changes[2] = %+v", out.Changes[2])
+	}
+}
+
+func TestRowsDuplicateKeys(t *testing.T) {
+	header := []string{"user", "fingerprint", "host"}
+	keys := &Keys{Columns: []string{"user", "fingerprint"}}
+	rowsA := [][]string{{"bob", "SHA256:x", "a"}, {"bob", "SHA256:x", "b"}}
+	rowsB := [][]string{{"bob", "SHA256:x", "a"}}
+	out := Rows(header, rowsA, header, rowsB, keys)
+	if out.Removed != 1 || out.Added != 0 || out.Changed != 0 || out.Changes[0].Row["host"] != "b" {
+		t.Errorf("Rows() = %+v", out)
+	}
+}
+
+func TestRowsWithoutKeys(t *testing.T) {
+	header := []string{"a", "b"}
+	rowsA := [][]string{{"1", "2"}, {"3", "4"}}
+	rowsB := [][]string{{"1", "2"}, {"3", "5"}}
+	// no keys and key columns missing from a header both fall back to whole rows
+	for _, keys := range []*Keys{nil, {Columns: []string{"missing"}}} {
+		out := Rows(header, rowsA, header, rowsB, keys)
+		if out.Added != 1 || out.Removed != 1 || out.Changed != 0 {
+			t.Errorf("Rows(%v) = %+v", keys, out)
+		}
+	}
+}
+
+// writeRun writes a run with outputs to dir, a manifest listing every output and the keys is written when keys is set
+func writeRun(t *testing.T, dir string, runtime string, outputs map[string]string, keys map[string][]string) {
+	os.MkdirAll(dir, 0700)
+	for output, content := range outputs {
+		if err := ioutil.WriteFile(filepath.Join(dir, runtime+"_"+output+".csv"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys == nil {
+		return
+	}
+	m := &baseline.Manifest{Runtime: runtime, Host: "suspect", Outputs: map[string]baseline.Output{}}
+	for output := range outputs {
+		m.Outputs[output] = baseline.Output{Keys: keys[output]}
+	}
+	if _, err := m.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeRun(t, filepath.Join(dir, "gold"), "Orion_2020-08-01T10_00_00Z", map[string]string{
+		"MacUsersModule":          "user,admin\nbob,false\nalice,true\n",
+		"MacAutorunsModule":       "source_file,program,atime\n/a.plist,/bin/a,1\n",
+		"MacChromeModule-history": "url\nhttps://a.example\n",
+	}, nil)
+	writeRun(t, filepath.Join(dir, "suspect"), "Orion_2020-08-02T10_00_00Z", map[string]string{
+		"MacUsersModule":          "user,admin\nbob,true\nalice,true\nmallory,true\n",
+		"MacAutorunsModule":       "source_file,program,atime\n/a.plist,/bin/a,2\n/<script>.plist,/bin/x,1\n",
+		"MacChromeModule-history": "url\nhttps://a.example\n",
+		"MacSSHModule":            "source_name,user,fingerprint\nauthorized_keys,bob,SHA256:y\n",
+	}, map[string][]string{"MacUsersModule": {"user"}})
+
+	a, err := baseline.Open(filepath.Join(dir, "gold"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := baseline.Open(filepath.Join(dir, "suspect"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Compare(a, b, map[string]Keys{autorunKeys.Output: *autorunKeys}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.RunB.Host != "suspect" || len(report.Outputs) != 4 {
+		t.Fatalf("Compare() = %+v", report)
+	}
+	byOutput := map[string]Output{}
+	for _, out := range report.Outputs {
+		byOutput[out.Output] = out
+	}
+	// users are keyed by the manifest, autoruns by the registered keys
+	if users := byOutput["MacUsersModule"]; users.Added != 1 || users.Changed != 1 || users.Removed != 0 || !reflect.DeepEqual(users.Keys, []string{"user"}) {
+		t.Errorf("users = %+v", users)
+	}
+	if autoruns := byOutput["MacAutorunsModule"]; autoruns.Added != 1 || autoruns.Changed != 0 {
+		t.Errorf("autoruns = %+v", autoruns)
+	}
+	if history := byOutput["MacChromeModule-history"]; len(history.Changes) != 0 || history.Module != "MacChromeModule" {
+		t.Errorf("history = %+v", history)
+	}
+	if ssh := byOutput["MacSSHModule"]; ssh.Status != StatusOnlyB || ssh.RowsB != 1 {
+		t.Errorf("ssh = %+v", ssh)
+	}
+
+	only, _ := Compare(a, b, nil, []string{"macchromemodule"})
+	if len(only.Outputs) != 1 || only.Outputs[0].Output != "MacChromeModule-history" {
+		t.Errorf("Compare(only) = %+v", only.Outputs)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, report, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"MacUsersModule,MacUsersModule,changed,user: bob,admin,false,true",
+		"MacUsersModule,MacUsersModule,added,user: mallory,,,user: mallory; admin: true",
+		"MacSSHModule,MacSSHModule,only in run B,,,,",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("csv report is missing %q:\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := Write(&buf, report, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Outputs) != 4 {
+		t.Errorf("json report = %v, %v", decoded, err)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, report, "html"); err != nil {
+		t.Fatal(err)
+	}
+	if html := buf.String(); strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") || !strings.Contains(html, `id="MacUsersModule"`) {
+		t.Errorf("html report does not escape values or misses sections:\n%s", html)
+	}
+
+	if err := Write(&buf, report, "xml"); err == nil {
+		t.Error("Write(xml) expected an error")
+	}
+}
+
+func TestCompareDerivedAndUnlisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	users := "user,admin\nbob,false\n"
+	writeRun(t, filepath.Join(dir, "gold"), "Orion_2020-08-01T10_00_00Z", map[string]string{
+		"MacUsersModule": users,
+		"Timeline":       "timestamp,module\n2020-07-31T21:15:00Z,MacUsersModule\n",
+		"Timeline.l2t":   "date,time\n07/31/2020,21:15:00\n",
+	}, nil)
+	suspect := filepath.Join(dir, "suspect")
+	writeRun(t, suspect, "Orion_2020-08-02T10_00_00Z", map[string]string{"MacUsersModule": users}, map[string][]string{})
+	// the baseline diff is derived and a csv left in the output directory is not listed by the manifest
+	writeRun(t, suspect, "Orion_2020-08-02T10_00_00Z", map[string]string{
+		baseline.DiffOutput: "module,output,change,row\n",
+		"MacStaleModule":    "a\n1\n",
+	}, nil)
+
+	a, _ := baseline.Open(filepath.Join(dir, "gold"))
+	b, _ := baseline.Open(suspect)
+	report, err := Compare(a, b, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Outputs) != 1 || report.Outputs[0].Output != "MacUsersModule" || len(report.Outputs[0].Changes) != 0 {
+		t.Errorf("Compare() = %+v, want only MacUsersModule unchanged", report.Outputs)
+	}
+}
+
+func TestCompareIncremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeRun(t, filepath.Join(dir, "gold"), "Orion_2020-08-01T10_00_00Z", map[string]string{
+		"MacChromeModule-history": "user,visit_time,url\n" +
+			"bob,2020-07-30T08:00:00Z,https://a.example\n" +
+			"bob,2020-07-31T21:15:00Z,https://b.example\n" +
+			"bob,2020-08-01T09:00:00Z,https://deleted.example\n" +
+			"bob,,https://c.example\n",
+	}, nil)
+	// run B was collected against a baseline with a high-water mark of 2020-07-31T21:15:00Z, so the first two rows
+	// were left out, the later row and the row without a time should have been written and were removed
+	suspect := filepath.Join(dir, "suspect")
+	runtime := "Orion_2020-08-02T10_00_00Z"
+	writeRun(t, suspect, runtime, map[string]string{
+		"MacChromeModule-history": "user,visit_time,url\nbob,2020-08-02T08:00:00Z,https://d.example\n",
+	}, nil)
+	since := time.Date(2020, 7, 31, 21, 15, 0, 0, time.UTC)
+	m := &baseline.Manifest{Runtime: runtime, Outputs: map[string]baseline.Output{
+		"MacChromeModule-history": {Since: &since, EventFields: []string{"visit_time"}},
+	}}
+	if _, err := m.Write(suspect); err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := baseline.Open(filepath.Join(dir, "gold"))
+	b, _ := baseline.Open(suspect)
+	report, err := Compare(a, b, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := report.Outputs[0]
+	if history.Added != 1 || history.Removed != 2 || history.Skipped != 2 || len(history.Changes) != 3 {
+		t.Fatalf("history = %+v", history)
+	}
+	for _, c := range history.Changes {
+		if c.Type == Removed && c.Row["url"] != "https://deleted.example" && c.Row["url"] != "https://c.example" {
+			t.Errorf("row %v left out by the baseline is reported as removed", c.Row)
+		}
+	}
+}
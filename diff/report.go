@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Formats lists the supported report formats
+var Formats = []string{"csv", "json", "html"}
+
+// Write writes report to w in format
+func Write(w io.Writer, report Report, format string) error {
+	switch format {
+	case "csv":
+		return WriteCSV(w, report)
+	case "json":
+		return WriteJSON(w, report)
+	case "html":
+		return WriteHTML(w, report)
+	}
+	return errors.New("unsupported diff format '" + format + "', expected one of " + strings.Join(Formats, ","))
+}
+
+// WriteCSV writes one line per added or removed row and one line per changed column of a changed row
+func WriteCSV(w io.Writer, report Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"module", "output", "change", "key", "column", "before", "after"})
+	for _, out := range report.Outputs {
+		switch out.Status {
+		case StatusOnlyA, StatusOnlyB:
+			cw.Write([]string{out.Module, out.Output, out.Status, "", "", "", ""})
+			continue
+		}
+		for _, c := range out.Changes {
+			key := FormatRow(out.Keys, c.Key)
+			switch c.Type {
+			case Added:
+				cw.Write([]string{out.Module, out.Output, c.Type, key, "", "", FormatRow(out.Columns, c.Row)})
+			case Removed:
+				cw.Write([]string{out.Module, out.Output, c.Type, key, "", FormatRow(out.Columns, c.Row), ""})
+			case Changed:
+				for _, f := range c.Fields {
+					cw.Write([]string{out.Module, out.Output, c.Type, key, f.Column, f.Before, f.After})
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report as an indented JSON document
+func WriteJSON(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// FormatRow renders row as "column: value; ..." in the order of columns followed by any other column, leaving
+// out empty values
+func FormatRow(columns []string, row map[string]string) string {
+	parts := []string{}
+	seen := map[string]bool{}
+	for _, c := range columns {
+		seen[c] = true
+		if row[c] != "" {
+			parts = append(parts, c+": "+row[c])
+		}
+	}
+	others := []string{}
+	for c := range row {
+		if !seen[c] && row[c] != "" {
+			others = append(others, c)
+		}
+	}
+	sort.Strings(others)
+	for _, c := range others {
+		parts = append(parts, c+": "+row[c])
+	}
+	return strings.Join(parts, "; ")
+}
+
+var htmlReport = template.Must(template.New("diff").Funcs(template.FuncMap{"row": FormatRow}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Orion diff {{.RunA.Runtime}} / {{.RunB.Runtime}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.added { background: #e6ffed; }
+.removed { background: #ffeef0; }
+.changed { background: #fff5b1; }
+td.value { font-family: monospace; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>Orion diff</h1>
+<table>
+<tr><th></th><th>Runtime</th><th>Host</th><th>Path</th></tr>
+<tr><th>Run A</th><td>{{.RunA.Runtime}}</td><td>{{.RunA.Host}}</td><td>{{.RunA.Path}}</td></tr>
+<tr><th>Run B</th><td>{{.RunB.Runtime}}</td><td>{{.RunB.Host}}</td><td>{{.RunB.Path}}</td></tr>
+</table>
+<h2>Summary</h2>
+<table>
+<tr><th>Output</th><th>Keys</th><th>Status</th><th>Rows A</th><th>Rows B</th><th>Added</th><th>Removed</th><th>Changed</th><th>Skipped</th></tr>
+{{range .Outputs}}<tr><td>{{if .Changes}}<a href="#{{.Output}}">{{.Output}}</a>{{else}}{{.Output}}{{end}}</td><td>{{range $i, $k := .Keys}}{{if $i}}, {{end}}{{$k}}{{end}}</td><td>{{.Status}}</td><td>{{.RowsA}}</td><td>{{.RowsB}}</td><td>{{.Added}}</td><td>{{.Removed}}</td><td>{{.Changed}}</td><td>{{.Skipped}}</td></tr>
+{{end}}</table>
+{{range .Outputs}}{{if .Changes}}{{$out := .}}
+<h2 id="{{.Output}}">{{.Output}}</h2>
+<table>
+<tr><th>Change</th><th>Key</th><th>Details</th></tr>
+{{range .Changes}}<tr class="{{.Type}}"><td>{{.Type}}</td><td class="value">{{row $out.Keys .Key}}</td><td class="value">{{if eq .Type "changed"}}{{range .Fields}}<b>{{.Column}}</b>: {{.Before}} &rarr; {{.After}}<br>{{end}}{{else}}{{row $out.Columns .Row}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a standalone HTML page with a summary table and the changes of each output
+func WriteHTML(w io.Writer, report Report) error {
+	return htmlReport.Execute(w, report)
+}
@@ -0,0 +1,211 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthonybm/Orion/baseline"
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"go.uber.org/zap"
+)
+
+// applyBaseline makes the incremental outputs of modules write only the rows newer than the high-water mark of
+// the baseline, it returns the number of rows skipped so far by output
+func applyBaseline(modules []string, i instance.Instance) map[string]*int64 {
+	skipped := make(map[string]*int64)
+	b := i.GetBaseline()
+	if b == nil {
+		return skipped
+	}
+	for _, module := range modules {
+		for _, def := range timelineDefinitions(module, i) {
+			if !def.Incremental {
+				continue
+			}
+			mark, ok := b.HighWater(def)
+			if !ok {
+				zap.L().Debug("Baseline has no rows for [" + def.Output + "], collecting all of them")
+				continue
+			}
+			def := def
+			count := new(int64)
+			skipped[def.Output] = count
+			datawriter.SetRowFilter(def.Output, func(header []string, row []string) bool {
+				t, ok := baseline.RowTime(def, header, row, i.GetTimestampFormat())
+				if ok && !t.After(mark) {
+					atomic.AddInt64(count, 1)
+					return false
+				}
+				return true
+			})
+			zap.L().Info("Collecting [" + def.Output + "] rows newer than " + mark.Format(time.RFC3339))
+		}
+	}
+	return skipped
+}
+
+// logBaselineSkips logs the rows left out of each incremental output and removes the row filters
+func logBaselineSkips(skipped map[string]*int64) {
+	for output, count := range skipped {
+		zap.L().Info("Skipped [" + strconv.FormatInt(atomic.LoadInt64(count), 10) + "] rows of [" + output + "] already in the baseline")
+	}
+	datawriter.ClearRowFilters()
+}
+
+// diffable returns true when the output of module is compared to the baseline, live state and disk listings
+// change on every run and are left out
+func diffable(module string, i instance.Instance) bool {
+	info := moduleInfo(module)
+	if _, native := typeRegistry[module]; !native {
+		if spec, ok := i.GetDeclarativeModule(module); ok {
+			info.Tags = spec.Tags
+		}
+	}
+	return !info.HasTag(moduleinfo.TagVolatile) && !info.HasTag(moduleinfo.TagDiskHeavy)
+}
+
+// writeBaselineDiff writes the rows each module output gained or lost since the baseline (new autoruns, users,
+// SSH keys...) to <runtime>_BaselineDiff.csv
+func writeBaselineDiff(modules []string, i instance.Instance) {
+	b := i.GetBaseline()
+	if b == nil {
+		return
+	}
+	mw, err := datawriter.NewOrionWriter(baseline.DiffOutput, i.GetOrionRuntime(), i.GetOrionOutputFormat(), i.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Failed to create baseline diff: " + err.Error())
+		return
+	}
+	defer mw.Close()
+	mw.WriteHeader([]string{"module", "output", "change", "row"})
+
+	total := 0
+	for _, module := range modules {
+		if !diffable(module, i) {
+			continue
+		}
+		incremental := make(map[string]bool)
+		for _, def := range timelineDefinitions(module, i) {
+			incremental[def.Output] = def.Incremental
+		}
+		for _, fp := range moduleOutputFiles(module, i) {
+			_, output, ok := baseline.OutputName(fp)
+			if !ok || incremental[output] {
+				continue
+			}
+			if !b.Has(output) {
+				zap.L().Debug("Baseline has no [" + output + "] output, not comparing it")
+				continue
+			}
+			header, rows, err := baseline.ReadFile(fp)
+			if err != nil {
+				zap.L().Error("Failed to read ["+output+"] for the baseline diff", zap.Error(err))
+				continue
+			}
+			previousHeader, previous, err := b.Rows(output)
+			if err != nil {
+				zap.L().Error("Failed to read baseline ["+output+"] for the baseline diff", zap.Error(err))
+				continue
+			}
+			added, removed := baseline.Diff(previousHeader, previous, header, rows)
+			for _, row := range added {
+				mw.Write([]string{module, output, "added", baseline.Format(header, row)})
+			}
+			for _, row := range removed {
+				mw.Write([]string{module, output, "removed", baseline.Format(previousHeader, row)})
+			}
+			total += len(added) + len(removed)
+		}
+	}
+	zap.L().Info("Wrote [" + strconv.Itoa(total) + "] changes since baseline " + b.Runtime() + " to " + mw.GetOutfilePath())
+}
+
+// writeManifest describes the run and its output files in <runtime>_manifest.json so later runs can use it as a baseline
+// and orion diff knows the key columns of each output
+// Incremental outputs keep the high-water mark of the baseline when the run added no newer rows
+// It returns the path of the manifest, empty on failure
+func writeManifest(modules []string, started time.Time, i instance.Instance) string {
+	m := baseline.Manifest{
+		Runtime:         i.GetOrionRuntime(),
+		Mode:            i.GetOrionMode(),
+		Started:         started.UTC(),
+		Finished:        time.Now().UTC(),
+		TimestampFormat: i.GetTimestampFormat(),
+		Modules:         append([]string{}, modules...),
+		Outputs:         make(map[string]baseline.Output),
+	}
+	sort.Strings(m.Modules)
+	if !i.ForensicMode() {
+		m.Host, _ = os.Hostname()
+	}
+	b := i.GetBaseline()
+	if b != nil {
+		m.Baseline = b.Runtime()
+	}
+
+	defs := make(map[string]timeline.Definition)
+	keys := make(map[string]diff.Keys)
+	for _, module := range modules {
+		for _, def := range timelineDefinitions(module, i) {
+			defs[def.Output] = def
+		}
+		for _, k := range diffKeys(module, i) {
+			keys[k.Output] = k
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(i.GetOrionOutputFilepath(), i.GetOrionRuntime()+"_*.csv"))
+	if err != nil {
+		zap.L().Error("Failed to list output files for the manifest", zap.Error(err))
+		return ""
+	}
+	for _, fp := range files {
+		// the super-timeline and baseline diff are derived from the module outputs and are not listed
+		runtime, output, ok := baseline.OutputName(fp)
+		if !ok || runtime != i.GetOrionRuntime() || baseline.Derived(output) {
+			continue
+		}
+		var def *timeline.Definition
+		if d, ok := defs[output]; ok {
+			def = &d
+		}
+		out, err := baseline.Describe(fp, def, i.GetTimestampFormat())
+		if err != nil {
+			zap.L().Error("Failed to describe "+fp+" in the manifest", zap.Error(err))
+			continue
+		}
+		if def != nil && def.Incremental && b != nil {
+			if mark, ok := b.HighWater(*def); ok {
+				// the rows up to the mark were left out by applyBaseline, orion diff must not report them as removed
+				since := mark
+				out.Since = &since
+				for _, ev := range def.Events {
+					out.EventFields = append(out.EventFields, ev.Field)
+				}
+				if out.HighWater == nil || out.HighWater.Before(mark) {
+					out.HighWater = &mark
+				}
+			}
+		}
+		if k, ok := keys[output]; ok {
+			out.Keys, out.Ignore = k.Columns, k.Ignore
+		}
+		m.Outputs[output] = out
+	}
+	fp, err := m.Write(i.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Failed to write manifest", zap.Error(err))
+		return ""
+	}
+	zap.L().Info("Wrote manifest of [" + strconv.Itoa(len(m.Outputs)) + "] outputs to " + fp)
+	return fp
+}
@@ -0,0 +1,122 @@
+// +build darwin linux
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthonybm/Orion/baseline"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+)
+
+const testConfig = `timestampFormat = "rfc3339nano"
+[modules]
+enabled = []
+`
+
+// copyTree copies the fixture target directory src into dst
+func copyTree(t *testing.T, src string, dst string) {
+	err := filepath.Walk(src, func(fp string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, fp)
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		data, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// collect runs modules against target into a new output directory under dir and returns it
+func collect(t *testing.T, dir string, runtime string, target string, modules []string, baselinePath string) string {
+	out := filepath.Join(dir, runtime)
+	if err := os.Mkdir(out, 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(config, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	inst, err := instance.NewInstance(target, "csv", out, runtime, "error", config, "mac", true, false, []string{"csv", "l2tcsv"}, instance.ModuleSelection{}, baselinePath, false)
+	if err != nil {
+		t.Fatalf("NewInstance() error: %s", err)
+	}
+	defer inst.CloseLogger()
+	if err := executeModules(modules, inst); err != nil {
+		t.Fatalf("executeModules() error: %s", err)
+	}
+	return out
+}
+
+// A run collected with --baseline leaves out the incremental rows the baseline has and writes the super-timeline
+// and baseline diff, comparing it with the baseline must show nothing changed
+func TestBaselineThenDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "target")
+	copyTree(t, "../mac/modules/macutmpx/testdata/target", target)
+	copyTree(t, "../mac/modules/macssh/testdata/target", target)
+	modules := []string{"MacUtmpxModule", "MacSSHModule"}
+
+	gold := collect(t, dir, "Orion_2020-08-01T10_00_00Z", target, modules, "")
+	suspect := collect(t, dir, "Orion_2020-08-02T10_00_00Z", target, modules, gold)
+
+	b, err := baseline.Open(suspect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	m := b.Manifest()
+	if m == nil {
+		t.Fatal("the run has no manifest")
+	}
+	utmpx, ok := m.Outputs["MacUtmpxModule"]
+	if !ok || utmpx.Since == nil || utmpx.Rows != 0 {
+		t.Errorf("manifest MacUtmpxModule = %+v, want every row left out since the baseline", utmpx)
+	}
+	for _, output := range []string{"Timeline", "Timeline.l2t", baseline.DiffOutput} {
+		if !b.HasDerived(output) {
+			t.Errorf("the run did not write %s", output)
+		}
+		if _, ok := m.Outputs[output]; ok {
+			t.Errorf("manifest lists the derived %s", output)
+		}
+	}
+
+	a, err := baseline.Open(gold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	report, err := diff.Compare(a, b, DiffKeys(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"MacSSHModule", "MacSSHModule-config", "MacUtmpxModule"}
+	if len(report.Outputs) != len(want) {
+		t.Fatalf("Compare() outputs = %+v, want %v", report.Outputs, want)
+	}
+	for i, out := range report.Outputs {
+		if out.Output != want[i] || out.Status != diff.StatusCompared || len(out.Changes) != 0 {
+			t.Errorf("Compare() %s = %+v, want no change", out.Output, out)
+		}
+	}
+	if skipped := report.Outputs[2].Skipped; skipped != 4 {
+		t.Errorf("Compare() MacUtmpxModule skipped %d rows, want 4", skipped)
+	}
+}
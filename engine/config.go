@@ -0,0 +1,98 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/anthonybm/Orion/configs"
+	"github.com/anthonybm/Orion/instance"
+)
+
+// defaultModuleConfig returns the config struct a registered module declares through its optional DefaultConfig() method
+func defaultModuleConfig(module string) (interface{}, bool) {
+	out, err := invoke(module, "DefaultConfig")
+	if err != nil || len(out) == 0 {
+		return nil, false
+	}
+	return out[0].Interface(), true
+}
+
+// ValidateConfig checks that every enabled, profile and command line module or tag exists, that every [throttle.profiles.<profile>]
+// table belongs to a profile and that every [modules.<Name>] table belongs to a module and only holds keys declared by its config struct
+func ValidateConfig(i instance.Instance) error {
+	conf := i.GetOrionConfig()
+	problems := []string{}
+
+	infos := allModuleInfo(&i)
+	enabled, _ := conf.GetModulesToExecute()
+	if _, err := expandModules(enabled, infos, i); err != nil {
+		problems = append(problems, "[modules] enabled: "+err.Error())
+	}
+	for _, profile := range conf.GetProfiles() {
+		entries, _ := conf.GetProfile(profile)
+		if _, err := expandModules(entries, infos, i); err != nil {
+			problems = append(problems, "[profiles] "+profile+": "+err.Error())
+		}
+	}
+	if _, err := SelectModules(i); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, profile := range conf.GetThrottleProfiles() {
+		if _, ok := conf.GetProfile(profile); !ok {
+			problems = append(problems, "[throttle.profiles."+profile+"]: no [profiles] entry named "+profile)
+		}
+		if err := conf.GetThrottle(profile).Validate(); err != nil {
+			problems = append(problems, "[throttle.profiles."+profile+"]: "+err.Error())
+		}
+	}
+
+	for _, module := range conf.GetModuleSections() {
+		if _, native := typeRegistry[module]; native {
+			defaults, ok := defaultModuleConfig(module)
+			if !ok {
+				problems = append(problems, "[modules."+module+"]: module takes no configuration")
+				continue
+			}
+			if err := conf.DecodeModuleConfig(module, defaults); err != nil {
+				problems = append(problems, err.Error())
+			}
+		} else if _, ok := i.GetDeclarativeModule(module); ok {
+			problems = append(problems, "[modules."+module+"]: declarative modules take no configuration")
+		} else if _, ok := i.GetPluginPath(module); !ok {
+			problems = append(problems, "[modules."+module+"]: unknown module")
+		}
+		// plugin sections are passed through to the plugin as is
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// moduleExists returns true if module is a registered, declarative or plugin module
+func moduleExists(module string, i instance.Instance) bool {
+	if _, ok := typeRegistry[module]; ok {
+		return true
+	}
+	if _, ok := i.GetDeclarativeModule(module); ok {
+		return true
+	}
+	_, ok := i.GetPluginPath(module)
+	return ok
+}
+
+// WriteConfigSchema writes a commented config file with every top-level key and the config section of
+// every registered module that declares one, holding default values
+func WriteConfigSchema(w io.Writer, mode string) error {
+	modules := make(map[string]interface{})
+	for module := range typeRegistry {
+		if defaults, ok := defaultModuleConfig(module); ok {
+			modules[module] = defaults
+		}
+	}
+	return configs.WriteSchema(w, mode, modules)
+}
@@ -0,0 +1,36 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/declarative"
+	"github.com/anthonybm/Orion/instance"
+	"go.uber.org/zap"
+)
+
+// declarativeModule returns the declarative spec for module, registered Go modules take precedence over specs with the same name
+func declarativeModule(module string, i instance.Instance) (declarative.Spec, bool) {
+	spec, ok := i.GetDeclarativeModule(module)
+	if !ok {
+		return spec, false
+	}
+	if _, native := typeRegistry[module]; native {
+		zap.L().Warn("Declarative module [" + module + "] has the same name as a registered module and is ignored")
+		return spec, false
+	}
+	return spec, true
+}
+
+// runDeclarative runs a declarative module spec against the files of its artifact definition
+func runDeclarative(spec declarative.Spec, i instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(spec.Name, i.GetOrionRuntime(), i.GetOrionOutputFormat(), i.GetOrionOutputFilepath())
+	if err != nil {
+		return err
+	}
+	files := i.GetArtifactPaths(spec.ArtifactName())
+	if len(files) == 0 {
+		zap.L().Debug("No files were found for artifact '"+spec.ArtifactName()+"'", zap.String("module", spec.Name))
+	}
+	return declarative.Run(spec, files, mw)
+}
@@ -0,0 +1,52 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"go.uber.org/zap"
+)
+
+// diffKeys returns the key columns declared by a module through its optional DiffKeys() method or by its declarative spec
+func diffKeys(module string, i instance.Instance) []diff.Keys {
+	if spec, ok := declarativeModule(module, i); ok {
+		if len(spec.DiffKeys) == 0 {
+			return []diff.Keys{}
+		}
+		return []diff.Keys{{Output: spec.Name, Columns: spec.DiffKeys}}
+	}
+	if _, ok := typeRegistry[module]; !ok {
+		return []diff.Keys{}
+	}
+	out, err := invoke(module, "DiffKeys")
+	if err != nil || len(out) == 0 {
+		return []diff.Keys{}
+	}
+	keys, ok := out[0].Interface().([]diff.Keys)
+	if !ok {
+		zap.L().Warn("DiffKeys() of [" + module + "] does not return []diff.Keys")
+		return []diff.Keys{}
+	}
+	return keys
+}
+
+// DiffKeys returns the key columns of the outputs of every registered module by output name, runs with a manifest
+// carry their own
+func DiffKeys() map[string]diff.Keys {
+	keys := make(map[string]diff.Keys)
+	for module := range typeRegistry {
+		out, err := invoke(module, "DiffKeys")
+		if err != nil || len(out) == 0 {
+			continue
+		}
+		declared, ok := out[0].Interface().([]diff.Keys)
+		if !ok {
+			continue
+		}
+		for _, k := range declared {
+			keys[k.Output] = k
+		}
+	}
+	return keys
+}
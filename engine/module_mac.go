@@ -1,8 +1,11 @@
+// +build darwin linux
+
 package engine
 
 /* Inspired by: https://github.com/graniet/operative-framework/blob/master/session/module.go */
 import (
 	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -14,24 +17,32 @@ import (
 	"sync"
 	"time"
 
+	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
-	"github.com/anthonybm/Orion/mac/modules/macapplesystemlog"
-	"github.com/anthonybm/Orion/mac/modules/macauditlog"
 	"github.com/anthonybm/Orion/mac/modules/macautoruns"
 	"github.com/anthonybm/Orion/mac/modules/macbash"
 	"github.com/anthonybm/Orion/mac/modules/macchrome"
+	"github.com/anthonybm/Orion/mac/modules/maccloudsync"
 	"github.com/anthonybm/Orion/mac/modules/maccookies"
 	"github.com/anthonybm/Orion/mac/modules/macdirlist"
-	"github.com/anthonybm/Orion/mac/modules/maceventtaps"
+	"github.com/anthonybm/Orion/mac/modules/macdock"
+	"github.com/anthonybm/Orion/mac/modules/macdsstore"
 	"github.com/anthonybm/Orion/mac/modules/macfirefox"
+	"github.com/anthonybm/Orion/mac/modules/macidevice"
+	"github.com/anthonybm/Orion/mac/modules/macimessage"
+	"github.com/anthonybm/Orion/mac/modules/macinetaccounts"
 	"github.com/anthonybm/Orion/mac/modules/macinstallhistory"
-	"github.com/anthonybm/Orion/mac/modules/maclivelsof"
-	"github.com/anthonybm/Orion/mac/modules/maclivenetstat"
-	"github.com/anthonybm/Orion/mac/modules/maclivepslist"
 	"github.com/anthonybm/Orion/mac/modules/macmru"
+	"github.com/anthonybm/Orion/mac/modules/macmsoffice"
 	"github.com/anthonybm/Orion/mac/modules/macnetconfig"
+	"github.com/anthonybm/Orion/mac/modules/macnotes"
+	"github.com/anthonybm/Orion/mac/modules/macnotifications"
+	"github.com/anthonybm/Orion/mac/modules/macperipherals"
 	"github.com/anthonybm/Orion/mac/modules/macquarantines"
+	"github.com/anthonybm/Orion/mac/modules/macquicklook"
+	"github.com/anthonybm/Orion/mac/modules/macrecentitems"
 	"github.com/anthonybm/Orion/mac/modules/macsample"
+	"github.com/anthonybm/Orion/mac/modules/macsavedstate"
 	"github.com/anthonybm/Orion/mac/modules/macspotlight"
 	"github.com/anthonybm/Orion/mac/modules/macssh"
 	"github.com/anthonybm/Orion/mac/modules/macsysteminfo"
@@ -39,22 +50,27 @@ import (
 	"github.com/anthonybm/Orion/mac/modules/macterminalstate"
 	"github.com/anthonybm/Orion/mac/modules/macusers"
 	"github.com/anthonybm/Orion/mac/modules/macutmpx"
+	"github.com/anthonybm/Orion/throttle"
 	"go.uber.org/zap"
 )
 
 var typeRegistry = make(map[string]reflect.Type)
 
+// unavailableModules are modules of the mode that do not build on this OS, configs enabling them are skipped with a
+// warning instead of failing as unknown modules
+var unavailableModules = make(map[string]bool)
+
 func registerType(elem interface{}) {
 	t := reflect.TypeOf(elem).Elem()
 	typeRegistry[t.Name()] = t
 }
 
 // init contains the registrations for module struct types, must be created before Orion runs
+// Modules that only collect from a live macOS system are registered in registry_darwin.go
 func init() {
 	registerType((*macsample.MacSampleModule)(nil))
 	registerType((*macinstallhistory.MacInstallHistoryModule)(nil))
 	registerType((*macsystemlog.MacSystemLogModule)(nil))
-	registerType((*macapplesystemlog.MacAppleSystemLogModule)(nil))
 	registerType((*macbash.MacBashModule)(nil))
 	registerType((*macssh.MacSSHModule)(nil))
 	registerType((*macquarantines.MacQuarantinesModule)(nil))
@@ -66,25 +82,41 @@ func init() {
 	registerType((*macspotlight.MacSpotlightShortcutsModule)(nil))
 	registerType((*macmru.MacMRUModule)(nil))
 	registerType((*macutmpx.MacUtmpxModule)(nil))
-	registerType((*macauditlog.MacAuditLogModule)(nil))
 	registerType((*macusers.MacUsersModule)(nil))
 	registerType((*macchrome.MacChromeModule)(nil))
 	registerType((*macfirefox.MacFirefoxModule)(nil))
 	registerType((*macterminalstate.MacTerminalStateModule)(nil))
-	registerType((*maceventtaps.MacEventTapsModule)(nil))
-	registerType((*maclivenetstat.MacLiveNetstat)(nil))
-	registerType((*maclivepslist.MacLivePslistModule)(nil))
-	registerType((*maclivelsof.MacLiveLsofModule)(nil))
+	registerType((*macsavedstate.MacSavedStateModule)(nil))
+	registerType((*macdock.MacDockModule)(nil))
+	registerType((*macrecentitems.MacRecentItemsModule)(nil))
+	registerType((*macquicklook.MacQuicklookModule)(nil))
+	registerType((*macdsstore.MacDSStoreModule)(nil))
+	registerType((*macimessage.MaciMessageModule)(nil))
+	registerType((*macnotes.MacNotesModule)(nil))
+	registerType((*macnotifications.MacNotificationsModule)(nil))
+	registerType((*macidevice.MaciDeviceModule)(nil))
+	registerType((*macperipherals.MacPeripheralsModule)(nil))
+	registerType((*macmsoffice.MacMSOfficeModule)(nil))
+	registerType((*macinetaccounts.MacINetAccountsModule)(nil))
+	registerType((*maccloudsync.MacCloudSyncModule)(nil))
 	// ... add future modules here
 }
 
 // Execute runs Orion modules based on Config file, should only be called once
 // Exposes instance functions
 func Execute(i instance.Instance) error {
-	modules, err := i.GetOrionModules()
+	err := ValidateConfig(i)
+	if err != nil {
+		return err
+	}
+	modules, err := SelectModules(i)
 	if err != nil {
 		return err
 	}
+	if len(modules) == 0 {
+		return errors.New("no modules selected to run")
+	}
+	throttle.Apply(i.GetThrottle())
 	err = executeModules(modules, i)
 
 	return err
@@ -104,9 +136,11 @@ func executeModules(modules []string, i instance.Instance) error {
 	zap.L().Debug("[" + strconv.Itoa(len(modules)) + "]" + " modules will execute")
 	execCount := 0
 	benchmarkStart := time.Now()
+	datawriter.StartStreams(i.GetStreams())
+	skipped := applyBaseline(modules, i)
 	if i.NoMultithreading() == false {
 		var wg sync.WaitGroup
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt)
 
 		go func() {
@@ -121,11 +155,24 @@ func executeModules(modules []string, i instance.Instance) error {
 				os.Exit(1)
 			}
 		}()
+		// A slot is taken before each module starts when the number of modules running at once is limited
+		var slots chan struct{}
+		if max := i.GetThrottle().MaxModules; max > 0 {
+			slots = make(chan struct{}, max)
+		}
 		for _, module := range modules {
 			wg.Add(1)
 			execCount++
 			zap.L().Debug(module + " sent to goroutine")
-			go executeModule(module, &wg, i /*fields here*/)
+			if slots == nil {
+				go executeModule(module, &wg, i /*fields here*/)
+				continue
+			}
+			slots <- struct{}{}
+			go func(module string) {
+				defer func() { <-slots }()
+				executeModule(module, &wg, i /*fields here*/)
+			}(module)
 		}
 		zap.L().Debug("[" + strconv.Itoa(execCount) + "/" + strconv.Itoa(len(modules)) + "]" + " modules have been sent to goroutines")
 		zap.L().Debug("Waiting for module goroutines to finish")
@@ -138,6 +185,24 @@ func executeModules(modules []string, i instance.Instance) error {
 	}
 	benchmark := time.Now().Sub(benchmarkStart)
 	zap.L().Info("Finished all " + strconv.Itoa(len(modules)) + " modules in " + benchmark.String())
+	datawriter.StopStreams()
+	logBaselineSkips(skipped)
+	if len(i.GetTimelineFormats()) > 0 {
+		buildTimeline(modules, i)
+	}
+	writeBaselineDiff(modules, i)
+	manifest := writeManifest(modules, benchmarkStart, i)
+	if i.GetReport() {
+		writeReport(manifest, modules, i)
+	}
+	if len(i.GetUploadSinks()) > 0 {
+		fp, err := packageOutput(i)
+		if err != nil {
+			zap.L().Error("Failed to package output for upload: " + err.Error())
+			return err
+		}
+		return uploadPackage(fp, i)
+	}
 	return nil
 }
 
@@ -145,6 +210,7 @@ func safeAbort(i instance.Instance, wg *sync.WaitGroup) {
 	defer wg.Done()
 	zap.L().Warn("DO NOT INTERRUPT - Packaging files before terminating...")
 	zap.L().Warn("Does not save output progress made by modules not yet complete!!!") // TODO Channels?
+	datawriter.StopStreams()
 	files, err := filepath.Glob(i.GetOrionOutputFilepath() + "/*")
 	if err != nil {
 		zap.L().Error("Failed to glob for output files to archive")
@@ -209,10 +275,36 @@ func executeModule(module string, wg *sync.WaitGroup, inst instance.Instance /*f
 	if wg != nil {
 		defer wg.Done()
 	}
+	// Upload the module output to streaming sinks once the module finishes, even when it failed
+	defer streamModuleOutput(module, inst)
 
 	zap.L().Debug("Starting [" + module + "] module.")
 	startTime := time.Now()
 
+	// Modules defined in declarative spec files are run by the declarative runner
+	if spec, ok := declarativeModule(module, inst); ok {
+		err := runDeclarative(spec, inst)
+		finishTime := time.Now()
+		if err != nil {
+			zap.L().Error("Exiting ["+module+"] module with errors. Total time: "+finishTime.Sub(startTime).String(), zap.Error(err))
+			return err
+		}
+		zap.L().Info("Finished [" + module + "] module. Total time: " + finishTime.Sub(startTime).String())
+		return nil
+	}
+
+	// External plugin executables are run over the plugin protocol
+	if path, ok := pluginModule(module, inst); ok {
+		err := runPlugin(path, module, inst)
+		finishTime := time.Now()
+		if err != nil {
+			zap.L().Error("Exiting ["+module+"] module with errors. Total time: "+finishTime.Sub(startTime).String(), zap.Error(err))
+			return err
+		}
+		zap.L().Info("Finished [" + module + "] module. Total time: " + finishTime.Sub(startTime).String())
+		return nil
+	}
+
 	// Take instance of *Module and turn into reflect.Value via reflect.ValueOf()
 	// Call MethodByName() with the name of the method we want to retreive
 	out, err := invoke(module, "Start", inst)
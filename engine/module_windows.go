@@ -2,6 +2,7 @@ package engine
 
 import (
 	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -13,13 +14,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/throttle"
 	"github.com/anthonybm/Orion/windows/modules/windowsdirlist"
 	"go.uber.org/zap"
 )
 
 var typeRegistry = make(map[string]reflect.Type)
 
+// unavailableModules are modules of the mode that do not build on this OS, configs enabling them are skipped with a
+// warning instead of failing as unknown modules
+var unavailableModules = make(map[string]bool)
+
 func registerType(elem interface{}) {
 	t := reflect.TypeOf(elem).Elem()
 	typeRegistry[t.Name()] = t
@@ -34,10 +41,18 @@ func init() {
 // Execute runs Orion modules based on Config file, should only be called once
 // Exposes instance functions
 func Execute(i instance.Instance) error {
-	modules, err := i.GetOrionModules()
+	err := ValidateConfig(i)
+	if err != nil {
+		return err
+	}
+	modules, err := SelectModules(i)
 	if err != nil {
 		return err
 	}
+	if len(modules) == 0 {
+		return errors.New("no modules selected to run")
+	}
+	throttle.Apply(i.GetThrottle())
 	err = executeModules(modules, i)
 
 	return err
@@ -57,9 +72,11 @@ func executeModules(modules []string, i instance.Instance) error {
 	zap.L().Debug("[" + strconv.Itoa(len(modules)) + "]" + " modules will execute")
 	execCount := 0
 	benchmarkStart := time.Now()
+	datawriter.StartStreams(i.GetStreams())
+	skipped := applyBaseline(modules, i)
 	if i.NoMultithreading() == false {
 		var wg sync.WaitGroup
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt)
 
 		go func() {
@@ -74,11 +91,24 @@ func executeModules(modules []string, i instance.Instance) error {
 				os.Exit(1)
 			}
 		}()
+		// A slot is taken before each module starts when the number of modules running at once is limited
+		var slots chan struct{}
+		if max := i.GetThrottle().MaxModules; max > 0 {
+			slots = make(chan struct{}, max)
+		}
 		for _, module := range modules {
 			wg.Add(1)
 			execCount++
 			zap.L().Debug(module + " sent to goroutine")
-			go executeModule(module, &wg, i /*fields here*/)
+			if slots == nil {
+				go executeModule(module, &wg, i /*fields here*/)
+				continue
+			}
+			slots <- struct{}{}
+			go func(module string) {
+				defer func() { <-slots }()
+				executeModule(module, &wg, i /*fields here*/)
+			}(module)
 		}
 		zap.L().Debug("[" + strconv.Itoa(execCount) + "/" + strconv.Itoa(len(modules)) + "]" + " modules have been sent to goroutines")
 		zap.L().Debug("Waiting for module goroutines to finish")
@@ -91,22 +121,35 @@ func executeModules(modules []string, i instance.Instance) error {
 	}
 	benchmark := time.Now().Sub(benchmarkStart)
 	zap.L().Info("Finished all " + strconv.Itoa(len(modules)) + " modules in " + benchmark.String())
-	archive(i)
+	datawriter.StopStreams()
+	logBaselineSkips(skipped)
+	if len(i.GetTimelineFormats()) > 0 {
+		buildTimeline(modules, i)
+	}
+	writeBaselineDiff(modules, i)
+	manifest := writeManifest(modules, benchmarkStart, i)
+	if i.GetReport() {
+		writeReport(manifest, modules, i)
+	}
+	if fp := archive(i); fp != "" {
+		return uploadPackage(fp, i)
+	}
 	return nil
 }
 
-func archive(i instance.Instance) {
+// archive zips the output directory, removes it and returns the path of the archive, empty on failure
+func archive(i instance.Instance) string {
 	files, err := filepath.Glob(i.GetOrionOutputFilepath() + "/*")
 	if err != nil {
 		zap.L().Error("Failed to glob for output files to archive")
-		return
+		return ""
 	}
 	output := i.GetOrionRuntime() + ".zip"
 
 	zf, err := os.Create(output)
 	if err != nil {
 		zap.L().Error("Failed to create output archive")
-		return
+		return ""
 	}
 	defer zf.Close()
 
@@ -169,12 +212,14 @@ func archive(i instance.Instance) {
 			}
 		}
 	}
+	return output
 }
 
 func safeAbort(i instance.Instance, wg *sync.WaitGroup) {
 	defer wg.Done()
 	zap.L().Warn("DO NOT INTERRUPT - Packaging files before terminating...")
 	zap.L().Warn("Does not save output progress made by modules not yet complete!!!") // TODO Channels?
+	datawriter.StopStreams()
 	files, err := filepath.Glob(i.GetOrionOutputFilepath() + "/*")
 	if err != nil {
 		zap.L().Error("Failed to glob for output files to archive")
@@ -239,10 +284,36 @@ func executeModule(module string, wg *sync.WaitGroup, inst instance.Instance /*f
 	if wg != nil {
 		defer wg.Done()
 	}
+	// Upload the module output to streaming sinks once the module finishes, even when it failed
+	defer streamModuleOutput(module, inst)
 
 	zap.L().Debug("Starting [" + module + "] module.")
 	startTime := time.Now()
 
+	// Modules defined in declarative spec files are run by the declarative runner
+	if spec, ok := declarativeModule(module, inst); ok {
+		err := runDeclarative(spec, inst)
+		finishTime := time.Now()
+		if err != nil {
+			zap.L().Error("Exiting ["+module+"] module with errors. Total time: "+finishTime.Sub(startTime).String(), zap.Error(err))
+			return err
+		}
+		zap.L().Info("Finished [" + module + "] module. Total time: " + finishTime.Sub(startTime).String())
+		return nil
+	}
+
+	// External plugin executables are run over the plugin protocol
+	if path, ok := pluginModule(module, inst); ok {
+		err := runPlugin(path, module, inst)
+		finishTime := time.Now()
+		if err != nil {
+			zap.L().Error("Exiting ["+module+"] module with errors. Total time: "+finishTime.Sub(startTime).String(), zap.Error(err))
+			return err
+		}
+		zap.L().Info("Finished [" + module + "] module. Total time: " + finishTime.Sub(startTime).String())
+		return nil
+	}
+
 	// Take instance of *Module and turn into reflect.Value via reflect.ValueOf()
 	// Call MethodByName() with the name of the method we want to retreive
 	out, err := invoke(module, "Start", inst)
@@ -0,0 +1,67 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/plugins"
+	"github.com/anthonybm/Orion/timeline"
+	"go.uber.org/zap"
+)
+
+// pluginTimelines holds the timeline definitions plugins declared while running, by module name
+var pluginTimelines = struct {
+	sync.Mutex
+	defs map[string][]timeline.Definition
+}{defs: make(map[string][]timeline.Definition)}
+
+// pluginModule returns the plugin executable for module, registered Go modules and declarative modules take precedence
+func pluginModule(module string, i instance.Instance) (string, bool) {
+	path, ok := i.GetPluginPath(module)
+	if !ok {
+		return path, false
+	}
+	if _, native := typeRegistry[module]; native {
+		zap.L().Warn("Plugin [" + path + "] has the same name as a registered module and is ignored")
+		return path, false
+	}
+	if _, declared := i.GetDeclarativeModule(module); declared {
+		zap.L().Warn("Plugin [" + path + "] has the same name as a declarative module and is ignored")
+		return path, false
+	}
+	return path, true
+}
+
+// runPlugin runs an external plugin, writing its outputs like a native module
+func runPlugin(path string, module string, i instance.Instance) error {
+	conf := i.GetOrionConfig()
+	section := conf.GetModuleSection(module)
+	timeout, _ := conf.GetPluginTimeout()
+	req := plugins.Request{
+		Module:          module,
+		Mode:            i.GetOrionMode(),
+		Target:          i.GetTargetPath(),
+		OSVersion:       i.GetTargetOSVersion(),
+		ForensicMode:    i.ForensicMode(),
+		TimestampFormat: i.GetTimestampFormat(),
+		Config:          section,
+	}
+	newWriter := func(output string) (datawriter.OrionWriter, error) {
+		return datawriter.NewOrionWriter(output, i.GetOrionRuntime(), i.GetOrionOutputFormat(), i.GetOrionOutputFilepath())
+	}
+
+	zap.L().Debug("Running plugin "+path, zap.String("module", module))
+	res, err := plugins.Run(path, req, time.Duration(timeout)*time.Second, newWriter)
+	if len(res.Timeline) > 0 {
+		pluginTimelines.Lock()
+		pluginTimelines.defs[module] = res.Timeline
+		pluginTimelines.Unlock()
+	}
+	zap.L().Debug("Plugin wrote ["+strconv.Itoa(res.Rows)+"] rows to ["+strconv.Itoa(len(res.Outputs))+"] outputs", zap.String("module", module))
+	return err
+}
@@ -0,0 +1,21 @@
+package engine
+
+import (
+	"github.com/anthonybm/Orion/mac/modules/macapplesystemlog"
+	"github.com/anthonybm/Orion/mac/modules/macauditlog"
+	"github.com/anthonybm/Orion/mac/modules/maceventtaps"
+	"github.com/anthonybm/Orion/mac/modules/maclivelsof"
+	"github.com/anthonybm/Orion/mac/modules/maclivenetstat"
+	"github.com/anthonybm/Orion/mac/modules/maclivepslist"
+)
+
+// init registers the modules that run macOS tools or frameworks (syslog, praudit, lsof, netstat, ps, Quartz event
+// taps) and so only build on darwin
+func init() {
+	registerType((*macapplesystemlog.MacAppleSystemLogModule)(nil))
+	registerType((*macauditlog.MacAuditLogModule)(nil))
+	registerType((*maceventtaps.MacEventTapsModule)(nil))
+	registerType((*maclivenetstat.MacLiveNetstat)(nil))
+	registerType((*maclivepslist.MacLivePslistModule)(nil))
+	registerType((*maclivelsof.MacLiveLsofModule)(nil))
+}
@@ -0,0 +1,16 @@
+package engine
+
+// init marks the modules registered in registry_darwin.go as unavailable, so the default mac config runs the
+// portable modules when analyzing mac evidence from Linux
+func init() {
+	for _, module := range []string{
+		"MacAppleSystemLogModule",
+		"MacAuditLogModule",
+		"MacEventTapsModule",
+		"MacLiveNetstat",
+		"MacLivePslistModule",
+		"MacLiveLsofModule",
+	} {
+		unavailableModules[module] = true
+	}
+}
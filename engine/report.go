@@ -0,0 +1,62 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"github.com/anthonybm/Orion/baseline"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/report"
+	"github.com/anthonybm/Orion/timeline"
+	"go.uber.org/zap"
+)
+
+// writeReport summarises the run in <runtime>_Report.html, the run is read back through its manifest so only the
+// output of this run is used, or from the output directory when the manifest could not be written
+func writeReport(manifest string, modules []string, i instance.Instance) {
+	if i.GetOrionOutputFormat() != "csv" {
+		zap.L().Warn("The HTML report reads csv output, not writing it for " + i.GetOrionOutputFormat() + " output")
+		return
+	}
+	source := manifest
+	if source == "" {
+		source = i.GetOrionOutputFilepath()
+	}
+	run, err := baseline.Open(source)
+	if err != nil {
+		zap.L().Error("Failed to read the run for the report", zap.Error(err))
+		return
+	}
+	defer run.Close()
+
+	defs := []timeline.Definition{}
+	for _, module := range modules {
+		defs = append(defs, timelineDefinitions(module, i)...)
+	}
+	r, err := report.Build(run, defs)
+	if err != nil {
+		zap.L().Error("Failed to build report", zap.Error(err))
+		return
+	}
+	fp, err := report.WriteFile(r, i.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Failed to write report", zap.Error(err))
+		return
+	}
+	zap.L().Info("Wrote report to " + fp)
+}
+
+// TimelineDefinitions returns the timeline definitions of every registered module, used to report on runs without
+// a super-timeline
+func TimelineDefinitions() []timeline.Definition {
+	defs := []timeline.Definition{}
+	for module := range typeRegistry {
+		out, err := invoke(module, "Timeline")
+		if err != nil || len(out) == 0 {
+			continue
+		}
+		if declared, ok := out[0].Interface().([]timeline.Definition); ok {
+			defs = append(defs, declared...)
+		}
+	}
+	return defs
+}
@@ -0,0 +1,195 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"go.uber.org/zap"
+)
+
+// moduleInfo returns the description, version and tags a registered module declares through its optional Info() method
+func moduleInfo(module string) moduleinfo.Info {
+	out, err := invoke(module, "Info")
+	if err == nil && len(out) > 0 {
+		if info, ok := out[0].Interface().(moduleinfo.Info); ok {
+			return info
+		}
+	}
+	return moduleinfo.Info{Name: module}
+}
+
+// allModuleInfo returns the info of every registered, declarative and plugin module, sorted by name
+// i may be nil when no config file was given, only registered modules are returned then
+func allModuleInfo(i *instance.Instance) []moduleinfo.Info {
+	infos := []moduleinfo.Info{}
+	for module := range typeRegistry {
+		infos = append(infos, moduleInfo(module))
+	}
+	if i != nil {
+		for _, module := range i.GetDeclarativeModules() {
+			if _, native := typeRegistry[module]; native {
+				continue
+			}
+			spec, _ := i.GetDeclarativeModule(module)
+			infos = append(infos, moduleinfo.Info{
+				Name:        spec.Name,
+				Mode:        spec.Mode,
+				Version:     spec.Version,
+				Description: spec.Description,
+				Author:      spec.Author,
+				Tags:        append([]string{"declarative"}, spec.Tags...),
+			})
+		}
+		for _, module := range i.GetPlugins() {
+			if !isPluginOnly(module, *i) {
+				continue
+			}
+			infos = append(infos, moduleinfo.Info{Name: module, Description: "external plugin", Tags: []string{"plugin"}})
+		}
+	}
+	sort.Slice(infos, func(a, b int) bool { return infos[a].Name < infos[b].Name })
+	return infos
+}
+
+// isPluginOnly returns true if module is a plugin not shadowed by a registered or declarative module
+func isPluginOnly(module string, i instance.Instance) bool {
+	if _, native := typeRegistry[module]; native {
+		return false
+	}
+	_, declarative := i.GetDeclarativeModule(module)
+	return !declarative
+}
+
+// SelectModules returns the modules to run, in order and without duplicates
+// The modules of --profile and --modules are combined, falling back to [modules] enabled when neither is set,
+// then --exclude is removed. Every entry is a module name or a tag selecting all modules with that tag
+func SelectModules(i instance.Instance) ([]string, error) {
+	selection := i.GetModuleSelection()
+	conf := i.GetOrionConfig()
+	infos := allModuleInfo(&i)
+
+	entries := []string{}
+	if selection.Profile != "" {
+		profile, ok := conf.GetProfile(selection.Profile)
+		if !ok {
+			return nil, errors.New("unknown profile '" + selection.Profile + "'")
+		}
+		entries = append(entries, profile...)
+	}
+	entries = append(entries, selection.Modules...)
+	if selection.Profile == "" && len(selection.Modules) == 0 {
+		enabled, err := conf.GetModulesToExecute()
+		if err != nil {
+			return nil, err
+		}
+		entries = enabled
+	}
+
+	selected, err := expandModules(entries, infos, i)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := expandModules(selection.Exclude, infos, i)
+	if err != nil {
+		return nil, fmt.Errorf("--exclude: %s", err.Error())
+	}
+	skip := make(map[string]bool)
+	for _, module := range excluded {
+		skip[module] = true
+	}
+	modules := []string{}
+	for _, module := range selected {
+		if !skip[module] {
+			modules = append(modules, module)
+		}
+	}
+	return modules, nil
+}
+
+// warnedUnavailable keeps the unavailable modules already warned about, selections are expanded more than once
+var warnedUnavailable = make(map[string]bool)
+
+// expandModules replaces tags in entries with the modules carrying that tag and drops duplicates
+// Module names take precedence over tags, unknown entries are returned as an error
+func expandModules(entries []string, infos []moduleinfo.Info, i instance.Instance) ([]string, error) {
+	modules := []string{}
+	seen := make(map[string]bool)
+	add := func(module string) {
+		if !seen[module] {
+			seen[module] = true
+			modules = append(modules, module)
+		}
+	}
+	unknown := []string{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if moduleExists(entry, i) {
+			add(entry)
+			continue
+		}
+		if unavailableModules[entry] {
+			if !warnedUnavailable[entry] {
+				warnedUnavailable[entry] = true
+				zap.L().Warn(entry + " is not available on this OS, skipping it")
+			}
+			continue
+		}
+		tagged := false
+		for _, info := range infos {
+			if info.HasTag(entry) {
+				add(info.Name)
+				tagged = true
+			}
+		}
+		if !tagged && !moduleinfo.KnownTag(entry) {
+			unknown = append(unknown, entry)
+		}
+	}
+	if len(unknown) > 0 {
+		return modules, errors.New("unknown modules or tags: " + strings.Join(unknown, ", "))
+	}
+	return modules, nil
+}
+
+// WriteModuleList writes every module available on this OS with its version, tags and description
+// Modules selected to run are marked with '*' when i is set
+func WriteModuleList(w io.Writer, i *instance.Instance) error {
+	selected := make(map[string]bool)
+	if i != nil {
+		modules, err := SelectModules(*i)
+		if err != nil {
+			return err
+		}
+		for _, module := range modules {
+			selected[module] = true
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  MODULE\tVERSION\tTAGS\tDESCRIPTION\n")
+	for _, info := range allModuleInfo(i) {
+		mark := " "
+		if selected[info.Name] {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\n", mark, info.Name, info.Version, strings.Join(info.Tags, ","), info.Summary())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if i != nil {
+		fmt.Fprintf(w, "\n* selected to run, profiles: %s\n", strings.Join(i.GetOrionConfig().GetProfiles(), ", "))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/timeline"
+	"go.uber.org/zap"
+)
+
+// timelineDefinitions returns the timeline definitions declared by a module through its optional Timeline() method
+// or by its declarative spec, plugins declare theirs while running
+func timelineDefinitions(module string, i instance.Instance) []timeline.Definition {
+	if spec, ok := declarativeModule(module, i); ok {
+		return spec.TimelineDefinitions()
+	}
+	if _, ok := pluginModule(module, i); ok {
+		pluginTimelines.Lock()
+		defer pluginTimelines.Unlock()
+		return pluginTimelines.defs[module]
+	}
+	out, err := invoke(module, "Timeline")
+	if err != nil || len(out) == 0 {
+		return []timeline.Definition{}
+	}
+	defs, ok := out[0].Interface().([]timeline.Definition)
+	if !ok {
+		zap.L().Warn("Timeline() of [" + module + "] does not return []timeline.Definition")
+		return []timeline.Definition{}
+	}
+	return defs
+}
+
+// buildTimeline merges the output of every executed module that declares event times into a sorted super-timeline
+func buildTimeline(modules []string, i instance.Instance) {
+	defs := make(map[string][]timeline.Definition)
+	for _, module := range modules {
+		d := timelineDefinitions(module, i)
+		if len(d) > 0 {
+			defs[module] = d
+		}
+	}
+	if len(defs) == 0 {
+		zap.L().Info("No executed modules declare timeline events, skipping timeline")
+		return
+	}
+
+	entries, err := timeline.Build(defs, i.GetOrionRuntime(), i.GetOrionOutputFilepath(), i.GetTimestampFormat())
+	if err != nil {
+		zap.L().Error("Failed to build timeline: " + err.Error())
+		return
+	}
+
+	host := ""
+	if !i.ForensicMode() {
+		host, _ = os.Hostname()
+	}
+	for _, format := range i.GetTimelineFormats() {
+		fp, err := timeline.WriteFile(entries, format, i.GetOrionRuntime(), i.GetOrionOutputFilepath(), host)
+		if err != nil {
+			zap.L().Error("Failed to write " + format + " timeline: " + err.Error())
+			continue
+		}
+		zap.L().Info("Wrote [" + strconv.Itoa(len(entries)) + "] timeline events to " + fp)
+	}
+}
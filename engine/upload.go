@@ -0,0 +1,133 @@
+// +build darwin linux windows
+
+package engine
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/anthonybm/Orion/instance"
+	"go.uber.org/zap"
+)
+
+// moduleOutputFiles returns the output files written by module (<runtime>_<module>.<ext> and <runtime>_<module>-<output>.<ext>)
+func moduleOutputFiles(module string, i instance.Instance) []string {
+	prefix := filepath.Join(i.GetOrionOutputFilepath(), i.GetOrionRuntime()+"_"+module)
+	files := []string{}
+	for _, pattern := range []string{prefix + ".*", prefix + "-*"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// streamModuleOutput uploads the output files of module to every sink with stream set, below a <runtime>/ directory
+func streamModuleOutput(module string, i instance.Instance) {
+	for _, sink := range i.GetUploadSinks() {
+		if !sink.Config().Stream {
+			continue
+		}
+		for _, fp := range moduleOutputFiles(module, i) {
+			name := i.GetOrionRuntime() + "/" + filepath.Base(fp)
+			err := sink.Upload(fp, name)
+			if err != nil {
+				zap.L().Error("Failed to stream "+filepath.Base(fp)+" to upload '"+sink.Config().Name+"'", zap.String("module", module), zap.Error(err))
+				continue
+			}
+			zap.L().Debug("Streamed "+filepath.Base(fp)+" to upload '"+sink.Config().Name+"'", zap.String("module", module))
+		}
+	}
+}
+
+// uploadPackage uploads the finished package fp to every configured sink and returns an error if any upload failed
+func uploadPackage(fp string, i instance.Instance) error {
+	failed := 0
+	for _, sink := range i.GetUploadSinks() {
+		start := time.Now()
+		zap.L().Info("Uploading " + fp + " to upload '" + sink.Config().Name + "'")
+		err := sink.Upload(fp, filepath.Base(fp))
+		if err != nil {
+			zap.L().Error("Failed to upload "+fp+" to upload '"+sink.Config().Name+"', run Orion with --upload-file to retry", zap.Error(err))
+			failed++
+			continue
+		}
+		zap.L().Info("Uploaded " + fp + " to upload '" + sink.Config().Name + "' in " + time.Now().Sub(start).String())
+	}
+	if failed > 0 {
+		return errors.New(strconv.Itoa(failed) + " of " + strconv.Itoa(len(i.GetUploadSinks())) + " uploads failed")
+	}
+	return nil
+}
+
+// UploadFile uploads an existing package to every configured sink, resuming interrupted uploads of the same file
+func UploadFile(fp string, i instance.Instance) error {
+	if len(i.GetUploadSinks()) == 0 {
+		return errors.New("no [[upload]] sinks in the config file")
+	}
+	if _, err := os.Stat(fp); err != nil {
+		return err
+	}
+	return uploadPackage(fp, i)
+}
+
+// packageOutput zips the output directory to <runtime>.zip for upload and returns the path of the archive
+// The output directory is kept
+func packageOutput(i instance.Instance) (string, error) {
+	files, err := filepath.Glob(i.GetOrionOutputFilepath() + "/*")
+	if err != nil {
+		return "", err
+	}
+	output := i.GetOrionRuntime() + ".zip"
+	zf, err := os.Create(output)
+	if err != nil {
+		return "", err
+	}
+	defer zf.Close()
+	zw := zip.NewWriter(zf)
+
+	for _, file := range files {
+		err := addToZip(zw, file)
+		if err != nil {
+			zap.L().Error("Failed to write " + file + " to archive: " + err.Error())
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return output, zf.Close()
+}
+
+func addToZip(zw *zip.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(file)
+	header.Method = zip.Deflate
+	wr, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(wr, f)
+	return err
+}
@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+"""Example Orion plugin, parses <target>/private/etc/hosts
+
+Copy it to the PluginDir of the config file (plugins.d) and add "MacHostsPlugin" to its modules list to run it. See plugins/plugins.go for the protocol.
+"""
+import json
+import os
+import sys
+
+
+def send(method, params):
+    sys.stdout.write(json.dumps({"jsonrpc": "2.0", "method": method, "params": params}) + "\n")
+
+
+def main():
+    request = json.loads(sys.stdin.readline())
+    params = request["params"]
+    config = params.get("config") or {}
+
+    send("schema", {"header": ["ip", "hostnames", "comment"]})
+    path = os.path.join(params["target"], "private/etc/hosts")
+    try:
+        with open(path, errors="replace") as f:
+            for line in f:
+                entry, _, comment = line.strip().partition("#")
+                fields = entry.split()
+                if not fields and not (comment and config.get("IncludeComments")):
+                    continue
+                send("row", {"values": [fields[0] if fields else "", " ".join(fields[1:]), comment.strip()]})
+    except OSError as e:
+        send("log", {"level": "warn", "message": "could not read %s: %s" % (path, e)})
+
+    sys.stdout.write(json.dumps({"jsonrpc": "2.0", "id": request["id"], "result": {}}) + "\n")
+
+
+if __name__ == "__main__":
+    main()
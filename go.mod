@@ -8,8 +8,11 @@ require (
 	github.com/beevik/etree v1.1.0
 	github.com/karrick/godirwalk v1.16.1
 	github.com/mattn/go-sqlite3 v1.14.2
+	github.com/pkg/sftp v1.12.0
 	github.com/pkg/xattr v0.4.1
 	go.uber.org/zap v1.16.0
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 	gopkg.in/djherbis/times.v1 v1.2.0
+	gopkg.in/yaml.v2 v2.3.0
 	howett.net/plist v0.0.0-20200419221736-3b63eb3a43b5
 )
@@ -3,9 +3,20 @@ package instance
 import (
 	"errors"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/anthonybm/Orion/artifacts"
+	"github.com/anthonybm/Orion/baseline"
 	"github.com/anthonybm/Orion/configs"
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/declarative"
+	"github.com/anthonybm/Orion/plugins"
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/upload"
 	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -35,10 +46,30 @@ type Instance struct {
 	targetpath       string
 	forensicMode     bool
 	mode             string
+	timelineFormats  []string
+	timestampFormat  string
+	artifacts        *artifacts.Registry
+	osVersion        string
+	declarative      map[string]declarative.Spec
+	plugins          map[string]string
+	selection        ModuleSelection
+	uploads          []upload.Sink
+	streams          []*datawriter.Stream
+	throttle         throttle.Config
+	baseline         *baseline.Baseline
+	report           bool
+}
+
+// ModuleSelection holds the command line module selection, entries are module names or module tags
+// An empty selection runs the modules enabled in the config file
+type ModuleSelection struct {
+	Profile string
+	Modules []string
+	Exclude []string
 }
 
 // NewInstance returns a new instance struct based on arguments, should only be called once per run
-func NewInstance(targetpath string, outputformat string, outputPath string, orionRuntime string, loglevel string, configpath string, mode string, noMultithreading bool, forensicMode bool) (Instance, error) {
+func NewInstance(targetpath string, outputformat string, outputPath string, orionRuntime string, loglevel string, configpath string, mode string, noMultithreading bool, forensicMode bool, timelineFormats []string, selection ModuleSelection, baselinePath string, report bool) (Instance, error) {
 	// Instantiate logger and handle any errors
 	logger, logfile, err := util.NewOrionLogger(loglevel, orionRuntime, outputPath)
 	if err != nil {
@@ -53,6 +84,76 @@ func NewInstance(targetpath string, outputformat string, outputPath string, orio
 		return Instance{}, errors.New("failed to parse config file")
 	}
 
+	if selection.Profile != "" {
+		if _, ok := config.GetProfile(selection.Profile); !ok {
+			logger.Error("Unknown profile '"+selection.Profile+"'", zap.Strings("profiles", config.GetProfiles()))
+			return Instance{}, errors.New("unknown profile '" + selection.Profile + "', config defines: " + strings.Join(config.GetProfiles(), ", "))
+		}
+	}
+
+	limits := config.GetThrottle(selection.Profile)
+	if err := limits.Validate(); err != nil {
+		logger.Error("Invalid [throttle] settings: ", zap.String("error", err.Error()))
+		return Instance{}, errors.New("invalid [throttle] settings: " + err.Error())
+	}
+
+	// Set how modules format timestamps for this run
+	timestampFormat, _ := config.GetTimestampFormat()
+	err = timeconv.SetOutputFormat(timestampFormat)
+	if err != nil {
+		logger.Error("Failed to set timestamp format: ", zap.String("error", err.Error()))
+		return Instance{}, err
+	}
+
+	// Load artifact definitions, then apply config files and overrides
+	registry, err := newArtifactRegistry(config, mode)
+	if err != nil {
+		logger.Error("Failed to load artifact definitions: ", zap.String("error", err.Error()))
+		return Instance{}, err
+	}
+	specs, err := loadDeclarativeModules(config, mode, registry)
+	if err != nil {
+		logger.Error("Failed to load declarative modules: ", zap.String("error", err.Error()))
+		return Instance{}, err
+	}
+	pluginDir, _ := config.GetPluginDir()
+	pluginPaths, err := plugins.Discover(pluginDir)
+	if err != nil {
+		logger.Error("Failed to discover plugins: ", zap.String("error", err.Error()))
+		return Instance{}, err
+	}
+	sinks := []upload.Sink{}
+	for _, cfg := range config.GetUploads() {
+		sink, err := upload.New(cfg)
+		if err != nil {
+			logger.Error("Failed to configure upload: ", zap.String("error", err.Error()))
+			return Instance{}, err
+		}
+		sinks = append(sinks, sink)
+	}
+	streams := []*datawriter.Stream{}
+	for _, cfg := range config.GetStreams() {
+		stream, err := datawriter.NewStream(cfg)
+		if err != nil {
+			logger.Error("Failed to configure stream: ", zap.String("error", err.Error()))
+			return Instance{}, err
+		}
+		streams = append(streams, stream)
+	}
+	var previous *baseline.Baseline
+	if baselinePath != "" {
+		previous, err = baseline.Open(baselinePath)
+		if err != nil {
+			logger.Error("Failed to open baseline: ", zap.String("error", err.Error()))
+			return Instance{}, errors.New("failed to open baseline: " + err.Error())
+		}
+		logger.Info("Collecting against baseline " + previous.Runtime() + " from " + baselinePath)
+	}
+	osVersion := artifacts.DetectOSVersion(targetpath, mode)
+	if osVersion != "" {
+		logger.Debug("Detected target OS version " + osVersion)
+	}
+
 	inst := Instance{
 		// orionwriter: orionWriter,
 		orionconfig:      config,
@@ -65,11 +166,84 @@ func NewInstance(targetpath string, outputformat string, outputPath string, orio
 		targetpath:       targetpath,
 		forensicMode:     forensicMode,
 		mode:             mode,
+		timelineFormats:  timelineFormats,
+		timestampFormat:  timestampFormat,
+		artifacts:        registry,
+		osVersion:        osVersion,
+		declarative:      specs,
+		plugins:          pluginPaths,
+		selection:        selection,
+		uploads:          sinks,
+		streams:          streams,
+		throttle:         limits,
+		baseline:         previous,
+		report:           report,
 	}
 
 	return inst, nil
 }
 
+// newArtifactRegistry returns the built-in artifact definitions for mode with the definitions and overrides from config applied
+func newArtifactRegistry(config configs.Config, mode string) (*artifacts.Registry, error) {
+	registry, err := artifacts.NewRegistry(mode)
+	if err != nil {
+		return registry, err
+	}
+	files, _ := config.GetArtifactFiles()
+	for _, file := range files {
+		err = registry.LoadFile(file)
+		if err != nil {
+			return registry, err
+		}
+	}
+	overrides, _ := config.GetArtifactOverrides()
+	for name, paths := range overrides {
+		registry.Override(name, paths)
+	}
+	additions, _ := config.GetArtifactAdditions()
+	for name, paths := range additions {
+		registry.AddPaths(name, paths)
+	}
+	return registry, nil
+}
+
+// loadDeclarativeModules returns the declarative module specs for mode from the files in config by module name
+// Inline spec paths are registered in registry as an artifact definition named after the module
+func loadDeclarativeModules(config configs.Config, mode string, registry *artifacts.Registry) (map[string]declarative.Spec, error) {
+	specs := make(map[string]declarative.Spec)
+	globs, _ := config.GetDeclarativeModuleFiles()
+	for _, glob := range globs {
+		files, err := filepath.Glob(glob)
+		if err != nil {
+			return specs, err
+		}
+		if len(files) == 0 {
+			zap.L().Warn("No declarative module files matched '" + glob + "'")
+		}
+		for _, file := range files {
+			loaded, err := declarative.LoadFile(file)
+			if err != nil {
+				return specs, err
+			}
+			for _, spec := range loaded {
+				if spec.Mode != "" && spec.Mode != mode {
+					continue
+				}
+				if _, ok := specs[spec.Name]; ok {
+					return specs, errors.New("declarative module '" + spec.Name + "' is defined more than once")
+				}
+				if spec.Artifact == "" {
+					registry.Override(spec.Name, spec.Paths)
+				} else if len(spec.Paths) > 0 {
+					registry.AddPaths(spec.Artifact, spec.Paths)
+				}
+				specs[spec.Name] = spec
+			}
+		}
+	}
+	return specs, nil
+}
+
 func (i Instance) CloseLogger() error {
 	return i.orionlogfile.Close()
 }
@@ -100,6 +274,11 @@ func (i Instance) GetOrionModules() ([]string, error) {
 	return mods, err
 }
 
+// GetModuleSelection returns the modules, profile and exclusions selected on the command line
+func (i Instance) GetModuleSelection() ModuleSelection {
+	return i.selection
+}
+
 // GetOrionConfig returns the Config for this Instance of Orion
 func (i Instance) GetOrionConfig() configs.Config {
 	return i.orionconfig
@@ -115,6 +294,97 @@ func (i Instance) ForensicMode() bool {
 	return i.forensicMode
 }
 
+// GetTimelineFormats returns the super-timeline formats to write after modules finish, empty if disabled
+func (i Instance) GetTimelineFormats() []string {
+	return i.timelineFormats
+}
+
+// GetTimestampFormat returns the format modules write timestamps in for this run
+func (i Instance) GetTimestampFormat() string {
+	return i.timestampFormat
+}
+
+// GetArtifactGlobs returns the path globs of the named artifact definition relative to the target path,
+// filtered by the target OS version
+func (i Instance) GetArtifactGlobs(name string) []string {
+	if i.artifacts == nil {
+		zap.L().Error("No artifact definitions loaded, cannot resolve '" + name + "'")
+		return []string{}
+	}
+	globs, err := i.artifacts.Globs(name, i.osVersion)
+	if err != nil {
+		zap.L().Error("Failed to resolve artifact: " + err.Error())
+	}
+	return globs
+}
+
+// GetArtifactPaths returns the paths on the target that match the named artifact definition
+func (i Instance) GetArtifactPaths(name string) []string {
+	return util.Multiglob(i.GetArtifactGlobs(name), i.GetTargetPath())
+}
+
+// GetDeclarativeModule returns the declarative module spec with the given name and whether it exists
+func (i Instance) GetDeclarativeModule(name string) (declarative.Spec, bool) {
+	spec, ok := i.declarative[name]
+	return spec, ok
+}
+
+// GetDeclarativeModules returns the names of the loaded declarative modules
+func (i Instance) GetDeclarativeModules() []string {
+	names := []string{}
+	for name := range i.declarative {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPlugins returns the module names of the discovered plugins
+func (i Instance) GetPlugins() []string {
+	names := []string{}
+	for name := range i.plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPluginPath returns the path of the plugin executable with the given module name and whether it exists
+func (i Instance) GetPluginPath(name string) (string, bool) {
+	path, ok := i.plugins[name]
+	return path, ok
+}
+
+// GetUploadSinks returns the sinks the finished package is uploaded to, empty if uploads are not configured
+func (i Instance) GetUploadSinks() []upload.Sink {
+	return i.uploads
+}
+
+// GetStreams returns the log pipelines module output rows are sent to, empty if streams are not configured
+func (i Instance) GetStreams() []*datawriter.Stream {
+	return i.streams
+}
+
+// GetThrottle returns the resource limits of the run, the [throttle] table overridden by the table of the selected profile
+func (i Instance) GetThrottle() throttle.Config {
+	return i.throttle
+}
+
+// GetBaseline returns the previous run given with --baseline, nil when collecting everything
+func (i Instance) GetBaseline() *baseline.Baseline {
+	return i.baseline
+}
+
+// GetReport returns true when an HTML report of the run is written after the modules finish
+func (i Instance) GetReport() bool {
+	return i.report
+}
+
+// GetTargetOSVersion returns the OS version detected on the target, empty if unknown
+func (i Instance) GetTargetOSVersion() string {
+	return i.osVersion
+}
+
 // GetTargetPath returns the string representing the path to the target for modules
 func (i Instance) GetTargetPath() string {
 	return i.targetpath
@@ -7,13 +7,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
 	"go.uber.org/zap"
 )
 
@@ -30,10 +30,6 @@ var (
 	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
 )
 
-var (
-	filepathAslLocation = "private/var/log/asl/*.asl"
-)
-
 // Start executes the module with Config instructions and writes to OrionWriter
 func (m MacAppleSystemLogModule) Start(inst instance.Instance) error {
 	zap.L().Warn("Does not parse multi-line asl entries", zap.String("module", moduleName))
@@ -45,6 +41,11 @@ func (m MacAppleSystemLogModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacAppleSystemLogModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagNeedsRoot}}
+}
+
 func (m MacAppleSystemLogModule) asl(inst instance.Instance) error {
 	header := []string{
 		"source_file",
@@ -64,9 +65,9 @@ func (m MacAppleSystemLogModule) asl(inst instance.Instance) error {
 	values := [][]string{}
 
 	// get all .asl files in path
-	files, _ := filepath.Glob(filepath.Join(inst.GetTargetPath(), filepathAslLocation))
+	files := inst.GetArtifactPaths("MacOSAppleSystemLogs")
 	if len(files) == 0 {
-		zap.L().Debug("files not found in: '"+filepath.Join(inst.GetTargetPath(), filepathAslLocation)+"'.", zap.String("module", moduleName))
+		zap.L().Debug(fmt.Sprintf("files not found in: '%s'.", inst.GetArtifactGlobs("MacOSAppleSystemLogs")), zap.String("module", moduleName))
 		return nil // Do not throw error for this
 	}
 
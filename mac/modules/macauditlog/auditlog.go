@@ -12,6 +12,7 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
 	"github.com/anthonybm/Orion/util"
 	"github.com/beevik/etree"
 	"go.uber.org/zap"
@@ -49,9 +50,6 @@ var (
 		"retval",
 		"text_fields",
 	}
-	filepathsAuditLogs = []string{
-		"private/var/audit/*",
-	}
 )
 
 func (m MacAuditLogModule) Start(inst instance.Instance) error {
@@ -62,6 +60,11 @@ func (m MacAuditLogModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacAuditLogModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagNeedsRoot}}
+}
+
 func (m MacAuditLogModule) auditlog(inst instance.Instance) error {
 	zap.L().Warn("Experimental module - no test data was used to generate - verify results!", zap.String("module", moduleName))
 
@@ -107,7 +110,7 @@ func (m MacAuditLogModule) parseAuditLogs(inst instance.Instance) ([][]string, e
 	values := [][]string{}
 	count := 0
 
-	auditLogPaths := util.Multiglob(filepathsAuditLogs, inst.GetTargetPath())
+	auditLogPaths := inst.GetArtifactPaths("MacOSAuditLogs")
 	if len(auditLogPaths) == 0 {
 		return [][]string{}, errors.New("no audit log files were found")
 	}
@@ -1,5 +1,3 @@
-// +build darwin
-
 package macautoruns
 
 import (
@@ -14,9 +12,14 @@ import (
 	"strings"
 
 	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/autoruns"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -26,7 +29,7 @@ type MacAutorunsModule struct {
 var (
 	moduleName  = "MacAutorunsModule"
 	mode        = "mac"
-	version     = "1.0"
+	version     = "1.1"
 	description = `
 	Reads and parses various persistent and auto-start programs, daemons, services
 	Tries to parse plist configuration files and check code signatures on programs
@@ -60,44 +63,6 @@ var (
 		"md5",
 		"extras",
 	}
-	filepathsCron = []string{
-		"private/var/at/tabs/*",
-	}
-	filepathsKernelExtentions = []string{
-		"System/Library/Extensions/*/Contents/Info.plist",
-		"Library/Extensions/*/Contents/Info.plist",
-	}
-	filepathsLaunchAgents = []string{
-		"System/Library/LaunchAgents/*",
-		"Library/LaunchAgents/*",
-	}
-	filepathsLaunchDaemons = []string{
-		"System/Library/LaunchDaemons",
-		"Library/LaunchDaemons",
-	}
-	filepathsLoginItems = []string{
-		"Users/*/Library/Preferences/com.apple.loginitems.plist",
-		"private/var/*/Library/Preferences/com.apple.loginitems.plist",
-	}
-	filepathsLoginRestartApps = []string{
-		"Users/*/Library/Preferences/ByHost/com.apple.loginwindow.*.plist",
-	}
-	filepathsSandboxLoginItemsGlob = []string{
-		"var/db/com.apple.xpc.launchd/disabled.*.plist",
-	}
-	filepathScriptingAdditions = []string{
-		"System/Library/ScriptingAdditions/*.osax",
-		"Library/ScriptingAdditions/*.osax",
-	}
-	filepathsStartupItems = []string{
-		"System/Library/StartupItems",
-		"Library/StartupItems",
-	}
-	filepathsPeriodic = []string{
-		"private/etc/periodic/daily",
-		"private/etc/periodic/weekly",
-		"private/etc/periodic/monthly",
-	}
 )
 
 func (m MacAutorunsModule) Start(inst instance.Instance) error {
@@ -108,6 +73,32 @@ func (m MacAutorunsModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacAutorunsModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagPersistence}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacAutorunsModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "mtime", Type: "Persistence Item Modified", MACB: "M..."},
+				{Field: "btime", Type: "Persistence Item Created", MACB: "...B"},
+			},
+			DescriptionFields: []string{"source_name", "source_file", "program"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacAutorunsModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_file", "program"}, Ignore: []string{"atime"}},
+	}
+}
+
 func (m MacAutorunsModule) autoruns(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -228,7 +219,7 @@ func (m MacAutorunsModule) kernelExtentions(inst instance.Instance) ([][]string,
 	values := [][]string{}
 	count := 0
 
-	kernelExtentionsPaths := util.Multiglob(filepathsKernelExtentions, inst.GetTargetPath())
+	kernelExtentionsPaths := inst.GetArtifactPaths("MacOSKernelExtensions")
 
 	for _, path := range kernelExtentionsPaths {
 		fi, err := os.Stat(path)
@@ -261,9 +252,7 @@ func (m MacAutorunsModule) kernelExtentions(inst instance.Instance) ([][]string,
 			continue
 		}
 		for _, item := range data {
-			if val, ok := item["CFBundleName"].(string); ok {
-				valmap["program_name"] = strings.TrimSpace(val)
-			}
+			valmap["program_name"] = autoruns.KextName(item)
 			extra, err := json.Marshal(item)
 			if err != nil {
 				valmap["extras"] = "<kext>" + strings.TrimSpace(fmt.Sprint(item)) + "</kext>"
@@ -289,8 +278,8 @@ func (m MacAutorunsModule) launchAgentsDaemons(inst instance.Instance) ([][]stri
 	values := [][]string{}
 	count := 0
 
-	launchPaths := util.Multiglob(filepathsLaunchAgents, inst.GetTargetPath())
-	launchPaths = append(launchPaths, util.Multiglob(filepathsLaunchDaemons, inst.GetTargetPath())...)
+	launchPaths := inst.GetArtifactPaths("MacOSLaunchAgents")
+	launchPaths = append(launchPaths, inst.GetArtifactPaths("MacOSLaunchDaemons")...)
 
 	for _, path := range launchPaths {
 		fi, err := os.Stat(path)
@@ -323,18 +312,11 @@ func (m MacAutorunsModule) launchAgentsDaemons(inst instance.Instance) ([][]stri
 			continue
 		}
 		for _, item := range data {
-			if val, ok := item["Label"].(string); ok {
-				valmap["program_name"] = val
-			}
-
-			if val, ok := item["Program"].(string); ok {
-				valmap["program"] = val
-			}
-
-			if val, ok := item["ProgramArguments"].([]interface{}); ok {
-				if len(val) > 1 {
-					valmap["program_arguments"] = fmt.Sprint(val[1:])
-				}
+			launchItem := autoruns.ParseLaunchItem(item)
+			valmap["program_name"] = launchItem.Label
+			valmap["program"] = launchItem.Program
+			if len(launchItem.Arguments) > 0 {
+				valmap["arguments"] = fmt.Sprint(launchItem.Arguments)
 			}
 
 			entry, err := util.UnsafeEntryFromMap(valmap, header)
@@ -355,7 +337,7 @@ func (m MacAutorunsModule) loginItems(inst instance.Instance) ([][]string, error
 	values := [][]string{}
 
 	// Glob LoginRestartApps Items
-	loginItemsPlistPaths := util.Multiglob(filepathsLoginItems, inst.GetTargetPath())
+	loginItemsPlistPaths := inst.GetArtifactPaths("MacOSLoginItems")
 	if len(loginItemsPlistPaths) == 0 {
 		return [][]string{}, errors.New("no Login Items were found")
 	}
@@ -384,22 +366,29 @@ func (m MacAutorunsModule) loginItems(inst instance.Instance) ([][]string, error
 		}
 
 		for _, item := range data {
-			machelpers.PrintPlistAsJSON(item)
-			zap.L().Warn(fmt.Sprintf("EXIT UNIMPLEMENTED PARSING OF: %s \n, had no test data", item), zap.String("module", moduleName))
-			// if val, ok := item["TALAppsToRelaunchAtLogin"].(interface{}); ok {
-			// 	for _, i := range val.([]interface{}) {
-			// 		valmap["program_name"] = fmt.Sprint(i.(map[string]interface{})["BundleId"])
-			// 		valmap["program"] = fmt.Sprint(i.(map[string]interface{})["Path"])
-
-			// 		entry, err := util.UnsafeEntryFromMap(valmap, header)
-			// 		if err != nil {
-			// 			zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
-			// 			continue
-			// 		}
-			// 		values = append(values, entry)
-			// 		count++
-			// 	}
-			// }
+			for _, loginItem := range autoruns.ParseLoginItems(item) {
+				valmap["program_name"] = loginItem.Name
+				valmap["program"] = ""
+				valmap["extras"] = ""
+				if len(loginItem.Data) > 0 {
+					bm, err := machelpers.DecodeBookmark(loginItem.Data)
+					if err != nil {
+						zap.L().Debug("could not decode login item '"+loginItem.Name+"': "+err.Error(), zap.String("module", moduleName))
+					} else {
+						valmap["program"] = bm.Path
+						extras, _ := json.Marshal(map[string]interface{}{"bookmark_format": bm.Format, "volume_name": bm.VolumeName, "target_created": timeconv.Format(bm.Created)})
+						valmap["extras"] = string(extras)
+					}
+				}
+
+				entry, err := util.UnsafeEntryFromMap(valmap, header)
+				if err != nil {
+					zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+					continue
+				}
+				values = append(values, entry)
+				count++
+			}
 		}
 	}
 	zap.L().Debug(fmt.Sprintf("Parsed [%d] Login Item entries", count), zap.String("module", moduleName))
@@ -411,7 +400,7 @@ func (m MacAutorunsModule) loginRestartApps(inst instance.Instance) ([][]string,
 	values := [][]string{}
 
 	// Glob LoginRestartApps Items
-	loginRestartAppsPlistPath := util.Multiglob(filepathsLoginRestartApps, inst.GetTargetPath())
+	loginRestartAppsPlistPath := inst.GetArtifactPaths("MacOSLoginRestartApps")
 	if len(loginRestartAppsPlistPath) == 0 {
 		return [][]string{}, errors.New("no Login Restart Apps were found")
 	}
@@ -441,19 +430,17 @@ func (m MacAutorunsModule) loginRestartApps(inst instance.Instance) ([][]string,
 		}
 
 		for _, item := range data {
-			if val, ok := item["TALAppsToRelaunchAtLogin"].(interface{}); ok {
-				for _, i := range val.([]interface{}) {
-					valmap["program_name"] = fmt.Sprint(i.(map[string]interface{})["BundleId"])
-					valmap["program"] = fmt.Sprint(i.(map[string]interface{})["Path"])
+			for _, app := range autoruns.ParseLoginRestartApps(item) {
+				valmap["program_name"] = app.BundleID
+				valmap["program"] = app.Path
 
-					entry, err := util.UnsafeEntryFromMap(valmap, header)
-					if err != nil {
-						zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
-						continue
-					}
-					values = append(values, entry)
-					count++
+				entry, err := util.UnsafeEntryFromMap(valmap, header)
+				if err != nil {
+					zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+					continue
 				}
+				values = append(values, entry)
+				count++
 			}
 		}
 	}
@@ -466,7 +453,7 @@ func (m MacAutorunsModule) cron(inst instance.Instance) ([][]string, error) {
 	values := [][]string{}
 
 	// Glob Cron
-	cronPaths := util.Multiglob(filepathsCron, inst.GetTargetPath())
+	cronPaths := inst.GetArtifactPaths("MacOSCronTabs")
 	if len(cronPaths) == 0 {
 		// return [][]string{}, errors.New("no cron items were found")
 		zap.L().Debug("No cron items were found", zap.String("module", moduleName))
@@ -519,7 +506,7 @@ func (m MacAutorunsModule) periodicItems(inst instance.Instance) ([][]string, er
 	values := [][]string{}
 
 	// Glob Periodic Items
-	periodicItemsPaths := util.Multiglob(filepathsPeriodic, inst.GetTargetPath())
+	periodicItemsPaths := inst.GetArtifactPaths("MacOSPeriodicScripts")
 	if len(periodicItemsPaths) == 0 {
 		return [][]string{}, errors.New("no periodic items were found")
 	}
@@ -557,7 +544,7 @@ func (m MacAutorunsModule) sandboxedLoginItems(inst instance.Instance) ([][]stri
 	values := [][]string{}
 
 	// Glob Sandboxed Login Items
-	sandboxLoginItemsPaths := util.Multiglob(filepathsSandboxLoginItemsGlob, inst.GetTargetPath())
+	sandboxLoginItemsPaths := inst.GetArtifactPaths("MacOSDisabledLaunchItems")
 	if len(sandboxLoginItemsPaths) == 0 {
 		return [][]string{}, errors.New("no sandbox login items were found")
 	}
@@ -588,19 +575,15 @@ func (m MacAutorunsModule) sandboxedLoginItems(inst instance.Instance) ([][]stri
 		// Format of this requires that we loop over key,vals and write entries for each False item
 
 		for _, i := range data {
-			for k, v := range i {
-				if val, ok := v.(bool); ok {
-					if val == false {
-						valmap["program_name"] = k
-						entry, err := util.UnsafeEntryFromMap(valmap, header)
-						if err != nil {
-							zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
-							continue
-						}
-						values = append(values, entry)
-						sandboxedLoginItemsCount++
-					}
+			for _, label := range autoruns.DisabledItems(i) {
+				valmap["program_name"] = label
+				entry, err := util.UnsafeEntryFromMap(valmap, header)
+				if err != nil {
+					zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+					continue
 				}
+				values = append(values, entry)
+				sandboxedLoginItemsCount++
 			}
 		}
 	}
@@ -613,7 +596,7 @@ func (m MacAutorunsModule) scriptingAdditions(inst instance.Instance) ([][]strin
 	values := [][]string{}
 	count := 0
 
-	scriptingAdditionsPaths := util.Multiglob(filepathScriptingAdditions, inst.GetTargetPath())
+	scriptingAdditionsPaths := inst.GetArtifactPaths("MacOSScriptingAdditions")
 	if len(scriptingAdditionsPaths) == 0 {
 		// return [][]string{}, errors.New("no cron items were found")
 		zap.L().Debug("No Scripting Additions were found", zap.String("module", moduleName))
@@ -664,7 +647,7 @@ func (m MacAutorunsModule) startupItems(inst instance.Instance) ([][]string, err
 	values := [][]string{}
 	count := 0
 
-	startupItemsPaths := util.Multiglob(filepathScriptingAdditions, inst.GetTargetPath())
+	startupItemsPaths := inst.GetArtifactPaths("MacOSStartupItems")
 	if len(startupItemsPaths) == 0 {
 		// return [][]string{}, errors.New("no cron items were found")
 		zap.L().Debug("No Startup Items were found", zap.String("module", moduleName))
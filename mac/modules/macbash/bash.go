@@ -1,17 +1,17 @@
-// +build darwin
-
 package macbash
 
 import (
-	"bufio"
-	"os"
 	"strconv"
-	"strings"
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/shellhistory"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -21,19 +21,29 @@ type MacBashModule struct {
 var (
 	moduleName  = "MacBashModule"
 	mode        = "mac"
-	version     = "1.0"
+	version     = "1.1"
 	description = `
-	Reads and parses the .*_history and .bash_sessions on disk
+	Reads and parses the bash, zsh and fish histories and the Terminal .bash_sessions and .zsh_sessions on disk
+	Commands are timestamped from bash #<epoch> lines (HISTTIMEFORMAT), zsh extended history and fish when: keys,
+	zsh also records the duration of commands in seconds
 	`
 	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
 )
 
 var (
-	filepathBashLocations = []string{
-		"Users/*/.*_history",
-		"Users/*/.bash_sessions/*",
-		"private/var/*/.*_history",
-		"private/var/*/.bash_sessions/*",
+	header = []string{
+		"mtime",
+		"atime",
+		"ctime",
+		"btime",
+		"src_file",
+		"user",
+		"shell",
+		"session_id",
+		"item_index",
+		"timestamp",
+		"duration",
+		"cmd",
 	}
 )
 
@@ -45,18 +55,24 @@ func (m MacBashModule) Start(inst instance.Instance) error {
 	return err
 }
 
-func (m MacBashModule) bash(inst instance.Instance) error {
-	header := []string{
-		"mtime",
-		"atime",
-		"ctime",
-		"btime",
-		"src_file",
-		"user",
-		"item_index",
-		"cmd",
+// Info returns the module description, version and tags shown by --list
+func (m MacBashModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacBashModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "timestamp", Type: "Shell Command Executed"}},
+			UserField:         "user",
+			DescriptionFields: []string{"shell", "session_id", "cmd"},
+		},
 	}
+}
 
+func (m MacBashModule) bash(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
 		zap.L().Error("Error running " + moduleName + ": " + err.Error())
@@ -65,38 +81,38 @@ func (m MacBashModule) bash(inst instance.Instance) error {
 
 	values := [][]string{}
 
-	// get users
-	files := util.Multiglob(filepathBashLocations, inst.GetTargetPath())
+	files := inst.GetArtifactPaths("MacOSBashHistory")
 	if len(files) <= 0 {
 		zap.L().Warn("No .*_history and .bash_sessions were found.", zap.String("module", moduleName))
 	} else {
-		zap.L().Debug("Parsing ["+strconv.Itoa(len(files))+"] bash items", zap.String("module", moduleName))
+		zap.L().Debug("Parsing ["+strconv.Itoa(len(files))+"] shell history items", zap.String("module", moduleName))
 	}
 
-	userlist := []string{}
-	// get all bash and history files
+	// parse all history and session files
 	parsedfilecount := 0
 	parsedentrycount := 0
 	for _, fp := range files {
-		user := util.GetUsernameFromPath(fp)
-		userlist = append(userlist, user)
+		historyFile, ok := shellhistory.Identify(fp)
+		if !ok {
+			zap.L().Debug("Skipping '"+fp+"', not a history file", zap.String("module", moduleName))
+			continue
+		}
+		commands, err := m.parseFile(fp, historyFile.Shell)
+		if err != nil {
+			zap.L().Debug("Could not parse '"+fp+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
 
-		// parse files
 		fileMetadata, err := machelpers.FileMetadata(fp, moduleName)
 		if err != nil {
 			zap.L().Debug("Could not get metadata for '"+fp+"': "+err.Error(), zap.String("module", moduleName))
 		}
-		file, err := os.Open(fp)
-		if err != nil {
-			zap.L().Debug("Could not open '"+fp+"': "+err.Error(), zap.String("module", moduleName))
-		}
-		defer file.Close()
-
-		scanner := bufio.NewScanner(file)
-		index := 0
-		for scanner.Scan() {
-			line := scanner.Text()
-
+		user := util.GetUsernameFromPath(fp)
+		for _, c := range commands {
+			duration := ""
+			if c.HasDuration {
+				duration = strconv.FormatInt(int64(c.Duration.Seconds()), 10)
+			}
 			entry := []string{
 				fileMetadata["mtime"],
 				fileMetadata["atime"],
@@ -104,15 +120,16 @@ func (m MacBashModule) bash(inst instance.Instance) error {
 				fileMetadata["btime"],
 				fp,
 				user,
-				strconv.Itoa(index),
-				strings.TrimSpace(line),
+				string(historyFile.Shell),
+				historyFile.SessionID,
+				strconv.Itoa(c.Index),
+				timeconv.Format(c.Time),
+				duration,
+				c.Command,
 			}
 			values = append(values, entry)
 			parsedentrycount++
 		}
-		if err := scanner.Err(); err != nil {
-			zap.L().Debug("error reading input: "+err.Error(), zap.String("module", moduleName))
-		}
 		parsedfilecount++
 	}
 
@@ -133,3 +150,13 @@ func (m MacBashModule) bash(inst instance.Instance) error {
 	}
 	return nil
 }
+
+// parseFile returns the commands of the history file fp
+func (m MacBashModule) parseFile(fp string, shell shellhistory.Shell) ([]shellhistory.Command, error) {
+	file, err := throttle.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return shellhistory.Parse(file, shell)
+}
@@ -0,0 +1,11 @@
+package macbash
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacBashModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacBashModule{}, moduletest.Options{Mask: []string{"mtime", "atime", "ctime", "btime"}})
+}
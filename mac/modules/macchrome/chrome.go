@@ -12,7 +12,10 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -27,9 +30,8 @@ var (
 )
 
 var (
-	filepathTemporary           = "ChromeTemp"
-	filepathsChromeLocationGlob = []string{"Users/*/Library/Application Support/Google/Chrome/"}
-	profileHeader               = []string{
+	filepathTemporary = "ChromeTemp"
+	profileHeader     = []string{
 		"user",
 		"profile",
 		"active_time",
@@ -107,6 +109,34 @@ func (m MacChromeModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacChromeModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagBrowser}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacChromeModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName + "-history",
+			Incremental:       true,
+			Events:            []timeline.Event{{Field: "visit_time", Type: "URL Visited"}},
+			UserField:         "user",
+			DescriptionFields: []string{"url", "title", "search_term"},
+		},
+		{
+			Output:      moduleName + "-downloads",
+			Incremental: true,
+			Events: []timeline.Event{
+				{Field: "download_started", Type: "Download Started"},
+				{Field: "download_finished", Type: "Download Finished"},
+			},
+			UserField:         "user",
+			DescriptionFields: []string{"download_url", "current_path"},
+		},
+	}
+}
+
 func (m MacChromeModule) chrome(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -118,6 +148,7 @@ func (m MacChromeModule) chrome(inst instance.Instance) error {
 	if err != nil {
 		return err
 	}
+	forensicMode = forensicMode || inst.ForensicMode()
 
 	profileValues := [][]string{}
 	downloadValues := [][]string{}
@@ -127,6 +158,7 @@ func (m MacChromeModule) chrome(inst instance.Instance) error {
 	// Start Parsing
 
 	// for all chrome dirs on disk parse their local state files
+	filepathsChromeLocationGlob := inst.GetArtifactGlobs("MacOSChromeDataDirectory")
 	chromeLocations := util.Multiglob(filepathsChromeLocationGlob, inst.GetTargetPath())
 	if len(chromeLocations) == 0 {
 		zap.L().Debug(fmt.Sprintf("No chrome files were found in %s", filepathsChromeLocationGlob), zap.String("module", moduleName))
@@ -178,7 +210,7 @@ func (m MacChromeModule) chrome(inst instance.Instance) error {
 						for key, val := range v.(map[string]interface{}) {
 							if itemexists(profileHeader, key) {
 								if strings.Contains(key, "time") {
-									valmap[key] = time.Unix(int64(val.(float64)), 0).UTC().String()
+									valmap[key] = timeconv.Format(timeconv.FromUnix(val.(float64)))
 								} else {
 									t, err := util.InterfaceToString(val)
 									valmap[key] = t
@@ -454,7 +486,7 @@ func (m MacChromeModule) parseChromeVisitHistoryValues(user string, profile stri
 
 		valmap["user"] = user
 		valmap["profile"] = profile
-		valmap["visit_time"], err = util.ChromeTime(e[0])
+		valmap["visit_time"], err = timeconv.ConvertToString(timeconv.WebKit, e[0])
 		if err != nil {
 			zap.L().Error(fmt.Sprintf("error parsing visit_time time - %s", err.Error()), zap.String("module", moduleName))
 		}
@@ -467,7 +499,7 @@ func (m MacChromeModule) parseChromeVisitHistoryValues(user string, profile stri
 		valmap["visit_duration"] = time.Duration(visitDurationInt * 1000).String()
 		valmap["visit_count"] = e[4]
 		valmap["typed_count"] = e[5]
-		valmap["last_visit_time"], err = util.ChromeTime(e[6])
+		valmap["last_visit_time"], err = timeconv.ConvertToString(timeconv.WebKit, e[6])
 		if err != nil {
 			zap.L().Error(fmt.Sprintf("error parsing visit_time time - %s", err.Error()), zap.String("module", moduleName))
 		}
@@ -544,11 +576,11 @@ func (m MacChromeModule) parseChromeDownloadValues(user string, profile string,
 		valmap["profile"] = profile
 		valmap["current_path"] = e[0]
 		valmap["download_path"] = e[1]
-		valmap["download_started"], err = util.ChromeTime(e[2])
+		valmap["download_started"], err = timeconv.ConvertToString(timeconv.WebKit, e[2])
 		if err != nil {
 			zap.L().Error(fmt.Sprintf("error parsing download_started time - %s", err.Error()), zap.String("module", moduleName))
 		}
-		valmap["download_finished"], err = util.ChromeTime(e[3])
+		valmap["download_finished"], err = timeconv.ConvertToString(timeconv.WebKit, e[3])
 		if err != nil {
 			zap.L().Error(fmt.Sprintf("error parsing download_finished time - %s", err.Error()), zap.String("module", moduleName))
 		}
@@ -560,7 +592,7 @@ func (m MacChromeModule) parseChromeDownloadValues(user string, profile string,
 			if err != nil {
 				zap.L().Error(fmt.Sprintf("Error converting %s to string - %s", e[6], err.Error()), zap.String("module", moduleName))
 			} else {
-				valmap["last_modified"] = val.UTC().Format(time.RFC3339)
+				valmap["last_modified"] = timeconv.Format(val)
 			}
 		}
 
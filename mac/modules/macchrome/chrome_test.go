@@ -0,0 +1,11 @@
+package macchrome
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacChromeModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacChromeModule{}, moduletest.Options{})
+}
@@ -0,0 +1,470 @@
+package maccloudsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacCloudSyncModule wraps Module methods
+type MacCloudSyncModule struct{}
+
+var (
+	moduleName  = "MacCloudSyncModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the databases of the iCloud Drive, Google Drive (Drive for desktop), OneDrive and Dropbox sync
+	clients of each user and lists the synced files with their path in the cloud, local path, size and modified time.
+	Files stay listed after they are removed from the disk, the local path is empty when the client does not record
+	where its files are synced to (Google Drive, OneDrive). Dropbox encrypts its file cache, its rows are the upload,
+	download and edit events of sync_history.db with the time of the event as modified
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"client",
+		"account",
+		"item_id",
+		"name",
+		"cloud_path",
+		"local_path",
+		"is_folder",
+		"size",
+		"created",
+		"modified",
+		"extras",
+	}
+)
+
+// Start starts the MacCloudSyncModule, should not be manually called
+func (m MacCloudSyncModule) Start(inst instance.Instance) error {
+	err := m.cloudsync(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacCloudSyncModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacCloudSyncModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "created", Type: "Cloud File Created"}, {Field: "modified", Type: "Cloud File Modified"}},
+			UserField:         "user",
+			DescriptionFields: []string{"client", "cloud_path"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacCloudSyncModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_file", "item_id"}},
+	}
+}
+
+func (m MacCloudSyncModule) cloudsync(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+	count := 0
+
+	for _, client := range []struct {
+		name     string
+		artifact string
+		parse    func(dbpath string) ([]map[string]string, error)
+	}{
+		{"iCloud Drive", "MacOSiCloudDriveClientDB", m.iCloudDrive},
+		{"Google Drive", "MacOSGoogleDriveFS", m.googleDrive},
+		{"OneDrive", "MacOSOneDriveSyncEngineDB", m.oneDrive},
+		{"Dropbox", "MacOSDropboxSyncHistory", m.dropbox},
+	} {
+		paths := inst.GetArtifactPaths(client.artifact)
+		count += len(paths)
+		for _, dbpath := range paths {
+			rows, err := client.parse(dbpath)
+			if err != nil {
+				zap.L().Error("could not read "+client.name+" database '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			for _, valmap := range rows {
+				valmap["source_file"] = dbpath
+				if strings.Contains(dbpath, "/Users/") {
+					valmap["user"] = util.GetUsernameFromPath(dbpath)
+				}
+				valmap["client"] = client.name
+
+				entry, err := util.UnsafeEntryFromMap(valmap, header)
+				if err != nil {
+					zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+					continue
+				}
+				values = append(values, entry)
+			}
+		}
+	}
+	if count == 0 {
+		zap.L().Warn("No cloud sync client databases were found", zap.String("module", moduleName))
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] synced files from %d databases", len(values), count), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// iCloudDrive lists the items of the CloudDocs client.db, paths are resolved from the parent of each item and the
+// local path is the default iCloud Drive folder of the user
+func (m MacCloudSyncModule) iCloudDrive(dbpath string) ([]map[string]string, error) {
+	column, err := m.columns(dbpath, "client_items")
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT IFNULL(item_id, '') AS item_id, IFNULL(item_parent_id, '') AS parent_id,
+	IFNULL(item_filename, '') AS name, IFNULL(%s, '') AS item_type, IFNULL(%s, '') AS size, IFNULL(%s, '') AS created,
+	IFNULL(%s, '') AS modified, IFNULL(%s, '') AS last_used FROM client_items ORDER BY rowid`,
+		column("item_type"), column("version_size"), column("item_birthtime"), column("version_mtime"), column("item_lastusedtime"))
+	entries, err := util.QueryDB(dbpath, query, []string{"item_id", "parent_id", "name", "item_type", "size", "created", "modified", "last_used"}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTree()
+	for _, e := range entries {
+		t.add(e[0], e[1], e[2])
+	}
+	localRoot := ""
+	if strings.Contains(dbpath, "/Users/") {
+		localRoot = path.Join("/Users", util.GetUsernameFromPath(dbpath), "Library/Mobile Documents/com~apple~CloudDocs")
+	}
+
+	rows := []map[string]string{}
+	for _, e := range entries {
+		// the root of the zone has no name
+		if e[2] == "" {
+			continue
+		}
+		valmap := m.row()
+		valmap["item_id"] = e[0]
+		valmap["name"] = e[2]
+		valmap["cloud_path"] = t.path(e[0])
+		if localRoot != "" {
+			valmap["local_path"] = path.Join(localRoot, valmap["cloud_path"])
+		}
+		// item_type is 0 for directories, other types are documents, symlinks and Finder bookmarks
+		valmap["is_folder"] = m.bool(e[3] == "0")
+		valmap["size"] = e[4]
+		valmap["created"] = m.time(timeconv.Unix, e[5])
+		valmap["modified"] = m.time(timeconv.Unix, e[6])
+		valmap["extras"] = m.extras(map[string]interface{}{"item_type": e[3], "last_used": m.time(timeconv.Unix, e[7])})
+		rows = append(rows, valmap)
+	}
+	return rows, nil
+}
+
+// googleDrive lists the items of the metadata_sqlite_db of Drive for desktop (DriveFS), the account is the folder
+// of the database
+func (m MacCloudSyncModule) googleDrive(dbpath string) ([]map[string]string, error) {
+	column, err := m.columns(dbpath, "items")
+	if err != nil {
+		return nil, err
+	}
+	// an item can have several parents, the path is resolved from the first
+	query := fmt.Sprintf(`SELECT i.stable_id, IFNULL(i.id, '') AS id,
+	IFNULL((SELECT p.parent_stable_id FROM stable_parents p WHERE p.item_stable_id = i.stable_id LIMIT 1), '') AS parent_id,
+	IFNULL(i.local_title, '') AS name, IFNULL(i.is_folder, 0) AS is_folder, IFNULL(%s, '') AS size,
+	IFNULL(%s, '') AS modified, IFNULL(%s, '') AS trashed, IFNULL(%s, '') AS mime_type FROM items i ORDER BY i.stable_id`,
+		column("file_size"), column("modified_date"), column("trashed"), column("mime_type"))
+	entries, err := util.QueryDB(dbpath, query, []string{"stable_id", "id", "parent_id", "name", "is_folder", "size", "modified", "trashed", "mime_type"}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTree()
+	for _, e := range entries {
+		t.add(e[0], e[2], e[3])
+	}
+
+	rows := []map[string]string{}
+	for _, e := range entries {
+		valmap := m.row()
+		valmap["account"] = filepath.Base(filepath.Dir(dbpath))
+		valmap["item_id"] = e[1]
+		valmap["name"] = e[3]
+		valmap["cloud_path"] = t.path(e[0])
+		valmap["is_folder"] = m.bool(e[4] == "1")
+		if e[4] != "1" {
+			valmap["size"] = e[5]
+		}
+		valmap["modified"] = m.time(timeconv.UnixMilli, e[6])
+		valmap["extras"] = m.extras(map[string]interface{}{"trashed": m.flag(e[7]), "mime_type": e[8]})
+		rows = append(rows, valmap)
+	}
+	return rows, nil
+}
+
+// oneDrive lists the folders and files of a SyncEngineDatabase.db, the account is the settings folder of the database
+// (Personal, Business1, ...)
+func (m MacCloudSyncModule) oneDrive(dbpath string) ([]map[string]string, error) {
+	column, err := m.columns(dbpath, "od_ClientFile_Records")
+	if err != nil {
+		return nil, err
+	}
+	folders, err := util.QueryDB(dbpath, `SELECT IFNULL(resourceID, '') AS id, IFNULL(parentResourceID, '') AS parent_id,
+	IFNULL(folderName, '') AS name FROM od_ClientFolder_Records ORDER BY rowid`, []string{"id", "parent_id", "name"}, false)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT IFNULL(resourceID, '') AS id, IFNULL(parentResourceID, '') AS parent_id,
+	IFNULL(fileName, '') AS name, IFNULL(%s, '') AS size, IFNULL(%s, '') AS modified
+	FROM od_ClientFile_Records ORDER BY rowid`, column("size"), column("lastChange"))
+	files, err := util.QueryDB(dbpath, query, []string{"id", "parent_id", "name", "size", "modified"}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTree()
+	for _, e := range folders {
+		t.add(e[0], e[1], e[2])
+	}
+
+	rows := []map[string]string{}
+	for _, e := range folders {
+		valmap := m.row()
+		valmap["account"] = filepath.Base(filepath.Dir(dbpath))
+		valmap["item_id"] = e[0]
+		valmap["name"] = e[2]
+		valmap["cloud_path"] = t.path(e[0])
+		valmap["is_folder"] = m.bool(true)
+		rows = append(rows, valmap)
+	}
+	for _, e := range files {
+		valmap := m.row()
+		valmap["account"] = filepath.Base(filepath.Dir(dbpath))
+		valmap["item_id"] = e[0]
+		valmap["name"] = e[2]
+		valmap["cloud_path"] = path.Join(t.path(e[1]), e[2])
+		valmap["is_folder"] = m.bool(false)
+		valmap["size"] = e[3]
+		valmap["modified"] = m.time(timeconv.Unix, e[4])
+		rows = append(rows, valmap)
+	}
+	return rows, nil
+}
+
+// dropbox lists the sync events of a Dropbox sync_history.db, the cloud path and account are found from the Dropbox
+// folders of info.json
+func (m MacCloudSyncModule) dropbox(dbpath string) ([]map[string]string, error) {
+	column, err := m.columns(dbpath, "sync_history")
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT rowid AS event_id, IFNULL(%s, '') AS file_id, IFNULL(local_path, '') AS local_path,
+	IFNULL(%s, '') AS is_dir, IFNULL(%s, '') AS timestamp, IFNULL(%s, '') AS event, IFNULL(%s, '') AS direction
+	FROM sync_history ORDER BY rowid`, column("file_id"), column("is_dir"), column("timestamp"), column("file_event_type"), column("direction"))
+	entries, err := util.QueryDB(dbpath, query, []string{"event_id", "file_id", "local_path", "is_dir", "timestamp", "event", "direction"}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// info.json is in .dropbox, the database in .dropbox/instance1
+	roots := m.dropboxRoots(filepath.Join(filepath.Dir(filepath.Dir(dbpath)), "info.json"))
+
+	rows := []map[string]string{}
+	for _, e := range entries {
+		valmap := m.row()
+		// a row is an event, the same file can have several
+		valmap["item_id"] = e[0]
+		if e[2] != "" {
+			valmap["name"] = path.Base(e[2])
+		}
+		valmap["local_path"] = e[2]
+		for _, account := range roots.accounts {
+			root := roots.paths[account]
+			if e[2] == root || strings.HasPrefix(e[2], root+"/") {
+				valmap["account"] = account
+				valmap["cloud_path"] = "/" + strings.TrimPrefix(strings.TrimPrefix(e[2], root), "/")
+				break
+			}
+		}
+		valmap["is_folder"] = m.bool(e[3] == "1")
+		valmap["modified"] = m.time(timeconv.Unix, e[4])
+		valmap["extras"] = m.extras(map[string]interface{}{"file_id": e[1], "event": e[5], "direction": e[6]})
+		rows = append(rows, valmap)
+	}
+	return rows, nil
+}
+
+// dropboxFolders are the folders of the Dropbox accounts (personal, business) of info.json
+type dropboxFolders struct {
+	accounts []string
+	paths    map[string]string
+}
+
+// dropboxRoots returns the Dropbox folder of each account of info.json, longer folders first as a business folder
+// can be in the personal one
+func (m MacCloudSyncModule) dropboxRoots(fp string) dropboxFolders {
+	roots := dropboxFolders{paths: map[string]string{}}
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		zap.L().Debug("could not read '"+fp+"': "+err.Error(), zap.String("module", moduleName))
+		return roots
+	}
+	info := map[string]struct {
+		Path string `json:"path"`
+	}{}
+	if err := json.Unmarshal(b, &info); err != nil {
+		zap.L().Debug("could not parse '"+fp+"': "+err.Error(), zap.String("module", moduleName))
+		return roots
+	}
+	for account, v := range info {
+		if v.Path != "" {
+			roots.accounts = append(roots.accounts, account)
+			roots.paths[account] = strings.TrimSuffix(v.Path, "/")
+		}
+	}
+	sort.Slice(roots.accounts, func(i, j int) bool {
+		return len(roots.paths[roots.accounts[i]]) > len(roots.paths[roots.accounts[j]])
+	})
+	return roots
+}
+
+// columns returns a function giving the first of the candidate columns present in the table, or NULL
+func (m MacCloudSyncModule) columns(dbpath string, table string) (func(candidates ...string) string, error) {
+	names, err := util.DBColumnNames(dbpath, table)
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]string{}
+	for _, name := range names {
+		columns[strings.ToLower(name)] = name
+	}
+	return func(candidates ...string) string {
+		for _, c := range candidates {
+			if name, ok := columns[strings.ToLower(c)]; ok {
+				return name
+			}
+		}
+		return "NULL"
+	}, nil
+}
+
+// row returns a row of the module output with every column empty
+func (m MacCloudSyncModule) row() map[string]string {
+	var valmap = make(map[string]string)
+	return util.InitializeMapToEmptyString(valmap, header)
+}
+
+// time formats a time column, values that cannot be converted are kept
+func (m MacCloudSyncModule) time(kind timeconv.Kind, v string) string {
+	s, err := timeconv.ConvertToString(kind, v)
+	if err != nil {
+		return v + "<FAILED TO CONVERT>"
+	}
+	return s
+}
+
+func (m MacCloudSyncModule) bool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// flag formats a 0/1 column, empty when the column is missing
+func (m MacCloudSyncModule) flag(v string) string {
+	if v == "" {
+		return ""
+	}
+	return m.bool(v != "0")
+}
+
+// extras returns the extras column of a row, empty values are left out
+func (m MacCloudSyncModule) extras(extras map[string]interface{}) string {
+	for k, v := range extras {
+		if v == "" {
+			delete(extras, k)
+		}
+	}
+	if len(extras) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
+	}
+	return string(b)
+}
+
+// tree resolves the path of the items of a sync client from the parent of each item
+type tree struct {
+	parents map[string]string
+	names   map[string]string
+}
+
+func newTree() tree {
+	return tree{parents: map[string]string{}, names: map[string]string{}}
+}
+
+func (t tree) add(id string, parent string, name string) {
+	t.parents[id] = parent
+	t.names[id] = name
+}
+
+// path returns the path of an item, parents are walked up until an item that is not in the tree (the root of the
+// client), items without a name are left out
+func (t tree) path(id string) string {
+	names := []string{}
+	seen := map[string]bool{}
+	for !seen[id] {
+		name, ok := t.names[id]
+		if !ok {
+			break
+		}
+		seen[id] = true
+		if name != "" {
+			names = append([]string{name}, names...)
+		}
+		id = t.parents[id]
+	}
+	return "/" + strings.Join(names, "/")
+}
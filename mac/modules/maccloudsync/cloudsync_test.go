@@ -0,0 +1,11 @@
+package maccloudsync
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacCloudSyncModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacCloudSyncModule{}, moduletest.Options{})
+}
@@ -8,7 +8,10 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -26,9 +29,7 @@ var (
 )
 
 var (
-	filepathChromeCookiesGlob  = []string{"Users/*/Library/Application Support/Google/Chrome"}
-	filepathFirefoxCookiesGlob = []string{"Users/*/Library/Application Support/Firefox/Profiles/*.*"}
-	filepathTemporary          = "CookiesTemp"
+	filepathTemporary = "CookiesTemp"
 )
 
 func (m MacCookiesModule) Start(inst instance.Instance) error {
@@ -40,6 +41,26 @@ func (m MacCookiesModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacCookiesModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagBrowser}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacCookiesModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "creation_utc", Type: "Cookie Created"},
+				{Field: "last_access_utc", Type: "Cookie Accessed"},
+			},
+			UserField:         "user",
+			DescriptionFields: []string{"browser", "host_key", "name"},
+		},
+	}
+}
+
 func (m MacCookiesModule) cookies(inst instance.Instance) error {
 	header := []string{
 		"browser",
@@ -75,10 +96,10 @@ func (m MacCookiesModule) cookies(inst instance.Instance) error {
 	}
 
 	// Glob chrome cookies
-	chromeCookiesFileLocations := util.Multiglob(filepathChromeCookiesGlob, inst.GetTargetPath())
+	chromeCookiesFileLocations := inst.GetArtifactPaths("MacOSChromeDataDirectory")
 
 	// Glob firefox cookies
-	firefoxCookiesFileLocations := util.Multiglob(filepathFirefoxCookiesGlob, inst.GetTargetPath())
+	firefoxCookiesFileLocations := inst.GetArtifactPaths("MacOSFirefoxProfiles")
 
 	if len(chromeCookiesFileLocations) == 0 {
 		zap.L().Warn("No Chrome cookies files were found!", zap.String("module", moduleName))
@@ -245,9 +266,10 @@ func (m MacCookiesModule) pullFirefoxCookiesDataFromDB(firefoxCookiesDBPath stri
 		valmap["name"] = e[1]
 		valmap["value"] = e[2]
 		valmap["path"] = e[3]
-		valmap["creation_utc"] = e[4]
-		valmap["expires_utc"] = e[5]
-		valmap["last_access_utc"] = e[6]
+		valmap["creation_utc"] = m.formatTime(timeconv.PRTime, e[4])
+		// expiry is seconds in older Firefox versions and milliseconds in recent ones
+		valmap["expires_utc"] = m.formatTime(timeconv.UnixSecondsOrMilli, e[5])
+		valmap["last_access_utc"] = m.formatTime(timeconv.PRTime, e[6])
 		valmap["is_secure"] = e[7]
 		valmap["ishttponly"] = e[8]
 		valmap["same_site"] = e[9]
@@ -332,9 +354,9 @@ func (m MacCookiesModule) pullChromeCookiesDataFromDB(chromeCookiesDBPath string
 		valmap["name"] = e[2]
 		valmap["value"] = e[3]
 		valmap["path"] = e[4]
-		valmap["creation_utc"] = e[0]
-		valmap["expires_utc"] = e[5]
-		valmap["last_access_utc"] = e[8]
+		valmap["creation_utc"] = m.formatTime(timeconv.WebKit, e[0])
+		valmap["expires_utc"] = m.formatTime(timeconv.WebKit, e[5])
+		valmap["last_access_utc"] = m.formatTime(timeconv.WebKit, e[8])
 		valmap["is_secure"] = e[6]
 		valmap["ishttponly"] = e[7]
 		valmap["same_site"] = e[13]
@@ -364,3 +386,13 @@ func (m MacCookiesModule) pullChromeCookiesDataFromDB(chromeCookiesDBPath string
 
 	return values, nil
 }
+
+// formatTime converts a raw cookie timestamp, keeping the raw value if it cannot be converted
+func (m MacCookiesModule) formatTime(kind timeconv.Kind, raw string) string {
+	s, err := timeconv.ConvertToString(kind, raw)
+	if err != nil {
+		zap.L().Debug("Failed to convert cookie timestamp: "+err.Error(), zap.String("module", moduleName))
+		return raw
+	}
+	return s
+}
@@ -6,14 +6,18 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/anthonybm/Orion/baseline"
 	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/machelpers"
 	"github.com/karrick/godirwalk"
@@ -35,8 +39,34 @@ var (
 	walkRootDir        string
 	verbose            bool
 	scratchBuffSize    = godirwalk.MinimumScratchBufferSize
+	previousFiles      map[string]baseline.File
+	reusedHashes       int
+	timestampFormat    string
 )
 
+// Config is the [modules.MacDirlistModule] config section
+type Config struct {
+	RootWalkDir        string   `toml:"RootWalkDir" comment:"directory to walk, relative to the target path"`
+	ExcludedDirs       []string `toml:"ExcludedDirs" comment:"directories to skip, matched as a substring of the path (recommend adding cloud storage paths)"`
+	ExcludedExts       []string `toml:"ExcludedExts" comment:"file and bundle extensions to skip"`
+	HashSizeLimitBytes int      `toml:"HashSizeLimitBytes" comment:"only hash files smaller than this, ~10x faster than hashing every file"`
+	DoHashMD5          bool     `toml:"DoHashMD5" comment:"hash files with MD5"`
+	DoHashSHA256       bool     `toml:"DoHashSHA256" comment:"hash files with SHA256"`
+	Verbose            bool     `toml:"Verbose" comment:"log every file walked"`
+}
+
+// DefaultConfig returns the [modules.MacDirlistModule] config section with default values
+func (m MacDirlistModule) DefaultConfig() interface{} {
+	return &Config{
+		RootWalkDir:        "/",
+		ExcludedDirs:       []string{".fseventsd", ".DocumentRevisions-V100", ".Spotlight-V100"},
+		ExcludedExts:       []string{".app", ".framework", ".lproj", ".plugin", ".kext", ".osax", ".bundle", ".driver", ".wdgt"},
+		HashSizeLimitBytes: 10485760,
+		DoHashMD5:          true,
+		DoHashSHA256:       true,
+	}
+}
+
 // Start executes the module with Config instructions and writes to OrionWriter
 func (m MacDirlistModule) Start(inst instance.Instance) error {
 	err := m.dirlist(inst)
@@ -46,12 +76,46 @@ func (m MacDirlistModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacDirlistModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagDiskHeavy}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacDirlistModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "mtime", Type: "File Modified", MACB: "M..."},
+				{Field: "atime", Type: "File Accessed", MACB: ".A.."},
+				{Field: "ctime", Type: "File Metadata Changed", MACB: "..C."},
+				{Field: "btime", Type: "File Created", MACB: "...B"},
+			},
+			DescriptionFields: []string{"path", "size", "sha256", "wherefrom_1"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacDirlistModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"path"}, Ignore: []string{"atime", "inode"}},
+	}
+}
+
 func (m MacDirlistModule) dirlist(inst instance.Instance) error {
-	doHashMD5, _ = inst.GetOrionConfig().GetDirlistDoHashMD5()
-	doHashSHA256, _ = inst.GetOrionConfig().GetDirlistDohashSHA256()
-	walkRootDir = inst.GetTargetPath()
-	hashSizeLimitBytes, _ = inst.GetOrionConfig().GetDirlistHashSizeLimitBytes()
+	cfg := m.DefaultConfig().(*Config)
+	err := inst.GetOrionConfig().DecodeModuleConfig(moduleName, cfg)
+	if err != nil {
+		return err
+	}
+	doHashMD5 = cfg.DoHashMD5
+	doHashSHA256 = cfg.DoHashSHA256
+	walkRootDir = filepath.Join(inst.GetTargetPath(), cfg.RootWalkDir)
+	hashSizeLimitBytes = cfg.HashSizeLimitBytes
 	verbose, _ = inst.GetOrionConfig().IsVerbose()
+	verbose = verbose || cfg.Verbose
 
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -65,6 +129,7 @@ func (m MacDirlistModule) dirlist(inst instance.Instance) error {
 		"owner",
 		"uid",
 		"gid",
+		"inode",
 		"mtime",
 		"atime",
 		"ctime",
@@ -79,6 +144,23 @@ func (m MacDirlistModule) dirlist(inst instance.Instance) error {
 		// "code_signatures",
 	}
 	values := [][]string{}
+	// The header is written first, rows are written early when the heap reaches the memory limit
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+
+	// Hashes of files unchanged since the baseline are copied from it instead of read again
+	previousFiles = nil
+	reusedHashes = 0
+	timestampFormat = inst.GetTimestampFormat()
+	if b := inst.GetBaseline(); b != nil && b.Has(moduleName) {
+		previousFiles, err = b.Files(moduleName, "inode")
+		if err != nil {
+			zap.L().Warn("Failed to read the baseline file list, hashing every file: "+err.Error(), zap.String("module", moduleName))
+			previousFiles = nil
+		}
+	}
 
 	count := 0
 	benchmarkStart := time.Now()
@@ -87,7 +169,7 @@ func (m MacDirlistModule) dirlist(inst instance.Instance) error {
 	// symcount := 0
 	// devicecount := 0
 
-	excludedDirs, _ := inst.GetOrionConfig().GetDirlistExcludedDirs()
+	excludedDirs := cfg.ExcludedDirs
 	// also for non-forensic mode, exclude /Volumes/* to prevent recusion of mounted volumes
 	if !inst.ForensicMode() {
 		excludeVols, _ := filepath.Glob(filepath.Join(inst.GetTargetPath(), "Volumes/*"))
@@ -98,7 +180,7 @@ func (m MacDirlistModule) dirlist(inst instance.Instance) error {
 	// 	excludedDirsMap[excDir] = true
 	// }
 
-	excludedExts, _ := inst.GetOrionConfig().GetDirlistExcludedExts()
+	excludedExts := cfg.ExcludedExts
 	excludedExtsMap := make(map[string]bool) // Map for fast access to search excluded
 	for _, excExt := range excludedExts {
 		excludedExtsMap[excExt] = true
@@ -123,6 +205,13 @@ func (m MacDirlistModule) dirlist(inst instance.Instance) error {
 				// wg.Add(1)
 				filecount++
 				values = append(values, parseRegular(osPathname, de /*, &wg*/))
+				if throttle.MemoryHigh() {
+					// Write the rows collected so far to free memory
+					if err := mw.WriteAll(values); err != nil {
+						zap.L().Error("Failed to write rows: "+err.Error(), zap.String("module", moduleName))
+					}
+					values = [][]string{}
+				}
 			}
 			// } else if de.IsSymlink() {
 			// 	symcount++
@@ -158,11 +247,11 @@ func (m MacDirlistModule) dirlist(inst instance.Instance) error {
 	// zap.L().Debug("SymLinks: "+strconv.Itoa(symcount), zap.String("module", moduleName))
 	// zap.L().Debug("Device: "+strconv.Itoa(devicecount), zap.String("module", moduleName))
 
-	// Write to output
-	err = mw.WriteHeader(header)
-	if err != nil {
-		return err
+	if previousFiles != nil {
+		zap.L().Info("Reused the hashes of ["+strconv.Itoa(reusedHashes)+"] files unchanged since the baseline", zap.String("module", moduleName))
 	}
+
+	// Write the remaining rows to output
 	err = mw.WriteAll(values)
 	if err != nil {
 		return err
@@ -192,14 +281,19 @@ func parseRegular(osPathname string, de *godirwalk.Dirent) []string {
 	hashSHA256 := "N/E"
 	hashMD5 := "N/E"
 	size, _ := strconv.Atoi(metadata["size"])
-	if doHashSHA256 && (size < hashSizeLimitBytes) {
+	previous, unchanged := previousFile(metadata)
+	if doHashSHA256 && (size < hashSizeLimitBytes) && unchanged && baseline.Hash(previous.SHA256) != "" {
+		hashSHA256 = previous.SHA256
+	} else if doHashSHA256 && (size < hashSizeLimitBytes) {
 		h, err := fileSHA256(osPathname)
 		if err != nil {
 			hashSHA256 = "ERROR"
 		}
 		hashSHA256 = h
 	}
-	if doHashMD5 && (size < hashSizeLimitBytes) {
+	if doHashMD5 && (size < hashSizeLimitBytes) && unchanged && baseline.Hash(previous.MD5) != "" {
+		hashMD5 = previous.MD5
+	} else if doHashMD5 && (size < hashSizeLimitBytes) {
 		h, err := fileMD5(osPathname)
 		if err != nil {
 			hashMD5 = "ERROR"
@@ -229,12 +323,17 @@ func parseRegular(osPathname string, de *godirwalk.Dirent) []string {
 	// 		fmt.Print(parsedWF)
 	// 	}
 	// }
+	if unchanged {
+		reusedHashes++
+	}
+
 	entry := []string{
 		metadata["mode"],  // "mode",
 		metadata["size"],  // "size",
 		"N/P",             // "owner",
 		metadata["uid"],   // "uid",
 		metadata["gid"],   // "gid",
+		metadata["inode"], // "inode",
 		metadata["mtime"], // "mtime",
 		metadata["atime"], // "atime",
 		metadata["ctime"], // "ctime",
@@ -253,8 +352,22 @@ func parseRegular(osPathname string, de *godirwalk.Dirent) []string {
 	return entry
 }
 
+// previousFile returns what the baseline recorded about the file described by metadata, false when the file
+// is new or its size, mtime or inode changed
+func previousFile(metadata map[string]string) (baseline.File, bool) {
+	previous, ok := previousFiles[metadata["path"]]
+	if !ok {
+		return baseline.File{}, false
+	}
+	mtime, err := timeline.ParseTime(metadata["mtime"], timestampFormat)
+	if err != nil || !previous.Unchanged(metadata["size"], mtime, metadata["inode"]) {
+		return baseline.File{}, false
+	}
+	return previous, true
+}
+
 func fileSHA256(fp string) (string, error) {
-	f, err := os.Open(fp)
+	f, err := throttle.Open(fp)
 	if err != nil {
 		return "", err
 	}
@@ -268,7 +381,7 @@ func fileSHA256(fp string) (string, error) {
 }
 
 func fileMD5(fp string) (string, error) {
-	f, err := os.Open(fp)
+	f, err := throttle.Open(fp)
 	if err != nil {
 		return "", err
 	}
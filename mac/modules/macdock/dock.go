@@ -0,0 +1,206 @@
+package macdock
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacDockModule wraps Module methods
+type MacDockModule struct{}
+
+var (
+	moduleName  = "MacDockModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the Dock preferences (com.apple.dock.plist) of each user
+	Lists the applications kept in the Dock (persistent-apps), the folders and files kept next to the Trash
+	(persistent-others) and the recently used applications (recent-apps), decoding the bookmarks of the tiles
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"source_name",
+		"item_index",
+		"order",
+		"name",
+		"url",
+		"target_created",
+		"volume_name",
+		"volume_uuid",
+		"file_id",
+		"source_key",
+		"extras",
+	}
+
+	// sections are the lists of tiles of the Dock, recent-apps was added in 10.14
+	sections = []string{"persistent-apps", "persistent-others", "recent-apps"}
+)
+
+// Start starts the MacDockModule, should not be manually called
+func (m MacDockModule) Start(inst instance.Instance) error {
+	err := m.dock(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacDockModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacDockModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_file", "source_key", "url"}, Ignore: []string{"item_index"}},
+	}
+}
+
+func (m MacDockModule) dock(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSDock")
+	if len(paths) == 0 {
+		zap.L().Warn("No Dock preferences were found", zap.String("module", moduleName))
+	}
+
+	for _, path := range paths {
+		vals, err := m.parseDock(path, inst.GetTargetPath())
+		if err != nil {
+			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		values = util.AppendToDoubleSlice(values, vals)
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] Dock tiles from %d files", len(values), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseDock returns a row for every tile of the sections of the Dock preferences path
+func (m MacDockModule) parseDock(path string, targetPath string) ([][]string, error) {
+	data, err := machelpers.DecodePlist(path, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	values := [][]string{}
+	for _, item := range data {
+		for _, section := range sections {
+			tiles, ok := item[section].([]interface{})
+			if !ok {
+				continue
+			}
+			for i, t := range tiles {
+				tile, ok := t.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				tileData, ok := tile["tile-data"].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				var valmap = make(map[string]string)
+				valmap = util.InitializeMapToEmptyString(valmap, header)
+				valmap["user"] = util.GetUsernameFromPath(path)
+				valmap["source_file"] = path
+				valmap["source_name"] = "Dock"
+				valmap["source_key"] = section
+				valmap["item_index"] = strconv.Itoa(i)
+				valmap["name"], _ = tileData["file-label"].(string)
+
+				extras := map[string]interface{}{}
+				if b, ok := tileData["book"].([]byte); ok {
+					if bm, err := machelpers.DecodeBookmark(b); err != nil {
+						zap.L().Debug("could not decode bookmark of '"+path+"': "+err.Error(), zap.String("module", moduleName))
+					} else {
+						for k, v := range bm.Columns() {
+							valmap[k] = v
+						}
+					}
+				}
+				// the URL of the tile is kept over the bookmark target, it is what the Dock opens
+				if fileData, ok := tileData["file-data"].(map[string]interface{}); ok {
+					if u, ok := fileData["_CFURLString"].(string); ok && u != "" {
+						valmap["url"] = u
+					}
+				}
+				if valmap["name"] == "" && valmap["url"] != "" {
+					valmap["name"] = strings.TrimSuffix(filepath.Base(valmap["url"]), ".app")
+				}
+				if bundleID, ok := tileData["bundle-identifier"].(string); ok {
+					extras["bundle_identifier"] = bundleID
+				}
+				if tileType, ok := tile["tile-type"].(string); ok {
+					extras["tile_type"] = tileType
+				}
+				if guid, ok := tile["GUID"].(uint64); ok {
+					extras["guid"] = guid
+				}
+				// modification dates of the target and its folder are HFS+ seconds
+				for key, name := range map[string]string{"file-mod-date": "file_modified", "parent-mod-date": "parent_modified"} {
+					if seconds, ok := tileData[key].(uint64); ok && seconds > 0 {
+						extras[name] = timeconv.Format(timeconv.FromHFSPlus(uint32(seconds)))
+					}
+				}
+				valmap["extras"] = m.extras(extras)
+
+				entry, err := util.UnsafeEntryFromMap(valmap, header)
+				if err != nil {
+					zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+					continue
+				}
+				values = append(values, entry)
+			}
+		}
+	}
+	return values, nil
+}
+
+// extras returns the extras column of a row
+func (m MacDockModule) extras(extras map[string]interface{}) string {
+	if len(extras) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
+	}
+	return string(b)
+}
@@ -0,0 +1,11 @@
+package macdock
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacDockModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacDockModule{}, moduletest.Options{})
+}
@@ -0,0 +1,198 @@
+package macdsstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/dsstore"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacDSStoreModule wraps Module methods
+type MacDSStoreModule struct{}
+
+var (
+	moduleName  = "MacDSStoreModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the .DS_Store files Finder leaves in the folders of each user's home (three levels deep) and Trash
+	Lists every file named by the records of a folder with its properties in extras and whether it is still on disk,
+	records of moved and deleted files stay until Finder rewrites the folder. Put back locations of trashed files are
+	given as their url
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"source_name",
+		"item_index",
+		"order",
+		"name",
+		"url",
+		"target_created",
+		"volume_name",
+		"volume_uuid",
+		"file_id",
+		"source_key",
+		"extras",
+	}
+)
+
+// Start starts the MacDSStoreModule, should not be manually called
+func (m MacDSStoreModule) Start(inst instance.Instance) error {
+	err := m.dsstore(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacDSStoreModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+func (m MacDSStoreModule) dsstore(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSDSStore")
+	if len(paths) == 0 {
+		zap.L().Warn("No .DS_Store files were found", zap.String("module", moduleName))
+	}
+
+	for _, path := range paths {
+		records, err := dsstore.ParseFile(path)
+		if err != nil {
+			// records read before the error are still listed
+			zap.L().Debug("could not parse all of '"+path+"': "+err.Error(), zap.String("module", moduleName))
+		}
+		values = util.AppendToDoubleSlice(values, m.rows(path, inst.GetTargetPath(), records))
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] files from %d .DS_Store", len(values), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// rows returns a row for every filename of the records of the .DS_Store path, records are sorted by filename
+func (m MacDSStoreModule) rows(path string, targetPath string, records []dsstore.Record) [][]string {
+	values := [][]string{}
+	dir := filepath.Dir(path)
+	// the folder as it is on the evidence, where the target is mounted elsewhere
+	folder := dir
+	if rel, err := filepath.Rel(targetPath, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		folder = filepath.Join("/", rel)
+	}
+
+	for start := 0; start < len(records); {
+		end := start + 1
+		for end < len(records) && records[end].Filename == records[start].Filename {
+			end++
+		}
+		name := records[start].Filename
+
+		var valmap = make(map[string]string)
+		valmap = util.InitializeMapToEmptyString(valmap, header)
+		valmap["user"] = util.GetUsernameFromPath(path)
+		valmap["source_file"] = path
+		valmap["source_name"] = "DS_Store"
+		valmap["source_key"] = folder
+		valmap["item_index"] = strconv.Itoa(len(values))
+		valmap["name"] = name
+		valmap["url"] = (&url.URL{Scheme: "file", Path: filepath.Join(folder, name)}).String()
+
+		extras := map[string]interface{}{}
+		for _, record := range records[start:end] {
+			extras[record.Code] = m.value(record)
+		}
+		// trashed files remember the folder (relative to the volume) and name they were put in the Trash from
+		if location, ok := extras["ptbL"].(string); ok {
+			original := name
+			if n, ok := extras["ptbN"].(string); ok && n != "" {
+				original = n
+			}
+			valmap["url"] = (&url.URL{Scheme: "file", Path: filepath.Join("/", location, original)}).String()
+		}
+		_, err := os.Lstat(filepath.Join(dir, name))
+		extras["on_disk"] = err == nil
+		valmap["extras"] = m.extras(extras)
+
+		entry, err := util.UnsafeEntryFromMap(valmap, header)
+		if err != nil {
+			zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+		} else {
+			values = append(values, entry)
+		}
+		start = end
+	}
+	return values
+}
+
+// value returns the value of a record for the extras column, icon locations as {x, y}, plists decoded and other
+// blobs as hex
+func (m MacDSStoreModule) value(record dsstore.Record) interface{} {
+	switch v := record.Value.(type) {
+	case time.Time:
+		return timeconv.Format(v)
+	case []byte:
+		if record.Code == "Iloc" && len(v) >= 8 {
+			return map[string]uint32{"x": binary.BigEndian.Uint32(v), "y": binary.BigEndian.Uint32(v[4:])}
+		}
+		if bytes.HasPrefix(v, []byte("bplist")) {
+			if decoded, err := machelpers.DecodePlistBytes(v); err == nil {
+				return decoded
+			}
+		}
+		return hex.EncodeToString(v)
+	}
+	return record.Value
+}
+
+// extras returns the extras column of a row
+func (m MacDSStoreModule) extras(extras map[string]interface{}) string {
+	if len(extras) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
+	}
+	return string(b)
+}
@@ -0,0 +1,11 @@
+package macdsstore
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacDSStoreModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacDSStoreModule{}, moduletest.Options{})
+}
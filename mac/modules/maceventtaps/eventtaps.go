@@ -14,6 +14,7 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
 	"github.com/anthonybm/Orion/util"
 	"go.uber.org/zap"
 )
@@ -67,6 +68,11 @@ func (m MacEventTapsModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacEventTapsModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagVolatile, moduleinfo.TagPersistence}}
+}
+
 func (m MacEventTapsModule) eventtaps(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -1,19 +1,20 @@
-// +build darwin
-
 package macfirefox
 
 import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -32,11 +33,10 @@ var (
 )
 
 var (
-	filepathTemporary           = "FirefoxTemp"
-	filepathFirefoxLocationGlob = []string{"Users/*/Library/Application Support/Firefox/Profiles/*.*"}
-	historyHeader               = []string{"user", "profile", "visit_date", "title", "url", "visit_count", "last_visit_date", "typed", "description"}
-	downloadHeader              = []string{"user", "profile", "download_url", "download_path", "download_started", "download_finished", "download_totalbytes"}
-	extensionHeader             = []string{"user", "profile", "name", "id", "creator", "description", "updateURL", "installDate", "updateDate", "sourceURI", "homepageURL"}
+	filepathTemporary = "FirefoxTemp"
+	historyHeader     = []string{"user", "profile", "visit_date", "title", "url", "visit_count", "last_visit_date", "typed", "description"}
+	downloadHeader    = []string{"user", "profile", "download_url", "download_path", "download_started", "download_finished", "download_totalbytes"}
+	extensionHeader   = []string{"user", "profile", "name", "id", "creator", "description", "updateURL", "installDate", "updateDate", "sourceURI", "homepageURL"}
 )
 
 // Start starts the MacFirefoxModule, should not be manually called
@@ -48,6 +48,43 @@ func (m MacFirefoxModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacFirefoxModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagBrowser}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacFirefoxModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName + "-history",
+			Incremental:       true,
+			Events:            []timeline.Event{{Field: "visit_date", Type: "URL Visited"}},
+			UserField:         "user",
+			DescriptionFields: []string{"url", "title"},
+		},
+		{
+			Output:      moduleName + "-downloads",
+			Incremental: true,
+			Events: []timeline.Event{
+				{Field: "download_started", Type: "Download Started"},
+				{Field: "download_finished", Type: "Download Finished"},
+			},
+			UserField:         "user",
+			DescriptionFields: []string{"download_url", "download_path"},
+		},
+		{
+			Output: moduleName + "-extensions",
+			Events: []timeline.Event{
+				{Field: "installDate", Type: "Extension Installed"},
+				{Field: "updateDate", Type: "Extension Updated"},
+			},
+			UserField:         "user",
+			DescriptionFields: []string{"name", "id"},
+		},
+	}
+}
+
 func (m MacFirefoxModule) firefox(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -64,9 +101,9 @@ func (m MacFirefoxModule) firefox(inst instance.Instance) error {
 	extensionValues := [][]string{}
 
 	// Start Parsing
-	firefoxLocations := util.Multiglob(filepathFirefoxLocationGlob, inst.GetTargetPath())
+	firefoxLocations := inst.GetArtifactPaths("MacOSFirefoxProfiles")
 	if len(firefoxLocations) == 0 {
-		zap.L().Debug(fmt.Sprintf("No firefox files were found in %s", filepathFirefoxLocationGlob), zap.String("module", moduleName))
+		zap.L().Debug(fmt.Sprintf("No firefox files were found in %s", inst.GetArtifactGlobs("MacOSFirefoxProfiles")), zap.String("module", moduleName))
 	} else {
 		for _, firefoxLocation := range firefoxLocations {
 			username := util.GetUsernameFromPath(firefoxLocation)
@@ -243,6 +280,12 @@ func (m MacFirefoxModule) parseVisitHistory(dbfilepath, username, profile string
 				valmap[k] = strings.TrimSpace(val)
 			}
 		}
+		for _, k := range []string{"visit_date", "last_visit_date"} {
+			valmap[k], err = timeconv.ConvertToString(timeconv.PRTime, valmap[k])
+			if err != nil {
+				zap.L().Debug(fmt.Sprintf("error parsing %s time - %s", k, err.Error()), zap.String("module", moduleName))
+			}
+		}
 
 		// Convert valmap to entry and append to values
 		entry, err := util.UnsafeEntryFromMap(valmap, historyHeader)
@@ -309,12 +352,17 @@ func (m MacFirefoxModule) parseDownloadHistory(dbfilepath, username, profile str
 		return [][]string{}, fmt.Errorf("found no columns for '%s'", dbfilepath)
 	}
 
-	// send query to db
+	// send query to db, a download is a place with a destinationFileURI and a metaData annotation
 	query := `
-	SELECT url,group_concat(content),dateAdded 
+	SELECT COALESCE(url,'') AS url,
+	COALESCE(MAX(CASE WHEN moz_anno_attributes.name = 'downloads/destinationFileURI' THEN content END),'') AS destination,
+	COALESCE(MAX(CASE WHEN moz_anno_attributes.name = 'downloads/metaData' THEN content END),'') AS metadata,
+	MIN(moz_annos.dateAdded) AS dateAdded
 	FROM moz_annos
-    LEFT JOIN moz_places ON moz_places.id = moz_annos.place_id
-    GROUP BY place_id`
+	JOIN moz_anno_attributes ON moz_anno_attributes.id = moz_annos.anno_attribute_id
+	LEFT JOIN moz_places ON moz_places.id = moz_annos.place_id
+	WHERE moz_anno_attributes.name LIKE 'downloads/%'
+	GROUP BY place_id`
 	entries, err := util.UnsafeQueryDBToMap(firefoxDBPathTemp, query)
 	if err != nil {
 		return [][]string{}, err
@@ -337,6 +385,13 @@ func (m MacFirefoxModule) parseDownloadHistory(dbfilepath, username, profile str
 				valmap[k] = strings.TrimSpace(val)
 			}
 		}
+		valmap["download_url"] = valmap["url"]
+		valmap["download_started"], err = timeconv.ConvertToString(timeconv.PRTime, valmap["dateAdded"])
+		if err != nil {
+			zap.L().Debug("error parsing download_started time - "+err.Error(), zap.String("module", moduleName))
+		}
+		valmap["download_path"] = downloadPath(valmap["destination"])
+		valmap["download_finished"], valmap["download_totalbytes"] = downloadMetadata(valmap["metadata"])
 
 		// Convert valmap to entry and append to values
 		entry, err := util.UnsafeEntryFromMap(valmap, downloadHeader)
@@ -358,6 +413,37 @@ func (m MacFirefoxModule) parseDownloadHistory(dbfilepath, username, profile str
 	zap.L().Warn("No test data used for firefox download history - VERIFY and update :) ", zap.String("module", moduleName))
 	return values, nil
 }
+
+// downloadPath returns the file path of a downloads/destinationFileURI annotation (file:///Users/bob/Downloads/a%20b.zip)
+func downloadPath(destination string) string {
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "file" {
+		return destination
+	}
+	return u.Path
+}
+
+// downloadMetadata returns the finish time and size of a download from its downloads/metaData annotation
+// ({"state":1,"endTime":1596272405000,"fileSize":2048}), endTime is milliseconds since 1970-01-01
+func downloadMetadata(metadata string) (string, string) {
+	if metadata == "" {
+		return "", ""
+	}
+	var meta struct {
+		EndTime  json.Number `json:"endTime"`
+		FileSize json.Number `json:"fileSize"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+		zap.L().Debug("error parsing download metadata - "+err.Error(), zap.String("module", moduleName))
+		return "", ""
+	}
+	finished, err := timeconv.ConvertToString(timeconv.UnixMilli, meta.EndTime.String())
+	if err != nil {
+		zap.L().Debug("error parsing download_finished time - "+err.Error(), zap.String("module", moduleName))
+	}
+	return finished, meta.FileSize.String()
+}
+
 func (m MacFirefoxModule) parseExtensionsValues(extensionFilepath, username, profile string) ([][]string, error) {
 	values := [][]string{}
 	count := 0
@@ -437,12 +523,12 @@ func (m MacFirefoxModule) parseExtensionsValues(extensionFilepath, username, pro
 			}
 			if addon.(map[string]interface{})["installDate"] != nil {
 				val := addon.(map[string]interface{})["installDate"].(float64)
-				valmap["installDate"] = time.Unix(int64(val)/1000, 0).UTC().Format(time.RFC3339)
+				valmap["installDate"] = timeconv.Format(timeconv.FromUnixMilli(int64(val)))
 			}
 
 			if addon.(map[string]interface{})["updateDate"] != nil {
 				val := addon.(map[string]interface{})["updateDate"].(float64)
-				valmap["updateDate"] = time.Unix(int64(val)/1000, 0).UTC().Format(time.RFC3339)
+				valmap["updateDate"] = timeconv.Format(timeconv.FromUnixMilli(int64(val)))
 			}
 			if val, err := util.InterfaceToString(addon.(map[string]interface{})["sourceURI"]); err != nil {
 				valmap["sourceURI"] = "ERR"
@@ -0,0 +1,11 @@
+package macfirefox
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacFirefoxModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacFirefoxModule{}, moduletest.Options{})
+}
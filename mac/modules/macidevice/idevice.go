@@ -0,0 +1,464 @@
+package macidevice
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MaciDeviceModule wraps Module methods
+type MaciDeviceModule struct{}
+
+var (
+	moduleName  = "MaciDeviceModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Lists the iPhone and iPad backups of each user (~/Library/Application Support/MobileSync/Backup) with the device
+	name, serial, IMEI, iOS version, last backup date and encryption status from Info.plist and Manifest.plist. The file
+	list of unencrypted backups (Manifest.db) is written to MaciDeviceModule-files
+	Devices paired with the Mac, from com.apple.iPod.plist of each user and the lockdown pair records
+	(/private/var/db/lockdown, root only), are written to MaciDeviceModule-paired
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"udid",
+		"device_name",
+		"product_type",
+		"product_version",
+		"build_version",
+		"serial_number",
+		"imei",
+		"meid",
+		"iccid",
+		"phone_number",
+		"last_backup_date",
+		"is_encrypted",
+		"passcode_set",
+		"applications",
+		"files",
+	}
+	filesHeader = []string{
+		"source_file",
+		"user",
+		"udid",
+		"file_id",
+		"domain",
+		"relative_path",
+		"type",
+		"size",
+		"created",
+		"modified",
+		"status_changed",
+		"backup_file",
+	}
+	pairedHeader = []string{
+		"source_file",
+		"user",
+		"source_name",
+		"device_id",
+		"device_class",
+		"product_type",
+		"firmware_version",
+		"build_version",
+		"serial_number",
+		"imei",
+		"meid",
+		"use_count",
+		"last_connected",
+		"host_id",
+		"system_buid",
+		"wifi_mac",
+		"mtime",
+		"btime",
+	}
+)
+
+// infoKeys maps the columns of a backup to their Info.plist keys
+var infoKeys = map[string]string{
+	"device_name":      "Device Name",
+	"product_type":     "Product Type",
+	"product_version":  "Product Version",
+	"build_version":    "Build Version",
+	"serial_number":    "Serial Number",
+	"imei":             "IMEI",
+	"meid":             "MEID",
+	"iccid":            "ICCID",
+	"phone_number":     "Phone Number",
+	"last_backup_date": "Last Backup Date",
+}
+
+// lockdownKeys maps the columns of a backup to the Lockdown dictionary of Manifest.plist, used when Info.plist is missing
+var lockdownKeys = map[string]string{
+	"udid":            "UniqueDeviceID",
+	"device_name":     "DeviceName",
+	"product_type":    "ProductType",
+	"product_version": "ProductVersion",
+	"build_version":   "BuildVersion",
+	"serial_number":   "SerialNumber",
+}
+
+// iPodKeys maps the columns of a paired device to its com.apple.iPod.plist keys
+var iPodKeys = map[string]string{
+	"device_class":     "Device Class",
+	"product_type":     "Product Type",
+	"firmware_version": "Firmware Version String",
+	"build_version":    "Build Version",
+	"serial_number":    "Serial Number",
+	"imei":             "IMEI",
+	"meid":             "MEID",
+	"use_count":        "Use Count",
+	"last_connected":   "Connected",
+}
+
+// manifestQuery lists the files of Manifest.db, NULLs are avoided as QueryDB does not handle them
+const manifestQuery = `SELECT fileID AS file_id, IFNULL(domain, '') AS domain, IFNULL(relativePath, '') AS relative_path,
+	IFNULL(flags, 0) AS flags, IFNULL(file, '') AS file FROM Files ORDER BY domain, relativePath`
+
+var manifestQueryHeaders = []string{"file_id", "domain", "relative_path", "flags", "file"}
+
+// fileTypes are the flags of a Manifest.db file
+var fileTypes = map[string]string{"1": "file", "2": "directory", "4": "symlink"}
+
+// Start starts the MaciDeviceModule, should not be manually called
+func (m MaciDeviceModule) Start(inst instance.Instance) error {
+	err := m.idevice(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MaciDeviceModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module outputs for the super-timeline
+func (m MaciDeviceModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "last_backup_date", Type: "iDevice Last Backup"}},
+			UserField:         "user",
+			DescriptionFields: []string{"device_name", "serial_number"},
+		},
+		{
+			Output:            moduleName + "-paired",
+			Events:            []timeline.Event{{Field: "last_connected", Type: "iDevice Last Connected"}, {Field: "btime", Type: "iDevice Paired"}},
+			UserField:         "user",
+			DescriptionFields: []string{"device_id", "product_type", "serial_number"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module outputs for orion diff
+func (m MaciDeviceModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_file"}},
+		{Output: moduleName + "-files", Columns: []string{"udid", "file_id"}},
+		{Output: moduleName + "-paired", Columns: []string{"source_file", "device_id"}},
+	}
+}
+
+func (m MaciDeviceModule) idevice(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+	filesWriter, err := datawriter.NewOrionWriter(moduleName+"-files", inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+	pairedWriter, err := datawriter.NewOrionWriter(moduleName+"-paired", inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+	fileValues := [][]string{}
+	pairedValues := [][]string{}
+
+	backups := inst.GetArtifactPaths("MacOSiDeviceBackups")
+	if len(backups) == 0 {
+		zap.L().Warn("No iDevice backups were found", zap.String("module", moduleName))
+	}
+	for _, backup := range backups {
+		valmap, encrypted := m.backup(backup, inst.GetTargetPath())
+		if !encrypted {
+			files := m.manifestFiles(backup, valmap["udid"])
+			valmap["files"] = strconv.Itoa(len(files))
+			fileValues = util.AppendToDoubleSlice(fileValues, files)
+		}
+		entry, err := util.UnsafeEntryFromMap(valmap, header)
+		if err != nil {
+			zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		values = append(values, entry)
+	}
+
+	iPodPlists := inst.GetArtifactPaths("MacOSiPodPreferences")
+	for _, path := range iPodPlists {
+		vals, err := m.iPodDevices(path, inst.GetTargetPath())
+		if err != nil {
+			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		pairedValues = util.AppendToDoubleSlice(pairedValues, vals)
+	}
+	pairRecords := inst.GetArtifactPaths("MacOSLockdownPairRecords")
+	for _, path := range pairRecords {
+		entry, ok := m.pairRecord(path, inst.GetTargetPath())
+		if ok {
+			pairedValues = append(pairedValues, entry)
+		}
+	}
+	if len(iPodPlists) == 0 && len(pairRecords) == 0 {
+		zap.L().Warn("No paired iDevice records were found", zap.String("module", moduleName))
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] backups with [%d] files and [%d] paired devices", len(values), len(fileValues), len(pairedValues)), zap.String("module", moduleName))
+
+	// Write to output
+	for _, output := range []struct {
+		w      datawriter.OrionWriter
+		header []string
+		values [][]string
+	}{{mw, header, values}, {filesWriter, filesHeader, fileValues}, {pairedWriter, pairedHeader, pairedValues}} {
+		err = output.w.WriteHeader(output.header)
+		if err != nil {
+			return err
+		}
+		err = output.w.WriteAll(output.values)
+		if err != nil {
+			return err
+		}
+		err = output.w.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backup returns the row of the backup directory from its Info.plist and Manifest.plist and whether it is encrypted
+func (m MaciDeviceModule) backup(dir string, targetPath string) (map[string]string, bool) {
+	var valmap = make(map[string]string)
+	valmap = util.InitializeMapToEmptyString(valmap, header)
+	valmap["source_file"] = dir
+	valmap["user"] = util.GetUsernameFromPath(dir)
+	// backups are named after the UDID of the device, Info.plist and the Lockdown dictionary of Manifest.plist hold it too
+	valmap["udid"] = filepath.Base(dir)
+
+	info, err := machelpers.DecodePlist(filepath.Join(dir, "Info.plist"), targetPath)
+	if err != nil {
+		zap.L().Debug("could not parse Info.plist of '"+dir+"': "+err.Error(), zap.String("module", moduleName))
+	}
+	for _, item := range info {
+		for column, key := range infoKeys {
+			valmap[column] = m.value(item[key])
+		}
+		if udid := m.value(item["Unique Identifier"]); udid != "" {
+			valmap["udid"] = udid
+		}
+	}
+
+	encrypted := false
+	manifest, err := machelpers.DecodePlist(filepath.Join(dir, "Manifest.plist"), targetPath)
+	if err != nil {
+		zap.L().Debug("could not parse Manifest.plist of '"+dir+"': "+err.Error(), zap.String("module", moduleName))
+	}
+	for _, item := range manifest {
+		encrypted, _ = item["IsEncrypted"].(bool)
+		valmap["is_encrypted"] = strconv.FormatBool(encrypted)
+		valmap["passcode_set"] = m.value(item["WasPasscodeSet"])
+		if apps, ok := item["Applications"].(map[string]interface{}); ok {
+			valmap["applications"] = strconv.Itoa(len(apps))
+		}
+		if lockdown, ok := item["Lockdown"].(map[string]interface{}); ok {
+			for column, key := range lockdownKeys {
+				if valmap[column] == "" {
+					valmap[column] = m.value(lockdown[key])
+				}
+			}
+		}
+		if valmap["last_backup_date"] == "" {
+			valmap["last_backup_date"] = m.value(item["Date"])
+		}
+	}
+	return valmap, encrypted
+}
+
+// manifestFiles returns a row for every file of Manifest.db, which is itself encrypted in encrypted backups
+func (m MaciDeviceModule) manifestFiles(dir string, udid string) [][]string {
+	dbpath := filepath.Join(dir, "Manifest.db")
+	if !util.FileExists(dbpath) {
+		// backups of iOS 9 and earlier list their files in Manifest.mbdb
+		return nil
+	}
+	entries, err := util.QueryDB(dbpath, manifestQuery, manifestQueryHeaders, false)
+	if err != nil {
+		zap.L().Error("could not query '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+		return nil
+	}
+	values := [][]string{}
+	for _, e := range entries {
+		var valmap = make(map[string]string)
+		valmap = util.InitializeMapToEmptyString(valmap, filesHeader)
+		for i, h := range manifestQueryHeaders {
+			valmap[h] = e[i]
+		}
+		valmap["source_file"] = dbpath
+		valmap["user"] = util.GetUsernameFromPath(dbpath)
+		valmap["udid"] = udid
+		valmap["type"] = fileTypes[valmap["flags"]]
+		// the content of a file is kept under the first two characters of its ID
+		if valmap["type"] == "file" && len(valmap["file_id"]) > 2 {
+			valmap["backup_file"] = filepath.Join(dir, valmap["file_id"][:2], valmap["file_id"])
+		}
+		m.fileValues(valmap)
+
+		entry, err := util.UnsafeEntryFromMap(valmap, filesHeader)
+		if err != nil {
+			zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		values = append(values, entry)
+	}
+	return values
+}
+
+// fileValues sets the size and dates of a Manifest.db row from its file column, an MBFile archived by NSKeyedArchiver
+// as returned by QueryDB ("b64:" prefixed)
+func (m MaciDeviceModule) fileValues(valmap map[string]string) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(valmap["file"], "b64:"))
+	if err != nil || len(b) == 0 {
+		return
+	}
+	objects, err := machelpers.UnarchiveNSKeyedArchiverBytes(b)
+	if err != nil || len(objects) == 0 {
+		zap.L().Debug(fmt.Sprintf("could not decode file %s of '%s'", valmap["file_id"], valmap["source_file"]), zap.String("module", moduleName))
+		return
+	}
+	file, ok := objects[0].(map[string]interface{})
+	if !ok {
+		return
+	}
+	valmap["size"] = m.value(file["Size"])
+	// dates are Unix seconds
+	for column, key := range map[string]string{"created": "Birth", "modified": "LastModified", "status_changed": "LastStatusChange"} {
+		if seconds, ok := file[key].(uint64); ok && seconds > 0 {
+			valmap[column] = timeconv.Format(time.Unix(int64(seconds), 0))
+		}
+	}
+}
+
+// iPodDevices returns a row for every device of the Devices dictionary of com.apple.iPod.plist, keyed by device ID
+func (m MaciDeviceModule) iPodDevices(path string, targetPath string) ([][]string, error) {
+	data, err := machelpers.DecodePlist(path, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	values := [][]string{}
+	for _, item := range data {
+		devices, ok := item["Devices"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ids := []string{}
+		for id := range devices {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			device, ok := devices[id].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, pairedHeader)
+			valmap["source_file"] = path
+			valmap["user"] = util.GetUsernameFromPath(path)
+			valmap["source_name"] = "iPod"
+			valmap["device_id"] = id
+			for column, key := range iPodKeys {
+				valmap[column] = m.value(device[key])
+			}
+
+			entry, err := util.UnsafeEntryFromMap(valmap, pairedHeader)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+	}
+	return values, nil
+}
+
+// pairRecord returns the row of a lockdown pair record, named after the UDID of the device and created when it was
+// first trusted, other plists of the lockdown directory are skipped
+func (m MaciDeviceModule) pairRecord(path string, targetPath string) ([]string, bool) {
+	data, err := machelpers.DecodePlist(path, targetPath)
+	if err != nil {
+		zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
+		return nil, false
+	}
+	if len(data) == 0 || m.value(data[0]["HostID"]) == "" {
+		return nil, false
+	}
+	var valmap = make(map[string]string)
+	valmap = util.InitializeMapToEmptyString(valmap, pairedHeader)
+	valmap["source_file"] = path
+	valmap["source_name"] = "lockdown"
+	valmap["device_id"] = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	valmap["host_id"] = m.value(data[0]["HostID"])
+	valmap["system_buid"] = m.value(data[0]["SystemBUID"])
+	valmap["wifi_mac"] = m.value(data[0]["WiFiMACAddress"])
+	metadata := machelpers.FileTimestamps(path, moduleName)
+	valmap["mtime"] = metadata["mtime"]
+	valmap["btime"] = metadata["btime"]
+
+	entry, err := util.UnsafeEntryFromMap(valmap, pairedHeader)
+	if err != nil {
+		zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+		return nil, false
+	}
+	return entry, true
+}
+
+// value formats a plist value, missing keys are empty
+func (m MaciDeviceModule) value(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return timeconv.Format(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,11 @@
+package macidevice
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMaciDeviceModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MaciDeviceModule{}, moduletest.Options{Mask: []string{"mtime", "btime"}})
+}
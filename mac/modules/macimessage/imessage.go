@@ -0,0 +1,257 @@
+package macimessage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/imessage"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MaciMessageModule wraps Module methods
+type MaciMessageModule struct{}
+
+var (
+	moduleName  = "MaciMessageModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the Messages database (chat.db) of each user, iMessage and SMS forwarded from a paired iPhone
+	Lists every message with its handle and chat, the text is decoded from attributedBody when the text column is empty.
+	Attachments are written to MaciMessageModule-attachments
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"message_id",
+		"guid",
+		"date",
+		"date_read",
+		"date_delivered",
+		"service",
+		"handle",
+		"is_from_me",
+		"chat_identifier",
+		"chat_name",
+		"text",
+		"attachments",
+	}
+	attachmentsHeader = []string{
+		"source_file",
+		"user",
+		"message_id",
+		"attachment_id",
+		"guid",
+		"created_date",
+		"filename",
+		"mime_type",
+		"transfer_name",
+		"total_bytes",
+	}
+)
+
+// queries avoid NULLs as QueryDB does not handle them, a message is listed once per chat it belongs to
+const (
+	messagesQuery = `SELECT m.ROWID AS message_id, IFNULL(m.guid, '') AS guid, IFNULL(m.date, 0) AS date,
+	IFNULL(m.date_read, 0) AS date_read, IFNULL(m.date_delivered, 0) AS date_delivered, IFNULL(m.service, '') AS service,
+	IFNULL(h.id, '') AS handle, IFNULL(m.is_from_me, 0) AS is_from_me, IFNULL(c.chat_identifier, '') AS chat_identifier,
+	IFNULL(c.display_name, '') AS chat_name, IFNULL(m.text, '') AS text, IFNULL(m.attributedBody, '') AS attributed_body,
+	(SELECT COUNT(*) FROM message_attachment_join j WHERE j.message_id = m.ROWID) AS attachments
+	FROM message m
+	LEFT JOIN handle h ON h.ROWID = m.handle_id
+	LEFT JOIN chat_message_join cmj ON cmj.message_id = m.ROWID
+	LEFT JOIN chat c ON c.ROWID = cmj.chat_id
+	ORDER BY m.ROWID`
+	attachmentsQuery = `SELECT j.message_id AS message_id, a.ROWID AS attachment_id, IFNULL(a.guid, '') AS guid,
+	IFNULL(a.created_date, 0) AS created_date, IFNULL(a.filename, '') AS filename, IFNULL(a.mime_type, '') AS mime_type,
+	IFNULL(a.transfer_name, '') AS transfer_name, IFNULL(a.total_bytes, 0) AS total_bytes
+	FROM attachment a
+	JOIN message_attachment_join j ON j.attachment_id = a.ROWID
+	ORDER BY j.message_id, a.ROWID`
+)
+
+var (
+	messagesQueryHeaders    = []string{"message_id", "guid", "date", "date_read", "date_delivered", "service", "handle", "is_from_me", "chat_identifier", "chat_name", "text", "attributed_body", "attachments"}
+	attachmentsQueryHeaders = []string{"message_id", "attachment_id", "guid", "created_date", "filename", "mime_type", "transfer_name", "total_bytes"}
+)
+
+// Start starts the MaciMessageModule, should not be manually called
+func (m MaciMessageModule) Start(inst instance.Instance) error {
+	err := m.imessage(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MaciMessageModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module outputs for the super-timeline
+func (m MaciMessageModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "date", Type: "Message Sent/Received"},
+				{Field: "date_delivered", Type: "Message Delivered"},
+				{Field: "date_read", Type: "Message Read"},
+			},
+			UserField:         "user",
+			DescriptionFields: []string{"handle", "service", "text"},
+		},
+		{
+			Output:            moduleName + "-attachments",
+			Events:            []timeline.Event{{Field: "created_date", Type: "Message Attachment Created"}},
+			UserField:         "user",
+			DescriptionFields: []string{"transfer_name", "mime_type"},
+		},
+	}
+}
+
+func (m MaciMessageModule) imessage(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+	attachmentsWriter, err := datawriter.NewOrionWriter(moduleName+"-attachments", inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+	attachmentValues := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSMessagesChatDB")
+	if len(paths) == 0 {
+		zap.L().Warn("No Messages databases were found", zap.String("module", moduleName))
+	}
+
+	for _, dbpath := range paths {
+		user := util.GetUsernameFromPath(dbpath)
+		entries, err := util.QueryDB(dbpath, messagesQuery, messagesQueryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query messages of '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, e := range entries {
+			row := toMap(messagesQueryHeaders, e)
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			valmap["source_file"] = dbpath
+			valmap["user"] = user
+			for _, k := range []string{"message_id", "guid", "service", "handle", "is_from_me", "chat_identifier", "chat_name", "text", "attachments"} {
+				valmap[k] = row[k]
+			}
+			for _, k := range []string{"date", "date_read", "date_delivered"} {
+				valmap[k] = m.messageTime(row[k])
+			}
+			if valmap["text"] == "" && row["attributed_body"] != "" {
+				valmap["text"] = m.attributedBody(row["attributed_body"], dbpath, row["message_id"])
+			}
+
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+
+		entries, err = util.QueryDB(dbpath, attachmentsQuery, attachmentsQueryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query attachments of '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, e := range entries {
+			row := toMap(attachmentsQueryHeaders, e)
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, attachmentsHeader)
+			valmap["source_file"] = dbpath
+			valmap["user"] = user
+			for _, k := range []string{"message_id", "attachment_id", "guid", "filename", "mime_type", "transfer_name", "total_bytes"} {
+				valmap[k] = row[k]
+			}
+			valmap["created_date"] = m.messageTime(row["created_date"])
+
+			entry, err := util.UnsafeEntryFromMap(valmap, attachmentsHeader)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			attachmentValues = append(attachmentValues, entry)
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] messages and [%d] attachments from %d databases", len(values), len(attachmentValues), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	for _, output := range []struct {
+		w      datawriter.OrionWriter
+		header []string
+		values [][]string
+	}{{mw, header, values}, {attachmentsWriter, attachmentsHeader, attachmentValues}} {
+		err = output.w.WriteHeader(output.header)
+		if err != nil {
+			return err
+		}
+		err = output.w.WriteAll(output.values)
+		if err != nil {
+			return err
+		}
+		err = output.w.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// messageTime formats a date column of chat.db
+func (m MaciMessageModule) messageTime(v string) string {
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return v + "<FAILED TO CONVERT>"
+	}
+	return timeconv.Format(imessage.MessageTime(i))
+}
+
+// attributedBody returns the text of the attributedBody of a message as returned by QueryDB ("b64:" prefixed)
+func (m MaciMessageModule) attributedBody(v string, dbpath string, messageID string) string {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, "b64:"))
+	if err != nil {
+		return ""
+	}
+	text, err := imessage.AttributedBodyText(b)
+	if err != nil {
+		zap.L().Debug(fmt.Sprintf("could not decode attributedBody of message %s of '%s': %s", messageID, dbpath, err.Error()), zap.String("module", moduleName))
+		return ""
+	}
+	return text
+}
+
+// toMap returns the values of a QueryDB entry by query header
+func toMap(headers []string, entry []string) map[string]string {
+	row := make(map[string]string, len(headers))
+	for i, h := range headers {
+		if i < len(entry) {
+			row[h] = entry[i]
+		}
+	}
+	return row
+}
@@ -0,0 +1,11 @@
+package macimessage
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMaciMessageModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MaciMessageModule{}, moduletest.Options{})
+}
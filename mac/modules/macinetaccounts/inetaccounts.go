@@ -0,0 +1,192 @@
+package macinetaccounts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacINetAccountsModule wraps Module methods
+type MacINetAccountsModule struct{}
+
+var (
+	moduleName  = "MacINetAccountsModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the Internet Accounts database (Accounts3.sqlite/Accounts4.sqlite) of each user
+	Lists every account added in Internet Accounts or by an app (iCloud, Google, Exchange, ...) with its type, user
+	name and the date it was added, child accounts (ex. the CloudKit account of iCloud) reference their parent account
+	Inspiration taken from mac_apt by ydkhatri
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"account_id",
+		"identifier",
+		"account_type",
+		"account_type_identifier",
+		"description",
+		"username",
+		"created",
+		"parent_account",
+		"owning_bundle_id",
+		"active",
+		"authenticated",
+		"visible",
+	}
+)
+
+var (
+	queryHeaders = []string{"account_id", "identifier", "account_type", "account_type_identifier", "description", "username", "created", "parent_account", "owning_bundle_id", "active", "authenticated", "visible"}
+
+	// optional columns of ZACCOUNT, missing ones are left empty
+	optionalColumns = []string{"ZOWNINGBUNDLEID", "ZACTIVE", "ZAUTHENTICATED", "ZVISIBLE"}
+)
+
+// Start starts the MacINetAccountsModule, should not be manually called
+func (m MacINetAccountsModule) Start(inst instance.Instance) error {
+	err := m.inetaccounts(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacINetAccountsModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacINetAccountsModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "created", Type: "Internet Account Added"}},
+			UserField:         "user",
+			DescriptionFields: []string{"account_type", "username"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacINetAccountsModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_file", "identifier"}, Ignore: []string{"account_id"}},
+	}
+}
+
+func (m MacINetAccountsModule) inetaccounts(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSInternetAccounts")
+	if len(paths) == 0 {
+		zap.L().Warn("No Internet Accounts databases were found", zap.String("module", moduleName))
+	}
+
+	for _, dbpath := range paths {
+		query, err := m.query(dbpath)
+		if err != nil {
+			zap.L().Error("could not read '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		entries, err := util.QueryDB(dbpath, query, queryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, e := range entries {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			for i, h := range queryHeaders {
+				valmap[h] = e[i]
+			}
+			valmap["source_file"] = dbpath
+			valmap["user"] = util.GetUsernameFromPath(dbpath)
+			raw := valmap["created"]
+			valmap["created"], err = timeconv.ConvertToString(timeconv.Cocoa, raw)
+			if err != nil {
+				valmap["created"] = raw + "<FAILED TO CONVERT>"
+			}
+			for _, k := range []string{"active", "authenticated", "visible"} {
+				switch valmap[k] {
+				case "1":
+					valmap[k] = "true"
+				case "0":
+					valmap[k] = "false"
+				}
+			}
+
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] accounts from %d databases", len(values), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// query returns the accounts query for the columns of the database, NULLs are avoided as QueryDB does not handle them
+func (m MacINetAccountsModule) query(dbpath string) (string, error) {
+	names, err := util.DBColumnNames(dbpath, "ZACCOUNT")
+	if err != nil {
+		return "", err
+	}
+	columns := map[string]bool{}
+	for _, name := range names {
+		columns[strings.ToUpper(name)] = true
+	}
+	optional := []interface{}{}
+	for _, c := range optionalColumns {
+		if columns[c] {
+			optional = append(optional, "a."+c)
+		} else {
+			optional = append(optional, "NULL")
+		}
+	}
+	return fmt.Sprintf(`SELECT a.Z_PK AS account_id, IFNULL(a.ZIDENTIFIER, '') AS identifier,
+	IFNULL(t.ZACCOUNTTYPEDESCRIPTION, '') AS account_type, IFNULL(t.ZIDENTIFIER, '') AS account_type_identifier,
+	IFNULL(a.ZACCOUNTDESCRIPTION, '') AS description, IFNULL(a.ZUSERNAME, '') AS username, IFNULL(a.ZDATE, '') AS created,
+	IFNULL(p.ZIDENTIFIER, '') AS parent_account, IFNULL(%s, '') AS owning_bundle_id, IFNULL(%s, '') AS active,
+	IFNULL(%s, '') AS authenticated, IFNULL(%s, '') AS visible
+	FROM ZACCOUNT a
+	LEFT JOIN ZACCOUNTTYPE t ON t.Z_PK = a.ZACCOUNTTYPE
+	LEFT JOIN ZACCOUNT p ON p.Z_PK = a.ZPARENTACCOUNT
+	ORDER BY a.Z_PK`, optional...), nil
+}
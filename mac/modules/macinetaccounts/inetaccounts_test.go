@@ -0,0 +1,11 @@
+package macinetaccounts
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacINetAccountsModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacINetAccountsModule{}, moduletest.Options{})
+}
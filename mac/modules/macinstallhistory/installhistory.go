@@ -1,5 +1,3 @@
-// +build darwin
-
 package macinstallhistory
 
 import (
@@ -8,7 +6,10 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -44,6 +45,23 @@ func (m MacInstallHistoryModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacInstallHistoryModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacInstallHistoryModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Incremental:       true,
+			Events:            []timeline.Event{{Field: "timestamp", Type: "Software Installed"}},
+			DescriptionFields: []string{"display_name", "display_version", "process_name"},
+		},
+	}
+}
+
 func (m MacInstallHistoryModule) installHistory(inst instance.Instance) error {
 	zap.L().Debug("Parsing InstallHistory.plist file from "+filepathInstallHistoryPlist, zap.String("module", moduleName))
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
@@ -100,7 +118,7 @@ func (m MacInstallHistoryModule) installHistory(inst instance.Instance) error {
 		}
 
 		entry := []string{
-			result.Date.UTC().Format(time.RFC3339),
+			timeconv.Format(result.Date),
 			result.ContentType,
 			result.DisplayName,
 			result.DisplayVersion,
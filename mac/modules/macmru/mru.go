@@ -1,21 +1,18 @@
-// +build darwin
-
 package macmru
 
-//#cgo CFLAGS: -x objective-c
-//#cgo LDFLAGS: -framework Foundation
-//#include "foundation.h"
-import "C"
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
-	"unsafe"
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/sfl2"
+	"github.com/anthonybm/Orion/moduleinfo"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/machelpers"
 	"go.uber.org/zap"
@@ -27,9 +24,10 @@ type MacMRUModule struct {
 var (
 	moduleName  = "MacMRUModule"
 	mode        = "mac"
-	version     = "1.0"
+	version     = "1.1"
 	description = `
 	Reads and parses the SFL, SFL2, and other various MRU plist files.
+	Bookmark and Alias records are decoded natively, giving the target path, creation date, volume and file ID.
 	Inspiration taken from Sarah Edwards, AutoMactc by CrowdStrike, and others.
 	`
 	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
@@ -44,31 +42,13 @@ var (
 		"order",
 		"name",
 		"url",
+		"target_created",
+		"volume_name",
+		"volume_uuid",
+		"file_id",
 		"source_key",
 		"extras",
 	}
-	filepathsSidebarPlists = []string{
-		"Users/*/Library/Preferences/com.apple.sidebarlists.plist",
-		"private/var/*/Library/Preferences/com.apple.sidebarlists.plist",
-	}
-	filepathsFinderPlists = []string{
-		"Users/*/Library/Preferences/com.apple.finder.plist",
-		"private/var/*/Library/Preferences/com.apple.finder.plist",
-	}
-	filepathsSecureBookmarks = []string{
-		"Users/*/Library/Containers/*/Data/Library/Preferences/*.securebookmarks.plist",
-		"private/var/*/Library/Containers/*/Data/Library/Preferences/*.securebookmarks.plist",
-	}
-	filepathsSFLs = []string{
-		"Users/*/Library/Application Support/com.apple.sharedfilelist/*.sfl",
-		"Users/*/Library/Application Support/com.apple.sharedfilelist/*/*.sfl",
-		"private/var/*/Library/Application Support/com.apple.sharedfilelist/*/*.sfl",
-	}
-	filepathsSFL2s = []string{
-		"Users/*/Library/Application Support/com.apple.sharedfilelist/*.sfl2",
-		"Users/*/Library/Application Support/com.apple.sharedfilelist/*/*.sfl2",
-		"private/var/*/Library/Application Support/com.apple.sharedfilelist/*/*.sfl2",
-	}
 )
 
 func (m MacMRUModule) Start(inst instance.Instance) error {
@@ -79,6 +59,11 @@ func (m MacMRUModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacMRUModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
 func (m MacMRUModule) mru(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -161,83 +146,16 @@ func (m MacMRUModule) mru(inst instance.Instance) error {
 	return nil
 }
 
-func (m MacMRUModule) SFLs(inst instance.Instance) ([][]string, error) {
-	values := [][]string{}
-	count := 0
-
-	SFLPaths := util.Multiglob(filepathsSFLs, inst.GetTargetPath())
-	if len(SFLPaths) == 0 {
-		return [][]string{}, errors.New("no SFL files were found")
-	}
-
-	for _, path := range SFLPaths {
-		var valmap = make(map[string]string)
-		valmap = util.InitializeMapToEmptyString(valmap, header)
-
-		valmap["user"] = util.GetUsernameFromPath(path)
-
-		// Parse plist/bplist
-		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
-		if err != nil {
-			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
-			continue
-		}
-		for _, item := range data {
-			machelpers.PrintPlistAsJSON(item)
-			return [][]string{}, errors.New("SFL - Unimplemented method - go build this feature :) ")
-		}
-	}
-	zap.L().Debug(fmt.Sprintf("Parsed [%d] SFL entries", count), zap.String("module", moduleName))
-
-	return values, nil
-}
-
-func (m MacMRUModule) SFL2s(inst instance.Instance) ([][]string, error) {
-	values := [][]string{}
-	count := 0
-
-	SFL2Paths := util.Multiglob(filepathsSFL2s, inst.GetTargetPath())
-	if len(SFL2Paths) == 0 {
-		return [][]string{}, errors.New("no SFL2 files were found")
-	}
-
-	for _, path := range SFL2Paths {
-		var valmap = make(map[string]string)
-		valmap = util.InitializeMapToEmptyString(valmap, header)
-
-		valmap["user"] = util.GetUsernameFromPath(path)
-
-		// Parse plist/bplist
-		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
-		if err != nil {
-			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
-			continue
-		}
-		for _, item := range data {
-			machelpers.PrintPlistAsJSON(item)
-			return [][]string{}, errors.New("SFL2 - Unimplemented method - go build this feature :) ")
-		}
-	}
-	zap.L().Debug(fmt.Sprintf("Parsed [%d] SFL2 entries", count), zap.String("module", moduleName))
-
-	return values, nil
-}
-
 func (m MacMRUModule) sidebarPlists(inst instance.Instance) ([][]string, error) {
 	values := [][]string{}
 	count := 0
 
-	sidebarPlistPaths := util.Multiglob(filepathsSidebarPlists, inst.GetTargetPath())
+	sidebarPlistPaths := inst.GetArtifactPaths("MacOSSidebarLists")
 	if len(sidebarPlistPaths) == 0 {
 		return [][]string{}, errors.New("no Sidebar Plists were found")
 	}
 
 	for _, path := range sidebarPlistPaths {
-		var valmap = make(map[string]string)
-		valmap = util.InitializeMapToEmptyString(valmap, header)
-
-		valmap["user"] = util.GetUsernameFromPath(path)
-
 		// Parse plist/bplist
 		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
 		if err != nil {
@@ -245,8 +163,55 @@ func (m MacMRUModule) sidebarPlists(inst instance.Instance) ([][]string, error)
 			continue
 		}
 		for _, item := range data {
-			machelpers.PrintPlistAsJSON(item)
-			return [][]string{}, errors.New("Sidebar Plists - Unimplemented method - go build this feature :) ")
+			// each section (favorites, systemitems, favoriteservers...) holds lists of items with an alias or bookmark
+			sections := []string{}
+			for k := range item {
+				sections = append(sections, k)
+			}
+			sort.Strings(sections)
+			for _, section := range sections {
+				lists, ok := item[section].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for _, list := range []string{"CustomListItems", "VolumesList"} {
+					listItems, ok := lists[list].([]interface{})
+					if !ok {
+						continue
+					}
+					for i, li := range listItems {
+						listItem, ok := li.(map[string]interface{})
+						if !ok {
+							continue
+						}
+						var valmap = make(map[string]string)
+						valmap = util.InitializeMapToEmptyString(valmap, header)
+						valmap["user"] = util.GetUsernameFromPath(path)
+						valmap["source_file"] = path
+						valmap["source_name"] = "SidebarPlist"
+						valmap["source_key"] = section + "/" + list
+						valmap["item_index"] = strconv.Itoa(i)
+						valmap["name"], _ = listItem["Name"].(string)
+						valmap["url"], _ = listItem["URL"].(string)
+						extras := map[string]interface{}{}
+						for _, key := range []string{"Bookmark", "Alias"} {
+							if b, ok := listItem[key].([]byte); ok {
+								m.bookmarkValues(valmap, extras, b)
+								break
+							}
+						}
+						valmap["extras"] = m.extras(extras)
+
+						entry, err := util.UnsafeEntryFromMap(valmap, header)
+						if err != nil {
+							zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+							continue
+						}
+						values = append(values, entry)
+						count++
+					}
+				}
+			}
 		}
 	}
 	zap.L().Debug(fmt.Sprintf("Parsed [%d] Sidebar Plist entries", count), zap.String("module", moduleName))
@@ -258,74 +223,67 @@ func (m MacMRUModule) finderPlists(inst instance.Instance) ([][]string, error) {
 	values := [][]string{}
 	count := 0
 
-	finderPlistPaths := util.Multiglob(filepathsFinderPlists, inst.GetTargetPath())
+	finderPlistPaths := inst.GetArtifactPaths("MacOSFinderPreferences")
 	if len(finderPlistPaths) == 0 {
 		return [][]string{}, errors.New("no Finder Plists were found")
 	}
 
 	for _, path := range finderPlistPaths {
-		var valmap = make(map[string]string)
-		valmap = util.InitializeMapToEmptyString(valmap, header)
-
-		valmap["user"] = util.GetUsernameFromPath(path)
-
 		// Parse plist/bplist
 		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
 		if err != nil {
 			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
 			continue
 		}
+		newValmap := func(sourceKey string, index int) map[string]string {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			valmap["user"] = util.GetUsernameFromPath(path)
+			valmap["source_file"] = path
+			valmap["source_name"] = "FinderPlist"
+			valmap["source_key"] = sourceKey
+			valmap["item_index"] = strconv.Itoa(index)
+			return valmap
+		}
 		for _, item := range data {
-			if fxrecentfolders, ok := item["FXRecentFolders"]; ok {
-				for _, fxitem := range fxrecentfolders.([]interface{}) {
-					// Extract name key from fxitem
-					if nameVal, ok := fxitem.(map[string]interface{})["name"]; ok {
-						valmap["name"], err = util.InterfaceToString(nameVal)
-						if err != nil {
-							valmap["name"] = "ERROR"
-							zap.L().Error(err.Error())
-						}
-					} else {
-						valmap["name"] = "ERROR"
+			if fxrecentfolders, ok := item["FXRecentFolders"].([]interface{}); ok {
+				for i, fxitem := range fxrecentfolders {
+					folder, ok := fxitem.(map[string]interface{})
+					if !ok {
+						continue
 					}
+					valmap := newValmap("FXRecentFolders", i)
+					valmap["name"], _ = folder["name"].(string)
+					extras := map[string]interface{}{}
+					if b, ok := folder["file-bookmark"].([]byte); ok {
+						m.bookmarkValues(valmap, extras, b)
+					}
+					valmap["extras"] = m.extras(extras)
 
-					// Extract file-bookmark from fxitem using Objective-C Foundation api
-					if _, ok := fxitem.(map[string]interface{})["file-bookmark"]; ok {
-						for _, item := range NSArrayURLToGoSliceURL(C.FinderFXRecentFolders()) {
-							valmap["url"] = item.String()
-
-							valmap["user"] = util.GetUsernameFromPath(path)
-							valmap["source_file"] = path
-							valmap["source_name"] = "FinderPlist"
-							valmap["source_key"] = "FXRecentFolders"
-							valmap["extras"] = ""
-							entry, err := util.UnsafeEntryFromMap(valmap, header)
-							if err != nil {
-								zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
-								continue
-							}
-							values = append(values, entry)
-							count++
-						}
+					entry, err := util.UnsafeEntryFromMap(valmap, header)
+					if err != nil {
+						zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+						continue
 					}
+					values = append(values, entry)
+					count++
 				}
 			}
-			if moveandcopy, ok := item["RecentMoveAndCopyDestinations"]; ok {
-				for _, moveandcopyitem := range moveandcopy.([]interface{}) {
-					// fmt.Println("MoveAndCopyItem: ", moveandcopyitem)
+			if moveandcopy, ok := item["RecentMoveAndCopyDestinations"].([]interface{}); ok {
+				for i, moveandcopyitem := range moveandcopy {
+					valmap := newValmap("RecentMoveAndCopyDestinations", i)
 					valmap["url"] = fmt.Sprint(moveandcopyitem)
 
-					valmap["user"] = util.GetUsernameFromPath(path)
-					valmap["source_file"] = path
-					valmap["source_name"] = "FinderPlist"
-					valmap["source_key"] = "RecentMoveAndCopyDestinations"
-					valmap["extras"] = ""
+					entry, err := util.UnsafeEntryFromMap(valmap, header)
+					if err != nil {
+						zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+						continue
+					}
+					values = append(values, entry)
 					count++
 				}
 			}
-			if _, ok := item["FXDesktopVolumePositions"]; ok {
-				zap.L().Warn("FinderPlist - Unimplemented parsing of 'FXDesktopVolumePositions' - go build this feature :) ", zap.String("module", moduleName))
-			}
+			// FXDesktopVolumePositions is parsed by MacPeripheralsModule
 			if _, ok := item["FXConnectToLastURL"]; ok {
 				zap.L().Warn("FinderPlist - Unimplemented parsing of 'FXConnectToLastURL' - go build this feature :) ", zap.String("module", moduleName))
 			}
@@ -352,15 +310,12 @@ func (m MacMRUModule) secureBookmarks(inst instance.Instance) ([][]string, error
 	values := [][]string{}
 	count := 0
 
-	secureBookmarkPaths := util.Multiglob(filepathsSecureBookmarks, inst.GetTargetPath())
+	secureBookmarkPaths := inst.GetArtifactPaths("MacOSSecureBookmarks")
 	if len(secureBookmarkPaths) == 0 {
 		return [][]string{}, errors.New("no Secure Bookmarks were found")
 	}
 
 	for _, path := range secureBookmarkPaths {
-		var valmap = make(map[string]string)
-		valmap = util.InitializeMapToEmptyString(valmap, header)
-
 		// Parse plist/bplist
 		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
 		if err != nil {
@@ -368,13 +323,31 @@ func (m MacMRUModule) secureBookmarks(inst instance.Instance) ([][]string, error
 			continue
 		}
 		for _, item := range data {
-			for k, v := range item {
+			// keys are the URLs the application was granted access to
+			urls := []string{}
+			for k := range item {
+				urls = append(urls, k)
+			}
+			sort.Strings(urls)
+			for i, k := range urls {
+				var valmap = make(map[string]string)
+				valmap = util.InitializeMapToEmptyString(valmap, header)
 				valmap["user"] = util.GetUsernameFromPath(path)
 				valmap["source_file"] = path
 				valmap["source_name"] = "SecureBookmarks"
-				valmap["name"] = util.GetUsernameFromPath(k)
-				valmap["url"] = fmt.Sprint(k)
-				valmap["extras"] = util.MapToJSONString(v.(map[string]interface{}))
+				valmap["source_key"] = strings.TrimSuffix(filepath.Base(path), ".securebookmarks.plist")
+				valmap["item_index"] = strconv.Itoa(i)
+				valmap["name"] = filepath.Base(k)
+				valmap["url"] = k
+				extras := map[string]interface{}{}
+				if v, ok := item[k].(map[string]interface{}); ok {
+					if b, ok := v["data"].([]byte); ok {
+						m.bookmarkValues(valmap, extras, b)
+					}
+				}
+				// the key is the URL as granted, keep it over the bookmark target
+				valmap["url"] = k
+				valmap["extras"] = m.extras(extras)
 
 				entry, err := util.UnsafeEntryFromMap(valmap, header)
 				if err != nil {
@@ -392,109 +365,95 @@ func (m MacMRUModule) secureBookmarks(inst instance.Instance) ([][]string, error
 }
 
 func (m MacMRUModule) sfl(inst instance.Instance) ([][]string, error) {
-	values := [][]string{}
-	count := 0
-
-	sflPaths := util.Multiglob(filepathsSFLs, inst.GetTargetPath())
-	if len(sflPaths) == 0 {
-		return [][]string{}, errors.New("no SFL files were found")
-	}
-
-	for _, path := range sflPaths {
-		var valmap = make(map[string]string)
-		valmap = util.InitializeMapToEmptyString(valmap, header)
-
-		valmap["user"] = util.GetUsernameFromPath(path)
-
-		// Parse plist/bplist
-		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
-		if err != nil {
-			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
-			continue
-		}
-		for _, item := range data {
-			machelpers.PrintPlistAsJSON(item)
-			return [][]string{}, errors.New("Unimplemented method - go build this feature :) ")
-		}
-	}
-	zap.L().Debug(fmt.Sprintf("Parsed [%d] SFL entries", count), zap.String("module", moduleName))
-
-	return values, nil
+	return m.sharedFileLists(inst, "MacOSSharedFileListsSFL", "SFL")
 }
 
 func (m MacMRUModule) sfl2(inst instance.Instance) ([][]string, error) {
+	return m.sharedFileLists(inst, "MacOSSharedFileListsSFL2", "SFL2")
+}
+
+// sharedFileLists parses the SFL or SFL2 lists of the artifact, the lists only differ in the keys of their items
+func (m MacMRUModule) sharedFileLists(inst instance.Instance, artifact string, sourceName string) ([][]string, error) {
 	values := [][]string{}
 	count := 0
 
-	sfl2Paths := util.Multiglob(filepathsSFL2s, inst.GetTargetPath())
-	if len(sfl2Paths) == 0 {
-		return [][]string{}, errors.New("no SFL2 files were found")
+	paths := inst.GetArtifactPaths(artifact)
+	if len(paths) == 0 {
+		return [][]string{}, errors.New("no " + sourceName + " files were found")
 	}
 
-	for _, path := range sfl2Paths {
-		var valmap = make(map[string]string)
-		valmap = util.InitializeMapToEmptyString(valmap, header)
-
-		valmap["user"] = util.GetUsernameFromPath(path)
-
-		// Parse plist/bplist
-		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
+	for _, path := range paths {
+		list, err := sfl2.ParseFile(path)
 		if err != nil {
-			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			zap.L().Error("could not parse "+strings.ToLower(sourceName)+" '"+path+"': "+err.Error(), zap.String("module", moduleName))
 			continue
 		}
-		for _, item := range data {
-			machelpers.PrintPlistAsJSON(item)
-			return [][]string{}, errors.New("Unimplemented method - go build this feature :) ")
+		for _, item := range list.Items {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			valmap["user"] = util.GetUsernameFromPath(path)
+			valmap["source_file"] = path
+			valmap["source_name"] = sourceName
+			valmap["source_key"] = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			valmap["item_index"] = strconv.Itoa(item.Index)
+			if item.Order > 0 {
+				valmap["order"] = strconv.FormatUint(item.Order, 10)
+			}
+			valmap["name"] = item.Name
+			extras := map[string]interface{}{"uuid": item.UUID, "visibility": item.Visibility, "properties": item.Properties}
+			if len(item.Bookmark) > 0 {
+				m.bookmarkValues(valmap, extras, item.Bookmark)
+			}
+			if item.URL != "" {
+				valmap["url"] = item.URL
+			}
+			valmap["extras"] = m.extras(extras)
+
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+			count++
 		}
 	}
-	zap.L().Debug(fmt.Sprintf("Parsed [%d] SFL2 entries", count), zap.String("module", moduleName))
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] %s entries", count, sourceName), zap.String("module", moduleName))
 
 	return values, nil
 }
 
-func NSArrayURLToGoSliceURL(arr *C.NSArray) []url.URL {
-	var result []url.URL
-	length := NSArrayLen(arr)
-
-	for i := uint(0); i < length; i++ {
-		nsurl := (*C.NSURL)(NSArrayItem(arr, i))
-		u := NSURLToGoURL(nsurl)
-		result = append(result, *u)
+// bookmarkValues sets the target columns of valmap from a Bookmark or Alias record, the creator and security scope
+// of the bookmark go to extras
+func (m MacMRUModule) bookmarkValues(valmap map[string]string, extras map[string]interface{}, b []byte) {
+	bm, err := machelpers.DecodeBookmark(b)
+	if err != nil {
+		zap.L().Debug("could not decode bookmark of '"+valmap["source_file"]+"': "+err.Error(), zap.String("module", moduleName))
+		return
+	}
+	for k, v := range bm.Columns() {
+		valmap[k] = v
+	}
+	if valmap["name"] == "" && bm.Path != "" {
+		valmap["name"] = filepath.Base(bm.Path)
+	}
+	extras["bookmark_format"] = bm.Format
+	if bm.UserName != "" {
+		extras["bookmark_user"] = bm.UserName
+	}
+	if bm.SecurityExtension != "" {
+		extras["security_extension"] = bm.SecurityExtension
 	}
-	return result
-}
-func NSArrayLen(arr *C.NSArray) uint { return uint(C.NSArrayLen(arr)) }
-
-func NSArrayItem(arr *C.NSArray, i uint) unsafe.Pointer {
-	return C.NSArrayItem(arr, C.ulong(i))
 }
 
-func NSStringToCString(s *C.NSString) *C.char { return C.NSStringToCString(s) }
-
-func NSStringToGoString(s *C.NSString) string { return C.GoString(NSStringToCString(s)) }
-
-func NSNumberToGoInt(i *C.NSNumber) int { return int(C.NSNumberToGoInt(i)) }
-
-func NSURLToGoURL(nsurlptr *C.NSURL) *url.URL {
-	nsurl := *C.NSURLData(nsurlptr)
-	userInfo := url.UserPassword(
-		NSStringToGoString(nsurl.user),
-		NSStringToGoString(nsurl.password),
-	)
-	host := NSStringToGoString(nsurl.host)
-
-	if nsurl.port != nil {
-		port := NSNumberToGoInt(nsurl.port)
-		host = host + ":" + strconv.FormatInt(int64(port), 10)
+// extras returns the extras column of a row
+func (m MacMRUModule) extras(extras map[string]interface{}) string {
+	if len(extras) == 0 {
+		return ""
 	}
-
-	return &url.URL{
-		Scheme:   NSStringToGoString(nsurl.scheme),
-		User:     userInfo, // username and password information
-		Host:     host,     // host or host:port
-		Path:     NSStringToGoString(nsurl.path),
-		RawQuery: NSStringToGoString(nsurl.query),    // encoded query values, without '?'
-		Fragment: NSStringToGoString(nsurl.fragment), // fragment for references, without '#'
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
 	}
+	return string(b)
 }
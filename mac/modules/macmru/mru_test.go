@@ -0,0 +1,11 @@
+package macmru
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacMRUModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacMRUModule{}, moduletest.Options{})
+}
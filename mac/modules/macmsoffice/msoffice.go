@@ -0,0 +1,355 @@
+package macmsoffice
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacMSOfficeModule wraps Module methods
+type MacMSOfficeModule struct{}
+
+var (
+	moduleName  = "MacMSOfficeModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the recently used documents of Microsoft Office 2016 and later (Word, Excel, PowerPoint, ...)
+	Documents are listed from the security scoped bookmarks of each Office app with the last time they were opened.
+	Every value of the Office registry (MicrosoftRegistrationDB.reg) is written to MacMSOfficeModule-registry with
+	its full key path and key modification time.
+	Inspiration taken from mac_apt by ydkhatri
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"source_name",
+		"item_index",
+		"order",
+		"name",
+		"url",
+		"target_created",
+		"volume_name",
+		"volume_uuid",
+		"file_id",
+		"source_key",
+		"last_used",
+		"extras",
+	}
+	registryHeader = []string{
+		"source_file",
+		"user",
+		"key_path",
+		"key_modified",
+		"value_name",
+		"value_type",
+		"value_data",
+	}
+)
+
+// queries avoid NULLs as QueryDB does not handle them
+const (
+	keysQuery = `SELECT node_id, IFNULL(parent_id, -1) AS parent_id, IFNULL(name, '') AS name,
+	IFNULL(write_time, 0) AS write_time FROM HKEY_CURRENT_USER ORDER BY node_id`
+	valuesQuery = `SELECT node_id, IFNULL(name, '') AS name, IFNULL(type, 0) AS type, IFNULL(value, '') AS value
+	FROM HKEY_CURRENT_USER_values ORDER BY node_id, name`
+)
+
+var (
+	keysQueryHeaders   = []string{"node_id", "parent_id", "name", "write_time"}
+	valuesQueryHeaders = []string{"node_id", "name", "type", "value"}
+
+	// registry value types used by Office
+	valueTypes = map[string]string{
+		"1":  "REG_SZ",
+		"2":  "REG_EXPAND_SZ",
+		"3":  "REG_BINARY",
+		"4":  "REG_DWORD",
+		"7":  "REG_MULTI_SZ",
+		"11": "REG_QWORD",
+	}
+)
+
+// Start starts the MacMSOfficeModule, should not be manually called
+func (m MacMSOfficeModule) Start(inst instance.Instance) error {
+	err := m.msoffice(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacMSOfficeModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module outputs for the super-timeline
+func (m MacMSOfficeModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "last_used", Type: "Office Document Opened"}},
+			UserField:         "user",
+			DescriptionFields: []string{"source_key", "url"},
+		},
+		{
+			Output:            moduleName + "-registry",
+			Events:            []timeline.Event{{Field: "key_modified", Type: "Office Registry Key Modified"}},
+			UserField:         "user",
+			DescriptionFields: []string{"key_path", "value_name", "value_data"},
+		},
+	}
+}
+
+func (m MacMSOfficeModule) msoffice(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+	registryWriter, err := datawriter.NewOrionWriter(moduleName+"-registry", inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := m.secureBookmarks(inst)
+	registryValues := m.registry(inst)
+
+	// Write to output
+	for _, output := range []struct {
+		w      datawriter.OrionWriter
+		header []string
+		values [][]string
+	}{{mw, header, values}, {registryWriter, registryHeader, registryValues}} {
+		err = output.w.WriteHeader(output.header)
+		if err != nil {
+			return err
+		}
+		err = output.w.WriteAll(output.values)
+		if err != nil {
+			return err
+		}
+		err = output.w.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// secureBookmarks lists the documents of the securebookmarks plists of the Office apps, keyed by the URL of the
+// document with the bookmark, last used date and a UUID as values
+func (m MacMSOfficeModule) secureBookmarks(inst instance.Instance) [][]string {
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSMSOfficeSecureBookmarks")
+	if len(paths) == 0 {
+		zap.L().Warn("No Office secure bookmarks were found", zap.String("module", moduleName))
+	}
+
+	for _, path := range paths {
+		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
+		if err != nil {
+			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		// com.microsoft.Word.securebookmarks.plist is the MRU of Word
+		app := strings.TrimPrefix(strings.TrimSuffix(filepath.Base(path), ".securebookmarks.plist"), "com.microsoft.")
+		for _, item := range data {
+			urls := []string{}
+			for k := range item {
+				urls = append(urls, k)
+			}
+			sort.Strings(urls)
+			for i, k := range urls {
+				var valmap = make(map[string]string)
+				valmap = util.InitializeMapToEmptyString(valmap, header)
+				valmap["source_file"] = path
+				valmap["user"] = util.GetUsernameFromPath(path)
+				valmap["source_name"] = "SecureBookmarks"
+				valmap["source_key"] = app
+				valmap["item_index"] = strconv.Itoa(i)
+				valmap["name"] = filepath.Base(k)
+				extras := map[string]interface{}{}
+				if v, ok := item[k].(map[string]interface{}); ok {
+					if b, ok := v["kBookmarkDataKey"].([]byte); ok {
+						m.bookmarkValues(valmap, extras, b)
+					}
+					if date, ok := v["kLastUsedDateKey"]; ok {
+						valmap["last_used"], err = timeconv.ConvertToString(timeconv.Cocoa, date)
+						if err != nil {
+							valmap["last_used"] = fmt.Sprint(date) + "<FAILED TO CONVERT>"
+						}
+					}
+					if uuid, ok := v["kUUIDKey"].(string); ok {
+						extras["uuid"] = uuid
+					}
+				}
+				// the key is the URL as opened, keep it over the bookmark target
+				valmap["url"] = k
+				valmap["extras"] = m.extras(extras)
+
+				entry, err := util.UnsafeEntryFromMap(valmap, header)
+				if err != nil {
+					zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+					continue
+				}
+				values = append(values, entry)
+			}
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] Office documents from %d secure bookmarks", len(values), len(paths)), zap.String("module", moduleName))
+
+	return values
+}
+
+// bookmarkValues sets the target columns of a row from the bookmark of a document
+func (m MacMSOfficeModule) bookmarkValues(valmap map[string]string, extras map[string]interface{}, b []byte) {
+	bm, err := machelpers.DecodeBookmark(b)
+	if err != nil {
+		zap.L().Debug("could not decode bookmark of '"+valmap["source_file"]+"': "+err.Error(), zap.String("module", moduleName))
+		return
+	}
+	for k, v := range bm.Columns() {
+		valmap[k] = v
+	}
+	extras["bookmark_format"] = bm.Format
+	if bm.Path != "" {
+		extras["bookmark_path"] = bm.Path
+	}
+	if bm.UserName != "" {
+		extras["bookmark_user"] = bm.UserName
+	}
+}
+
+// registry lists every value of the Office registries, a SQLite database with the keys in HKEY_CURRENT_USER and their
+// values in HKEY_CURRENT_USER_values
+func (m MacMSOfficeModule) registry(inst instance.Instance) [][]string {
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSMicrosoftRegistrationDB")
+	if len(paths) == 0 {
+		zap.L().Warn("No Office registries were found", zap.String("module", moduleName))
+	}
+
+	for _, dbpath := range paths {
+		keys, err := util.QueryDB(dbpath, keysQuery, keysQueryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query keys of '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		parents := map[string]string{}
+		names := map[string]string{}
+		modified := map[string]string{}
+		for _, k := range keys {
+			parents[k[0]] = k[1]
+			names[k[0]] = k[2]
+			modified[k[0]], err = timeconv.ConvertToString(timeconv.FILETIME, k[3])
+			if err != nil {
+				modified[k[0]] = k[3] + "<FAILED TO CONVERT>"
+			}
+		}
+
+		entries, err := util.QueryDB(dbpath, valuesQuery, valuesQueryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query values of '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, e := range entries {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, registryHeader)
+			valmap["source_file"] = dbpath
+			valmap["user"] = util.GetUsernameFromPath(dbpath)
+			valmap["key_path"] = m.keyPath(e[0], parents, names)
+			valmap["key_modified"] = modified[e[0]]
+			valmap["value_name"] = e[1]
+			valmap["value_type"] = e[2]
+			if t, ok := valueTypes[e[2]]; ok {
+				valmap["value_type"] = t
+			}
+			valmap["value_data"] = m.valueData(valmap["value_type"], e[3])
+
+			entry, err := util.UnsafeEntryFromMap(valmap, registryHeader)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] Office registry values from %d registries", len(values), len(paths)), zap.String("module", moduleName))
+
+	return values
+}
+
+// keyPath returns the path of a key from the root of the registry, the keys are walked up until a key without a
+// known parent
+func (m MacMSOfficeModule) keyPath(node string, parents map[string]string, names map[string]string) string {
+	path := []string{}
+	seen := map[string]bool{}
+	for node != "" && !seen[node] {
+		name, ok := names[node]
+		if !ok {
+			break
+		}
+		seen[node] = true
+		path = append([]string{name}, path...)
+		node = parents[node]
+	}
+	return strings.Join(path, `\`)
+}
+
+// valueData returns the data of a value, strings stored as blobs are UTF-16LE and other blobs are kept as returned by
+// QueryDB ("b64:" prefixed)
+func (m MacMSOfficeModule) valueData(valueType string, v string) string {
+	if !strings.HasPrefix(v, "b64:") {
+		return v
+	}
+	switch valueType {
+	case "REG_SZ", "REG_EXPAND_SZ", "REG_MULTI_SZ":
+	default:
+		return v
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, "b64:"))
+	if err != nil || len(b)%2 != 0 {
+		return v
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+	}
+	// REG_MULTI_SZ strings are separated by NULs
+	return strings.Replace(strings.TrimRight(string(utf16.Decode(u)), "\x00"), "\x00", "; ", -1)
+}
+
+// extras returns the extras column of a row
+func (m MacMSOfficeModule) extras(extras map[string]interface{}) string {
+	if len(extras) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
+	}
+	return string(b)
+}
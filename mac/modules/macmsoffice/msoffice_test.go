@@ -0,0 +1,11 @@
+package macmsoffice
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacMSOfficeModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacMSOfficeModule{}, moduletest.Options{})
+}
@@ -1,38 +1,96 @@
-// +build darwin
-
 package macnetconfig
 
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/netconfig"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
 type MacNetconfigModule struct {
 }
 
+var (
+	bssHeader = []string{
+		"source_file",
+		"SSIDString",
+		"bssid",
+		"channel",
+		"last_associated_at",
+	}
+	netusageHeader = []string{
+		"source_file",
+		"process",
+		"bundle_name",
+		"first_seen",
+		"last_seen",
+		"wifi_in",
+		"wifi_out",
+		"wired_in",
+		"wired_out",
+		"wwan_in",
+		"wwan_out",
+	}
+	dhcpHeader = []string{
+		"source_file",
+		"interface",
+		"ip_address",
+		"lease_start",
+		"lease_end",
+		"router_ip",
+		"router_mac",
+		"ssid",
+	}
+	dnsHeader = []string{
+		"source_file",
+		"source_name",
+		"domain",
+		"line",
+		"directive",
+		"address",
+		"value",
+	}
+)
+
+// netusageQuery sums the usage of each process, NULLs are avoided as QueryDB does not handle them and byte counts are
+// stored as REAL in some versions
+const netusageQuery = `SELECT IFNULL(p.ZPROCNAME, '') AS process, %s AS bundle_name,
+	IFNULL(p.ZFIRSTTIMESTAMP, '') AS first_seen, IFNULL(p.ZTIMESTAMP, '') AS last_seen,
+	CAST(IFNULL(SUM(u.ZWIFIIN), 0) AS INTEGER) AS wifi_in, CAST(IFNULL(SUM(u.ZWIFIOUT), 0) AS INTEGER) AS wifi_out,
+	CAST(IFNULL(SUM(u.ZWIREDIN), 0) AS INTEGER) AS wired_in, CAST(IFNULL(SUM(u.ZWIREDOUT), 0) AS INTEGER) AS wired_out,
+	CAST(IFNULL(SUM(u.ZWWANIN), 0) AS INTEGER) AS wwan_in, CAST(IFNULL(SUM(u.ZWWANOUT), 0) AS INTEGER) AS wwan_out
+	FROM ZPROCESS p LEFT JOIN ZLIVEUSAGE u ON u.ZHASPROCESS = p.Z_PK
+	GROUP BY p.Z_PK ORDER BY p.Z_PK`
+
+var netusageQueryHeaders = []string{"process", "bundle_name", "first_seen", "last_seen", "wifi_in", "wifi_out", "wired_in", "wired_out", "wwan_in", "wwan_out"}
+
 var (
 	moduleName  = "MacNetconfigModule"
 	mode        = "mac"
 	version     = "1.0"
 	description = `
-	Reads and parses the network config plist
+	Reads and parses the network config plists, known Wi-Fi networks of com.apple.airport.preferences.plist and of
+	com.apple.wifi.known-networks.plist (11 and later) and the network interfaces
+	The access points each known network was associated with are written to MacNetconfigModule-bss, the bytes sent and
+	received by each process from netusage.sqlite to MacNetconfigModule-netusage, DHCP leases to MacNetconfigModule-dhcp
+	and the entries of /etc/hosts, resolv.conf and /etc/resolver to MacNetconfigModule-dns
 	`
 	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
 )
 
-var (
-	filepathAirportPreferencesPlist = "Library/Preferences/SystemConfiguration/com.apple.airport.preferences.plist"
-	filepathNetworkInterfacesPlist  = "Library/Preferences/SystemConfiguration/NetworkInterfaces.plist"
-)
-
 func (m MacNetconfigModule) Start(inst instance.Instance) error {
 	err := m.netconfig(inst)
 	if err != nil {
@@ -41,30 +99,44 @@ func (m MacNetconfigModule) Start(inst instance.Instance) error {
 	return err
 }
 
-func (m MacNetconfigModule) netconfig(inst instance.Instance) error {
-	header := []string{
-		"type",
-		"AddedAt",
-		"Captive",
-		"CaptiveBypass",
-		"Disabled",
-		"HiddenNetwork",
-		"LastAutoJoinAt",
-		"LastManualJoinAt",
-		"NetworkWasCaptive",
-		"Passpoint",
-		"PersonalHotspot",
-		"PossiblyHiddenNetwork",
-		"RoamingProfileType",
-		"SPRoaming",
-		"SSID",
-		"SSIDString",
-		"SecurityType",
-		"ShareMode",
-		"SystemMode",
-		"TemporarilyDisabled",
-		"UserRole",
+// Info returns the module description, version and tags shown by --list
+func (m MacNetconfigModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacNetconfigModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "AddedAt", Type: "Wi-Fi Network Added"},
+				{Field: "LastAutoJoinAt", Type: "Wi-Fi Network Auto Joined"},
+				{Field: "LastManualJoinAt", Type: "Wi-Fi Network Manually Joined"},
+				{Field: "UpdatedAt", Type: "Wi-Fi Network Updated"},
+			},
+			DescriptionFields: []string{"type", "SSIDString", "SecurityType"},
+		},
+		{
+			Output:            moduleName + "-bss",
+			Events:            []timeline.Event{{Field: "last_associated_at", Type: "Wi-Fi Access Point Associated"}},
+			DescriptionFields: []string{"SSIDString", "bssid"},
+		},
+		{
+			Output:            moduleName + "-netusage",
+			Events:            []timeline.Event{{Field: "first_seen", Type: "Process First Network Use"}, {Field: "last_seen", Type: "Process Last Network Use"}},
+			DescriptionFields: []string{"process", "bundle_name"},
+		},
+		{
+			Output:            moduleName + "-dhcp",
+			Events:            []timeline.Event{{Field: "lease_start", Type: "DHCP Lease Obtained"}},
+			DescriptionFields: []string{"interface", "ip_address", "ssid", "router_ip"},
+		},
 	}
+}
+
+func (m MacNetconfigModule) netconfig(inst instance.Instance) error {
+	header := append([]string{"type"}, netconfig.Fields...)
 	values := [][]string{}
 
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
@@ -76,18 +148,36 @@ func (m MacNetconfigModule) netconfig(inst instance.Instance) error {
 	// Read and parse Airport Interfaces
 	airportvalues, err := m.airport(inst)
 	if err != nil {
-		zap.L().Error("Failed to parse '" + filepathAirportPreferencesPlist + "': " + err.Error())
+		zap.L().Error("Failed to parse '" + netconfig.AirportPreferencesPlist + "': " + err.Error())
 	}
 	values = util.AppendToDoubleSlice(values, airportvalues)
 
 	// Read and parse Network Interfaces
 	networkinterfacevalues, err := m.networkinterface(inst)
 	if err != nil {
-		zap.L().Error("Failed to parse '" + filepathNetworkInterfacesPlist + "' " + err.Error())
+		zap.L().Error("Failed to parse '" + netconfig.NetworkInterfacesPlist + "' " + err.Error())
 	}
 	values = util.AppendToDoubleSlice(values, networkinterfacevalues)
 
+	// Read and parse the known networks of 11 and later
+	knownnetworkvalues, bssValues := m.knownNetworks(inst, header)
+	values = util.AppendToDoubleSlice(values, knownnetworkvalues)
+
+	netusageValues := m.netusage(inst)
+	dhcpValues := m.dhcp(inst)
+	dnsValues := m.dns(inst)
+
 	// Write to output
+	outputs := []struct {
+		name   string
+		header []string
+		values [][]string
+	}{
+		{moduleName + "-bss", bssHeader, bssValues},
+		{moduleName + "-netusage", netusageHeader, netusageValues},
+		{moduleName + "-dhcp", dhcpHeader, dhcpValues},
+		{moduleName + "-dns", dnsHeader, dnsValues},
+	}
 	err = mw.WriteHeader(header)
 	if err != nil {
 		return err
@@ -100,301 +190,216 @@ func (m MacNetconfigModule) netconfig(inst instance.Instance) error {
 	if err != nil {
 		return err
 	}
+	for _, output := range outputs {
+		w, err := datawriter.NewOrionWriter(output.name, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+		if err != nil {
+			zap.L().Error("Error running " + moduleName + ": " + err.Error())
+			return err
+		}
+		err = w.WriteHeader(output.header)
+		if err != nil {
+			return err
+		}
+		err = w.WriteAll(output.values)
+		if err != nil {
+			return err
+		}
+		err = w.Close()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (m MacNetconfigModule) networkinterface(inst instance.Instance) ([][]string, error) {
 	// Read and parse NetworkInterface data
-	networkInterfaceData, err := machelpers.DecodePlist(filepathNetworkInterfacesPlist, inst.GetTargetPath())
+	networkInterfaceData, err := machelpers.DecodePlist(netconfig.NetworkInterfacesPlist, inst.GetTargetPath())
 	if err != nil {
-		return [][]string{}, errors.New("failed to decode '" + filepathNetworkInterfacesPlist + "': " + err.Error())
+		return [][]string{}, errors.New("failed to decode '" + netconfig.NetworkInterfacesPlist + "': " + err.Error())
 	}
 
-	interfaces, err := machelpers.GetSingleValueInterfaceFromPlist(networkInterfaceData, "Interfaces") // Should be []map[string]interface{}
+	interfaces, err := netconfig.Interfaces(networkInterfaceData)
 	if err != nil {
-		return [][]string{}, errors.New("failed to decode 'Interfaces' key for '" + filepathNetworkInterfacesPlist + "': " + err.Error())
+		return [][]string{}, errors.New("failed to decode 'Interfaces' key for '" + netconfig.NetworkInterfacesPlist + "': " + err.Error())
 	}
 
-	// Parse entries from Interfaces
+	// Parse entries from Interfaces, fields interfaces do not have are N/A
 	networkinterfacecount := 0
 	values := [][]string{}
-
-	for _, entry := range interfaces.([]interface{}) {
-		// fmt.Println("Type: " + reflect.ValueOf(entry).String())
-		// machelpers.PrintPlistAsJSON(entry)
-		// panic("EXIT TEST")
-
-		// machelpers.PrintPlistAsJSON(entry) // For debuging values of Plist
-		// fmt.Println("Type: " + reflect.ValueOf(entry.(map[string]interface{})["AddedAt"]).String())
-		// fmt.Println(fmt.Sprint(entry.(map[string]interface{})["AddedAt"]))
-
-		var valmap = make(map[string]string)
-
-		valmap["AddedAt"] = "N/A"
-		valmap["Captive"] = "N/A"
-		valmap["CaptiveBypass"] = "N/A"
-		valmap["Disabled"] = "N/A"
-		valmap["HiddenNetwork"] = "N/A"
-		valmap["LastAutoJoinAt"] = "N/A"
-		valmap["LastManualJoinAt"] = "N/A"
-		valmap["NetworkWasCaptive"] = "N/A"
-		valmap["Passpoint"] = "N/A"
-		valmap["PersonalHotspot"] = "N/A"
-		valmap["PossiblyHiddenNetwork"] = "N/A"
-		valmap["RoamingProfileType"] = "N/A"
-		valmap["SPRoaming"] = "N/A"
-		valmap["SSID"] = "N/A"
-		valmap["SSIDString"] = "N/A"
-		valmap["SecurityType"] = "N/A"
-		valmap["ShareMode"] = "N/A"
-		valmap["SystemMode"] = "N/A"
-		valmap["TemporarilyDisabled"] = "N/A"
-		valmap["UserRole"] = "N/A"
-
-		// Use 'ok' syntax to check if map contains key or not
-		if addedAtVal, ok := entry.(map[string]interface{})["AddedAt"]; ok {
-			valmap["AddedAt"] = addedAtVal.(time.Time).UTC().Format(time.RFC3339)
-		}
-		if captiveVal, ok := entry.(map[string]interface{})["Captive"]; ok {
-			valmap["Captive"] = strconv.FormatBool(captiveVal.(bool))
-		}
-		if captiveBypassVal, ok := entry.(map[string]interface{})["CaptiveBypass"]; ok {
-			valmap["CaptiveBypass"] = strconv.FormatBool(captiveBypassVal.(bool))
-		}
-		if disabledVal, ok := entry.(map[string]interface{})["Disabled"]; ok {
-			valmap["Disabled"] = strconv.FormatBool(disabledVal.(bool))
-		}
-		if hiddenNetworkVal, ok := entry.(map[string]interface{})["HiddenNetwork"]; ok {
-			valmap["HiddenNetwork"] = strconv.FormatBool(hiddenNetworkVal.(bool))
-		}
-		if lastAutoJoinAtVal, ok := entry.(map[string]interface{})["LastAutoJoinAt"]; ok {
-			valmap["LastAutoJoinAt"] = lastAutoJoinAtVal.(time.Time).UTC().Format(time.RFC3339)
-		}
-		if lastManualJoinAtVal, ok := entry.(map[string]interface{})["LastManualJoinAt"]; ok {
-			valmap["LastManualJoinAt"] = lastManualJoinAtVal.(time.Time).UTC().Format(time.RFC3339)
-		}
-		if networkWasCaptiveVal, ok := entry.(map[string]interface{})["NetworkWasCaptive"]; ok {
-			valmap["NetworkWasCaptive"] = strconv.FormatBool(networkWasCaptiveVal.(bool))
-		}
-		if passpointVal, ok := entry.(map[string]interface{})["Passpoint"]; ok {
-			valmap["Passpoint"] = strconv.FormatBool(passpointVal.(bool))
-		}
-		if personalHotspotVal, ok := entry.(map[string]interface{})["PersonalHotspot"]; ok {
-			valmap["PersonalHotspot"] = strconv.FormatBool(personalHotspotVal.(bool))
-		}
-		if possiblyHiddenNetworkVal, ok := entry.(map[string]interface{})["PossiblyHiddenNetwork"]; ok {
-			valmap["PossiblyHiddenNetwork"] = strconv.FormatBool(possiblyHiddenNetworkVal.(bool))
-		}
-		if roamingProfileTypeVal, ok := entry.(map[string]interface{})["RoamingProfileType"]; ok {
-			valmap["RoamingProfileType"] = roamingProfileTypeVal.(string)
-		}
-		if spRoamingVal, ok := entry.(map[string]interface{})["SPRoaming"]; ok {
-			valmap["SPRoaming"] = strconv.FormatBool(spRoamingVal.(bool))
-		}
-		if ssidVal, ok := entry.(map[string]interface{})["SSID"]; ok {
-			valmap["SSID"] = fmt.Sprint(ssidVal)
-		}
-		if ssidStringVal, ok := entry.(map[string]interface{})["SSIDString"]; ok {
-			valmap["SSIDString"] = fmt.Sprint(ssidStringVal)
-		}
-		if securityTypeVal, ok := entry.(map[string]interface{})["SecurityType"]; ok {
-			valmap["SecurityType"] = fmt.Sprint(securityTypeVal)
-		}
-		if shareModeVal, ok := entry.(map[string]interface{})["ShareMode"]; ok {
-			valmap["ShareMode"] = strconv.FormatUint(shareModeVal.(uint64), 10)
-		}
-		if systemModeVal, ok := entry.(map[string]interface{})["SystemMode"]; ok {
-			valmap["SystemMode"] = strconv.FormatBool(systemModeVal.(bool))
-		}
-		if temporarilyDisabledVal, ok := entry.(map[string]interface{})["TemporarilyDisabled"]; ok {
-			valmap["TemporarilyDisabled"] = strconv.FormatBool(temporarilyDisabledVal.(bool))
-		}
-		if userRoleVal, ok := entry.(map[string]interface{})["UserRole"]; ok {
-			valmap["UserRole"] = strconv.FormatUint(userRoleVal.(uint64), 10)
-		}
-
-		if bsdName, ok := entry.(map[string]interface{})["BSD Name"]; ok {
-			valmap["BSDName"] = fmt.Sprint(bsdName)
-		}
-
-		// TODO Fix names
-		/*
-			"Active": true,
-			"BSD Name": "en0",
-			"IOBuiltin": true,
-			"IOInterfaceNamePrefix": "en",
-			"IOInterfaceType": 6,
-			"IOInterfaceUnit": 0,
-			"IOMACAddress": "pIPnHx6z",
-			"IOPathMatch": "IOService:/AppleACPIPlatformExpert/PCI0@0/AppleACPIPCI/RP01@1C/IOPP/ARPT@0/AppleBCMWLANBusInterfacePCIe/AppleBCMWLANCore/en0",
-			"SCNetworkInterfaceInfo": {
-				"UserDefinedName": "Wi-Fi"
-			},
-			"SCNetworkInterfaceType": "IEEE80211"
-		*/
-
-		networkInterfacesEntry := []string{
-			valmap["BSDName"],
-			valmap["AddedAt"],
-			valmap["Captive"],
-			valmap["CaptiveBypass"],
-			valmap["Disabled"],
-			valmap["HiddenNetwork"],
-			valmap["LastAutoJoinAt"],
-			valmap["LastManualJoinAt"],
-			valmap["NetworkWasCaptive"],
-			valmap["Passpoint"],
-			valmap["PersonalHotspot"],
-			valmap["PossiblyHiddenNetwork"],
-			valmap["RoamingProfileType"],
-			valmap["SPRoaming"],
-			valmap["SSID"],
-			valmap["SSIDString"],
-			valmap["SecurityType"],
-			valmap["ShareMode"],
-			valmap["SystemMode"],
-			valmap["TemporarilyDisabled"],
-			valmap["UserRole"],
+	for _, iface := range interfaces {
+		networkInterfacesEntry := []string{iface.BSDName}
+		for _, field := range netconfig.Fields {
+			val, ok := iface.Values[field]
+			if !ok {
+				val = "N/A"
+			}
+			networkInterfacesEntry = append(networkInterfacesEntry, val)
 		}
 		values = append(values, networkInterfacesEntry)
 		networkinterfacecount++
 	}
 
-	//
 	zap.L().Debug(fmt.Sprintf("Parsed [%d] networkinterface entries", networkinterfacecount), zap.String("module", moduleName))
 	return values, nil
 }
 
 func (m MacNetconfigModule) airport(inst instance.Instance) ([][]string, error) {
 	// Read and parse Airport data
-	airportData, err := machelpers.DecodePlist(filepathAirportPreferencesPlist, inst.GetTargetPath())
+	airportData, err := machelpers.DecodePlist(netconfig.AirportPreferencesPlist, inst.GetTargetPath())
 	if err != nil {
-		return [][]string{}, errors.New("failed to decode '" + filepathAirportPreferencesPlist + "': " + err.Error())
+		return [][]string{}, errors.New("failed to decode '" + netconfig.AirportPreferencesPlist + "': " + err.Error())
 	}
 
-	knownNetworks, err := machelpers.GetSingleValueInterfaceFromPlist(airportData, "KnownNetworks") // KnownNetworks is map[string]interface{}
+	knownNetworks, err := netconfig.KnownNetworks(airportData)
 	if err != nil {
-		return [][]string{}, errors.New("failed to decode 'KnownNetworks' key for '" + filepathAirportPreferencesPlist + "': " + err.Error())
+		return [][]string{}, errors.New("failed to decode 'KnownNetworks' key for '" + netconfig.AirportPreferencesPlist + "': " + err.Error())
 	}
 
 	// Parse entries from KnownNetworks
 	airportcount := 0
 	values := [][]string{}
-	for _, entry := range knownNetworks.(map[string]interface{}) { // knownNetworks should be map[string]interface{}
-		// machelpers.PrintPlistAsJSON(entry) // For debuging values of Plist
-		// fmt.Println("Type: " + reflect.ValueOf(entry.(map[string]interface{})["AddedAt"]).String())
-		// fmt.Println(fmt.Sprint(entry.(map[string]interface{})["AddedAt"]))
-
-		var valmap = make(map[string]string) // valmap stores values that will be placed in output entry
-		valmap["AddedAt"] = ""
-		valmap["Captive"] = ""
-		valmap["CaptiveBypass"] = ""
-		valmap["Disabled"] = ""
-		valmap["HiddenNetwork"] = ""
-		valmap["LastAutoJoinAt"] = ""
-		valmap["LastManualJoinAt"] = ""
-		valmap["NetworkWasCaptive"] = ""
-		valmap["Passpoint"] = ""
-		valmap["PersonalHotspot"] = ""
-		valmap["PossiblyHiddenNetwork"] = ""
-		valmap["RoamingProfileType"] = ""
-		valmap["SPRoaming"] = ""
-		valmap["SSID"] = ""
-		valmap["SSIDString"] = ""
-		valmap["SecurityType"] = ""
-		valmap["ShareMode"] = ""
-		valmap["SystemMode"] = ""
-		valmap["TemporarilyDisabled"] = ""
-		valmap["UserRole"] = ""
-
-		// Use 'ok' syntax to check if map contains key or not
-		if addedAtVal, ok := entry.(map[string]interface{})["AddedAt"]; ok {
-			valmap["AddedAt"] = addedAtVal.(time.Time).UTC().Format(time.RFC3339)
-		}
-		if captiveVal, ok := entry.(map[string]interface{})["Captive"]; ok {
-			valmap["Captive"] = strconv.FormatBool(captiveVal.(bool))
-		}
-		if captiveBypassVal, ok := entry.(map[string]interface{})["CaptiveBypass"]; ok {
-			valmap["CaptiveBypass"] = strconv.FormatBool(captiveBypassVal.(bool))
+	for _, network := range knownNetworks {
+		airportEntry := []string{"Airport"}
+		for _, field := range netconfig.Fields {
+			airportEntry = append(airportEntry, network.Values[field])
 		}
-		if disabledVal, ok := entry.(map[string]interface{})["Disabled"]; ok {
-			valmap["Disabled"] = strconv.FormatBool(disabledVal.(bool))
-		}
-		if hiddenNetworkVal, ok := entry.(map[string]interface{})["HiddenNetwork"]; ok {
-			valmap["HiddenNetwork"] = strconv.FormatBool(hiddenNetworkVal.(bool))
-		}
-		if lastAutoJoinAtVal, ok := entry.(map[string]interface{})["LastAutoJoinAt"]; ok {
-			valmap["LastAutoJoinAt"] = lastAutoJoinAtVal.(time.Time).UTC().Format(time.RFC3339)
-		}
-		if lastManualJoinAtVal, ok := entry.(map[string]interface{})["LastManualJoinAt"]; ok {
-			valmap["LastManualJoinAt"] = lastManualJoinAtVal.(time.Time).UTC().Format(time.RFC3339)
-		}
-		if networkWasCaptiveVal, ok := entry.(map[string]interface{})["NetworkWasCaptive"]; ok {
-			valmap["NetworkWasCaptive"] = strconv.FormatBool(networkWasCaptiveVal.(bool))
-		}
-		if passpointVal, ok := entry.(map[string]interface{})["Passpoint"]; ok {
-			valmap["Passpoint"] = strconv.FormatBool(passpointVal.(bool))
-		}
-		if personalHotspotVal, ok := entry.(map[string]interface{})["PersonalHotspot"]; ok {
-			valmap["PersonalHotspot"] = strconv.FormatBool(personalHotspotVal.(bool))
-		}
-		if possiblyHiddenNetworkVal, ok := entry.(map[string]interface{})["PossiblyHiddenNetwork"]; ok {
-			valmap["PossiblyHiddenNetwork"] = strconv.FormatBool(possiblyHiddenNetworkVal.(bool))
-		}
-		if roamingProfileTypeVal, ok := entry.(map[string]interface{})["RoamingProfileType"]; ok {
-			valmap["RoamingProfileType"] = roamingProfileTypeVal.(string)
-		}
-		if spRoamingVal, ok := entry.(map[string]interface{})["SPRoaming"]; ok {
-			valmap["SPRoaming"] = strconv.FormatBool(spRoamingVal.(bool))
-		}
-		if ssidVal, ok := entry.(map[string]interface{})["SSID"]; ok {
-			valmap["SSID"] = fmt.Sprint(ssidVal)
-		}
-		if ssidStringVal, ok := entry.(map[string]interface{})["SSIDString"]; ok {
-			valmap["SSIDString"] = fmt.Sprint(ssidStringVal)
+		values = append(values, airportEntry)
+		airportcount++
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] airport entries", airportcount), zap.String("module", moduleName))
+	return values, nil
+}
+
+// knownNetworks returns the rows of com.apple.wifi.known-networks.plist in the layout of header and the access points
+// of each network
+func (m MacNetconfigModule) knownNetworks(inst instance.Instance, header []string) ([][]string, [][]string) {
+	values := [][]string{}
+	bssValues := [][]string{}
+	for _, path := range inst.GetArtifactPaths("MacOSWiFiKnownNetworks") {
+		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
+		if err != nil {
+			zap.L().Error("Failed to parse '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, network := range netconfig.WiFiKnownNetworks(data) {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			for k, v := range network.Values {
+				valmap[k] = v
+			}
+			valmap["type"] = "WiFiKnownNetworks"
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+			for _, bss := range network.BSS {
+				bssValues = append(bssValues, []string{path, network.Values["SSIDString"], bss.BSSID, bss.Channel, bss.LastAssociatedAt})
+			}
 		}
-		if securityTypeVal, ok := entry.(map[string]interface{})["SecurityType"]; ok {
-			valmap["SecurityType"] = fmt.Sprint(securityTypeVal)
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] known networks with [%d] access points", len(values), len(bssValues)), zap.String("module", moduleName))
+	return values, bssValues
+}
+
+// netusage returns the network usage of each process of netusage.sqlite
+func (m MacNetconfigModule) netusage(inst instance.Instance) [][]string {
+	values := [][]string{}
+	for _, dbpath := range inst.GetArtifactPaths("MacOSNetUsage") {
+		// the bundle name of a process is missing from older versions
+		bundle := "''"
+		if columns, err := util.DBColumnNames(dbpath, "ZPROCESS"); err == nil && util.SliceContainsString(columns, "ZBUNDLENAME") {
+			bundle = "IFNULL(p.ZBUNDLENAME, '')"
+		}
+		entries, err := util.QueryDB(dbpath, fmt.Sprintf(netusageQuery, bundle), netusageQueryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, e := range entries {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, netusageHeader)
+			for i, h := range netusageQueryHeaders {
+				valmap[h] = e[i]
+			}
+			valmap["source_file"] = dbpath
+			for _, k := range []string{"first_seen", "last_seen"} {
+				raw := valmap[k]
+				valmap[k], err = timeconv.ConvertToString(timeconv.Cocoa, raw)
+				if err != nil {
+					valmap[k] = raw + "<FAILED TO CONVERT>"
+				}
+			}
+			entry, err := util.UnsafeEntryFromMap(valmap, netusageHeader)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
 		}
-		if shareModeVal, ok := entry.(map[string]interface{})["ShareMode"]; ok {
-			valmap["ShareMode"] = strconv.FormatUint(shareModeVal.(uint64), 10)
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] netusage processes", len(values)), zap.String("module", moduleName))
+	return values
+}
+
+// dhcp returns the DHCP leases, which are named after their interface followed by the client ID in later versions
+func (m MacNetconfigModule) dhcp(inst instance.Instance) [][]string {
+	values := [][]string{}
+	for _, path := range inst.GetArtifactPaths("MacOSDHCPLeases") {
+		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
+		if err != nil {
+			zap.L().Error("Failed to parse '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		lease, err := netconfig.DHCPLease(data)
+		if err != nil {
+			zap.L().Error("Failed to parse '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		iface := strings.TrimSuffix(filepath.Base(path), ".plist")
+		if i := strings.Index(iface, "-"); i >= 0 {
+			iface = iface[:i]
+		}
+		leaseEnd := ""
+		if !lease.Start.IsZero() && lease.Length > 0 {
+			leaseEnd = timeconv.Format(lease.Start.Add(time.Duration(lease.Length) * time.Second))
+		}
+		values = append(values, []string{path, iface, lease.IPAddress, timeconv.Format(lease.Start), leaseEnd, lease.RouterIP, lease.RouterMAC, lease.SSID})
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] DHCP leases", len(values)), zap.String("module", moduleName))
+	return values
+}
+
+// dns returns the entries of the hosts file and the directives of the resolver configuration files, the files of
+// /etc/resolver are named after the domain they configure
+func (m MacNetconfigModule) dns(inst instance.Instance) [][]string {
+	values := [][]string{}
+	for _, path := range inst.GetArtifactPaths("MacOSHostsFile") {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			zap.L().Error("could not read '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
 		}
-		if systemModeVal, ok := entry.(map[string]interface{})["SystemMode"]; ok {
-			valmap["SystemMode"] = strconv.FormatBool(systemModeVal.(bool))
+		for _, host := range netconfig.ParseHosts(b) {
+			values = append(values, []string{path, "hosts", "", strconv.Itoa(host.Line), "", host.Address, strings.Join(host.Names, " ")})
 		}
-		if temporarilyDisabledVal, ok := entry.(map[string]interface{})["TemporarilyDisabled"]; ok {
-			valmap["TemporarilyDisabled"] = strconv.FormatBool(temporarilyDisabledVal.(bool))
+	}
+	for _, path := range inst.GetArtifactPaths("MacOSResolverConfigs") {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			zap.L().Error("could not read '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
 		}
-		if userRoleVal, ok := entry.(map[string]interface{})["UserRole"]; ok {
-			valmap["UserRole"] = strconv.FormatUint(userRoleVal.(uint64), 10)
+		sourceName, domain := "resolv.conf", ""
+		if filepath.Base(filepath.Dir(path)) == "resolver" {
+			sourceName, domain = "resolver", filepath.Base(path)
 		}
-
-		airportEntry := []string{
-			"Airport",
-			valmap["AddedAt"],
-			valmap["Captive"],
-			valmap["CaptiveBypass"],
-			valmap["Disabled"],
-			valmap["HiddenNetwork"],
-			valmap["LastAutoJoinAt"],
-			valmap["LastManualJoinAt"],
-			valmap["NetworkWasCaptive"],
-			valmap["Passpoint"],
-			valmap["PersonalHotspot"],
-			valmap["PossiblyHiddenNetwork"],
-			valmap["RoamingProfileType"],
-			valmap["SPRoaming"],
-			valmap["SSID"],
-			valmap["SSIDString"],
-			valmap["SecurityType"],
-			valmap["ShareMode"],
-			valmap["SystemMode"],
-			valmap["TemporarilyDisabled"],
-			valmap["UserRole"],
+		for _, d := range netconfig.ParseResolver(b) {
+			values = append(values, []string{path, sourceName, domain, strconv.Itoa(d.Line), d.Name, "", d.Value})
 		}
-		values = append(values, airportEntry)
-		airportcount++
 	}
-	zap.L().Debug(fmt.Sprintf("Parsed [%d] airport entries", airportcount), zap.String("module", moduleName))
-	return values, nil
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] hosts and resolver entries", len(values)), zap.String("module", moduleName))
+	return values
 }
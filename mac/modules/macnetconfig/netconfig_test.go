@@ -0,0 +1,11 @@
+package macnetconfig
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacNetconfigModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacNetconfigModule{}, moduletest.Options{})
+}
@@ -0,0 +1,206 @@
+package macnotes
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/notes"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacNotesModule wraps Module methods
+type MacNotesModule struct{}
+
+var (
+	moduleName  = "MacNotesModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the Notes database (NoteStore.sqlite) of each user, notes of 10.11 and later
+	Lists every note with its folder, dates and text decoded from the gzipped protobuf body, bodies of password protected
+	notes are encrypted and left empty. Notes in Recently Deleted are listed with that folder
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"note_id",
+		"identifier",
+		"title",
+		"folder",
+		"created",
+		"modified",
+		"password_protected",
+		"snippet",
+		"text",
+	}
+)
+
+// table holds notes, folders and accounts, the numbered columns of an entity depend on the version of Notes
+const table = "ZICCLOUDSYNCINGOBJECT"
+
+var (
+	queryHeaders = []string{"note_id", "identifier", "title", "folder", "created", "modified", "password_protected", "snippet", "data"}
+
+	// candidate columns, the first present in the table is used
+	titleColumns    = []string{"ZTITLE1", "ZTITLE"}
+	createdColumns  = []string{"ZCREATIONDATE3", "ZCREATIONDATE1", "ZCREATIONDATE"}
+	modifiedColumns = []string{"ZMODIFICATIONDATE1", "ZMODIFICATIONDATE"}
+	folderColumns   = []string{"ZTITLE2", "ZTITLE"}
+)
+
+// Start starts the MacNotesModule, should not be manually called
+func (m MacNotesModule) Start(inst instance.Instance) error {
+	err := m.notes(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacNotesModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacNotesModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "created", Type: "Note Created"}, {Field: "modified", Type: "Note Modified"}},
+			UserField:         "user",
+			DescriptionFields: []string{"folder", "title"},
+		},
+	}
+}
+
+func (m MacNotesModule) notes(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSNotes")
+	if len(paths) == 0 {
+		zap.L().Warn("No Notes databases were found", zap.String("module", moduleName))
+	}
+
+	for _, dbpath := range paths {
+		query, err := m.query(dbpath)
+		if err != nil {
+			zap.L().Error("could not read '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		entries, err := util.QueryDB(dbpath, query, queryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, e := range entries {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			for i, h := range queryHeaders {
+				valmap[h] = e[i]
+			}
+			valmap["source_file"] = dbpath
+			valmap["user"] = util.GetUsernameFromPath(dbpath)
+			for _, k := range []string{"created", "modified"} {
+				raw := valmap[k]
+				valmap[k], err = timeconv.ConvertToString(timeconv.Cocoa, raw)
+				if err != nil {
+					valmap[k] = raw + "<FAILED TO CONVERT>"
+				}
+			}
+			if valmap["password_protected"] == "1" {
+				valmap["password_protected"] = "true"
+			} else {
+				valmap["password_protected"] = "false"
+				valmap["text"] = m.text(valmap["data"], dbpath, valmap["note_id"])
+			}
+
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] notes from %d databases", len(values), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// query returns the notes query for the columns of the database, NULLs are avoided as QueryDB does not handle them
+func (m MacNotesModule) query(dbpath string) (string, error) {
+	names, err := util.DBColumnNames(dbpath, table)
+	if err != nil {
+		return "", err
+	}
+	columns := map[string]bool{}
+	for _, name := range names {
+		columns[strings.ToUpper(name)] = true
+	}
+	column := func(alias string, candidates []string) string {
+		for _, c := range candidates {
+			if columns[c] {
+				return alias + "." + c
+			}
+		}
+		return "NULL"
+	}
+	protected := "0"
+	if columns["ZISPASSWORDPROTECTED"] {
+		protected = "n.ZISPASSWORDPROTECTED"
+	}
+	return fmt.Sprintf(`SELECT n.Z_PK AS note_id, IFNULL(n.ZIDENTIFIER, '') AS identifier, IFNULL(%s, '') AS title,
+	IFNULL(%s, '') AS folder, IFNULL(%s, '') AS created, IFNULL(%s, '') AS modified, IFNULL(%s, 0) AS password_protected,
+	IFNULL(n.ZSNIPPET, '') AS snippet, IFNULL(d.ZDATA, '') AS data
+	FROM ZICNOTEDATA d
+	JOIN %s n ON n.Z_PK = d.ZNOTE
+	LEFT JOIN %s f ON f.Z_PK = n.ZFOLDER
+	ORDER BY n.Z_PK`,
+		column("n", titleColumns), column("f", folderColumns), column("n", createdColumns), column("n", modifiedColumns),
+		protected, table, table), nil
+}
+
+// text returns the text of a note body as returned by QueryDB ("b64:" prefixed)
+func (m MacNotesModule) text(v string, dbpath string, noteID string) string {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, "b64:"))
+	if err != nil || len(b) == 0 {
+		return ""
+	}
+	text, err := notes.NoteText(b)
+	if err != nil {
+		zap.L().Debug(fmt.Sprintf("could not decode body of note %s of '%s': %s", noteID, dbpath, err.Error()), zap.String("module", moduleName))
+		return ""
+	}
+	return text
+}
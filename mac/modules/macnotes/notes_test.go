@@ -0,0 +1,11 @@
+package macnotes
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacNotesModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacNotesModule{}, moduletest.Options{})
+}
@@ -0,0 +1,175 @@
+package macnotifications
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/notifications"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacNotificationsModule wraps Module methods
+type MacNotificationsModule struct{}
+
+var (
+	moduleName  = "MacNotificationsModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the Notification Center database (db2/db) of 10.13 and later
+	Lists every notification still in the database with the app that posted it, its title, subtitle and body and the
+	date it was delivered, message previews and 2FA codes are often only found here
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"rec_id",
+		"uuid",
+		"app",
+		"title",
+		"subtitle",
+		"body",
+		"category",
+		"delivered_date",
+		"presented",
+	}
+)
+
+// query avoids NULLs as QueryDB does not handle them
+const query = `SELECT r.rec_id AS rec_id, IFNULL(r.uuid, '') AS uuid, IFNULL(a.identifier, '') AS app,
+	IFNULL(r.data, '') AS data, IFNULL(r.delivered_date, '') AS delivered_date, IFNULL(r.presented, '') AS presented
+	FROM record r LEFT JOIN app a ON a.app_id = r.app_id ORDER BY r.rec_id`
+
+var queryHeaders = []string{"rec_id", "uuid", "app", "data", "delivered_date", "presented"}
+
+// Start starts the MacNotificationsModule, should not be manually called
+func (m MacNotificationsModule) Start(inst instance.Instance) error {
+	err := m.notifications(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacNotificationsModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacNotificationsModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "delivered_date", Type: "Notification Delivered"}},
+			UserField:         "user",
+			DescriptionFields: []string{"app", "title", "body"},
+		},
+	}
+}
+
+func (m MacNotificationsModule) notifications(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSNotificationCenterDB")
+	if len(paths) == 0 {
+		zap.L().Warn("No Notification Center databases were found", zap.String("module", moduleName))
+	}
+
+	for _, dbpath := range paths {
+		entries, err := util.QueryDB(dbpath, query, queryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		// the database of 15 and later is in the home directory, earlier ones are under the per user temporary folder
+		user := ""
+		if strings.Contains(dbpath, "/Users/") {
+			user = util.GetUsernameFromPath(dbpath)
+		}
+		for _, e := range entries {
+			row := make(map[string]string)
+			for i, h := range queryHeaders {
+				row[h] = e[i]
+			}
+
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			valmap["source_file"] = dbpath
+			valmap["user"] = user
+			valmap["rec_id"] = row["rec_id"]
+			valmap["uuid"] = m.uuid(row["uuid"])
+			valmap["app"] = row["app"]
+			valmap["presented"] = row["presented"]
+			valmap["delivered_date"], err = timeconv.ConvertToString(timeconv.Cocoa, row["delivered_date"])
+			if err != nil {
+				valmap["delivered_date"] = row["delivered_date"] + "<FAILED TO CONVERT>"
+			}
+
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(row["data"], "b64:"))
+			if err == nil && len(b) > 0 {
+				n, err := notifications.ParseRecord(b)
+				if err != nil {
+					zap.L().Debug(fmt.Sprintf("could not decode record %s of '%s': %s", row["rec_id"], dbpath, err.Error()), zap.String("module", moduleName))
+				} else {
+					if valmap["app"] == "" {
+						valmap["app"] = n.App
+					}
+					valmap["title"] = n.Title
+					valmap["subtitle"] = n.Subtitle
+					valmap["body"] = n.Body
+					valmap["category"] = n.Category
+				}
+			}
+
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] notifications from %d databases", len(values), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// uuid formats the 16 bytes uuid of a record as returned by QueryDB ("b64:" prefixed)
+func (m MacNotificationsModule) uuid(v string) string {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, "b64:"))
+	if err != nil || len(b) != 16 {
+		return v
+	}
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
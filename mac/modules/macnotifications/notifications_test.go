@@ -0,0 +1,11 @@
+package macnotifications
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacNotificationsModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacNotificationsModule{}, moduletest.Options{})
+}
@@ -0,0 +1,476 @@
+package macperipherals
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacPeripheralsModule wraps Module methods
+type MacPeripheralsModule struct{}
+
+var (
+	moduleName  = "MacPeripheralsModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Lists the peripherals and external volumes seen by the Mac
+	Bluetooth devices known to the system and whether they are paired (com.apple.Bluetooth.plist, and
+	com.apple.MobileBluetooth.devices.plist in 12 and later), volumes mounted on the desktop of each user with their
+	creation date (FXDesktopVolumePositions of com.apple.finder.plist) and USB mass storage devices with their serial
+	from the USBMSC kernel messages of system.log (10.11 and earlier, later versions only log them to the unified log)
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"source_name",
+		"device_type",
+		"name",
+		"address",
+		"serial_number",
+		"vendor_id",
+		"product_id",
+		"paired",
+		"first_seen",
+		"last_seen",
+		"volume_created",
+		"extras",
+	}
+)
+
+// bluetoothDates are the dates of a DeviceCache entry of com.apple.Bluetooth.plist, the latest is when the device was
+// last seen
+var bluetoothDates = map[string]string{
+	"LastInquiryUpdate":  "last_inquiry_update",
+	"LastNameUpdate":     "last_name_update",
+	"LastServicesUpdate": "last_services_update",
+}
+
+// usbmsc matches the kernel message logged when a USB mass storage device is attached, with its serial, vendor ID,
+// product ID and revision
+var usbmsc = regexp.MustCompile(`(?m)^([A-Z][a-z]{2} [ 0-9]\d \d\d:\d\d:\d\d) \S+ kernel\[0\]: USBMSC Identifier \(non-unique\): (\S+) (0x[0-9a-fA-F]+) (0x[0-9a-fA-F]+) (0x[0-9a-fA-F]+)`)
+
+// Start starts the MacPeripheralsModule, should not be manually called
+func (m MacPeripheralsModule) Start(inst instance.Instance) error {
+	err := m.peripherals(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacPeripheralsModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacPeripheralsModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "first_seen", Type: "Peripheral First Seen"},
+				{Field: "last_seen", Type: "Peripheral Last Seen"},
+				{Field: "volume_created", Type: "Volume Created"},
+			},
+			UserField:         "user",
+			DescriptionFields: []string{"device_type", "name", "address", "serial_number"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacPeripheralsModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_file", "device_type", "name", "address", "serial_number", "volume_created"}},
+	}
+}
+
+func (m MacPeripheralsModule) peripherals(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	for _, source := range []struct {
+		artifact string
+		parse    func(path string, targetPath string) ([]map[string]string, error)
+	}{
+		{"MacOSBluetoothPreferences", m.bluetooth},
+		{"MacOSMobileBluetoothDevices", m.mobileBluetooth},
+		{"MacOSFinderPreferences", m.desktopVolumes},
+		{"MacOSSystemLogs", m.usbLog},
+	} {
+		paths := inst.GetArtifactPaths(source.artifact)
+		if len(paths) == 0 {
+			zap.L().Debug(fmt.Sprintf("files not found in: '%s'.", inst.GetArtifactGlobs(source.artifact)), zap.String("module", moduleName))
+		}
+		for _, path := range paths {
+			rows, err := source.parse(path, inst.GetTargetPath())
+			if err != nil {
+				zap.L().Error("could not parse '"+path+"': "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			for _, valmap := range rows {
+				entry, err := util.UnsafeEntryFromMap(valmap, header)
+				if err != nil {
+					zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+					continue
+				}
+				values = append(values, entry)
+			}
+		}
+	}
+	if len(values) == 0 {
+		zap.L().Warn("No peripherals were found", zap.String("module", moduleName))
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] peripherals", len(values)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// newValmap returns a row of path, the user is only set for files in a home directory
+func (m MacPeripheralsModule) newValmap(path, sourceName, deviceType string) map[string]string {
+	var valmap = make(map[string]string)
+	valmap = util.InitializeMapToEmptyString(valmap, header)
+	valmap["source_file"] = path
+	if strings.Contains(path, "/Users/") {
+		valmap["user"] = util.GetUsernameFromPath(path)
+	}
+	valmap["source_name"] = sourceName
+	valmap["device_type"] = deviceType
+	return valmap
+}
+
+// bluetooth returns a row for every device of the DeviceCache of com.apple.Bluetooth.plist, paired devices are listed
+// in PairedDevices and may be missing from the cache
+func (m MacPeripheralsModule) bluetooth(path string, targetPath string) ([]map[string]string, error) {
+	data, err := machelpers.DecodePlist(path, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]string{}
+	for _, item := range data {
+		paired := map[string]bool{}
+		if addresses, ok := item["PairedDevices"].([]interface{}); ok {
+			for _, a := range addresses {
+				if s, ok := a.(string); ok {
+					paired[m.address(s)] = true
+				}
+			}
+		}
+		// devices are keyed by normalized address, PairedDevices and DeviceCache do not always format them alike
+		cache := map[string]map[string]interface{}{}
+		if deviceCache, ok := item["DeviceCache"].(map[string]interface{}); ok {
+			for a, d := range deviceCache {
+				if device, ok := d.(map[string]interface{}); ok {
+					cache[m.address(a)] = device
+				}
+			}
+		}
+		addresses := []string{}
+		for a := range cache {
+			addresses = append(addresses, a)
+		}
+		for a := range paired {
+			if _, ok := cache[a]; !ok {
+				addresses = append(addresses, a)
+			}
+		}
+		sort.Strings(addresses)
+		for _, address := range addresses {
+			valmap := m.newValmap(path, "Bluetooth", "bluetooth")
+			valmap["address"] = address
+			valmap["paired"] = strconv.FormatBool(paired[address])
+			extras := map[string]interface{}{}
+			if device, ok := cache[address]; ok {
+				valmap["name"], _ = device["Name"].(string)
+				if valmap["name"] == "" {
+					valmap["name"], _ = device["displayName"].(string)
+				}
+				valmap["vendor_id"] = m.id(device["VendorID"])
+				valmap["product_id"] = m.id(device["ProductID"])
+				var last time.Time
+				for key, name := range bluetoothDates {
+					if t, ok := device[key].(time.Time); ok {
+						extras[name] = timeconv.Format(t)
+						if t.After(last) {
+							last = t
+						}
+					}
+				}
+				valmap["last_seen"] = timeconv.Format(last)
+				if cod, ok := device["ClassOfDevice"].(uint64); ok {
+					extras["class_of_device"] = fmt.Sprintf("0x%06x", cod)
+				}
+				if manufacturer, ok := device["Manufacturer"].(uint64); ok {
+					extras["manufacturer"] = manufacturer
+				}
+			}
+			valmap["extras"] = m.extras(extras)
+			rows = append(rows, valmap)
+		}
+	}
+	return rows, nil
+}
+
+// mobileBluetooth returns a row for every device of com.apple.MobileBluetooth.devices.plist, the paired devices of 12
+// and later keyed by address, LastSeenTime is Unix seconds
+func (m MacPeripheralsModule) mobileBluetooth(path string, targetPath string) ([]map[string]string, error) {
+	data, err := machelpers.DecodePlist(path, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]string{}
+	for _, item := range data {
+		addresses := []string{}
+		for a := range item {
+			addresses = append(addresses, a)
+		}
+		sort.Strings(addresses)
+		for _, a := range addresses {
+			device, ok := item[a].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			valmap := m.newValmap(path, "MobileBluetooth", "bluetooth")
+			valmap["address"] = m.address(a)
+			valmap["paired"] = "true"
+			valmap["name"], _ = device["Name"].(string)
+			if valmap["name"] == "" {
+				valmap["name"], _ = device["DefaultName"].(string)
+			}
+			valmap["vendor_id"] = m.id(device["VID"])
+			valmap["product_id"] = m.id(device["PID"])
+			if seconds, ok := device["LastSeenTime"].(uint64); ok && seconds > 0 {
+				valmap["last_seen"] = timeconv.Format(time.Unix(int64(seconds), 0))
+			}
+			extras := map[string]interface{}{}
+			if name, ok := device["DefaultName"].(string); ok && name != valmap["name"] {
+				extras["default_name"] = name
+			}
+			valmap["extras"] = m.extras(extras)
+			rows = append(rows, valmap)
+		}
+	}
+	return rows, nil
+}
+
+// desktopVolumes returns a row for every volume of FXDesktopVolumePositions of com.apple.finder.plist, which the Finder
+// keys by the volume name and its creation date in Cocoa seconds as a hex float ("0x1.b3c5a8p+29") or, in older
+// versions, as the bits of a double ("0x41c..."), the position of the icon is kept in extras
+func (m MacPeripheralsModule) desktopVolumes(path string, targetPath string) ([]map[string]string, error) {
+	data, err := machelpers.DecodePlist(path, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]string{}
+	for _, item := range data {
+		volumes, ok := item["FXDesktopVolumePositions"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keys := []string{}
+		for k := range volumes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			valmap := m.newValmap(path, "FXDesktopVolumePositions", "volume")
+			valmap["name"] = k
+			if i := strings.LastIndex(k, "_0x"); i >= 0 {
+				if created, ok := m.volumeCreated(k[i+1:]); ok {
+					valmap["name"] = k[:i]
+					valmap["volume_created"] = timeconv.Format(created)
+				}
+			}
+			extras := map[string]interface{}{"key": k}
+			if position, ok := volumes[k].(map[string]interface{}); ok {
+				for _, key := range []string{"AnchorRelativeTo", "ScreenID", "xRelative", "yRelative"} {
+					if v, ok := position[key]; ok {
+						extras[key] = v
+					}
+				}
+			}
+			valmap["extras"] = m.extras(extras)
+			rows = append(rows, valmap)
+		}
+	}
+	return rows, nil
+}
+
+// volumeCreated decodes the creation date suffix of a FXDesktopVolumePositions key
+func (m MacPeripheralsModule) volumeCreated(s string) (time.Time, bool) {
+	var seconds float64
+	if strings.Contains(s, "p") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = f
+	} else {
+		bits, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+		if err != nil || len(s) != 18 {
+			return time.Time{}, false
+		}
+		seconds = math.Float64frombits(bits)
+	}
+	t, err := timeconv.Convert(timeconv.Cocoa, seconds)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// usbLog returns a row for every USB mass storage device of a system.log, with the first and last time it was
+// attached. Lines of system.log have no year, it is taken from the modification time of the file and a line dated
+// after it is of the year before. Lines are in the local time of the Mac, which is not recorded, and are reported as UTC
+func (m MacPeripheralsModule) usbLog(path string, targetPath string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	modified := stat.ModTime().UTC()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	type device struct {
+		serial, vendorID, productID, revision string
+		first, last                           time.Time
+		count                                 int
+	}
+	devices := map[string]*device{}
+	order := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		match := usbmsc.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		t, err := time.Parse("Jan _2 15:04:05", match[1])
+		if err != nil {
+			continue
+		}
+		t = t.AddDate(modified.Year(), 0, 0)
+		if t.After(modified.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		key := strings.Join(match[2:5], " ")
+		d, ok := devices[key]
+		if !ok {
+			d = &device{serial: match[2], vendorID: m.hexID(match[3]), productID: m.hexID(match[4]), revision: match[5], first: t, last: t}
+			devices[key] = d
+			order = append(order, key)
+		}
+		if t.Before(d.first) {
+			d.first = t
+		}
+		if t.After(d.last) {
+			d.last = t
+		}
+		d.count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	rows := []map[string]string{}
+	for _, key := range order {
+		d := devices[key]
+		valmap := m.newValmap(path, "system.log", "usb")
+		valmap["serial_number"] = d.serial
+		valmap["vendor_id"] = d.vendorID
+		valmap["product_id"] = d.productID
+		valmap["first_seen"] = timeconv.Format(d.first)
+		valmap["last_seen"] = timeconv.Format(d.last)
+		valmap["extras"] = m.extras(map[string]interface{}{"revision": d.revision, "attach_count": d.count})
+		rows = append(rows, valmap)
+	}
+	return rows, nil
+}
+
+// address formats a Bluetooth address as 00:11:22:aa:bb:cc, the plists key devices as 00-11-22-aa-bb-cc
+func (m MacPeripheralsModule) address(s string) string {
+	return strings.ToLower(strings.Replace(s, "-", ":", -1))
+}
+
+// id formats a vendor or product ID as hex
+func (m MacPeripheralsModule) id(v interface{}) string {
+	if i, ok := v.(uint64); ok {
+		return fmt.Sprintf("0x%04x", i)
+	}
+	return ""
+}
+
+// hexID formats a vendor or product ID logged as hex the same way as id
+func (m MacPeripheralsModule) hexID(s string) string {
+	i, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+	if err != nil {
+		return s
+	}
+	return m.id(i)
+}
+
+// extras returns the extras column of a row
+func (m MacPeripheralsModule) extras(extras map[string]interface{}) string {
+	if len(extras) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
+	}
+	return string(b)
+}
@@ -0,0 +1,23 @@
+package macperipherals
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacPeripheralsModuleGolden(t *testing.T) {
+	// system.log lines have no year, it is taken from the modification time of the logs which git does not keep,
+	// the times are set on a copy so the checkout is left alone
+	target := moduletest.CopyTarget(t, moduletest.TargetDir)
+	modified := time.Date(2020, 8, 2, 0, 0, 0, 0, time.UTC)
+	for _, log := range []string{"system.log", "system.log.0.gz"} {
+		if err := os.Chtimes(filepath.Join(target, "private/var/log", log), modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moduletest.RunGolden(t, MacPeripheralsModule{}, moduletest.Options{Target: target})
+}
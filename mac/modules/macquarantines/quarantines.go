@@ -1,5 +1,3 @@
-// +build darwin
-
 package macquarantines
 
 import (
@@ -9,7 +7,10 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 	"howett.net/plist"
 )
@@ -27,16 +28,6 @@ var (
 type MacQuarantinesModule struct {
 }
 
-var (
-	quarantineEventsV2Filepaths = []string{
-		"Users/*/Library/Preferences/com.apple.LaunchServices.QuarantineEventsV2",
-		"private/var/*/Library/Preferences/com.apple.LaunchServices.QuarantineEventsV2",
-	}
-	gatekeeperFilepaths = []string{
-		"private/var/db/.LastGKReject",
-	}
-)
-
 type lastGKRejectPlist struct {
 }
 
@@ -48,6 +39,24 @@ func (m MacQuarantinesModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacQuarantinesModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacQuarantinesModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Incremental:       true,
+			Events:            []timeline.Event{{Field: "TimeStamp", Type: "File Quarantined"}},
+			UserField:         "user",
+			DescriptionFields: []string{"AgentName", "DataURLString", "OriginURLString"},
+		},
+	}
+}
+
 func (m MacQuarantinesModule) quarantines(inst instance.Instance) error {
 	quarantineheader := []string{
 		"user",
@@ -137,7 +146,7 @@ func (m MacQuarantinesModule) quarantines(inst instance.Instance) error {
 }
 
 func (m MacQuarantinesModule) getQuarantineEventsV2Filenames(inst instance.Instance) ([]string, error) {
-	quarantineEventsV2filenames := util.Multiglob(quarantineEventsV2Filepaths, inst.GetTargetPath())
+	quarantineEventsV2filenames := inst.GetArtifactPaths("MacOSQuarantineEvents")
 	if len(quarantineEventsV2filenames) <= 0 {
 		return quarantineEventsV2filenames, errors.New("no QuarantineEventsV2 files were found")
 	}
@@ -145,7 +154,7 @@ func (m MacQuarantinesModule) getQuarantineEventsV2Filenames(inst instance.Insta
 }
 
 func (m MacQuarantinesModule) getGatekeeperLastRejectFilenames(inst instance.Instance) ([]string, error) {
-	gatekeeperLastRejectFilenames := util.Multiglob(gatekeeperFilepaths, inst.GetTargetPath())
+	gatekeeperLastRejectFilenames := inst.GetArtifactPaths("MacOSGatekeeperLastReject")
 	if len(gatekeeperLastRejectFilenames) <= 0 {
 		return gatekeeperLastRejectFilenames, errors.New("no .LastGKReject files were found")
 	}
@@ -190,18 +199,14 @@ func (m MacQuarantinesModule) parseQuarantineEventsV2Database(dbpath string) ([]
 
 	// We have to modify timestamp and prepend user
 	timestampIndex := 1
-	for _, e := range entries {
+	for i, e := range entries {
 		tmp := e[timestampIndex]
-		f, err := strconv.ParseFloat(e[timestampIndex], 64)
-		if err != nil {
-			e[timestampIndex] = tmp + "<FAILED TO CONVERT>"
-		}
-		e[timestampIndex], err = util.CocoaTime(int64(f))
+		e[timestampIndex], err = timeconv.ConvertToString(timeconv.Cocoa, tmp)
 		if err != nil {
 			e[timestampIndex] = tmp + "<FAILED TO CONVERT>"
 		}
 
-		e = util.Prepend(e, util.GetUsernameFromPath(dbpath))
+		entries[i] = util.Prepend(e, util.GetUsernameFromPath(dbpath))
 	}
 
 	return entries, nil
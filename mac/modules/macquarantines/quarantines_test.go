@@ -0,0 +1,11 @@
+package macquarantines
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacQuarantinesModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacQuarantinesModule{}, moduletest.Options{})
+}
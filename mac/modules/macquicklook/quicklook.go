@@ -0,0 +1,221 @@
+package macquicklook
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// MacQuicklookModule wraps Module methods
+type MacQuicklookModule struct{}
+
+var (
+	moduleName  = "MacQuicklookModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the Quick Look thumbnail cache (com.apple.QuickLook.thumbnailcache/index.sqlite) of each user
+	Every file Finder or Quick Look made a thumbnail of is listed with the last time and number of times a thumbnail was
+	shown, files stay in the cache after they are deleted or their volume is removed.
+	Inspiration taken from AutoMactc by CrowdStrike
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"source_name",
+		"item_index",
+		"order",
+		"name",
+		"url",
+		"target_created",
+		"volume_name",
+		"volume_uuid",
+		"file_id",
+		"source_key",
+		"last_hit",
+		"hit_count",
+		"extras",
+	}
+)
+
+// query lists every thumbnail of the cached files, files without a thumbnail once, NULLs are avoided as QueryDB does
+// not handle them
+const query = `SELECT f.rowid AS file_rowid, IFNULL(f.folder, '') AS folder, IFNULL(f.file_name, '') AS file_name,
+	IFNULL(f.version, '') AS version, IFNULL(f.fs_id, '') AS fs_id, IFNULL(t.width, '') AS width,
+	IFNULL(t.height, '') AS height, IFNULL(t.bitmapdata_location, '') AS bitmapdata_location,
+	IFNULL(t.bitmapdata_length, '') AS bitmapdata_length, IFNULL(t.hit_count, '') AS hit_count,
+	IFNULL(t.last_hit_date, '') AS last_hit_date
+	FROM files f LEFT JOIN thumbnails t ON t.file_id = f.rowid ORDER BY f.rowid, t.rowid`
+
+var queryHeaders = []string{"file_rowid", "folder", "file_name", "version", "fs_id", "width", "height", "bitmapdata_location", "bitmapdata_length", "hit_count", "last_hit_date"}
+
+// Start starts the MacQuicklookModule, should not be manually called
+func (m MacQuicklookModule) Start(inst instance.Instance) error {
+	err := m.quicklook(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacQuicklookModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacQuicklookModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "last_hit", Type: "Quick Look Thumbnail Shown"}},
+			UserField:         "user",
+			DescriptionFields: []string{"url", "hit_count"},
+		},
+	}
+}
+
+func (m MacQuicklookModule) quicklook(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSQuickLookThumbnailCache")
+	if len(paths) == 0 {
+		zap.L().Warn("No Quick Look thumbnail caches were found", zap.String("module", moduleName))
+	}
+
+	for _, dbpath := range paths {
+		entries, err := util.QueryDB(dbpath, query, queryHeaders, false)
+		if err != nil {
+			zap.L().Error("could not query '"+dbpath+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for i, e := range entries {
+			row := make(map[string]string)
+			for j, h := range queryHeaders {
+				row[h] = e[j]
+			}
+
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			valmap["source_file"] = dbpath
+			// the cache is under the per user temporary folder, the user is found from the home of the files
+			if strings.HasPrefix(row["folder"], "/Users/") {
+				valmap["user"] = util.GetUsernameFromPath(row["folder"])
+			}
+			valmap["source_name"] = "QuickLook"
+			valmap["item_index"] = strconv.Itoa(i)
+			valmap["name"] = row["file_name"]
+			valmap["url"] = (&url.URL{Scheme: "file", Path: path.Join(row["folder"], row["file_name"])}).String()
+			// fs_id is <device>.<inode> of the file
+			if dot := strings.LastIndex(row["fs_id"], "."); dot >= 0 {
+				valmap["file_id"] = row["fs_id"][dot+1:]
+			}
+			valmap["source_key"] = row["folder"]
+			valmap["hit_count"] = row["hit_count"]
+			valmap["last_hit"], err = timeconv.ConvertToString(timeconv.Cocoa, row["last_hit_date"])
+			if err != nil {
+				valmap["last_hit"] = row["last_hit_date"] + "<FAILED TO CONVERT>"
+			}
+
+			extras := map[string]interface{}{}
+			for k, v := range m.version(row["version"]) {
+				extras[k] = v
+			}
+			for _, k := range []string{"fs_id", "width", "height", "bitmapdata_location", "bitmapdata_length"} {
+				if row[k] != "" {
+					extras[k] = row[k]
+				}
+			}
+			valmap["extras"] = m.extras(extras)
+
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] thumbnails from %d caches", len(values), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// version returns the modification date, size and generator of the file as they were when the thumbnail was made,
+// kept in the version plist of the file as returned by QueryDB ("b64:" prefixed)
+func (m MacQuicklookModule) version(v string) map[string]string {
+	values := map[string]string{}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, "b64:"))
+	if err != nil || len(b) == 0 {
+		return values
+	}
+	decoded, err := machelpers.DecodePlistBytes(b)
+	if err != nil {
+		zap.L().Debug("could not decode version plist: "+err.Error(), zap.String("module", moduleName))
+		return values
+	}
+	version, ok := decoded.(map[string]interface{})
+	if !ok {
+		return values
+	}
+	if date, ok := version["date"]; ok {
+		if modified, err := timeconv.ConvertToString(timeconv.Cocoa, date); err == nil {
+			values["file_modified"] = modified
+		}
+	}
+	if size, ok := version["size"]; ok {
+		values["file_size"] = fmt.Sprint(size)
+	}
+	if generator, ok := version["gen"].(string); ok {
+		values["generator"] = generator
+	}
+	return values
+}
+
+// extras returns the extras column of a row
+func (m MacQuicklookModule) extras(extras map[string]interface{}) string {
+	if len(extras) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
+	}
+	return string(b)
+}
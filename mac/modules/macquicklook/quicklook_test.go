@@ -0,0 +1,11 @@
+package macquicklook
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacQuicklookModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacQuicklookModule{}, moduletest.Options{})
+}
@@ -0,0 +1,178 @@
+package macrecentitems
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"go.uber.org/zap"
+)
+
+// MacRecentItemsModule wraps Module methods
+type MacRecentItemsModule struct{}
+
+var (
+	moduleName  = "MacRecentItemsModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and parses the legacy Apple menu recent items (com.apple.recentitems.plist) of each user
+	Recent applications, documents, servers and hosts were kept there up to 10.10 before moving to the shared file lists
+	read by MacMRUModule. Bookmark and Alias records are decoded natively
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"source_file",
+		"user",
+		"source_name",
+		"item_index",
+		"order",
+		"name",
+		"url",
+		"target_created",
+		"volume_name",
+		"volume_uuid",
+		"file_id",
+		"source_key",
+		"extras",
+	}
+)
+
+// Start starts the MacRecentItemsModule, should not be manually called
+func (m MacRecentItemsModule) Start(inst instance.Instance) error {
+	err := m.recentItems(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacRecentItemsModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+func (m MacRecentItemsModule) recentItems(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+
+	paths := inst.GetArtifactPaths("MacOSRecentItems")
+	if len(paths) == 0 {
+		zap.L().Warn("No recent items plists were found", zap.String("module", moduleName))
+	}
+
+	for _, path := range paths {
+		data, err := machelpers.DecodePlist(path, inst.GetTargetPath())
+		if err != nil {
+			zap.L().Error("could not parse plist '"+path+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		for _, item := range data {
+			// each section (RecentApplications, RecentDocuments, RecentServers, Hosts) holds its items in CustomListItems
+			sections := []string{}
+			for k := range item {
+				sections = append(sections, k)
+			}
+			sort.Strings(sections)
+			for _, section := range sections {
+				list, ok := item[section].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				listItems, ok := list["CustomListItems"].([]interface{})
+				if !ok {
+					continue
+				}
+				for i, li := range listItems {
+					listItem, ok := li.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					var valmap = make(map[string]string)
+					valmap = util.InitializeMapToEmptyString(valmap, header)
+					valmap["user"] = util.GetUsernameFromPath(path)
+					valmap["source_file"] = path
+					valmap["source_name"] = "RecentItems"
+					valmap["source_key"] = section
+					valmap["item_index"] = strconv.Itoa(i)
+					valmap["name"], _ = listItem["Name"].(string)
+					valmap["url"], _ = listItem["URL"].(string)
+					extras := map[string]interface{}{}
+					for _, key := range []string{"Bookmark", "Alias"} {
+						if b, ok := listItem[key].([]byte); ok {
+							m.bookmarkValues(valmap, extras, b)
+							break
+						}
+					}
+					valmap["extras"] = m.extras(extras)
+
+					entry, err := util.UnsafeEntryFromMap(valmap, header)
+					if err != nil {
+						zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+						continue
+					}
+					values = append(values, entry)
+				}
+			}
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] recent items from %d files", len(values), len(paths)), zap.String("module", moduleName))
+
+	// Write to output
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteAll(values)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// bookmarkValues sets the target columns of valmap from a Bookmark or Alias record, its format goes to extras
+func (m MacRecentItemsModule) bookmarkValues(valmap map[string]string, extras map[string]interface{}, b []byte) {
+	bm, err := machelpers.DecodeBookmark(b)
+	if err != nil {
+		zap.L().Debug("could not decode bookmark of '"+valmap["source_file"]+"': "+err.Error(), zap.String("module", moduleName))
+		return
+	}
+	for k, v := range bm.Columns() {
+		valmap[k] = v
+	}
+	if valmap["name"] == "" && bm.Path != "" {
+		valmap["name"] = filepath.Base(bm.Path)
+	}
+	extras["bookmark_format"] = bm.Format
+}
+
+// extras returns the extras column of a row
+func (m MacRecentItemsModule) extras(extras map[string]interface{}) string {
+	if len(extras) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extras)
+	if err != nil {
+		return fmt.Sprint(extras)
+	}
+	return string(b)
+}
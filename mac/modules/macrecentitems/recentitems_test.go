@@ -0,0 +1,11 @@
+package macrecentitems
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacRecentItemsModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacRecentItemsModule{}, moduletest.Options{})
+}
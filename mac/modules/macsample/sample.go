@@ -1,5 +1,3 @@
-// +build darwin
-
 package macsample
 
 import (
@@ -8,6 +6,7 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
 
 	"go.uber.org/zap"
 	"howett.net/plist"
@@ -45,6 +44,11 @@ func (m MacSampleModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacSampleModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
 func (m MacSampleModule) osVersion(inst instance.Instance) error {
 	zap.L().Debug("Grabbing OS version from " + filepathSystemVersionPlist)
 
@@ -0,0 +1,198 @@
+package macsavedstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
+	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/savedstate"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
+	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"go.uber.org/zap"
+)
+
+// MacSavedStateModule wraps Module methods
+type MacSavedStateModule struct{}
+
+var (
+	moduleName  = "MacSavedStateModule"
+	mode        = "mac"
+	version     = "1.0"
+	description = `
+	Reads and decrypts the Saved Application State of every application for each user on disk
+	Lists the windows with their title and the URLs found in their restoration data, with app specific details for
+	Terminal, Preview, TextEdit and Finder. Every decoded object is written to MacSavedStateModule-objects
+	`
+	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
+)
+
+var (
+	header = []string{
+		"mtime",
+		"atime",
+		"ctime",
+		"btime",
+		"source_dir",
+		"user",
+		"bundle_id",
+		"window_id",
+		"window_title",
+		"main_window",
+		"datablocks",
+		"urls",
+		"details",
+	}
+	objectsHeader = []string{
+		"source_dir",
+		"user",
+		"bundle_id",
+		"window_id",
+		"datablock",
+		"path",
+		"value",
+	}
+)
+
+// Start starts the MacSavedStateModule, should not be manually called
+func (m MacSavedStateModule) Start(inst instance.Instance) error {
+	err := m.savedstate(inst)
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+	}
+	return err
+}
+
+// Info returns the module description, version and tags shown by --list
+func (m MacSavedStateModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacSavedStateModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "mtime", Type: "Application State Saved", MACB: "M..."}},
+			UserField:         "user",
+			DescriptionFields: []string{"bundle_id", "window_title", "urls"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacSavedStateModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_dir", "window_id"}, Ignore: []string{"atime"}},
+	}
+}
+
+func (m MacSavedStateModule) savedstate(inst instance.Instance) error {
+	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+	objectsWriter, err := datawriter.NewOrionWriter(moduleName+"-objects", inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
+
+	values := [][]string{}
+	objectValues := [][]string{}
+
+	dirs := inst.GetArtifactPaths("MacOSSavedApplicationState")
+	if len(dirs) == 0 {
+		zap.L().Debug(fmt.Sprintf("No saved application states were found in %s", inst.GetArtifactGlobs("MacOSSavedApplicationState")), zap.String("module", moduleName))
+	}
+
+	for _, dir := range dirs {
+		user := util.GetUsernameFromPath(dir)
+		state, err := savedstate.ReadState(dir)
+		if os.IsNotExist(err) && len(state.Windows) == 0 {
+			zap.L().Debug("Skipping '"+dir+"', no windows.plist", zap.String("module", moduleName))
+			continue
+		}
+		if err != nil {
+			// windows.plist may still have given the window titles
+			zap.L().Debug(fmt.Sprintf("could not parse all of %s - %s", dir, err.Error()), zap.String("module", moduleName))
+		}
+
+		// data.data is rewritten whenever the application saves its state
+		timestamps := filepath.Join(dir, savedstate.DataFile)
+		if _, err := os.Stat(timestamps); err != nil {
+			timestamps = filepath.Join(dir, savedstate.WindowsFile)
+		}
+		metadata := machelpers.FileTimestamps(timestamps, moduleName)
+
+		for _, window := range state.Windows {
+			var valmap = make(map[string]string)
+			valmap = util.InitializeMapToEmptyString(valmap, header)
+			valmap["mtime"] = metadata["mtime"]
+			valmap["atime"] = metadata["atime"]
+			valmap["ctime"] = metadata["ctime"]
+			valmap["btime"] = metadata["btime"]
+			valmap["source_dir"] = dir
+			valmap["user"] = user
+			valmap["bundle_id"] = state.BundleID
+			valmap["window_id"] = strconv.FormatUint(uint64(window.ID), 10)
+			valmap["window_title"] = window.Title
+			valmap["main_window"] = strconv.FormatBool(window.Main)
+			valmap["datablocks"] = strconv.Itoa(len(window.Blocks))
+
+			objects := []interface{}{}
+			for _, block := range window.Blocks {
+				objects = append(objects, block.Objects...)
+				for _, v := range savedstate.Flatten(block.Objects) {
+					objectValues = append(objectValues, []string{dir, user, state.BundleID, valmap["window_id"], strconv.Itoa(block.Index), v.Path, v.Value})
+				}
+			}
+			valmap["urls"] = strings.Join(savedstate.URLs(objects), " ")
+			if details := savedstate.Extract(state.BundleID, objects); details != nil {
+				b, err := json.Marshal(details)
+				if err != nil {
+					valmap["details"] = fmt.Sprint(details)
+				} else {
+					valmap["details"] = string(b)
+				}
+			}
+
+			entry, err := util.UnsafeEntryFromMap(valmap, header)
+			if err != nil {
+				zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+				continue
+			}
+			values = append(values, entry)
+		}
+	}
+	zap.L().Debug(fmt.Sprintf("Parsed [%d] windows and [%d] objects from %d saved states", len(values), len(objectValues), len(dirs)), zap.String("module", moduleName))
+
+	// Write to output
+	for _, output := range []struct {
+		w      datawriter.OrionWriter
+		header []string
+		values [][]string
+	}{{mw, header, values}, {objectsWriter, objectsHeader, objectValues}} {
+		err = output.w.WriteHeader(output.header)
+		if err != nil {
+			return err
+		}
+		err = output.w.WriteAll(output.values)
+		if err != nil {
+			return err
+		}
+		err = output.w.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package macsavedstate
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacSavedStateModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacSavedStateModule{}, moduletest.Options{Mask: []string{"mtime", "atime", "ctime", "btime"}})
+}
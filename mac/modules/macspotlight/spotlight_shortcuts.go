@@ -1,5 +1,3 @@
-// +build darwin
-
 package macspotlight
 
 import (
@@ -8,6 +6,8 @@ import (
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/machelpers"
 	"go.uber.org/zap"
@@ -36,10 +36,6 @@ var (
 		"last_used",
 		"url",
 	}
-	filepathsSpotlightShortcutsPlists = []string{
-		"Users/*/Library/Application Support/com.apple.spotlight/com.apple.spotlight.Shortcuts",
-		"private/var/*/Library/Application Support/com.apple.spotlight/com.apple.spotlight.Shortcuts",
-	}
 )
 
 func (m MacSpotlightShortcutsModule) Start(inst instance.Instance) error {
@@ -51,6 +47,23 @@ func (m MacSpotlightShortcutsModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacSpotlightShortcutsModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacSpotlightShortcutsModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Events:            []timeline.Event{{Field: "last_used", Type: "Spotlight Shortcut Used"}},
+			UserField:         "user",
+			DescriptionFields: []string{"shortcut", "display_name", "url"},
+		},
+	}
+}
+
 func (m MacSpotlightShortcutsModule) shortcuts(inst instance.Instance) error {
 	values := [][]string{}
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
@@ -59,7 +72,7 @@ func (m MacSpotlightShortcutsModule) shortcuts(inst instance.Instance) error {
 		return err
 	}
 
-	spotlightShortcutPlistPaths := util.Multiglob(filepathsSpotlightShortcutsPlists, inst.GetTargetPath())
+	spotlightShortcutPlistPaths := inst.GetArtifactPaths("MacOSSpotlightShortcuts")
 	if len(spotlightShortcutPlistPaths) == 0 {
 		return errors.New("no spotlight shortcuts plists were found")
 	}
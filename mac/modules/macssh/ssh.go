@@ -1,17 +1,17 @@
-// +build darwin
-
 package macssh
 
 import (
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/sshfiles"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/throttle"
 	"github.com/anthonybm/Orion/util"
 	"go.uber.org/zap"
 )
@@ -22,19 +22,42 @@ type MacSSHModule struct {
 var (
 	moduleName  = "MacSSHModule"
 	mode        = "mac"
-	version     = "1.0"
+	version     = "1.1"
 	description = `
-	Reads and parses the SSH known_hosts and authorized_keys on disk
+	Reads and parses the SSH known_hosts, authorized_keys, public and private keys of users, and the SSH client and
+	server configs. Flags authorized_keys options (command=, environment=, from=...), unencrypted private keys and
+	config directives running commands or weakening authentication
 	`
 	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
 )
 
 var (
-	filepathSSHLocations = []string{
-		"Users/*/.ssh/known_hosts",
-		"Users/*/.ssh/authorized_keys",
-		"private/var/*/.ssh/known_hosts",
-		"private/var/*/.ssh/authorized_keys",
+	header = []string{
+		"source_file",
+		"source_name",
+		"user",
+		"line",
+		"keytype",
+		"bits",
+		"fingerprint",
+		"fingerprint_md5",
+		"comment",
+		"host",
+		"hashed_host",
+		"marker",
+		"options",
+		"encrypted",
+		"flags",
+	}
+	configHeader = []string{
+		"source_file",
+		"source_name",
+		"user",
+		"line",
+		"block",
+		"keyword",
+		"value",
+		"flags",
 	}
 )
 
@@ -46,130 +69,192 @@ func (m MacSSHModule) Start(inst instance.Instance) error {
 	return err
 }
 
-func (m MacSSHModule) ssh(inst instance.Instance) error {
-	header := []string{
-		"source_name",
-		"user",
-		"bits",
-		"fingerprint",
-		"host",
-		"keytype",
+// Info returns the module description, version and tags shown by --list
+func (m MacSSHModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagPersistence}}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacSSHModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"source_file", "host", "fingerprint"}, Ignore: []string{"line"}},
+		{Output: moduleName + "-config", Columns: []string{"source_file", "block", "keyword", "value"}, Ignore: []string{"line"}},
 	}
-	values := [][]string{}
+}
 
+func (m MacSSHModule) ssh(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
 		zap.L().Error("Error running " + moduleName + ": " + err.Error())
 		return err
 	}
+	configWriter, err := datawriter.NewOrionWriter(moduleName+"-config", inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		zap.L().Error("Error running " + moduleName + ": " + err.Error())
+		return err
+	}
 
-	// get all ssh files from locations
-	filenames := util.Multiglob(filepathSSHLocations, inst.GetTargetPath())
+	values := [][]string{}
+	configValues := [][]string{}
 
+	// get all ssh files from locations
+	filenames := inst.GetArtifactPaths("MacOSSSHUserFiles")
 	if len(filenames) == 0 {
-		zap.L().Error("Module exiting, files not found in: '"+strings.Join(filepathSSHLocations, " OR ")+"'.", zap.String("module", moduleName))
-		return nil // Do not throw error for this
+		zap.L().Warn("No user files found in: '"+strings.Join(inst.GetArtifactGlobs("MacOSSSHUserFiles"), " OR ")+"'.", zap.String("module", moduleName))
 	}
 
 	// parse each ssh file
 	count := 0
-	countEntries := 0
 	for _, file := range filenames {
-		v, err := m.parseSSHFile(file)
+		b, err := throttle.ReadFile(file)
 		if err != nil {
-			zap.L().Error("failed to parse '"+file+"': "+err.Error(), zap.String("module", moduleName))
-		} else {
-			count++
-			for _, entry := range v {
-				values = append(values, entry)
-				countEntries++
+			zap.L().Error("failed to read '"+file+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		user := util.GetUsernameFromPath(file)
+		name := filepath.Base(file)
+		switch {
+		case strings.HasPrefix(name, "known_hosts"):
+			values = append(values, m.entries(file, "known_hosts", user, sshfiles.ParseKnownHosts(b))...)
+		case strings.HasPrefix(name, "authorized_keys"):
+			values = append(values, m.entries(file, "authorized_keys", user, sshfiles.ParseAuthorizedKeys(b))...)
+		case strings.HasSuffix(name, ".pub"):
+			values = append(values, m.entries(file, "public_key", user, sshfiles.ParseAuthorizedKeys(b))...)
+		case name == "config":
+			configValues = append(configValues, m.directives(file, "ssh_config", user, sshfiles.ParseConfig(b))...)
+		case sshfiles.IsPrivateKey(b):
+			entry, err := m.privateKey(file, user, b)
+			if err != nil {
+				zap.L().Error("failed to parse private key '"+file+"': "+err.Error(), zap.String("module", moduleName))
+				continue
 			}
+			values = append(values, entry)
+		default:
+			zap.L().Debug("Skipping '"+file+"', not an SSH file", zap.String("module", moduleName))
+			continue
 		}
+		count++
 	}
-	zap.L().Debug(fmt.Sprintf("Parsed %d entries from %d of %d .ssh files", countEntries, count, len(filenames)))
 
-	// Write to output
-	err = mw.WriteHeader(header)
-	if err != nil {
-		return err
-	}
-	err = mw.WriteAll(values)
-	if err != nil {
-		return err
+	// parse the client and server configs
+	for _, file := range inst.GetArtifactPaths("MacOSSSHConfigs") {
+		b, err := throttle.ReadFile(file)
+		if err != nil {
+			zap.L().Error("failed to read '"+file+"': "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		sourceName := "ssh_config"
+		if strings.Contains(file, "sshd_config") {
+			sourceName = "sshd_config"
+		}
+		configValues = append(configValues, m.directives(file, sourceName, "", sshfiles.ParseConfig(b))...)
+		count++
 	}
-	err = mw.Close()
-	if err != nil {
-		return err
+	zap.L().Debug(fmt.Sprintf("Parsed %d keys and %d config directives from %d SSH files", len(values), len(configValues), count), zap.String("module", moduleName))
+
+	// Write to output
+	for _, output := range []struct {
+		w      datawriter.OrionWriter
+		header []string
+		values [][]string
+	}{{mw, header, values}, {configWriter, configHeader, configValues}} {
+		err = output.w.WriteHeader(output.header)
+		if err != nil {
+			return err
+		}
+		err = output.w.WriteAll(output.values)
+		if err != nil {
+			return err
+		}
+		err = output.w.Close()
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (m MacSSHModule) parseSSHFile(fp string) ([][]string, error) {
-	sshCmd := exec.Command("ssh-keygen", "-l", "-f", fp)
-	sshOut, outerr := sshCmd.StdoutPipe()
-	sshErr, errerr := sshCmd.StderrPipe()
-	if outerr != nil {
-		return [][]string{}, errors.New("sshOut error - could not parse ssh log: " + fp + ": " + outerr.Error())
-	}
-	if errerr != nil {
-		return [][]string{}, errors.New("sshErr error - could not parse ssh log: " + fp + ": " + errerr.Error())
-	}
-	sshCmd.Start()
-	sshOutBytes, outbyteserr := ioutil.ReadAll(sshOut)
-	sshErrorBytes, errbyteserr := ioutil.ReadAll(sshErr)
-	if outbyteserr != nil {
-		return [][]string{}, errors.New("sshOutBytes error - could not parse ssh log: " + fp + ": " + outbyteserr.Error())
-	}
-	if errbyteserr != nil {
-		return [][]string{}, errors.New("sshErrorBytes error - could not parse ssh log: " + fp + ": " + errbyteserr.Error())
-	}
-	waiterr := sshCmd.Wait()
-	if waiterr != nil {
-		return [][]string{}, errors.New("sshCmd wait error - could not parse ssh log: " + fp + ": " + waiterr.Error())
-	}
-
-	sshOutString := string(sshOutBytes)
-	sshErrorString := string(sshErrorBytes)
+// entries converts the keys of a known_hosts, authorized_keys or public key file to output rows
+func (m MacSSHModule) entries(fp string, sourceName string, user string, entries []sshfiles.Entry) [][]string {
+	values := [][]string{}
+	for _, e := range entries {
+		if e.Error != "" {
+			zap.L().Debug(fmt.Sprintf("Skipping line %d of '%s': %s", e.Line, fp, e.Error), zap.String("module", moduleName))
+			continue
+		}
+		valmap := m.keyValues(fp, sourceName, user, e.Key)
+		valmap["line"] = strconv.Itoa(e.Line)
+		valmap["comment"] = e.Comment
+		valmap["host"] = strings.Join(e.Hosts, ",")
+		if len(e.Hosts) > 0 {
+			valmap["hashed_host"] = strconv.FormatBool(e.Hashed)
+		}
+		valmap["marker"] = e.Marker
+		valmap["options"] = strings.Join(e.Options, ",")
+		valmap["flags"] = strings.Join(e.Flags(), ", ")
 
-	if len(sshErrorString) > 0 {
-		if !strings.Contains(sshErrorString, "NOTE:") {
-			return [][]string{}, errors.New(fmt.Sprintf("sshErrorString not empty - could not parse '%s': %s", fp, sshErrorString))
+		entry, err := util.UnsafeEntryFromMap(valmap, header)
+		if err != nil {
+			zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+			continue
 		}
+		values = append(values, entry)
 	}
-	if strings.Contains(sshOutString, "is not a public key file") {
-		return [][]string{}, errors.New(fmt.Sprintf("could not parse " + fp + ": " + sshOutString))
-	}
+	return values
+}
 
-	cont, err := m.parseSSHString(sshOutString)
+// privateKey returns the output row of a private key file
+func (m MacSSHModule) privateKey(fp string, user string, b []byte) ([]string, error) {
+	key, err := sshfiles.ParsePrivateKey(b)
 	if err != nil {
-		return [][]string{}, err
+		return []string{}, err
 	}
-	var entries [][]string
-
-	count := 0
-	for i, item := range cont {
-		if len(item) > 0 {
-			entries = append(entries, m.parseSSHEntry(item, fp))
-			count = i
-		}
+	valmap := m.keyValues(fp, "private_key", user, key.Key)
+	if valmap["keytype"] == "" {
+		valmap["keytype"] = key.PEMType
 	}
-
-	zap.L().Debug("parsed ["+strconv.Itoa(count)+"] items from '"+fp+"'", zap.String("module", moduleName))
-	return entries, nil
+	valmap["encrypted"] = strconv.FormatBool(key.Encrypted)
+	valmap["flags"] = strings.Join(key.Flags(), ", ")
+	return util.UnsafeEntryFromMap(valmap, header)
 }
 
-func (m MacSSHModule) parseSSHString(sshOutString string) ([]string, error) {
-	return strings.Split(sshOutString, "\n"), nil
+// keyValues returns the values of the key columns shared by every row of the keys output
+func (m MacSSHModule) keyValues(fp string, sourceName string, user string, key sshfiles.Key) map[string]string {
+	var valmap = make(map[string]string)
+	valmap = util.InitializeMapToEmptyString(valmap, header)
+	valmap["source_file"] = fp
+	valmap["source_name"] = sourceName
+	valmap["user"] = user
+	valmap["keytype"] = key.Type
+	if key.Bits > 0 {
+		valmap["bits"] = strconv.Itoa(key.Bits)
+	}
+	valmap["fingerprint"] = key.SHA256
+	valmap["fingerprint_md5"] = key.MD5
+	return valmap
 }
 
-func (m MacSSHModule) parseSSHEntry(item string, fp string) []string {
-	data := strings.Split(item, " ")
-	entry := []string{
-		fp,
-		data[0],
-		data[1],
-		data[2],
-		data[3],
+// directives converts the directives of an ssh_config or sshd_config file to output rows
+func (m MacSSHModule) directives(fp string, sourceName string, user string, directives []sshfiles.Directive) [][]string {
+	values := [][]string{}
+	for _, d := range directives {
+		var valmap = make(map[string]string)
+		valmap = util.InitializeMapToEmptyString(valmap, configHeader)
+		valmap["source_file"] = fp
+		valmap["source_name"] = sourceName
+		valmap["user"] = user
+		valmap["line"] = strconv.Itoa(d.Line)
+		valmap["block"] = d.Block
+		valmap["keyword"] = d.Keyword
+		valmap["value"] = d.Value
+		valmap["flags"] = strings.Join(d.Flags(), ", ")
+
+		entry, err := util.UnsafeEntryFromMap(valmap, configHeader)
+		if err != nil {
+			zap.L().Error("Failed to convert map to entry: "+err.Error(), zap.String("module", moduleName))
+			continue
+		}
+		values = append(values, entry)
 	}
-	return entry
+	return values
 }
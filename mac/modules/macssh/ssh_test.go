@@ -0,0 +1,11 @@
+package macssh
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacSSHModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacSSHModule{}, moduletest.Options{})
+}
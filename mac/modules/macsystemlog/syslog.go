@@ -1,19 +1,18 @@
-// +build darwin
-
 package macsystemlog
 
 import (
 	"bufio"
 	"compress/gzip"
+	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
 	"go.uber.org/zap"
 )
 
@@ -35,10 +34,6 @@ var (
 	author = "Anthony Martinez, martinez.anthonyb@gmail.com"
 )
 
-var (
-	filepathSystemLogLocation = "private/var/log/system.log*"
-)
-
 func (m MacSystemLogModule) Start(inst instance.Instance) error {
 	zap.L().Warn("Does not parse multi-line system.log entries", zap.String("module", moduleName))
 	err := m.systemLog(inst)
@@ -48,6 +43,11 @@ func (m MacSystemLogModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacSystemLogModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
 func (m MacSystemLogModule) systemLog(inst instance.Instance) error {
 	header := []string{
 		"source_file",
@@ -66,9 +66,9 @@ func (m MacSystemLogModule) systemLog(inst instance.Instance) error {
 	}
 
 	// get all system.log files in path
-	files, _ := filepath.Glob(filepath.Join(inst.GetTargetPath(), filepathSystemLogLocation))
+	files := inst.GetArtifactPaths("MacOSSystemLogs")
 	if len(files) == 0 {
-		zap.L().Debug("files not found in: '"+filepathSystemLogLocation+"'.", zap.String("module", moduleName))
+		zap.L().Debug(fmt.Sprintf("files not found in: '%s'.", inst.GetArtifactGlobs("MacOSSystemLogs")), zap.String("module", moduleName))
 		return nil // Do not throw error for this
 	}
 
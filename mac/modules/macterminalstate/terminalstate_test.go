@@ -0,0 +1,11 @@
+package macterminalstate
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacTerminalStateModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacTerminalStateModule{}, moduletest.Options{})
+}
@@ -1,17 +1,16 @@
-// +build darwin
-
 package macusers
 
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os/exec"
 	"reflect"
 	"strings"
 
 	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/machelpers"
 	"go.uber.org/zap"
@@ -44,19 +43,9 @@ var (
 		"admin",
 		"last_logged_in_user",
 	}
-	filepathsDeletedUsersPlist = []string{
-		"Library/Preferences/com.apple.preferences.accounts.plist",
-	}
 	filepathAdminUsersPlist = "private/var/db/dslocal/nodes/Default/groups/admin.plist"
 
 	filepathLoginWindowPlist = "Library/Preferences/com.apple.loginwindow.plist"
-
-	filepathsLiveUsers = []string{
-		"Users/*",
-	}
-	filepathsPrivateUsers = []string{
-		"private/var/*",
-	}
 )
 
 func (m MacUsersModule) Start(inst instance.Instance) error {
@@ -67,6 +56,34 @@ func (m MacUsersModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacUsersModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagNeedsRoot}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacUsersModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "btime", Type: "User Home Created", MACB: "...B"},
+				{Field: "mtime", Type: "User Home Modified", MACB: "M..."},
+				{Field: "date_deleted", Type: "User Deleted"},
+			},
+			UserField:         "user",
+			DescriptionFields: []string{"unique_id", "real_name", "admin"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m MacUsersModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"user"}, Ignore: []string{"atime"}},
+	}
+}
+
 func (m MacUsersModule) users(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -78,6 +95,7 @@ func (m MacUsersModule) users(inst instance.Instance) error {
 	if err != nil {
 		return err
 	}
+	forensicMode = forensicMode || inst.ForensicMode()
 
 	values := [][]string{}
 
@@ -128,11 +146,11 @@ func (m MacUsersModule) users(inst instance.Instance) error {
 	notUsers := []string{".localized", "Shared", "agentx", "at", "audit", "backups", "db", "empty",
 		"folders", "install", "jabberd", "lib", "log", "mail", "msgs", "netboot",
 		"networkd", "rpc", "run", "rwho", "spool", "tmp", "vm", "yp", "ma"}
-	liveUsers := util.Multiglob(filepathsLiveUsers, inst.GetTargetPath())
+	liveUsers := inst.GetArtifactPaths("MacOSUserDirectories")
 	if len(liveUsers) == 0 {
 		zap.L().Debug("No live users were found", zap.String("module", moduleName))
 	}
-	privateUsers := util.Multiglob(filepathsPrivateUsers, inst.GetTargetPath())
+	privateUsers := inst.GetArtifactPaths("MacOSPrivateVarDirectories")
 	if len(privateUsers) == 0 {
 		zap.L().Debug("No private users were found", zap.String("module", moduleName))
 	}
@@ -148,7 +166,7 @@ func (m MacUsersModule) users(inst instance.Instance) error {
 	// Enumerate all user plists in from either /private/var/db/dslocal/nodes or via dscl command
 	// u1 : kval : vval
 	usersMap := make(map[string]map[string]string)
-	userPlists := util.Multiglob([]string{"/private/var/db/dslocal/nodes/Default/users/*"}, inst.GetTargetPath())
+	userPlists := inst.GetArtifactPaths("MacOSLocalUsers")
 	if len(userPlists) == 0 {
 		zap.L().Debug("No user plists were found", zap.String("module", moduleName))
 	} else {
@@ -171,7 +189,7 @@ func (m MacUsersModule) users(inst instance.Instance) error {
 	if !forensicMode && len(usersMap) == 0 {
 		usersMap, err = m.usersFromDSCL()
 		if err != nil {
-			zap.L().Error(fmt.Sprintf("Users from dscl live - %s", err.Error(), zap.String("module", moduleName)))
+			zap.L().Error(fmt.Sprintf("Users from dscl live - %s", err.Error()), zap.String("module", moduleName))
 		}
 	} else if forensicMode && len(usersMap) == 0 {
 		// If running in forensic mode and there was still an error accessing dslocal, operate only with the paths for each user
@@ -310,133 +328,11 @@ func (m MacUsersModule) users(inst instance.Instance) error {
 	return nil
 }
 
-func (m MacUsersModule) usersFromDSCL() (map[string]map[string]string, error) {
-	// UniqueID
-	userIDsCmd := exec.Command("dscl", ".", "-list", "Users", "UniqueID")
-	userIDsOut, outerr := userIDsCmd.StdoutPipe()
-	userIDsErr, errerr := userIDsCmd.StderrPipe()
-	if outerr != nil {
-		return map[string]map[string]string{}, errors.New("userIDsOut error - could not parse userIDs via dscl: " + outerr.Error())
-	}
-	if errerr != nil {
-		return map[string]map[string]string{}, errors.New("userIDsErr error - could not parse userIDs via dscl: " + errerr.Error())
-	}
-	userIDsCmd.Start()
-	userIDsOutBytes, outbyteserr := ioutil.ReadAll(userIDsOut)
-	userIDsErrorBytes, errbyteserr := ioutil.ReadAll(userIDsErr)
-	if outbyteserr != nil {
-		return map[string]map[string]string{}, errors.New("userIDsOutBytes error - could not parse userIDs via dscl: " + outbyteserr.Error())
-	}
-	if errbyteserr != nil {
-		return map[string]map[string]string{}, errors.New("userIDsErrorBytes error - could not parse userIDs via dscl: " + errbyteserr.Error())
-	}
-	waiterr := userIDsCmd.Wait()
-	if waiterr != nil {
-		return map[string]map[string]string{}, errors.New("userIDsCmd wait error - could not parse userIDs via dscl: " + waiterr.Error())
-	}
-
-	userIDsOutString := string(userIDsOutBytes)
-	userIDsErrorString := string(userIDsErrorBytes)
-
-	if len(userIDsErrorString) > 0 {
-		if !strings.Contains(userIDsErrorString, "NOTE:") {
-			return map[string]map[string]string{}, errors.New(fmt.Sprintf("userIDsErrorString not empty - could not parse: %s", userIDsErrorString))
-		}
-	}
-
-	usersMap := make(map[string]map[string]string)
-	for _, userID := range strings.Split(userIDsOutString, "\n") {
-		userIDData := strings.Split(userID, " ")
-		usersMap[userIDData[0]] = map[string]string{
-			"uid":       userIDData[len(userIDData)-1],
-			"real_name": "",
-		}
-	}
-
-	// RealName
-	userNamesCmd := exec.Command("dscl", ".", "-list", "Users", "UniqueID")
-	userNamesOut, outerr := userNamesCmd.StdoutPipe()
-	userNamesErr, errerr := userNamesCmd.StderrPipe()
-	if outerr != nil {
-		return map[string]map[string]string{}, errors.New("userNamesOut error - could not parse userNames via dscl: " + outerr.Error())
-	}
-	if errerr != nil {
-		return map[string]map[string]string{}, errors.New("userNamesErr error - could not parse userNames via dscl: " + errerr.Error())
-	}
-	userNamesCmd.Start()
-	userNamesOutBytes, outbyteserr := ioutil.ReadAll(userNamesOut)
-	userNamesErrorBytes, errbyteserr := ioutil.ReadAll(userNamesErr)
-	if outbyteserr != nil {
-		return map[string]map[string]string{}, errors.New("userNamesOutBytes error - could not parse userNames via dscl: " + outbyteserr.Error())
-	}
-	if errbyteserr != nil {
-		return map[string]map[string]string{}, errors.New("userNamesErrorBytes error - could not parse userNames via dscl: " + errbyteserr.Error())
-	}
-	waiterr = userNamesCmd.Wait()
-	if waiterr != nil {
-		return map[string]map[string]string{}, errors.New("userNamesCmd wait error - could not parse userNames via dscl: " + waiterr.Error())
-	}
-
-	userNamesOutString := string(userNamesOutBytes)
-	userNamesErrorString := string(userNamesErrorBytes)
-
-	if len(userNamesErrorString) > 0 {
-		if !strings.Contains(userNamesErrorString, "NOTE:") {
-			return map[string]map[string]string{}, errors.New(fmt.Sprintf("userNamesErrorString not empty - could not parse: %s", userNamesErrorString))
-		}
-	}
-
-	for _, userID := range strings.Split(userNamesOutString, "\n") {
-		userNameData := strings.Split(userID, " ")
-		usersMap[userNameData[0]]["real_name"] = strings.Join(userNameData[1:], " ")
-	}
-
-	return usersMap, nil
-}
-
-// adminsFromDSCL uses exce to run the DSCL command to retrieve a slice of admin users as strings
-func (m MacUsersModule) adminsFromDSCL() ([]string, error) {
-	adminsCmd := exec.Command("dscl", ".", "-read", "/Groups/admin", "GroupMembership")
-	adminsOut, outerr := adminsCmd.StdoutPipe()
-	adminsErr, errerr := adminsCmd.StderrPipe()
-	if outerr != nil {
-		return []string{}, errors.New("adminsOut error - could not parse admins via dscl: " + outerr.Error())
-	}
-	if errerr != nil {
-		return []string{}, errors.New("adminsErr error - could not parse admins via dscl: " + errerr.Error())
-	}
-	adminsCmd.Start()
-	adminsOutBytes, outbyteserr := ioutil.ReadAll(adminsOut)
-	adminsErrorBytes, errbyteserr := ioutil.ReadAll(adminsErr)
-	if outbyteserr != nil {
-		return []string{}, errors.New("adminsOutBytes error - could not parse admins via dscl: " + outbyteserr.Error())
-	}
-	if errbyteserr != nil {
-		return []string{}, errors.New("adminsErrorBytes error - could not parse admins via dscl: " + errbyteserr.Error())
-	}
-	waiterr := adminsCmd.Wait()
-	if waiterr != nil {
-		return []string{}, errors.New("adminsCmd wait error - could not parse admins via dscl: " + waiterr.Error())
-	}
-
-	adminsOutString := string(adminsOutBytes)
-	adminsErrorString := string(adminsErrorBytes)
-
-	if len(adminsErrorString) > 0 {
-		if !strings.Contains(adminsErrorString, "NOTE:") {
-			return []string{}, errors.New(fmt.Sprintf("adminsErrorString not empty - could not parse: %s", adminsErrorString))
-		}
-	}
-
-	entries := strings.Split(strings.Split(strings.TrimSpace(adminsOutString), ":")[1], " ")
-	return entries, nil
-}
-
 func (m MacUsersModule) deletedUsers(inst instance.Instance) ([][]string, error) {
 	values := [][]string{}
 	count := 0
 
-	deletedUsersPaths := util.Multiglob(filepathsDeletedUsersPlist, inst.GetTargetPath())
+	deletedUsersPaths := inst.GetArtifactPaths("MacOSDeletedUsers")
 	if len(deletedUsersPaths) == 0 {
 		return [][]string{}, errors.New("no deleted users were found")
 	}
@@ -0,0 +1,133 @@
+// +build darwin
+
+package macusers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+func (m MacUsersModule) usersFromDSCL() (map[string]map[string]string, error) {
+	// UniqueID
+	userIDsCmd := exec.Command("dscl", ".", "-list", "Users", "UniqueID")
+	userIDsOut, outerr := userIDsCmd.StdoutPipe()
+	userIDsErr, errerr := userIDsCmd.StderrPipe()
+	if outerr != nil {
+		return map[string]map[string]string{}, errors.New("userIDsOut error - could not parse userIDs via dscl: " + outerr.Error())
+	}
+	if errerr != nil {
+		return map[string]map[string]string{}, errors.New("userIDsErr error - could not parse userIDs via dscl: " + errerr.Error())
+	}
+	userIDsCmd.Start()
+	userIDsOutBytes, outbyteserr := ioutil.ReadAll(userIDsOut)
+	userIDsErrorBytes, errbyteserr := ioutil.ReadAll(userIDsErr)
+	if outbyteserr != nil {
+		return map[string]map[string]string{}, errors.New("userIDsOutBytes error - could not parse userIDs via dscl: " + outbyteserr.Error())
+	}
+	if errbyteserr != nil {
+		return map[string]map[string]string{}, errors.New("userIDsErrorBytes error - could not parse userIDs via dscl: " + errbyteserr.Error())
+	}
+	waiterr := userIDsCmd.Wait()
+	if waiterr != nil {
+		return map[string]map[string]string{}, errors.New("userIDsCmd wait error - could not parse userIDs via dscl: " + waiterr.Error())
+	}
+
+	userIDsOutString := string(userIDsOutBytes)
+	userIDsErrorString := string(userIDsErrorBytes)
+
+	if len(userIDsErrorString) > 0 {
+		if !strings.Contains(userIDsErrorString, "NOTE:") {
+			return map[string]map[string]string{}, errors.New(fmt.Sprintf("userIDsErrorString not empty - could not parse: %s", userIDsErrorString))
+		}
+	}
+
+	usersMap := make(map[string]map[string]string)
+	for _, userID := range strings.Split(userIDsOutString, "\n") {
+		userIDData := strings.Split(userID, " ")
+		usersMap[userIDData[0]] = map[string]string{
+			"uid":       userIDData[len(userIDData)-1],
+			"real_name": "",
+		}
+	}
+
+	// RealName
+	userNamesCmd := exec.Command("dscl", ".", "-list", "Users", "UniqueID")
+	userNamesOut, outerr := userNamesCmd.StdoutPipe()
+	userNamesErr, errerr := userNamesCmd.StderrPipe()
+	if outerr != nil {
+		return map[string]map[string]string{}, errors.New("userNamesOut error - could not parse userNames via dscl: " + outerr.Error())
+	}
+	if errerr != nil {
+		return map[string]map[string]string{}, errors.New("userNamesErr error - could not parse userNames via dscl: " + errerr.Error())
+	}
+	userNamesCmd.Start()
+	userNamesOutBytes, outbyteserr := ioutil.ReadAll(userNamesOut)
+	userNamesErrorBytes, errbyteserr := ioutil.ReadAll(userNamesErr)
+	if outbyteserr != nil {
+		return map[string]map[string]string{}, errors.New("userNamesOutBytes error - could not parse userNames via dscl: " + outbyteserr.Error())
+	}
+	if errbyteserr != nil {
+		return map[string]map[string]string{}, errors.New("userNamesErrorBytes error - could not parse userNames via dscl: " + errbyteserr.Error())
+	}
+	waiterr = userNamesCmd.Wait()
+	if waiterr != nil {
+		return map[string]map[string]string{}, errors.New("userNamesCmd wait error - could not parse userNames via dscl: " + waiterr.Error())
+	}
+
+	userNamesOutString := string(userNamesOutBytes)
+	userNamesErrorString := string(userNamesErrorBytes)
+
+	if len(userNamesErrorString) > 0 {
+		if !strings.Contains(userNamesErrorString, "NOTE:") {
+			return map[string]map[string]string{}, errors.New(fmt.Sprintf("userNamesErrorString not empty - could not parse: %s", userNamesErrorString))
+		}
+	}
+
+	for _, userID := range strings.Split(userNamesOutString, "\n") {
+		userNameData := strings.Split(userID, " ")
+		usersMap[userNameData[0]]["real_name"] = strings.Join(userNameData[1:], " ")
+	}
+
+	return usersMap, nil
+}
+
+// adminsFromDSCL uses exce to run the DSCL command to retrieve a slice of admin users as strings
+func (m MacUsersModule) adminsFromDSCL() ([]string, error) {
+	adminsCmd := exec.Command("dscl", ".", "-read", "/Groups/admin", "GroupMembership")
+	adminsOut, outerr := adminsCmd.StdoutPipe()
+	adminsErr, errerr := adminsCmd.StderrPipe()
+	if outerr != nil {
+		return []string{}, errors.New("adminsOut error - could not parse admins via dscl: " + outerr.Error())
+	}
+	if errerr != nil {
+		return []string{}, errors.New("adminsErr error - could not parse admins via dscl: " + errerr.Error())
+	}
+	adminsCmd.Start()
+	adminsOutBytes, outbyteserr := ioutil.ReadAll(adminsOut)
+	adminsErrorBytes, errbyteserr := ioutil.ReadAll(adminsErr)
+	if outbyteserr != nil {
+		return []string{}, errors.New("adminsOutBytes error - could not parse admins via dscl: " + outbyteserr.Error())
+	}
+	if errbyteserr != nil {
+		return []string{}, errors.New("adminsErrorBytes error - could not parse admins via dscl: " + errbyteserr.Error())
+	}
+	waiterr := adminsCmd.Wait()
+	if waiterr != nil {
+		return []string{}, errors.New("adminsCmd wait error - could not parse admins via dscl: " + waiterr.Error())
+	}
+
+	adminsOutString := string(adminsOutBytes)
+	adminsErrorString := string(adminsErrorBytes)
+
+	if len(adminsErrorString) > 0 {
+		if !strings.Contains(adminsErrorString, "NOTE:") {
+			return []string{}, errors.New(fmt.Sprintf("adminsErrorString not empty - could not parse: %s", adminsErrorString))
+		}
+	}
+
+	entries := strings.Split(strings.Split(strings.TrimSpace(adminsOutString), ":")[1], " ")
+	return entries, nil
+}
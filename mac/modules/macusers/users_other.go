@@ -0,0 +1,17 @@
+// +build !darwin
+
+package macusers
+
+import "errors"
+
+// errNoDSCL is returned when the users of a live system are asked for off macOS, run in forensic mode against the
+// evidence instead
+var errNoDSCL = errors.New("dscl is only available on macOS, use forensic mode to read users from a target")
+
+func (m MacUsersModule) usersFromDSCL() (map[string]map[string]string, error) {
+	return map[string]map[string]string{}, errNoDSCL
+}
+
+func (m MacUsersModule) adminsFromDSCL() ([]string, error) {
+	return []string{}, errNoDSCL
+}
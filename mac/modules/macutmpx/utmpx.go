@@ -1,21 +1,18 @@
-// +build darwin
-
 package macutmpx
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/anthonybm/Orion/datawriter"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/mac/parsers/utmpx"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -42,10 +39,6 @@ var (
 		"timestamp",
 		"hostname",
 	}
-	filepathsUtmpx = []string{
-		"private/var/run/utmpx",
-	}
-	utmpxLineSize = 628
 )
 
 func (m MacUtmpxModule) Start(inst instance.Instance) error {
@@ -56,6 +49,24 @@ func (m MacUtmpxModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m MacUtmpxModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m MacUtmpxModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output:            moduleName,
+			Incremental:       true,
+			Events:            []timeline.Event{{Field: "timestamp", Type: "Login Record"}},
+			UserField:         "login_name",
+			DescriptionFields: []string{"logon_type", "tty_name", "hostname"},
+		},
+	}
+}
+
 func (m MacUtmpxModule) utmpx(inst instance.Instance) error {
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
 	if err != nil {
@@ -99,7 +110,7 @@ func (m MacUtmpxModule) parseUtmpx(inst instance.Instance) ([][]string, error) {
 	values := [][]string{}
 	count := 0
 
-	utmpxFilepaths := util.Multiglob(filepathsUtmpx, inst.GetTargetPath())
+	utmpxFilepaths := inst.GetArtifactPaths("MacOSUtmpx")
 	if len(utmpxFilepaths) == 0 {
 		return [][]string{}, errors.New("no UTMPX files were found")
 	}
@@ -108,73 +119,23 @@ func (m MacUtmpxModule) parseUtmpx(inst instance.Instance) ([][]string, error) {
 		var valmap = make(map[string]string)
 		valmap = util.InitializeMapToEmptyString(valmap, header)
 
-		// Open utmpx file
-		f, err := os.Open(path)
-		if err != nil {
-			zap.L().Error(fmt.Sprintf("could not open '%s': %s", path, err.Error()))
-			continue
-		}
-		defer f.Close()
-
-		// https://opensource.apple.com/source/Libc/Libc-1158.50.2/include/NetBSD/utmpx.h.auto.html
-		// https://github.com/libyal/dtformats/blob/master/documentation/Utmp%20login%20records%20format.asciidoc
-		type utmpxData struct {
-			LoginName [256]uint8
-			ID        [4]uint8
-			TtyName   [32]uint8
-			Pid       int32
-			LogonType int16
-			Padding   [2]byte
-			Epoch     int32
-			Usec      int32
-			Hostname  [256]uint8
-			Reserved  [64]byte
-		}
-
-		// Read header of utmpx file, head of reader will point to next segment after this
-		headerBuff := make([]byte, utmpxLineSize)
-		headerBytesRead, err := f.Read(headerBuff)
+		records, err := utmpx.ParseFile(path)
 		if err != nil {
-			zap.L().Error(fmt.Sprintf("failed to read utmpx header from '%s': %s", path, err.Error()))
+			zap.L().Error(fmt.Sprintf("could not parse '%s': %s", path, err.Error()))
 			continue
 		}
-		if headerBytesRead != utmpxLineSize {
-			zap.L().Error(fmt.Sprintf("utmpx header smaller than expected- expected '%d' got '%d'", utmpxLineSize, headerBytesRead))
-			continue
-		}
-		// fmt.Println(string(headerBuff[:])) // Debug print header - uncomment
-
-		for {
-			utmpxBuff := make([]byte, utmpxLineSize)
-			utmpxBuffRead, err := io.ReadFull(f, utmpxBuff)
-			if err != nil {
-				break // Done reading
-			}
-			if utmpxBuffRead != utmpxLineSize {
-				break // Done reading
-			}
-
-			utmpxEntry := utmpxData{}
-			err = binary.Read(bytes.NewReader(utmpxBuff), binary.LittleEndian, &utmpxEntry)
-			if err == io.EOF {
-				break
-			}
-
-			timestampNum := utmpxEntry.Epoch + utmpxEntry.Usec
-			timestamp := time.Unix(int64(timestampNum), 0)
-
-			hostName := util.GetPrintableString(string(utmpxEntry.Hostname[:])) // vars still have non-printable characters due to unpacking
-
+		for _, record := range records {
+			hostName := record.Hostname
 			if hostName == "" {
 				hostName = "localhost"
 			}
 
-			valmap["login_name"] = util.GetPrintableString(string(utmpxEntry.LoginName[:]))
-			valmap["id"] = util.GetPrintableString(string(utmpxEntry.ID[:]))
-			valmap["tty_name"] = util.GetPrintableString(string(utmpxEntry.TtyName[:]))
-			valmap["pid"] = strconv.FormatInt(int64(utmpxEntry.Pid), 10)
-			valmap["logon_type"] = strconv.FormatInt(int64(utmpxEntry.LogonType), 10)
-			valmap["timestamp"] = timestamp.UTC().Format(time.RFC3339)
+			valmap["login_name"] = record.LoginName
+			valmap["id"] = record.ID
+			valmap["tty_name"] = record.TtyName
+			valmap["pid"] = strconv.FormatInt(int64(record.Pid), 10)
+			valmap["logon_type"] = strconv.FormatInt(int64(record.LogonType), 10)
+			valmap["timestamp"] = timeconv.Format(record.Time)
 			valmap["hostname"] = hostName
 
 			entry, err := util.UnsafeEntryFromMap(valmap, header)
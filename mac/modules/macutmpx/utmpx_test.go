@@ -0,0 +1,11 @@
+package macutmpx
+
+import (
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestMacUtmpxModuleGolden(t *testing.T) {
+	moduletest.RunGolden(t, MacUtmpxModule{}, moduletest.Options{})
+}
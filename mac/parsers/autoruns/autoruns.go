@@ -0,0 +1,120 @@
+// Package autoruns reads the fields of the plists macOS uses to start programs automatically
+// (launchd jobs, login items, login restart apps, disabled launch items and kernel extensions)
+//
+// The functions take a decoded plist as returned by machelpers.DecodePlist and never fail, fields missing or of an
+// unexpected type are left empty
+package autoruns
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LaunchItem is a launchd job of a LaunchAgents or LaunchDaemons plist
+// Arguments are the ProgramArguments after the program itself
+type LaunchItem struct {
+	Label     string
+	Program   string
+	Arguments []string
+}
+
+// ParseLaunchItem returns the job of a launchd plist
+func ParseLaunchItem(plist map[string]interface{}) LaunchItem {
+	item := LaunchItem{}
+	if val, ok := plist["Label"].(string); ok {
+		item.Label = val
+	}
+	args := []string{}
+	if val, ok := plist["ProgramArguments"].([]interface{}); ok {
+		for _, arg := range val {
+			args = append(args, fmt.Sprint(arg))
+		}
+	}
+	if val, ok := plist["Program"].(string); ok {
+		item.Program = val
+	}
+	if len(args) > 1 {
+		item.Arguments = args[1:]
+	}
+	return item
+}
+
+// RestartApp is an application reopened at login (com.apple.loginwindow.<UUID>.plist)
+type RestartApp struct {
+	BundleID string
+	Path     string
+}
+
+// ParseLoginRestartApps returns the TALAppsToRelaunchAtLogin of a loginwindow plist
+func ParseLoginRestartApps(plist map[string]interface{}) []RestartApp {
+	apps := []RestartApp{}
+	val, ok := plist["TALAppsToRelaunchAtLogin"].([]interface{})
+	if !ok {
+		return apps
+	}
+	for _, i := range val {
+		app, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		apps = append(apps, RestartApp{BundleID: fmt.Sprint(app["BundleId"]), Path: fmt.Sprint(app["Path"])})
+	}
+	return apps
+}
+
+// LoginItem is an item of the login items list (com.apple.loginitems.plist, 10.12 and earlier)
+// Data is the Bookmark or legacy Alias record of the item, decoded with machelpers.DecodeBookmark
+type LoginItem struct {
+	Name string
+	Data []byte
+}
+
+// ParseLoginItems returns the SessionItems of a loginitems plist
+func ParseLoginItems(plist map[string]interface{}) []LoginItem {
+	items := []LoginItem{}
+	session, ok := plist["SessionItems"].(map[string]interface{})
+	if !ok {
+		return items
+	}
+	list, ok := session["CustomListItems"].([]interface{})
+	if !ok {
+		return items
+	}
+	for _, i := range list {
+		li, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		item := LoginItem{}
+		item.Name, _ = li["Name"].(string)
+		for _, key := range []string{"Bookmark", "Alias"} {
+			if data, ok := li[key].([]byte); ok {
+				item.Data = data
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// DisabledItems returns the sorted labels set to false in a launchd disabled.<UID>.plist or loginitems overrides
+func DisabledItems(plist map[string]interface{}) []string {
+	labels := []string{}
+	for k, v := range plist {
+		if val, ok := v.(bool); ok && !val {
+			labels = append(labels, k)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// KextName returns the CFBundleName of a kernel extension Info.plist
+func KextName(plist map[string]interface{}) string {
+	if val, ok := plist["CFBundleName"].(string); ok {
+		return strings.TrimSpace(val)
+	}
+	return ""
+}
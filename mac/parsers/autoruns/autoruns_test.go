@@ -0,0 +1,68 @@
+package autoruns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLaunchItem(t *testing.T) {
+	got := ParseLaunchItem(map[string]interface{}{
+		"Label":            "com.example.agent",
+		"ProgramArguments": []interface{}{"/usr/local/bin/agent", "--daemon", uint64(5)},
+	})
+	want := LaunchItem{Label: "com.example.agent", Arguments: []string{"--daemon", "5"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLaunchItem() = %+v, want %+v", got, want)
+	}
+	if got := ParseLaunchItem(map[string]interface{}{"Label": 1, "Program": "/bin/sh"}); !reflect.DeepEqual(got, LaunchItem{Program: "/bin/sh"}) {
+		t.Errorf("ParseLaunchItem() = %+v", got)
+	}
+}
+
+func TestParseLoginRestartApps(t *testing.T) {
+	got := ParseLoginRestartApps(map[string]interface{}{
+		"TALAppsToRelaunchAtLogin": []interface{}{
+			map[string]interface{}{"BundleId": "com.apple.Terminal", "Path": "/System/Applications/Utilities/Terminal.app"},
+			"not a dictionary",
+		},
+	})
+	want := []RestartApp{{BundleID: "com.apple.Terminal", Path: "/System/Applications/Utilities/Terminal.app"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLoginRestartApps() = %+v, want %+v", got, want)
+	}
+	if got := ParseLoginRestartApps(map[string]interface{}{"TALAppsToRelaunchAtLogin": "x"}); len(got) != 0 {
+		t.Errorf("ParseLoginRestartApps() = %+v", got)
+	}
+}
+
+func TestParseLoginItems(t *testing.T) {
+	got := ParseLoginItems(map[string]interface{}{
+		"SessionItems": map[string]interface{}{
+			"CustomListItems": []interface{}{
+				map[string]interface{}{"Name": "Updater", "Alias": []byte("alis"), "Flags": uint64(0)},
+				map[string]interface{}{"Name": "Helper", "Bookmark": []byte("book")},
+				"not a dictionary",
+			},
+		},
+	})
+	want := []LoginItem{{Name: "Updater", Data: []byte("alis")}, {Name: "Helper", Data: []byte("book")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLoginItems() = %+v, want %+v", got, want)
+	}
+	if got := ParseLoginItems(map[string]interface{}{"SessionItems": "x"}); len(got) != 0 {
+		t.Errorf("ParseLoginItems() = %+v", got)
+	}
+}
+
+func TestDisabledItems(t *testing.T) {
+	got := DisabledItems(map[string]interface{}{"com.b": false, "com.a": false, "com.c": true, "com.d": "false"})
+	if !reflect.DeepEqual(got, []string{"com.a", "com.b"}) {
+		t.Errorf("DisabledItems() = %v", got)
+	}
+}
+
+func TestKextName(t *testing.T) {
+	if got := KextName(map[string]interface{}{"CFBundleName": " Example "}); got != "Example" {
+		t.Errorf("KextName() = %q", got)
+	}
+}
@@ -0,0 +1,280 @@
+// Package dsstore parses the .DS_Store files Finder writes in the folders it displays
+//
+// A .DS_Store is a Buddy Allocator file whose "DSDB" block is the root of a B-tree of records, each naming a file of
+// the folder and one of its properties (icon position, comment, put back location of trashed files, sizes...).
+// Records of files that were moved or deleted stay until Finder rewrites the folder
+//
+// https://metacpan.org/dist/Mac-Finder-DSStore/view/DSStoreFormat.pod
+// https://wiki.mozilla.org/DS_Store_File_Format
+package dsstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+	"unicode/utf16"
+
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/util/timeconv"
+)
+
+// Magic is the Buddy Allocator signature following the leading 0x00000001 of the file
+const Magic = "Bud1"
+
+// headerOffset is the size of the leading 0x00000001, block offsets are relative to the end of it
+const headerOffset = 4
+
+// Record is a property of a file of the folder
+// Code names the property (ex. Iloc icon location, cmmt comment, ptbL and ptbN put back location and name, modD
+// modification date, logS logical size) and Type how Value is stored: long, shor (uint32), bool (bool), type (four
+// character string), ustr (string), blob ([]byte), comp (uint64) and dutc (time.Time)
+type Record struct {
+	Filename string
+	Code     string
+	Type     string
+	Value    interface{}
+}
+
+// ParseFile returns the records of the .DS_Store fp
+func ParseFile(fp string) ([]Record, error) {
+	f, err := throttle.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse returns the records of a .DS_Store in B-tree order, which sorts them by filename
+func Parse(b []byte) ([]Record, error) {
+	if len(b) < headerOffset+32 || binary.BigEndian.Uint32(b) != 1 || string(b[4:8]) != Magic {
+		return nil, errors.New("not a .DS_Store, missing Bud1 header")
+	}
+	a := allocator{data: b[headerOffset:]}
+	rootOffset := binary.BigEndian.Uint32(b[8:])
+	rootSize := binary.BigEndian.Uint32(b[12:])
+	if binary.BigEndian.Uint32(b[16:]) != rootOffset {
+		return nil, errors.New("corrupt .DS_Store header, root block offsets differ")
+	}
+	root, err := a.slice(rootOffset, rootSize)
+	if err != nil {
+		return nil, fmt.Errorf("could not read root block: %s", err.Error())
+	}
+	if err := a.readRoot(root); err != nil {
+		return nil, err
+	}
+
+	id, ok := a.directory["DSDB"]
+	if !ok {
+		return nil, errors.New(".DS_Store has no DSDB directory")
+	}
+	db, err := a.block(id)
+	if err != nil {
+		return nil, fmt.Errorf("could not read DSDB block: %s", err.Error())
+	}
+	if len(db) < 20 {
+		return nil, errors.New("DSDB block too short")
+	}
+	// the header holds the root node, the number of internal levels, records, nodes and the page size
+	levels := int(binary.BigEndian.Uint32(db[4:]))
+	records := []Record{}
+	if err := a.walk(binary.BigEndian.Uint32(db), levels+1, map[uint32]bool{}, &records); err != nil {
+		return records, err
+	}
+	return records, nil
+}
+
+// allocator holds the file after its leading 0x00000001 with the block addresses and directory of its root block
+type allocator struct {
+	data      []byte
+	addresses []uint32
+	directory map[string]uint32
+}
+
+// slice returns size bytes at offset
+func (a *allocator) slice(offset, size uint32) ([]byte, error) {
+	end := uint64(offset) + uint64(size)
+	if end > uint64(len(a.data)) {
+		return nil, fmt.Errorf("block at %#x of %d bytes is out of the file", offset, size)
+	}
+	return a.data[offset:end], nil
+}
+
+// block returns the block id, its address holds the offset in its upper bits and log2 of its size in the lower 5
+func (a *allocator) block(id uint32) ([]byte, error) {
+	if int(id) >= len(a.addresses) {
+		return nil, fmt.Errorf("block %d is not allocated", id)
+	}
+	address := a.addresses[id]
+	return a.slice(address&^0x1f, 1<<(address&0x1f))
+}
+
+// readRoot reads the block addresses and the directory of the root block, the free lists that follow are ignored
+func (a *allocator) readRoot(root []byte) error {
+	r := bytes.NewReader(root)
+	var count, unknown uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return errors.New("root block too short")
+	}
+	binary.Read(r, binary.BigEndian, &unknown)
+	// the address table is padded to a multiple of 256 entries
+	if uint64(count) > uint64(len(root))/4 {
+		return fmt.Errorf("root block lists %d blocks", count)
+	}
+	a.addresses = make([]uint32, count)
+	if err := binary.Read(r, binary.BigEndian, a.addresses); err != nil {
+		return errors.New("root block address table truncated")
+	}
+	if padding := (256 - count%256) % 256; padding > 0 {
+		if _, err := r.Seek(int64(padding)*4, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+
+	var entries uint32
+	if err := binary.Read(r, binary.BigEndian, &entries); err != nil {
+		return errors.New("root block directory truncated")
+	}
+	a.directory = map[string]uint32{}
+	for i := uint32(0); i < entries; i++ {
+		n, err := r.ReadByte()
+		if err != nil {
+			return errors.New("root block directory truncated")
+		}
+		name := make([]byte, n)
+		var id uint32
+		if _, err := io.ReadFull(r, name); err != nil {
+			return errors.New("root block directory truncated")
+		}
+		if err := binary.Read(r, binary.BigEndian, &id); err != nil {
+			return errors.New("root block directory truncated")
+		}
+		a.directory[string(name)] = id
+	}
+	return nil
+}
+
+// walk appends the records of the node id and its children in order, levels is the number of levels left including
+// the node and seen guards against nodes linked more than once
+func (a *allocator) walk(id uint32, levels int, seen map[uint32]bool, records *[]Record) error {
+	if levels <= 0 || seen[id] {
+		return fmt.Errorf("B-tree node %d is linked more than once or deeper than the tree", id)
+	}
+	seen[id] = true
+	node, err := a.block(id)
+	if err != nil {
+		return err
+	}
+	if len(node) < 8 {
+		return fmt.Errorf("B-tree node %d too short", id)
+	}
+	// P is 0 in leaves, internal nodes hold (child, record) pairs and P is their rightmost child
+	p := binary.BigEndian.Uint32(node)
+	count := binary.BigEndian.Uint32(node[4:])
+	offset := 8
+	for i := uint32(0); i < count; i++ {
+		if p != 0 {
+			if offset+4 > len(node) {
+				return fmt.Errorf("B-tree node %d truncated", id)
+			}
+			if err := a.walk(binary.BigEndian.Uint32(node[offset:]), levels-1, seen, records); err != nil {
+				return err
+			}
+			offset += 4
+		}
+		record, n, err := parseRecord(node[offset:])
+		if err != nil {
+			return fmt.Errorf("B-tree node %d record %d: %s", id, i, err.Error())
+		}
+		*records = append(*records, record)
+		offset += n
+	}
+	if p != 0 {
+		return a.walk(p, levels-1, seen, records)
+	}
+	return nil
+}
+
+// parseRecord returns the record at the start of b and its size
+func parseRecord(b []byte) (Record, int, error) {
+	name, offset, err := utf16String(b)
+	if err != nil {
+		return Record{}, 0, err
+	}
+	if offset+8 > len(b) {
+		return Record{}, 0, errors.New("record truncated")
+	}
+	record := Record{Filename: name, Code: string(b[offset : offset+4]), Type: string(b[offset+4 : offset+8])}
+	offset += 8
+	p := b[offset:]
+	n := 0
+	switch record.Type {
+	case "long", "shor":
+		// shor values are padded to 4 bytes as well
+		if len(p) < 4 {
+			return Record{}, 0, errors.New("record value truncated")
+		}
+		record.Value, n = binary.BigEndian.Uint32(p), 4
+	case "bool":
+		if len(p) < 1 {
+			return Record{}, 0, errors.New("record value truncated")
+		}
+		record.Value, n = p[0] != 0, 1
+	case "type":
+		if len(p) < 4 {
+			return Record{}, 0, errors.New("record value truncated")
+		}
+		record.Value, n = string(p[:4]), 4
+	case "comp":
+		if len(p) < 8 {
+			return Record{}, 0, errors.New("record value truncated")
+		}
+		record.Value, n = binary.BigEndian.Uint64(p), 8
+	case "dutc":
+		// 1/65536 seconds since 1904-01-01
+		if len(p) < 8 {
+			return Record{}, 0, errors.New("record value truncated")
+		}
+		ticks := binary.BigEndian.Uint64(p)
+		record.Value, n = timeconv.FromHFSPlus(uint32(ticks>>16)).Add(time.Duration(ticks&0xffff)*time.Second/65536), 8
+	case "blob":
+		if len(p) < 4 || uint64(binary.BigEndian.Uint32(p)) > uint64(len(p)-4) {
+			return Record{}, 0, errors.New("record value truncated")
+		}
+		size := int(binary.BigEndian.Uint32(p))
+		record.Value, n = append([]byte{}, p[4:4+size]...), 4+size
+	case "ustr":
+		s, size, err := utf16String(p)
+		if err != nil {
+			return Record{}, 0, err
+		}
+		record.Value, n = s, size
+	default:
+		return Record{}, 0, fmt.Errorf("unknown record type %q", record.Type)
+	}
+	return record, offset + n, nil
+}
+
+// utf16String returns a character count prefixed UTF-16BE string and its size
+func utf16String(b []byte) (string, int, error) {
+	if len(b) < 4 {
+		return "", 0, errors.New("string truncated")
+	}
+	count := uint64(binary.BigEndian.Uint32(b))
+	if 4+count*2 > uint64(len(b)) {
+		return "", 0, errors.New("string truncated")
+	}
+	units := make([]uint16, count)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[4+i*2:])
+	}
+	return string(utf16.Decode(units)), 4 + int(count)*2, nil
+}
@@ -0,0 +1,47 @@
+package dsstore
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/DS_Store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iloc := func(x, y uint32) []byte {
+		b := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0}
+		binary.BigEndian.PutUint32(b, x)
+		binary.BigEndian.PutUint32(b[4:], y)
+		return b
+	}
+	// the middle record is held by the internal node, between the records of its two leaves
+	want := []Record{
+		{".", "vSrn", "long", uint32(1)},
+		{"notes.txt", "cmmt", "ustr", "call back about the wire transfer"},
+		{"notes.txt", "Iloc", "blob", iloc(64, 48)},
+		{"plan.docx", "Iloc", "blob", iloc(160, 48)},
+		{"plan.docx", "logS", "comp", uint64(24576)},
+		{"plan.docx", "modD", "dutc", time.Date(2020, 7, 30, 12, 0, 0, 0, time.UTC)},
+		{"secret.zip", "Iloc", "blob", iloc(256, 48)},
+		{"secret.zip", "ph1S", "comp", uint64(1048576)},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Parse() = %+v, want %+v", records, want)
+	}
+
+	if _, err := Parse(b[:100]); err == nil {
+		t.Error("Parse() of a truncated .DS_Store succeeded")
+	}
+	if _, err := Parse([]byte("not a .DS_Store file at all, just some text")); err == nil {
+		t.Error("Parse() of garbage succeeded")
+	}
+}
@@ -0,0 +1,107 @@
+// Package imessage decodes the values of the Messages database (~/Library/Messages/chat.db) that are not plain columns
+//
+// Messages keeps the text of a message in message.text and, with its formatting, in message.attributedBody. Since
+// macOS 13 text is often only in attributedBody, an NSAttributedString archived by NSArchiver (a "streamtyped"
+// typedstream) or, in some versions and for some message kinds, by NSKeyedArchiver
+package imessage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anthonybm/Orion/util/machelpers"
+)
+
+// cocoaUnixDelta is the Unix time of the Cocoa epoch, 2001-01-01
+const cocoaUnixDelta = 978307200
+
+// nanosecondThreshold separates dates in Cocoa seconds (before 10.13) from dates in Cocoa nanoseconds
+const nanosecondThreshold = 100000000000
+
+// MessageTime converts a date of the message and attachment tables, Cocoa seconds before 10.13 and Cocoa nanoseconds
+// after, 0 returns the zero time
+func MessageTime(v int64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	if v > nanosecondThreshold || v < -nanosecondThreshold {
+		return time.Unix(cocoaUnixDelta, v).UTC()
+	}
+	return time.Unix(cocoaUnixDelta+v, 0).UTC()
+}
+
+// typedStreamMagic starts NSArchiver archives, after a version byte and the length of the signature
+var typedStreamMagic = []byte("streamtyped")
+
+// AttributedBodyText returns the string of an archived NSAttributedString (message.attributedBody)
+func AttributedBodyText(b []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(b, []byte("bplist")):
+		return keyedArchiveText(b)
+	case len(b) > 2 && bytes.HasPrefix(b[2:], typedStreamMagic):
+		return typedStreamText(b)
+	}
+	return "", errors.New("attributedBody is neither a typedstream nor a keyed archive")
+}
+
+// keyedArchiveText returns the NSString of the root NSAttributedString of an NSKeyedArchiver plist
+func keyedArchiveText(b []byte) (string, error) {
+	objects, err := machelpers.UnarchiveNSKeyedArchiverBytes(b)
+	if err != nil {
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", errors.New("keyed archive has no root object")
+	}
+	switch root := objects[0].(type) {
+	case string:
+		return root, nil
+	case map[string]interface{}:
+		if s, ok := root["NSString"].(string); ok {
+			return s, nil
+		}
+	}
+	return "", errors.New("keyed archive root is not an NSAttributedString")
+}
+
+// typedStreamText returns the first NSString of a typedstream, its bytes are stored as a C string ('+') with a length
+// prefix of one byte, or 0x81 and a 16 bit or 0x82 and a 32 bit little endian length
+func typedStreamText(b []byte) (string, error) {
+	start := -1
+	for _, class := range []string{"NSString", "NSMutableString"} {
+		if i := bytes.Index(b, []byte(class)); i >= 0 && (start < 0 || i < start) {
+			start = i + len(class)
+		}
+	}
+	if start < 0 {
+		return "", errors.New("typedstream holds no NSString")
+	}
+	i := bytes.Index(b[start:], []byte{0x84, 0x01, '+'})
+	if i < 0 {
+		return "", errors.New("typedstream NSString has no bytes")
+	}
+	p := b[start+i+3:]
+	if len(p) == 0 {
+		return "", errors.New("typedstream NSString truncated")
+	}
+	length, n := uint64(p[0]), 1
+	switch p[0] {
+	case 0x81:
+		if len(p) < 3 {
+			return "", errors.New("typedstream NSString truncated")
+		}
+		length, n = uint64(binary.LittleEndian.Uint16(p[1:])), 3
+	case 0x82:
+		if len(p) < 5 {
+			return "", errors.New("typedstream NSString truncated")
+		}
+		length, n = uint64(binary.LittleEndian.Uint32(p[1:])), 5
+	}
+	if uint64(n)+length > uint64(len(p)) {
+		return "", fmt.Errorf("typedstream NSString of %d bytes truncated", length)
+	}
+	return string(p[n : uint64(n)+length]), nil
+}
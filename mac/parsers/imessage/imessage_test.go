@@ -0,0 +1,67 @@
+package imessage
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// typedStream archives an NSAttributedString of s as NSArchiver does
+func typedStream(class, s string) []byte {
+	b := []byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x12NSAttributedString\x00\x84\x84\x08NSObject\x00\x85\x92\x84\x84\x84")
+	b = append(b, byte(len(class)))
+	b = append(b, class...)
+	b = append(b, 0x01, 0x94, 0x84, 0x01, '+')
+	if len(s) < 0x80 {
+		b = append(b, byte(len(s)))
+	} else {
+		b = append(b, 0x81, 0, 0)
+		binary.LittleEndian.PutUint16(b[len(b)-2:], uint16(len(s)))
+	}
+	b = append(b, s...)
+	return append(b, 0x86, 0x84, 0x02, 'i', 'I', 0x01)
+}
+
+func TestAttributedBodyText(t *testing.T) {
+	long := strings.Repeat("wire the funds today ", 10)
+	for _, tt := range []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"short", typedStream("NSString", "Are you free at 3?"), "Are you free at 3?"},
+		{"long", typedStream("NSString", long), long},
+		{"mutable", typedStream("NSMutableString", "ok"), "ok"},
+	} {
+		got, err := AttributedBodyText(tt.b)
+		if err != nil {
+			t.Errorf("%s: AttributedBodyText() error %s", tt.name, err.Error())
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: AttributedBodyText() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	truncated := typedStream("NSString", long)
+	if _, err := AttributedBodyText(truncated[:len(truncated)-100]); err == nil {
+		t.Error("AttributedBodyText() of a truncated typedstream succeeded")
+	}
+	if _, err := AttributedBodyText([]byte("plain text")); err == nil {
+		t.Error("AttributedBodyText() of garbage succeeded")
+	}
+}
+
+func TestMessageTime(t *testing.T) {
+	want := time.Date(2020, 8, 1, 9, 0, 0, 0, time.UTC)
+	if got := MessageTime(617965200); !got.Equal(want) {
+		t.Errorf("MessageTime(seconds) = %s, want %s", got, want)
+	}
+	if got := MessageTime(617965200000000000); !got.Equal(want) {
+		t.Errorf("MessageTime(nanoseconds) = %s, want %s", got, want)
+	}
+	if got := MessageTime(0); !got.IsZero() {
+		t.Errorf("MessageTime(0) = %s, want zero time", got)
+	}
+}
@@ -0,0 +1,292 @@
+// Package netconfig parses the network configuration plists of /Library/Preferences/SystemConfiguration
+//
+// Known Wi-Fi networks come from com.apple.airport.preferences.plist and network interfaces from
+// NetworkInterfaces.plist, both share the same fields which are formatted to strings as they are output. From 11 known
+// networks are kept in /Library/Preferences/com.apple.wifi.known-networks.plist, whose keys are mapped to the same
+// fields. DHCP leases, hosts and resolver configuration files are parsed too
+package netconfig
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthonybm/Orion/util/timeconv"
+)
+
+const (
+	// AirportPreferencesPlist is the path of the Wi-Fi preferences under the target
+	AirportPreferencesPlist = "Library/Preferences/SystemConfiguration/com.apple.airport.preferences.plist"
+	// NetworkInterfacesPlist is the path of the network interfaces under the target
+	NetworkInterfacesPlist = "Library/Preferences/SystemConfiguration/NetworkInterfaces.plist"
+)
+
+// Fields are the keys read from known networks and interfaces, in output order
+var Fields = []string{
+	"AddedAt",
+	"Captive",
+	"CaptiveBypass",
+	"Disabled",
+	"HiddenNetwork",
+	"LastAutoJoinAt",
+	"LastManualJoinAt",
+	"NetworkWasCaptive",
+	"Passpoint",
+	"PersonalHotspot",
+	"PossiblyHiddenNetwork",
+	"RoamingProfileType",
+	"SPRoaming",
+	"SSID",
+	"SSIDString",
+	"SecurityType",
+	"ShareMode",
+	"SystemMode",
+	"TemporarilyDisabled",
+	"UpdatedAt",
+	"UserRole",
+}
+
+// knownNetworksFields maps the keys of com.apple.wifi.known-networks.plist to the Fields of the airport preferences
+var knownNetworksFields = map[string]string{
+	"JoinedBySystemAt":       "LastAutoJoinAt",
+	"JoinedByUserAt":         "LastManualJoinAt",
+	"Hidden":                 "HiddenNetwork",
+	"SupportedSecurityTypes": "SecurityType",
+	"IsPersonalHotspot":      "PersonalHotspot",
+}
+
+// Entry is a known network or an interface
+// Values holds the formatted Fields present in the plist, BSDName is only set for interfaces and BSS for known networks
+// of com.apple.wifi.known-networks.plist
+type Entry struct {
+	BSDName string
+	Values  map[string]string
+	BSS     []BSS
+}
+
+// BSS is an access point a known network was associated with
+type BSS struct {
+	BSSID            string
+	Channel          string
+	LastAssociatedAt string
+}
+
+// KnownNetworks returns the KnownNetworks of a decoded airport preferences plist, sorted by their key
+func KnownNetworks(data []map[string]interface{}) ([]Entry, error) {
+	entries := []Entry{}
+	for _, item := range data {
+		networks, ok := item["KnownNetworks"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(networks))
+		for k := range networks {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if network, ok := networks[k].(map[string]interface{}); ok {
+				entries = append(entries, Entry{Values: values(network)})
+			}
+		}
+		return entries, nil
+	}
+	return entries, errors.New("did not find a value for given key 'KnownNetworks'")
+}
+
+// WiFiKnownNetworks returns the networks of a decoded com.apple.wifi.known-networks.plist, keyed by
+// wifi.network.ssid.<SSID> and sorted by their key. Keys of __OSSpecific__ are read when the network does not have them
+func WiFiKnownNetworks(data []map[string]interface{}) []Entry {
+	entries := []Entry{}
+	for _, item := range data {
+		keys := make([]string, 0, len(item))
+		for k := range item {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			network, ok := item[k].(map[string]interface{})
+			if !ok || !strings.HasPrefix(k, "wifi.") {
+				continue
+			}
+			osSpecific, _ := network["__OSSpecific__"].(map[string]interface{})
+			merged := map[string]interface{}{}
+			for _, source := range []map[string]interface{}{osSpecific, network} {
+				for key, v := range source {
+					if field, ok := knownNetworksFields[key]; ok {
+						key = field
+					}
+					merged[key] = v
+				}
+			}
+			entry := Entry{Values: values(merged)}
+			// the SSID is data, it is only output as SSIDString
+			if ssid, ok := network["SSID"].([]byte); ok {
+				delete(entry.Values, "SSID")
+				entry.Values["SSIDString"] = string(ssid)
+			}
+			for _, source := range []map[string]interface{}{network, osSpecific} {
+				if list, ok := source["BSSList"].([]interface{}); ok {
+					entry.BSS = bssList(list)
+					break
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// bssList returns the access points of a BSSList
+func bssList(list []interface{}) []BSS {
+	bss := []BSS{}
+	for _, b := range list {
+		d, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		v := values(map[string]interface{}{"BSSID": d["BSSID"], "Channel": d["Channel"], "LastAssociatedAt": d["LastAssociatedAt"]}, "BSSID", "Channel", "LastAssociatedAt")
+		bss = append(bss, BSS{BSSID: v["BSSID"], Channel: v["Channel"], LastAssociatedAt: v["LastAssociatedAt"]})
+	}
+	return bss
+}
+
+// Interfaces returns the Interfaces of a decoded NetworkInterfaces plist
+func Interfaces(data []map[string]interface{}) ([]Entry, error) {
+	entries := []Entry{}
+	for _, item := range data {
+		interfaces, ok := item["Interfaces"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, i := range interfaces {
+			iface, ok := i.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			entry := Entry{Values: values(iface)}
+			if bsdName, ok := iface["BSD Name"]; ok {
+				entry.BSDName = fmt.Sprint(bsdName)
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+	return entries, errors.New("did not find a value for given key 'Interfaces'")
+}
+
+// values formats the Fields, or fields when given, present in entry, values of an unexpected type are kept as printed
+// by fmt
+func values(entry map[string]interface{}, fields ...string) map[string]string {
+	if len(fields) == 0 {
+		fields = Fields
+	}
+	valmap := make(map[string]string)
+	for _, field := range fields {
+		val, ok := entry[field]
+		if !ok || val == nil {
+			continue
+		}
+		switch v := val.(type) {
+		case time.Time:
+			valmap[field] = timeconv.Format(v)
+		case bool:
+			valmap[field] = strconv.FormatBool(v)
+		case uint64:
+			valmap[field] = strconv.FormatUint(v, 10)
+		default:
+			valmap[field] = fmt.Sprint(v)
+		}
+	}
+	return valmap
+}
+
+// Lease is a DHCP lease of /private/var/db/dhcpclient/leases
+type Lease struct {
+	IPAddress string
+	RouterIP  string
+	RouterMAC string
+	SSID      string
+	Start     time.Time
+	Length    uint64
+}
+
+// DHCPLease returns the lease of a decoded lease plist
+func DHCPLease(data []map[string]interface{}) (Lease, error) {
+	for _, item := range data {
+		lease := Lease{}
+		lease.IPAddress, _ = item["IPAddress"].(string)
+		lease.RouterIP, _ = item["RouterIPAddress"].(string)
+		if mac, ok := item["RouterHardwareAddress"].([]byte); ok {
+			lease.RouterMAC = net.HardwareAddr(mac).String()
+		}
+		lease.SSID, _ = item["SSID"].(string)
+		lease.Start, _ = item["LeaseStartDate"].(time.Time)
+		lease.Length, _ = item["LeaseLength"].(uint64)
+		return lease, nil
+	}
+	return Lease{}, errors.New("lease plist is empty")
+}
+
+// Host is an entry of a hosts file, mapping an address to hostnames
+type Host struct {
+	Line    int
+	Address string
+	Names   []string
+}
+
+// ParseHosts returns the entries of a hosts file, comments are skipped
+func ParseHosts(b []byte) []Host {
+	hosts := []Host{}
+	for _, l := range lines(b) {
+		if len(l.fields) < 2 {
+			continue
+		}
+		hosts = append(hosts, Host{Line: l.n, Address: l.fields[0], Names: l.fields[1:]})
+	}
+	return hosts
+}
+
+// Directive is a line of resolv.conf or of a /etc/resolver file, such as nameserver or search
+type Directive struct {
+	Line  int
+	Name  string
+	Value string
+}
+
+// ParseResolver returns the directives of a resolver configuration file, comments are skipped
+func ParseResolver(b []byte) []Directive {
+	directives := []Directive{}
+	for _, l := range lines(b) {
+		directives = append(directives, Directive{Line: l.n, Name: l.fields[0], Value: strings.Join(l.fields[1:], " ")})
+	}
+	return directives
+}
+
+// line is a line of a configuration file split in fields
+type line struct {
+	n      int
+	fields []string
+}
+
+// lines returns the lines of b with their line number, without comments and blank lines
+func lines(b []byte) []line {
+	result := []line{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		if i := strings.IndexAny(text, "#;"); i >= 0 {
+			text = text[:i]
+		}
+		if fields := strings.Fields(text); len(fields) > 0 {
+			result = append(result, line{n: n, fields: fields})
+		}
+	}
+	return result
+}
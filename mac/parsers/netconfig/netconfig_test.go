@@ -0,0 +1,92 @@
+package netconfig
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKnownNetworks(t *testing.T) {
+	added := time.Date(2020, 8, 1, 9, 0, 0, 0, time.UTC)
+	data := []map[string]interface{}{{
+		"KnownNetworks": map[string]interface{}{
+			"wifi.ssid.<62>": map[string]interface{}{"SSIDString": "b", "Disabled": true, "UserRole": uint64(2)},
+			"wifi.ssid.<61>": map[string]interface{}{"SSIDString": "a", "AddedAt": added, "Unknown": "x"},
+			"wifi.ssid.<63>": "not a dictionary",
+		},
+	}}
+	got, err := KnownNetworks(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Values: map[string]string{"SSIDString": "a", "AddedAt": "2020-08-01T09:00:00Z"}},
+		{Values: map[string]string{"SSIDString": "b", "Disabled": "true", "UserRole": "2"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("KnownNetworks() = %+v, want %+v", got, want)
+	}
+	if _, err := KnownNetworks([]map[string]interface{}{{}}); err == nil {
+		t.Error("KnownNetworks() without KnownNetworks did not fail")
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	data := []map[string]interface{}{{
+		"Interfaces": []interface{}{
+			map[string]interface{}{"BSD Name": "en0", "Active": true},
+			map[string]interface{}{"BSD Name": "en1", "Disabled": "yes"},
+		},
+	}}
+	got, err := Interfaces(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{BSDName: "en0", Values: map[string]string{}},
+		{BSDName: "en1", Values: map[string]string{"Disabled": "yes"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Interfaces() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWiFiKnownNetworks(t *testing.T) {
+	joined := time.Date(2020, 8, 1, 9, 0, 0, 0, time.UTC)
+	data := []map[string]interface{}{{
+		"wifi.network.ssid.Cafe": map[string]interface{}{
+			"SSID":                   []byte("Cafe"),
+			"SupportedSecurityTypes": "Open",
+			"JoinedByUserAt":         joined,
+			"__OSSpecific__":         map[string]interface{}{"TemporarilyDisabled": false, "BSSList": []interface{}{map[string]interface{}{"BSSID": "a4:83:e7:00:00:01", "Channel": uint64(6), "LastAssociatedAt": joined}}},
+		},
+		"Version": uint64(1),
+	}}
+	got := WiFiKnownNetworks(data)
+	want := []Entry{{
+		Values: map[string]string{"SSIDString": "Cafe", "SecurityType": "Open", "LastManualJoinAt": "2020-08-01T09:00:00Z", "TemporarilyDisabled": "false"},
+		BSS:    []BSS{{BSSID: "a4:83:e7:00:00:01", Channel: "6", LastAssociatedAt: "2020-08-01T09:00:00Z"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WiFiKnownNetworks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseHostsAndResolver(t *testing.T) {
+	hosts := ParseHosts([]byte("##\n# Host Database\n127.0.0.1\tlocalhost\n\n10.0.0.5 files.corp.example.com files # file server\n"))
+	wantHosts := []Host{
+		{Line: 3, Address: "127.0.0.1", Names: []string{"localhost"}},
+		{Line: 5, Address: "10.0.0.5", Names: []string{"files.corp.example.com", "files"}},
+	}
+	if !reflect.DeepEqual(hosts, wantHosts) {
+		t.Errorf("ParseHosts() = %+v, want %+v", hosts, wantHosts)
+	}
+	directives := ParseResolver([]byte("; generated by DHCP\nnameserver 10.0.0.1\nsearch corp.example.com example.com\n"))
+	wantDirectives := []Directive{
+		{Line: 2, Name: "nameserver", Value: "10.0.0.1"},
+		{Line: 3, Name: "search", Value: "corp.example.com example.com"},
+	}
+	if !reflect.DeepEqual(directives, wantDirectives) {
+		t.Errorf("ParseResolver() = %+v, want %+v", directives, wantDirectives)
+	}
+}
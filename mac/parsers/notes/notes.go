@@ -0,0 +1,92 @@
+// Package notes decodes the note bodies of the Notes database
+// (~/Library/Group Containers/group.com.apple.notes/NoteStore.sqlite)
+//
+// The body of a note (ZICNOTEDATA.ZDATA) is a gzipped protobuf NoteStoreProto whose document holds the note, the text
+// of the note is field 2 of the note and its formatting runs the fields that follow. Bodies of password protected
+// notes are encrypted and cannot be decoded
+//
+// https://github.com/threeplanetssoftware/apple_cloud_notes_parser/blob/master/proto/notestore.proto
+package notes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// protobuf field numbers of NoteStoreProto.document, Document.note and Note.note_text
+const (
+	fieldDocument = 2
+	fieldNote     = 3
+	fieldNoteText = 2
+)
+
+// gzipMagic starts gzip streams, bodies of iOS and older macOS versions may be stored uncompressed
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// NoteText returns the text of a note body
+func NoteText(b []byte) (string, error) {
+	if bytes.HasPrefix(b, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		b, err = ioutil.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("could not decompress note body: %s", err.Error())
+		}
+	}
+	for _, num := range []uint64{fieldDocument, fieldNote, fieldNoteText} {
+		var err error
+		b, err = field(b, num)
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(b), nil
+}
+
+// field returns the first length delimited field num of the protobuf message b, other fields are skipped
+func field(b []byte, num uint64) ([]byte, error) {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("invalid protobuf field key")
+		}
+		b = b[n:]
+		switch key & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return nil, errors.New("invalid protobuf varint")
+			}
+			b = b[n:]
+		case 1: // 64 bit
+			if len(b) < 8 {
+				return nil, errors.New("protobuf message truncated")
+			}
+			b = b[8:]
+		case 2: // length delimited
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return nil, errors.New("protobuf message truncated")
+			}
+			value := b[n : n+int(length)]
+			if key>>3 == num {
+				return value, nil
+			}
+			b = b[n+int(length):]
+		case 5: // 32 bit
+			if len(b) < 4 {
+				return nil, errors.New("protobuf message truncated")
+			}
+			b = b[4:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", key&7)
+		}
+	}
+	return nil, fmt.Errorf("protobuf field %d not found", num)
+}
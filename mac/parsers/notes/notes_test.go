@@ -0,0 +1,46 @@
+package notes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+)
+
+// message encodes length delimited fields, a varint field 1 is added first as Notes does for versions
+func message(fields map[uint64][]byte) []byte {
+	b := []byte{0x08, 0x01}
+	varint := make([]byte, binary.MaxVarintLen64)
+	for num, value := range fields {
+		b = append(b, varint[:binary.PutUvarint(varint, num<<3|2)]...)
+		b = append(b, varint[:binary.PutUvarint(varint, uint64(len(value)))]...)
+		b = append(b, value...)
+	}
+	return b
+}
+
+func TestNoteText(t *testing.T) {
+	note := message(map[uint64][]byte{fieldNoteText: []byte("Passwords\nbank: hunter2")})
+	body := message(map[uint64][]byte{fieldDocument: message(map[uint64][]byte{fieldNote: note})})
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(body)
+	w.Close()
+
+	for name, b := range map[string][]byte{"gzip": buf.Bytes(), "raw": body} {
+		got, err := NoteText(b)
+		if err != nil {
+			t.Fatalf("%s: NoteText() error %s", name, err.Error())
+		}
+		if got != "Passwords\nbank: hunter2" {
+			t.Errorf("%s: NoteText() = %q", name, got)
+		}
+	}
+
+	if _, err := NoteText(body[:len(body)-5]); err == nil {
+		t.Error("NoteText() of a truncated body succeeded")
+	}
+	if _, err := NoteText([]byte{0x1f, 0x8b, 0x08, 0x00}); err == nil {
+		t.Error("NoteText() of a truncated gzip stream succeeded")
+	}
+}
@@ -0,0 +1,53 @@
+// Package notifications decodes the records of the Notification Center database of 10.13 and later
+// (/private/var/folders/*/*/0/com.apple.notificationcenter/db2/db, moved to
+// ~/Library/Group Containers/group.com.apple.usernoted/db2/db in 15)
+//
+// Each row of the record table keeps the notification as a binary plist (record.data) with the bundle ID of the app,
+// the request (title, subtitle, body, category, identifier) and the date it was requested
+package notifications
+
+import (
+	"errors"
+	"time"
+
+	"github.com/anthonybm/Orion/util/machelpers"
+	"github.com/anthonybm/Orion/util/timeconv"
+)
+
+// Notification is the content of a record
+type Notification struct {
+	App        string
+	Title      string
+	Subtitle   string
+	Body       string
+	Category   string
+	Identifier string
+	Date       time.Time
+}
+
+// ParseRecord decodes the data plist of a record
+func ParseRecord(b []byte) (Notification, error) {
+	decoded, err := machelpers.DecodePlistBytes(b)
+	if err != nil {
+		return Notification{}, err
+	}
+	data, ok := decoded.(map[string]interface{})
+	if !ok {
+		return Notification{}, errors.New("notification record is not a dictionary")
+	}
+	n := Notification{}
+	n.App, _ = data["app"].(string)
+	if date, ok := data["date"]; ok {
+		n.Date, _ = timeconv.Convert(timeconv.Cocoa, date)
+	}
+	req, ok := data["req"].(map[string]interface{})
+	if !ok {
+		return n, nil
+	}
+	n.Title, _ = req["titl"].(string)
+	n.Subtitle, _ = req["subt"].(string)
+	n.Body, _ = req["body"].(string)
+	n.Category, _ = req["cate"].(string)
+	n.Identifier, _ = req["iden"].(string)
+	return n, nil
+}
@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"howett.net/plist"
+)
+
+func TestParseRecord(t *testing.T) {
+	b, err := plist.Marshal(map[string]interface{}{
+		"app":  "com.apple.MobileSMS",
+		"date": float64(617965200),
+		"req": map[string]interface{}{
+			"titl": "Alice",
+			"body": "Did you get the invoice?",
+			"cate": "MessageExtension",
+			"iden": "0A1B2C3D",
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseRecord(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Notification{
+		App:        "com.apple.MobileSMS",
+		Title:      "Alice",
+		Body:       "Did you get the invoice?",
+		Category:   "MessageExtension",
+		Identifier: "0A1B2C3D",
+		Date:       time.Date(2020, 8, 1, 9, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRecord() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseRecord([]byte("not a plist")); err == nil {
+		t.Error("ParseRecord() of garbage succeeded")
+	}
+}
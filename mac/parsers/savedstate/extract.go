@@ -0,0 +1,213 @@
+package savedstate
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anthonybm/Orion/util/machelpers"
+)
+
+// Value is a leaf of the decoded objects of a block, Path joins the dictionary keys and array indexes leading to it
+type Value struct {
+	Path  string
+	Value string
+}
+
+// Flatten returns the leaves of objects, dictionaries in key order so the result is stable
+// Bookmarks are replaced by the URL of their target and other data by its size
+func Flatten(objects []interface{}) []Value {
+	values := []Value{}
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch o := v.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(o))
+			for k := range o {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(path+"."+k, o[k])
+			}
+		case []interface{}:
+			for i, e := range o {
+				walk(path+"."+strconv.Itoa(i), e)
+			}
+		case []byte:
+			if u := bookmarkURL(o); u != "" {
+				values = append(values, Value{Path: path, Value: u})
+			} else {
+				values = append(values, Value{Path: path, Value: fmt.Sprintf("<%d bytes>", len(o))})
+			}
+		case nil:
+		case string:
+			if o != "$null" {
+				values = append(values, Value{Path: path, Value: o})
+			}
+		default:
+			values = append(values, Value{Path: path, Value: fmt.Sprint(o)})
+		}
+	}
+	for i, o := range objects {
+		walk(strconv.Itoa(i), o)
+	}
+	return values
+}
+
+// URLs returns the distinct URLs found in objects, URL strings and bookmark targets, in Flatten order
+func URLs(objects []interface{}) []string {
+	return urlsUnder(objects)
+}
+
+// Extract returns the values the extractor of the application bundleID picks out of the objects of a window, nil
+// when the application has no extractor or nothing was found
+func Extract(bundleID string, objects []interface{}) map[string]interface{} {
+	extractor, ok := extractors[strings.ToLower(bundleID)]
+	if !ok {
+		return nil
+	}
+	details := extractor(objects)
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// extractors by lower case bundle ID
+var extractors = map[string]func(objects []interface{}) map[string]interface{}{
+	"com.apple.terminal": terminalDetails,
+	"com.apple.preview":  documentDetails,
+	"com.apple.textedit": documentDetails,
+	"com.apple.finder":   finderDetails,
+}
+
+// TerminalTab is a tab archived by Terminal with its scrollback
+type TerminalTab struct {
+	WorkingDirectoryURL       string
+	WorkingDirectoryURLString string
+	Lines                     []string
+}
+
+// TerminalTabs returns the tabs of the Window Settings archived by Terminal
+func TerminalTabs(objects []interface{}) []TerminalTab {
+	tabs := []TerminalTab{}
+	for _, o := range objects {
+		m, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		settings, ok := m["Window Settings"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, s := range settings {
+			settings, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			tab := TerminalTab{Lines: []string{}}
+			if val, ok := settings["Tab Working Directory URL"]; ok {
+				tab.WorkingDirectoryURL = fmt.Sprint(val)
+			}
+			if val, ok := settings["Tab Working Directory URL String"]; ok {
+				tab.WorkingDirectoryURLString = fmt.Sprint(val)
+			}
+			if lines, ok := settings["Tab Contents v2"].([]interface{}); ok {
+				for _, line := range lines {
+					tab.Lines = append(tab.Lines, fmt.Sprint(line))
+				}
+			}
+			tabs = append(tabs, tab)
+		}
+	}
+	return tabs
+}
+
+// terminalDetails returns the working directories and the number of scrollback lines of the tabs
+func terminalDetails(objects []interface{}) map[string]interface{} {
+	details := map[string]interface{}{}
+	directories := []string{}
+	lines := 0
+	for _, tab := range TerminalTabs(objects) {
+		if tab.WorkingDirectoryURLString != "" {
+			directories = append(directories, tab.WorkingDirectoryURLString)
+		}
+		lines += len(tab.Lines)
+	}
+	if len(directories) > 0 {
+		details["working_directories"] = directories
+	}
+	if lines > 0 {
+		details["lines"] = lines
+	}
+	return details
+}
+
+// documentDetails returns the documents of NSDocument based applications (Preview, TextEdit), which archive the
+// document and autosave URLs or bookmarks of their windows
+func documentDetails(objects []interface{}) map[string]interface{} {
+	details := map[string]interface{}{}
+	if documents := urlsUnder(objects, "document", "autosave", "fileurl"); len(documents) > 0 {
+		details["documents"] = documents
+	}
+	return details
+}
+
+// finderDetails returns the folders Finder windows were showing, archived as target URLs or bookmarks
+func finderDetails(objects []interface{}) map[string]interface{} {
+	details := map[string]interface{}{}
+	if targets := urlsUnder(objects, "target"); len(targets) > 0 {
+		details["targets"] = targets
+	}
+	return details
+}
+
+// urlsUnder returns the distinct URLs of objects whose path has a key containing one of the lower case names, any
+// path when no name is given
+func urlsUnder(objects []interface{}, names ...string) []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, v := range Flatten(objects) {
+		if seen[v.Value] || !isURL(v.Value) {
+			continue
+		}
+		path := strings.ToLower(v.Path)
+		matched := len(names) == 0
+		for _, name := range names {
+			if strings.Contains(path, name) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			seen[v.Value] = true
+			urls = append(urls, v.Value)
+		}
+	}
+	return urls
+}
+
+// isURL returns true for absolute URLs such as file:// and https:// values
+func isURL(s string) bool {
+	if !strings.Contains(s, "://") || strings.ContainsAny(s, " \t\n") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != ""
+}
+
+// bookmarkURL returns the target URL of a bookmark, empty when b is not one
+func bookmarkURL(b []byte) string {
+	if !bytes.HasPrefix(b, []byte("book")) {
+		return ""
+	}
+	bm, err := machelpers.DecodeBookmark(b)
+	if err != nil {
+		return ""
+	}
+	return bm.URL()
+}
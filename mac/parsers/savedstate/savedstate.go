@@ -0,0 +1,213 @@
+// Package savedstate decrypts the window data of macOS saved application state directories
+// (~/Library/Saved Application State/<bundle id>.savedState)
+//
+// windows.plist lists the windows with their title and the AES key of their data, data.data holds a NSCR1000 block
+// per window whose payload is AES-128-CBC encrypted and carries an NSKeyedArchiver plist
+//
+// The archives hold whatever each application chose to restore its windows with. Flatten and URLs read any of
+// them, the extractors of extract.go pick out what Terminal, Preview, TextEdit and Finder are known to archive
+package savedstate
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/util/machelpers"
+	"go.uber.org/zap"
+)
+
+// File names of a saved state directory
+const (
+	WindowsFile = "windows.plist"
+	DataFile    = "data.data"
+)
+
+const magic = "NSCR1000"
+
+var iv = []byte{35, 46, 57, 24, 85, 35, 24, 74, 87, 35, 88, 98, 66, 32, 14, 05}
+
+// Block is a decrypted data block, Index is its position in data.data and Objects the unarchived plist objects
+type Block struct {
+	Index    int
+	WindowID uint32
+	Objects  []interface{}
+}
+
+// WindowInfo is a window listed in windows.plist, Key is nil for windows without data
+type WindowInfo struct {
+	ID    uint32
+	Title string
+	Main  bool
+	Key   []byte
+}
+
+// Window is a window of a saved state with the decrypted data blocks of its window id
+type Window struct {
+	WindowInfo
+	Blocks []Block
+}
+
+// State is a saved state directory, BundleID is the application the directory is named after
+type State struct {
+	BundleID string
+	Windows  []Window
+}
+
+// ParseWindowInfo returns the windows of a windows.plist in the order they are listed
+func ParseWindowInfo(b []byte) ([]WindowInfo, error) {
+	data, err := machelpers.DecodePlistBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	windows, ok := data.([]interface{})
+	if !ok {
+		return nil, errors.New("windows.plist is not an array")
+	}
+	infos := []WindowInfo{}
+	for _, w := range windows {
+		m, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := m["NSWindowID"].(uint64)
+		if !ok {
+			continue
+		}
+		info := WindowInfo{ID: uint32(id)}
+		info.Title, _ = m["NSTitle"].(string)
+		info.Main, _ = m["NSIsMainWindow"].(bool)
+		info.Key, _ = m["NSDataKey"].([]byte)
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// ParseWindows returns the data keys of the windows of a windows.plist by window id, windows without a key map to nil
+func ParseWindows(b []byte) (map[uint32][]byte, error) {
+	infos, err := ParseWindowInfo(b)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[uint32][]byte)
+	for _, info := range infos {
+		keys[info.ID] = info.Key
+	}
+	return keys, nil
+}
+
+// ParseData decrypts the blocks of data.data with the window keys, blocks of unknown windows or without a plist are
+// skipped
+func ParseData(data []byte, keys map[uint32][]byte) ([]Block, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, errors.New("bad file header for data.data")
+	}
+	blocks := []Block{}
+	for index, chunk := range bytes.Split(data[len(magic):], []byte(magic)) {
+		if len(chunk) < 8 {
+			continue
+		}
+		windowID := binary.BigEndian.Uint32(chunk[0:4])
+		blocksize := binary.BigEndian.Uint32(chunk[4:8]) // includes the magic and these 8 bytes
+		if uint32(len(chunk))+uint32(len(magic)) != blocksize {
+			zap.L().Debug(fmt.Sprintf("Skipping datablock %d of WindowID %d, size %d does not match", index, windowID, blocksize), zap.String("parser", "savedstate"))
+			continue
+		}
+		key := keys[windowID]
+		if key == nil {
+			zap.L().Debug(fmt.Sprintf("Key not found in windows.plist for WindowID %d (datablock %d)", windowID, index), zap.String("parser", "savedstate"))
+			continue
+		}
+		plaintext, err := decrypt(chunk[8:], key)
+		if err != nil {
+			zap.L().Debug(fmt.Sprintf("Failed to decrypt datablock %d of WindowID %d - %s", index, windowID, err.Error()), zap.String("parser", "savedstate"))
+			continue
+		}
+
+		// the decrypted block holds the plist size right before the bplist header
+		offset := bytes.Index(plaintext, []byte("bplist"))
+		if offset < 4 {
+			continue
+		}
+		size := int(binary.BigEndian.Uint32(plaintext[offset-4 : offset]))
+		if offset+size > len(plaintext) {
+			continue
+		}
+		objects, err := machelpers.UnarchiveNSKeyedArchiverBytes(plaintext[offset : offset+size])
+		if err != nil {
+			return blocks, fmt.Errorf("failed to decode plist of datablock %d - %s", index, err.Error())
+		}
+		blocks = append(blocks, Block{Index: index, WindowID: windowID, Objects: objects})
+	}
+	return blocks, nil
+}
+
+// ReadDir decrypts the data blocks of the saved state directory dir
+func ReadDir(dir string) ([]Block, error) {
+	windows, err := throttle.ReadFile(filepath.Join(dir, WindowsFile))
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ParseWindows(windows)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s - %s", filepath.Join(dir, WindowsFile), err.Error())
+	}
+	data, err := throttle.ReadFile(filepath.Join(dir, DataFile))
+	if err != nil {
+		return nil, err
+	}
+	return ParseData(data, keys)
+}
+
+// ReadState returns the windows of the saved state directory dir with their decrypted data blocks
+// A missing or undecodable data.data leaves the windows without blocks, windows.plist alone still gives the titles
+func ReadState(dir string) (State, error) {
+	state := State{BundleID: strings.TrimSuffix(filepath.Base(dir), ".savedState")}
+	windows, err := throttle.ReadFile(filepath.Join(dir, WindowsFile))
+	if err != nil {
+		return state, err
+	}
+	infos, err := ParseWindowInfo(windows)
+	if err != nil {
+		return state, fmt.Errorf("could not decode %s - %s", filepath.Join(dir, WindowsFile), err.Error())
+	}
+	keys := make(map[uint32][]byte)
+	for _, info := range infos {
+		keys[info.ID] = info.Key
+	}
+
+	blocks := []Block{}
+	data, err := throttle.ReadFile(filepath.Join(dir, DataFile))
+	if err == nil {
+		blocks, err = ParseData(data, keys)
+	}
+	for _, info := range infos {
+		window := Window{WindowInfo: info, Blocks: []Block{}}
+		for _, block := range blocks {
+			if block.WindowID == info.ID {
+				window.Blocks = append(window.Blocks, block)
+			}
+		}
+		state.Windows = append(state.Windows, window)
+	}
+	return state, err
+}
+
+func decrypt(ciphertext []byte, key []byte) ([]byte, error) {
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("data is not a multiple of the block size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	return plaintext, nil
+}
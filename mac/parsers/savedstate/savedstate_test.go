@@ -0,0 +1,121 @@
+package savedstate
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"howett.net/plist"
+)
+
+// block returns a data.data block of window id holding archive, encrypted with key
+func block(t *testing.T, id uint32, key []byte, archive []byte) []byte {
+	var payload bytes.Buffer
+	payload.WriteString("rchv")
+	binary.Write(&payload, binary.BigEndian, uint32(len(archive)))
+	payload.Write(archive)
+	payload.Write(make([]byte, (aes.BlockSize-payload.Len()%aes.BlockSize)%aes.BlockSize))
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := payload.Bytes()
+	cipher.NewCBCEncrypter(c, iv).CryptBlocks(encrypted, encrypted)
+
+	var b bytes.Buffer
+	b.WriteString(magic)
+	binary.Write(&b, binary.BigEndian, []uint32{id, uint32(16 + len(encrypted))})
+	b.Write(encrypted)
+	return b.Bytes()
+}
+
+func TestReadDir(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	archive, err := plist.Marshal(map[string]interface{}{
+		"$archiver": "NSKeyedArchiver",
+		"$version":  100000,
+		"$top":      map[string]interface{}{"root": plist.UID(1)},
+		"$objects":  []interface{}{"$null", "ls -la"},
+	}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows, err := plist.Marshal([]interface{}{
+		map[string]interface{}{"NSWindowID": uint64(7), "NSDataKey": key},
+		map[string]interface{}{"NSWindowID": uint64(8)},
+	}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// blocks of windows without a key or missing from windows.plist are skipped
+	data := append(block(t, 7, key, archive), block(t, 8, key, archive)...)
+	data = append(data, block(t, 9, key, archive)...)
+
+	dir, err := ioutil.TempDir("", "savedstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, WindowsFile), windows, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, DataFile), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Block{{Index: 0, WindowID: 7, Objects: []interface{}{"ls -la"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDir() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseData([]byte("NSCR0000"), nil); err == nil {
+		t.Error("ParseData() of a bad header succeeded")
+	}
+}
+
+func TestReadState(t *testing.T) {
+	state, err := ReadState("../../modules/macsavedstate/testdata/target/Users/bob/Library/Saved Application State/com.apple.finder.savedState")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.BundleID != "com.apple.finder" || len(state.Windows) != 1 {
+		t.Fatalf("ReadState() = %+v", state)
+	}
+	window := state.Windows[0]
+	if window.ID != 5 || window.Title != "Projects" || !window.Main || len(window.Blocks) != 1 {
+		t.Fatalf("ReadState() window = %+v", window)
+	}
+
+	objects := window.Blocks[0].Objects
+	wantValues := []Value{{Path: "0.TargetBookmark", Value: "file:///Users/bob/Projects"}, {Path: "0.ViewStyle", Value: "Nlsv"}}
+	if got := Flatten(objects); !reflect.DeepEqual(got, wantValues) {
+		t.Errorf("Flatten() = %+v, want %+v", got, wantValues)
+	}
+	wantDetails := map[string]interface{}{"targets": []string{"file:///Users/bob/Projects"}}
+	if got := Extract("com.apple.finder", objects); !reflect.DeepEqual(got, wantDetails) {
+		t.Errorf("Extract() = %+v, want %+v", got, wantDetails)
+	}
+	if got := Extract("com.example.unknown", objects); got != nil {
+		t.Errorf("Extract() of an application without extractor = %+v", got)
+	}
+}
+
+func TestTerminalTabs(t *testing.T) {
+	objects := []interface{}{map[string]interface{}{"Window Settings": []interface{}{
+		map[string]interface{}{"Tab Working Directory URL String": "file:///tmp/", "Tab Contents v2": []interface{}{"ls", "pwd"}},
+	}}}
+	want := []TerminalTab{{WorkingDirectoryURLString: "file:///tmp/", Lines: []string{"ls", "pwd"}}}
+	if got := TerminalTabs(objects); !reflect.DeepEqual(got, want) {
+		t.Errorf("TerminalTabs() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+// Package sfl2 parses the shared file lists (recent items) of macOS 10.13 and later
+// (~/Library/Application Support/com.apple.sharedfilelist/*.sfl2), and the SFL lists of 10.11 and 10.12 they replaced
+//
+// An SFL2 file is an NSKeyedArchiver plist whose root dictionary holds the list properties and an array of items,
+// each with a bookmark of the file it points to. SFL files have the same layout but archive their items as
+// SFLListItem objects with lower case keys, an order and the URL of the target
+package sfl2
+
+import (
+	"errors"
+
+	"github.com/anthonybm/Orion/util/machelpers"
+)
+
+// Item is an entry of a shared file list
+// Properties are the CustomItemProperties of the item, Name is only set by some lists, Order and URL only by SFL
+type Item struct {
+	Index      int
+	UUID       string
+	Name       string
+	URL        string
+	Order      uint64
+	Visibility uint64
+	Bookmark   []byte
+	Properties map[string]interface{}
+}
+
+// List is a shared file list
+type List struct {
+	Items      []Item
+	Properties map[string]interface{}
+}
+
+// Parse returns the list of the unarchived objects of an SFL2 file
+func Parse(objects []interface{}) (List, error) {
+	if len(objects) == 0 {
+		return List{}, errors.New("sfl2 has no root object")
+	}
+	root, ok := objects[0].(map[string]interface{})
+	if !ok {
+		return List{}, errors.New("sfl2 root is not a dictionary")
+	}
+	items, ok := root["items"].([]interface{})
+	if !ok {
+		return List{}, errors.New("sfl2 has no items array")
+	}
+
+	list := List{Items: []Item{}, Properties: map[string]interface{}{}}
+	if properties, ok := root["properties"].(map[string]interface{}); ok {
+		list.Properties = properties
+	}
+	for i, v := range items {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		item := Item{Index: i, Properties: map[string]interface{}{}}
+		item.UUID, _ = first(m, "uuid", "uniqueIdentifier").(string)
+		item.Name, _ = first(m, "Name", "name").(string)
+		item.URL, _ = m["URL"].(string)
+		item.Order, _ = m["order"].(uint64)
+		item.Visibility, _ = m["visibility"].(uint64)
+		item.Bookmark, _ = first(m, "Bookmark", "bookmark").([]byte)
+		if properties, ok := first(m, "CustomItemProperties", "properties").(map[string]interface{}); ok {
+			item.Properties = properties
+		}
+		list.Items = append(list.Items, item)
+	}
+	return list, nil
+}
+
+// first returns the value of the first of keys found in m, SFL2 and SFL name the same fields differently
+func first(m map[string]interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// ParseFile returns the list of the SFL2 or SFL file fp
+func ParseFile(fp string) (List, error) {
+	objects, err := machelpers.UnarchiveNSKeyedArchiver(fp)
+	if err != nil {
+		return List{}, err
+	}
+	return Parse(objects)
+}
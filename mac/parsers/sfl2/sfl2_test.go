@@ -0,0 +1,39 @@
+package sfl2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	// the NSKeyedArchiver fixture of util/machelpers is a recent documents list
+	list, err := ParseFile("../../../util/machelpers/testdata/com.apple.LSSharedFileList.RecentDocuments.sfl2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 {
+		t.Fatalf("ParseFile() = %d items, want 2", len(list.Items))
+	}
+	item := list.Items[1]
+	if item.Index != 1 || item.UUID != "3F2504E0-4F89-11D3-9A0C-0305E82C3302" || item.Visibility != 0 || !bytes.HasPrefix(item.Bookmark, []byte("book")) || item.Properties == nil {
+		t.Errorf("ParseFile() item = %+v", item)
+	}
+
+	if _, err := Parse([]interface{}{map[string]interface{}{}}); err == nil {
+		t.Error("Parse() of a root without items succeeded")
+	}
+}
+
+func TestParseFileSFL(t *testing.T) {
+	list, err := ParseFile("../../modules/macmru/testdata/target/Users/bob/Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentServers.sfl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("ParseFile() = %d items, want 1", len(list.Items))
+	}
+	item := list.Items[0]
+	if item.Name != "fileserver" || item.URL != "smb://fileserver.example.com/share" || item.UUID != "6B29FC40-CA47-1067-B31D-00DD010662DA" || item.Order != 1 || !bytes.HasPrefix(item.Bookmark, []byte("book")) {
+		t.Errorf("ParseFile() item = %+v", item)
+	}
+}
@@ -0,0 +1,217 @@
+// Package shellhistory parses the history files of bash, zsh and fish, including the per-session history files
+// Terminal keeps in ~/.bash_sessions and ~/.zsh_sessions
+//
+// Commands get a timestamp when the format records one: bash with HISTTIMEFORMAT set writes a #<epoch> line before
+// each command, zsh with EXTENDED_HISTORY (the macOS default) writes ": <start>:<elapsed>;<command>" and fish writes
+// a "when:" key
+package shellhistory
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shell identifies the history format of a file
+type Shell string
+
+const (
+	// Bash is the bash history format, one command per line with optional #<epoch> lines
+	Bash Shell = "bash"
+	// Zsh is the zsh history format, plain or extended
+	Zsh Shell = "zsh"
+	// Fish is the YAML like fish history format
+	Fish Shell = "fish"
+)
+
+// Command is a command of a history file
+// Index is the position of the command in the file, Time is zero and HasDuration false when the format or the
+// shell settings did not record them
+type Command struct {
+	Index       int
+	Command     string
+	Time        time.Time
+	Duration    time.Duration
+	HasDuration bool
+}
+
+// File describes a history file found on disk
+// SessionID is the Terminal session the file belongs to, empty for the main history of a shell
+type File struct {
+	Shell     Shell
+	SessionID string
+}
+
+var (
+	bashTimestamp = regexp.MustCompile(`^#([0-9]{9,11})$`)
+	zshExtended   = regexp.MustCompile(`^: *([0-9]+):([0-9]+);`)
+	sessionFile   = regexp.MustCompile(`^([0-9A-Fa-f-]{36})\.(history|historynew)$`)
+)
+
+// Identify returns the format and session of the history file fp, ok is false for files that are not history (ex.
+// the .session and _expiration files next to the session histories)
+func Identify(fp string) (File, bool) {
+	name := filepath.Base(fp)
+	dir := filepath.Base(filepath.Dir(fp))
+	switch {
+	case dir == ".bash_sessions" || dir == ".zsh_sessions":
+		m := sessionFile.FindStringSubmatch(name)
+		if m == nil {
+			return File{}, false
+		}
+		if dir == ".zsh_sessions" {
+			return File{Shell: Zsh, SessionID: m[1]}, true
+		}
+		return File{Shell: Bash, SessionID: m[1]}, true
+	case name == "fish_history":
+		return File{Shell: Fish}, true
+	case name == ".zsh_history" || name == ".histfile":
+		return File{Shell: Zsh}, true
+	case strings.HasSuffix(name, "_history"):
+		return File{Shell: Bash}, true
+	}
+	return File{}, false
+}
+
+// Parse returns the commands of a history file in the given format
+func Parse(r io.Reader, shell Shell) ([]Command, error) {
+	switch shell {
+	case Zsh:
+		return ParseZsh(r)
+	case Fish:
+		return ParseFish(r)
+	}
+	return ParseBash(r)
+}
+
+// ParseBash returns the commands of a bash history file, a #<epoch> line timestamps the command following it
+func ParseBash(r io.Reader) ([]Command, error) {
+	commands := []Command{}
+	var ts time.Time
+	err := eachLine(r, func(line string) {
+		if m := bashTimestamp.FindStringSubmatch(line); m != nil {
+			epoch, _ := strconv.ParseInt(m[1], 10, 64)
+			ts = time.Unix(epoch, 0).UTC()
+			return
+		}
+		if strings.TrimSpace(line) == "" {
+			return
+		}
+		commands = append(commands, Command{Index: len(commands), Command: strings.TrimSpace(line), Time: ts})
+		ts = time.Time{}
+	})
+	return commands, err
+}
+
+// ParseZsh returns the commands of a zsh history file
+// Lines ending with a backslash continue a multi-line command, and zsh metafied bytes are restored
+func ParseZsh(r io.Reader) ([]Command, error) {
+	commands := []Command{}
+	var current *Command
+	continued := false
+	err := eachLine(r, func(line string) {
+		line = unmetafy(line)
+		if continued && current != nil {
+			current.Command += "\n" + strings.TrimSuffix(line, "\\")
+			continued = strings.HasSuffix(line, "\\")
+			return
+		}
+		c := Command{Index: len(commands)}
+		if m := zshExtended.FindStringSubmatch(line); m != nil {
+			start, _ := strconv.ParseInt(m[1], 10, 64)
+			elapsed, _ := strconv.ParseInt(m[2], 10, 64)
+			c.Time = time.Unix(start, 0).UTC()
+			c.Duration = time.Duration(elapsed) * time.Second
+			c.HasDuration = true
+			line = line[len(m[0]):]
+		} else if strings.TrimSpace(line) == "" {
+			return
+		}
+		continued = strings.HasSuffix(line, "\\")
+		c.Command = strings.TrimSuffix(line, "\\")
+		commands = append(commands, c)
+		current = &commands[len(commands)-1]
+	})
+	for i := range commands {
+		commands[i].Command = strings.TrimSpace(commands[i].Command)
+	}
+	return commands, err
+}
+
+// ParseFish returns the commands of a fish_history file, a list of "- cmd:" entries with a "when:" epoch
+func ParseFish(r io.Reader) ([]Command, error) {
+	commands := []Command{}
+	err := eachLine(r, func(line string) {
+		switch {
+		case strings.HasPrefix(line, "- cmd:"):
+			cmd := strings.TrimSpace(strings.TrimPrefix(line, "- cmd:"))
+			commands = append(commands, Command{Index: len(commands), Command: unescapeFish(cmd)})
+		case strings.HasPrefix(strings.TrimSpace(line), "when:") && len(commands) > 0:
+			epoch, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "when:")), 10, 64)
+			if err == nil {
+				commands[len(commands)-1].Time = time.Unix(epoch, 0).UTC()
+			}
+		}
+	})
+	return commands, err
+}
+
+// eachLine calls fn with every line of r, long lines are kept whole
+func eachLine(r io.Reader, fn func(line string)) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			fn(strings.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unmetafy restores the bytes zsh escapes in its history file, 0x83 followed by the byte xor 32
+func unmetafy(line string) string {
+	if strings.IndexByte(line, 0x83) < 0 {
+		return line
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(line); i++ {
+		if line[i] == 0x83 && i+1 < len(line) {
+			i++
+			buf.WriteByte(line[i] ^ 32)
+			continue
+		}
+		buf.WriteByte(line[i])
+	}
+	return buf.String()
+}
+
+// unescapeFish restores the newlines and backslashes fish escapes in commands
+func unescapeFish(cmd string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(cmd); i++ {
+		if cmd[i] == '\\' && i+1 < len(cmd) {
+			switch cmd[i+1] {
+			case 'n':
+				buf.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				buf.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		buf.WriteByte(cmd[i])
+	}
+	return buf.String()
+}
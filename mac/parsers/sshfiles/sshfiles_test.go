@@ -0,0 +1,122 @@
+package sshfiles
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testKey(t *testing.T) (ssh.PublicKey, string) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sshPub, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	pub, line := testKey(t)
+	entries := ParseAuthorizedKeys([]byte("# comment\n\n" +
+		line + " bob@laptop\n" +
+		`command="/tmp/.x/backdoor",no-pty,from="10.0.0.0/8" ` + line + "\n" +
+		"not a key\n"))
+	if len(entries) != 3 {
+		t.Fatalf("ParseAuthorizedKeys() returned %d entries", len(entries))
+	}
+	want := Entry{Line: 3, Key: Key{Type: "ssh-ed25519", Bits: 256, SHA256: ssh.FingerprintSHA256(pub), MD5: ssh.FingerprintLegacyMD5(pub)}, Comment: "bob@laptop"}
+	if !reflect.DeepEqual(entries[0], want) {
+		t.Errorf("entry = %+v, want %+v", entries[0], want)
+	}
+	if got := entries[1].Options; !reflect.DeepEqual(got, []string{`command="/tmp/.x/backdoor"`, "no-pty", `from="10.0.0.0/8"`}) {
+		t.Errorf("options = %v", got)
+	}
+	if got := entries[1].Flags(); !reflect.DeepEqual(got, []string{"command option", "from option"}) {
+		t.Errorf("Flags() = %v", got)
+	}
+	if entries[2].Line != 5 || entries[2].Error == "" {
+		t.Errorf("invalid line entry = %+v", entries[2])
+	}
+}
+
+func TestParseKnownHosts(t *testing.T) {
+	_, line := testKey(t)
+	entries := ParseKnownHosts([]byte(
+		"github.com,140.82.112.3 " + line + "\n" +
+			"|1|JfKTdBh7rNbXkVAQCRp4OQoPfmI=|USECr3SWf1JUPsms5AqfD5QfxkM= " + line + "\n" +
+			"@revoked * " + line + "\n"))
+	if len(entries) != 3 {
+		t.Fatalf("ParseKnownHosts() returned %d entries", len(entries))
+	}
+	if !reflect.DeepEqual(entries[0].Hosts, []string{"github.com", "140.82.112.3"}) || entries[0].Hashed {
+		t.Errorf("entry = %+v", entries[0])
+	}
+	if !entries[1].Hashed {
+		t.Errorf("hashed entry = %+v", entries[1])
+	}
+	if entries[2].Marker != "revoked" {
+		t.Errorf("marker = %q", entries[2].Marker)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der := x509.MarshalPKCS1PrivateKey(rsaKey)
+	plain := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der})
+	if !IsPrivateKey(plain) || IsPrivateKey([]byte("ssh-rsa AAAA")) {
+		t.Error("IsPrivateKey() wrong")
+	}
+	key, err := ParsePrivateKey(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Encrypted || key.Key.Type != "ssh-rsa" || key.Key.Bits != 1024 || !reflect.DeepEqual(key.Flags(), []string{"unencrypted private key"}) {
+		t.Errorf("ParsePrivateKey() = %+v", key)
+	}
+
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", der, []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = ParsePrivateKey(pem.EncodeToMemory(block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Encrypted || key.PEMType != "RSA PRIVATE KEY" || len(key.Flags()) != 0 {
+		t.Errorf("ParsePrivateKey(encrypted) = %+v", key)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	directives := ParseConfig([]byte("# sshd\nPermitRootLogin yes\nPasswordAuthentication no\n\nHost jump\n  ProxyCommand=ssh -W %h:%p bastion\n  ProxyCommand none\n"))
+	want := []Directive{
+		{Line: 2, Keyword: "PermitRootLogin", Value: "yes"},
+		{Line: 3, Keyword: "PasswordAuthentication", Value: "no"},
+		{Line: 5, Block: "Host jump", Keyword: "Host", Value: "jump"},
+		{Line: 6, Block: "Host jump", Keyword: "ProxyCommand", Value: "ssh -W %h:%p bastion"},
+		{Line: 7, Block: "Host jump", Keyword: "ProxyCommand", Value: "none"},
+	}
+	if !reflect.DeepEqual(directives, want) {
+		t.Fatalf("ParseConfig() = %+v, want %+v", directives, want)
+	}
+	flagged := []string{}
+	for _, d := range directives {
+		flagged = append(flagged, d.Flags()...)
+	}
+	if !reflect.DeepEqual(flagged, []string{"PermitRootLogin yes", "ProxyCommand"}) {
+		t.Errorf("Flags() = %v", flagged)
+	}
+}
@@ -0,0 +1,90 @@
+// Package utmpx parses the utmpx login records file of macOS (/private/var/run/utmpx)
+//
+// https://opensource.apple.com/source/Libc/Libc-1158.50.2/include/NetBSD/utmpx.h.auto.html
+// https://github.com/libyal/dtformats/blob/master/documentation/Utmp%20login%20records%20format.asciidoc
+package utmpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/anthonybm/Orion/util"
+)
+
+// RecordSize is the size of a utmpx record, the file starts with a signature record of the same size
+const RecordSize = 628
+
+// Record is a login record
+// LogonType is the ut_type value (ex. 2 BOOT_TIME, 7 USER_PROCESS, 8 DEAD_PROCESS)
+type Record struct {
+	LoginName string
+	ID        string
+	TtyName   string
+	Pid       int32
+	LogonType int16
+	Time      time.Time
+	Hostname  string
+}
+
+// record is the on-disk layout of a record, little endian
+type record struct {
+	LoginName [256]uint8
+	ID        [4]uint8
+	TtyName   [32]uint8
+	Pid       int32
+	LogonType int16
+	Padding   [2]byte
+	Epoch     int32
+	Usec      int32
+	Hostname  [256]uint8
+	Reserved  [64]byte
+}
+
+// Parse reads the records of r after its signature record, a truncated last record is ignored
+func Parse(r io.Reader) ([]Record, error) {
+	header := make([]byte, RecordSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read utmpx header: %s", err.Error())
+	}
+	if n != RecordSize {
+		return nil, fmt.Errorf("utmpx header smaller than expected- expected '%d' got '%d'", RecordSize, n)
+	}
+
+	records := []Record{}
+	buf := make([]byte, RecordSize)
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			break // Done reading
+		}
+		raw := record{}
+		if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &raw); err != nil {
+			break
+		}
+		records = append(records, Record{
+			// vars still have non-printable characters due to unpacking
+			LoginName: util.GetPrintableString(string(raw.LoginName[:])),
+			ID:        util.GetPrintableString(string(raw.ID[:])),
+			TtyName:   util.GetPrintableString(string(raw.TtyName[:])),
+			Pid:       raw.Pid,
+			LogonType: raw.LogonType,
+			Time:      time.Unix(int64(raw.Epoch), int64(raw.Usec)*1000),
+			Hostname:  util.GetPrintableString(string(raw.Hostname[:])),
+		})
+	}
+	return records, nil
+}
+
+// ParseFile reads the records of the utmpx file fp
+func ParseFile(fp string) ([]Record, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
@@ -0,0 +1,43 @@
+package utmpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	newRecord := func(login, tty string, logonType int16, epoch int32, host string) record {
+		var r record
+		copy(r.LoginName[:], login)
+		copy(r.TtyName[:], tty)
+		copy(r.Hostname[:], host)
+		r.Pid = 42
+		r.LogonType = logonType
+		r.Epoch = epoch
+		r.Usec = 500000
+		return r
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []record{
+		newRecord("utmpx-1.00", "", 10, 0, ""),
+		newRecord("bob", "ttys000", 7, 1596272400, "10.0.0.5"),
+	})
+	// a truncated record is left out
+	buf.Write(make([]byte, RecordSize/2))
+
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{{LoginName: "bob", TtyName: "ttys000", Pid: 42, LogonType: 7, Time: time.Unix(1596272400, 500000000), Hostname: "10.0.0.5"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+
+	if _, err := Parse(bytes.NewReader(make([]byte, 10))); err == nil {
+		t.Error("Parse() of a short header succeeded")
+	}
+}
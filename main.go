@@ -3,14 +3,18 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/anthonybm/Orion/baseline"
+	"github.com/anthonybm/Orion/diff"
 	"github.com/anthonybm/Orion/engine"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/report"
+	"github.com/anthonybm/Orion/timeline"
 	"go.uber.org/zap"
 
 	"github.com/akamensky/argparse"
@@ -22,12 +26,36 @@ func main() {
 		orionVersion = "0.2.0-alpha"
 	)
 
+	// orion diff <runA> <runB> compares two runs instead of collecting
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(diffCommand(os.Args[2:]))
+	}
+	// orion report <run> summarises a finished run in an HTML page
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(reportCommand(os.Args[2:]))
+	}
+
 	// Argument parsing
 	parser := argparse.NewParser("Orion", "Orion framework for triage of relevant incident response and forensics artifacts from various operating systems")
 	var (
 		listmodules *bool = parser.Flag("", "list", &argparse.Options{
 			Required: false,
-			Help:     "List available modules.",
+			Help:     "List available modules with their version, tags and description, marking the modules selected to run when a config is set.",
+		})
+		profile *string = parser.String("", "profile", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Run the modules of a [profiles] entry of the config file",
+		})
+		modulesFlag *string = parser.String("", "modules", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Comma separated modules or tags to run instead of [modules] enabled, added to --profile",
+		})
+		excludeFlag *string = parser.String("", "exclude", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Comma separated modules or tags to skip (ex. volatile,disk-heavy)",
 		})
 		loglevel *string = parser.Selector("l" /*short arg name*/, "log-level" /*long arg name*/, []string{"none", "info", "debug", "error"}, &argparse.Options{
 			Required: false,
@@ -54,8 +82,31 @@ func main() {
 			Help:     "Set the output directory for files generated by Orion.",
 		})
 		configpath *string = parser.String("c", "config", &argparse.Options{
-			Required: true,
-			Help:     "Set the config path",
+			Required: false,
+			Help:     "Set the config path, required unless printing the config schema",
+		})
+		validateConfig *bool = parser.Flag("", "validate-config", &argparse.Options{
+			Required: false,
+			Help:     "Validate the config file against the modules and their config sections, then exit.",
+		})
+		printConfigSchema *bool = parser.Flag("", "print-config-schema", &argparse.Options{
+			Required: false,
+			Help:     "Print every config key and module config section with its default value, then exit.",
+		})
+		uploadFile *string = parser.String("", "upload-file", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Upload an existing package to the [[upload]] sinks of the config file, resuming interrupted uploads, then exit",
+		})
+		baselinePath *string = parser.String("", "baseline", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Collect incrementally against a previous run: its output directory, zip package or _manifest.json",
+		})
+		reportFlag *bool = parser.Flag("", "report", &argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Write a self-contained HTML summary of the run (<runtime>_Report.html) after modules finish",
 		})
 		testingMode *bool = parser.Flag("T", "testing-mode", &argparse.Options{
 			Required: false,
@@ -72,6 +123,11 @@ func main() {
 			Default:  "/",
 			Help:     "Specify the root target path to reference artifacts from - i.e. <target>/pathToPlist.plist",
 		})
+		timelineFlag *string = parser.String("", "timeline", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Build a merged super-timeline after modules finish, comma separated formats (csv,jsonl,l2tcsv,tln)",
+		})
 	)
 
 	// Return immediately if failed to parse args
@@ -82,31 +138,76 @@ func main() {
 		return
 	}
 
+	// Print the config schema without requiring a config file
+	if *printConfigSchema {
+		err := engine.WriteConfigSchema(os.Stdout, *mode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Main] Failed to print config schema: %s\n", err)
+		}
+		return
+	}
+	// List the modules built for this OS without requiring a config file
+	if *listmodules && *configpath == "" {
+		err := engine.WriteModuleList(os.Stdout, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Main] Failed to list modules: %s\n", err)
+		}
+		return
+	}
+	if *configpath == "" {
+		fmt.Fprintf(os.Stderr, "[Main] A config file is required, set it with -c/--config\n")
+		fmt.Print(parser.Usage(nil))
+		return
+	}
+
+	// Validate requested timeline formats
+	timelineFormats := []string{}
+	if *timelineFlag != "" {
+		for _, format := range strings.Split(*timelineFlag, ",") {
+			format = strings.ToLower(strings.TrimSpace(format))
+			if !timeline.ValidFormat(format) {
+				fmt.Fprintf(os.Stderr, "[Main] Unsupported timeline format '%s', expected one of %s\n", format, strings.Join(timeline.Formats, ","))
+				return
+			}
+			timelineFormats = append(timelineFormats, format)
+		}
+	}
+
 	// Instantiate new Orion instance and handle any errors
-	inst, err := instance.NewInstance(*targetPath, *outputformat, *outputPath, orionRuntime, *loglevel, *configpath, *mode, *noMultithreading, *forensicMode)
+	selection := instance.ModuleSelection{
+		Profile: *profile,
+		Modules: splitList(*modulesFlag),
+		Exclude: splitList(*excludeFlag),
+	}
+	inst, err := instance.NewInstance(*targetPath, *outputformat, *outputPath, orionRuntime, *loglevel, *configpath, *mode, *noMultithreading, *forensicMode, timelineFormats, selection, *baselinePath, *reportFlag)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[Main] Failed to instantiate Orion instance: %s\n", err)
 		return
 	}
 
 	// Handle flags that return immediatly
-	if *listmodules {
-		// Grab string list of modules to execute from config, throw error if field does not exist
-		modulesToExecute, err := inst.GetOrionModules()
+	if *validateConfig {
+		err := engine.ValidateConfig(inst)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "[Main] Failed to grab Orion modules from config file: %s", err)
-			return
+			fmt.Fprintf(os.Stderr, "[Main] %s\n", err)
+			os.Exit(1)
 		}
-		if len(modulesToExecute) == 0 {
-			fmt.Fprint(os.Stderr, "[Main] No Orion modules were grabbed from config file")
-			return
+		fmt.Fprintf(os.Stdout, "[Main] Config file '%s' is valid\n", *configpath)
+		return
+	}
+	if *uploadFile != "" {
+		err := engine.UploadFile(*uploadFile, inst)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Main] Failed to upload '%s': %s\n", *uploadFile, err)
+			os.Exit(1)
 		}
-
-		sort.Strings(modulesToExecute)
-
-		fmt.Fprintf(os.Stdout, "[Main] Running the following modules: \n")
-		for _, moduleName := range modulesToExecute {
-			fmt.Fprintf(os.Stdout, "\t%s\n", moduleName)
+		fmt.Fprintf(os.Stdout, "[Main] Uploaded '%s'\n", *uploadFile)
+		return
+	}
+	if *listmodules {
+		err := engine.WriteModuleList(os.Stdout, &inst)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Main] Failed to list modules: %s\n", err)
 		}
 		return
 	}
@@ -133,20 +234,20 @@ func main() {
 	if *testingMode { // print some testing information
 		fmt.Fprint(os.Stdout, "Multithreading enabled is:", !*noMultithreading, "\n")
 
-		// Grab string list of modules to execute from config, throw error if field does not exist
-		modulesToExecute, err := inst.GetOrionModules()
+		// Grab string list of modules selected from config and command line, throw error if none are selected
+		modulesToExecute, err := engine.SelectModules(inst)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to grab Orion modules from config file: %s", err)
+			fmt.Fprintf(os.Stderr, "Failed to select Orion modules: %s", err)
 			return
 		}
 		if len(modulesToExecute) == 0 {
-			fmt.Fprint(os.Stderr, "No Orion modules were grabbed from config file")
+			fmt.Fprint(os.Stderr, "No Orion modules were selected")
 			return
 		}
 
 		fmt.Fprintf(os.Stdout, "Running the following '"+inst.GetOrionMode()+"' modules: \n")
 		for _, moduleName := range modulesToExecute {
-			fmt.Fprintf(os.Stdout, "\t%s\n", strings.Split(moduleName, strings.Title(strings.ToLower(inst.GetOrionMode())))[1])
+			fmt.Fprintf(os.Stdout, "\t%s\n", strings.TrimPrefix(moduleName, strings.Title(strings.ToLower(inst.GetOrionMode()))))
 		}
 	}
 
@@ -160,3 +261,179 @@ func main() {
 	}
 	return
 }
+
+// splitList returns the trimmed, non-empty entries of a comma separated flag value
+func splitList(value string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// diffCommand runs orion diff <runA> <runB> [-f csv|json|html] [-o file] [--modules list] and returns the exit code
+// Runs are output directories, zip packages or _manifest.json files
+func diffCommand(args []string) int {
+	parser := argparse.NewParser("Orion diff", "Compare the output of two Orion runs module by module: Orion diff <runA> <runB>, each run an output directory, zip package or _manifest.json")
+	var (
+		format *string = parser.Selector("f", "format", diff.Formats, &argparse.Options{
+			Required: false,
+			Default:  "csv",
+			Help:     "Set the report format",
+		})
+		outputFile *string = parser.String("o", "output", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Write the report to a file instead of stdout",
+		})
+		modules *string = parser.String("", "modules", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Comma separated modules or outputs to compare, every output by default",
+		})
+	)
+
+	// argparse has no positional arguments, the runs are the arguments that are neither flags nor flag values
+	runs := []string{}
+	flags := []string{"diff"}
+	for n := 0; n < len(args); n++ {
+		arg := args[n]
+		if !strings.HasPrefix(arg, "-") {
+			runs = append(runs, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		switch arg {
+		case "-f", "--format", "-o", "--output", "--modules":
+			if n+1 < len(args) {
+				n++
+				flags = append(flags, args[n])
+			}
+		}
+	}
+	err := parser.Parse(flags)
+	if err == nil && len(runs) != 2 {
+		err = fmt.Errorf("expected two runs to compare, got %d", len(runs))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to parse command line arguments: %s\n", err)
+		fmt.Print(parser.Usage(err))
+		return 1
+	}
+
+	a, err := baseline.Open(runs[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to open run '%s': %s\n", runs[0], err)
+		return 1
+	}
+	defer a.Close()
+	b, err := baseline.Open(runs[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to open run '%s': %s\n", runs[1], err)
+		return 1
+	}
+	defer b.Close()
+
+	report, err := diff.Compare(a, b, engine.DiffKeys(), splitList(*modules))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to compare runs: %s\n", err)
+		return 1
+	}
+	w := os.Stdout
+	if *outputFile != "" {
+		w, err = os.Create(*outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Main] Failed to create '%s': %s\n", *outputFile, err)
+			return 1
+		}
+		defer w.Close()
+	}
+	err = diff.Write(w, report, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to write diff report: %s\n", err)
+		return 1
+	}
+	if *outputFile != "" {
+		added, removed, changed := 0, 0, 0
+		for _, out := range report.Outputs {
+			added, removed, changed = added+out.Added, removed+out.Removed, changed+out.Changed
+		}
+		fmt.Fprintf(os.Stdout, "[Main] Compared %d outputs of %s and %s: %d added, %d removed, %d changed rows, wrote %s\n", len(report.Outputs), a.Runtime(), b.Runtime(), added, removed, changed, *outputFile)
+	}
+	return 0
+}
+
+// reportCommand runs orion report <run> [-o file] and returns the exit code
+// The run is an output directory, zip package or _manifest.json file, the report is written next to it by default
+func reportCommand(args []string) int {
+	parser := argparse.NewParser("Orion report", "Summarise a finished Orion run in a self-contained HTML page: Orion report <run>, the run an output directory, zip package or _manifest.json")
+	var (
+		outputFile *string = parser.String("o", "output", &argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Write the report to a file instead of <runtime>_Report.html next to the run",
+		})
+	)
+
+	// argparse has no positional arguments, the run is the argument that is neither a flag nor a flag value
+	runs := []string{}
+	flags := []string{"report"}
+	for n := 0; n < len(args); n++ {
+		arg := args[n]
+		if !strings.HasPrefix(arg, "-") {
+			runs = append(runs, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		if (arg == "-o" || arg == "--output") && n+1 < len(args) {
+			n++
+			flags = append(flags, args[n])
+		}
+	}
+	err := parser.Parse(flags)
+	if err == nil && len(runs) != 1 {
+		err = fmt.Errorf("expected one run to report on, got %d", len(runs))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to parse command line arguments: %s\n", err)
+		fmt.Print(parser.Usage(err))
+		return 1
+	}
+
+	run, err := baseline.Open(runs[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to open run '%s': %s\n", runs[0], err)
+		return 1
+	}
+	defer run.Close()
+	r, err := report.Build(run, engine.TimelineDefinitions())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to build report: %s\n", err)
+		return 1
+	}
+
+	fp := *outputFile
+	if fp == "" {
+		dir := runs[0]
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		fp, err = report.WriteFile(r, dir)
+	} else {
+		var f *os.File
+		f, err = os.Create(fp)
+		if err == nil {
+			err = report.WriteHTML(f, r)
+			f.Close()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Main] Failed to write report '%s': %s\n", fp, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "[Main] Wrote report of %s to %s\n", run.Runtime(), fp)
+	return 0
+}
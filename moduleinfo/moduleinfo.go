@@ -0,0 +1,52 @@
+package moduleinfo
+
+import "strings"
+
+// Tags describing how a module collects, used to select modules (ex. --exclude disk-heavy)
+const (
+	// TagVolatile marks modules recording live system state (processes, connections, open files)
+	TagVolatile = "volatile"
+	// TagDiskHeavy marks modules reading large parts of the disk, slow on big volumes
+	TagDiskHeavy = "disk-heavy"
+	// TagNeedsRoot marks modules that collect little or nothing without Root/Admin permissions
+	TagNeedsRoot = "needs-root"
+	// TagBrowser marks modules parsing web browser artifacts
+	TagBrowser = "browser"
+	// TagPersistence marks modules parsing autostart and persistence locations
+	TagPersistence = "persistence"
+)
+
+// KnownTag returns true if tag is one of the tags above, which select nothing rather than being unknown when no
+// module available on this OS carries them
+func KnownTag(tag string) bool {
+	switch tag {
+	case TagVolatile, TagDiskHeavy, TagNeedsRoot, TagBrowser, TagPersistence:
+		return true
+	}
+	return false
+}
+
+// Info describes a module, returned by the optional Info() method of a module
+type Info struct {
+	Name        string
+	Mode        string
+	Version     string
+	Description string
+	Author      string
+	Tags        []string
+}
+
+// HasTag returns true if the module is tagged with tag
+func (i Info) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary returns the description collapsed to a single line
+func (i Info) Summary() string {
+	return strings.Join(strings.Fields(i.Description), " ")
+}
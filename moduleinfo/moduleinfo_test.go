@@ -0,0 +1,26 @@
+package moduleinfo
+
+import "testing"
+
+func TestInfo(t *testing.T) {
+	info := Info{
+		Name: "MacSSHModule",
+		Description: `
+	Reads and parses the SSH known_hosts
+	and authorized_keys on disk
+	`,
+		Tags: []string{TagPersistence, TagNeedsRoot},
+	}
+	if !info.HasTag(TagNeedsRoot) || info.HasTag(TagVolatile) {
+		t.Errorf("HasTag() wrong for tags %v", info.Tags)
+	}
+	if got, want := info.Summary(), "Reads and parses the SSH known_hosts and authorized_keys on disk"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
+
+func TestKnownTag(t *testing.T) {
+	if !KnownTag(TagVolatile) || KnownTag("volatil") {
+		t.Error("KnownTag() wrong")
+	}
+}
@@ -0,0 +1,236 @@
+// Package moduletest runs modules against fixture target directories and compares their output with golden files
+//
+// A module test points Run at a directory laid out like the target file system (testdata/target/Users/bob/...),
+// the module resolves its artifact paths under it as it does for -t, and its output is kept in memory
+//
+//	outputs := moduletest.Run(t, MacUtmpxModule{}, "testdata/target", moduletest.Options{})
+//	moduletest.Golden(t, outputs, "testdata/golden")
+//
+// Module packages name that test Test<Module>Golden and use RunGolden, which does both with the testdata layout
+//
+//	func TestMacUtmpxModuleGolden(t *testing.T) {
+//		moduletest.RunGolden(t, MacUtmpxModule{}, moduletest.Options{})
+//	}
+//
+// Golden files are <dir>/<output>.csv, run go test with -update to rewrite them after an intended change
+package moduletest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the output of the modules under test")
+
+// TargetPlaceholder replaces the fixture target path in output values so golden files do not depend on the checkout
+const TargetPlaceholder = "$TARGET"
+
+// Masked replaces the values of masked columns
+const Masked = "*"
+
+// Fixture directories of a module package used by RunGolden
+const (
+	TargetDir = "testdata/target"
+	GoldenDir = "testdata/golden"
+)
+
+// Module is a module under test
+type Module interface {
+	Start(inst instance.Instance) error
+}
+
+// Options configure a module run
+// Config is the content of the config file, a default enabling nothing is used when empty
+// Mask lists columns whose values depend on the fixture checkout (ex. file times) and are replaced with Masked
+// Target replaces TargetDir in RunGolden (ex. a CopyTarget copy)
+type Options struct {
+	Mode     string
+	Config   string
+	Forensic bool
+	Mask     []string
+	Target   string
+}
+
+// defaultConfig formats timestamps the same way for every run
+const defaultConfig = `timestampFormat = "rfc3339nano"
+[modules]
+enabled = []
+`
+
+var runs int64
+
+// Run runs module against the fixture target directory and returns its outputs by output name, each with its header
+// first and the rows sorted since modules walk maps and globs in no set order
+func Run(t testing.TB, module Module, target string, opts Options) map[string][][]string {
+	t.Helper()
+	if opts.Mode == "" {
+		opts.Mode = "mac"
+	}
+	if opts.Config == "" {
+		opts.Config = defaultConfig
+	}
+	target, err := filepath.Abs(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "moduletest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	config := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(config, []byte(opts.Config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// every run has its own runtime so the in-memory outputs of parallel tests stay apart
+	runtime := "Orion_moduletest_" + strconv.FormatInt(atomic.AddInt64(&runs, 1), 10)
+	inst, err := instance.NewInstance(target, datawriter.MemoryOutputType, dir, runtime, "error", config, opts.Mode, true, opts.Forensic, []string{}, instance.ModuleSelection{}, "", false)
+	if err != nil {
+		t.Fatalf("NewInstance() error: %s", err)
+	}
+	defer inst.CloseLogger()
+	if err := module.Start(inst); err != nil {
+		t.Errorf("Start() error: %s", err)
+	}
+
+	outputs := datawriter.MemoryOutputs(runtime)
+	for name, rows := range outputs {
+		outputs[name] = normalize(rows, target, opts.Mask)
+	}
+	return outputs
+}
+
+// RunGolden runs module against the TargetDir fixtures of the package under test and compares its outputs with
+// the golden files in GoldenDir
+func RunGolden(t testing.TB, module Module, opts Options) {
+	t.Helper()
+	target := opts.Target
+	if target == "" {
+		target = TargetDir
+	}
+	Golden(t, Run(t, module, target, opts), GoldenDir)
+}
+
+// CopyTarget copies the fixture target directory to a temporary directory and returns the copy, for tests that
+// change the fixtures (ex. set file times) before running a module
+func CopyTarget(t testing.TB, target string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "moduletest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	err = filepath.Walk(target, func(fp string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(target, fp)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(dst, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(fp)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dst)
+		}
+		data, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(dst, data, info.Mode().Perm())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// normalize replaces the target path and masked values and sorts the rows after the header
+func normalize(rows [][]string, target string, mask []string) [][]string {
+	if len(rows) == 0 {
+		return rows
+	}
+	masked := map[int]bool{}
+	for i, name := range rows[0] {
+		for _, m := range mask {
+			if name == m {
+				masked[i] = true
+			}
+		}
+	}
+	for _, row := range rows[1:] {
+		for i, value := range row {
+			if masked[i] && value != "" {
+				row[i] = Masked
+				continue
+			}
+			row[i] = strings.Replace(value, target, TargetPlaceholder, -1)
+		}
+	}
+	body := rows[1:]
+	sort.SliceStable(body, func(a, b int) bool { return strings.Join(body[a], "\x1f") < strings.Join(body[b], "\x1f") })
+	return rows
+}
+
+// Golden compares every output with <dir>/<output>.csv, golden files without an output fail the test too
+func Golden(t testing.TB, outputs map[string][][]string, dir string) {
+	t.Helper()
+	for name, rows := range outputs {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.WriteAll(rows)
+		if err := w.Error(); err != nil {
+			t.Fatal(err)
+		}
+		GoldenFile(t, filepath.Join(dir, name+".csv"), buf.Bytes())
+	}
+	if *update {
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.csv"))
+	for _, fp := range files {
+		if _, ok := outputs[strings.TrimSuffix(filepath.Base(fp), ".csv")]; !ok {
+			t.Errorf("golden file %s has no matching output", fp)
+		}
+	}
+}
+
+// GoldenFile compares got with the golden file fp, or rewrites fp with -update
+func GoldenFile(t testing.TB, fp string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("%s, run go test with -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run go test with -update if the change is intended\ngot:\n%s\nwant:\n%s", fp, got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package moduletest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/instance"
+)
+
+// notesModule writes a row per line of the notes of bob, and a second output with the file size
+type notesModule struct{}
+
+func (m notesModule) Start(inst instance.Instance) error {
+	fp := filepath.Join(inst.GetTargetPath(), "Users", "bob", "notes.txt")
+	data, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return err
+	}
+	mw, err := datawriter.NewOrionWriter("NotesModule", inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
+	if err != nil {
+		return err
+	}
+	mw.WriteHeader([]string{"path", "line"})
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// written last line first, Run sorts them
+	for i := len(lines) - 1; i >= 0; i-- {
+		mw.Write([]string{fp, lines[i]})
+	}
+	mw.Close()
+
+	sizes, err := datawriter.NewOrionWriter("NotesModule-sizes", mw.GetOrionRuntime(), mw.GetOutputType(), filepath.Dir(mw.GetOutfilePath()))
+	if err != nil {
+		return err
+	}
+	return sizes.WriteOutput([]string{"path", "size", "mtime"}, [][]string{{fp, "11", "2020-08-01T10:00:00Z"}})
+}
+
+func TestRun(t *testing.T) {
+	outputs := Run(t, notesModule{}, "testdata/target", Options{Mask: []string{"mtime"}})
+	want := map[string][][]string{
+		"NotesModule": {
+			{"path", "line"},
+			{"$TARGET/Users/bob/notes.txt", "alpha"},
+			{"$TARGET/Users/bob/notes.txt", "beta"},
+		},
+		"NotesModule-sizes": {
+			{"path", "size", "mtime"},
+			{"$TARGET/Users/bob/notes.txt", "11", "*"},
+		},
+	}
+	if !reflect.DeepEqual(outputs, want) {
+		t.Errorf("Run() = %v, want %v", outputs, want)
+	}
+	Golden(t, outputs, "testdata/golden")
+}
+
+func TestRunGolden(t *testing.T) {
+	RunGolden(t, notesModule{}, Options{Mask: []string{"mtime"}})
+}
+
+func TestCopyTarget(t *testing.T) {
+	target := CopyTarget(t, "testdata/target")
+	fp := filepath.Join(target, "Users", "bob", "notes.txt")
+	if err := ioutil.WriteFile(fp, []byte("gamma\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	outputs := Run(t, notesModule{}, target, Options{})
+	if rows := outputs["NotesModule"]; len(rows) != 2 || rows[1][1] != "gamma" {
+		t.Errorf("Run() of the copy = %v, want the changed notes", rows)
+	}
+	if data, _ := ioutil.ReadFile("testdata/target/Users/bob/notes.txt"); strings.Contains(string(data), "gamma") {
+		t.Error("CopyTarget() changed the fixtures")
+	}
+}
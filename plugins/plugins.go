@@ -0,0 +1,411 @@
+// Package plugins runs external module executables that speak the Orion plugin protocol
+//
+// Orion starts the plugin, writes a single JSON-RPC 2.0 "run" request line to its stdin and closes stdin.
+// The plugin streams line delimited JSON-RPC notifications back on stdout:
+//
+//	{"jsonrpc":"2.0","method":"schema","params":{"output":"history","header":["user","visit_time","url"],"timeline":{...}}}
+//	{"jsonrpc":"2.0","method":"row","params":{"output":"history","values":["alice","2020-10-18T19:22:12Z","https://example.com"]}}
+//	{"jsonrpc":"2.0","method":"log","params":{"level":"warn","message":"skipped locked database"}}
+//
+// and finishes with a response to the request id, either {"jsonrpc":"2.0","id":1,"result":{}} or
+// {"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"..."}}. Stderr is captured into the Orion log.
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/timeline"
+	"go.uber.org/zap"
+)
+
+// ProtocolVersion is sent with every run request so plugins can reject versions they do not understand
+const ProtocolVersion = 1
+
+// maxLineBytes bounds a single protocol line written by a plugin
+const maxLineBytes = 16 * 1024 * 1024
+
+// stderrTailLines is the number of stderr lines kept for the error of a failed plugin
+const stderrTailLines = 20
+
+// exitGrace is how long a plugin has to exit after responding to the run request before it is killed
+var exitGrace = 10 * time.Second
+
+// Request is the params of the run request sent to a plugin
+type Request struct {
+	Protocol        int                    `json:"protocol"`
+	Module          string                 `json:"module"`
+	Mode            string                 `json:"mode"`
+	Target          string                 `json:"target"`
+	OSVersion       string                 `json:"os_version"`
+	ForensicMode    bool                   `json:"forensic_mode"`
+	TimestampFormat string                 `json:"timestamp_format"`
+	Config          map[string]interface{} `json:"config"`
+}
+
+// Result summarizes a plugin run
+type Result struct {
+	Rows     int
+	Outputs  []string
+	Timeline []timeline.Definition
+}
+
+// WriterFunc returns the writer for a plugin output name (ex. "MacZoomPlugin" or "MacZoomPlugin-meetings")
+type WriterFunc func(output string) (datawriter.OrionWriter, error)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type schemaParams struct {
+	Output   string   `json:"output"`
+	Header   []string `json:"header"`
+	Timeline *struct {
+		Events            []timeline.Event `json:"events"`
+		UserField         string           `json:"user_field"`
+		DescriptionFields []string         `json:"description_fields"`
+	} `json:"timeline"`
+}
+
+type rowParams struct {
+	Output string                 `json:"output"`
+	Values []interface{}          `json:"values"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type logParams struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// output is an open output file of a plugin
+type output struct {
+	header []string
+	mw     datawriter.OrionWriter
+}
+
+// Discover returns the plugin executables in dir by module name, the name of a plugin is its file name without extension
+func Discover(dir string) (map[string]string, error) {
+	found := make(map[string]string)
+	if dir == "" {
+		return found, nil
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		zap.L().Debug("Plugin directory '" + dir + "' does not exist")
+		return found, nil
+	} else if err != nil {
+		return found, err
+	}
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || !isExecutable(info) {
+			continue
+		}
+		name := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+		if existing, ok := found[name]; ok {
+			return found, fmt.Errorf("plugins '%s' and '%s' have the same module name", existing, info.Name())
+		}
+		found[name] = filepath.Join(dir, info.Name())
+	}
+	return found, nil
+}
+
+func isExecutable(info os.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(info.Name()), ".exe")
+	}
+	return info.Mode()&0111 != 0
+}
+
+// Run executes the plugin at path and writes the rows it streams back with writers from newWriter
+// The plugin is killed once timeout passes, a zero timeout disables the limit
+func Run(path string, req Request, timeout time.Duration, newWriter WriterFunc) (Result, error) {
+	result := Result{Outputs: []string{}, Timeline: []timeline.Definition{}}
+	req.Protocol = ProtocolVersion
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return result, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return result, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return result, err
+	}
+	if err := cmd.Start(); err != nil {
+		return result, fmt.Errorf("failed to start plugin '%s': %s", path, err.Error())
+	}
+
+	// Unblock the readers once the plugin times out, its children may still hold the pipes open
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdout.Close()
+			stderr.Close()
+		case <-done:
+		}
+	}()
+
+	// Capture stderr into the log, keeping the last lines for the error message
+	var stderrWg sync.WaitGroup
+	tail := []string{}
+	stderrWg.Add(1)
+	go func() {
+		defer stderrWg.Done()
+		scanner := bufio.NewScanner(stderr)
+		scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+		for scanner.Scan() {
+			line := scanner.Text()
+			zap.L().Debug("stderr: "+line, zap.String("module", req.Module))
+			tail = append(tail, line)
+			if len(tail) > stderrTailLines {
+				tail = tail[1:]
+			}
+		}
+	}()
+
+	id := 1
+	params, _ := json.Marshal(req)
+	line, _ := json.Marshal(message{JSONRPC: "2.0", ID: &id, Method: "run", Params: params})
+	_, err = stdin.Write(append(line, '\n'))
+	stdin.Close()
+	if err != nil {
+		zap.L().Debug("Failed to write run request: "+err.Error(), zap.String("module", req.Module))
+	}
+
+	outputs := make(map[string]*output)
+	protocolErr := readMessages(stdout, id, req.Module, newWriter, outputs, &result)
+	if protocolErr != nil {
+		// The plugin cannot be trusted to finish cleanly
+		cmd.Process.Kill()
+	}
+	// The plugin should exit once it responded, it is killed when it keeps stdout open past exitGrace so a plugin
+	// that never exits cannot hang the run when the timeout is disabled
+	drained := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, stdout)
+		close(drained)
+	}()
+	lingered := false
+	select {
+	case <-drained:
+	case <-time.After(exitGrace):
+		zap.L().Warn("Plugin did not exit "+exitGrace.String()+" after responding, killing it", zap.String("module", req.Module))
+		lingered = true
+		cmd.Process.Kill()
+		stdout.Close()
+		stderr.Close()
+		<-drained
+	}
+	for _, o := range outputs {
+		if err := o.mw.Close(); err != nil {
+			zap.L().Error("Failed to close plugin output: "+err.Error(), zap.String("module", req.Module))
+		}
+	}
+
+	stderrWg.Wait()
+	waitErr := cmd.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("plugin timed out after %s", timeout.String())
+	}
+	if protocolErr != nil {
+		return result, protocolErr
+	}
+	if waitErr != nil && !lingered {
+		msg := "plugin exited with error: " + waitErr.Error()
+		if len(tail) > 0 {
+			msg += ": " + strings.Join(tail, " | ")
+		}
+		return result, errors.New(msg)
+	}
+	return result, nil
+}
+
+// readMessages handles the notifications of a plugin until it responds to the run request
+func readMessages(r io.Reader, id int, module string, newWriter WriterFunc, outputs map[string]*output, result *Result) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return fmt.Errorf("invalid protocol message '%s': %s", truncate(line), err.Error())
+		}
+		if msg.ID != nil && msg.Method == "" {
+			if *msg.ID != id {
+				return fmt.Errorf("response for unknown request id %d", *msg.ID)
+			}
+			if msg.Error != nil {
+				return fmt.Errorf("plugin returned error %d: %s", msg.Error.Code, msg.Error.Message)
+			}
+			return nil
+		}
+
+		var err error
+		switch msg.Method {
+		case "schema":
+			err = handleSchema(msg.Params, module, newWriter, outputs, result)
+		case "row":
+			err = handleRow(msg.Params, module, outputs)
+			if err == nil {
+				result.Rows++
+			}
+		case "log":
+			handleLog(msg.Params, module)
+		default:
+			err = fmt.Errorf("unknown method '%s'", msg.Method)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("plugin closed stdout without responding to the run request")
+}
+
+func handleSchema(raw json.RawMessage, module string, newWriter WriterFunc, outputs map[string]*output, result *Result) error {
+	var p schemaParams
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("invalid schema: %s", err.Error())
+	}
+	if len(p.Header) == 0 {
+		return errors.New("schema header is empty")
+	}
+	if _, ok := outputs[p.Output]; ok {
+		return fmt.Errorf("schema for output '%s' sent more than once", p.Output)
+	}
+	name := module
+	if p.Output != "" {
+		name = module + "-" + p.Output
+	}
+	mw, err := newWriter(name)
+	if err != nil {
+		return err
+	}
+	if err := mw.WriteHeader(p.Header); err != nil {
+		return err
+	}
+	outputs[p.Output] = &output{header: p.Header, mw: mw}
+	result.Outputs = append(result.Outputs, name)
+	if p.Timeline != nil && len(p.Timeline.Events) > 0 {
+		result.Timeline = append(result.Timeline, timeline.Definition{
+			Output:            name,
+			Events:            p.Timeline.Events,
+			UserField:         p.Timeline.UserField,
+			DescriptionFields: p.Timeline.DescriptionFields,
+		})
+	}
+	return nil
+}
+
+func handleRow(raw json.RawMessage, module string, outputs map[string]*output) error {
+	var p rowParams
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&p); err != nil {
+		return fmt.Errorf("invalid row: %s", err.Error())
+	}
+	o, ok := outputs[p.Output]
+	if !ok {
+		return fmt.Errorf("row for output '%s' sent before its schema", p.Output)
+	}
+	entry := make([]string, len(o.header))
+	if p.Fields != nil {
+		for i, h := range o.header {
+			entry[i] = stringify(p.Fields[h])
+		}
+	} else {
+		if len(p.Values) != len(o.header) {
+			return fmt.Errorf("row has %d values, output '%s' has %d columns", len(p.Values), p.Output, len(o.header))
+		}
+		for i, v := range p.Values {
+			entry[i] = stringify(v)
+		}
+	}
+	return o.mw.Write(entry)
+}
+
+func handleLog(raw json.RawMessage, module string) {
+	var p logParams
+	if err := json.Unmarshal(raw, &p); err != nil {
+		zap.L().Warn("Invalid plugin log message: "+err.Error(), zap.String("module", module))
+		return
+	}
+	switch strings.ToLower(p.Level) {
+	case "error":
+		zap.L().Error(p.Message, zap.String("module", module))
+	case "warn", "warning":
+		zap.L().Warn(p.Message, zap.String("module", module))
+	case "info":
+		zap.L().Info(p.Message, zap.String("module", module))
+	default:
+		zap.L().Debug(p.Message, zap.String("module", module))
+	}
+}
+
+// stringify returns the output string of a JSON value
+func stringify(v interface{}) string {
+	switch u := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return u
+	case json.Number:
+		return u.String()
+	case bool:
+		return fmt.Sprintf("%t", u)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func truncate(s string) string {
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthonybm/Orion/datawriter"
+)
+
+// writePlugin writes a shell script plugin to dir and returns its path
+func writePlugin(t *testing.T, dir string, name string, script string) string {
+	fp := filepath.Join(dir, name)
+	err := ioutil.WriteFile(fp, []byte("#!/bin/sh\nread request\n"+script), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+func runPlugin(t *testing.T, script string, timeout time.Duration) (Result, string, error) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins are not supported on windows")
+	}
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fp := writePlugin(t, dir, "TestPlugin", script)
+	newWriter := func(output string) (datawriter.OrionWriter, error) {
+		return datawriter.NewOrionWriter(output, "test", "csv", dir)
+	}
+	res, err := Run(fp, Request{Module: "TestPlugin", Target: "/"}, timeout, newWriter)
+	return res, dir, err
+}
+
+func TestRun(t *testing.T) {
+	script := `echo "$request" >&2
+echo '{"jsonrpc":"2.0","method":"schema","params":{"header":["a","b"],"timeline":{"events":[{"field":"b","type":"Seen"}]}}}'
+echo '{"jsonrpc":"2.0","method":"schema","params":{"output":"extra","header":["c"]}}'
+echo '{"jsonrpc":"2.0","method":"row","params":{"values":["x",13247522532000001]}}'
+echo '{"jsonrpc":"2.0","method":"row","params":{"fields":{"b":true}}}'
+echo '{"jsonrpc":"2.0","method":"log","params":{"level":"info","message":"hello"}}'
+echo '{"jsonrpc":"2.0","method":"row","params":{"output":"extra","values":[null]}}'
+echo '{"jsonrpc":"2.0","id":1,"result":{}}'
+`
+	res, dir, err := runPlugin(t, script, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 3 || len(res.Outputs) != 2 || len(res.Timeline) != 1 || res.Timeline[0].Output != "TestPlugin" {
+		t.Errorf("Run() = %+v", res)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "test_TestPlugin.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "a,b\nx,13247522532000001\n,true\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	data, err = ioutil.ReadFile(filepath.Join(dir, "test_TestPlugin-extra.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "c\n\"\"\n"; got != want && got != "c\n\n" {
+		t.Errorf("extra output = %q, want %q", got, want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"error response", `echo '{"jsonrpc":"2.0","id":1,"error":{"code":2,"message":"no database"}}'`, "no database"},
+		{"row before schema", `echo '{"jsonrpc":"2.0","method":"row","params":{"values":["x"]}}'`, "before its schema"},
+		{"wrong row width", `echo '{"jsonrpc":"2.0","method":"schema","params":{"header":["a"]}}'
+echo '{"jsonrpc":"2.0","method":"row","params":{"values":["x","y"]}}'`, "has 2 values"},
+		{"garbage", `echo 'not json'`, "invalid protocol message"},
+		{"no response", `echo 'oops' >&2; exit 3`, "without responding"},
+		{"exit status", `echo '{"jsonrpc":"2.0","id":1,"result":{}}'; echo 'boom' >&2; exit 3`, "boom"},
+		{"timeout", `sleep 5`, "timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := runPlugin(t, tt.script, time.Second)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Run() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunLingering(t *testing.T) {
+	defer func(grace time.Duration) { exitGrace = grace }(exitGrace)
+	exitGrace = 100 * time.Millisecond
+	// without a timeout a plugin that responds and keeps running is killed instead of blocking the run
+	script := `echo '{"jsonrpc":"2.0","method":"schema","params":{"header":["a"]}}'
+echo '{"jsonrpc":"2.0","method":"row","params":{"values":["x"]}}'
+echo '{"jsonrpc":"2.0","id":1,"result":{}}'
+exec sleep 30
+`
+	start := time.Now()
+	res, _, err := runPlugin(t, script, 0)
+	if err != nil || res.Rows != 1 {
+		t.Errorf("Run() = %+v, %v", res, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Run() returned after %s, want the plugin killed", elapsed)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not used on windows")
+	}
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writePlugin(t, dir, "MacOne.py", "")
+	writePlugin(t, dir, "MacTwo", "")
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0600)
+
+	found, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found["MacOne"] != filepath.Join(dir, "MacOne.py") || found["MacTwo"] == "" {
+		t.Errorf("Discover() = %v", found)
+	}
+	if found, err := Discover(filepath.Join(dir, "missing")); err != nil || len(found) != 0 {
+		t.Errorf("Discover(missing) = %v, %v", found, err)
+	}
+
+	writePlugin(t, dir, "MacTwo.sh", "")
+	if _, err := Discover(dir); err == nil {
+		t.Error("Discover expected an error for duplicate plugin names")
+	}
+}
@@ -0,0 +1,146 @@
+package report
+
+import (
+	"bufio"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Suffix ends the name of the report written next to the module output, <runtime>_Report.html
+const Suffix = "_Report.html"
+
+// barHeight is the height in pixels of the tallest bar of the histogram, the height of .histogram in the page style
+const barHeight = 120
+
+var funcs = template.FuncMap{
+	"height": func(count int, max int) int {
+		if max == 0 || count == 0 {
+			return 0
+		}
+		// keep single events visible next to busy buckets
+		if h := count * barHeight / max; h > 0 {
+			return h
+		}
+		return 1
+	},
+	"first": func(buckets []Bucket) Bucket { return buckets[0] },
+	"last":  func(buckets []Bucket) Bucket { return buckets[len(buckets)-1] },
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	},
+}
+
+var page = template.Must(template.New("report").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Orion report {{.Runtime}}{{if .Host}} {{.Host}}{{end}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 2em; color: #222; }
+nav a { margin-right: 1em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+table.data th { cursor: pointer; user-select: none; }
+table.data th.asc:after { content: " \25B2"; }
+table.data th.desc:after { content: " \25BC"; }
+td { font-family: monospace; word-break: break-all; max-width: 40em; }
+tr.warn { background: #fff5b1; }
+tr.alert { background: #ffdce0; }
+p.note { color: #555; }
+input.filter { margin-bottom: 0.5em; width: 20em; }
+.histogram { display: flex; align-items: flex-end; height: 120px; border-bottom: 1px solid #999; overflow-x: auto; }
+.histogram div { background: #4a7ab5; min-width: 3px; flex: 1 0 3px; margin-right: 1px; }
+.histogram div:hover { background: #e36209; }
+.axis { display: flex; justify-content: space-between; color: #555; }
+</style>
+</head>
+<body>
+<h1>Orion report</h1>
+<table>
+<tr><th>Runtime</th><td>{{.Runtime}}</td></tr>
+{{if .Host}}<tr><th>Host</th><td>{{.Host}}</td></tr>
+{{end}}{{if .Mode}}<tr><th>Mode</th><td>{{.Mode}}</td></tr>
+{{end}}{{if not .Started.IsZero}}<tr><th>Started</th><td>{{date .Started}}</td></tr>
+<tr><th>Finished</th><td>{{date .Finished}}</td></tr>
+{{end}}<tr><th>Generated</th><td>{{date .Generated}}</td></tr>
+</table>
+<nav>{{if .System}}<a href="#{{.System.ID}}">{{.System.Title}}</a>{{end}}{{range .Tables}}<a href="#{{.ID}}">{{.Title}}</a>{{end}}<a href="#timeline">Timeline</a><a href="#{{.Outputs.ID}}">{{.Outputs.Title}}</a></nav>
+{{with .System}}{{template "table" .}}{{end}}
+{{range .Tables}}{{template "table" .}}{{end}}
+<h2 id="timeline">Timeline</h2>
+{{with .Histogram}}{{if .Buckets}}<p class="note">{{.Total}} events of the {{.Source}} per {{.Unit}}, UTC</p>
+<div class="histogram">{{$max := .Max}}{{range .Buckets}}<div style="height: {{height .Count $max}}px" title="{{.Label}}: {{.Count}}"></div>{{end}}</div>
+<div class="axis"><span>{{(first .Buckets).Label}}</span><span>peak {{.Max}} per {{.Unit}}</span><span>{{(last .Buckets).Label}}</span></div>
+{{else}}<p class="note">No dated events found</p>
+{{end}}{{end}}
+{{template "table" .Outputs}}
+<script>
+(function () {
+  function text(row, n) { return row.cells[n] ? row.cells[n].textContent : ""; }
+  function compare(a, b) {
+    var x = parseFloat(a), y = parseFloat(b);
+    if (!isNaN(x) && !isNaN(y) && String(x) === a.trim() && String(y) === b.trim()) { return x - y; }
+    return a.localeCompare(b);
+  }
+  document.querySelectorAll("table.data").forEach(function (table) {
+    var body = table.tBodies[0];
+    table.querySelectorAll("th").forEach(function (th, n) {
+      th.addEventListener("click", function () {
+        var asc = !th.classList.contains("asc");
+        table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+        th.classList.add(asc ? "asc" : "desc");
+        Array.prototype.slice.call(body.rows).sort(function (a, b) {
+          return asc ? compare(text(a, n), text(b, n)) : compare(text(b, n), text(a, n));
+        }).forEach(function (row) { body.appendChild(row); });
+      });
+    });
+    var filter = document.getElementById(table.id + "-filter");
+    filter.addEventListener("input", function () {
+      var words = filter.value.toLowerCase().split(/\s+/).filter(Boolean);
+      Array.prototype.forEach.call(body.rows, function (row) {
+        var content = row.textContent.toLowerCase();
+        row.style.display = words.every(function (w) { return content.indexOf(w) >= 0; }) ? "" : "none";
+      });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+{{define "table"}}<h2 id="{{.ID}}">{{.Title}}</h2>
+{{if .Note}}<p class="note">{{.Note}}</p>
+{{end}}<input class="filter" id="{{.ID}}-table-filter" type="search" placeholder="Filter">
+<table class="data" id="{{.ID}}-table">
+<thead><tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr{{if .Class}} class="{{.Class}}"{{end}}>{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+{{end}}`))
+
+// WriteHTML writes the report to w as a standalone HTML page, styles and scripts are inline so it opens offline
+func WriteHTML(w io.Writer, r Report) error {
+	return page.Execute(w, r)
+}
+
+// WriteFile writes the report to <dir>/<runtime>_Report.html and returns the file path
+func WriteFile(r Report, dir string) (string, error) {
+	fp := filepath.Join(dir, r.Runtime+Suffix)
+	f, err := os.Create(fp)
+	if err != nil {
+		return fp, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := WriteHTML(w, r); err != nil {
+		return fp, err
+	}
+	return fp, w.Flush()
+}
@@ -0,0 +1,538 @@
+// Package report builds a self-contained HTML summary of an Orion run: system information, users, suspicious
+// autoruns, recent quarantine events and downloads, SSH keys, network connections, module errors and a histogram
+// of timeline events, so a first look at a run needs no spreadsheet
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthonybm/Orion/baseline"
+	"github.com/anthonybm/Orion/timeline"
+)
+
+// Output names of the modules the report summarises
+const (
+	systemInfoOutput       = "MacSystemInfoModule"
+	usersOutput            = "MacUsersModule"
+	autorunsOutput         = "MacAutorunsModule"
+	quarantinesOutput      = "MacQuarantinesModule"
+	chromeDownloadsOutput  = "MacChromeModule-downloads"
+	firefoxDownloadsOutput = "MacFirefoxModule-downloads"
+	sshOutput              = "MacSSHModule"
+	netstatOutput          = "MacLiveNetstat"
+	timelineOutput         = timeline.Output
+)
+
+// RecentDays is how far back from the latest event quarantine events and downloads are listed
+const RecentDays = 30
+
+// Row classes highlighting rows of a table
+const (
+	ClassAlert = "alert"
+	ClassWarn  = "warn"
+)
+
+// Row is a row of a table, Class highlights it (ex. admins, unsigned autoruns)
+type Row struct {
+	Class string
+	Cells []string
+}
+
+// Table is a section of the report
+type Table struct {
+	ID      string
+	Title   string
+	Note    string
+	Columns []string
+	Rows    []Row
+}
+
+// Bucket is a bar of the timeline histogram
+type Bucket struct {
+	Label string
+	Count int
+}
+
+// Histogram counts timeline events per hour, day or month depending on the span of the events
+type Histogram struct {
+	Unit    string
+	Source  string
+	Total   int
+	Max     int
+	Buckets []Bucket
+}
+
+// Report is the summary of a run
+type Report struct {
+	Runtime   string
+	Host      string
+	Mode      string
+	Started   time.Time
+	Finished  time.Time
+	Generated time.Time
+	System    *Table
+	Tables    []Table
+	Histogram Histogram
+	Outputs   Table
+}
+
+// Build summarises the run read from run, defs are the timeline definitions used for the histogram when the run
+// has no super-timeline
+// Sections whose module did not run are left out
+func Build(run *baseline.Baseline, defs []timeline.Definition) (Report, error) {
+	r := Report{Runtime: run.Runtime(), Generated: time.Now().UTC()}
+	if m := run.Manifest(); m != nil {
+		r.Host, r.Mode, r.Started, r.Finished = m.Host, m.Mode, m.Started, m.Finished
+	}
+
+	type section func(*baseline.Baseline) (*Table, error)
+	var err error
+	if r.System, err = systemTable(run); err != nil {
+		return r, err
+	}
+	for _, build := range []section{usersTable, autorunsTable, quarantinesTable, downloadsTable, sshTable, netstatTable, errorsTable} {
+		t, err := build(run)
+		if err != nil {
+			return r, err
+		}
+		if t != nil {
+			r.Tables = append(r.Tables, *t)
+		}
+	}
+	if r.Histogram, err = histogram(run, defs); err != nil {
+		return r, err
+	}
+	if r.Outputs, err = outputsTable(run); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// records returns the rows of output by column name, nil when the run did not write it
+func records(run *baseline.Baseline, output string) ([]map[string]string, error) {
+	if !run.Has(output) {
+		return nil, nil
+	}
+	out := []map[string]string{}
+	err := run.Each(output, func(header []string, row []string) {
+		m := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(row) {
+				m[name] = row[i]
+			}
+		}
+		out = append(out, m)
+	})
+	return out, err
+}
+
+// cells returns the values of columns in rec
+func cells(rec map[string]string, columns ...string) []string {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = rec[c]
+	}
+	return values
+}
+
+// systemTable lists the system information as name and value pairs
+func systemTable(run *baseline.Baseline) (*Table, error) {
+	if !run.Has(systemInfoOutput) {
+		return nil, nil
+	}
+	t := &Table{ID: "system", Title: "System", Columns: []string{"name", "value"}, Rows: []Row{}}
+	err := run.Each(systemInfoOutput, func(header []string, row []string) {
+		for i, name := range header {
+			if i < len(row) {
+				t.Rows = append(t.Rows, Row{Cells: []string{name, row[i]}})
+			}
+		}
+	})
+	return t, err
+}
+
+// usersTable lists local users, admins are highlighted
+func usersTable(run *baseline.Baseline) (*Table, error) {
+	recs, err := records(run, usersOutput)
+	if recs == nil || err != nil {
+		return nil, err
+	}
+	columns := []string{"user", "real_name", "unique_id", "admin", "last_logged_in_user", "btime", "mtime", "date_deleted"}
+	t := &Table{ID: "users", Title: "Users", Columns: columns, Rows: []Row{}}
+	admins := 0
+	for _, rec := range recs {
+		row := Row{Cells: cells(rec, columns...)}
+		if rec["admin"] == "true" {
+			row.Class = ClassWarn
+			admins++
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	t.Note = strconv.Itoa(len(recs)) + " users, " + strconv.Itoa(admins) + " admins (highlighted)"
+	return t, nil
+}
+
+// unusualDirs are locations autoruns rarely run from legitimately
+var unusualDirs = []string{"/tmp/", "/private/tmp/", "/var/tmp/", "/private/var/tmp/", "/users/shared/", "/library/caches/"}
+
+// interpreters run scripts or fetch payloads, autoruns launching them deserve a look
+var interpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "csh": true, "tcsh": true, "ksh": true,
+	"python": true, "perl": true, "ruby": true, "php": true, "node": true,
+	"osascript": true, "curl": true, "wget": true, "nc": true, "ncat": true, "openssl": true,
+}
+
+// AutorunReasons returns why an autorun row is suspicious, empty for unremarkable ones
+func AutorunReasons(rec map[string]string) []string {
+	reasons := []string{}
+	switch sig := strings.Trim(rec["code_signatures"], "[]"); {
+	case strings.EqualFold(sig, "Unsigned"):
+		reasons = append(reasons, "unsigned")
+	case strings.HasPrefix(sig, "ERROR"):
+		reasons = append(reasons, "signature check failed ("+sig+")")
+	}
+
+	program := strings.ToLower(rec["program"])
+	for _, dir := range unusualDirs {
+		if strings.HasPrefix(program, dir) {
+			reasons = append(reasons, "runs from "+dir)
+			break
+		}
+	}
+	if strings.HasPrefix(program, "/users/") && !strings.HasPrefix(program, "/users/shared/") && !strings.Contains(program, "/applications/") {
+		reasons = append(reasons, "runs from a home directory")
+	}
+	for _, part := range strings.Split(program, "/") {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			reasons = append(reasons, "hidden path")
+			break
+		}
+	}
+	// versioned interpreters are named like python3.8
+	if interpreters[strings.TrimRight(path.Base(program), "0123456789.")] {
+		reasons = append(reasons, "runs "+path.Base(program))
+	}
+	return reasons
+}
+
+// autorunsTable lists the autoruns that are unsigned or run from unusual locations or through interpreters
+func autorunsTable(run *baseline.Baseline) (*Table, error) {
+	recs, err := records(run, autorunsOutput)
+	if recs == nil || err != nil {
+		return nil, err
+	}
+	columns := []string{"source_name", "source_file", "program", "arguments", "code_signatures", "sha256", "mtime"}
+	t := &Table{ID: "autoruns", Title: "Unsigned or unusual autoruns", Columns: append([]string{"reasons"}, columns...), Rows: []Row{}}
+	for _, rec := range recs {
+		reasons := AutorunReasons(rec)
+		if len(reasons) == 0 {
+			continue
+		}
+		row := Row{Class: ClassWarn, Cells: append([]string{strings.Join(reasons, ", ")}, cells(rec, columns...)...)}
+		if len(reasons) > 1 {
+			row.Class = ClassAlert
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	t.Note = strconv.Itoa(len(t.Rows)) + " of " + strconv.Itoa(len(recs)) + " autoruns, rows with several reasons are highlighted"
+	return t, nil
+}
+
+// event is a row with the time of its event
+type event struct {
+	time time.Time
+	row  Row
+}
+
+// recent keeps the events within RecentDays of the latest one, latest first
+func recent(events []event) ([]Row, time.Time) {
+	var latest time.Time
+	for _, ev := range events {
+		if ev.time.After(latest) {
+			latest = ev.time
+		}
+	}
+	since := latest.AddDate(0, 0, -RecentDays)
+	kept := []event{}
+	for _, ev := range events {
+		if !ev.time.Before(since) {
+			kept = append(kept, ev)
+		}
+	}
+	sort.SliceStable(kept, func(a, b int) bool { return kept[a].time.After(kept[b].time) })
+	rows := make([]Row, len(kept))
+	for n, ev := range kept {
+		rows[n] = ev.row
+	}
+	return rows, latest
+}
+
+// recentNote describes what a table of recent events holds
+func recentNote(rows []Row, total int, latest time.Time) string {
+	if latest.IsZero() {
+		return "No dated events among " + strconv.Itoa(total) + " rows"
+	}
+	return strconv.Itoa(len(rows)) + " of " + strconv.Itoa(total) + " events in the " + strconv.Itoa(RecentDays) +
+		" days up to the latest one (" + latest.Format(time.RFC3339) + ")"
+}
+
+// quarantinesTable lists the recent quarantine events
+func quarantinesTable(run *baseline.Baseline) (*Table, error) {
+	recs, err := records(run, quarantinesOutput)
+	if recs == nil || err != nil {
+		return nil, err
+	}
+	columns := []string{"TimeStamp", "user", "AgentName", "DataURLString", "OriginURLString", "SenderName", "SenderAddress"}
+	events := []event{}
+	for _, rec := range recs {
+		t, err := timeline.ParseTime(rec["TimeStamp"], run.TimestampFormat())
+		if err != nil {
+			continue
+		}
+		events = append(events, event{time: t, row: Row{Cells: cells(rec, columns...)}})
+	}
+	rows, latest := recent(events)
+	return &Table{ID: "quarantines", Title: "Recent quarantine events", Note: recentNote(rows, len(recs), latest), Columns: columns, Rows: rows}, nil
+}
+
+// downloadsTable lists the recent downloads of every browser
+func downloadsTable(run *baseline.Baseline) (*Table, error) {
+	columns := []string{"download_started", "browser", "user", "download_url", "path"}
+	sources := []struct {
+		browser, output, path string
+	}{
+		{"Chrome", chromeDownloadsOutput, "current_path"},
+		{"Firefox", firefoxDownloadsOutput, "download_path"},
+	}
+	events := []event{}
+	total, found := 0, false
+	for _, src := range sources {
+		recs, err := records(run, src.output)
+		if err != nil {
+			return nil, err
+		}
+		found = found || recs != nil
+		total += len(recs)
+		for _, rec := range recs {
+			t, err := timeline.ParseTime(rec["download_started"], run.TimestampFormat())
+			if err != nil {
+				continue
+			}
+			events = append(events, event{time: t, row: Row{Cells: []string{rec["download_started"], src.browser, rec["user"], rec["download_url"], rec[src.path]}}})
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	rows, latest := recent(events)
+	return &Table{ID: "downloads", Title: "Recent downloads", Note: recentNote(rows, total, latest), Columns: columns, Rows: rows}, nil
+}
+
+// sshTable lists SSH keys and known hosts
+func sshTable(run *baseline.Baseline) (*Table, error) {
+	recs, err := records(run, sshOutput)
+	if recs == nil || err != nil {
+		return nil, err
+	}
+	columns := []string{"source_name", "user", "keytype", "bits", "fingerprint", "host", "comment", "options", "flags"}
+	t := &Table{ID: "ssh", Title: "SSH keys", Columns: columns, Rows: []Row{}}
+	for _, rec := range recs {
+		row := Row{Cells: cells(rec, columns...)}
+		if strings.Contains(rec["source_name"], "authorized_keys") || rec["flags"] != "" {
+			row.Class = ClassWarn
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	t.Note = strconv.Itoa(len(recs)) + " keys, authorized keys and flagged keys are highlighted"
+	return t, nil
+}
+
+// netstatTable lists the live network connections, established connections to remote hosts first
+func netstatTable(run *baseline.Baseline) (*Table, error) {
+	recs, err := records(run, netstatOutput)
+	if recs == nil || err != nil {
+		return nil, err
+	}
+	columns := []string{"protocol", "state", "source_ip", "source_port", "dest_ip", "dest_port"}
+	t := &Table{ID: "network", Title: "Network connections", Columns: columns, Rows: []Row{}}
+	established := 0
+	for _, rec := range recs {
+		row := Row{Cells: cells(rec, columns...)}
+		if strings.EqualFold(rec["state"], "ESTABLISHED") && !loopback(rec["dest_ip"]) {
+			row.Class = ClassWarn
+			established++
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	sort.SliceStable(t.Rows, func(a, b int) bool { return t.Rows[a].Class != "" && t.Rows[b].Class == "" })
+	t.Note = strconv.Itoa(len(recs)) + " connections, " + strconv.Itoa(established) + " established to remote hosts (highlighted)"
+	return t, nil
+}
+
+func loopback(ip string) bool {
+	return strings.HasPrefix(ip, "127.") || ip == "::1" || ip == "localhost" || ip == "*"
+}
+
+// ansi matches the color codes of the log level
+var ansi = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// errorsTable lists the errors logged during the run
+func errorsTable(run *baseline.Baseline) (*Table, error) {
+	f, err := run.Log()
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	columns := []string{"time", "level", "module", "message", "error"}
+	t := &Table{ID: "errors", Title: "Module errors", Columns: columns, Rows: []Row{}}
+	warnings := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		entry := map[string]interface{}{}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		values := make(map[string]string, len(entry))
+		for k, v := range entry {
+			if s, ok := v.(string); ok {
+				values[k] = ansi.ReplaceAllString(s, "")
+			}
+		}
+		switch values["level"] {
+		case "ERROR", "DPANIC", "PANIC", "FATAL":
+			t.Rows = append(t.Rows, Row{Class: ClassAlert, Cells: cells(values, columns...)})
+		case "WARN":
+			warnings++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	t.Note = strconv.Itoa(len(t.Rows)) + " errors and " + strconv.Itoa(warnings) + " warnings logged, see the log for warnings"
+	return t, nil
+}
+
+// histogram counts the events of the super-timeline of the run, or of the outputs described by defs when the run
+// has none
+func histogram(run *baseline.Baseline, defs []timeline.Definition) (Histogram, error) {
+	times := []time.Time{}
+	h := Histogram{Buckets: []Bucket{}}
+	if run.HasDerived(timelineOutput) {
+		h.Source = "super-timeline"
+		def := timeline.Definition{Output: timelineOutput, Events: []timeline.Event{{Field: "timestamp"}}}
+		defs = []timeline.Definition{def}
+	} else {
+		h.Source = "module outputs"
+	}
+	for _, def := range defs {
+		if !run.Has(def.Output) && !run.HasDerived(def.Output) {
+			continue
+		}
+		err := run.Each(def.Output, func(header []string, row []string) {
+			for _, ev := range def.Events {
+				for i, name := range header {
+					if name != ev.Field || i >= len(row) || row[i] == "" {
+						continue
+					}
+					if t, err := timeline.ParseTime(row[i], run.TimestampFormat()); err == nil {
+						times = append(times, t)
+					}
+				}
+			}
+		})
+		if err != nil {
+			return h, err
+		}
+	}
+	return bucket(h, times), nil
+}
+
+// bucket counts times per hour when they span up to two days, per day up to 120 days and per month beyond
+// Times before 1990, zero dates written by modules, are left out
+func bucket(h Histogram, times []time.Time) Histogram {
+	floor := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	kept := times[:0]
+	for _, t := range times {
+		if !t.Before(floor) {
+			kept = append(kept, t.UTC())
+		}
+	}
+	if len(kept) == 0 {
+		return h
+	}
+	sort.Slice(kept, func(a, b int) bool { return kept[a].Before(kept[b]) })
+	first, last := kept[0], kept[len(kept)-1]
+
+	var truncate func(time.Time) time.Time
+	var next func(time.Time) time.Time
+	var layout string
+	switch span := last.Sub(first); {
+	case span <= 48*time.Hour:
+		h.Unit, layout = "hour", "2006-01-02 15:00"
+		truncate = func(t time.Time) time.Time { return t.Truncate(time.Hour) }
+		next = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case span <= 120*24*time.Hour:
+		h.Unit, layout = "day", "2006-01-02"
+		truncate = func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	default:
+		h.Unit, layout = "month", "2006-01"
+		truncate = func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC) }
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	}
+
+	// empty buckets are kept so gaps in activity show
+	n := 0
+	for b := truncate(first); !b.After(last); b = next(b) {
+		bucket := Bucket{Label: b.Format(layout)}
+		for end := next(b); n < len(kept) && kept[n].Before(end); n++ {
+			bucket.Count++
+		}
+		if bucket.Count > h.Max {
+			h.Max = bucket.Count
+		}
+		h.Total += bucket.Count
+		h.Buckets = append(h.Buckets, bucket)
+	}
+	return h
+}
+
+// outputsTable lists every output of the run with its row count
+func outputsTable(run *baseline.Baseline) (Table, error) {
+	t := Table{ID: "outputs", Title: "Outputs", Columns: []string{"output", "rows"}, Rows: []Row{}}
+	for _, output := range run.Outputs() {
+		count := 0
+		if out, ok := manifestOutput(run, output); ok {
+			count = out.Rows
+		} else if err := run.Each(output, func(header []string, row []string) { count++ }); err != nil {
+			return t, err
+		}
+		row := Row{Cells: []string{output, strconv.Itoa(count)}}
+		if count == 0 {
+			row.Class = ClassWarn
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// manifestOutput returns the description of output recorded in the manifest of the run
+func manifestOutput(run *baseline.Baseline, output string) (baseline.Output, bool) {
+	if m := run.Manifest(); m != nil {
+		out, ok := m.Outputs[output]
+		return out, ok
+	}
+	return baseline.Output{}, false
+}
@@ -0,0 +1,177 @@
+package report
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthonybm/Orion/baseline"
+	"github.com/anthonybm/Orion/timeline"
+)
+
+const runtime = "Orion_2020-08-02T10_00_00Z"
+
+// writeRun writes the output files and log of a run to dir
+func writeRun(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, runtime+name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAutorunReasons(t *testing.T) {
+	for _, tc := range []struct {
+		rec  map[string]string
+		want []string
+	}{
+		{map[string]string{"program": "/usr/libexec/logd", "code_signatures": "[Software Signing Apple Code Signing Certification Authority Apple Root CA]"}, []string{}},
+		{map[string]string{"program": "/Applications/App.app/Contents/MacOS/App", "code_signatures": "[Unsigned]"}, []string{"unsigned"}},
+		{map[string]string{"program": "/private/tmp/.x/update", "code_signatures": "[ERROR-FILE-DNE]"}, []string{"signature check failed (ERROR-FILE-DNE)", "runs from /private/tmp/", "hidden path"}},
+		{map[string]string{"program": "/Users/bob/Library/agent", "code_signatures": "[Developer ID Application: Bob]"}, []string{"runs from a home directory"}},
+		{map[string]string{"program": "/usr/local/bin/python3.8", "code_signatures": "[Developer ID Application: Python]"}, []string{"runs python3.8"}},
+	} {
+		if got := AutorunReasons(tc.rec); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("AutorunReasons(%v) = %v, want %v", tc.rec["program"], got, tc.want)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	base := time.Date(2020, 8, 1, 10, 30, 0, 0, time.UTC)
+	// zero dates written by modules are left out
+	times := []time.Time{base, base.Add(2 * time.Hour), base.Add(2 * time.Hour), time.Unix(0, 0), {}}
+	h := bucket(Histogram{}, times)
+	if h.Unit != "hour" || h.Total != 3 || h.Max != 2 || len(h.Buckets) != 3 || h.Buckets[0].Label != "2020-08-01 10:00" || h.Buckets[1].Count != 0 {
+		t.Errorf("bucket(hours) = %+v", h)
+	}
+	h = bucket(Histogram{}, []time.Time{base, base.AddDate(0, 0, 9)})
+	if h.Unit != "day" || len(h.Buckets) != 10 {
+		t.Errorf("bucket(days) = %+v", h)
+	}
+	h = bucket(Histogram{}, []time.Time{base, base.AddDate(2, 0, 0)})
+	if h.Unit != "month" || len(h.Buckets) != 25 || h.Buckets[24].Label != "2022-08" {
+		t.Errorf("bucket(months) = %+v", h)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeRun(t, dir, map[string]string{
+		"_MacSystemInfoModule.csv": "hostname,os_version\nsuspect,10.15.6\n",
+		"_MacUsersModule.csv":      "user,admin\nbob,true\nalice,false\n",
+		"_MacAutorunsModule.csv": "source_name,program,code_signatures\n" +
+			"launch_agents,/usr/libexec/logd,[Apple Root CA]\n" +
+			"launch_agents,/tmp/<script>evil</script>,[Unsigned]\n",
+		"_MacQuarantinesModule.csv": "TimeStamp,user,DataURLString\n" +
+			"2020-08-01T09:00:00Z,bob,https://recent.example\n" +
+			"2020-05-01T09:00:00Z,bob,https://old.example\n",
+		"_MacChromeModule-downloads.csv": "download_url,current_path,download_started,user\nhttps://dl.example,/Users/bob/Downloads/a.dmg,2020-08-01T08:00:00Z,bob\n",
+		"_MacLiveNetstat.csv":            "protocol,state,source_ip,source_port,dest_ip,dest_port\ntcp4,LISTEN,*,22,*,*\ntcp4,ESTABLISHED,10.0.0.2,5000,93.184.216.34,443\n",
+		"_MacDirlistModule.csv":          "path,mtime\n/a,2020-08-01T09:10:00Z\n/b,2020-08-01T11:10:00Z\n",
+		".json": `{"level":"\u001b[34mINFO\u001b[0m","time":"2020-08-02T10:00:00.000Z","message":"Starting"}` + "\n" +
+			`{"level":"\u001b[31mERROR\u001b[0m","time":"2020-08-02T10:00:01.000Z","message":"Failed to parse","module":"MacSSHModule","error":"permission denied"}` + "\n" +
+			`{"level":"\u001b[33mWARN\u001b[0m","time":"2020-08-02T10:00:02.000Z","message":"Skipping"}` + "\n",
+	})
+	run, err := baseline.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := []timeline.Definition{{Output: "MacDirlistModule", Events: []timeline.Event{{Field: "mtime", Type: "Modified"}}}}
+	r, err := Build(run, defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.System == nil || !reflect.DeepEqual(r.System.Rows[0].Cells, []string{"hostname", "suspect"}) {
+		t.Errorf("system = %+v", r.System)
+	}
+	tables := map[string]Table{}
+	for _, table := range r.Tables {
+		tables[table.ID] = table
+	}
+	if _, ok := tables["ssh"]; ok {
+		t.Error("ssh section is present for a run without MacSSHModule output")
+	}
+	if users := tables["users"]; len(users.Rows) != 2 || users.Rows[0].Class != ClassWarn || users.Rows[1].Class != "" {
+		t.Errorf("users = %+v", users)
+	}
+	if autoruns := tables["autoruns"]; len(autoruns.Rows) != 1 || autoruns.Rows[0].Cells[0] != "unsigned, runs from /tmp/" {
+		t.Errorf("autoruns = %+v", autoruns)
+	}
+	if quarantines := tables["quarantines"]; len(quarantines.Rows) != 1 || quarantines.Rows[0].Cells[3] != "https://recent.example" {
+		t.Errorf("quarantines = %+v", quarantines)
+	}
+	if downloads := tables["downloads"]; len(downloads.Rows) != 1 || downloads.Rows[0].Cells[1] != "Chrome" {
+		t.Errorf("downloads = %+v", downloads)
+	}
+	if network := tables["network"]; network.Rows[0].Class != ClassWarn || network.Rows[0].Cells[4] != "93.184.216.34" {
+		t.Errorf("network = %+v", network)
+	}
+	if errors := tables["errors"]; len(errors.Rows) != 1 || !reflect.DeepEqual(errors.Rows[0].Cells, []string{"2020-08-02T10:00:01.000Z", "ERROR", "MacSSHModule", "Failed to parse", "permission denied"}) {
+		t.Errorf("errors = %+v", errors)
+	}
+	if r.Histogram.Source != "module outputs" || r.Histogram.Total != 2 || len(r.Histogram.Buckets) != 3 {
+		t.Errorf("histogram = %+v", r.Histogram)
+	}
+	if len(r.Outputs.Rows) != 7 {
+		t.Errorf("outputs = %+v", r.Outputs)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	if strings.Contains(html, "<script>evil") || !strings.Contains(html, "&lt;script&gt;evil") {
+		t.Error("html report does not escape values")
+	}
+	for _, id := range []string{`id="users-table"`, `id="users-table-filter"`, `id="timeline"`, `title="2020-08-01 09:00: 1"`} {
+		if !strings.Contains(html, id) {
+			t.Errorf("html report is missing %s", id)
+		}
+	}
+
+	fp, err := WriteFile(r, dir)
+	if err != nil || filepath.Base(fp) != runtime+Suffix {
+		t.Errorf("WriteFile() = %v, %v", fp, err)
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeRun(t, dir, map[string]string{
+		"_Timeline.csv":         "timestamp,module,output,event_type,user,description\n2020-08-01T09:10:00Z,MacDirlistModule,MacDirlistModule,Modified,,/a\n",
+		"_MacDirlistModule.csv": "path,mtime\n/a,2020-08-01T09:10:00Z\n/b,2020-08-01T11:10:00Z\n",
+	})
+	run, err := baseline.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := []timeline.Definition{{Output: "MacDirlistModule", Events: []timeline.Event{{Field: "mtime"}}}}
+	r, err := Build(run, defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the super-timeline is preferred over the module outputs
+	if r.Histogram.Source != "super-timeline" || r.Histogram.Total != 1 || len(r.Tables) != 0 || r.System != nil {
+		t.Errorf("Build() = %+v", r)
+	}
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+}
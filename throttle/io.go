@@ -0,0 +1,117 @@
+package throttle
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxReadChunk keeps single reads small so throttled readers take turns instead of bursting
+const maxReadChunk = 64 << 10
+
+// limiter is a token bucket shared by every throttled reader, holding at most one second of tokens
+type limiter struct {
+	mutex  sync.Mutex
+	rate   float64 // bytes per second, 0 is unlimited
+	tokens float64
+	last   time.Time
+}
+
+var ioLimiter = &limiter{}
+
+// SetIORate limits the combined rate of throttled reads to bytesPerSec, 0 removes the limit
+func SetIORate(bytesPerSec int64) {
+	ioLimiter.mutex.Lock()
+	defer ioLimiter.mutex.Unlock()
+	ioLimiter.rate = float64(bytesPerSec)
+	ioLimiter.tokens = 0
+	ioLimiter.last = time.Now()
+}
+
+// limited returns true when reads are rate limited
+func (l *limiter) limited() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rate > 0
+}
+
+// take removes n tokens from the bucket and returns how long the caller has to wait for them
+func (l *limiter) take(n int) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// reader throttles reads from r
+type reader struct {
+	r io.Reader
+}
+
+// Reader returns r with reads limited by the IO rate and paused while the heap is above the memory limit
+func Reader(r io.Reader) io.Reader {
+	return &reader{r: r}
+}
+
+func (t *reader) Read(p []byte) (int, error) {
+	WaitForMemory()
+	if !ioLimiter.limited() {
+		return t.r.Read(p)
+	}
+	if len(p) > maxReadChunk {
+		p = p[:maxReadChunk]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		time.Sleep(ioLimiter.take(n))
+	}
+	return n, err
+}
+
+// File is a file opened for throttled reading
+type File struct {
+	*os.File
+	reader io.Reader
+}
+
+// Open opens the file fp for reading with reads throttled like Reader
+func Open(fp string) (*File, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: f, reader: Reader(f)}, nil
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	return f.reader.Read(p)
+}
+
+// WriteTo hides the WriteTo of *os.File so io.Copy goes through the throttled Read
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{f.reader})
+}
+
+// ReadFile reads the whole file fp with reads throttled like Reader
+func ReadFile(fp string) ([]byte, error) {
+	f, err := Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
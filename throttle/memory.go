@@ -0,0 +1,93 @@
+package throttle
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// memoryCheckInterval is how often the heap is compared to the memory limit
+	memoryCheckInterval = 500 * time.Millisecond
+	// maxMemoryPause bounds how long a read waits for the heap to shrink, modules holding on to
+	// their buffers would otherwise never continue
+	maxMemoryPause = 30 * time.Second
+)
+
+var (
+	memoryMutex sync.Mutex
+	memoryHigh  int32
+	stopMonitor chan struct{}
+)
+
+// SetMemoryLimit starts comparing the heap to limit bytes, 0 stops the comparison
+func SetMemoryLimit(limit int64) {
+	memoryMutex.Lock()
+	defer memoryMutex.Unlock()
+	if stopMonitor != nil {
+		close(stopMonitor)
+		stopMonitor = nil
+	}
+	atomic.StoreInt32(&memoryHigh, 0)
+	if limit <= 0 {
+		return
+	}
+	stopMonitor = make(chan struct{})
+	go monitorMemory(limit, stopMonitor)
+}
+
+// monitorMemory marks the heap as high while it is above limit, forcing a garbage collection each time
+// The mark is cleared once the heap is back below 90% of limit
+func monitorMemory(limit int64, stop chan struct{}) {
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		heap := heapSize()
+		high := atomic.LoadInt32(&memoryHigh) == 1
+		if heap > limit {
+			debug.FreeOSMemory()
+			heap = heapSize()
+		}
+		switch {
+		case heap > limit && !high:
+			zap.L().Warn(fmt.Sprintf("Heap of %d MB is above the %d MB memory limit, pausing reads and writing dirlist rows to disk", heap>>20, limit>>20))
+			atomic.StoreInt32(&memoryHigh, 1)
+		case high && heap < limit/10*9:
+			zap.L().Info(fmt.Sprintf("Heap back to %d MB, below the %d MB memory limit", heap>>20, limit>>20))
+			atomic.StoreInt32(&memoryHigh, 0)
+		}
+	}
+}
+
+func heapSize() int64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64(m.HeapAlloc)
+}
+
+// MemoryHigh returns true while the heap is above the memory limit, modules holding rows in memory
+// should write them out when it does
+func MemoryHigh() bool {
+	return atomic.LoadInt32(&memoryHigh) == 1
+}
+
+// WaitForMemory blocks while the heap is above the memory limit, for at most maxMemoryPause
+func WaitForMemory() {
+	if !MemoryHigh() {
+		return
+	}
+	deadline := time.Now().Add(maxMemoryPause)
+	for MemoryHigh() && time.Now().Before(deadline) {
+		time.Sleep(memoryCheckInterval)
+	}
+}
@@ -0,0 +1,21 @@
+package throttle
+
+import (
+	"syscall"
+)
+
+const (
+	prioDarwinProcess = 4      // PRIO_DARWIN_PROCESS
+	prioDarwinBG      = 0x1000 // PRIO_DARWIN_BG
+)
+
+// setNice sets the nice value of the process
+func setNice(nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice)
+}
+
+// setIOPriority moves the process to the background band, which throttles its disk and network IO
+// macOS has no separate low and idle IO classes for a whole process
+func setIOPriority(priority string) error {
+	return syscall.Setpriority(prioDarwinProcess, 0, prioDarwinBG)
+}
@@ -0,0 +1,56 @@
+package throttle
+
+import (
+	"io/ioutil"
+	"strconv"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess  = 1
+	ioprioClassShift  = 13
+	ioprioClassBE     = 2
+	ioprioClassIdle   = 3
+	ioprioLowestLevel = 7
+)
+
+// threads returns the ids of every thread of the process, priorities are per thread on Linux
+func threads() []int {
+	tids := []int{}
+	entries, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return []int{0}
+	}
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids
+}
+
+// setNice sets the nice value of every thread, threads started later inherit it
+func setNice(nice int) error {
+	var err error
+	for _, tid := range threads() {
+		if e := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// setIOPriority moves every thread to the idle IO class or the lowest best-effort level, like ionice
+func setIOPriority(priority string) error {
+	prio := ioprioClassBE<<ioprioClassShift | ioprioLowestLevel
+	if priority == IOPriorityIdle {
+		prio = ioprioClassIdle << ioprioClassShift
+	}
+	var err error
+	for _, tid := range threads() {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio)); errno != 0 {
+			err = errno
+		}
+	}
+	return err
+}
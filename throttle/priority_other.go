@@ -0,0 +1,16 @@
+// +build !darwin,!linux,!windows
+
+package throttle
+
+import (
+	"errors"
+	"runtime"
+)
+
+func setNice(nice int) error {
+	return errors.New("not supported on " + runtime.GOOS)
+}
+
+func setIOPriority(priority string) error {
+	return errors.New("not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,39 @@
+package throttle
+
+import (
+	"syscall"
+)
+
+const (
+	belowNormalPriorityClass   = 0x00004000
+	idlePriorityClass          = 0x00000040
+	processModeBackgroundBegin = 0x00100000
+)
+
+var procSetPriorityClass = syscall.NewLazyDLL("kernel32.dll").NewProc("SetPriorityClass")
+
+func setPriorityClass(class uintptr) error {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	r, _, err := procSetPriorityClass.Call(uintptr(process), class)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// setNice maps nice values to priority classes, 1-9 to below normal and 10-19 to idle
+func setNice(nice int) error {
+	if nice >= 10 {
+		return setPriorityClass(idlePriorityClass)
+	}
+	return setPriorityClass(belowNormalPriorityClass)
+}
+
+// setIOPriority puts the process in background mode, which lowers its IO and memory priority
+// Windows has no separate low and idle IO classes for a whole process
+func setIOPriority(priority string) error {
+	return setPriorityClass(processModeBackgroundBegin)
+}
@@ -0,0 +1,165 @@
+// Package throttle limits the resources Orion takes from the host it runs on: file read rate, CPUs,
+// concurrently running modules, process priority and heap size
+package throttle
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// IO priorities
+const (
+	IOPriorityNormal = "normal"
+	IOPriorityLow    = "low"
+	IOPriorityIdle   = "idle"
+)
+
+// Config holds the resource limits of a run, zero values leave a resource unlimited
+type Config struct {
+	MaxIOBytesPerSec int64  `toml:"maxIOBytesPerSec" comment:"combined read rate of module file reads and hashing, 0 is unlimited"`
+	MaxProcs         int    `toml:"maxProcs" comment:"cap on GOMAXPROCS (CPUs used at once), 0 uses every CPU"`
+	MaxModules       int    `toml:"maxModules" comment:"modules running at the same time, 0 runs every module at once"`
+	Nice             int    `toml:"nice" comment:"CPU priority from 0 (normal) to 19 (lowest), like nice"`
+	IOPriority       string `toml:"ioPriority" comment:"normal, low or idle, like ionice (mac: low and idle both use the background band)"`
+	MemoryLimitMB    int    `toml:"memoryLimitMB" comment:"soft heap ceiling, above it file reads pause and the dirlist modules write buffered rows to disk, 0 is unlimited"`
+}
+
+// Settings is the [throttle] table of the config file, [throttle.profiles.<profile>] tables override the
+// limits when Orion runs with --profile <profile>
+type Settings struct {
+	Config
+	Profiles map[string]Profile `toml:"profiles" comment:"limits for a --profile, keys left out use the [throttle] value"`
+}
+
+// Profile is a [throttle.profiles.<profile>] table, nil fields were left out and keep the [throttle] value so
+// an explicit 0 lifts a limit for the profile
+type Profile struct {
+	MaxIOBytesPerSec *int64  `toml:"maxIOBytesPerSec"`
+	MaxProcs         *int    `toml:"maxProcs"`
+	MaxModules       *int    `toml:"maxModules"`
+	Nice             *int    `toml:"nice"`
+	IOPriority       *string `toml:"ioPriority"`
+	MemoryLimitMB    *int    `toml:"memoryLimitMB"`
+}
+
+// ForProfile returns the limits for a run with profile, the [throttle] limits overridden by the
+// [throttle.profiles.<profile>] table
+func (s Settings) ForProfile(profile string) Config {
+	cfg := s.Config
+	if override, ok := s.Profiles[profile]; ok {
+		cfg = cfg.Merge(override)
+	}
+	return cfg
+}
+
+// Merge returns cfg with every value set in override applied
+func (cfg Config) Merge(override Profile) Config {
+	if override.MaxIOBytesPerSec != nil {
+		cfg.MaxIOBytesPerSec = *override.MaxIOBytesPerSec
+	}
+	if override.MaxProcs != nil {
+		cfg.MaxProcs = *override.MaxProcs
+	}
+	if override.MaxModules != nil {
+		cfg.MaxModules = *override.MaxModules
+	}
+	if override.Nice != nil {
+		cfg.Nice = *override.Nice
+	}
+	if override.IOPriority != nil {
+		cfg.IOPriority = *override.IOPriority
+	}
+	if override.MemoryLimitMB != nil {
+		cfg.MemoryLimitMB = *override.MemoryLimitMB
+	}
+	return cfg
+}
+
+// Validate returns an error describing every invalid limit
+func (cfg Config) Validate() error {
+	problems := []string{}
+	if cfg.MaxIOBytesPerSec < 0 {
+		problems = append(problems, "maxIOBytesPerSec must not be negative")
+	}
+	if cfg.MaxProcs < 0 {
+		problems = append(problems, "maxProcs must not be negative")
+	}
+	if cfg.MaxModules < 0 {
+		problems = append(problems, "maxModules must not be negative")
+	}
+	if cfg.Nice < 0 || cfg.Nice > 19 {
+		problems = append(problems, "nice must be between 0 and 19")
+	}
+	switch strings.ToLower(cfg.IOPriority) {
+	case "", IOPriorityNormal, IOPriorityLow, IOPriorityIdle:
+	default:
+		problems = append(problems, "unknown ioPriority '"+cfg.IOPriority+"', expected normal, low or idle")
+	}
+	if cfg.MemoryLimitMB < 0 {
+		problems = append(problems, "memoryLimitMB must not be negative")
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// IsZero returns true when cfg sets no limit
+func (cfg Config) IsZero() bool {
+	return cfg == Config{} || cfg == Config{IOPriority: IOPriorityNormal}
+}
+
+// String describes the limits for logs
+func (cfg Config) String() string {
+	parts := []string{}
+	if cfg.MaxIOBytesPerSec > 0 {
+		parts = append(parts, fmt.Sprintf("io %d bytes/s", cfg.MaxIOBytesPerSec))
+	}
+	if cfg.MaxProcs > 0 {
+		parts = append(parts, fmt.Sprintf("maxProcs %d", cfg.MaxProcs))
+	}
+	if cfg.MaxModules > 0 {
+		parts = append(parts, fmt.Sprintf("maxModules %d", cfg.MaxModules))
+	}
+	if cfg.Nice > 0 {
+		parts = append(parts, fmt.Sprintf("nice %d", cfg.Nice))
+	}
+	if cfg.IOPriority != "" && cfg.IOPriority != IOPriorityNormal {
+		parts = append(parts, "ioPriority "+cfg.IOPriority)
+	}
+	if cfg.MemoryLimitMB > 0 {
+		parts = append(parts, fmt.Sprintf("memory %d MB", cfg.MemoryLimitMB))
+	}
+	if len(parts) == 0 {
+		return "unlimited"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Apply puts the limits of cfg in place for the rest of the run
+// Priority changes that fail are logged, they do not stop Orion
+func Apply(cfg Config) {
+	if cfg.IsZero() {
+		return
+	}
+	zap.L().Info("Throttling enabled: " + cfg.String())
+	if cfg.MaxProcs > 0 && cfg.MaxProcs < runtime.GOMAXPROCS(0) {
+		runtime.GOMAXPROCS(cfg.MaxProcs)
+	}
+	SetIORate(cfg.MaxIOBytesPerSec)
+	if cfg.Nice > 0 {
+		if err := setNice(cfg.Nice); err != nil {
+			zap.L().Warn("Failed to lower CPU priority: " + err.Error())
+		}
+	}
+	if p := strings.ToLower(cfg.IOPriority); p == IOPriorityLow || p == IOPriorityIdle {
+		if err := setIOPriority(p); err != nil {
+			zap.L().Warn("Failed to lower IO priority: " + err.Error())
+		}
+	}
+	SetMemoryLimit(int64(cfg.MemoryLimitMB) << 20)
+}
@@ -0,0 +1,145 @@
+package throttle
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestForProfile(t *testing.T) {
+	ten, one, zero, idle := int64(10), 1, 0, IOPriorityIdle
+	s := Settings{
+		Config: Config{MaxIOBytesPerSec: 100, Nice: 5, MemoryLimitMB: 512},
+		Profiles: map[string]Profile{
+			"dbhost":  {MaxIOBytesPerSec: &ten, MaxProcs: &one, IOPriority: &idle},
+			"nolimit": {Nice: &zero, MemoryLimitMB: &zero},
+		},
+	}
+	if got := s.ForProfile(""); got != s.Config {
+		t.Errorf("ForProfile() = %+v, want %+v", got, s.Config)
+	}
+	if got := s.ForProfile("quick"); got != s.Config {
+		t.Errorf("ForProfile(quick) = %+v, want %+v", got, s.Config)
+	}
+	want := Config{MaxIOBytesPerSec: 10, MaxProcs: 1, Nice: 5, IOPriority: IOPriorityIdle, MemoryLimitMB: 512}
+	if got := s.ForProfile("dbhost"); got != want {
+		t.Errorf("ForProfile(dbhost) = %+v, want %+v", got, want)
+	}
+	// an explicit 0 lifts the [throttle] limit
+	want = Config{MaxIOBytesPerSec: 100}
+	if got := s.ForProfile("nolimit"); got != want {
+		t.Errorf("ForProfile(nolimit) = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Config{
+		{},
+		{MaxIOBytesPerSec: 1 << 20, MaxProcs: 2, MaxModules: 4, Nice: 19, IOPriority: "Idle", MemoryLimitMB: 512},
+		{IOPriority: IOPriorityNormal},
+	}
+	for _, cfg := range valid {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %s", cfg, err)
+		}
+	}
+	invalid := []Config{
+		{MaxIOBytesPerSec: -1},
+		{MaxProcs: -1},
+		{MaxModules: -2},
+		{Nice: 20},
+		{Nice: -5},
+		{IOPriority: "realtime"},
+		{MemoryLimitMB: -1},
+	}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected an error", cfg)
+		}
+	}
+	if !(Config{IOPriority: IOPriorityNormal}).IsZero() || (Config{Nice: 1}).IsZero() {
+		t.Error("IsZero() is wrong")
+	}
+}
+
+func TestReaderRate(t *testing.T) {
+	SetIORate(1 << 20)
+	defer SetIORate(0)
+
+	data := make([]byte, 300<<10)
+	start := time.Now()
+	n, err := io.Copy(ioutil.Discard, Reader(bytes.NewReader(data)))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("copied %d bytes: %v", n, err)
+	}
+	// 300 KiB at 1 MiB/s with an empty bucket takes about 290ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("reading 300 KiB at 1 MiB/s took %s", elapsed)
+	}
+
+	SetIORate(0)
+	start = time.Now()
+	io.Copy(ioutil.Discard, Reader(bytes.NewReader(data)))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unthrottled read took %s", elapsed)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "throttle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(fp, make([]byte, 200<<10), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	SetIORate(1 << 20)
+	defer SetIORate(0)
+	start := time.Now()
+	data, err := ReadFile(fp)
+	if err != nil || len(data) != 200<<10 {
+		t.Fatalf("ReadFile() = %d bytes, %v", len(data), err)
+	}
+	f, err := Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(ioutil.Discard, f); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("reading 400 KiB at 1 MiB/s took %s", elapsed)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	memoryCheckInterval = 10 * time.Millisecond
+	maxMemoryPause = 50 * time.Millisecond
+
+	SetMemoryLimit(1) // every heap is above one byte
+	deadline := time.Now().Add(2 * time.Second)
+	for !MemoryHigh() && time.Now().Before(deadline) {
+		time.Sleep(memoryCheckInterval)
+	}
+	if !MemoryHigh() {
+		t.Fatal("MemoryHigh() = false above the limit")
+	}
+	start := time.Now()
+	WaitForMemory()
+	if elapsed := time.Since(start); elapsed < maxMemoryPause || elapsed > time.Second {
+		t.Errorf("WaitForMemory() returned after %s, want about %s", elapsed, maxMemoryPause)
+	}
+
+	SetMemoryLimit(0)
+	if MemoryHigh() {
+		t.Error("MemoryHigh() = true without a limit")
+	}
+}
@@ -0,0 +1,174 @@
+package timeline
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthonybm/Orion/util/timeconv"
+	"go.uber.org/zap"
+)
+
+// Definition describes the event time columns of a single module output file
+// Output is the name the module gave its OrionWriter (ex. "MacChromeModule-history")
+// Incremental marks append-only history, a run with a baseline only writes the rows newer than the baseline
+type Definition struct {
+	Output            string
+	Events            []Event
+	UserField         string
+	DescriptionFields []string
+	Incremental       bool
+}
+
+// Event maps a timestamp column to the meaning of that timestamp
+// MACB is optional and used by the l2tcsv format (ex. "M...", ".A..", "..C.", "...B")
+type Event struct {
+	Field string
+	Type  string
+	MACB  string
+}
+
+// Entry is a single row of the merged timeline
+type Entry struct {
+	Time        time.Time
+	Module      string
+	Output      string
+	EventType   string
+	User        string
+	Description string
+	MACB        string
+}
+
+// Formats lists the supported timeline output formats
+var Formats = []string{"csv", "jsonl", "l2tcsv", "tln"}
+
+// ParseTime parses a timestamp written by a module into a UTC time.Time, format is the timestamp format of the
+// run that wrote it ("" when unknown)
+func ParseTime(s string, format string) (time.Time, error) {
+	return timeconv.Parse(s, format)
+}
+
+// ValidFormat returns true if the given format is a supported timeline format
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the CSV output file described by def and returns its timeline entries
+// Rows without a parseable timestamp for an event are skipped for that event, format is the timestamp format
+// the output was written with
+func Load(module string, def Definition, fp string, format string) ([]Entry, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return []Entry{}, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return []Entry{}, nil
+	} else if err != nil {
+		return []Entry{}, err
+	}
+
+	index := make(map[string]int)
+	for i, h := range header {
+		index[h] = i
+	}
+	get := func(row []string, field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	entries := []Entry{}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return entries, err
+		}
+
+		desc := []string{}
+		for _, field := range def.DescriptionFields {
+			if v := get(row, field); v != "" {
+				desc = append(desc, field+": "+v)
+			}
+		}
+
+		for _, ev := range def.Events {
+			ts := get(row, ev.Field)
+			if ts == "" {
+				continue
+			}
+			t, err := ParseTime(ts, format)
+			if err != nil {
+				zap.L().Debug("Skipping timeline event: "+err.Error(), zap.String("output", def.Output), zap.String("field", ev.Field))
+				continue
+			}
+			macb := ev.MACB
+			if macb == "" {
+				macb = "...."
+			}
+			entries = append(entries, Entry{
+				Time:        t,
+				Module:      module,
+				Output:      def.Output,
+				EventType:   ev.Type,
+				User:        get(row, def.UserField),
+				Description: strings.Join(desc, "; "),
+				MACB:        macb,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// Build loads every defined module output found in outputpath and returns the merged entries sorted by time
+// defs maps the module name to the definitions it declared, format is the timestamp format of the run
+func Build(defs map[string][]Definition, runtime string, outputpath string, format string) ([]Entry, error) {
+	entries := []Entry{}
+	for module, moduleDefs := range defs {
+		for _, def := range moduleDefs {
+			fp := filepath.Join(outputpath, runtime+"_"+def.Output+".csv")
+			if _, err := os.Stat(fp); os.IsNotExist(err) {
+				zap.L().Debug("No output to add to timeline for '"+def.Output+"'", zap.String("module", module))
+				continue
+			}
+			e, err := Load(module, def, fp, format)
+			if err != nil {
+				zap.L().Error("Failed to load '"+fp+"' into timeline: "+err.Error(), zap.String("module", module))
+				continue
+			}
+			entries = append(entries, e...)
+		}
+	}
+	Sort(entries)
+	return entries, nil
+}
+
+// Sort orders entries by time, then by output and event type for stable output
+func Sort(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.Before(entries[j].Time)
+		}
+		if entries[i].Output != entries[j].Output {
+			return entries[i].Output < entries[j].Output
+		}
+		return entries[i].EventType < entries[j].EventType
+	})
+}
@@ -0,0 +1,234 @@
+package timeline
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const runtime = "Orion_2020-08-02T10_00_00Z"
+
+var (
+	visit  = time.Date(2020, 7, 31, 21, 15, 0, 0, time.UTC)
+	login  = time.Date(2020, 8, 1, 8, 1, 0, 250000000, time.UTC)
+	unique = time.Date(2020, 8, 1, 9, 0, 0, 0, time.UTC)
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "timeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func writeOutput(t *testing.T, dir string, output string, content string) string {
+	fp := filepath.Join(dir, runtime+"_"+output+".csv")
+	if err := ioutil.WriteFile(fp, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in     string
+		format string
+		want   time.Time
+		ok     bool
+	}{
+		{"2020-07-31T21:15:00Z", "rfc3339nano", visit, true},
+		{"2020-08-01T08:01:00.25Z", "rfc3339nano", login, true},
+		{"2020-08-01T10:00:00+01:00", "rfc3339", unique, true},
+		{"2020-08-01 09:00:00 +0000 UTC", "", unique, true},
+		{"1596272400", "epoch", unique, true},
+		{"1596272400000", "epoch_ms", unique, true},
+		{"1596272400000", "", unique, true},
+		{"", "", time.Time{}, false},
+		{"0", "epoch", time.Time{}, false},
+		{"yesterday", "", time.Time{}, false},
+		{"1596272400<FAILED TO CONVERT>", "epoch", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in, tt.format)
+		if (err == nil) != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q, %q) = %s, %v, want %s ok %v", tt.in, tt.format, got, err, tt.want, tt.ok)
+		}
+		if err == nil && got.Location() != time.UTC {
+			t.Errorf("ParseTime(%q, %q) location = %s, want UTC", tt.in, tt.format, got.Location())
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	def := Definition{
+		Output: "MacChromeModule-history",
+		Events: []Event{
+			{Field: "visit_time", Type: "URL Visited", MACB: ".A.."},
+			{Field: "first_seen", Type: "URL First Seen"},
+		},
+		UserField:         "user",
+		DescriptionFields: []string{"url", "title"},
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    []Entry
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			want:    []Entry{},
+		},
+		{
+			name:    "both events, default MACB",
+			content: "user,visit_time,first_seen,url,title\nbob,2020-07-31T21:15:00Z,2020-08-01T09:00:00Z,https://a.example,A\n",
+			want: []Entry{
+				{Time: visit, Module: "MacChromeModule", Output: def.Output, EventType: "URL Visited", User: "bob", Description: "url: https://a.example; title: A", MACB: ".A.."},
+				{Time: unique, Module: "MacChromeModule", Output: def.Output, EventType: "URL First Seen", User: "bob", Description: "url: https://a.example; title: A", MACB: "...."},
+			},
+		},
+		{
+			name:    "missing event, user and description columns",
+			content: "visit_time,url\n2020-07-31T21:15:00Z,https://a.example\n",
+			want: []Entry{
+				{Time: visit, Module: "MacChromeModule", Output: def.Output, EventType: "URL Visited", Description: "url: https://a.example", MACB: ".A.."},
+			},
+		},
+		{
+			name:    "short rows and empty values",
+			content: "user,visit_time,first_seen,url,title\nbob,2020-07-31T21:15:00Z\nalice,,,,\n",
+			want: []Entry{
+				{Time: visit, Module: "MacChromeModule", Output: def.Output, EventType: "URL Visited", User: "bob", MACB: ".A.."},
+			},
+		},
+		{
+			name:    "unparseable times",
+			content: "user,visit_time,first_seen,url,title\nbob,13245675309<FAILED TO CONVERT>,2020-08-01T09:00:00Z,,\nbob,never,unknown,,\n",
+			want: []Entry{
+				{Time: unique, Module: "MacChromeModule", Output: def.Output, EventType: "URL First Seen", User: "bob", MACB: "...."},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := writeOutput(t, tempDir(t), def.Output, tt.content)
+			got, err := Load("MacChromeModule", def, fp, "rfc3339nano")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load("MacChromeModule", def, filepath.Join(tempDir(t), "missing.csv"), "rfc3339nano"); err == nil {
+		t.Error("Load() of a missing file expected an error")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := tempDir(t)
+	writeOutput(t, dir, "MacChromeModule-history", "user,visit_time\nbob,2020-08-01T09:00:00Z\nbob,2020-07-31T21:15:00Z\n")
+	writeOutput(t, dir, "MacUtmpxModule", "login_name,timestamp\nbob,2020-08-01T08:01:00.25Z\nbob,2020-08-01T09:00:00Z\n")
+	defs := map[string][]Definition{
+		"MacChromeModule": {
+			{Output: "MacChromeModule-history", Events: []Event{{Field: "visit_time", Type: "URL Visited"}}, UserField: "user"},
+			{Output: "MacChromeModule-downloads", Events: []Event{{Field: "start_time", Type: "File Downloaded"}}},
+		},
+		"MacUtmpxModule": {
+			{Output: "MacUtmpxModule", Events: []Event{{Field: "timestamp", Type: "Logon"}, {Field: "timestamp", Type: "Login"}}, UserField: "login_name"},
+		},
+	}
+	entries, err := Build(defs, runtime, dir, "rfc3339nano")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sorted by time, then output, then event type, outputs that were not written are left out
+	want := []struct {
+		time      time.Time
+		output    string
+		eventType string
+	}{
+		{visit, "MacChromeModule-history", "URL Visited"},
+		{login, "MacUtmpxModule", "Login"},
+		{login, "MacUtmpxModule", "Logon"},
+		{unique, "MacChromeModule-history", "URL Visited"},
+		{unique, "MacUtmpxModule", "Login"},
+		{unique, "MacUtmpxModule", "Logon"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Build() = %+v, want %d entries", entries, len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if !e.Time.Equal(w.time) || e.Output != w.output || e.EventType != w.eventType {
+			t.Errorf("entry %d = %s %s %s, want %s %s %s", i, e.Time, e.Output, e.EventType, w.time, w.output, w.eventType)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	entries := []Entry{
+		{Time: login, Module: "MacUtmpxModule", Output: "MacUtmpxModule", EventType: "Login", User: "bob", Description: "tty_name: console", MACB: "...."},
+		{Time: unique, Module: "MacChromeModule", Output: "MacChromeModule-history", EventType: "URL Visited", User: "bob", Description: "url: https://a.example/?a|b; title: \"A\",\nB", MACB: ".A.."},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "csv",
+			want: "timestamp,module,output,event_type,user,description\n" +
+				"2020-08-01T08:01:00.25Z,MacUtmpxModule,MacUtmpxModule,Login,bob,tty_name: console\n" +
+				"2020-08-01T09:00:00Z,MacChromeModule,MacChromeModule-history,URL Visited,bob,\"url: https://a.example/?a|b; title: \"\"A\"\",\nB\"\n",
+		},
+		{
+			format: "jsonl",
+			want: `{"description":"tty_name: console","event_type":"Login","module":"MacUtmpxModule","output":"MacUtmpxModule","timestamp":"2020-08-01T08:01:00.25Z","user":"bob"}` + "\n" +
+				`{"description":"url: https://a.example/?a|b; title: \"A\",\nB","event_type":"URL Visited","module":"MacChromeModule","output":"MacChromeModule-history","timestamp":"2020-08-01T09:00:00Z","user":"bob"}` + "\n",
+		},
+		{
+			format: "l2tcsv",
+			want: "date,time,timezone,MACB,source,sourcetype,type,user,host,short,desc,version,filename,inode,notes,format,extra\n" +
+				"08/01/2020,08:01:00,UTC,....,MacUtmpxModule,MacUtmpxModule,Login,bob,host1,Login,tty_name: console,2,,,,Orion,\n" +
+				"08/01/2020,09:00:00,UTC,.A..,MacChromeModule,MacChromeModule-history,URL Visited,bob,host1,URL Visited,\"url: https://a.example/?a|b; title: \"\"A\"\",\nB\",2,,,,Orion,\n",
+		},
+		{
+			format: "tln",
+			want: "1596268860|MacUtmpxModule|host1|bob|Login - tty_name: console\n" +
+				"1596272400|MacChromeModule|host1|bob|URL Visited - url: https://a.example/?a/b; title: \"A\", B\n",
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, entries, tt.format, "host1"); err != nil {
+			t.Fatalf("Write(%s) error: %s", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("Write(%s) =\n%s\nwant\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+	if err := Write(&bytes.Buffer{}, entries, "xml", "host1"); err == nil {
+		t.Error("Write(xml) expected an error")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := tempDir(t)
+	for format, name := range map[string]string{"csv": "_Timeline.csv", "l2tcsv": "_Timeline.l2t.csv", "tln": "_Timeline.tln"} {
+		fp, err := WriteFile([]Entry{}, format, runtime, dir, "host1")
+		if err != nil || fp != filepath.Join(dir, runtime+name) {
+			t.Errorf("WriteFile(%s) = %s, %v", format, fp, err)
+		}
+	}
+	if !ValidFormat("jsonl") || ValidFormat("xml") {
+		t.Error("ValidFormat() does not match Formats")
+	}
+}
@@ -0,0 +1,145 @@
+package timeline
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timelineHeader = []string{"timestamp", "module", "output", "event_type", "user", "description"}
+	l2tHeader      = []string{"date", "time", "timezone", "MACB", "source", "sourcetype", "type", "user", "host", "short", "desc", "version", "filename", "inode", "notes", "format", "extra"}
+)
+
+// extensions maps a timeline format to the extension of the file it is written to
+var extensions = map[string]string{
+	"csv":    "csv",
+	"jsonl":  "jsonl",
+	"l2tcsv": "l2t.csv",
+	"tln":    "tln",
+}
+
+// Output names the super-timeline files, <runtime>_Timeline.<ext>
+const Output = "Timeline"
+
+// WriteFile writes entries in the given format to <outputpath>/<runtime>_Timeline.<ext> and returns the path written
+func WriteFile(entries []Entry, format string, runtime string, outputpath string, host string) (string, error) {
+	ext, ok := extensions[format]
+	if !ok {
+		return "", errors.New("unsupported timeline format '" + format + "'")
+	}
+	if _, err := os.Stat(outputpath); os.IsNotExist(err) {
+		os.MkdirAll(outputpath, 0700)
+	}
+	fp := filepath.Join(outputpath, runtime+"_"+Output+"."+ext)
+	f, err := os.Create(fp)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	err = Write(w, entries, format, host)
+	if err != nil {
+		return fp, err
+	}
+	return fp, w.Flush()
+}
+
+// Write writes entries to w in the given format
+func Write(w io.Writer, entries []Entry, format string, host string) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, entries)
+	case "jsonl":
+		return writeJSONL(w, entries)
+	case "l2tcsv":
+		return writeL2T(w, entries, host)
+	case "tln":
+		return writeTLN(w, entries, host)
+	}
+	return errors.New("unsupported timeline format '" + format + "'")
+}
+
+func writeCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	cw.Write(timelineHeader)
+	for _, e := range entries {
+		cw.Write([]string{e.Time.Format(time.RFC3339Nano), e.Module, e.Output, e.EventType, e.User, e.Description})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONL(w io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		err := enc.Encode(map[string]string{
+			"timestamp":   e.Time.Format(time.RFC3339Nano),
+			"module":      e.Module,
+			"output":      e.Output,
+			"event_type":  e.EventType,
+			"user":        e.User,
+			"description": e.Description,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeL2T writes the log2timeline/Plaso l2t_csv format, all times are UTC
+func writeL2T(w io.Writer, entries []Entry, host string) error {
+	cw := csv.NewWriter(w)
+	cw.Write(l2tHeader)
+	for _, e := range entries {
+		cw.Write([]string{
+			e.Time.Format("01/02/2006"),
+			e.Time.Format("15:04:05"),
+			"UTC",
+			e.MACB,
+			e.Module,
+			e.Output,
+			e.EventType,
+			e.User,
+			host,
+			e.EventType,
+			e.Description,
+			"2",
+			"",
+			"",
+			"",
+			"Orion",
+			"",
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTLN writes Harlan Carvey's five field TLN format: Time|Source|Host|User|Description
+func writeTLN(w io.Writer, entries []Entry, host string) error {
+	clean := strings.NewReplacer("|", "/", "\n", " ", "\r", " ")
+	for _, e := range entries {
+		line := strings.Join([]string{
+			strconv.FormatInt(e.Time.Unix(), 10),
+			clean.Replace(e.Module),
+			clean.Replace(host),
+			clean.Replace(e.User),
+			clean.Replace(e.EventType + " - " + e.Description),
+		}, "|")
+		_, err := io.WriteString(w, line+"\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// httpSink uploads with HTTP PUT or a multipart/form-data POST
+// PUT uploads of files larger than the chunk size are sent as consecutive PUT requests with a Content-Range
+// header and resume from the last acknowledged chunk
+type httpSink struct {
+	cfg    Config
+	client *http.Client
+}
+
+func newHTTPSink(cfg Config) (Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("upload '%s': invalid http url '%s'", cfg.Name, cfg.URL)
+	}
+	cfg.Method = strings.ToLower(cfg.Method)
+	if cfg.Method == "" {
+		cfg.Method = MethodPut
+	}
+	if cfg.Method != MethodPut && cfg.Method != MethodMultipart {
+		return nil, fmt.Errorf("upload '%s': unknown http method '%s', expected put or multipart", cfg.Name, cfg.Method)
+	}
+	if cfg.Field == "" {
+		cfg.Field = "file"
+	}
+	return &httpSink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}, nil
+}
+
+func (s *httpSink) Config() Config {
+	return s.cfg
+}
+
+// target returns the request URL for the remote path, appended to the configured URL when it ends with /
+func (s *httpSink) target(remote string) string {
+	if !strings.HasSuffix(s.cfg.URL, "/") {
+		return s.cfg.URL
+	}
+	segments := strings.Split(remote, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s.cfg.URL + strings.Join(segments, "/")
+}
+
+func (s *httpSink) Upload(fp string, name string) error {
+	remote := s.cfg.remotePath(name)
+	info, err := os.Stat(fp)
+	if err != nil {
+		return err
+	}
+	if s.cfg.Method == MethodMultipart {
+		return retry(s.cfg.Retries, "http upload of "+remote, func() error {
+			return s.postMultipart(fp, remote)
+		})
+	}
+	if info.Size() <= int64(s.cfg.ChunkSize) {
+		return retry(s.cfg.Retries, "http upload of "+remote, func() error {
+			f, err := os.Open(fp)
+			if err != nil {
+				return permanentError{err}
+			}
+			defer f.Close()
+			return s.put(remote, f, info.Size(), "")
+		})
+	}
+	return s.putChunked(fp, info.Size(), remote)
+}
+
+// putChunked sends the file in chunk size PUT requests, saving the offset after every acknowledged chunk
+func (s *httpSink) putChunked(fp string, size int64, remote string) error {
+	f, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sp := statePath(s.cfg, fp, remote)
+	st := loadState(sp)
+	if st.Offset > 0 {
+		zap.L().Info(fmt.Sprintf("Resuming http upload of %s at byte %d of %d", remote, st.Offset, size))
+	}
+	for st.Offset < size {
+		chunk, err := readChunk(f, st.Offset, s.cfg.ChunkSize)
+		if err != nil {
+			return err
+		}
+		contentRange := fmt.Sprintf("bytes %d-%d/%d", st.Offset, st.Offset+int64(len(chunk))-1, size)
+		err = retry(s.cfg.Retries, "http upload of "+remote+" "+contentRange, func() error {
+			return s.put(remote, bytes.NewReader(chunk), int64(len(chunk)), contentRange)
+		})
+		if err != nil {
+			return err
+		}
+		st.Offset += int64(len(chunk))
+		saveState(sp, st)
+	}
+	os.Remove(sp)
+	return nil
+}
+
+func (s *httpSink) put(remote string, body io.Reader, size int64, contentRange string) error {
+	req, err := http.NewRequest(http.MethodPut, s.target(remote), body)
+	if err != nil {
+		return permanentError{err}
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if contentRange != "" {
+		req.Header.Set("Content-Range", contentRange)
+	}
+	return s.send(req)
+}
+
+// postMultipart streams the file as the form field of a multipart/form-data POST to the configured URL,
+// with the remote path as the file name and the "path" form field
+func (s *httpSink) postMultipart(fp string, remote string) error {
+	f, err := os.Open(fp)
+	if err != nil {
+		return permanentError{err}
+	}
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile(s.cfg.Field, remote)
+		if err == nil {
+			_, err = io.Copy(part, f)
+		}
+		if err == nil {
+			err = mw.WriteField("path", remote)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, pr)
+	if err != nil {
+		pr.Close()
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	err = s.send(req)
+	pr.Close()
+	return err
+}
+
+// send adds the configured headers and credentials to req and checks the response status
+// 308 is accepted as the acknowledgement of a chunk by resumable upload servers
+func (s *httpSink) send(req *http.Request) error {
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusPermanentRedirect {
+		return nil
+	}
+	u := *req.URL
+	u.User = nil
+	err = fmt.Errorf("http %s %s returned %s: %s", req.Method, u.String(), resp.Status, strings.TrimSpace(string(msg)))
+	if !retryableStatus(resp.StatusCode) {
+		return permanentError{err}
+	}
+	return err
+}
@@ -0,0 +1,333 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// s3Sink uploads to S3 compatible object storage (AWS S3, MinIO, Ceph, ...) with path style requests
+// signed with AWS Signature Version 4. Files larger than the chunk size use a multipart upload that
+// resumes from the last uploaded part
+type s3Sink struct {
+	cfg      Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func newS3Sink(cfg Config) (Sink, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("upload '%s': bucket is required for s3", cfg.Name)
+	}
+	endpoint, err := url.Parse(cfg.URL)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("upload '%s': invalid s3 endpoint '%s'", cfg.Name, cfg.URL)
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.AccessKey == "" {
+		cfg.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if cfg.SecretKey == "" {
+		cfg.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.SessionToken == "" {
+		cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("upload '%s': s3 credentials not set, use accessKey/secretKey or AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY", cfg.Name)
+	}
+	if cfg.ChunkSize < minS3ChunkSize {
+		cfg.ChunkSize = minS3ChunkSize
+	}
+	return &s3Sink{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}, nil
+}
+
+func (s *s3Sink) Config() Config {
+	return s.cfg
+}
+
+func (s *s3Sink) Upload(fp string, name string) error {
+	key := s.cfg.remotePath(name)
+	f, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() <= int64(s.cfg.ChunkSize) {
+		body, err := readChunk(f, 0, int(info.Size()))
+		if err != nil && info.Size() > 0 {
+			return err
+		}
+		return retry(s.cfg.Retries, "s3 upload of "+key, func() error {
+			_, err := s.do(http.MethodPut, key, nil, body)
+			return err
+		})
+	}
+	return s.multipart(f, info.Size(), fp, key)
+}
+
+// multipart uploads f in chunk size parts, saving the upload id and finished parts to resume after a failure
+func (s *s3Sink) multipart(f *os.File, size int64, fp string, key string) error {
+	sp := statePath(s.cfg, fp, key)
+	st := loadState(sp)
+
+	if st.UploadID != "" {
+		zap.L().Info(fmt.Sprintf("Resuming s3 upload of %s, %d parts already uploaded", key, len(st.Parts)))
+	} else {
+		err := retry(s.cfg.Retries, "s3 create multipart upload of "+key, func() error {
+			resp, err := s.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+			if err != nil {
+				return err
+			}
+			var result struct {
+				UploadID string `xml:"UploadId"`
+			}
+			if err := xml.Unmarshal(resp, &result); err != nil || result.UploadID == "" {
+				return fmt.Errorf("unexpected create multipart upload response: %s", string(resp))
+			}
+			st.UploadID = result.UploadID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		saveState(sp, st)
+	}
+
+	parts := int((size + int64(s.cfg.ChunkSize) - 1) / int64(s.cfg.ChunkSize))
+	for part := 1; part <= parts; part++ {
+		if _, done := st.Parts[part]; done {
+			continue
+		}
+		chunk, err := readChunk(f, int64(part-1)*int64(s.cfg.ChunkSize), s.cfg.ChunkSize)
+		if err != nil {
+			return err
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(part)}, "uploadId": {st.UploadID}}
+		var etag string
+		err = retry(s.cfg.Retries, fmt.Sprintf("s3 upload of %s part %d/%d", key, part, parts), func() error {
+			var err error
+			etag, err = s.doETag(http.MethodPut, key, query, chunk)
+			return err
+		})
+		if err != nil {
+			var notFound s3Error
+			if errors.As(err, &notFound) && notFound.Code == "NoSuchUpload" {
+				// The server dropped the multipart upload, start over next time
+				os.Remove(sp)
+			}
+			return err
+		}
+		st.Parts[part] = etag
+		saveState(sp, st)
+	}
+
+	var complete bytes.Buffer
+	complete.WriteString("<CompleteMultipartUpload>")
+	for part := 1; part <= parts; part++ {
+		fmt.Fprintf(&complete, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", part, xmlEscape(st.Parts[part]))
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+	err := retry(s.cfg.Retries, "s3 complete multipart upload of "+key, func() error {
+		_, err := s.do(http.MethodPost, key, url.Values{"uploadId": {st.UploadID}}, complete.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	os.Remove(sp)
+	return nil
+}
+
+// s3Error is an error response of the server
+type s3Error struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e s3Error) Error() string {
+	return fmt.Sprintf("s3 error %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// do sends a signed request for key and returns the response body
+func (s *s3Sink) do(method string, key string, query url.Values, body []byte) ([]byte, error) {
+	resp, _, err := s.send(method, key, query, body)
+	return resp, err
+}
+
+// doETag sends a signed request for key and returns the ETag header of the response
+func (s *s3Sink) doETag(method string, key string, query url.Values, body []byte) (string, error) {
+	_, header, err := s.send(method, key, query, body)
+	if err != nil {
+		return "", err
+	}
+	etag := header.Get("ETag")
+	if etag == "" {
+		return "", errors.New("s3 response is missing the ETag header")
+	}
+	return etag, nil
+}
+
+func (s *s3Sink) send(method string, key string, query url.Values, body []byte) ([]byte, http.Header, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	u.RawPath = uriEncode(u.Path, false) // send the path exactly as it is signed
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, permanentError{err}
+	}
+	sum := sha256.Sum256(body)
+	signV4(req, hex.EncodeToString(sum[:]), s.cfg.AccessKey, s.cfg.SecretKey, s.cfg.SessionToken, s.cfg.Region, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		e := s3Error{Status: resp.StatusCode}
+		xml.Unmarshal(data, &e)
+		if e.Code == "" {
+			e.Code = http.StatusText(resp.StatusCode)
+		}
+		if !retryableStatus(resp.StatusCode) {
+			return nil, nil, permanentError{e}
+		}
+		return nil, nil, e
+	}
+	return data, resp.Header, nil
+}
+
+// retryableStatus returns true for HTTP status codes worth retrying (server errors, throttling and timeouts)
+func retryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+}
+
+// signV4 adds the AWS Signature Version 4 Authorization header to req for the s3 service
+// Every header already set on req is signed
+func signV4(req *http.Request, payloadHash string, accessKey string, secretKey string, sessionToken string, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if sessionToken != "" {
+		req.Header.Set("x-amz-security-token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := []string{}
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery returns the query sorted by key with SigV4 encoding
+func canonicalQuery(query url.Values) string {
+	keys := []string{}
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := []string{}
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent encodes everything but unreserved characters, slashes are kept unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
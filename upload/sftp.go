@@ -0,0 +1,204 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpSink uploads over SFTP, writing to <name>.part and renaming it once complete
+// An existing .part file is appended to, resuming an interrupted upload
+type sftpSink struct {
+	cfg  Config
+	dir  string
+	dial func() (*sftp.Client, io.Closer, error)
+}
+
+func newSFTPSink(cfg Config) (Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Scheme != "sftp" || u.Hostname() == "" {
+		return nil, fmt.Errorf("upload '%s': invalid sftp url '%s', expected sftp://user@host:port/dir", cfg.Name, cfg.URL)
+	}
+	if cfg.Username == "" && u.User != nil {
+		cfg.Username = u.User.Username()
+	}
+	if cfg.Password == "" && u.User != nil {
+		cfg.Password, _ = u.User.Password()
+	}
+	if cfg.Username == "" {
+		return nil, fmt.Errorf("upload '%s': sftp username is required", cfg.Name)
+	}
+	if cfg.KeyPassphrase == "" {
+		cfg.KeyPassphrase = os.Getenv("ORION_UPLOAD_KEY_PASSPHRASE")
+	}
+
+	auth := []ssh.AuthMethod{}
+	if cfg.KeyFile != "" {
+		signer, err := loadPrivateKey(cfg.KeyFile, cfg.KeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("upload '%s': %s", cfg.Name, err.Error())
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("upload '%s': sftp needs a keyFile or password", cfg.Name)
+	}
+	hostKeyCallback, err := hostKeyCallback(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("upload '%s': %s", cfg.Name, err.Error())
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "22")
+	}
+	clientConfig := &ssh.ClientConfig{
+		User:            cfg.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         time.Duration(cfg.Timeout) * time.Second,
+	}
+	s := &sftpSink{cfg: cfg, dir: u.Path}
+	s.dial = func() (*sftp.Client, io.Closer, error) {
+		conn, err := ssh.Dial("tcp", addr, clientConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := sftp.NewClient(conn)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		return client, conn, nil
+	}
+	return s, nil
+}
+
+// loadPrivateKey returns the signer of an OpenSSH or PEM private key file
+func loadPrivateKey(fp string, passphrase string) (ssh.Signer, error) {
+	data, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != "" {
+		return ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// hostKeyCallback verifies the server key against the hostKey fingerprint, else against the known_hosts file
+func hostKeyCallback(cfg Config) (ssh.HostKeyCallback, error) {
+	if cfg.InsecureIgnoreHostKey {
+		zap.L().Warn("upload '" + cfg.Name + "': sftp host key verification is disabled")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	if cfg.HostKey != "" {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) == cfg.HostKey || ssh.FingerprintLegacyMD5(key) == strings.TrimPrefix(cfg.HostKey, "MD5:") {
+				return nil
+			}
+			return fmt.Errorf("host key %s of %s does not match hostKey %s", ssh.FingerprintSHA256(key), hostname, cfg.HostKey)
+		}, nil
+	}
+	knownHostsFile := cfg.KnownHosts
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.New("cannot find ~/.ssh/known_hosts, set knownHosts or hostKey")
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	return knownhosts.New(knownHostsFile)
+}
+
+func (s *sftpSink) Config() Config {
+	return s.cfg
+}
+
+func (s *sftpSink) Upload(fp string, name string) error {
+	remote := path.Join(s.dir, s.cfg.remotePath(name))
+	return retry(s.cfg.Retries, "sftp upload of "+remote, func() error {
+		return s.upload(fp, remote)
+	})
+}
+
+func (s *sftpSink) upload(fp string, remote string) error {
+	f, err := os.Open(fp)
+	if err != nil {
+		return permanentError{err}
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return permanentError{err}
+	}
+
+	client, conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer client.Close()
+
+	if err := client.MkdirAll(path.Dir(remote)); err != nil {
+		return err
+	}
+	part := remote + ".part"
+	var offset int64
+	if st, err := client.Stat(part); err == nil && st.Size() <= info.Size() {
+		offset = st.Size()
+		zap.L().Info(fmt.Sprintf("Resuming sftp upload of %s at byte %d of %d", remote, offset, info.Size()))
+	}
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	rf, err := client.OpenFile(part, flags)
+	if err != nil {
+		return err
+	}
+	if _, err := rf.Seek(offset, io.SeekStart); err != nil {
+		rf.Close()
+		return err
+	}
+	buf := make([]byte, s.cfg.ChunkSize)
+	for offset < info.Size() {
+		n, err := f.ReadAt(buf, offset)
+		if n == 0 && err != nil {
+			rf.Close()
+			return permanentError{err}
+		}
+		if _, err := rf.Write(buf[:n]); err != nil {
+			rf.Close()
+			return err
+		}
+		offset += int64(n)
+	}
+	if err := rf.Close(); err != nil {
+		return err
+	}
+
+	// Rename does not replace an existing file on every server
+	if _, err := client.Stat(remote); err == nil {
+		if err := client.Remove(remote); err != nil {
+			return err
+		}
+	}
+	return client.Rename(part, remote)
+}
@@ -0,0 +1,244 @@
+package upload
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sink types
+const (
+	TypeS3   = "s3"
+	TypeSFTP = "sftp"
+	TypeHTTP = "http"
+)
+
+// HTTP upload methods
+const (
+	MethodPut       = "put"
+	MethodMultipart = "multipart"
+)
+
+const (
+	defaultChunkSize = 8 << 20 // 8 MiB
+	minS3ChunkSize   = 5 << 20 // S3 rejects multipart parts below 5 MiB, except the last
+	defaultRetries   = 5
+	defaultTimeout   = 300
+	defaultPrefix    = "{host}"
+)
+
+// retryDelay is the wait before the first retry, doubled on every further attempt up to maxRetryDelay
+var (
+	retryDelay    = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// Config is an [[upload]] table of the config file
+// String values expand environment variables (ex. secretKey = "${ORION_S3_SECRET}")
+type Config struct {
+	Name                  string            `toml:"name" comment:"name used in logs, defaults to the type"`
+	Type                  string            `toml:"type" comment:"s3, sftp or http"`
+	URL                   string            `toml:"url" comment:"s3: endpoint (ex. https://s3.us-east-1.amazonaws.com, http://minio:9000), sftp: sftp://user@host:22/dir, http: upload URL, the file name is appended when it ends with /"`
+	Bucket                string            `toml:"bucket" comment:"s3 bucket"`
+	Region                string            `toml:"region" comment:"s3 region, defaults to $AWS_REGION or us-east-1"`
+	Prefix                string            `toml:"prefix" comment:"remote path prefix, {host} is replaced with the hostname"`
+	AccessKey             string            `toml:"accessKey" comment:"s3 access key, defaults to $AWS_ACCESS_KEY_ID"`
+	SecretKey             string            `toml:"secretKey" comment:"s3 secret key, defaults to $AWS_SECRET_ACCESS_KEY"`
+	SessionToken          string            `toml:"sessionToken" comment:"s3 session token, defaults to $AWS_SESSION_TOKEN"`
+	Username              string            `toml:"username" comment:"sftp user (defaults to the url user) or http basic auth user"`
+	Password              string            `toml:"password" comment:"sftp or http basic auth password, defaults to $ORION_UPLOAD_PASSWORD"`
+	KeyFile               string            `toml:"keyFile" comment:"sftp private key file"`
+	KeyPassphrase         string            `toml:"keyPassphrase" comment:"sftp private key passphrase, defaults to $ORION_UPLOAD_KEY_PASSPHRASE"`
+	KnownHosts            string            `toml:"knownHosts" comment:"sftp known_hosts file, defaults to ~/.ssh/known_hosts"`
+	HostKey               string            `toml:"hostKey" comment:"sftp expected host key fingerprint (ex. SHA256:...), checked instead of knownHosts"`
+	InsecureIgnoreHostKey bool              `toml:"insecureIgnoreHostKey" comment:"sftp only, do not verify the server host key"`
+	Method                string            `toml:"method" comment:"http: put (default) or multipart (multipart/form-data POST)"`
+	Field                 string            `toml:"field" comment:"http multipart form field of the file, defaults to file"`
+	Headers               map[string]string `toml:"headers" comment:"http request headers (ex. Authorization = \"Bearer ${ORION_UPLOAD_TOKEN}\")"`
+	ChunkSize             int               `toml:"chunkSize" comment:"bytes per s3 part, sftp write or http put chunk, 0 uses 8 MiB"`
+	Retries               int               `toml:"retries" comment:"attempts per request, 0 uses 5"`
+	Timeout               int               `toml:"timeout" comment:"seconds per request, 0 uses 300"`
+	Stream                bool              `toml:"stream" comment:"also upload module output files as each module finishes"`
+}
+
+// Sink uploads files to remote storage
+type Sink interface {
+	// Upload copies the local file fp to name below the sink prefix, resuming a previous partial upload of the same file
+	Upload(fp string, name string) error
+	// Config returns the config the sink was created from
+	Config() Config
+}
+
+// New validates cfg, fills in defaults and credentials from the environment and returns the sink
+func New(cfg Config) (Sink, error) {
+	cfg = cfg.expand()
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("upload '%s': url is required", cfg.Name)
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = defaultChunkSize
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = defaultRetries
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultPrefix
+	}
+	if cfg.Password == "" {
+		cfg.Password = os.Getenv("ORION_UPLOAD_PASSWORD")
+	}
+
+	switch strings.ToLower(cfg.Type) {
+	case TypeS3:
+		return newS3Sink(cfg)
+	case TypeSFTP:
+		return newSFTPSink(cfg)
+	case TypeHTTP:
+		return newHTTPSink(cfg)
+	}
+	return nil, fmt.Errorf("upload '%s': unknown type '%s', expected s3, sftp or http", cfg.Name, cfg.Type)
+}
+
+// expand returns cfg with environment variables expanded in every string value
+func (cfg Config) expand() Config {
+	for _, s := range []*string{
+		&cfg.Name, &cfg.Type, &cfg.URL, &cfg.Bucket, &cfg.Region, &cfg.Prefix,
+		&cfg.AccessKey, &cfg.SecretKey, &cfg.SessionToken,
+		&cfg.Username, &cfg.Password, &cfg.KeyFile, &cfg.KeyPassphrase, &cfg.KnownHosts, &cfg.HostKey,
+		&cfg.Method, &cfg.Field,
+	} {
+		*s = os.ExpandEnv(*s)
+	}
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	cfg.Headers = headers
+	return cfg
+}
+
+// remotePath returns name below the prefix of cfg with {host} replaced by the hostname, always using forward slashes
+func (cfg Config) remotePath(name string) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown-host"
+	}
+	prefix := strings.Replace(cfg.Prefix, "{host}", host, -1)
+	return strings.TrimPrefix(path.Join(prefix, filepath.ToSlash(name)), "/")
+}
+
+// permanentError marks an error retrying cannot fix (ex. access denied)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// retry calls fn up to attempts times, waiting longer after each failure, and returns the last error
+func retry(attempts int, what string, fn func() error) error {
+	delay := retryDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt == attempts {
+			break
+		}
+		zap.L().Warn(fmt.Sprintf("%s failed (attempt %d of %d), retrying in %s: %s", what, attempt, attempts, delay, err.Error()))
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+	return fmt.Errorf("%s failed after %d attempts: %s", what, attempts, err.Error())
+}
+
+// state records the progress of a chunked upload so an interrupted upload of the same file can resume
+// UploadID and Parts are used by s3 multipart uploads, Offset by http chunked uploads
+type state struct {
+	UploadID string         `json:"upload_id,omitempty"`
+	Parts    map[int]string `json:"parts,omitempty"`
+	Offset   int64          `json:"offset,omitempty"`
+}
+
+// stateDir holds the progress of unfinished uploads, outside of the output directory that gets archived
+var stateDir = filepath.Join(os.TempDir(), "orion-upload")
+
+// statePath returns the progress file of uploading fp to remote with the sink cfg
+// The file size and modification time are part of the key so a changed file starts over, and so is the chunk
+// size because saved parts and offsets only line up with the chunks they were uploaded as
+func statePath(cfg Config, fp string, remote string) string {
+	key := cfg.Type + "|" + cfg.URL + "|" + cfg.Bucket + "|" + remote + "|" + fp + "|" + strconv.Itoa(cfg.ChunkSize)
+	if info, err := os.Stat(fp); err == nil {
+		key += fmt.Sprintf("|%d|%d", info.Size(), info.ModTime().UnixNano())
+	}
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(stateDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadState returns the saved progress in sp, empty if there is none
+func loadState(sp string) state {
+	st := state{Parts: make(map[int]string)}
+	data, err := ioutil.ReadFile(sp)
+	if err != nil {
+		return st
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		zap.L().Warn("Ignoring unreadable upload progress file " + sp + ": " + err.Error())
+		return state{Parts: make(map[int]string)}
+	}
+	if st.Parts == nil {
+		st.Parts = make(map[int]string)
+	}
+	return st
+}
+
+// saveState writes the progress to sp, failures only cost the ability to resume
+func saveState(sp string, st state) {
+	data, err := json.Marshal(st)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(sp), 0700)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(sp, data, 0600)
+	}
+	if err != nil {
+		zap.L().Warn("Failed to save upload progress to " + sp + ": " + err.Error())
+	}
+}
+
+// readChunk returns up to size bytes of f starting at offset
+func readChunk(f *os.File, offset int64, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && n == 0 {
+		return nil, err
+	}
+	return buf[:n], nil
+}
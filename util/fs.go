@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/anthonybm/Orion/throttle"
 	"go.uber.org/zap"
 )
 
@@ -103,7 +104,7 @@ func CopyFiles(fileglobnames []string, destfolder string, rootTargetPath string)
 // CopyFile copies a file from 'from' to 'to', with an attempt to perform a copy & rename
 // to avoid chaos if anything goes wrong partway.
 func CopyFile(from string, to string, mode os.FileMode) error {
-	fromFile, err := os.Open(from)
+	fromFile, err := throttle.Open(from)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,451 @@
+package machelpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/anthonybm/Orion/util/timeconv"
+)
+
+/*
+Bookmark ("book") and legacy Alias ("alis") records are the opaque blobs macOS stores to find a file again after it
+was moved or renamed: SFL/SFL2 lists, sidebar lists, Finder preferences, security scoped bookmarks, login items and
+the Dock all keep them. Foundation resolves them against the live filesystem, these decoders only read what the
+record holds so evidence can be decoded on any host
+
+Formats described by https://michaellynn.github.io/2015/10/24/apples-bookmarkdata-exposed/ and the mac_alias project
+*/
+
+// Bookmark formats returned in Bookmark.Format
+const (
+	BookmarkFormatBookmark = "bookmark"
+	BookmarkFormatAlias    = "alias"
+)
+
+// Bookmark is the target a Bookmark or Alias record points to
+// FileIDs are the CNIDs (inode numbers) of the path components of a bookmark, the target last, and the parent folder
+// and target CNIDs of an alias. SecurityExtension is the sandbox extension of security scoped bookmarks, which names
+// the access granted (ex. com.apple.app-sandbox.read-write) and the target path
+type Bookmark struct {
+	Format            string
+	Path              string
+	FileIDs           []uint64
+	Created           time.Time
+	VolumeName        string
+	VolumePath        string
+	VolumeURL         string
+	VolumeUUID        string
+	VolumeCreated     time.Time
+	UserName          string
+	UID               int64
+	SecurityExtension string
+}
+
+// URL returns the file URL of the bookmark target, empty if the record held no path
+func (b Bookmark) URL() string {
+	if b.Path == "" {
+		return ""
+	}
+	return (&url.URL{Scheme: "file", Path: b.Path}).String()
+}
+
+// Columns returns the target columns modules write for a bookmark: url, target_created, volume_name, volume_uuid and
+// file_id, the CNID of the target
+func (b Bookmark) Columns() map[string]string {
+	columns := map[string]string{
+		"url":            b.URL(),
+		"target_created": timeconv.Format(b.Created),
+		"volume_name":    b.VolumeName,
+		"volume_uuid":    b.VolumeUUID,
+		"file_id":        "",
+	}
+	if len(b.FileIDs) > 0 {
+		columns["file_id"] = strconv.FormatUint(b.FileIDs[len(b.FileIDs)-1], 10)
+	}
+	return columns
+}
+
+// DecodeBookmark decodes a Bookmark or a legacy Alias record, whichever b holds
+func DecodeBookmark(b []byte) (Bookmark, error) {
+	if bytes.HasPrefix(b, []byte("book")) {
+		return ParseBookmark(b)
+	}
+	if len(b) >= 8 {
+		if version := binary.BigEndian.Uint16(b[6:8]); version == 2 || version == 3 {
+			return ParseAlias(b)
+		}
+	}
+	return Bookmark{}, errors.New("not a bookmark or alias record")
+}
+
+// bookmark record keys
+const (
+	bookmarkPath              = 0x1004
+	bookmarkFileIDs           = 0x1005
+	bookmarkCreated           = 0x1040
+	bookmarkVolumePath        = 0x2002
+	bookmarkVolumeURL         = 0x2005
+	bookmarkVolumeName        = 0x2010
+	bookmarkVolumeUUID        = 0x2011
+	bookmarkVolumeCreated     = 0x2013
+	bookmarkUserName          = 0xc011
+	bookmarkUID               = 0xc012
+	bookmarkSecurityExtension = 0xf080
+	bookmarkSecurityReadOnly  = 0xf081
+)
+
+// bookmark record types, the low byte is the subtype
+const (
+	bookmarkTypeString = 0x0100
+	bookmarkTypeData   = 0x0200
+	bookmarkTypeNumber = 0x0300
+	bookmarkTypeDate   = 0x0400
+	bookmarkTypeBool   = 0x0500
+	bookmarkTypeArray  = 0x0600
+	bookmarkTypeDict   = 0x0700
+	bookmarkTypeUUID   = 0x0800
+	bookmarkTypeURL    = 0x0900
+	bookmarkTypeNull   = 0x0a00
+)
+
+// bookmarkData is the body of a bookmark, record offsets are relative to its start
+type bookmarkData []byte
+
+// ParseBookmark decodes a Bookmark ("book") record
+func ParseBookmark(b []byte) (Bookmark, error) {
+	if len(b) < 16 || !bytes.HasPrefix(b, []byte("book")) {
+		return Bookmark{}, errors.New("bookmark: missing book header")
+	}
+	headerSize := binary.LittleEndian.Uint32(b[12:16])
+	if int(headerSize) < 16 || int(headerSize)+4 > len(b) {
+		return Bookmark{}, fmt.Errorf("bookmark: invalid header size %d", headerSize)
+	}
+	data := bookmarkData(b[headerSize:])
+	toc, err := data.tableOfContents()
+	if err != nil {
+		return Bookmark{}, err
+	}
+
+	bm := Bookmark{Format: BookmarkFormatBookmark}
+	value := func(key uint32) interface{} {
+		offset, ok := toc[key]
+		if !ok {
+			return nil
+		}
+		v, err := data.value(offset, 0)
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+	if components, ok := value(bookmarkPath).([]interface{}); ok {
+		names := []string{}
+		for _, c := range components {
+			if s, ok := c.(string); ok {
+				names = append(names, s)
+			}
+		}
+		bm.Path = "/" + strings.Join(names, "/")
+	}
+	if ids, ok := value(bookmarkFileIDs).([]interface{}); ok {
+		for _, id := range ids {
+			if n, ok := id.(int64); ok {
+				bm.FileIDs = append(bm.FileIDs, uint64(n))
+			}
+		}
+	}
+	bm.Created, _ = value(bookmarkCreated).(time.Time)
+	bm.VolumePath, _ = value(bookmarkVolumePath).(string)
+	bm.VolumeURL, _ = value(bookmarkVolumeURL).(string)
+	bm.VolumeName, _ = value(bookmarkVolumeName).(string)
+	bm.VolumeUUID, _ = value(bookmarkVolumeUUID).(string)
+	bm.VolumeCreated, _ = value(bookmarkVolumeCreated).(time.Time)
+	bm.UserName, _ = value(bookmarkUserName).(string)
+	bm.UID, _ = value(bookmarkUID).(int64)
+	for _, key := range []uint32{bookmarkSecurityExtension, bookmarkSecurityReadOnly} {
+		if ext, ok := value(key).([]byte); ok {
+			bm.SecurityExtension = strings.TrimRight(string(ext), "\x00")
+			break
+		}
+	}
+	return bm, nil
+}
+
+// tableOfContents returns the record offset of every key of the tables of contents, the first table holding a key
+// wins
+func (d bookmarkData) tableOfContents() (map[uint32]uint32, error) {
+	if len(d) < 4 {
+		return nil, errors.New("bookmark: truncated body")
+	}
+	toc := map[uint32]uint32{}
+	offset := binary.LittleEndian.Uint32(d[0:4])
+	seen := map[uint32]bool{}
+	for offset != 0 && !seen[offset] {
+		seen[offset] = true
+		if uint64(offset)+20 > uint64(len(d)) {
+			return nil, fmt.Errorf("bookmark: table of contents at %d is out of bounds", offset)
+		}
+		if magic := binary.LittleEndian.Uint32(d[offset+4:]); magic != 0xfffffffe {
+			return nil, fmt.Errorf("bookmark: invalid table of contents magic 0x%x", magic)
+		}
+		next := binary.LittleEndian.Uint32(d[offset+12:])
+		count := binary.LittleEndian.Uint32(d[offset+16:])
+		for i := uint64(0); i < uint64(count); i++ {
+			entry := uint64(offset) + 20 + 12*i
+			if entry+12 > uint64(len(d)) {
+				return nil, errors.New("bookmark: truncated table of contents")
+			}
+			key := binary.LittleEndian.Uint32(d[entry:])
+			if _, ok := toc[key]; !ok {
+				toc[key] = binary.LittleEndian.Uint32(d[entry+4:])
+			}
+		}
+		offset = next
+	}
+	return toc, nil
+}
+
+// value decodes the record at offset, depth bounds the nesting of arrays and dictionaries
+func (d bookmarkData) value(offset uint32, depth int) (interface{}, error) {
+	if depth > 8 {
+		return nil, errors.New("bookmark: records nested too deep")
+	}
+	if uint64(offset)+8 > uint64(len(d)) {
+		return nil, fmt.Errorf("bookmark: record at %d is out of bounds", offset)
+	}
+	length := binary.LittleEndian.Uint32(d[offset:])
+	typ := binary.LittleEndian.Uint32(d[offset+4:])
+	if uint64(offset)+8+uint64(length) > uint64(len(d)) {
+		return nil, fmt.Errorf("bookmark: record at %d is truncated", offset)
+	}
+	payload := d[offset+8 : offset+8+length]
+
+	switch typ & 0xff00 {
+	case bookmarkTypeString:
+		return string(payload), nil
+	case bookmarkTypeData:
+		return []byte(payload), nil
+	case bookmarkTypeNumber:
+		return bookmarkNumber(typ&0xff, payload)
+	case bookmarkTypeDate:
+		if len(payload) != 8 {
+			return nil, errors.New("bookmark: invalid date record")
+		}
+		return timeconv.FromCocoa(math.Float64frombits(binary.BigEndian.Uint64(payload))), nil
+	case bookmarkTypeBool:
+		return typ&0xff == 1, nil
+	case bookmarkTypeArray, bookmarkTypeDict:
+		values := []interface{}{}
+		for i := 0; i+4 <= len(payload); i += 4 {
+			v, err := d.value(binary.LittleEndian.Uint32(payload[i:]), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		if typ&0xff00 == bookmarkTypeArray {
+			return values, nil
+		}
+		dict := map[string]interface{}{}
+		for i := 0; i+1 < len(values); i += 2 {
+			dict[fmt.Sprint(values[i])] = values[i+1]
+		}
+		return dict, nil
+	case bookmarkTypeUUID:
+		if len(payload) != 16 {
+			return nil, errors.New("bookmark: invalid UUID record")
+		}
+		p := payload
+		return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", p[0:4], p[4:6], p[6:8], p[8:10], p[10:16])), nil
+	case bookmarkTypeURL:
+		if typ&0xff == 1 {
+			return string(payload), nil
+		}
+		// relative URLs are the offsets of the base URL and of the relative part
+		if len(payload) != 8 {
+			return nil, errors.New("bookmark: invalid relative URL record")
+		}
+		base, err := d.value(binary.LittleEndian.Uint32(payload[0:]), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := d.value(binary.LittleEndian.Uint32(payload[4:]), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Sprint(base) + fmt.Sprint(rel), nil
+	case bookmarkTypeNull:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("bookmark: unknown record type 0x%x", typ)
+}
+
+// bookmarkNumber decodes a number record, subtypes are the CFNumberType of the value
+func bookmarkNumber(subtype uint32, p []byte) (interface{}, error) {
+	switch {
+	case subtype == 1 && len(p) == 1:
+		return int64(int8(p[0])), nil
+	case subtype == 2 && len(p) == 2:
+		return int64(int16(binary.LittleEndian.Uint16(p))), nil
+	case subtype == 3 && len(p) == 4:
+		return int64(int32(binary.LittleEndian.Uint32(p))), nil
+	case subtype == 4 && len(p) == 8:
+		return int64(binary.LittleEndian.Uint64(p)), nil
+	case subtype == 5 && len(p) == 4:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(p))), nil
+	case subtype == 6 && len(p) == 8:
+		return math.Float64frombits(binary.LittleEndian.Uint64(p)), nil
+	}
+	return nil, fmt.Errorf("bookmark: invalid number record of subtype %d", subtype)
+}
+
+// alias record tags
+const (
+	aliasCarbonPath        = 2
+	aliasUnicodeFileName   = 14
+	aliasUnicodeVolumeName = 15
+	aliasVolumeCreatedHR   = 16
+	aliasCreatedHR         = 17
+	aliasPOSIXPath         = 18
+	aliasPOSIXMountPoint   = 19
+)
+
+// ParseAlias decodes a legacy Alias record (version 2 or 3) as stored by Carbon and in pre 10.11 plists
+// The record holds the HFS path of the target and, when written by 10.5 or later, its POSIX path and mount point
+func ParseAlias(b []byte) (Bookmark, error) {
+	if len(b) < 8 {
+		return Bookmark{}, errors.New("alias: truncated record")
+	}
+	if size := int(binary.BigEndian.Uint16(b[4:6])); size > 8 && size < len(b) {
+		b = b[:size]
+	}
+	bm := Bookmark{Format: BookmarkFormatAlias}
+	var parentID, fileID uint32
+	var fileName, carbonPath, posixPath, mountPoint string
+	tags := 0
+
+	switch version := binary.BigEndian.Uint16(b[6:8]); version {
+	case 2:
+		if len(b) < 150 {
+			return Bookmark{}, errors.New("alias: truncated version 2 record")
+		}
+		bm.VolumeName = pascalString(b[10:38])
+		if t := binary.BigEndian.Uint32(b[38:42]); t != 0 {
+			bm.VolumeCreated = timeconv.FromHFSPlus(t)
+		}
+		parentID = binary.BigEndian.Uint32(b[46:50])
+		fileName = pascalString(b[50:114])
+		fileID = binary.BigEndian.Uint32(b[114:118])
+		if t := binary.BigEndian.Uint32(b[118:122]); t != 0 {
+			bm.Created = timeconv.FromHFSPlus(t)
+		}
+		tags = 150
+	case 3:
+		if len(b) < 58 {
+			return Bookmark{}, errors.New("alias: truncated version 3 record")
+		}
+		bm.VolumeCreated = aliasHighResTime(b[10:18])
+		parentID = binary.BigEndian.Uint32(b[24:28])
+		fileID = binary.BigEndian.Uint32(b[28:32])
+		bm.Created = aliasHighResTime(b[32:40])
+		tags = 58
+	default:
+		return Bookmark{}, fmt.Errorf("alias: unsupported version %d", version)
+	}
+
+	for tags+4 <= len(b) {
+		tag := int16(binary.BigEndian.Uint16(b[tags:]))
+		length := int(binary.BigEndian.Uint16(b[tags+2:]))
+		if tag == -1 || tags+4+length > len(b) {
+			break
+		}
+		value := b[tags+4 : tags+4+length]
+		switch tag {
+		case aliasCarbonPath:
+			carbonPath = string(value)
+		case aliasUnicodeFileName:
+			if name := aliasUnicodeString(value); name != "" {
+				fileName = name
+			}
+		case aliasUnicodeVolumeName:
+			if name := aliasUnicodeString(value); name != "" {
+				bm.VolumeName = name
+			}
+		case aliasVolumeCreatedHR:
+			bm.VolumeCreated = aliasHighResTime(value)
+		case aliasCreatedHR:
+			bm.Created = aliasHighResTime(value)
+		case aliasPOSIXPath:
+			posixPath = string(value)
+		case aliasPOSIXMountPoint:
+			mountPoint = string(value)
+		}
+		tags += 4 + length + length%2
+	}
+
+	for _, id := range []uint32{parentID, fileID} {
+		if id != 0 {
+			bm.FileIDs = append(bm.FileIDs, uint64(id))
+		}
+	}
+	bm.VolumePath = mountPoint
+	switch {
+	case posixPath != "" && mountPoint != "" && mountPoint != "/":
+		bm.Path = path.Join(mountPoint, posixPath)
+	case posixPath != "":
+		bm.Path = posixPath
+	case carbonPath != "":
+		// Volume:folder:file, only the components after the volume name are known to be a path
+		if i := strings.Index(carbonPath, ":"); i >= 0 {
+			bm.Path = "/" + strings.Replace(carbonPath[i+1:], ":", "/", -1)
+		}
+	case fileName != "":
+		bm.Path = fileName
+	}
+	return bm, nil
+}
+
+// pascalString returns the length prefixed string of a fixed size field
+func pascalString(b []byte) string {
+	if len(b) == 0 || int(b[0]) >= len(b) {
+		return ""
+	}
+	return string(b[1 : 1+int(b[0])])
+}
+
+// aliasUnicodeString returns a character count prefixed UTF-16BE string
+func aliasUnicodeString(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(b))
+	if 2+2*count > len(b) {
+		return ""
+	}
+	chars := make([]uint16, count)
+	for i := range chars {
+		chars[i] = binary.BigEndian.Uint16(b[2+2*i:])
+	}
+	return string(utf16.Decode(chars))
+}
+
+// aliasHighResTime converts the 65536ths of a second since 1904-01-01 of version 3 aliases
+func aliasHighResTime(b []byte) time.Time {
+	if len(b) < 8 {
+		return time.Time{}
+	}
+	v := binary.BigEndian.Uint64(b)
+	if v == 0 {
+		return time.Time{}
+	}
+	return timeconv.FromHFSPlus(uint32(v >> 16)).Add(time.Duration(v&0xffff) * time.Second / 65536)
+}
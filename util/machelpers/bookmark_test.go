@@ -0,0 +1,85 @@
+package machelpers
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeBookmark(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/plan.docx.bookmark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bm, err := DecodeBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Bookmark{
+		Format:        BookmarkFormatBookmark,
+		Path:          "/Users/bob/Documents/plan.docx",
+		FileIDs:       []uint64{100005, 200003, 300009, 400009},
+		Created:       time.Date(2020, 7, 30, 12, 0, 0, 0, time.UTC),
+		VolumeName:    "Macintosh HD",
+		VolumePath:    "/",
+		VolumeURL:     "file:///",
+		VolumeUUID:    "0A81F3B1-51D9-3335-B3E3-169C3640360D",
+		VolumeCreated: time.Date(2019, 10, 7, 21, 0, 0, 0, time.UTC),
+		UserName:      "bob",
+		UID:           501,
+	}
+	ext := bm.SecurityExtension
+	bm.SecurityExtension = ""
+	if !reflect.DeepEqual(bm, want) {
+		t.Errorf("DecodeBookmark() = %+v, want %+v", bm, want)
+	}
+	if !strings.HasSuffix(ext, "com.apple.app-sandbox.read-write;01;01000004;0000000000055f12;/users/bob/documents/plan.docx") {
+		t.Errorf("DecodeBookmark() security extension = %q", ext)
+	}
+	if u := bm.URL(); u != "file:///Users/bob/Documents/plan.docx" {
+		t.Errorf("URL() = %q", u)
+	}
+	wantColumns := map[string]string{
+		"url":            "file:///Users/bob/Documents/plan.docx",
+		"target_created": "2020-07-30T12:00:00Z",
+		"volume_name":    "Macintosh HD",
+		"volume_uuid":    "0A81F3B1-51D9-3335-B3E3-169C3640360D",
+		"file_id":        "400009",
+	}
+	if columns := bm.Columns(); !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("Columns() = %v, want %v", columns, wantColumns)
+	}
+
+	if _, err := DecodeBookmark(b[:60]); err == nil {
+		t.Error("DecodeBookmark() of a truncated bookmark succeeded")
+	}
+}
+
+func TestDecodeAlias(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/Projects.alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bm, err := DecodeBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Bookmark{
+		Format:        BookmarkFormatAlias,
+		Path:          "/Users/bob/Projects",
+		FileIDs:       []uint64{100003, 200008},
+		Created:       time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC),
+		VolumeName:    "Macintosh HD",
+		VolumePath:    "/",
+		VolumeCreated: time.Date(2019, 10, 7, 21, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(bm, want) {
+		t.Errorf("DecodeBookmark() = %+v, want %+v", bm, want)
+	}
+
+	if _, err := DecodeBookmark([]byte("not a bookmark")); err == nil {
+		t.Error("DecodeBookmark() of garbage succeeded")
+	}
+}
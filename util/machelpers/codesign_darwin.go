@@ -0,0 +1,84 @@
+// +build darwin
+
+package machelpers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GetCodesignatures returns the signing authorities of fp read with codesign, ["Unsigned"] when it has none or an
+// ERROR-* value when it cannot be checked
+func GetCodesignatures(fp string) []string {
+	_, err := os.Stat(fp)
+	if os.IsNotExist(err) {
+		return []string{"ERROR-FILE-DNE"}
+	}
+
+	signers, err := getSignatureChain(fp)
+	if err != nil {
+		signers, err = getCodeSignaturesFromSubProcess(fp)
+		if err != nil {
+			return []string{"ERROR-GETSIG-FAIL"}
+		}
+	}
+	if len(signers) == 0 {
+		return []string{"Unsigned"}
+	}
+	return signers
+}
+
+func getSignatureChain(fp string) ([]string, error) {
+	return []string{}, errors.New("unimplemented method")
+}
+
+func getCodeSignaturesFromSubProcess(fp string) ([]string, error) {
+	codesignCmd := exec.Command("codesign", "-dv", "--verbose=2", fp)
+	codesignOut, outerr := codesignCmd.StdoutPipe()
+	codesignErr, errerr := codesignCmd.StderrPipe()
+	if outerr != nil {
+		return []string{}, errors.New("codesignOut error - could not parse codesign: " + fp + ": " + outerr.Error())
+	}
+	if errerr != nil {
+		return []string{}, errors.New("codesignErr error - could not parse codesign: " + fp + ": " + errerr.Error())
+	}
+	codesignCmd.Start()
+	codesignOutBytes, outbyteserr := ioutil.ReadAll(codesignOut)
+	codesignErrorBytes, errbyteserr := ioutil.ReadAll(codesignErr)
+	if outbyteserr != nil {
+		return []string{}, errors.New("codesignOutBytes error - could not parse codesign: " + fp + ": " + outbyteserr.Error())
+	}
+	if errbyteserr != nil {
+		return []string{}, errors.New("codesignErrorBytes error - could not parse codesign: " + fp + ": " + errbyteserr.Error())
+	}
+	waiterr := codesignCmd.Wait()
+	if waiterr != nil {
+		return []string{}, errors.New("codesignCmd wait error - could not parse codesign: " + fp + ": " + waiterr.Error())
+	}
+
+	codesignOutString := string(codesignOutBytes)
+	codesignErrorString := string(codesignErrorBytes)
+
+	if len(codesignErrorString) > 0 {
+		if !strings.Contains(codesignErrorString, "NOTE:") {
+			return []string{}, fmt.Errorf("codesignErrorString not empty - could not parse '%s': %s", fp, codesignErrorString)
+		}
+	}
+
+	codesignData := strings.Split(codesignOutString, "\n")
+	signers := []string{}
+	for _, line := range codesignData {
+		if strings.HasPrefix(line, "Authority=") {
+			nline := strings.Replace(line, "Authority=", "", 1)
+			signers = append(signers, nline)
+		}
+	}
+	if len(signers) == 0 {
+		return []string{"Unsigned"}, nil
+	}
+	return signers, nil
+}
@@ -0,0 +1,17 @@
+// +build !darwin
+
+package machelpers
+
+import "os"
+
+// notChecked is returned where codesign is not available, signatures of evidence analyzed off macOS are left to be
+// checked on a Mac
+const notChecked = "NOT-CHECKED"
+
+// GetCodesignatures returns ["NOT-CHECKED"] as code signatures can only be read with codesign on macOS
+func GetCodesignatures(fp string) []string {
+	if _, err := os.Stat(fp); os.IsNotExist(err) {
+		return []string{"ERROR-FILE-DNE"}
+	}
+	return []string{notChecked}
+}
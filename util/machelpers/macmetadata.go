@@ -1,19 +1,16 @@
-// +build darwin
+// +build !windows
 
 package machelpers
 
 import (
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"syscall"
-	"time"
 
+	"github.com/anthonybm/Orion/util/timeconv"
 	"github.com/pkg/xattr"
 	"go.uber.org/zap"
 	"gopkg.in/djherbis/times.v1"
@@ -32,13 +29,13 @@ func FileTimestamps(fp string, modulename string) map[string]string {
 		return m
 	}
 
-	m["mtime"] = timestat.ModTime().UTC().Format(time.RFC3339)
-	m["atime"] = timestat.AccessTime().UTC().Format(time.RFC3339)
+	m["mtime"] = timeconv.Format(timestat.ModTime())
+	m["atime"] = timeconv.Format(timestat.AccessTime())
 	if timestat.HasChangeTime() {
-		m["ctime"] = timestat.ChangeTime().UTC().Format(time.RFC3339)
+		m["ctime"] = timeconv.Format(timestat.ChangeTime())
 	}
 	if timestat.HasBirthTime() {
-		m["btime"] = timestat.BirthTime().UTC().Format(time.RFC3339)
+		m["btime"] = timeconv.Format(timestat.BirthTime())
 	}
 
 	return m
@@ -56,6 +53,7 @@ func FileMetadata(fp string, modulename string) (map[string]string, error) {
 	m["btime"] = "NO VALUE"
 	m["path"] = "NO VALUE"
 	m["name"] = "NO VALUE"
+	m["inode"] = "NO VALUE"
 
 	stat, err := os.Lstat(fp)
 	if err != nil {
@@ -85,6 +83,7 @@ func FileMetadata(fp string, modulename string) (map[string]string, error) {
 	if stat, ok := stat.Sys().(*syscall.Stat_t); ok {
 		UID = int(stat.Uid)
 		GID = int(stat.Gid)
+		m["inode"] = strconv.FormatUint(uint64(stat.Ino), 10)
 	} else {
 		zap.L().Debug("getting file metadata for non-linux file: '" + fp + "'")
 		UID = os.Getuid()
@@ -92,13 +91,13 @@ func FileMetadata(fp string, modulename string) (map[string]string, error) {
 	}
 	m["uid"] = strconv.Itoa(UID)
 	m["gid"] = strconv.Itoa(GID)
-	m["mtime"] = timestat.ModTime().UTC().String()
-	m["atime"] = timestat.AccessTime().UTC().String()
+	m["mtime"] = timeconv.Format(timestat.ModTime())
+	m["atime"] = timeconv.Format(timestat.AccessTime())
 	if timestat.HasChangeTime() {
-		m["ctime"] = timestat.ChangeTime().UTC().String()
+		m["ctime"] = timeconv.Format(timestat.ChangeTime())
 	}
 	if timestat.HasBirthTime() {
-		m["btime"] = timestat.BirthTime().UTC().String()
+		m["btime"] = timeconv.Format(timestat.BirthTime())
 	}
 	m["path"] = fp
 	m["name"] = filepath.Base(fp)
@@ -134,73 +133,3 @@ func ReadAttrFile(filename string) []byte {
 	b, _ := ioutil.ReadFile(filename)
 	return b
 }
-
-func GetCodesignatures(fp string) []string {
-	_, err := os.Stat(fp)
-	if os.IsNotExist(err) {
-		return []string{"ERROR-FILE-DNE"}
-	}
-
-	signers, err := getSignatureChain(fp)
-	if err != nil {
-		signers, err = getCodeSignaturesFromSubProcess(fp)
-		if err != nil {
-			return []string{"ERROR-GETSIG-FAIL"}
-		}
-	}
-	if len(signers) == 0 {
-		return []string{"Unsigned"}
-	}
-	return signers
-}
-
-func getSignatureChain(fp string) ([]string, error) {
-	return []string{}, errors.New("unimplemented method")
-}
-
-func getCodeSignaturesFromSubProcess(fp string) ([]string, error) {
-	codesignCmd := exec.Command("codesign", "-dv", "--verbose=2", fp)
-	codesignOut, outerr := codesignCmd.StdoutPipe()
-	codesignErr, errerr := codesignCmd.StderrPipe()
-	if outerr != nil {
-		return []string{}, errors.New("codesignOut error - could not parse codesign: " + fp + ": " + outerr.Error())
-	}
-	if errerr != nil {
-		return []string{}, errors.New("codesignErr error - could not parse codesign: " + fp + ": " + errerr.Error())
-	}
-	codesignCmd.Start()
-	codesignOutBytes, outbyteserr := ioutil.ReadAll(codesignOut)
-	codesignErrorBytes, errbyteserr := ioutil.ReadAll(codesignErr)
-	if outbyteserr != nil {
-		return []string{}, errors.New("codesignOutBytes error - could not parse codesign: " + fp + ": " + outbyteserr.Error())
-	}
-	if errbyteserr != nil {
-		return []string{}, errors.New("codesignErrorBytes error - could not parse codesign: " + fp + ": " + errbyteserr.Error())
-	}
-	waiterr := codesignCmd.Wait()
-	if waiterr != nil {
-		return []string{}, errors.New("codesignCmd wait error - could not parse codesign: " + fp + ": " + waiterr.Error())
-	}
-
-	codesignOutString := string(codesignOutBytes)
-	codesignErrorString := string(codesignErrorBytes)
-
-	if len(codesignErrorString) > 0 {
-		if !strings.Contains(codesignErrorString, "NOTE:") {
-			return []string{}, fmt.Errorf("codesignErrorString not empty - could not parse '%s': %s", fp, codesignErrorString)
-		}
-	}
-
-	codesignData := strings.Split(codesignOutString, "\n")
-	signers := []string{}
-	for _, line := range codesignData {
-		if strings.HasPrefix(line, "Authority=") {
-			nline := strings.Replace(line, "Authority=", "", 1)
-			signers = append(signers, nline)
-		}
-	}
-	if len(signers) == 0 {
-		return []string{"Unsigned"}, nil
-	}
-	return signers, nil
-}
@@ -1,5 +1,3 @@
-// +build darwin
-
 package machelpers
 
 import (
@@ -52,6 +50,13 @@ const (
 	nsMutableData   = "NSMutableData"
 )
 
+// classes converted to strings
+const (
+	nsURL      = "NSURL"
+	nsUUID     = "NSUUID"
+	nullObject = "$null"
+)
+
 /* CONSTANTS END */
 
 // UnarchiveNSKeyedArchiver extracts an NSKeyedArchiver Plist from a given filepath, (XML or Binary), and returns an array of the NSObjects converted to usable Go Types
@@ -77,8 +82,14 @@ func UnarchiveNSKeyedArchiverBytes(f []byte) ([]interface{}, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Unarchive NSKeyedArchiver: %s", err.Error())
 	}
-	nsKeyedArchiverData := plistData.(map[string]interface{})
-	// fmt.Println(nsKeyedArchiverData)
+	nsKeyedArchiverData, ok := plistData.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unarchive NSKeyedArchiver: plist root is not a dictionary")
+	}
+	err = verifyCorrectArchiver(nsKeyedArchiverData)
+	if err != nil {
+		return nil, fmt.Errorf("Unarchive NSKeyedArchiver: %s", err.Error())
+	}
 
 	ret, err := extractNSObjectsFromTop(nsKeyedArchiverData[topKey].(map[string]interface{}), nsKeyedArchiverData[objectsKey].([]interface{}))
 	if err != nil {
@@ -160,6 +171,17 @@ func extractNSObjects(objectRefs []plist.UID, objects []interface{}) ([]interfac
 			continue
 		}
 
+		if object, ok := objectRef.(map[string]interface{}); ok {
+			if _, ok := object[class]; ok {
+				value, err := extractObject(object, objects)
+				if err != nil {
+					return nil, err
+				}
+				returnValue[i] = value
+				continue
+			}
+		}
+
 		objectType := reflect.TypeOf(objectRef).String()
 		// fmt.Println(objectRef)
 		// panic(fmt.Sprintf("Unknown object type:%s", objectType))
@@ -178,7 +200,6 @@ func isNSPrimativeObject(object map[string]interface{}, objects []interface{}) (
 	if className == nsMutableString || className == nsMutableData {
 		return object, true
 	}
-	zap.L().Error(fmt.Sprintf("could not use classname %s", className), zap.String("util", "nskeyedarchiver"))
 	return object, false
 }
 
@@ -251,6 +272,43 @@ func extractNSPrimativeObject(object map[string]interface{}, objects []interface
 	return result, nil
 }
 
+// extractObject converts an instance of a class archived with its own keys (ex. SFLListItem) to a map of its decoded
+// keys, NSURL and NSUUID are converted to their string form
+func extractObject(object map[string]interface{}, objects []interface{}) (interface{}, error) {
+	name, err := resolveClass(object[class], objects)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(object))
+	for k, v := range object {
+		if k == class {
+			continue
+		}
+		if uid, ok := v.(plist.UID); ok {
+			values, err := extractNSObjects([]plist.UID{uid}, objects)
+			if err != nil {
+				return nil, err
+			}
+			v = values[0]
+		}
+		result[k] = v
+	}
+
+	switch name {
+	case nsURL:
+		relative, _ := result["NS.relative"].(string)
+		if base, ok := result["NS.base"].(string); ok && base != nullObject {
+			return base + relative, nil
+		}
+		return relative, nil
+	case nsUUID:
+		if b, ok := result["NS.uuidbytes"].([]byte); ok && len(b) == 16 {
+			return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])), nil
+		}
+	}
+	return result, nil
+}
+
 func resolveClass(classInfo interface{}, objects []interface{}) (string, error) {
 	if v, ok := classInfo.(plist.UID); ok {
 		classDict := objects[v].(map[string]interface{})
@@ -0,0 +1,20 @@
+package machelpers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anthonybm/Orion/moduletest"
+)
+
+func TestUnarchiveNSKeyedArchiver(t *testing.T) {
+	objects, err := UnarchiveNSKeyedArchiver("testdata/com.apple.LSSharedFileList.RecentDocuments.sfl2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moduletest.GoldenFile(t, "testdata/golden/com.apple.LSSharedFileList.RecentDocuments.json", append(b, '\n'))
+}
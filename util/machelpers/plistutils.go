@@ -1,5 +1,3 @@
-// +build darwin
-
 package machelpers
 
 import (
@@ -7,10 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"path/filepath"
+	"strings"
 
+	"github.com/anthonybm/Orion/throttle"
 	"howett.net/plist"
 )
 
@@ -28,7 +27,7 @@ func plistFromBytes(plistBytes []byte) (interface{}, error) {
 
 // PlistFromFilepath decodes a binary or XML based Plist using local method
 func plistFromFilepath(fp string) (interface{}, error) {
-	f, err := ioutil.ReadFile(fp)
+	f, err := throttle.ReadFile(fp)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +40,12 @@ func DecodePlistBytes(plistBytes []byte) (interface{}, error) {
 }
 
 // DecodePlist returns an array of maps corresponding to entries within the given plist, values are interface
+// fp is joined to targetPath unless it is already under it, as the artifact paths resolved from the instance are
 func DecodePlist(fp, targetPath string) ([]map[string]interface{}, error) {
-	plistInterfaceData, err := plistFromFilepath(filepath.Join(targetPath, fp))
+	if rel, err := filepath.Rel(targetPath, fp); err != nil || !filepath.IsAbs(fp) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		fp = filepath.Join(targetPath, fp)
+	}
+	plistInterfaceData, err := plistFromFilepath(fp)
 	if err != nil {
 		return nil, err
 	}
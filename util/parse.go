@@ -9,6 +9,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/anthonybm/Orion/util/timeconv"
 	"go.uber.org/zap"
 )
 
@@ -30,7 +31,7 @@ func InterfaceToString(i interface{}) (string, error) {
 		return strconv.FormatInt(int64(val), 10), nil
 	}
 	if val, ok := i.(time.Time); ok {
-		return val.UTC().Format(time.RFC3339), nil
+		return timeconv.Format(val), nil
 	}
 	if val, ok := i.([]uint8); ok {
 		return string(val[:]), nil
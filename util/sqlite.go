@@ -93,6 +93,8 @@ func UnsafeQueryDBToMap(dbfile, query string) ([]map[string]interface{}, error)
 				entry[k] = strconv.FormatInt(u, 10)
 			case []uint8:
 				entry[k] = fmt.Sprintf("b64:%s", base64.StdEncoding.EncodeToString(u))
+			case nil:
+				entry[k] = ""
 			default:
 				zap.L().Error("Type <" + reflect.TypeOf(v).String() + "> not currently processed by sqlite util!!")
 				entry[k] = "ERR-UNIMPL"
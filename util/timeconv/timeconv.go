@@ -0,0 +1,353 @@
+// Package timeconv converts the various epoch based timestamps found in artifacts to time.Time
+// and formats them consistently for module output
+package timeconv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind identifies the epoch and resolution of a raw timestamp value
+type Kind string
+
+const (
+	// Cocoa is Core Foundation/Mac absolute time, seconds (int or float) since 2001-01-01
+	Cocoa Kind = "cocoa"
+	// WebKit is WebKit/Chrome time, microseconds since 1601-01-01
+	WebKit Kind = "webkit"
+	// PRTime is Mozilla PRTime, microseconds since 1970-01-01
+	PRTime Kind = "prtime"
+	// FILETIME is Windows FILETIME, 100 nanosecond intervals since 1601-01-01
+	FILETIME Kind = "filetime"
+	// HFSPlus is HFS+ time, seconds since 1904-01-01
+	HFSPlus Kind = "hfs+"
+	// APFS is APFS time, nanoseconds since 1970-01-01
+	APFS Kind = "apfs"
+	// Unix is seconds (int or float) since 1970-01-01
+	Unix Kind = "unix"
+	// UnixMilli is milliseconds since 1970-01-01
+	UnixMilli Kind = "unix_ms"
+	// UnixMicro is microseconds since 1970-01-01
+	UnixMicro Kind = "unix_us"
+	// UnixNano is nanoseconds since 1970-01-01
+	UnixNano Kind = "unix_ns"
+	// UnixSecondsOrMilli is seconds or milliseconds since 1970-01-01 told apart by magnitude, for values whose unit
+	// changed between application versions (ex. Firefox cookie expiry)
+	UnixSecondsOrMilli Kind = "unix_s_or_ms"
+)
+
+// Kinds lists every supported timestamp kind
+var Kinds = []Kind{Cocoa, WebKit, PRTime, FILETIME, HFSPlus, APFS, Unix, UnixMilli, UnixMicro, UnixNano, UnixSecondsOrMilli}
+
+// milliThreshold is the smallest UnixSecondsOrMilli value read as milliseconds, 2001-09-09 in milliseconds and
+// year 33658 in seconds
+const milliThreshold = 1e12
+
+// seconds between each epoch and the Unix epoch
+const (
+	cocoaUnixDelta   int64 = 978307200
+	webkitUnixDelta  int64 = 11644473600
+	hfsPlusUnixDelta int64 = 2082844800
+)
+
+// Output formats that timestamps can be written in
+const (
+	FormatRFC3339Nano = "rfc3339nano"
+	FormatRFC3339     = "rfc3339"
+	FormatEpoch       = "epoch"
+	FormatEpochMilli  = "epoch_ms"
+)
+
+// OutputFormats lists every supported output format
+var OutputFormats = []string{FormatRFC3339Nano, FormatRFC3339, FormatEpoch, FormatEpochMilli}
+
+var (
+	outputMutex  = &sync.RWMutex{}
+	outputFormat = FormatRFC3339Nano
+)
+
+// SetOutputFormat sets the format used by Format for the remainder of the run
+func SetOutputFormat(format string) error {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = FormatRFC3339Nano
+	}
+	for _, f := range OutputFormats {
+		if f == format {
+			outputMutex.Lock()
+			outputFormat = format
+			outputMutex.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported timestamp output format '%s', expected one of %s", format, strings.Join(OutputFormats, ", "))
+}
+
+// GetOutputFormat returns the output format currently used by Format
+func GetOutputFormat() string {
+	outputMutex.RLock()
+	defer outputMutex.RUnlock()
+	return outputFormat
+}
+
+// Format formats t in UTC using the configured output format, the zero time is returned as an empty string
+func Format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	t = t.UTC()
+	switch GetOutputFormat() {
+	case FormatRFC3339:
+		return t.Format(time.RFC3339)
+	case FormatEpoch:
+		if t.Nanosecond() == 0 {
+			return strconv.FormatInt(t.Unix(), 10)
+		}
+		return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', -1, 64)
+	case FormatEpochMilli:
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// FromCocoa converts Cocoa/CF absolute time in seconds, keeping microsecond precision
+func FromCocoa(seconds float64) time.Time {
+	return fromFloatSeconds(seconds, cocoaUnixDelta)
+}
+
+// FromWebKit converts WebKit/Chrome microseconds since 1601-01-01
+func FromWebKit(microseconds int64) time.Time {
+	return time.Unix(microseconds/1e6-webkitUnixDelta, (microseconds%1e6)*1e3).UTC()
+}
+
+// FromPRTime converts Mozilla PRTime microseconds since the Unix epoch
+func FromPRTime(microseconds int64) time.Time {
+	return time.Unix(microseconds/1e6, (microseconds%1e6)*1e3).UTC()
+}
+
+// FromFILETIME converts Windows FILETIME 100 nanosecond intervals since 1601-01-01
+func FromFILETIME(intervals uint64) time.Time {
+	return time.Unix(int64(intervals/1e7)-webkitUnixDelta, int64(intervals%1e7)*100).UTC()
+}
+
+// FromHFSPlus converts HFS+ seconds since 1904-01-01
+func FromHFSPlus(seconds uint32) time.Time {
+	return time.Unix(int64(seconds)-hfsPlusUnixDelta, 0).UTC()
+}
+
+// FromAPFS converts APFS nanoseconds since the Unix epoch
+func FromAPFS(nanoseconds int64) time.Time {
+	return time.Unix(0, nanoseconds).UTC()
+}
+
+// FromUnix converts Unix seconds, keeping microsecond precision
+func FromUnix(seconds float64) time.Time {
+	return fromFloatSeconds(seconds, 0)
+}
+
+// FromUnixMilli converts Unix milliseconds
+func FromUnixMilli(milliseconds int64) time.Time {
+	return time.Unix(milliseconds/1e3, (milliseconds%1e3)*1e6).UTC()
+}
+
+// FromUnixMicro converts Unix microseconds
+func FromUnixMicro(microseconds int64) time.Time {
+	return FromPRTime(microseconds)
+}
+
+// FromUnixNano converts Unix nanoseconds
+func FromUnixNano(nanoseconds int64) time.Time {
+	return FromAPFS(nanoseconds)
+}
+
+// fromFloatSeconds converts fractional seconds relative to an epoch delta, rounding to the microsecond
+// since float64 cannot hold more precision than that for current dates
+func fromFloatSeconds(seconds float64, delta int64) time.Time {
+	whole, frac := math.Modf(seconds)
+	usec := int64(math.Round(frac * 1e6))
+	return time.Unix(int64(whole)+delta, usec*1e3).UTC()
+}
+
+// Convert converts a raw value of the given kind to time.Time
+// value may be a string, integer, float or []byte holding a decimal number
+// A zero or empty value returns the zero time without an error
+func Convert(kind Kind, value interface{}) (time.Time, error) {
+	var (
+		i       int64
+		f       float64
+		isFloat bool
+	)
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case []byte:
+		return Convert(kind, string(v))
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return time.Time{}, nil
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			i = n
+			break
+		}
+		u, err := strconv.ParseUint(v, 10, 64)
+		if err == nil {
+			if kind == FILETIME {
+				if u == 0 {
+					return time.Time{}, nil
+				}
+				return FromFILETIME(u), nil
+			}
+			return time.Time{}, fmt.Errorf("%s timestamp '%s' is out of range", kind, v)
+		}
+		fv, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("could not parse %s timestamp '%s'", kind, v)
+		}
+		f, isFloat = fv, true
+	case int:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint32:
+		i = int64(v)
+	case uint64:
+		if kind == FILETIME {
+			if v == 0 {
+				return time.Time{}, nil
+			}
+			return FromFILETIME(v), nil
+		}
+		if v > math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("%s timestamp '%d' is out of range", kind, v)
+		}
+		i = int64(v)
+	case float32:
+		f, isFloat = float64(v), true
+	case float64:
+		f, isFloat = v, true
+	case time.Time:
+		return v.UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert %s timestamp of type %T", kind, value)
+	}
+
+	if isFloat {
+		if f == 0 {
+			return time.Time{}, nil
+		}
+		switch kind {
+		case Cocoa:
+			return FromCocoa(f), nil
+		case Unix:
+			return FromUnix(f), nil
+		case UnixSecondsOrMilli:
+			if math.Abs(f) < milliThreshold {
+				return FromUnix(f), nil
+			}
+		}
+		// integer based kinds stored as REAL
+		i = int64(f)
+	}
+	if i == 0 {
+		return time.Time{}, nil
+	}
+
+	switch kind {
+	case Cocoa:
+		return time.Unix(i+cocoaUnixDelta, 0).UTC(), nil
+	case WebKit:
+		return FromWebKit(i), nil
+	case PRTime, UnixMicro:
+		return FromPRTime(i), nil
+	case FILETIME:
+		return FromFILETIME(uint64(i)), nil
+	case HFSPlus:
+		if i < 0 || i > math.MaxUint32 {
+			return time.Time{}, fmt.Errorf("hfs+ timestamp '%d' is out of range", i)
+		}
+		return FromHFSPlus(uint32(i)), nil
+	case APFS, UnixNano:
+		return FromAPFS(i), nil
+	case Unix:
+		return time.Unix(i, 0).UTC(), nil
+	case UnixMilli:
+		return FromUnixMilli(i), nil
+	case UnixSecondsOrMilli:
+		if i >= milliThreshold || i <= -milliThreshold {
+			return FromUnixMilli(i), nil
+		}
+		return time.Unix(i, 0).UTC(), nil
+	}
+	return time.Time{}, errors.New("unsupported timestamp kind '" + string(kind) + "'")
+}
+
+// ConvertToString converts a raw value of the given kind and formats it with Format
+func ConvertToString(kind Kind, value interface{}) (string, error) {
+	t, err := Convert(kind, value)
+	if err != nil {
+		return "", err
+	}
+	return Format(t), nil
+}
+
+// ValidKind returns true if the given kind is supported
+func ValidKind(kind string) bool {
+	for _, k := range Kinds {
+		if string(k) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLayouts are the timestamp layouts accepted by Parse, tried in order
+var parseLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05.999999999 -0700 MST", // time.Time.String()
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"Jan _2 15:04:05 2006",
+	"Mon Jan _2 15:04:05 2006",
+}
+
+// Parse parses a timestamp previously written by Format (in any output format) or by time.Time.String()
+// format is the output format the timestamp was written with, it decides whether a bare integer is seconds or
+// milliseconds; when it is unknown ("") the integer is told apart by magnitude like UnixSecondsOrMilli
+func Parse(s string, format string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return time.Time{}, errors.New("empty timestamp")
+	}
+	// time.Time.String() appends a monotonic clock reading, strip it
+	if idx := strings.Index(s, " m="); idx != -1 {
+		s = s[:idx]
+	}
+	for _, layout := range parseLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	// epoch output, seconds or milliseconds
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		if !strings.Contains(s, ".") && (format == FormatEpochMilli || (format == "" && f >= milliThreshold)) {
+			return FromUnixMilli(int64(f)), nil
+		}
+		return FromUnix(f), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp format '%s'", s)
+}
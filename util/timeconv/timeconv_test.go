@@ -0,0 +1,161 @@
+package timeconv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  Kind
+		value interface{}
+		want  string
+	}{
+		{"cocoa int", Cocoa, int64(624741732), "2020-10-18T19:22:12Z"},
+		{"cocoa float keeps subseconds", Cocoa, 624741732.123456, "2020-10-18T19:22:12.123456Z"},
+		{"cocoa float string", Cocoa, "624741732.5", "2020-10-18T19:22:12.5Z"},
+		{"cocoa negative", Cocoa, int64(-978307200), "1970-01-01T00:00:00Z"},
+		{"webkit", WebKit, int64(13247522532123456), "2020-10-18T19:22:12.123456Z"},
+		{"webkit string", WebKit, "13247522532000001", "2020-10-18T19:22:12.000001Z"},
+		{"webkit uint64", WebKit, uint64(13247522532000000), "2020-10-18T19:22:12Z"},
+		{"prtime", PRTime, int64(1603048932123456), "2020-10-18T19:22:12.123456Z"},
+		{"filetime", FILETIME, uint64(132475225321234567), "2020-10-18T19:22:12.1234567Z"},
+		{"filetime string", FILETIME, "132475225320000000", "2020-10-18T19:22:12Z"},
+		{"hfs+", HFSPlus, uint32(3685893732), "2020-10-18T19:22:12Z"},
+		{"apfs", APFS, int64(1603048932123456789), "2020-10-18T19:22:12.123456789Z"},
+		{"unix seconds", Unix, int64(1603048932), "2020-10-18T19:22:12Z"},
+		{"unix float seconds", Unix, 1603048932.25, "2020-10-18T19:22:12.25Z"},
+		{"unix milliseconds", UnixMilli, int64(1603048932123), "2020-10-18T19:22:12.123Z"},
+		{"unix microseconds", UnixMicro, "1603048932123456", "2020-10-18T19:22:12.123456Z"},
+		{"unix nanoseconds", UnixNano, int64(1603048932000000001), "2020-10-18T19:22:12.000000001Z"},
+		{"unix seconds by magnitude", UnixSecondsOrMilli, "1603048932", "2020-10-18T19:22:12Z"},
+		{"unix far future seconds by magnitude", UnixSecondsOrMilli, int64(253402300799), "9999-12-31T23:59:59Z"},
+		{"unix milliseconds by magnitude", UnixSecondsOrMilli, "1603048932123", "2020-10-18T19:22:12.123Z"},
+		{"unix float milliseconds by magnitude", UnixSecondsOrMilli, 1603048932123.0, "2020-10-18T19:22:12.123Z"},
+		{"zero is empty", WebKit, "0", ""},
+		{"empty is empty", Cocoa, "", ""},
+		{"nil is empty", PRTime, nil, ""},
+		{"bytes", Unix, []byte("1603048932"), "2020-10-18T19:22:12Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertToString(tt.kind, tt.value)
+			if err != nil {
+				t.Fatalf("ConvertToString(%s, %v) returned error: %s", tt.kind, tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ConvertToString(%s, %v) = %q, want %q", tt.kind, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  Kind
+		value interface{}
+	}{
+		{"not a number", WebKit, "yesterday"},
+		{"unsupported type", Unix, struct{}{}},
+		{"unsupported kind", Kind("martian"), int64(1)},
+		{"hfs+ out of range", HFSPlus, int64(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Convert(tt.kind, tt.value); err == nil {
+				t.Errorf("Convert(%s, %v) expected an error", tt.kind, tt.value)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	defer SetOutputFormat(FormatRFC3339Nano)
+	ts := time.Date(2020, 10, 18, 19, 22, 12, 500000000, time.UTC)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatRFC3339Nano, "2020-10-18T19:22:12.5Z"},
+		{FormatRFC3339, "2020-10-18T19:22:12Z"},
+		{FormatEpoch, "1603048932.5"},
+		{FormatEpochMilli, "1603048932500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if err := SetOutputFormat(tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if got := Format(ts); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+			parsed, err := Parse(Format(ts), tt.format)
+			if err != nil {
+				t.Fatalf("Parse(Format()) returned error: %s", err)
+			}
+			if tt.format != FormatRFC3339 && !parsed.Equal(ts) {
+				t.Errorf("Parse(Format()) = %s, want %s", parsed, ts)
+			}
+		})
+	}
+	if err := SetOutputFormat("stardate"); err == nil {
+		t.Error("SetOutputFormat expected an error for an unknown format")
+	}
+	if got := Format(time.Time{}); got != "" {
+		t.Errorf("Format(zero) = %q, want empty", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	want := time.Date(2020, 10, 18, 19, 22, 12, 0, time.UTC)
+	tests := []string{
+		"2020-10-18T19:22:12Z",
+		"2020-10-18T21:22:12+02:00",
+		"2020-10-18 19:22:12 +0000 UTC",
+		"2020-10-18 19:22:12 +0000 UTC m=+0.000000001",
+		"2020-10-18 19:22:12",
+		"1603048932",
+	}
+	for _, s := range tests {
+		t.Run(s, func(t *testing.T) {
+			got, err := Parse(s, FormatRFC3339Nano)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %s", s, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("Parse(%q) = %s, want %s", s, got, want)
+			}
+		})
+	}
+	if _, err := Parse("not a time", FormatRFC3339Nano); err == nil {
+		t.Error("Parse expected an error for garbage input")
+	}
+}
+
+// A bare integer is read in the unit of the format it was written with, whatever format this process writes
+func TestParseEpochUnit(t *testing.T) {
+	if err := SetOutputFormat(FormatRFC3339Nano); err != nil {
+		t.Fatal(err)
+	}
+	seconds := time.Date(2020, 10, 18, 19, 22, 12, 0, time.UTC)
+	milli := time.Date(2020, 10, 18, 19, 22, 12, 500000000, time.UTC)
+	tests := []struct {
+		in     string
+		format string
+		want   time.Time
+	}{
+		{"1603048932", FormatEpoch, seconds},
+		{"1603048932500", FormatEpochMilli, milli},
+		{"1603048932", "", seconds},
+		{"1603048932500", "", milli},
+		{"1603048932.5", FormatEpochMilli, milli},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.format)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Parse(%q, %q) = %s, %v, want %s", tt.in, tt.format, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package windowshelpers
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// fileFlagOpenReparsePoint opens a symbolic link or junction itself instead of its target
+const fileFlagOpenReparsePoint = 0x00200000
+
+// FileID returns the volume serial number and file index of fp, the NTFS equivalent of an inode
+// (ex. 1a2b3c4d-0001000000002f3a)
+func FileID(fp string) (string, error) {
+	name, err := syscall.UTF16PtrFromString(fp)
+	if err != nil {
+		return "", err
+	}
+	// Opening with no access only reads metadata, backup semantics allows opening directories
+	h, err := syscall.CreateFile(name, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS|fileFlagOpenReparsePoint, 0)
+	if err != nil {
+		return "", err
+	}
+	defer syscall.CloseHandle(h)
+
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &info); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08x-%08x%08x", info.VolumeSerialNumber, info.FileIndexHigh, info.FileIndexLow), nil
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/anthonybm/Orion/util/timeconv"
 	"gopkg.in/djherbis/times.v1"
 )
 
@@ -21,6 +22,7 @@ func FileMetadata(fp string, modulename string) (map[string]string, error) {
 	m["btime"] = "NO VALUE"
 	m["path"] = "NO VALUE"
 	m["name"] = "NO VALUE"
+	m["file_id"] = "NO VALUE"
 
 	stat, err := os.Lstat(fp)
 	if err != nil {
@@ -57,16 +59,19 @@ func FileMetadata(fp string, modulename string) (map[string]string, error) {
 	// }
 	// m["uid"] = strconv.Itoa(UID)
 	// m["gid"] = strconv.Itoa(GID)
-	m["mtime"] = timestat.ModTime().UTC().String()
-	m["atime"] = timestat.AccessTime().UTC().String()
+	m["mtime"] = timeconv.Format(timestat.ModTime())
+	m["atime"] = timeconv.Format(timestat.AccessTime())
 	if timestat.HasChangeTime() {
-		m["ctime"] = timestat.ChangeTime().UTC().String()
+		m["ctime"] = timeconv.Format(timestat.ChangeTime())
 	}
 	if timestat.HasBirthTime() {
-		m["btime"] = timestat.BirthTime().UTC().String()
+		m["btime"] = timeconv.Format(timestat.BirthTime())
 	}
 	m["path"] = fp
 	m["name"] = filepath.Base(fp)
+	if id, err := FileID(fp); err == nil {
+		m["file_id"] = id
+	}
 
 	return m, nil
 }
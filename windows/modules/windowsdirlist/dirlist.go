@@ -6,15 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/anthonybm/Orion/baseline"
 	"github.com/anthonybm/Orion/datawriter"
+	"github.com/anthonybm/Orion/diff"
 	"github.com/anthonybm/Orion/instance"
+	"github.com/anthonybm/Orion/moduleinfo"
+	"github.com/anthonybm/Orion/throttle"
+	"github.com/anthonybm/Orion/timeline"
 	"github.com/anthonybm/Orion/util"
 	"github.com/anthonybm/Orion/util/windowshelpers"
 	"github.com/karrick/godirwalk"
@@ -35,8 +39,35 @@ var (
 	doHashSHA256       bool
 	walkRootDir        string
 	scratchBuffSize    = godirwalk.MinimumScratchBufferSize
+	previousFiles      map[string]baseline.File
+	reusedHashes       int
+	timestampFormat    string
 )
 
+// Config is the [modules.WindowsDirlistModule] config section
+type Config struct {
+	RootWalkDir        string   `toml:"RootWalkDir" comment:"directory to walk, empty walks every logical drive"`
+	ExcludedDirs       []string `toml:"ExcludedDirs" comment:"directory globs to skip relative to each drive, without the drive letter"`
+	ExcludedDrives     []string `toml:"ExcludedDrives" comment:"logical drives to skip, including the colon (ex. \"D:\")"`
+	ExcludedExts       []string `toml:"ExcludedExts" comment:"file extensions to skip"`
+	HashSizeLimitBytes int      `toml:"HashSizeLimitBytes" comment:"only hash files smaller than this, ~10x faster than hashing every file"`
+	DoHashMD5          bool     `toml:"DoHashMD5" comment:"hash files with MD5"`
+	DoHashSHA256       bool     `toml:"DoHashSHA256" comment:"hash files with SHA256"`
+	Verbose            bool     `toml:"Verbose" comment:"log every file walked"`
+}
+
+// DefaultConfig returns the [modules.WindowsDirlistModule] config section with default values
+func (m WindowsDirlistModule) DefaultConfig() interface{} {
+	return &Config{
+		ExcludedDirs:       []string{},
+		ExcludedDrives:     []string{},
+		ExcludedExts:       []string{},
+		HashSizeLimitBytes: 10485760,
+		DoHashMD5:          true,
+		DoHashSHA256:       true,
+	}
+}
+
 // Start executes the module with instance instructions
 func (m WindowsDirlistModule) Start(inst instance.Instance) error {
 	err := m.dirlist(inst)
@@ -46,11 +77,46 @@ func (m WindowsDirlistModule) Start(inst instance.Instance) error {
 	return err
 }
 
+// Info returns the module description, version and tags shown by --list
+func (m WindowsDirlistModule) Info() moduleinfo.Info {
+	return moduleinfo.Info{Name: moduleName, Mode: mode, Version: version, Description: description, Author: author, Tags: []string{moduleinfo.TagDiskHeavy}}
+}
+
+// Timeline declares the event time columns of the module output for the super-timeline
+func (m WindowsDirlistModule) Timeline() []timeline.Definition {
+	return []timeline.Definition{
+		{
+			Output: moduleName,
+			Events: []timeline.Event{
+				{Field: "mtime", Type: "File Modified", MACB: "M..."},
+				{Field: "atime", Type: "File Accessed", MACB: ".A.."},
+				{Field: "ctime", Type: "File Metadata Changed", MACB: "..C."},
+				{Field: "btime", Type: "File Created", MACB: "...B"},
+			},
+			DescriptionFields: []string{"path", "name", "size", "sha256"},
+		},
+	}
+}
+
+// DiffKeys declares the columns identifying a row of the module output for orion diff
+func (m WindowsDirlistModule) DiffKeys() []diff.Keys {
+	return []diff.Keys{
+		{Output: moduleName, Columns: []string{"path"}, Ignore: []string{"atime", "file_id"}},
+	}
+}
+
 func (m WindowsDirlistModule) dirlist(inst instance.Instance) error {
-	doHashMD5, _ = inst.GetOrionConfig().GetDirlistDoHashMD5()
-	doHashSHA256, _ = inst.GetOrionConfig().GetDirlistDohashSHA256()
-	hashSizeLimitBytes, _ = inst.GetOrionConfig().GetDirlistHashSizeLimitBytes()
+	cfg := m.DefaultConfig().(*Config)
+	err := inst.GetOrionConfig().DecodeModuleConfig(moduleName, cfg)
+	if err != nil {
+		return err
+	}
+	doHashMD5 = cfg.DoHashMD5
+	doHashSHA256 = cfg.DoHashSHA256
+	walkRootDir = cfg.RootWalkDir
+	hashSizeLimitBytes = cfg.HashSizeLimitBytes
 	verbose, _ = inst.GetOrionConfig().IsVerbose()
+	verbose = verbose || cfg.Verbose
 
 	// Create OrionWriter
 	mw, err := datawriter.NewOrionWriter(moduleName, inst.GetOrionRuntime(), inst.GetOrionOutputFormat(), inst.GetOrionOutputFilepath())
@@ -68,10 +134,28 @@ func (m WindowsDirlistModule) dirlist(inst instance.Instance) error {
 		"atime",
 		"ctime",
 		"btime",
+		"file_id",
 		"sha256",
 		"md5",
 	}
 	values := [][]string{}
+	// The header is written first, rows are written early when the heap reaches the memory limit
+	err = mw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+
+	// Hashes of files unchanged since the baseline are copied from it instead of read again
+	previousFiles = nil
+	reusedHashes = 0
+	timestampFormat = inst.GetTimestampFormat()
+	if b := inst.GetBaseline(); b != nil && b.Has(moduleName) {
+		previousFiles, err = b.Files(moduleName, "file_id")
+		if err != nil {
+			zap.L().Warn("Failed to read the baseline file list, hashing every file: "+err.Error(), zap.String("module", moduleName))
+			previousFiles = nil
+		}
+	}
 
 	count := 0
 	benchmarkStart := time.Now()
@@ -81,12 +165,15 @@ func (m WindowsDirlistModule) dirlist(inst instance.Instance) error {
 	// devicecount := 0
 
 	// Get a list of logical drives to use as root target paths
-	walkRootDirs, err := windowsWalkRootDir(walkRootDir)
-	if err != nil {
-		return errors.New("Could not determine dirlist target root path! " + err.Error())
+	walkRootDirs := []string{walkRootDir}
+	if walkRootDir == "" {
+		walkRootDirs, err = windowsWalkRootDir(walkRootDir)
+		if err != nil {
+			return errors.New("Could not determine dirlist target root path! " + err.Error())
+		}
 	}
 	// Remove logical drives from target paths list based on excluded drives from Config
-	excludedDrives, _ := inst.GetOrionConfig().GetDirlistExcludedDrives()
+	excludedDrives := cfg.ExcludedDrives
 	for i, r := range walkRootDirs {
 		for _, d := range excludedDrives {
 			if r == d {
@@ -99,7 +186,7 @@ func (m WindowsDirlistModule) dirlist(inst instance.Instance) error {
 	}
 
 	// Exclude directories via Glob using the list of drive root target paths
-	excludedDirsNames, _ := inst.GetOrionConfig().GetDirlistExcludedDirs()
+	excludedDirsNames := cfg.ExcludedDirs
 	excludedDirs := util.MultiMultiGlob(excludedDirsNames, walkRootDirs)
 	zap.L().Debug(fmt.Sprintf("Want to exclude: %s", excludedDirsNames), zap.String("module", moduleName))
 	zap.L().Debug(fmt.Sprintf("Actually excluding: %s", excludedDirs), zap.String("module", moduleName))
@@ -109,7 +196,7 @@ func (m WindowsDirlistModule) dirlist(inst instance.Instance) error {
 	// 	excludedDirsMap[excDir] = true
 	// }
 
-	excludedExts, _ := inst.GetOrionConfig().GetDirlistExcludedExts()
+	excludedExts := cfg.ExcludedExts
 	excludedExtsMap := make(map[string]bool) // Map for fast access to search excluded
 	for _, excExt := range excludedExts {
 		excludedExtsMap[excExt] = true
@@ -133,6 +220,13 @@ func (m WindowsDirlistModule) dirlist(inst instance.Instance) error {
 					}
 					filecount++
 					values = append(values, parseRegular(osPathname, de))
+					if throttle.MemoryHigh() {
+						// Write the rows collected so far to free memory
+						if err := mw.WriteAll(values); err != nil {
+							zap.L().Error("Failed to write rows: "+err.Error(), zap.String("module", moduleName))
+						}
+						values = [][]string{}
+					}
 				}
 				// } else if de.IsSymlink() {
 				// 	symcount++
@@ -168,11 +262,11 @@ func (m WindowsDirlistModule) dirlist(inst instance.Instance) error {
 	// zap.L().Debug("SymLinks: "+strconv.Itoa(symcount), zap.String("module", moduleName))
 	// zap.L().Debug("Device: "+strconv.Itoa(devicecount), zap.String("module", moduleName))
 
-	// Write to output
-	err = mw.WriteHeader(header)
-	if err != nil {
-		return err
+	if previousFiles != nil {
+		zap.L().Info("Reused the hashes of ["+strconv.Itoa(reusedHashes)+"] files unchanged since the baseline", zap.String("module", moduleName))
 	}
+
+	// Write the remaining rows to output
 	err = mw.WriteAll(values)
 	if err != nil {
 		return err
@@ -202,14 +296,19 @@ func parseRegular(osPathname string, de *godirwalk.Dirent) []string {
 	hashMD5 := "N/E"
 	size, _ := strconv.Atoi(metadata["size"])
 
-	if doHashSHA256 && (size < hashSizeLimitBytes) {
+	previous, unchanged := previousFile(metadata)
+	if doHashSHA256 && (size < hashSizeLimitBytes) && unchanged && baseline.Hash(previous.SHA256) != "" {
+		hashSHA256 = previous.SHA256
+	} else if doHashSHA256 && (size < hashSizeLimitBytes) {
 		h, err := fileSHA256(osPathname)
 		if err != nil {
 			hashSHA256 = "ERROR"
 		}
 		hashSHA256 = h
 	}
-	if doHashMD5 && (size < hashSizeLimitBytes) {
+	if doHashMD5 && (size < hashSizeLimitBytes) && unchanged && baseline.Hash(previous.MD5) != "" {
+		hashMD5 = previous.MD5
+	} else if doHashMD5 && (size < hashSizeLimitBytes) {
 		h, err := fileMD5(osPathname)
 		if err != nil {
 			hashMD5 = "ERROR"
@@ -217,15 +316,20 @@ func parseRegular(osPathname string, de *godirwalk.Dirent) []string {
 		hashMD5 = h
 	}
 
+	if unchanged {
+		reusedHashes++
+	}
+
 	entry := []string{
-		metadata["path"],  // "path",
-		metadata["name"],  // "name",
-		metadata["mode"],  // "mode",
-		metadata["size"],  // "size",
-		metadata["mtime"], // "mtime",
-		metadata["atime"], // "atime",
-		metadata["ctime"], // "ctime",
-		metadata["btime"], // "btime",
+		metadata["path"],    // "path",
+		metadata["name"],    // "name",
+		metadata["mode"],    // "mode",
+		metadata["size"],    // "size",
+		metadata["mtime"],   // "mtime",
+		metadata["atime"],   // "atime",
+		metadata["ctime"],   // "ctime",
+		metadata["btime"],   // "btime",
+		metadata["file_id"], // "file_id",
 		hashSHA256,
 		hashMD5,
 	}
@@ -234,8 +338,22 @@ func parseRegular(osPathname string, de *godirwalk.Dirent) []string {
 	return entry
 }
 
+// previousFile returns what the baseline recorded about the file described by metadata, false when the file
+// is new or its size, mtime or file id changed
+func previousFile(metadata map[string]string) (baseline.File, bool) {
+	previous, ok := previousFiles[metadata["path"]]
+	if !ok {
+		return baseline.File{}, false
+	}
+	mtime, err := timeline.ParseTime(metadata["mtime"], timestampFormat)
+	if err != nil || !previous.Unchanged(metadata["size"], mtime, metadata["file_id"]) {
+		return baseline.File{}, false
+	}
+	return previous, true
+}
+
 func fileSHA256(fp string) (string, error) {
-	f, err := os.Open(fp)
+	f, err := throttle.Open(fp)
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("file open error %s for %s: ", err.Error(), fp), zap.String("module", moduleName))
 		return "", err
@@ -251,7 +369,7 @@ func fileSHA256(fp string) (string, error) {
 }
 
 func fileMD5(fp string) (string, error) {
-	f, err := os.Open(fp)
+	f, err := throttle.Open(fp)
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("file open error %s for %s: ", err.Error(), fp), zap.String("module", moduleName))
 		return "", err